@@ -0,0 +1,25 @@
+package core
+
+import (
+	. "github.com/jtejido/golucene/core/analysis"
+	"io"
+)
+
+// core/WhitespaceAnalyzer.java
+
+/* An Analyzer that uses WhitespaceTokenizer. */
+type WhitespaceAnalyzer struct {
+	*AnalyzerImpl
+}
+
+/* Creates a new WhitespaceAnalyzer */
+func NewWhitespaceAnalyzer() *WhitespaceAnalyzer {
+	ans := new(WhitespaceAnalyzer)
+	ans.AnalyzerImpl = NewAnalyzer()
+	ans.Spi = ans
+	return ans
+}
+
+func (a *WhitespaceAnalyzer) CreateComponents(fieldName string, reader io.RuneReader) *TokenStreamComponents {
+	return NewTokenStreamComponentsUnfiltered(NewWhitespaceTokenizer(reader))
+}
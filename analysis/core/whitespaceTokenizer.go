@@ -0,0 +1,31 @@
+package core
+
+import (
+	. "github.com/jtejido/golucene/analysis/util"
+	"io"
+	"unicode"
+)
+
+// core/WhitespaceTokenizer.java
+
+/*
+A WhitespaceTokenizer is a tokenizer that divides text at whitespace.
+Adjacent sequences of non-Whitespace characters form tokens.
+*/
+type WhitespaceTokenizer struct {
+	*CharTokenizer
+}
+
+/* Construct a new WhitespaceTokenizer. */
+func NewWhitespaceTokenizer(in io.RuneReader) *WhitespaceTokenizer {
+	ans := new(WhitespaceTokenizer)
+	ans.CharTokenizer = NewCharTokenizer(ans, in)
+	return ans
+}
+
+/*
+Collects only characters which do not satisfy unicode.IsSpace().
+*/
+func (t *WhitespaceTokenizer) IsTokenChar(c rune) bool {
+	return !unicode.IsSpace(c)
+}
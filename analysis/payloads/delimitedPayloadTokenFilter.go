@@ -0,0 +1,69 @@
+package payloads
+
+import (
+	. "github.com/jtejido/golucene/core/analysis"
+	. "github.com/jtejido/golucene/core/analysis/tokenattributes"
+)
+
+// payloads/DelimitedPayloadTokenFilter.java
+
+const DEFAULT_DELIMITER = '|'
+
+/*
+Characters before the delimiter are the "token", those after are the
+payload.
+
+For example, if the delimiter is '|', then for the string
+"foo|bar", foo is the token and "bar" is a payload.
+
+Note, you can also include a PayloadEncoder to convert the payload in
+an appropriate way (from characters to bytes).
+
+Note make sure your Tokenizer doesn't split on the delimiter, or this
+won't work.
+*/
+type DelimitedPayloadTokenFilter struct {
+	*TokenFilter
+	input     TokenStream
+	termAtt   CharTermAttribute
+	payAtt    PayloadAttribute
+	delimiter rune
+	encoder   PayloadEncoder
+}
+
+func NewDelimitedPayloadTokenFilter(input TokenStream, delimiter rune,
+	encoder PayloadEncoder) *DelimitedPayloadTokenFilter {
+
+	ans := &DelimitedPayloadTokenFilter{
+		TokenFilter: NewTokenFilter(input),
+		input:       input,
+		delimiter:   delimiter,
+		encoder:     encoder,
+	}
+	ans.termAtt = ans.Attributes().Add("CharTermAttribute").(CharTermAttribute)
+	ans.payAtt = ans.Attributes().Add("PayloadAttribute").(PayloadAttribute)
+	return ans
+}
+
+func (f *DelimitedPayloadTokenFilter) IncrementToken() (bool, error) {
+	ok, err := f.input.IncrementToken()
+	if err != nil || !ok {
+		return false, err
+	}
+	buffer := f.termAtt.Buffer()
+	length := f.termAtt.Length()
+	for i := 0; i < length; i++ {
+		if buffer[i] == f.delimiter {
+			payload, err := f.encoder.Encode(buffer[i+1 : length])
+			if err != nil {
+				return false, err
+			}
+			f.payAtt.SetPayload(payload)
+			f.termAtt.SetLength(i) // simply set a new length
+			return true, nil
+		}
+	}
+	// we have not seen the delimiter
+	f.payAtt.SetPayload(nil)
+	return true, nil
+}
@@ -0,0 +1,50 @@
+package payloads
+
+import (
+	. "github.com/jtejido/golucene/core/analysis"
+	. "github.com/jtejido/golucene/core/analysis/tokenattributes"
+)
+
+// payloads/NumericPayloadTokenFilter.java
+
+/*
+Assigns a payload to a token based on the TypeAttribute.Type().
+*/
+type NumericPayloadTokenFilter struct {
+	*TokenFilter
+	input      TokenStream
+	typeMatch  string
+	thePayload []byte
+
+	payloadAtt PayloadAttribute
+	typeAtt    TypeAttribute
+}
+
+func NewNumericPayloadTokenFilter(input TokenStream, payload float32,
+	typeMatch string) *NumericPayloadTokenFilter {
+
+	if typeMatch == "" {
+		panic("typeMatch cannot be empty")
+	}
+	ans := &NumericPayloadTokenFilter{
+		TokenFilter: NewTokenFilter(input),
+		input:       input,
+		// Need to encode the payload
+		thePayload: EncodeFloat(payload),
+		typeMatch:  typeMatch,
+	}
+	ans.payloadAtt = ans.Attributes().Add("PayloadAttribute").(PayloadAttribute)
+	ans.typeAtt = ans.Attributes().Add("TypeAttribute").(TypeAttribute)
+	return ans
+}
+
+func (f *NumericPayloadTokenFilter) IncrementToken() (bool, error) {
+	ok, err := f.input.IncrementToken()
+	if err != nil || !ok {
+		return false, err
+	}
+	if f.typeAtt.Type() == f.typeMatch {
+		f.payloadAtt.SetPayload(f.thePayload)
+	}
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package payloads
+
+import (
+	"strconv"
+)
+
+// payloads/PayloadEncoder.java
+
+/*
+Mainly for use with the DelimitedPayloadTokenFilter, converts char
+buffers to payload bytes.
+
+NOTE: This interface is subject to change
+*/
+type PayloadEncoder interface {
+	// Convert a rune slice to a byte slice, suitable for use as a
+	// payload.
+	Encode(buffer []rune) ([]byte, error)
+}
+
+// payloads/FloatEncoder.java
+
+/*
+Encode a character array float as a payload. See
+PayloadHelper.EncodeFloat().
+*/
+type FloatEncoder struct{}
+
+func NewFloatEncoder() *FloatEncoder {
+	return &FloatEncoder{}
+}
+
+func (e *FloatEncoder) Encode(buffer []rune) ([]byte, error) {
+	payload, err := strconv.ParseFloat(string(buffer), 32)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeFloat(float32(payload)), nil
+}
+
+// payloads/IntegerEncoder.java
+
+/*
+Encode a character array integer as a payload. See
+PayloadHelper.EncodeInt().
+*/
+type IntegerEncoder struct{}
+
+func NewIntegerEncoder() *IntegerEncoder {
+	return &IntegerEncoder{}
+}
+
+func (e *IntegerEncoder) Encode(buffer []rune) ([]byte, error) {
+	payload, err := strconv.ParseInt(string(buffer), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeInt(int32(payload)), nil
+}
+
+// payloads/IdentityEncoder.java
+
+/* Does nothing other than convert the runes to UTF-8 bytes. */
+type IdentityEncoder struct{}
+
+func NewIdentityEncoder() *IdentityEncoder {
+	return &IdentityEncoder{}
+}
+
+func (e *IdentityEncoder) Encode(buffer []rune) ([]byte, error) {
+	return []byte(string(buffer)), nil
+}
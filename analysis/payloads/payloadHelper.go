@@ -0,0 +1,33 @@
+package payloads
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// payloads/PayloadHelper.java
+
+/* Utility methods for encoding payloads. */
+
+func EncodeFloat(payload float32) []byte {
+	return EncodeInt(int32(math.Float32bits(payload)))
+}
+
+func EncodeInt(payload int32) []byte {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(payload))
+	return data
+}
+
+/*
+Decode the payload that was encoded using EncodeFloat(). NOTE: the
+slice must be at least 4 bytes long.
+*/
+func DecodeFloat(bytes []byte) float32 {
+	return math.Float32frombits(uint32(DecodeInt(bytes)))
+}
+
+/* Decode the payload that was encoded using EncodeInt(). */
+func DecodeInt(bytes []byte) int32 {
+	return int32(binary.BigEndian.Uint32(bytes))
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	. "github.com/jtejido/golucene/core/analysis"
+	. "github.com/jtejido/golucene/core/analysis/tokenattributes"
+	"io"
+)
+
+// util/CharTokenizer.java
+
+const MAX_WORD_LEN = 255
+
+type CharTokenizerSPI interface {
+	// Returns true iff a codepoint should be included in a token. This
+	// tokenizer generates as tokens adjacent sequences of codepoints
+	// which satisfy this predicate. Codepoints for which this is false
+	// are used to define token boundaries and are not included in
+	// tokens.
+	IsTokenChar(c rune) bool
+	// Called on each token character to normalize it before it is
+	// added to the token. The default implementation does nothing.
+	// Subclasses may use this to, e.g., lowercase tokens.
+	Normalize(c rune) rune
+}
+
+/* An abstract base class for simple, character-oriented tokenizers. */
+type CharTokenizer struct {
+	*Tokenizer
+	spi CharTokenizerSPI
+
+	offset, finalOffset int
+
+	termAtt   CharTermAttribute
+	offsetAtt OffsetAttribute
+}
+
+/* Creates a new CharTokenizer instance */
+func NewCharTokenizer(spi CharTokenizerSPI, input io.RuneReader) *CharTokenizer {
+	ans := &CharTokenizer{
+		Tokenizer: NewTokenizer(input),
+		spi:       spi,
+	}
+	ans.termAtt = ans.Attributes().Add("CharTermAttribute").(CharTermAttribute)
+	ans.offsetAtt = ans.Attributes().Add("OffsetAttribute").(OffsetAttribute)
+	return ans
+}
+
+func (t *CharTokenizer) Normalize(c rune) rune {
+	return c
+}
+
+func (t *CharTokenizer) IncrementToken() (bool, error) {
+	t.Attributes().Clear()
+	length, start := 0, -1 // this variable is always initialized
+	buffer := t.termAtt.Buffer()
+	for {
+		c, _, err := t.Input.ReadRune()
+		if err == io.EOF {
+			if length > 0 {
+				break
+			}
+			t.finalOffset = t.CorrectOffset(t.offset)
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		t.offset++
+
+		if t.spi.IsTokenChar(c) { // if it's a token char
+			if length == 0 { // start of token
+				start = t.offset - 1
+			}
+			if length >= len(buffer)-1 { // check if a supplementary could run out of bounds
+				buffer = t.termAtt.ResizeBuffer(2 + length) // make sure a supplementary fits in the buffer
+			}
+			buffer[length] = t.spi.Normalize(c) // buffer it, normalized
+			length++
+			if length >= MAX_WORD_LEN { // buffer overflow! make sure to check for >= surrogate pair could break == test
+				break
+			}
+		} else if length > 0 { // at non-Letter w/ chars
+			break // return 'em
+		}
+	}
+
+	t.termAtt.SetLength(length)
+	t.finalOffset = t.CorrectOffset(start + length)
+	t.offsetAtt.SetOffset(t.CorrectOffset(start), t.finalOffset)
+	return true, nil
+}
+
+func (t *CharTokenizer) End() error {
+	if err := t.Tokenizer.End(); err != nil {
+		return err
+	}
+	// set final offset
+	t.offsetAtt.SetOffset(t.finalOffset, t.finalOffset)
+	return nil
+}
+
+func (t *CharTokenizer) Reset() error {
+	if err := t.Tokenizer.Reset(); err != nil {
+		return err
+	}
+	t.offset = 0
+	t.finalOffset = 0
+	return nil
+}
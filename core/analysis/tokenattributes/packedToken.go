@@ -60,6 +60,10 @@ func (a *PackedTokenAttributeImpl) SetOffset(startOffset, endOffset int) {
 	a.endOffset = endOffset
 }
 
+func (a *PackedTokenAttributeImpl) Type() string {
+	return a.typ
+}
+
 func (a *PackedTokenAttributeImpl) SetType(typ string) {
 	a.typ = typ
 }
@@ -126,8 +126,9 @@ func (e *SegmentTermsEnum) getArc(ord int) *fst.Arc {
 	return e.arcs[ord]
 }
 
+/* Terms are always in unicode order; comparators are not ported. */
 func (e *SegmentTermsEnum) Comparator() sort.Interface {
-	panic("not implemented yet")
+	return nil
 }
 
 // Pushes a frame we seek'd to
@@ -187,7 +188,7 @@ func (e *SegmentTermsEnum) SeekExact(target []byte) (ok bool, err error) {
 	// fmt.Printf("BTTR.seekExact seg=%v target=%v:%v current=%v (exists?=%v) validIndexPrefix=%v\n",
 	// 	e.fr.parent.segment, e.fr.fieldInfo.Name, brToString(target),
 	// 	brToString(e.term.bytes), e.termExists, e.validIndexPrefix)
-	e.printSeekState()
+	// e.printSeekState()
 
 	var arc *fst.Arc
 	var targetUpto int
@@ -238,10 +239,9 @@ func (e *SegmentTermsEnum) SeekExact(target []byte) (ok bool, err error) {
 			arc = e.arcs[1+targetUpto]
 			assert2(arc.Label == int(target[targetUpto]),
 				"arc.label=%c targetLabel=%c", arc.Label, target[targetUpto])
-			panic("not implemented yet")
-			// if arc.Output != noOutputs {
-			// 	output = e.fstOutputs.Add(output, arc.Output).([]byte)
-			// }
+			if !fst.CompareFSTValue(arc.Output, noOutput) {
+				output = fstOutputs.Add(output, arc.Output)
+			}
 			if arc.IsFinal() {
 				lastFrame = e.stack[1+lastFrame.ord]
 			}
@@ -413,8 +413,182 @@ func (e *SegmentTermsEnum) SeekExact(target []byte) (ok bool, err error) {
 	}
 }
 
-func (e *SegmentTermsEnum) SeekCeil(text []byte) SeekStatus {
-	panic("not implemented yet")
+func (e *SegmentTermsEnum) SeekCeil(target []byte) (SeekStatus, error) {
+	assert2(e.fr.index != nil, "terms index was not loaded")
+
+	e.term.Grow(1 + len(target))
+
+	e.eof = false
+
+	var arc *fst.Arc
+	var targetUpto int
+	var output interface{}
+	var err error
+
+	e.targetBeforeCurrentLength = e.currentFrame.ord
+
+	if e.currentFrame.ord != e.staticFrame.ord {
+		// We are already seek'd; find the common
+		// prefix of new seek term vs current term and
+		// re-use the corresponding seek state.  For
+		// example, if app first seeks to foobar, then
+		// seeks to foobaz, we can re-use the seek state
+		// for the first 5 bytes.
+
+		arc = e.arcs[0]
+		assert(arc.IsFinal())
+		output = arc.Output
+		targetUpto = 0
+
+		lastFrame := e.stack[0]
+		assert(e.validIndexPrefix <= e.term.Length())
+
+		targetLimit := len(target)
+		if e.validIndexPrefix < targetLimit {
+			targetLimit = e.validIndexPrefix
+		}
+
+		cmp := 0
+
+		// First compare up to valid seek frames:
+		for targetUpto < targetLimit {
+			cmp = int(e.term.At(targetUpto)) - int(target[targetUpto])
+			if cmp != 0 {
+				break
+			}
+			arc = e.arcs[1+targetUpto]
+			assert2(arc.Label == int(target[targetUpto]),
+				"arc.label=%c targetLabel=%c", arc.Label, target[targetUpto])
+			if !fst.CompareFSTValue(arc.Output, noOutput) {
+				output = fstOutputs.Add(output, arc.Output)
+			}
+			if arc.IsFinal() {
+				lastFrame = e.stack[1+lastFrame.ord]
+			}
+			targetUpto++
+		}
+
+		if cmp == 0 {
+			targetUptoMid := targetUpto
+			// Second compare the rest of the term, but
+			// don't save arc/output/frame:
+			targetLimit2 := len(target)
+			if e.term.Length() < targetLimit2 {
+				targetLimit2 = e.term.Length()
+			}
+			for targetUpto < targetLimit2 {
+				cmp = int(e.term.At(targetUpto)) - int(target[targetUpto])
+				if cmp != 0 {
+					break
+				}
+				targetUpto++
+			}
+
+			if cmp == 0 {
+				cmp = e.term.Length() - len(target)
+			}
+			targetUpto = targetUptoMid
+		}
+
+		if cmp < 0 {
+			// Common case: target term is after current
+			// term, ie, app is seeking multiple terms
+			// in sorted order
+			e.currentFrame = lastFrame
+		} else if cmp > 0 {
+			// Uncommon case: target term
+			// is before current term; this means we can
+			// keep the currentFrame but we must rewind it
+			// (so we scan from the start)
+			e.targetBeforeCurrentLength = 0
+			e.currentFrame = lastFrame
+			e.currentFrame.rewind()
+		} else {
+			// Target is exactly the same as current term
+			assert(e.term.Length() == len(target))
+			if e.termExists {
+				return SEEK_STATUS_FOUND, nil
+			}
+		}
+	} else {
+		e.targetBeforeCurrentLength = -1
+		arc = e.fr.index.FirstArc(e.arcs[0])
+
+		// Empty string prefix must have an output (block) in the index!
+		assert(arc.IsFinal() && arc.Output != nil)
+
+		output = arc.Output
+
+		e.currentFrame = e.staticFrame
+
+		targetUpto = 0
+		if e.currentFrame, err = e.pushFrame(arc, fstOutputs.Add(output, arc.NextFinalOutput).([]byte), 0); err != nil {
+			return 0, err
+		}
+	}
+
+	for targetUpto < len(target) {
+		targetLabel := int(target[targetUpto])
+		nextArc, err := e.fr.index.FindTargetArc(targetLabel, arc, e.getArc(1+targetUpto), e.fstReader)
+		if err != nil {
+			return 0, err
+		}
+		if nextArc == nil {
+			// Index is exhausted
+			e.validIndexPrefix = e.currentFrame.prefix
+			return e.scanCeil(target)
+		}
+		// Follow this arc
+		e.term.Set(targetUpto, byte(targetLabel))
+		arc = nextArc
+		// Aggregate output as we go:
+		assert(arc.Output != nil)
+		if !fst.CompareFSTValue(arc.Output, noOutput) {
+			output = fstOutputs.Add(output, arc.Output)
+		}
+		targetUpto++
+
+		if arc.IsFinal() {
+			if e.currentFrame, err = e.pushFrame(arc,
+				fstOutputs.Add(output, arc.NextFinalOutput).([]byte),
+				targetUpto); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	e.validIndexPrefix = e.currentFrame.prefix
+	return e.scanCeil(target)
+}
+
+/*
+Scans the current frame, where the index left off, to the ceiling of
+target, moving on to the next term if the frame has none.
+*/
+func (e *SegmentTermsEnum) scanCeil(target []byte) (SeekStatus, error) {
+	e.currentFrame.scanToFloorFrame(target)
+
+	if err := e.currentFrame.loadBlock(); err != nil {
+		return 0, err
+	}
+
+	result, err := e.currentFrame.scanToTerm(target, false)
+	if err != nil {
+		return 0, err
+	}
+	if result != SEEK_STATUS_END {
+		return result, nil
+	}
+	e.term.Copy(target)
+	e.termExists = false
+	next, err := e.Next()
+	if err != nil {
+		return 0, err
+	}
+	if next != nil {
+		return SEEK_STATUS_NOT_FOUND, nil
+	}
+	return SEEK_STATUS_END, nil
 }
 
 func (e *SegmentTermsEnum) printSeekState() {
@@ -505,12 +679,94 @@ func (e *SegmentTermsEnum) printSeekState() {
 }
 
 func (e *SegmentTermsEnum) Next() (buf []byte, err error) {
-	panic("not implemented yet")
+	if e.in == nil {
+		// Fresh TermsEnum; seek to first term:
+		var arc *fst.Arc
+		if e.fr.index != nil {
+			arc = e.fr.index.FirstArc(e.arcs[0])
+			// Empty string prefix must have an output in the index!
+			assert(arc.IsFinal())
+		}
+		if e.currentFrame, err = e.pushFrame(arc, e.fr.rootCode, 0); err != nil {
+			return nil, err
+		}
+		if err = e.currentFrame.loadBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	e.targetBeforeCurrentLength = e.currentFrame.ord
+
+	assert(!e.eof)
+
+	if e.currentFrame == e.staticFrame {
+		// If seek was previously called and the term was cached, or
+		// seek(TermState) was called, usually caller is just going to
+		// pull a D/&PEnum or get docFreq, etc. But, if they then call
+		// next(), this method catches up all internal state so next()
+		// works properly:
+		ok, err := e.SeekExact(copyBytes(nil, e.term.Bytes()[:e.term.Length()]))
+		if err != nil {
+			return nil, err
+		}
+		assert(ok)
+	}
+
+	// Pop finished blocks
+	for e.currentFrame.nextEnt == e.currentFrame.entCount {
+		if !e.currentFrame.isLastInFloor {
+			if err = e.currentFrame.loadNextFloorBlock(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if e.currentFrame.ord == 0 {
+			e.eof = true
+			e.term.SetLength(0)
+			e.validIndexPrefix = 0
+			e.currentFrame.rewind()
+			e.termExists = false
+			return nil, nil
+		}
+		lastFP := e.currentFrame.fpOrig
+		e.currentFrame = e.stack[e.currentFrame.ord-1]
+
+		if e.currentFrame.nextEnt == -1 || e.currentFrame.lastSubFP != lastFP {
+			// We popped into a frame that's not loaded yet or not scan'd
+			// to the right entry
+			e.currentFrame.scanToFloorFrame(e.term.Bytes()[:e.term.Length()])
+			if err = e.currentFrame.loadBlock(); err != nil {
+				return nil, err
+			}
+			e.currentFrame.scanToSubBlock(lastFP)
+		}
+
+		// Note that the seek state (last seek) has been invalidated
+		// beyond this depth
+		if e.currentFrame.prefix < e.validIndexPrefix {
+			e.validIndexPrefix = e.currentFrame.prefix
+		}
+	}
+
+	for e.currentFrame.next() {
+		// Push to new block:
+		if e.currentFrame, err = e.pushFrameAt(nil, e.currentFrame.lastSubFP, e.term.Length()); err != nil {
+			return nil, err
+		}
+		// This is a "next" frame -- even if it's floor'd we must
+		// pretend it isn't so we don't try to scan to the right floor
+		// frame:
+		e.currentFrame.isFloor = false
+		if err = e.currentFrame.loadBlock(); err != nil {
+			return nil, err
+		}
+	}
+	return e.term.Bytes()[:e.term.Length()], nil
 }
 
 func (e *SegmentTermsEnum) Term() []byte {
 	assert(!e.eof)
-	return e.term.Bytes()
+	return e.term.Bytes()[:e.term.Length()]
 }
 
 func assert(ok bool) {
@@ -0,0 +1,141 @@
+package blocktree_test
+
+import (
+	"fmt"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"sort"
+	"testing"
+)
+
+/*
+Returns terms sharing prefixes with more entries than fit in one
+block, and enough distinct following labels for the writer to split
+them into floor blocks, at the root and below "x" and "yy".
+*/
+func floorBlockTerms() []string {
+	var terms []string
+	for c := 'a'; c <= 'z'; c++ {
+		for d := 0; d < 10; d++ {
+			terms = append(terms, fmt.Sprintf("x%c%v", c, d))
+			terms = append(terms, fmt.Sprintf("yy%c%v", c, d))
+		}
+		terms = append(terms, fmt.Sprintf("%c", c))
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func openFloorBlockTerms(t *testing.T, terms []string) Terms {
+	d := docu.NewDocument()
+	for _, term := range terms {
+		d.Add(docu.NewFieldFromString("body", term, docu.STRING_FIELD_TYPE_NOT_STORED))
+	}
+	r := testindex.NewReader(t, d.Fields())
+	t.Cleanup(func() { r.Close() })
+	leaves := r.Leaves()
+	if len(leaves) != 1 {
+		t.Fatalf("expected one segment, but %v", len(leaves))
+	}
+	return leaves[0].Reader().(index.AtomicReader).Fields().Terms("body")
+}
+
+func TestFloorBlocksEnumeration(t *testing.T) {
+	terms := floorBlockTerms()
+	e := openFloorBlockTerms(t, terms).Iterator(nil)
+	for i := 0; ; i++ {
+		term, err := e.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if term == nil {
+			if i != len(terms) {
+				t.Errorf("expected %v terms, but %v", len(terms), i)
+			}
+			break
+		}
+		if i >= len(terms) || string(term) != terms[i] {
+			t.Fatalf("term %v: unexpected %q", i, term)
+		}
+	}
+}
+
+func TestFloorBlocksSeekExact(t *testing.T) {
+	terms := floorBlockTerms()
+	tr := openFloorBlockTerms(t, terms)
+	e := tr.Iterator(nil)
+	// forwards, then backwards on the same enum, then each on a new one
+	targets := append([]string{}, terms...)
+	for i := len(terms) - 1; i >= 0; i-- {
+		targets = append(targets, terms[i])
+	}
+	for _, target := range targets {
+		ok, err := e.SeekExact([]byte(target))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("%q: not found", target)
+		} else if term := string(e.Term()); term != target {
+			t.Errorf("%q: positioned on %q", target, term)
+		}
+	}
+	for _, target := range terms {
+		if ok, err := tr.Iterator(nil).SeekExact([]byte(target)); err != nil || !ok {
+			t.Errorf("%q: not found on a new enum %v", target, err)
+		}
+	}
+	for _, target := range []string{"", "0", "x0", "xa", "xa10", "xm", "y0", "yy", "yym", "yyz9a", "zz"} {
+		if ok, err := tr.Iterator(nil).SeekExact([]byte(target)); err != nil || ok {
+			t.Errorf("%q: should not be found %v", target, err)
+		}
+	}
+}
+
+func TestFloorBlocksSeekCeil(t *testing.T) {
+	terms := floorBlockTerms()
+	tr := openFloorBlockTerms(t, terms)
+	targets := []string{"", "0", "a", "b0", "x", "xa", "xa0", "xa05", "xa9", "xb", "xm5", "xz9",
+		"xz9z", "y", "yy", "yya", "yyk3", "yyk30", "yyz", "yyz9", "yyz9a", "z", "zz"}
+	shared := tr.Iterator(nil)
+	for _, enum := range []string{"new", "shared"} {
+		for _, target := range targets {
+			e := shared
+			if enum == "new" {
+				e = tr.Iterator(nil)
+			}
+			status, err := e.SeekCeil([]byte(target))
+			if err != nil {
+				t.Fatal(err)
+			}
+			i := sort.SearchStrings(terms, target)
+			switch {
+			case i == len(terms):
+				if status != SEEK_STATUS_END {
+					t.Errorf("%v %q: expected END, but %v", enum, target, status)
+				}
+				continue
+			case terms[i] == target:
+				if status != SEEK_STATUS_FOUND {
+					t.Errorf("%v %q: expected FOUND, but %v", enum, target, status)
+				}
+			default:
+				if status != SEEK_STATUS_NOT_FOUND {
+					t.Errorf("%v %q: expected NOT_FOUND, but %v", enum, target, status)
+				}
+			}
+			if term := string(e.Term()); term != terms[i] {
+				t.Errorf("%v %q: expected ceiling %q, but %q", enum, target, terms[i], term)
+			}
+			next, err := e.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i+1 < len(terms) && string(next) != terms[i+1] {
+				t.Errorf("%v %q: expected next %q, but %q", enum, target, terms[i+1], next)
+			}
+		}
+	}
+}
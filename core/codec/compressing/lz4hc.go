@@ -0,0 +1,132 @@
+package compressing
+
+// codecs/compressing/LZ4.java#compressHC
+
+const (
+	HASH_LOG_HC        = 15
+	HASH_TABLE_SIZE_HC = 1 << HASH_LOG_HC
+	MAX_ATTEMPTS       = 256
+	MASK               = MAX_DISTANCE - 1
+)
+
+func hashHC(i int) int {
+	return hash(i, HASH_LOG_HC)
+}
+
+/*
+Hash table with chains, used by LZ4CompressHC() to find the longest
+match among the recent occurrences of every 4 bytes sequence.
+*/
+type LZ4HCHashTable struct {
+	nextToUpdate int
+	hashTable    []int    // last offset of every hash, -1 if none
+	chainTable   []uint16 // distance to the previous offset with the same hash
+}
+
+func (h *LZ4HCHashTable) reset() {
+	if h.hashTable == nil {
+		h.hashTable = make([]int, HASH_TABLE_SIZE_HC)
+		h.chainTable = make([]uint16, MAX_DISTANCE)
+	}
+	for i := range h.hashTable {
+		h.hashTable[i] = -1
+	}
+	for i := range h.chainTable {
+		h.chainTable[i] = 0
+	}
+	h.nextToUpdate = 0
+}
+
+func (h *LZ4HCHashTable) addHash(bytes []byte, off int) {
+	hashValue := hashHC(readInt(bytes, off))
+	delta := MAX_DISTANCE - 1
+	if prev := h.hashTable[hashValue]; prev >= 0 && off-prev < MAX_DISTANCE {
+		delta = off - prev
+	}
+	h.chainTable[off&MASK] = uint16(delta)
+	h.hashTable[hashValue] = off
+}
+
+func (h *LZ4HCHashTable) insert(bytes []byte, off int) {
+	for ; h.nextToUpdate < off; h.nextToUpdate++ {
+		h.addHash(bytes, h.nextToUpdate)
+	}
+}
+
+/*
+Returns the longest match for the bytes at off among at most
+MAX_ATTEMPTS previous occurrences. Matches never extend past limit.
+matchLen is 0 if no match was found.
+*/
+func (h *LZ4HCHashTable) findBestMatch(bytes []byte, off, limit int) (matchRef, matchLen int) {
+	h.insert(bytes, off)
+
+	v := readInt(bytes, off)
+	ref := h.hashTable[hashHC(v)]
+	for attempts := 0; ref >= 0 && off-ref < MAX_DISTANCE && attempts < MAX_ATTEMPTS; attempts++ {
+		if bytes[ref+matchLen] == bytes[off+matchLen] && readInt(bytes, ref) == v {
+			l := MIN_MATCH + commonBytesUpTo(bytes, ref+MIN_MATCH, off+MIN_MATCH, limit)
+			if l > matchLen {
+				matchRef, matchLen = ref, l
+			}
+		}
+		ref -= int(h.chainTable[ref&MASK])
+	}
+	return
+}
+
+// Returns the number of common bytes at o1 and o2 (o1 < o2), not
+// reading past limit.
+func commonBytesUpTo(bytes []byte, o1, o2, limit int) int {
+	count := 0
+	for o2+count < limit && bytes[o1+count] == bytes[o2+count] {
+		count++
+	}
+	return count
+}
+
+/*
+Compress bytes into out. It is slower than LZ4Compress() but should
+provide higher compression ratios, while the compressed data remains
+as fast to decompress. ht shouldn't be shared across threads but can
+safely be reused.
+*/
+func LZ4CompressHC(bytes []byte, out DataOutput, ht *LZ4HCHashTable) error {
+	end := len(bytes)
+	anchor, offset := 0, 1
+
+	if end > LAST_LITERALS+MIN_MATCH {
+		limit := end - LAST_LITERALS
+		matchLimit := limit - MIN_MATCH
+		ht.reset()
+
+		for offset < matchLimit {
+			ref, matchLen := ht.findBestMatch(bytes, offset, limit)
+			if matchLen < MIN_MATCH {
+				offset++
+				continue
+			}
+
+			// lazy matching: prefer the next position if its match is
+			// wider by more than the literal it costs
+			if offset+1 < matchLimit {
+				if ref2, matchLen2 := ht.findBestMatch(bytes, offset+1, limit); matchLen2 > matchLen+1 {
+					offset++
+					ref, matchLen = ref2, matchLen2
+				}
+			}
+
+			err := encodeSequence(bytes[anchor:offset], offset-ref, matchLen, out)
+			if err != nil {
+				return err
+			}
+			offset += matchLen
+			anchor = offset
+		}
+	}
+
+	// last literals
+	literalLen := end - anchor
+	assert(literalLen >= LAST_LITERALS || literalLen == end)
+	return encodeLastLiterals(bytes[anchor:], out)
+}
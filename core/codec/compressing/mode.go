@@ -1,17 +1,48 @@
 package compressing
 
 import (
+	"bytes"
+	"compress/flate"
 	"errors"
 	"fmt"
+	"io"
 )
 
+// codec/compressing/CompressionMode.java
+
+/*
+A compression mode. Tells how much effort should be spent on
+compression and decompression of stored fields.
+*/
 type CompressionMode interface {
 	NewCompressor() Compressor
 	NewDecompressor() Decompressor
 }
 
 const (
+	/*
+		A compression mode that trades compression ratio for speed.
+		Although the compression ratio might remain high, compression and
+		decompression are very fast. Use this mode with indices that have
+		a high update rate but should be able to load documents from disk
+		quickly.
+	*/
 	COMPRESSION_MODE_FAST = CompressionModeDefaults(1)
+	/*
+		A compression mode that trades speed for compression ratio.
+		Although compression and decompression might be slow, this
+		compression mode should provide a good compression ratio. This
+		mode might be interesting if/when your index size is much bigger
+		than your OS cache.
+	*/
+	COMPRESSION_MODE_HIGH_COMPRESSION = CompressionModeDefaults(2)
+	/*
+		This compression mode is similar to FAST but it spends more time
+		compressing in order to improve the compression ratio. This
+		compression mode is best used with indices that have a low update
+		rate but should be able to load documents from disk quickly.
+	*/
+	COMPRESSION_MODE_FAST_DECOMPRESSION = CompressionModeDefaults(3)
 )
 
 type CompressionModeDefaults int
@@ -23,6 +54,14 @@ func (m CompressionModeDefaults) NewCompressor() Compressor {
 		return Compressor(func(bytes []byte, out DataOutput) error {
 			return LZ4Compress(bytes, out, ht)
 		})
+	case 2:
+		// same level as BEST_COMPRESSION
+		return newDeflateCompressor(flate.BestCompression)
+	case 3:
+		var ht = new(LZ4HCHashTable)
+		return Compressor(func(bytes []byte, out DataOutput) error {
+			return LZ4CompressHC(bytes, out, ht)
+		})
 	default:
 		panic("not implemented yet")
 	}
@@ -30,13 +69,28 @@ func (m CompressionModeDefaults) NewCompressor() Compressor {
 
 func (m CompressionModeDefaults) NewDecompressor() Decompressor {
 	switch int(m) {
-	case 1:
+	case 1, 3:
 		return LZ4Decompressor
+	case 2:
+		return newDeflateDecompressor()
 	default:
 		panic("not implemented yet")
 	}
 }
 
+func (m CompressionModeDefaults) String() string {
+	switch int(m) {
+	case 1:
+		return "FAST"
+	case 2:
+		return "HIGH_COMPRESSION"
+	case 3:
+		return "FAST_DECOMPRESSION"
+	default:
+		return fmt.Sprintf("CompressionMode(%v)", int(m))
+	}
+}
+
 // codec/compressing/Compressor.java
 
 /*
@@ -75,3 +129,82 @@ func LZ4Decompressor(in DataInput, originalLength, offset, length int, bytes []b
 	}
 	return res[offset : offset+length], nil
 }
+
+func newDeflateCompressor(level int) Compressor {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	assert(err == nil)
+	return Compressor(func(bytes []byte, out DataOutput) error {
+		buf.Reset()
+		w.Reset(&buf)
+		if _, err := w.Write(bytes); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		if err := out.WriteVInt(int32(buf.Len())); err != nil {
+			return err
+		}
+		return out.WriteBytes(buf.Bytes())
+	})
+}
+
+func newDeflateDecompressor() Decompressor {
+	var compressed []byte
+	var r io.ReadCloser
+	return Decompressor(func(in DataInput, originalLength, offset, length int, buf []byte) (res []byte, err error) {
+		assert(offset+length <= originalLength)
+		if length == 0 {
+			return buf[:0], nil
+		}
+		compressedLength, err := readVInt(in)
+		if err != nil {
+			return nil, err
+		}
+		if cap(compressed) < compressedLength {
+			compressed = make([]byte, compressedLength)
+		}
+		compressed = compressed[:compressedLength]
+		if err = in.ReadBytes(compressed); err != nil {
+			return nil, err
+		}
+
+		if r == nil {
+			r = flate.NewReader(bytes.NewReader(compressed))
+		} else if err = r.(flate.Resetter).Reset(bytes.NewReader(compressed), nil); err != nil {
+			return nil, err
+		}
+		res = buf
+		if cap(res) < originalLength {
+			res = make([]byte, originalLength)
+		}
+		res = res[:originalLength]
+		if _, err = io.ReadFull(r, res); err != nil {
+			return nil, errors.New(fmt.Sprintf("Corrupted: %v (resource=%v)", err, in))
+		}
+		// the stream must end exactly at originalLength
+		var extra [1]byte
+		if n, _ := r.Read(extra[:]); n > 0 {
+			return nil, errors.New(fmt.Sprintf("Corrupted: lengths mismatch: > %v (resource=%v)", originalLength, in))
+		}
+		return res[offset : offset+length], nil
+	})
+}
+
+func readVInt(in DataInput) (int, error) {
+	var n, shift uint
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n |= uint(b&0x7F) << shift
+		if b&0x80 == 0 {
+			return int(n), nil
+		}
+		if shift += 7; shift > 28 {
+			return 0, errors.New("Invalid vInt detected (too many bits)")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package compressing
+
+import (
+	"bytes"
+	"github.com/jtejido/golucene/core/store"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func compress(t *testing.T, c Compressor, data []byte) []byte {
+	buf := make([]byte, len(data)*2+64)
+	out := store.NewByteArrayDataOutput(buf)
+	if err := c(data, out); err != nil {
+		t.Fatal(err)
+	}
+	return buf[:out.Position()]
+}
+
+func TestCompressionModes(t *testing.T) {
+	random := make([]byte, 70000)
+	rand.New(rand.NewSource(42)).Read(random)
+	// repeats farther apart than MAX_DISTANCE
+	far := append(append([]byte{}, random...), random[:1000]...)
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog; ", 2000))
+	inputs := [][]byte{
+		[]byte("a"),
+		[]byte("abcdefghijkl"),
+		random,
+		far,
+		text,
+	}
+
+	sizes := make(map[CompressionModeDefaults]int)
+	for _, mode := range []CompressionModeDefaults{
+		COMPRESSION_MODE_FAST,
+		COMPRESSION_MODE_HIGH_COMPRESSION,
+		COMPRESSION_MODE_FAST_DECOMPRESSION,
+	} {
+		c, d := mode.NewCompressor(), mode.NewDecompressor()
+		for _, data := range inputs {
+			compressed := compress(t, c, data)
+			if bytes.Equal(data, text) {
+				sizes[mode] = len(compressed)
+			}
+
+			got, err := d(store.NewByteArrayDataInput(compressed), len(data), 0, len(data), nil)
+			if err != nil {
+				t.Fatalf("%v: %v", mode, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("%v: round trip failed for %v bytes", mode, len(data))
+			}
+
+			// partial decompression
+			offset, length := len(data)/3, len(data)/2
+			got, err = d(store.NewByteArrayDataInput(compressed), len(data), offset, length, nil)
+			if err != nil {
+				t.Fatalf("%v: %v", mode, err)
+			}
+			if !bytes.Equal(got, data[offset:offset+length]) {
+				t.Fatalf("%v: partial decompression failed for %v bytes", mode, len(data))
+			}
+		}
+	}
+
+	if sizes[COMPRESSION_MODE_HIGH_COMPRESSION] >= sizes[COMPRESSION_MODE_FAST] {
+		t.Errorf("expected HIGH_COMPRESSION (%v bytes) to be smaller than FAST (%v bytes)",
+			sizes[COMPRESSION_MODE_HIGH_COMPRESSION], sizes[COMPRESSION_MODE_FAST])
+	}
+	if sizes[COMPRESSION_MODE_FAST_DECOMPRESSION] > sizes[COMPRESSION_MODE_FAST] {
+		t.Errorf("expected FAST_DECOMPRESSION (%v bytes) to be no larger than FAST (%v bytes)",
+			sizes[COMPRESSION_MODE_FAST_DECOMPRESSION], sizes[COMPRESSION_MODE_FAST])
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/jtejido/golucene/core/store"
 	"github.com/jtejido/golucene/core/util"
 	"github.com/jtejido/golucene/core/util/packed"
+	"io"
+	"math"
 )
 
 // codec/compressing/CompressingStoredFieldsReader.java
@@ -182,7 +184,15 @@ func (r *CompressingStoredFieldsReader) readField(in util.DataInput,
 	visitor StoredFieldVisitor, info *model.FieldInfo, bits int) (err error) {
 	switch bits & TYPE_MASK {
 	case BYTE_ARR:
-		panic("not implemented yet")
+		var length int
+		if length, err = int32AsInt(in.ReadVInt()); err != nil {
+			return err
+		}
+		data := make([]byte, length)
+		if err = in.ReadBytes(data); err != nil {
+			return err
+		}
+		return visitor.BinaryField(info, data)
 	case STRING:
 		var length int
 		if length, err = int32AsInt(in.ReadVInt()); err != nil {
@@ -192,18 +202,150 @@ func (r *CompressingStoredFieldsReader) readField(in util.DataInput,
 		if err = in.ReadBytes(data); err != nil {
 			return err
 		}
-		visitor.StringField(info, string(data))
+		return visitor.StringField(info, string(data))
 	case NUMERIC_INT:
-		panic("not implemented yet")
+		var n int32
+		if n, err = in.ReadInt(); err != nil {
+			return err
+		}
+		return visitor.IntField(info, int(n))
 	case NUMERIC_FLOAT:
-		panic("not implemented yet")
+		var n int32
+		if n, err = in.ReadInt(); err != nil {
+			return err
+		}
+		return visitor.FloatField(info, math.Float32frombits(uint32(n)))
 	case NUMERIC_LONG:
-		panic("not implemented yet")
+		var n int64
+		if n, err = in.ReadLong(); err != nil {
+			return err
+		}
+		return visitor.LongField(info, n)
 	case NUMERIC_DOUBLE:
-		panic("not implemented yet")
+		var n int64
+		if n, err = in.ReadLong(); err != nil {
+			return err
+		}
+		return visitor.DoubleField(info, math.Float64frombits(uint64(n)))
+	default:
+		panic(fmt.Sprintf("Unknown type flag: %x", bits))
+	}
+}
+
+func skipField(in util.DataInput, bits int) (err error) {
+	switch bits & TYPE_MASK {
+	case BYTE_ARR, STRING:
+		var length int32
+		if length, err = in.ReadVInt(); err != nil {
+			return err
+		}
+		return skipBytes(in, int64(length))
+	case NUMERIC_INT, NUMERIC_FLOAT:
+		_, err = in.ReadInt()
+	case NUMERIC_LONG, NUMERIC_DOUBLE:
+		_, err = in.ReadLong()
 	default:
 		panic(fmt.Sprintf("Unknown type flag: %x", bits))
 	}
+	return err
+}
+
+func skipBytes(in util.DataInput, count int64) error {
+	switch v := in.(type) {
+	case *store.ByteArrayDataInput:
+		v.SkipBytes(count)
+		return nil
+	case *bigChunkInput:
+		return v.skipBytes(int(count))
+	default:
+		return in.ReadBytes(make([]byte, count))
+	}
+}
+
+/*
+DataInput over a document of a chunk which was compressed in several
+slices of chunkSize bytes. Slices are only decompressed when they are
+read, so the remaining ones are never decompressed if the visitor
+stops early.
+*/
+type bigChunkInput struct {
+	*util.DataInputImpl
+	r            *CompressingStoredFieldsReader
+	bytes        []byte // decompressed bytes not consumed yet
+	length       int    // length of the document
+	totalLength  int    // length of the chunk
+	decompressed int    // bytes of the document decompressed so far
+	slice        int    // next slice to decompress
+}
+
+func newBigChunkInput(r *CompressingStoredFieldsReader,
+	offset, length, totalLength int) (*bigChunkInput, error) {
+
+	assert(r.chunkSize > 0)
+	assert(offset < r.chunkSize)
+	bytes, err := r.decompressor(r.fieldsStream, r.chunkSize, offset,
+		min(length, r.chunkSize-offset), nil)
+	if err != nil {
+		return nil, err
+	}
+	ans := &bigChunkInput{
+		r:            r,
+		bytes:        bytes,
+		length:       length,
+		totalLength:  totalLength,
+		decompressed: len(bytes),
+		slice:        1,
+	}
+	ans.DataInputImpl = util.NewDataInput(ans)
+	return ans, nil
+}
+
+func (in *bigChunkInput) fillBuffer() (err error) {
+	assert(in.decompressed <= in.length)
+	if in.decompressed == in.length {
+		return io.EOF
+	}
+	sliceLength := min(in.r.chunkSize, in.totalLength-in.slice*in.r.chunkSize)
+	toDecompress := min(in.length-in.decompressed, sliceLength)
+	if in.bytes, err = in.r.decompressor(in.r.fieldsStream, sliceLength,
+		0, toDecompress, nil); err != nil {
+		return err
+	}
+	in.decompressed += toDecompress
+	in.slice++
+	return nil
+}
+
+func (in *bigChunkInput) ReadByte() (b byte, err error) {
+	if len(in.bytes) == 0 {
+		if err = in.fillBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	b, in.bytes = in.bytes[0], in.bytes[1:]
+	return b, nil
+}
+
+func (in *bigChunkInput) ReadBytes(buf []byte) error {
+	for len(buf) > len(in.bytes) {
+		n := copy(buf, in.bytes)
+		buf = buf[n:]
+		if err := in.fillBuffer(); err != nil {
+			return err
+		}
+	}
+	in.bytes = in.bytes[copy(buf, in.bytes):]
+	return nil
+}
+
+func (in *bigChunkInput) skipBytes(count int) error {
+	for count > len(in.bytes) {
+		count -= len(in.bytes)
+		if err := in.fillBuffer(); err != nil {
+			return err
+		}
+	}
+	in.bytes = in.bytes[count:]
 	return nil
 }
 
@@ -253,7 +395,18 @@ func (r *CompressingStoredFieldsReader) VisitDocument(docID int, visitor StoredF
 			return errors.New(fmt.Sprintf("bitsPerStoredFields=%v (resource=%v)",
 				bitsPerStoredFields, r.fieldsStream))
 		} else {
-			panic("not implemented yet")
+			it := packed.ReaderIteratorNoHeader(
+				r.fieldsStream, packed.PackedFormat(packed.PACKED), r.packedIntsVersion,
+				chunkDocs, bitsPerStoredFields, 1)
+			var n int64
+			for i := 0; i < chunkDocs; i++ {
+				if n, err = it.Next(); err != nil {
+					return err
+				}
+				if i == docID-docBase {
+					numStoredFields = int(n)
+				}
+			}
 		}
 
 		bitsPerLength, err := int32AsInt(r.fieldsStream.ReadVInt())
@@ -309,7 +462,9 @@ func (r *CompressingStoredFieldsReader) VisitDocument(docID int, visitor StoredF
 
 	var documentInput util.DataInput
 	if r.version >= VERSION_BIG_CHUNKS && totalLength >= 2*r.chunkSize {
-		panic("not implemented yet")
+		if documentInput, err = newBigChunkInput(r, offset, length, totalLength); err != nil {
+			return err
+		}
 	} else {
 		var bytes []byte
 		if totalLength <= BUFFER_REUSE_THRESHOLD {
@@ -342,12 +497,15 @@ func (r *CompressingStoredFieldsReader) VisitDocument(docID int, visitor StoredF
 		}
 		switch status {
 		case STORED_FIELD_VISITOR_STATUS_YES:
-			r.readField(documentInput, visitor, fieldInfo, bits)
+			err = r.readField(documentInput, visitor, fieldInfo, bits)
 		case STORED_FIELD_VISITOR_STATUS_NO:
-			panic("not implemented yet")
+			err = skipField(documentInput, bits)
 		case STORED_FIELD_VISITOR_STATUS_STOP:
 			return nil
 		}
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package lucene41
 
 import (
 	"fmt"
+	"github.com/jtejido/golucene/core/store"
 	"github.com/jtejido/golucene/core/util"
 	"github.com/jtejido/golucene/core/util/packed"
 	"math"
@@ -210,6 +211,20 @@ func (u *ForUtil) readBlock(in IndexInput, encoded []byte, decoded []int32) (err
 	return nil
 }
 
+/* Skip the next block of data. */
+func (u *ForUtil) skipBlock(in store.IndexInput) error {
+	numBits, err := in.ReadByte()
+	if err != nil {
+		return err
+	}
+	if int(numBits) == ALL_VALUES_EQUAL {
+		_, err = in.ReadVInt()
+		return err
+	}
+	assert2(numBits > 0 && numBits <= 32, "%v", numBits)
+	return in.Seek(in.FilePointer() + int64(u.encodedSizes[numBits]))
+}
+
 func encodedSize(format packed.PackedFormat, packedIntsVersion int32, bitsPerValue uint32) int32 {
 	byteCount := format.ByteCount(packedIntsVersion, LUCENE41_BLOCK_SIZE, bitsPerValue)
 	// assert byteCount >= 0 && byteCount <= math.MaxInt32()
@@ -795,10 +795,11 @@ func (de *blockDocsAndPositionsEnum) skipPositions() (err error) {
 			// if (DEBUG) {
 			//   System.out.println("        skip whole block @ fp=" + posIn.getFilePointer());
 			// }
-			// assert(de.posIn.FilePointer() != de.lastPosBlockFP)
-			// de.forUtil.skipBlock(de.posIn)
-			// toSkip -= LUCENE41_BLOCK_SIZE
-			panic("niy")
+			assert(de.posIn.FilePointer() != de.lastPosBlockFP)
+			if err = de.forUtil.skipBlock(de.posIn); err != nil {
+				return
+			}
+			toSkip -= LUCENE41_BLOCK_SIZE
 		}
 		if err = de.refillPositions(); err != nil {
 			return
@@ -1108,7 +1109,7 @@ func (de *everythingEnum) reset(liveDocs util.Bits, termState *intBlockTermState
 	}
 
 	de.needsOffsets = (flags & DOCS_POSITIONS_ENUM_FLAG_OFF_SETS) != 0
-	de.needsPayloads = (flags & DOCS_POSITIONS_ENUM_FLAG_OFF_SETS) != 0
+	de.needsPayloads = (flags & DOCS_POSITIONS_ENUM_FLAG_PAYLOADS) != 0
 
 	de.doc = -1
 	de.accum = 0
@@ -1191,12 +1192,12 @@ func (de *everythingEnum) refillPositions() (err error) {
 				// }
 				de.payloadLengthBuffer[i] = payloadLength
 				de.posDeltaBuffer[i] = code >> 1
-				if de.payloadLength != 0 {
+				if payloadLength != 0 {
 					if de.payloadByteUpto+int(payloadLength) > len(de.payloadBytes) {
 						de.payloadBytes = util.GrowByteSlice(de.payloadBytes, de.payloadByteUpto+int(payloadLength))
 					}
 					//System.out.println("          read payload @ pos.fp=" + posIn.getFilePointer());
-					if err = de.posIn.ReadBytes(de.payloadBytes[de.payloadByteUpto:payloadLength]); err != nil {
+					if err = de.posIn.ReadBytes(de.payloadBytes[de.payloadByteUpto : de.payloadByteUpto+int(payloadLength)]); err != nil {
 						return err
 					}
 					de.payloadByteUpto += int(payloadLength)
@@ -1257,10 +1258,17 @@ func (de *everythingEnum) refillPositions() (err error) {
 				}
 			} else {
 				// this works, because when writing a vint block we always force the first length to be written
-				// de.forUtil.skipBlock(payIn); // skip over lengths
-				// int numBytes = payIn.readVInt(); // read length of payloadBytes
-				// payIn.seek(payIn.getFilePointer() + numBytes); // skip over payloadBytes
-				panic("niy")
+				if err = de.forUtil.skipBlock(de.payIn); err != nil { // skip over lengths
+					return
+				}
+				numBytes, err := de.payIn.ReadVInt() // read length of payloadBytes
+				if err != nil {
+					return err
+				}
+				// skip over payloadBytes
+				if err = de.payIn.Seek(de.payIn.FilePointer() + int64(numBytes)); err != nil {
+					return err
+				}
 			}
 			de.payloadByteUpto = 0
 		}
@@ -1278,9 +1286,12 @@ func (de *everythingEnum) refillPositions() (err error) {
 				}
 			} else {
 				// this works, because when writing a vint block we always force the first length to be written
-				// de.forUtil.skipBlock(payIn); // skip over starts
-				// de.forUtil.skipBlock(payIn); // skip over lengths
-				panic("niy")
+				if err = de.forUtil.skipBlock(de.payIn); err != nil { // skip over starts
+					return
+				}
+				if err = de.forUtil.skipBlock(de.payIn); err != nil { // skip over lengths
+					return
+				}
 			}
 		}
 	}
@@ -1408,9 +1419,37 @@ func (de *everythingEnum) skipPositions() (err error) {
 	} else {
 		toSkip -= leftInBlock
 		for toSkip >= LUCENE41_BLOCK_SIZE {
-			panic("niy")
+			assert(de.posIn.FilePointer() != de.lastPosBlockFP)
+			if err = de.forUtil.skipBlock(de.posIn); err != nil {
+				return
+			}
+			if de.indexHasPayloads {
+				// Skip payloadLength block:
+				if err = de.forUtil.skipBlock(de.payIn); err != nil {
+					return
+				}
+				// Skip payloadBytes block:
+				numBytes, err := de.payIn.ReadVInt()
+				if err != nil {
+					return err
+				}
+				if err = de.payIn.Seek(de.payIn.FilePointer() + int64(numBytes)); err != nil {
+					return err
+				}
+			}
+			if de.indexHasOffsets {
+				if err = de.forUtil.skipBlock(de.payIn); err != nil {
+					return
+				}
+				if err = de.forUtil.skipBlock(de.payIn); err != nil {
+					return
+				}
+			}
+			toSkip -= LUCENE41_BLOCK_SIZE
+		}
+		if err = de.refillPositions(); err != nil {
+			return
 		}
-		de.refillPositions()
 		de.payloadByteUpto = 0
 		de.posBufferUpto = 0
 		for de.posBufferUpto < toSkip {
@@ -1457,12 +1496,16 @@ func (de *everythingEnum) NextPosition() (pos int, err error) {
 	}
 
 	if de.posPendingCount > de.freq {
-		de.skipPositions()
+		if err = de.skipPositions(); err != nil {
+			return
+		}
 		de.posPendingCount = de.freq
 	}
 
 	if de.posBufferUpto == LUCENE41_BLOCK_SIZE {
-		de.refillPositions()
+		if err = de.refillPositions(); err != nil {
+			return
+		}
 		de.posBufferUpto = 0
 	}
 	de.position += int(de.posDeltaBuffer[de.posBufferUpto])
@@ -333,8 +333,7 @@ func (w *Lucene41PostingsWriter) AddPosition(position int,
 			if w.payloadByteUpto+len(payload) > len(w.payloadBytes) {
 				w.payloadBytes = util.GrowByteSlice(w.payloadBytes, w.payloadByteUpto+len(payload))
 			}
-			copy(w.payloadBytes, payload)
-			//System.arraycopy(payload.bytes, payload.offset, payloadBytes, payloadByteUpto, len(payload));
+			copy(w.payloadBytes[w.payloadByteUpto:], payload)
 			w.payloadByteUpto += len(payload)
 		}
 	}
@@ -486,7 +485,7 @@ func (w *Lucene41PostingsWriter) FinishTerm(_state *BlockTermState) error {
 						// if (DEBUG) {
 						//   System.out.println("          write payload @ pos.fp=" + posOut.getFilePointer());
 						// }
-						if err = w.posOut.WriteBytes(w.payloadBytes[payloadBytesReadUpto:payloadLength]); err != nil {
+						if err = w.posOut.WriteBytes(w.payloadBytes[payloadBytesReadUpto : payloadBytesReadUpto+payloadLength]); err != nil {
 							return err
 						}
 						payloadBytesReadUpto += payloadLength
@@ -1,19 +1,20 @@
 package lucene410
 
 import (
+	"fmt"
 	"github.com/jtejido/golucene/core/codec/lucene40"
-	"github.com/jtejido/golucene/core/codec/lucene41"
 	"github.com/jtejido/golucene/core/codec/lucene42"
 	"github.com/jtejido/golucene/core/codec/lucene46"
 	"github.com/jtejido/golucene/core/codec/lucene49"
 	"github.com/jtejido/golucene/core/codec/perfield"
 	. "github.com/jtejido/golucene/core/codec/spi"
+	"github.com/jtejido/golucene/core/util/hnsw"
 )
 
 // codec/lucene410/Lucene410Codec.java
 
 func init() {
-	RegisterCodec(newLucene410Codec())
+	RegisterCodec(NewLucene410Codec(BEST_SPEED))
 }
 
 /*
@@ -22,14 +23,23 @@ postings and docvalues format.
 
 If you want to reuse functionality of this codec in another codec,
 extend FilterCodec (or embeds the Codec in Go style).
+
+The StoredFieldsMode only applies to stored fields written with this
+instance, e.g. by setting it on IndexWriterConfig.SetCodec(); segments
+are always read back with the mode they were written with.
+
+Dense vectors are written by Lucene410HnswVectorsFormat, this codec
+implementing KnnVectorsCodec.
 */
 type Lucene410Codec struct {
 	*CodecImpl
+	knnVectorsFormat KnnVectorsFormat
 }
 
-func newLucene410Codec() *Lucene410Codec {
+/* Instantiates a new codec, specifying the stored fields compression mode to use. */
+func NewLucene410Codec(mode StoredFieldsMode) *Lucene410Codec {
 	return &Lucene410Codec{NewCodec("Lucene410",
-		lucene41.NewLucene41StoredFieldsFormat(),
+		NewLucene410StoredFieldsFormat(mode),
 		lucene42.NewLucene42TermVectorsFormat(),
 		lucene46.NewLucene46FieldInfosFormat(),
 		lucene46.NewLucene46SegmentInfoFormat(),
@@ -41,5 +51,15 @@ func newLucene410Codec() *Lucene410Codec {
 			panic("not implemented yet")
 		}),
 		new(lucene49.Lucene49NormsFormat),
-	)}
+	), NewLucene410HnswVectorsFormat(hnsw.DEFAULT_MAX_CONN, hnsw.DEFAULT_BEAM_WIDTH)}
+}
+
+func (codec *Lucene410Codec) KnnVectorsFormat() KnnVectorsFormat {
+	return codec.knnVectorsFormat
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
 }
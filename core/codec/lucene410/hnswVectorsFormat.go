@@ -0,0 +1,403 @@
+package lucene410
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/codec"
+	. "github.com/jtejido/golucene/core/codec/spi"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/core/util/hnsw"
+	"math"
+	"sort"
+)
+
+// codecs/lucene410/Lucene410HnswVectorsFormat.java
+
+const (
+	HNSW_VECTORS_META_CODEC      = "Lucene410HnswVectorsMeta"
+	HNSW_VECTORS_DATA_CODEC      = "Lucene410HnswVectorsData"
+	HNSW_VECTORS_META_EXTENSION  = "vem"
+	HNSW_VECTORS_DATA_EXTENSION  = "vec"
+	HNSW_VECTORS_VERSION_START   = 0
+	HNSW_VECTORS_VERSION_CURRENT = HNSW_VECTORS_VERSION_START
+)
+
+/*
+Lucene 4.10 dense vectors format, searched through an HNSW graph per
+field and segment.
+
+The .vem file holds, per field with vectors: its number, similarity
+function, dimension, number of vectors, the offset of its data in the
+.vec file and its graph's entry node. It ends with -1.
+
+The .vec file holds, per field: the docIDs having a vector, delta
+encoded; the vectors, as the bits of their float32 components; then
+per vector, its number of levels, and per level, its neighbors as
+delta encoded ordinals.
+
+Vectors and graphs are read in memory when the segment is opened.
+Graphs of merged segments are rebuilt from their vectors.
+*/
+type Lucene410HnswVectorsFormat struct {
+	maxConn, beamWidth int
+}
+
+/*
+Returns a format building graphs with up to maxConn neighbors per node
+on upper levels, and 2*maxConn on level 0, exploring beamWidth
+candidates when adding a node.
+*/
+func NewLucene410HnswVectorsFormat(maxConn, beamWidth int) *Lucene410HnswVectorsFormat {
+	assert2(maxConn > 1, "maxConn must be > 1, got %v", maxConn)
+	assert2(beamWidth > 0, "beamWidth must be > 0, got %v", beamWidth)
+	return &Lucene410HnswVectorsFormat{maxConn, beamWidth}
+}
+
+func (f *Lucene410HnswVectorsFormat) VectorsWriter(state *SegmentWriteState) (KnnVectorsWriter, error) {
+	return newHnswVectorsWriter(state, f.maxConn, f.beamWidth)
+}
+
+func (f *Lucene410HnswVectorsFormat) VectorsReader(state SegmentReadState) (KnnVectorsReader, error) {
+	return newHnswVectorsReader(state)
+}
+
+func (f *Lucene410HnswVectorsFormat) String() string {
+	return fmt.Sprintf("Lucene410HnswVectorsFormat(maxConn=%v, beamWidth=%v)", f.maxConn, f.beamWidth)
+}
+
+// codecs/lucene410/Lucene410HnswVectorsWriter.java
+
+type hnswVectorsWriter struct {
+	meta, data         store.IndexOutput
+	maxConn, beamWidth int
+}
+
+func newHnswVectorsWriter(state *SegmentWriteState, maxConn, beamWidth int) (w *hnswVectorsWriter, err error) {
+	w = &hnswVectorsWriter{maxConn: maxConn, beamWidth: beamWidth}
+	var success = false
+	defer func() {
+		if !success {
+			util.CloseWhileSuppressingError(w.meta, w.data)
+		}
+	}()
+
+	metaName := util.SegmentFileName(state.SegmentInfo.Name, state.SegmentSuffix, HNSW_VECTORS_META_EXTENSION)
+	if w.meta, err = state.Directory.CreateOutput(metaName, state.Context); err != nil {
+		return nil, err
+	}
+	if err = codec.WriteHeader(w.meta, HNSW_VECTORS_META_CODEC, HNSW_VECTORS_VERSION_CURRENT); err != nil {
+		return nil, err
+	}
+	dataName := util.SegmentFileName(state.SegmentInfo.Name, state.SegmentSuffix, HNSW_VECTORS_DATA_EXTENSION)
+	if w.data, err = state.Directory.CreateOutput(dataName, state.Context); err != nil {
+		return nil, err
+	}
+	if err = codec.WriteHeader(w.data, HNSW_VECTORS_DATA_CODEC, HNSW_VECTORS_VERSION_CURRENT); err != nil {
+		return nil, err
+	}
+	success = true
+	return w, nil
+}
+
+func (w *hnswVectorsWriter) AddField(fi *FieldInfo, values VectorValues) error {
+	assert2(fi.VectorDimension() == values.Dimension(),
+		"field '%v' has vectors of dimension %v, got %v", fi.Name, fi.VectorDimension(), values.Dimension())
+	graph := hnsw.NewBuilder(values, fi.VectorSimilarity(), w.maxConn, w.beamWidth, hnsw.DEFAULT_SEED).Build()
+
+	if err := store.Stream(w.meta).WriteVInt(fi.Number).
+		WriteVInt(int32(fi.VectorSimilarity())).
+		WriteVInt(int32(values.Dimension())).
+		WriteVInt(int32(values.Size())).
+		WriteLong(w.data.FilePointer()).
+		WriteVInt(int32(graph.EntryNode())).
+		Close(); err != nil {
+		return err
+	}
+
+	lastDoc := 0
+	for ord := 0; ord < values.Size(); ord++ {
+		doc := values.Doc(ord)
+		assert2(ord == 0 || doc > lastDoc, "docs out of order: %v after %v", doc, lastDoc)
+		if err := w.data.WriteVInt(int32(doc - lastDoc)); err != nil {
+			return err
+		}
+		lastDoc = doc
+	}
+	for ord := 0; ord < values.Size(); ord++ {
+		for _, v := range values.Vector(ord) {
+			if err := w.data.WriteInt(int32(math.Float32bits(v))); err != nil {
+				return err
+			}
+		}
+	}
+	for node := 0; node < graph.Size(); node++ {
+		if err := w.data.WriteVInt(int32(graph.Levels(node))); err != nil {
+			return err
+		}
+		for level := 0; level < graph.Levels(node); level++ {
+			neighbors := append([]int(nil), graph.Neighbors(node, level)...)
+			sort.Ints(neighbors)
+			if err := w.data.WriteVInt(int32(len(neighbors))); err != nil {
+				return err
+			}
+			last := 0
+			for _, n := range neighbors {
+				if err := w.data.WriteVInt(int32(n - last)); err != nil {
+					return err
+				}
+				last = n
+			}
+		}
+	}
+	return nil
+}
+
+func (w *hnswVectorsWriter) Close() (err error) {
+	var success = false
+	defer func() {
+		if success {
+			err = util.Close(w.meta, w.data)
+		} else {
+			util.CloseWhileSuppressingError(w.meta, w.data)
+		}
+	}()
+
+	if err = w.meta.WriteVInt(-1); err != nil { // write EOF marker
+		return
+	}
+	if err = codec.WriteFooter(w.meta); err != nil {
+		return
+	}
+	if err = codec.WriteFooter(w.data); err != nil {
+		return
+	}
+	success = true
+	return nil
+}
+
+// codecs/lucene410/Lucene410HnswVectorsReader.java
+
+type hnswVectorsReader struct {
+	fields map[string]*hnswVectorsField
+}
+
+/* The vectors of a field, read in memory, and their graph. */
+type hnswVectorsField struct {
+	info      *FieldInfo
+	sim       VectorSimilarityFunction
+	dimension int
+	offset    int64
+	entryNode int
+	docs      []int
+	vectors   [][]float32
+	graph     *hnsw.Graph
+}
+
+func newHnswVectorsReader(state SegmentReadState) (r *hnswVectorsReader, err error) {
+	r = &hnswVectorsReader{fields: make(map[string]*hnswVectorsField)}
+
+	var entries []*hnswVectorsField
+	metaName := util.SegmentFileName(state.SegmentInfo.Name, state.SegmentSuffix, HNSW_VECTORS_META_EXTENSION)
+	if err = readChecksummed(state, metaName, HNSW_VECTORS_META_CODEC, func(in store.ChecksumIndexInput) (err error) {
+		entries, err = readHnswVectorsEntries(in, state.FieldInfos)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	dataName := util.SegmentFileName(state.SegmentInfo.Name, state.SegmentSuffix, HNSW_VECTORS_DATA_EXTENSION)
+	if err = readChecksummed(state, dataName, HNSW_VECTORS_DATA_CODEC, func(in store.ChecksumIndexInput) error {
+		for _, entry := range entries {
+			if in.FilePointer() != entry.offset {
+				return errors.New(fmt.Sprintf(
+					"invalid vector data offset for field '%v': %v, expected %v (resource=%v)",
+					entry.info.Name, entry.offset, in.FilePointer(), in))
+			}
+			if err := entry.readData(in); err != nil {
+				return err
+			}
+			r.fields[entry.info.Name] = entry
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+/* Opens a file, checks its header, reads it and checks its footer. */
+func readChecksummed(state SegmentReadState, name, codecName string,
+	read func(store.ChecksumIndexInput) error) (err error) {
+
+	var in store.ChecksumIndexInput
+	if in, err = state.Dir.OpenChecksumInput(name, state.Context); err != nil {
+		return err
+	}
+	var success = false
+	defer func() {
+		if success {
+			err = util.Close(in)
+		} else {
+			util.CloseWhileSuppressingError(in)
+		}
+	}()
+
+	if _, err = codec.CheckHeader(in, codecName, HNSW_VECTORS_VERSION_START, HNSW_VECTORS_VERSION_CURRENT); err != nil {
+		return err
+	}
+	if err = read(in); err != nil {
+		return err
+	}
+	if _, err = codec.CheckFooter(in); err != nil {
+		return err
+	}
+	success = true
+	return nil
+}
+
+func readHnswVectorsEntries(meta store.IndexInput, infos FieldInfos) (entries []*hnswVectorsField, err error) {
+	for {
+		var fieldNumber, sim, dimension, size, entryNode int32
+		if fieldNumber, err = meta.ReadVInt(); err != nil || fieldNumber == -1 {
+			return
+		}
+		info := infos.FieldInfoByNumber(int(fieldNumber))
+		if info == nil {
+			return nil, errors.New(fmt.Sprintf("Invalid field number: %v (resource=%v)", fieldNumber, meta))
+		}
+		entry := &hnswVectorsField{info: info}
+		if sim, err = meta.ReadVInt(); err != nil {
+			return
+		}
+		if dimension, err = meta.ReadVInt(); err != nil {
+			return
+		}
+		if size, err = meta.ReadVInt(); err != nil {
+			return
+		}
+		if entry.offset, err = meta.ReadLong(); err != nil {
+			return
+		}
+		if entryNode, err = meta.ReadVInt(); err != nil {
+			return
+		}
+		entry.sim = VectorSimilarityFunction(sim)
+		entry.dimension = int(dimension)
+		entry.entryNode = int(entryNode)
+		if entry.dimension != info.VectorDimension() || entry.sim != info.VectorSimilarity() {
+			return nil, errors.New(fmt.Sprintf(
+				"vector dimension/similarity %v/%v of field '%v' differ from its field info: %v/%v (resource=%v)",
+				entry.dimension, entry.sim, info.Name, info.VectorDimension(), info.VectorSimilarity(), meta))
+		}
+		entry.docs = make([]int, size)
+		entries = append(entries, entry)
+	}
+}
+
+func (f *hnswVectorsField) readData(in store.IndexInput) error {
+	doc := 0
+	for i := range f.docs {
+		delta, err := in.ReadVInt()
+		if err != nil {
+			return err
+		}
+		doc += int(delta)
+		f.docs[i] = doc
+	}
+
+	f.vectors = make([][]float32, len(f.docs))
+	for i := range f.vectors {
+		f.vectors[i] = make([]float32, f.dimension)
+		for j := range f.vectors[i] {
+			bits, err := in.ReadInt()
+			if err != nil {
+				return err
+			}
+			f.vectors[i][j] = math.Float32frombits(uint32(bits))
+		}
+	}
+
+	neighbors := make([][][]int, len(f.docs))
+	for node := range neighbors {
+		levels, err := in.ReadVInt()
+		if err != nil {
+			return err
+		}
+		neighbors[node] = make([][]int, levels)
+		for level := range neighbors[node] {
+			count, err := in.ReadVInt()
+			if err != nil {
+				return err
+			}
+			neighbors[node][level] = make([]int, count)
+			last := 0
+			for i := range neighbors[node][level] {
+				delta, err := in.ReadVInt()
+				if err != nil {
+					return err
+				}
+				last += int(delta)
+				neighbors[node][level][i] = last
+			}
+		}
+	}
+	f.graph = hnsw.NewGraph(f.entryNode, neighbors)
+	return nil
+}
+
+func (f *hnswVectorsField) Dimension() int           { return f.dimension }
+func (f *hnswVectorsField) Size() int                { return len(f.docs) }
+func (f *hnswVectorsField) Doc(ord int) int          { return f.docs[ord] }
+func (f *hnswVectorsField) Vector(ord int) []float32 { return f.vectors[ord] }
+
+func (r *hnswVectorsReader) VectorValues(field string) VectorValues {
+	if f, ok := r.fields[field]; ok {
+		return f
+	}
+	return nil
+}
+
+func (r *hnswVectorsReader) Search(field string, target []float32, k int,
+	acceptDocs util.Bits, visitedLimit int) (docs []int, scores []float32, complete bool, err error) {
+
+	f, ok := r.fields[field]
+	if !ok {
+		return nil, nil, true, nil
+	}
+	if len(target) != f.dimension {
+		return nil, nil, false, errors.New(fmt.Sprintf(
+			"vector query dimension: %v differs from field dimension: %v (field '%v')",
+			len(target), f.dimension, field))
+	}
+	var acceptOrds util.Bits
+	if acceptDocs != nil {
+		acceptOrds = &acceptedOrds{acceptDocs, f}
+	}
+	neighbors, complete := hnsw.Search(target, k, f, f.sim, f.graph, acceptOrds, visitedLimit)
+	docs = make([]int, len(neighbors))
+	scores = make([]float32, len(neighbors))
+	for i, n := range neighbors {
+		docs[i], scores[i] = f.docs[n.Node], n.Score
+	}
+	return docs, scores, complete, nil
+}
+
+func (r *hnswVectorsReader) Close() error {
+	return nil
+}
+
+/* Views the accepted docs as accepted vector ordinals. */
+type acceptedOrds struct {
+	acceptDocs util.Bits
+	field      *hnswVectorsField
+}
+
+func (b *acceptedOrds) At(ord int) bool {
+	return b.acceptDocs.At(b.field.docs[ord])
+}
+
+func (b *acceptedOrds) Length() int {
+	return len(b.field.docs)
+}
@@ -0,0 +1,120 @@
+package lucene410
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/codec"
+	"github.com/jtejido/golucene/core/codec/compressing"
+	"github.com/jtejido/golucene/core/codec/lucene40"
+	"github.com/jtejido/golucene/core/codec/lucene41"
+	. "github.com/jtejido/golucene/core/codec/spi"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// Configuration option for stored fields.
+type StoredFieldsMode int
+
+const (
+	// Trade compression ratio for retrieval speed.
+	BEST_SPEED = StoredFieldsMode(iota)
+	// Trade retrieval speed for compression ratio.
+	BEST_COMPRESSION
+)
+
+func (mode StoredFieldsMode) String() string {
+	switch mode {
+	case BEST_SPEED:
+		return "BEST_SPEED"
+	case BEST_COMPRESSION:
+		return "BEST_COMPRESSION"
+	default:
+		return fmt.Sprintf("StoredFieldsMode(%v)", int(mode))
+	}
+}
+
+const highCompressionFormatName = "Lucene410StoredFieldsHigh"
+
+/*
+Lucene 4.10 stored fields format, with a choice of StoredFieldsMode.
+
+BEST_SPEED writes exactly what Lucene41StoredFieldsFormat writes: LZ4
+compressed chunks of 16KB. BEST_COMPRESSION compresses chunks of 60KB
+with DEFLATE, which gives much better compression ratios for text at
+the expense of slower document loading.
+
+The mode is recorded in the codec headers of the stored fields files,
+so a reader always picks the right decompressor, whatever mode the
+codec used to read the segment was created with. Different segments
+of the same index may therefore use different modes.
+*/
+type Lucene410StoredFieldsFormat struct {
+	mode StoredFieldsMode
+}
+
+func NewLucene410StoredFieldsFormat(mode StoredFieldsMode) *Lucene410StoredFieldsFormat {
+	assert2(mode == BEST_SPEED || mode == BEST_COMPRESSION, "unknown stored fields mode: %v", mode)
+	return &Lucene410StoredFieldsFormat{mode}
+}
+
+func (format *Lucene410StoredFieldsFormat) FieldsReader(d store.Directory,
+	si *model.SegmentInfo, fn model.FieldInfos, ctx store.IOContext) (StoredFieldsReader, error) {
+
+	mode, err := readStoredFieldsMode(d, si, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return impl(mode).FieldsReader(d, si, fn, ctx)
+}
+
+func (format *Lucene410StoredFieldsFormat) FieldsWriter(d store.Directory,
+	si *model.SegmentInfo, ctx store.IOContext) (StoredFieldsWriter, error) {
+
+	return impl(format.mode).FieldsWriter(d, si, ctx)
+}
+
+func (format *Lucene410StoredFieldsFormat) String() string {
+	return fmt.Sprintf("Lucene410StoredFieldsFormat(mode=%v)", format.mode)
+}
+
+func impl(mode StoredFieldsMode) StoredFieldsFormat {
+	if mode == BEST_COMPRESSION {
+		return compressing.NewCompressingStoredFieldsFormat(
+			highCompressionFormatName, "", compressing.COMPRESSION_MODE_HIGH_COMPRESSION, 61440)
+	}
+	return lucene41.NewLucene41StoredFieldsFormat()
+}
+
+/*
+Detects the mode a segment's stored fields were written with, from
+the codec header of its fields index file.
+*/
+func readStoredFieldsMode(d store.Directory, si *model.SegmentInfo,
+	ctx store.IOContext) (mode StoredFieldsMode, err error) {
+
+	name := util.SegmentFileName(si.Name, "", lucene40.FIELDS_INDEX_EXTENSION)
+	in, err := d.OpenInput(name, ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	magic, err := in.ReadInt()
+	if err != nil {
+		return 0, err
+	}
+	if magic != codec.CODEC_MAGIC {
+		return 0, fmt.Errorf(
+			"codec header mismatch: actual header=%v vs expected header=%v (resource: %v)",
+			magic, codec.CODEC_MAGIC, in)
+	}
+	formatName, err := in.ReadString()
+	if err != nil {
+		return 0, err
+	}
+	if formatName == highCompressionFormatName+compressing.CODEC_SFX_IDX {
+		return BEST_COMPRESSION, nil
+	}
+	// anything else is checked by the Lucene41 reader
+	return BEST_SPEED, nil
+}
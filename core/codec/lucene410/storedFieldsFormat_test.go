@@ -0,0 +1,78 @@
+package lucene410_test
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/codec/lucene410"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"strings"
+	"testing"
+)
+
+func TestStoredFieldsModes(t *testing.T) {
+	dir := testindex.NewDirectory(t)
+
+	body := func(i int) string {
+		return strings.Repeat(fmt.Sprintf("archived text number %v ", i), 50)
+	}
+	// one segment per mode, in the same index
+	for i, mode := range []lucene410.StoredFieldsMode{lucene410.BEST_SPEED, lucene410.BEST_COMPRESSION} {
+		conf := testindex.NewConfig()
+		conf.SetCodec(lucene410.NewLucene410Codec(mode))
+		conf.SetUseCompoundFile(false)
+		w := testindex.NewWriter(t, dir, conf)
+		for j := 0; j < 10; j++ {
+			d := docu.NewDocument()
+			d.Add(docu.NewTextFieldFromString("body", body(i*10+j), docu.STORE_YES))
+			if err := w.AddDocument(d.Fields()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the mode is recorded in the fields index header
+	files, err := dir.ListAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	high := 0
+	for _, name := range files {
+		if strings.HasSuffix(name, ".fdx") {
+			in, err := dir.OpenInput(name, store.IO_CONTEXT_READONCE)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := make([]byte, in.Length())
+			err = in.ReadBytes(data)
+			in.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "Lucene410StoredFieldsHighIndex") {
+				high++
+			}
+		}
+	}
+	if high != 1 {
+		t.Errorf("expected 1 BEST_COMPRESSION segment, but %v", high)
+	}
+
+	// the default codec reads back both segments
+	r := testindex.OpenReader(t, dir)
+	if n := len(r.Leaves()); n != 2 {
+		t.Fatalf("expected 2 segments, but %v", n)
+	}
+	for i := 0; i < 20; i++ {
+		d, err := r.Document(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := d.Get("body"); v != body(i) {
+			t.Errorf("doc %v: expected %q, but %q", i, body(i), v)
+		}
+	}
+}
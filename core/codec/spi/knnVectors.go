@@ -0,0 +1,67 @@
+package spi
+
+import (
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/util"
+	"io"
+)
+
+// codecs/KnnVectorsFormat.java
+
+/*
+Encodes/decodes the dense vectors of a segment, along with the graph
+used to search them for nearest neighbors.
+
+It's not part of Codec, so that existing codecs remain valid; a codec
+supporting dense vectors implements KnnVectorsCodec.
+*/
+type KnnVectorsFormat interface {
+	// Returns a KnnVectorsWriter to write the vectors of a segment.
+	VectorsWriter(state *SegmentWriteState) (w KnnVectorsWriter, err error)
+	// Returns a KnnVectorsReader to read the vectors of a segment.
+	VectorsReader(state SegmentReadState) (r KnnVectorsReader, err error)
+}
+
+/* Implemented by codecs supporting dense vectors. */
+type KnnVectorsCodec interface {
+	Codec
+	// Encodes/decodes dense vectors
+	KnnVectorsFormat() KnnVectorsFormat
+}
+
+/*
+Returns the KnnVectorsFormat of codec, or nil if it doesn't support
+dense vectors.
+*/
+func KnnVectorsFormatOf(codec Codec) KnnVectorsFormat {
+	if c, ok := codec.(KnnVectorsCodec); ok {
+		return c.KnnVectorsFormat()
+	}
+	return nil
+}
+
+// codecs/KnnVectorsWriter.java
+
+/* Codec API for writing dense vectors. */
+type KnnVectorsWriter interface {
+	io.Closer
+	// Writes all vectors of a field, and the graph to search them.
+	// Fields are added in increasing field number order.
+	AddField(fi *FieldInfo, values VectorValues) error
+}
+
+// codecs/KnnVectorsReader.java
+
+/* Codec API for reading dense vectors. */
+type KnnVectorsReader interface {
+	io.Closer
+	// Returns the vectors of a field, or nil if it has none.
+	VectorValues(field string) VectorValues
+	// Returns the docIDs and scores of the (approximately) k nearest
+	// vectors of field to target, best first, skipping the documents
+	// not in acceptDocs if it's not nil. At most visitedLimit vectors
+	// are compared to target; complete is false if the limit was hit
+	// before the search ended.
+	Search(field string, target []float32, k int, acceptDocs util.Bits,
+		visitedLimit int) (docs []int, scores []float32, complete bool, err error)
+}
@@ -64,6 +64,22 @@ func (doc *Document) Get(name string) string {
 	return ""
 }
 
+/*
+Returns the binary value of the field with the given name if any
+exist in this document, or nil. If multiple fields exist with this
+name, this method returns the first value added.
+*/
+func (doc *Document) GetBinary(name string) []byte {
+	for _, field := range doc.fields {
+		if field.Name() == name {
+			if v := field.BinaryValue(); v != nil {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
 // document/DocumentStoredFieldVisitor.java
 /*
 A StoredFieldVisitor that creates a Document containing all
@@ -85,10 +101,19 @@ func NewDocumentStoredFieldVisitor() *DocumentStoredFieldVisitor {
 	}
 }
 
+/* Load only the fields with the given names. */
+func NewDocumentStoredFieldVisitorFor(fieldsToAdd ...string) *DocumentStoredFieldVisitor {
+	ans := NewDocumentStoredFieldVisitor()
+	ans.fieldsToAdd = make(map[string]bool)
+	for _, name := range fieldsToAdd {
+		ans.fieldsToAdd[name] = true
+	}
+	return ans
+}
+
 func (visitor *DocumentStoredFieldVisitor) BinaryField(fi *FieldInfo, value []byte) error {
-	panic("not implemented yet")
-	// visitor.doc.add(newStoredField(fieldInfo.name, value))
-	// return nil
+	visitor.doc.Add(NewStoredFieldFromBytes(fi.Name, value))
+	return nil
 }
 
 func (visitor *DocumentStoredFieldVisitor) StringField(fi *FieldInfo, value string) error {
@@ -102,19 +127,23 @@ func (visitor *DocumentStoredFieldVisitor) StringField(fi *FieldInfo, value stri
 }
 
 func (visitor *DocumentStoredFieldVisitor) IntField(fi *FieldInfo, value int) error {
-	panic("not implemented yet")
+	visitor.doc.Add(NewStoredFieldFromInt(fi.Name, int32(value)))
+	return nil
 }
 
 func (visitor *DocumentStoredFieldVisitor) LongField(fi *FieldInfo, value int64) error {
-	panic("not implemented yet")
+	visitor.doc.Add(NewStoredFieldFromLong(fi.Name, value))
+	return nil
 }
 
 func (visitor *DocumentStoredFieldVisitor) FloatField(fi *FieldInfo, value float32) error {
-	panic("not implemented yet")
+	visitor.doc.Add(NewStoredFieldFromFloat(fi.Name, value))
+	return nil
 }
 
 func (visitor *DocumentStoredFieldVisitor) DoubleField(fi *FieldInfo, value float64) error {
-	panic("not implemented yet")
+	visitor.doc.Add(NewStoredFieldFromDouble(fi.Name, value))
+	return nil
 }
 
 func (visitor *DocumentStoredFieldVisitor) NeedsField(fi *FieldInfo) (status StoredFieldVisitorStatus, err error) {
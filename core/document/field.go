@@ -47,12 +47,34 @@ func NewFieldFromString(name, value string, ft *FieldType) *Field {
 	return &Field{_type: ft, _name: name, _data: value, _boost: 1}
 }
 
+/*
+Create field with TokenStream value, for a pre-analyzed field. The
+field is indexed through the TokenStream, hence cannot be stored.
+*/
+func NewFieldFromTokenStream(name string, tokenStream analysis.TokenStream, ft *FieldType) *Field {
+	assert2(name != "", "name cannot be empty")
+	assert2(tokenStream != nil, "tokenStream cannot be nil")
+	assert2(ft.Indexed() && ft.Tokenized(), "TokenStream fields must be indexed and tokenized")
+	assert2(!ft.Stored(), "TokenStream fields cannot be stored")
+	return &Field{_type: ft, _name: name, _boost: 1, _tokenStream: tokenStream}
+}
+
 func (f *Field) StringValue() string {
-	switch f._data.(type) {
+	switch v := f._data.(type) {
 	case string:
-		return f._data.(string)
+		return v
 	case int:
-		return strconv.Itoa(f._data.(int))
+		return strconv.Itoa(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte, io.RuneReader, nil:
+		return ""
 	default:
 		log.Println("Unknown type", f._data)
 		panic("not implemented yet")
@@ -174,6 +196,26 @@ func (ts *StringTokenStream) IncrementToken() (bool, error) {
 	return true, nil
 }
 
+func (ts *StringTokenStream) End() error {
+	if err := ts.TokenStreamImpl.End(); err != nil {
+		return err
+	}
+	// set final offset
+	finalOffset := len(ts.value)
+	ts.offsetAttribute.SetOffset(finalOffset, finalOffset)
+	return nil
+}
+
+func (ts *StringTokenStream) Reset() error {
+	ts.used = false
+	return nil
+}
+
+func (ts *StringTokenStream) Close() error {
+	ts.value = ""
+	return nil
+}
+
 /* Specifies whether and how a field should be stored. */
 type Store int
 
@@ -276,6 +318,7 @@ func NewTextFieldFromString(name, value string, store Store) *TextField {
 var STORED_FIELD_TYPE = func() *FieldType {
 	ans := newFieldType()
 	ans.stored = true
+	ans.frozen = true
 	return ans
 }()
 
@@ -291,9 +334,41 @@ type StoredField struct {
 /*
 Create a stored-only field with the given binary value.
 
-NOTE: the provided byte[] is not copied so be sure
+NOTE: the provided []byte is not copied so be sure
 not to change it until you're done with this field.
 */
-// func newStoredField(name string, value []byte) *StoredField {
-// 	return &StoredField{newStringField(name, value, STORED_FIELD_TYPE)}
-// }
+func NewStoredFieldFromBytes(name string, value []byte) *StoredField {
+	assert2(name != "", "name cannot be empty")
+	assert2(value != nil, "value cannot be nil")
+	return &StoredField{&Field{_type: STORED_FIELD_TYPE, _name: name, _data: value, _boost: 1}}
+}
+
+// Create a stored-only field with the given string value.
+func NewStoredFieldFromString(name, value string) *StoredField {
+	return &StoredField{NewFieldFromString(name, value, STORED_FIELD_TYPE)}
+}
+
+// Create a stored-only field with the given int value.
+func NewStoredFieldFromInt(name string, value int32) *StoredField {
+	return newStoredFieldFromNumber(name, value)
+}
+
+// Create a stored-only field with the given int64 value.
+func NewStoredFieldFromLong(name string, value int64) *StoredField {
+	return newStoredFieldFromNumber(name, value)
+}
+
+// Create a stored-only field with the given float32 value.
+func NewStoredFieldFromFloat(name string, value float32) *StoredField {
+	return newStoredFieldFromNumber(name, value)
+}
+
+// Create a stored-only field with the given float64 value.
+func NewStoredFieldFromDouble(name string, value float64) *StoredField {
+	return newStoredFieldFromNumber(name, value)
+}
+
+func newStoredFieldFromNumber(name string, value interface{}) *StoredField {
+	assert2(name != "", "name cannot be empty")
+	return &StoredField{&Field{_type: STORED_FIELD_TYPE, _name: name, _data: value, _boost: 1}}
+}
@@ -96,6 +96,22 @@ func (ft *FieldType) IndexOptions() model.IndexOptions  { return ft._indexOption
 func (ft *FieldType) NumericType() NumericType          { return ft.numericType }
 func (ft *FieldType) DocValueType() model.DocValuesType { return ft._docValueType }
 
+func (ft *FieldType) SetTokenized(v bool) { ft.checkIfFrozen(); ft._tokenized = v }
+func (ft *FieldType) SetOmitNorms(v bool) { ft.checkIfFrozen(); ft._omitNorms = v }
+func (ft *FieldType) SetIndexOptions(v model.IndexOptions) {
+	ft.checkIfFrozen()
+	ft._indexOptions = v
+}
+
+/*
+Prevents future changes. Note, it is recommended that this is called
+once the FieldType's properties have been set, to prevent unintentional
+state changes.
+*/
+func (ft *FieldType) Freeze() {
+	ft.frozen = true
+}
+
 // Prints a Field for human consumption.
 func (ft *FieldType) String() string {
 	var buf bytes.Buffer
@@ -0,0 +1,50 @@
+package document
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index/model"
+)
+
+// document/KnnVectorField.java
+
+/*
+Field type of dense vectors: neither indexed nor stored, vectors are
+written by the KnnVectorsFormat of the codec.
+*/
+var KNN_VECTOR_FIELD_TYPE = func() *FieldType {
+	ans := newFieldType()
+	ans.frozen = true
+	return ans
+}()
+
+/*
+A field holding a dense float32 vector, searched for its nearest
+neighbors with KnnVectorQuery. A document may hold at most one vector
+per field, and all vectors of a field must have the same dimension and
+similarity function.
+*/
+type KnnVectorField struct {
+	*Field
+	vector []float32
+	sim    model.VectorSimilarityFunction
+}
+
+func NewKnnVectorField(name string, vector []float32, sim model.VectorSimilarityFunction) *KnnVectorField {
+	assert2(name != "", "name cannot be empty")
+	assert2(len(vector) > 0, "vector cannot be empty")
+	assert2(sim >= model.VECTOR_SIMILARITY_EUCLIDEAN && sim <= model.VECTOR_SIMILARITY_COSINE,
+		fmt.Sprintf("unknown vector similarity function: %v", sim))
+	return &KnnVectorField{&Field{_type: KNN_VECTOR_FIELD_TYPE, _name: name, _boost: 1}, vector, sim}
+}
+
+func (f *KnnVectorField) VectorValue() []float32 {
+	return f.vector
+}
+
+func (f *KnnVectorField) VectorSimilarity() model.VectorSimilarityFunction {
+	return f.sim
+}
+
+func (f *KnnVectorField) String() string {
+	return fmt.Sprintf("KnnVectorField<%v:%v %v>", f._name, f.sim, f.vector)
+}
@@ -0,0 +1,433 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Mapping between tagged Go structs and documents.
+
+Struct fields are mapped with the "lucene" tag:
+
+	type Photo struct {
+		ID      string    `lucene:"id,keyword,stored"`
+		Title   string    `lucene:"title,text,stored"`
+		Tags    []string  `lucene:"tag,keyword"`
+		Size    int64     `lucene:"size,stored"`
+		Taken   time.Time `lucene:"taken,keyword,stored"`
+		Thumb   []byte    `lucene:"thumb,stored"`
+		Private string    `lucene:"-"`
+	}
+
+The first tag item is the field name, defaulting to the struct field
+name. It may be followed by:
+
+	text     indexed and tokenized (string and []string only)
+	keyword  indexed as a single token
+	stored   value is stored and restored by DecodeStruct()
+
+A field must be indexed or stored. Numbers are indexed as a single
+prefix coded term (see NumericKeyword()), which sorts in numeric
+order, and stored as numbers; unsigned values above math.MaxInt64
+cannot be mapped. time.Time is indexed as "20060102150405" in UTC
+(which sorts chronologically), and stored in its binary form, which
+keeps the nanoseconds and the zone offset. []byte can only be stored.
+Slices of strings produce one field per value. Embedded structs are
+flattened; struct fields without a lucene tag are ignored. Empty
+strings and nil []byte are skipped, as they cannot be indexed nor
+stored.
+*/
+
+const (
+	mappingText = iota + 1
+	mappingKeyword
+)
+
+// time.Time keywords are indexed with this layout, in UTC
+const TIME_KEYWORD_LAYOUT = "20060102150405"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type fieldMapping struct {
+	name   string
+	index  []int // for reflect.Value.FieldByIndex
+	kind   int   // 0 if not indexed
+	stored bool
+}
+
+type structMapping struct {
+	fields []*fieldMapping
+}
+
+var mappingCache = struct {
+	sync.RWMutex
+	m map[reflect.Type]*structMapping
+}{m: make(map[reflect.Type]*structMapping)}
+
+func mappingOf(t reflect.Type) (*structMapping, error) {
+	mappingCache.RLock()
+	m, ok := mappingCache.m[t]
+	mappingCache.RUnlock()
+	if ok {
+		return m, nil
+	}
+
+	m = new(structMapping)
+	if err := m.parse(t, nil); err != nil {
+		return nil, err
+	}
+	mappingCache.Lock()
+	mappingCache.m[t] = m
+	mappingCache.Unlock()
+	return m, nil
+}
+
+func (m *structMapping) parse(t reflect.Type, parent []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag, tagged := sf.Tag.Lookup("lucene")
+		if !tagged {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := m.parse(sf.Type, index); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+		if sf.PkgPath != "" {
+			return errors.New(fmt.Sprintf("%v.%v: unexported field cannot be mapped", t, sf.Name))
+		}
+
+		items := strings.Split(tag, ",")
+		f := &fieldMapping{name: items[0], index: index}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		for _, opt := range items[1:] {
+			switch strings.TrimSpace(opt) {
+			case "text":
+				f.kind = mappingText
+			case "keyword":
+				f.kind = mappingKeyword
+			case "stored":
+				f.stored = true
+			case "":
+			default:
+				return errors.New(fmt.Sprintf("%v.%v: unknown lucene tag option %q", t, sf.Name, opt))
+			}
+		}
+		if err := f.check(sf.Type); err != nil {
+			return errors.New(fmt.Sprintf("%v.%v: %v", t, sf.Name, err))
+		}
+		m.fields = append(m.fields, f)
+	}
+	return nil
+}
+
+func (f *fieldMapping) check(t reflect.Type) error {
+	if f.kind == 0 && !f.stored {
+		return errors.New("field must be indexed or stored")
+	}
+	switch {
+	case t.Kind() == reflect.String,
+		t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		return nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		if f.kind != 0 {
+			return errors.New("[]byte can only be stored")
+		}
+		return nil
+	case f.kind == mappingText:
+		return errors.New(fmt.Sprintf("%v cannot be indexed as text", t))
+	case t == timeType, isNumber(t.Kind()):
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("unsupported type %v", t))
+	}
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return rv, errors.New(fmt.Sprintf("expected a struct or a pointer to struct, but %T", v))
+	}
+	return rv, nil
+}
+
+/*
+Converts the lucene-tagged fields of v, a struct or a pointer to
+struct, into fields ready to be passed to IndexWriter.AddDocument().
+*/
+func EncodeStruct(v interface{}) ([]model.IndexableField, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+	m, err := mappingOf(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	var fields []model.IndexableField
+	for _, f := range m.fields {
+		if fields, err = f.encode(rv.FieldByIndex(f.index), fields); err != nil {
+			return nil, errors.New(fmt.Sprintf("field %v: %v", f.name, err))
+		}
+	}
+	return fields, nil
+}
+
+/* Like EncodeStruct(), but returns the fields in a Document. */
+func NewDocumentFromStruct(v interface{}) (*Document, error) {
+	fields, err := EncodeStruct(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{fields}, nil
+}
+
+func (f *fieldMapping) encode(v reflect.Value, fields []model.IndexableField) ([]model.IndexableField, error) {
+	switch {
+	case v.Kind() == reflect.String:
+		return f.encodeString(v.String(), fields), nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			fields = f.encodeString(v.Index(i).String(), fields)
+		}
+		return fields, nil
+	case v.Kind() == reflect.Slice:
+		if v.IsNil() {
+			return fields, nil
+		}
+		return append(fields, NewStoredFieldFromBytes(f.name, v.Bytes())), nil
+	case v.Kind() == reflect.Struct: // time.Time
+		t := v.Interface().(time.Time)
+		if f.kind == mappingKeyword {
+			fields = append(fields, NewFieldFromString(f.name,
+				t.UTC().Format(TIME_KEYWORD_LAYOUT), STRING_FIELD_TYPE_NOT_STORED))
+		}
+		if f.stored {
+			data, err := t.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, NewStoredFieldFromBytes(f.name, data))
+		}
+		return fields, nil
+	}
+
+	keyword, err := numericKeyword(v)
+	if err != nil {
+		return nil, err
+	}
+	if f.kind == mappingKeyword {
+		fields = append(fields, NewFieldFromString(f.name, keyword, STRING_FIELD_TYPE_NOT_STORED))
+	}
+	if f.stored {
+		var stored *StoredField
+		switch v.Kind() {
+		case reflect.Int32, reflect.Int16, reflect.Int8:
+			stored = NewStoredFieldFromInt(f.name, int32(v.Int()))
+		case reflect.Int, reflect.Int64:
+			stored = NewStoredFieldFromLong(f.name, v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			stored = NewStoredFieldFromLong(f.name, int64(v.Uint()))
+		case reflect.Float32:
+			stored = NewStoredFieldFromFloat(f.name, float32(v.Float()))
+		case reflect.Float64:
+			stored = NewStoredFieldFromDouble(f.name, v.Float())
+		}
+		fields = append(fields, stored)
+	}
+	return fields, nil
+}
+
+/*
+Returns the term a number is indexed as by a keyword mapping, e.g.
+to build a TermQuery on it. Values of 32 bits or less are coded as
+Lucene's IntField and the others as LongField, at full precision;
+floats are first converted to their sortable integer form. Terms of
+the same mapped type sort in numeric order.
+*/
+func NumericKeyword(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if !isNumber(rv.Kind()) {
+		return "", errors.New(fmt.Sprintf("expected a number, but %T", v))
+	}
+	return numericKeyword(rv)
+}
+
+func numericKeyword(v reflect.Value) (string, error) {
+	bytes := util.NewBytesRefBuilder()
+	switch v.Kind() {
+	case reflect.Int32, reflect.Int16, reflect.Int8:
+		util.IntToPrefixCoded(int32(v.Int()), 0, bytes)
+	case reflect.Int, reflect.Int64:
+		util.LongToPrefixCoded(v.Int(), 0, bytes)
+	case reflect.Uint16, reflect.Uint8:
+		util.IntToPrefixCoded(int32(v.Uint()), 0, bytes)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return "", errors.New(fmt.Sprintf("%v overflows int64", v.Uint()))
+		}
+		util.LongToPrefixCoded(int64(v.Uint()), 0, bytes)
+	case reflect.Float32:
+		util.IntToPrefixCoded(util.FloatToSortableInt(float32(v.Float())), 0, bytes)
+	case reflect.Float64:
+		util.LongToPrefixCoded(util.DoubleToSortableLong(v.Float()), 0, bytes)
+	}
+	return string(bytes.Get().ToBytes()), nil
+}
+
+func (f *fieldMapping) encodeString(value string, fields []model.IndexableField) []model.IndexableField {
+	if value == "" {
+		return fields
+	}
+	var ft *FieldType
+	switch {
+	case f.kind == mappingText && f.stored:
+		ft = TEXT_FIELD_TYPE_STORED
+	case f.kind == mappingText:
+		ft = TEXT_FIELD_TYPE_NOT_STORED
+	case f.kind == mappingKeyword && f.stored:
+		ft = STRING_FIELD_TYPE_STORED
+	case f.kind == mappingKeyword:
+		ft = STRING_FIELD_TYPE_NOT_STORED
+	default:
+		ft = STORED_FIELD_TYPE
+	}
+	return append(fields, NewFieldFromString(f.name, value, ft))
+}
+
+/*
+Sets the stored lucene-tagged fields of v, a pointer to struct, from
+doc, e.g. as returned by IndexReader.Document(). Fields which are not
+stored, or missing from doc, are left untouched.
+*/
+func DecodeStruct(doc *Document, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New(fmt.Sprintf("expected a non-nil pointer to struct, but %T", v))
+	}
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	m, err := mappingOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range m.fields {
+		if f.stored {
+			if err = f.decode(doc, rv.FieldByIndex(f.index)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fieldMapping) decode(doc *Document, v reflect.Value) error {
+	var values []model.IndexableField
+	for _, field := range doc.fields {
+		if field.Name() == f.name {
+			values = append(values, field)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(values[0].StringValue())
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		s := reflect.MakeSlice(v.Type(), 0, len(values))
+		for _, field := range values {
+			s = reflect.Append(s, reflect.ValueOf(field.StringValue()).Convert(v.Type().Elem()))
+		}
+		v.Set(s)
+		return nil
+	case v.Kind() == reflect.Slice:
+		v.SetBytes(values[0].BinaryValue())
+		return nil
+	case v.Kind() == reflect.Struct: // time.Time
+		// skip the keyword, if also indexed
+		var data []byte
+		for _, field := range values {
+			if data = field.BinaryValue(); data != nil {
+				break
+			}
+		}
+		var t time.Time
+		if err := t.UnmarshalBinary(data); err != nil {
+			return errors.New(fmt.Sprintf("field %v: %v", f.name, err))
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	var n interface{}
+	for _, field := range values {
+		if n = field.NumericValue(); n != nil {
+			break
+		}
+	}
+	var i int64
+	var fl float64
+	switch number := n.(type) {
+	case int32:
+		i, fl = int64(number), float64(number)
+	case int64:
+		i, fl = number, float64(number)
+	case float32:
+		i, fl = int64(number), float64(number)
+	case float64:
+		i, fl = int64(number), number
+	default:
+		return errors.New(fmt.Sprintf("field %v: stored value is not a number", f.name))
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(i) {
+			return errors.New(fmt.Sprintf("field %v: %v overflows %v", f.name, i, v.Type()))
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return errors.New(fmt.Sprintf("field %v: %v overflows %v", f.name, i, v.Type()))
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		if v.OverflowFloat(fl) {
+			return errors.New(fmt.Sprintf("field %v: %v overflows %v", f.name, fl, v.Type()))
+		}
+		v.SetFloat(fl)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package document
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type mappedBase struct {
+	ID string `lucene:"id,keyword,stored"`
+}
+
+type mappedPhoto struct {
+	mappedBase
+	Title   string    `lucene:"title,text,stored"`
+	Tags    []string  `lucene:"tag,keyword,stored"`
+	Size    int64     `lucene:"size,stored"`
+	Width   int32     `lucene:"width,keyword,stored"`
+	Ratio   float64   `lucene:"ratio,stored"`
+	Taken   time.Time `lucene:"taken,keyword,stored"`
+	Thumb   []byte    `lucene:"thumb,stored"`
+	Body    string    `lucene:"body,text"`
+	Private string    `lucene:"-"`
+	Ignored string
+}
+
+func TestStructMapping(t *testing.T) {
+	photo := &mappedPhoto{
+		mappedBase: mappedBase{"p1"},
+		Title:      "sunset at the beach",
+		Tags:       []string{"sea", "sky"},
+		Size:       1 << 40,
+		Width:      640,
+		Ratio:      1.5,
+		Taken:      time.Date(2014, 9, 26, 18, 30, 0, 0, time.UTC),
+		Thumb:      []byte{1, 2, 3},
+		Body:       "long description",
+		Private:    "secret",
+	}
+	doc, err := NewDocumentFromStruct(photo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string][]*FieldType)
+	for _, f := range doc.Fields() {
+		byName[f.Name()] = append(byName[f.Name()], f.FieldType().(*FieldType))
+	}
+	if fts := byName["title"]; len(fts) != 1 || !fts[0].Tokenized() || !fts[0].Stored() {
+		t.Errorf("title should be stored text, but %v", fts)
+	}
+	if fts := byName["id"]; len(fts) != 1 || fts[0].Tokenized() || !fts[0].Indexed() {
+		t.Errorf("id should be a stored keyword, but %v", fts)
+	}
+	if fts := byName["body"]; len(fts) != 1 || fts[0].Stored() {
+		t.Errorf("body should not be stored, but %v", fts)
+	}
+	if n := len(byName["tag"]); n != 2 {
+		t.Errorf("expected 2 tag fields, but %v", n)
+	}
+	if n := len(byName["width"]); n != 2 {
+		t.Errorf("expected width to be indexed and stored separately, but %v fields", n)
+	}
+	if _, ok := byName["Private"]; ok {
+		t.Error("Private should not be mapped")
+	}
+	if _, ok := byName["Ignored"]; ok {
+		t.Error("Ignored should not be mapped")
+	}
+	for _, f := range doc.Fields() {
+		if f.Name() == "taken" && f.FieldType().Indexed() && f.StringValue() != "20140926183000" {
+			t.Errorf("unexpected time keyword %v", f.StringValue())
+		}
+	}
+
+	var decoded mappedPhoto
+	if err = DecodeStruct(doc, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := *photo
+	expected.Body, expected.Private = "", ""
+	if !decoded.Taken.Equal(expected.Taken) {
+		t.Errorf("expected %v, but %v", expected.Taken, decoded.Taken)
+	}
+	decoded.Taken = expected.Taken
+	if !bytes.Equal(decoded.Thumb, expected.Thumb) {
+		t.Errorf("expected %v, but %v", expected.Thumb, decoded.Thumb)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected %+v, but %+v", expected, decoded)
+	}
+
+	// invalid mappings
+	if _, err = EncodeStruct(struct {
+		N int `lucene:"n,text"`
+	}{}); err == nil {
+		t.Error("expected error for numbers indexed as text")
+	}
+	if _, err = EncodeStruct(struct {
+		B []byte `lucene:"b,keyword"`
+	}{}); err == nil {
+		t.Error("expected error for indexed []byte")
+	}
+	if _, err = EncodeStruct(struct {
+		S string `lucene:"s"`
+	}{}); err == nil {
+		t.Error("expected error for a field neither indexed nor stored")
+	}
+	if err = DecodeStruct(doc, decoded); err == nil {
+		t.Error("expected error when decoding into a non-pointer")
+	}
+}
+
+func TestStructMappingTimes(t *testing.T) {
+	type event struct {
+		At time.Time `lucene:"at,stored"`
+	}
+	for _, at := range []time.Time{
+		{},
+		time.Date(1500, 1, 2, 3, 4, 5, 6, time.UTC),
+		time.Date(3000, 12, 31, 23, 59, 59, 999999999, time.FixedZone("IST", 5*3600+1800)),
+	} {
+		doc, err := NewDocumentFromStruct(event{at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded event
+		if err = DecodeStruct(doc, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.At.Equal(at) {
+			t.Errorf("expected %v, but %v", at, decoded.At)
+		}
+		_, expected := at.Zone()
+		if _, offset := decoded.At.Zone(); offset != expected {
+			t.Errorf("%v: zone offset was lost: %v", at, decoded.At)
+		}
+	}
+}
+
+func TestStructMappingNumbers(t *testing.T) {
+	type number struct {
+		L int64   `lucene:"l,keyword"`
+		D float64 `lucene:"d,keyword"`
+	}
+	values := []float64{math.Inf(-1), -1e10, -3.5, -1, 0, 0.25, 1, 2, 10, 1e10, math.Inf(1)}
+	var longs, doubles []string
+	for _, v := range values {
+		doc, err := NewDocumentFromStruct(number{int64(math.Max(math.Min(v, 1e15), -1e15)), v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range doc.Fields() {
+			if f.Name() == "l" {
+				longs = append(longs, f.StringValue())
+			} else {
+				doubles = append(doubles, f.StringValue())
+			}
+		}
+		if k, _ := NumericKeyword(v); doubles[len(doubles)-1] != k {
+			t.Errorf("%v: indexed as %q, but NumericKeyword() returned %q", v, doubles[len(doubles)-1], k)
+		}
+	}
+	if !sort.StringsAreSorted(longs) {
+		t.Errorf("int64 keywords should sort numerically: %q", longs)
+	}
+	if !sort.StringsAreSorted(doubles) {
+		t.Errorf("float64 keywords should sort numerically: %q", doubles)
+	}
+
+	if _, err := EncodeStruct(struct {
+		U uint64 `lucene:"u,keyword"`
+	}{math.MaxUint64}); err == nil {
+		t.Error("expected error for uint64 overflowing int64")
+	}
+
+	doc, err := NewDocumentFromStruct(struct {
+		N int64 `lucene:"n,stored"`
+	}{1 << 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var narrow struct {
+		N int32 `lucene:"n,stored"`
+	}
+	if err = DecodeStruct(doc, &narrow); err == nil {
+		t.Errorf("expected overflow error, but decoded %v", narrow.N)
+	}
+	var unsigned struct {
+		N uint8 `lucene:"n,stored"`
+	}
+	if err = DecodeStruct(doc, &unsigned); err == nil {
+		t.Errorf("expected overflow error, but decoded %v", unsigned.N)
+	}
+}
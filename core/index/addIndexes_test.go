@@ -0,0 +1,163 @@
+package index_test
+
+import (
+	"fmt"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestAddIndexes(t *testing.T) {
+	newWriter := func(dir store.Directory, useCompoundFile bool) *index.IndexWriter {
+		conf := testindex.NewConfig()
+		conf.SetUseCompoundFile(useCompoundFile)
+		w, err := index.NewIndexWriter(dir, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	addDocs := func(w *index.IndexWriter, texts ...string) {
+		for _, text := range texts {
+			d := docu.NewDocument()
+			d.Add(docu.NewTextFieldFromString("body", text, docu.STORE_YES))
+			if err := w.AddDocument(d.Fields()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	closeWriter := func(w *index.IndexWriter) {
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// returns the stored bodies of all documents matching term
+	hitsOf := func(dir store.Directory, term string) []string {
+		r, err := index.OpenDirectoryReader(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		docs, err := search.NewIndexSearcher(r).Search(
+			search.NewTermQuery(index.NewTerm("body", term)), nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bodies []string
+		for _, hit := range docs.ScoreDocs {
+			d, err := r.Document(hit.Doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bodies = append(bodies, d.Get("body"))
+		}
+		sort.Strings(bodies)
+		return bodies
+	}
+	assertHits := func(dir store.Directory, term string, expected ...string) {
+		hits := hitsOf(dir, term)
+		if len(hits) != len(expected) {
+			t.Fatalf("%v: expected %v, but %v", term, expected, hits)
+		}
+		for i, v := range expected {
+			if hits[i] != v {
+				t.Errorf("%v: expected %v, but %v", term, expected, hits)
+			}
+		}
+	}
+
+	shard1 := testindex.NewDirectory(t)
+	w := newWriter(shard1, true)
+	addDocs(w, "a b", "b c")
+	closeWriter(w)
+
+	shard2 := testindex.NewDirectory(t)
+	w = newWriter(shard2, false)
+	addDocs(w, "c d")
+	closeWriter(w)
+
+	dest := testindex.NewDirectory(t)
+	w = newWriter(dest, false)
+	addDocs(w, "a e")
+	if err := w.AddIndexesFromDirectories(shard1, dest); err == nil {
+		t.Error("expected error when adding the writer's own directory")
+	}
+	if err := w.AddIndexesFromDirectories(shard1, shard2); err != nil {
+		t.Fatal(err)
+	}
+	closeWriter(w)
+	assertHits(dest, "a", "a b", "a e")
+	assertHits(dest, "c", "b c", "c d")
+
+	merged := testindex.NewDirectory(t)
+	w = newWriter(merged, true)
+	addDocs(w, "b e")
+	readers := make([]index.IndexReader, 0, 2)
+	for _, dir := range []store.Directory{shard1, shard2} {
+		r, err := index.OpenDirectoryReader(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		readers = append(readers, r)
+	}
+	if err := w.AddIndexesFromReaders(readers...); err != nil {
+		t.Fatal(err)
+	}
+	closeWriter(w)
+	assertHits(merged, "b", "a b", "b c", "b e")
+	assertHits(merged, "d", "c d")
+}
+
+func TestAddIndexesMergeRateLimit(t *testing.T) {
+	texts := make([]string, 50)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("doc%v lorem ipsum dolor sit amet %v", i, i*7919)
+	}
+	source := testindex.NewReader(t, testindex.TextDocs("body", texts...)...)
+
+	// writes the documents of source to a new index, and returns how
+	// long it took and the size of the new index
+	addIndexes := func(mbPerSec float64) (time.Duration, int64) {
+		cms := index.NewConcurrentMergeScheduler()
+		cms.SetMergeMBPerSec(mbPerSec)
+		dir := testindex.NewDirectory(t)
+		w := testindex.NewWriter(t, dir, testindex.NewConfig().SetMergeScheduler(cms))
+		start := time.Now()
+		if err := w.AddIndexesFromReaders(source); err != nil {
+			t.Fatal(err)
+		}
+		elapsed := time.Since(start)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		files, err := dir.ListAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := int64(0)
+		for _, file := range files {
+			n, err := dir.FileLength(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size += n
+		}
+		return elapsed, size
+	}
+
+	_, size := addIndexes(0)
+	const mbPerSec = 0.01
+	elapsed, _ := addIndexes(mbPerSec)
+	// the new segment makes up most of the index; allow for the
+	// segments file and the bytes left below the pause threshold
+	if expected := time.Duration(float64(size) / 2 / (mbPerSec * 1024 * 1024) * float64(time.Second)); elapsed < expected {
+		t.Errorf("expected writing %v bytes at %v MB/sec to take at least %v, but %v",
+			size, mbPerSec, expected, elapsed)
+	}
+}
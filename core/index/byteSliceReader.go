@@ -97,5 +97,19 @@ func (r *ByteSliceReader) nextSlice() {
 }
 
 func (r *ByteSliceReader) ReadBytes(buf []byte) error {
-	panic("not implemented yet")
+	for len(buf) > 0 {
+		numLeft := r.limit - r.upto
+		if numLeft < len(buf) {
+			// read entire slice
+			copy(buf, r.buffer[r.upto:r.upto+numLeft])
+			buf = buf[numLeft:]
+			r.nextSlice()
+		} else {
+			// this slice is the last one
+			copy(buf, r.buffer[r.upto:r.upto+len(buf)])
+			r.upto += len(buf)
+			break
+		}
+	}
+	return nil
 }
@@ -58,7 +58,13 @@ func (r *CompositeReaderImpl) Context() IndexReaderContext {
 	if r.readerContext == nil {
 		// log.Print("Obtaining context for: ", r)
 		// assert getSequentialSubReaders() != null;
-		r.readerContext = newCompositeReaderContext(r)
+		// build the context around the outermost reader, so that
+		// Context().Reader() returns e.g. the DirectoryReader itself:
+		if self, ok := r.CompositeReaderSPI.(CompositeReader); ok {
+			r.readerContext = newCompositeReaderContext(self)
+		} else {
+			r.readerContext = newCompositeReaderContext(r)
+		}
 	}
 	return r.readerContext
 }
@@ -126,15 +132,15 @@ type CompositeReaderContextBuilder struct {
 	leafDocBase int
 }
 
-func newCompositeReaderContextBuilder(r CompositeReader) CompositeReaderContextBuilder {
-	return CompositeReaderContextBuilder{reader: r, leaves: list.New()}
+func newCompositeReaderContextBuilder(r CompositeReader) *CompositeReaderContextBuilder {
+	return &CompositeReaderContextBuilder{reader: r, leaves: list.New()}
 }
 
-func (b CompositeReaderContextBuilder) build() *CompositeReaderContext {
+func (b *CompositeReaderContextBuilder) build() *CompositeReaderContext {
 	return b.build4(nil, b.reader, 0, 0).(*CompositeReaderContext)
 }
 
-func (b CompositeReaderContextBuilder) build4(parent *CompositeReaderContext,
+func (b *CompositeReaderContextBuilder) build4(parent *CompositeReaderContext,
 	reader IndexReader, ord, docBase int) IndexReaderContext {
 	// log.Printf("Building context from %v(parent: %v, %v-%v)", reader, parent, ord, docBase)
 	if ar, ok := reader.(AtomicReader); ok {
@@ -158,7 +164,7 @@ func (b CompositeReaderContextBuilder) build4(parent *CompositeReaderContext,
 	newDocBase := 0
 	for i, r := range sequentialSubReaders {
 		children[i] = b.build4(newParent, r, i, newDocBase)
-		newDocBase = r.MaxDoc()
+		newDocBase += r.MaxDoc()
 	}
 	// assert newDocBase == cr.maxDoc()
 	return newParent
@@ -243,7 +249,16 @@ func (r *BaseCompositeReader) VisitDocument(docID int, visitor StoredFieldVisito
 }
 
 func (r *BaseCompositeReader) DocFreq(term *Term) (int, error) {
-	panic("not implemented yet")
+	r.ensureOpen()
+	total := 0 // sum freqs in subreaders
+	for _, sub := range r.subReaders {
+		n, err := sub.DocFreq(term)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
 }
 
 func (r *BaseCompositeReader) TotalTermFreq(term *Term) int64 {
@@ -2,7 +2,9 @@ package index
 
 import (
 	"fmt"
+	"github.com/jtejido/golucene/core/store"
 	"log"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -38,6 +40,14 @@ largest merges are paused until one of the smaller merges completes.
 If more than MaxMergeCount() merges are requested then this class
 will forcefully throttle the incoming goroutines by pausing until one
 or more merges complete.
+
+The write IO of merges can be rate limited with SetMergeMBPerSec(),
+so that merging leaves IO to searches. IndexWriter applies the limit
+to every output it creates with a MERGE IOContext. Note that
+IndexWriter.merge() is not ported yet, so for now these are only the
+segments written by IndexWriter.AddIndexesFromReaders(). There is no
+adaptive (auto) IO throttle either: it would tune the rate to the
+merge backlog, and there is no backlog while merges cannot run.
 */
 type ConcurrentMergeScheduler struct {
 	sync.Locker
@@ -47,7 +57,7 @@ type ConcurrentMergeScheduler struct {
 	// ones, letting the smaller ones run, up until maxMergeCount
 	// merges at which point we forcefully pause incoming routines
 	// (that presumably are the ones causing so much merging).
-	maxRoutineCount int
+	maxRoutineCount int32 // atomic
 
 	// Max number of merges we accept before forcefully throttling the
 	// incoming routines
@@ -65,6 +75,9 @@ type ConcurrentMergeScheduler struct {
 	chSync               chan *sync.WaitGroup
 	concurrentMergeCount int32 // atomic
 	numMergeRoutines     int32 // atomic
+
+	// Rate limiter shared by the write IO of all merges.
+	mergeRateLimiter *store.SimpleRateLimiter
 }
 
 func NewConcurrentMergeScheduler() *ConcurrentMergeScheduler {
@@ -72,6 +85,8 @@ func NewConcurrentMergeScheduler() *ConcurrentMergeScheduler {
 		Locker:    &sync.Mutex{},
 		chRequest: make(chan *MergeJob),
 		chSync:    make(chan *sync.WaitGroup),
+
+		mergeRateLimiter: store.NewSimpleRateLimiter(math.Inf(1)),
 	}
 	cms.SetMaxMergesAndRoutines(DEFAULT_MAX_MERGE_COUNT, DEFAULT_MAX_ROUTINE_COUNT)
 	return cms
@@ -92,7 +107,7 @@ func (cms *ConcurrentMergeScheduler) worker(id int) {
 	fmt.Printf("CMS Worker %v is started.\n", id)
 	var isRunning = true
 	var wg *sync.WaitGroup
-	for isRunning && id < cms.maxRoutineCount {
+	for isRunning && id < int(atomic.LoadInt32(&cms.maxRoutineCount)) {
 		select {
 		case job := <-cms.chRequest:
 			cms.process(job)
@@ -119,7 +134,6 @@ func (cms *ConcurrentMergeScheduler) process(job *MergeJob) {
 		cms.message("    launch new thread [%v]", atomic.AddInt32(&cms.mergeThreadCount, 1))
 		cms.message("  merge thread: start")
 	}
-
 	err := job.writer.merge(job.merge)
 	if err != nil {
 		// Ignore the error if it was due to abort:
@@ -138,17 +152,53 @@ func (cms *ConcurrentMergeScheduler) SetMaxMergesAndRoutines(maxMergeCount, maxR
 	assert2(maxRoutineCount <= maxMergeCount, fmt.Sprintf(
 		"maxRoutineCount should be <= maxMergeCount (= %v)", maxMergeCount))
 
-	oldCount := cms.maxRoutineCount
-	cms.maxRoutineCount = maxRoutineCount
-	cms.maxMergeCount = maxMergeCount
-
 	cms.Lock()
 	defer cms.Unlock()
+	oldCount := int(atomic.SwapInt32(&cms.maxRoutineCount, int32(maxRoutineCount)))
+	cms.maxMergeCount = maxMergeCount
 	for i := oldCount; i < maxRoutineCount; i++ {
 		go cms.worker(i)
 	}
 }
 
+/*
+Sets the maximum (approx) MB/sec allowed by the write IO of merges.
+Pass a non-positive value to have no limit, which is the default. The
+new rate applies to outputs already being written too.
+*/
+func (cms *ConcurrentMergeScheduler) SetMergeMBPerSec(mbPerSec float64) {
+	if mbPerSec <= 0 {
+		mbPerSec = math.Inf(1)
+	}
+	cms.mergeRateLimiter.SetMbPerSec(mbPerSec)
+	if cms.verbose() {
+		cms.message("merge IO rate limit: %v", formatMBPerSec(mbPerSec))
+	}
+}
+
+/*
+Returns the MB/sec allowed by the write IO of merges, +Inf if there
+is no limit.
+*/
+func (cms *ConcurrentMergeScheduler) MergeMBPerSec() float64 {
+	return cms.mergeRateLimiter.MbPerSec()
+}
+
+/*
+Returns the RateLimiter that limits the write IO of merges. IndexWriter
+applies it to all outputs it creates with a MERGE IOContext.
+*/
+func (cms *ConcurrentMergeScheduler) MergeRateLimiter() store.RateLimiter {
+	return cms.mergeRateLimiter
+}
+
+func formatMBPerSec(mbPerSec float64) string {
+	if math.IsInf(mbPerSec, 1) {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%.1f MB/sec", mbPerSec)
+}
+
 /*
 Returns true if verbosing is enabled. This method is usually used in
 conjunction with message(), like that:
@@ -252,5 +302,8 @@ func (cms *ConcurrentMergeScheduler) handleMergeError(err error) {
 }
 
 func (cms *ConcurrentMergeScheduler) String() string {
-	panic("not implemented yet")
+	cms.Lock() // synchronized
+	defer cms.Unlock()
+	return fmt.Sprintf("ConcurrentMergeScheduler: maxRoutineCount=%v, maxMergeCount=%v, mergeMBPerSec=%v",
+		atomic.LoadInt32(&cms.maxRoutineCount), cms.maxMergeCount, formatMBPerSec(cms.MergeMBPerSec()))
 }
@@ -0,0 +1,30 @@
+package index
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMergeMBPerSec(t *testing.T) {
+	cms := NewConcurrentMergeScheduler()
+	defer cms.Close()
+	if v := cms.MergeMBPerSec(); !math.IsInf(v, 1) {
+		t.Fatalf("expected merges to be unlimited by default, but %v MB/sec", v)
+	}
+
+	cms.SetMergeMBPerSec(2.5)
+	if v := cms.MergeMBPerSec(); v != 2.5 {
+		t.Errorf("expected 2.5 MB/sec, but %v", v)
+	}
+	if v := cms.MergeRateLimiter().MbPerSec(); v != 2.5 {
+		t.Errorf("expected limiter at 2.5 MB/sec, but %v", v)
+	}
+	if s := cms.String(); s != "ConcurrentMergeScheduler: maxRoutineCount=1, maxMergeCount=2, mergeMBPerSec=2.5 MB/sec" {
+		t.Errorf("unexpected %v", s)
+	}
+
+	cms.SetMergeMBPerSec(0)
+	if v := cms.MergeRateLimiter().MbPerSec(); !math.IsInf(v, 1) {
+		t.Errorf("expected unlimited limiter, but %v", v)
+	}
+}
@@ -2,6 +2,7 @@ package index
 
 import (
 	"github.com/jtejido/golucene/core/analysis"
+	"github.com/jtejido/golucene/core/codec/spi"
 	"github.com/jtejido/golucene/core/util"
 )
 
@@ -139,6 +140,15 @@ func (conf *IndexWriterConfig) SetIndexDeletionPolicy(delPolicy IndexDeletionPol
 	return conf
 }
 
+/*
+Expert: allows to open a certain commit point. The default is nil
+which opens the latest commit point.
+*/
+func (conf *IndexWriterConfig) SetIndexCommit(commit IndexCommit) *IndexWriterConfig {
+	conf.commit = commit
+	return conf
+}
+
 type Similarity interface {
 	ComputeNorm(fs *FieldInvertState) int64
 }
@@ -171,6 +181,29 @@ func (conf *IndexWriterConfig) SetMergeScheduler(mergeScheduler MergeScheduler)
 	return conf
 }
 
+/*
+Set the Codec.
+
+Only takes effect when IndexWriter is first created.
+*/
+func (conf *IndexWriterConfig) SetCodec(codec spi.Codec) *IndexWriterConfig {
+	assert2(codec != nil, "codec must not be nil")
+	conf.codec = codec
+	return conf
+}
+
+/*
+Sets the Schema documents added to the IndexWriter are validated
+against. It is persisted on commit. The default is nil, which accepts
+any field.
+
+Only takes effect when IndexWriter is first created.
+*/
+func (conf *IndexWriterConfig) SetSchema(schema *Schema) *IndexWriterConfig {
+	conf.schema = schema
+	return conf
+}
+
 // L310
 func (conf *IndexWriterConfig) MergePolicy() MergePolicy {
 	return conf.mergePolicy
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jtejido/golucene/core/analysis"
 	. "github.com/jtejido/golucene/core/codec/spi"
@@ -72,6 +73,9 @@ func (c *DefaultIndexingChain) flush(state *SegmentWriteState) (err error) {
 
 		return
 	}
+	if err = c.writeVectors(state); err != nil {
+		return
+	}
 
 	// it's possible all docs hit non-aboritng errors...
 	if err = c.initStoredFieldsWriter(); err != nil {
@@ -150,6 +154,39 @@ func (c *DefaultIndexingChain) writeDocValues(state *SegmentWriteState) (err err
 	return nil
 }
 
+/* Writes all buffered dense vectors (called from flush()) */
+func (c *DefaultIndexingChain) writeVectors(state *SegmentWriteState) (err error) {
+	if !state.FieldInfos.HasVectorValues {
+		return nil
+	}
+	var writer KnnVectorsWriter
+	if writer, err = KnnVectorsFormatOf(c.docWriter.codec).VectorsWriter(state); err != nil {
+		return err
+	}
+	var success = false
+	defer func() {
+		if success {
+			err = util.Close(writer)
+		} else {
+			util.CloseWhileSuppressingError(writer)
+		}
+	}()
+
+	for _, fi := range state.FieldInfos.Values {
+		if !fi.HasVectorValues() {
+			continue
+		}
+		perField := c.perField(fi.Name)
+		assert(perField != nil && perField.vectors != nil)
+		if err = perField.vectors.flush(state, writer); err != nil {
+			return err
+		}
+		perField.vectors = nil
+	}
+	success = true
+	return nil
+}
+
 /*
 Catch up for all docs before us that had no stored fields, or hit
 non-aborting errors before writing stored fields.
@@ -368,13 +405,15 @@ func (c *DefaultIndexingChain) processField(field IndexableField,
 			fp.fieldGen = fieldGen
 		}
 	} else {
-		panic("not implemented yet")
+		if err := verifyFieldType(fieldName, fieldType); err != nil {
+			return 0, err
+		}
 	}
 
 	// Add stored fields:
 	if fieldType.Stored() {
 		if fp == nil {
-			panic("not implemented yet")
+			fp = c.getOrAddField(fieldName, fieldType, false)
 		}
 		if fieldType.Stored() {
 			if err := func() error {
@@ -403,9 +442,39 @@ func (c *DefaultIndexingChain) processField(field IndexableField,
 		panic("not implemented yet")
 	}
 
+	// Add dense vectors:
+	if vf, ok := field.(IndexableVectorField); ok {
+		if fp == nil {
+			fp = c.getOrAddField(fieldName, fieldType, false)
+		}
+		if err := fp.addVector(vf); err != nil {
+			return 0, err
+		}
+	}
+
 	return fieldCount, nil
 }
 
+func verifyFieldType(name string, ft IndexableFieldType) error {
+	if ft.StoreTermVectors() {
+		return errors.New(fmt.Sprintf(
+			"cannot store term vectors for a field that is not indexed (field=\"%v\")", name))
+	}
+	if ft.StoreTermVectorPositions() {
+		return errors.New(fmt.Sprintf(
+			"cannot store term vector positions for a field that is not indexed (field=\"%v\")", name))
+	}
+	if ft.StoreTermVectorOffsets() {
+		return errors.New(fmt.Sprintf(
+			"cannot store term vector offsets for a field that is not indexed (field=\"%v\")", name))
+	}
+	if ft.StoreTermVectorPayloads() {
+		return errors.New(fmt.Sprintf(
+			"cannot store term vector payloads for a field that is not indexed (field=\"%v\")", name))
+	}
+	return nil
+}
+
 /*
 Returns a previously created PerField, or nil if this field name
 wasn't seen yet.
@@ -483,6 +552,9 @@ type PerField struct {
 	// Lazy init'd:
 	norms *NumericDocValuesWriter
 
+	// non-nil if this field had dense vectors in this segment:
+	vectors *VectorValuesWriter
+
 	// reused
 	tokenStream analysis.TokenStream
 }
@@ -502,6 +574,21 @@ func newPerField(parent *DefaultIndexingChain,
 	return ans
 }
 
+func (f *PerField) addVector(field IndexableVectorField) error {
+	if KnnVectorsFormatOf(f.docWriter.codec) == nil {
+		return errors.New(fmt.Sprintf(
+			"codec %v does not support dense vectors (field=\"%v\")", f.docWriter.codec.Name(), f.fieldInfo.Name))
+	}
+	vector := field.VectorValue()
+	if err := f.fieldInfos.SetVectorAttributes(f.fieldInfo, len(vector), field.VectorSimilarity()); err != nil {
+		return err
+	}
+	if f.vectors == nil {
+		f.vectors = newVectorValuesWriter(f.fieldInfo, f.bytesUsed)
+	}
+	return f.vectors.addValue(f.docState.docID, vector)
+}
+
 func (f *PerField) setInvertState() {
 	f.invertState = newFieldInvertState(f.fieldInfo.Name)
 	f.termsHashPerField = f.termsHash.addField(f.invertState, f.fieldInfo)
@@ -7,6 +7,7 @@ import (
 	"github.com/jtejido/golucene/core/util"
 	// "io"
 	"errors"
+	"os"
 	"strings"
 )
 
@@ -14,11 +15,12 @@ const DEFAULT_TERMS_INDEX_DIVISOR = 1
 
 type DirectoryReader interface {
 	IndexReader
-	// doOpenIfChanged() error
+	doOpenIfChanged() (DirectoryReader, error)
 	// doOpenIfChanged(c IndexCommit) error
 	// doOpenIfChanged(w IndexWriter, c IndexCommit) error
 	Version() int64
 	IsCurrent() bool
+	IndexCommit() IndexCommit
 }
 
 type DirectoryReaderImpl struct {
@@ -42,6 +44,92 @@ func OpenDirectoryReader(directory store.Directory) (r DirectoryReader, err erro
 	return openStandardDirectoryReader(directory, nil, DEFAULT_TERMS_INDEX_DIVISOR)
 }
 
+/*
+Opens a near real time reader from the writer: it searches all the
+documents added so far, without committing them. Pending documents
+are flushed to new segments, which the writer keeps on disk until the
+reader is closed.
+
+If applyAllDeletes is true, all buffered deletes are applied (made
+visible) first. Note that SegmentReader doesn't support deletions
+yet.
+
+Use OpenDirectoryReaderIfChanged() to see changes made since.
+*/
+func OpenDirectoryReaderFromWriter(writer *IndexWriter, applyAllDeletes bool) (DirectoryReader, error) {
+	return writer.getReader(applyAllDeletes, nil)
+}
+
+/*
+Expert: returns an IndexReader reading the index in the given
+IndexCommit.
+*/
+func OpenDirectoryReaderFromCommit(commit IndexCommit) (r DirectoryReader, err error) {
+	return openStandardDirectoryReader(commit.Directory(), commit, DEFAULT_TERMS_INDEX_DIVISOR)
+}
+
+/*
+If the index has changed since the provided reader was opened, open
+and return a new reader; else, return nil.
+
+This method is typically far less costly than opening a fully new
+DirectoryReader as it shares resources (for example sub-readers) with
+the provided DirectoryReader, when possible.
+
+The provided reader is not closed (you are responsible for doing so);
+if a new reader is returned you also must eventually close it. Be
+sure to never close a reader while other goroutines are still using
+it; see SearcherManager to simplify managing this.
+*/
+func OpenDirectoryReaderIfChanged(oldReader DirectoryReader) (DirectoryReader, error) {
+	return oldReader.doOpenIfChanged()
+}
+
+/*
+Returns all commit points that exist in the Directory. Normally,
+because the default is KeepOnlyLastCommitDeletionPolicy, there would
+be only one commit point. But if you're using a custom
+IndexDeletionPolicy then there could be many commits. Once you have a
+given commit, you can open a reader on it by calling
+OpenDirectoryReaderFromCommit(). There must be at least one commit in
+the Directory, else this method returns an error.
+
+The returned commits are sorted from oldest to latest.
+*/
+func ListCommits(dir store.Directory) ([]IndexCommit, error) {
+	files, err := dir.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	latest := &SegmentInfos{}
+	if err = latest.ReadAll(dir); err != nil {
+		return nil, err
+	}
+	currentGen := latest.generation
+
+	commits := []IndexCommit{newReaderCommit(latest, dir)}
+
+	for _, fileName := range files {
+		if strings.HasPrefix(fileName, INDEX_FILENAME_SEGMENTS) &&
+			fileName != INDEX_FILENAME_SEGMENTS_GEN &&
+			GenerationFromSegmentsFileName(fileName) < currentGen {
+
+			sis := &SegmentInfos{}
+			// a segments_N file may be removed by a concurrent writer
+			// between listing and reading; just skip it then
+			if err := sis.Read(dir, fileName); err == nil {
+				commits = append(commits, newReaderCommit(sis, dir))
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+
+	util.TimSort(IndexCommits(commits))
+	return commits, nil
+}
+
 /*
 Returns true if an index likely exists at the specified directory. Note that
 if a corrupt index exists, or if an index in the process of committing
@@ -88,8 +176,9 @@ func IsIndexFileExists(files []string) bool {
 
 type StandardDirectoryReader struct {
 	*DirectoryReaderImpl
-	writer       *IndexWriter // NRT
-	segmentInfos *SegmentInfos
+	writer          *IndexWriter // NRT
+	segmentInfos    *SegmentInfos
+	applyAllDeletes bool
 }
 
 // TODO support IndexWriter
@@ -133,6 +222,92 @@ func openStandardDirectoryReader(directory store.Directory,
 	return obj.(*StandardDirectoryReader), err
 }
 
+/*
+Opens a reader for the given commit, reusing the readers of segments
+that did not change since oldReaders were opened.
+*/
+func openStandardDirectoryReaderReusing(directory store.Directory, infos *SegmentInfos,
+	oldReaders []IndexReader, termInfosIndexDivisor int) (r *StandardDirectoryReader, err error) {
+
+	// we put the old SegmentReaders in a map, that allows us
+	// to lookup a reader using its segment name
+	segmentReaders := make(map[string]*SegmentReader)
+	for _, old := range oldReaders {
+		if sr, ok := old.(*SegmentReader); ok {
+			segmentReaders[sr.si.Info.Name] = sr
+		}
+	}
+
+	newReaders := make([]AtomicReader, len(infos.Segments))
+	defer func() {
+		if err != nil {
+			// close all readers we opened or incRef'd so far
+			for _, nr := range newReaders {
+				if nr != nil {
+					util.CloseWhileSuppressingError(nr)
+				}
+			}
+		}
+	}()
+
+	for i := len(infos.Segments) - 1; i >= 0; i-- {
+		commitInfo := infos.Segments[i]
+		old, ok := segmentReaders[commitInfo.Info.Name]
+		if ok && old.si.DelGen() == commitInfo.DelGen() &&
+			old.si.FieldInfosGen() == commitInfo.FieldInfosGen() &&
+			old.TryIncRef() {
+			// segment is unchanged; share the old reader
+			newReaders[i] = old
+			continue
+		}
+		// TODO share core readers of segments whose deletes or
+		// doc values updates changed
+		var sr *SegmentReader
+		if sr, err = NewSegmentReader(commitInfo, termInfosIndexDivisor, store.IO_CONTEXT_READ); err != nil {
+			return nil, err
+		}
+		newReaders[i] = sr
+	}
+	return newStandardDirectoryReader(directory, newReaders, infos, termInfosIndexDivisor, false), nil
+}
+
+func (r *StandardDirectoryReader) doOpenIfChanged() (DirectoryReader, error) {
+	r.ensureOpen()
+	if r.writer != nil && !r.writer.isClosed() {
+		return r.doOpenFromWriter()
+	}
+	if r.IsCurrent() {
+		return nil, nil
+	}
+	obj, err := NewFindSegmentsFile(r.directory, func(segmentFileName string) (interface{}, error) {
+		sis := &SegmentInfos{}
+		if err := sis.Read(r.directory, segmentFileName); err != nil {
+			return nil, err
+		}
+		return openStandardDirectoryReaderReusing(r.directory, sis,
+			r.getSequentialSubReaders(), DEFAULT_TERMS_INDEX_DIVISOR)
+	}).run(nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*StandardDirectoryReader), nil
+}
+
+func (r *StandardDirectoryReader) doOpenFromWriter() (DirectoryReader, error) {
+	if r.writer.nrtIsCurrent(r.segmentInfos) {
+		return nil, nil
+	}
+	reader, err := r.writer.getReader(r.applyAllDeletes, r.getSequentialSubReaders())
+	if err != nil {
+		return nil, err
+	}
+	// if in fact no changes took place, return nil:
+	if reader.Version() == r.segmentInfos.version {
+		return nil, reader.decRef()
+	}
+	return reader, nil
+}
+
 func (r *StandardDirectoryReader) String() string {
 	var buf bytes.Buffer
 	buf.WriteString("StandardDirectoryReader(")
@@ -157,7 +332,9 @@ func (r *StandardDirectoryReader) Version() int64 {
 
 func (r *StandardDirectoryReader) IsCurrent() bool {
 	r.ensureOpen()
-	// if writer == nill || writer.IsClosed() {
+	if r.writer != nil && !r.writer.isClosed() {
+		return r.writer.nrtIsCurrent(r.segmentInfos)
+	}
 	// Fully read the segments file: this ensures that it's
 	// completely written so that if
 	// IndexWriter.prepareCommit has been called (but not
@@ -168,9 +345,14 @@ func (r *StandardDirectoryReader) IsCurrent() bool {
 
 	// we loaded SegmentInfos from the directory
 	return sis.version == r.segmentInfos.version
-	// } else {
-	// return writer.nrtIsCurrent(r.segmentInfos)
-	// }
+}
+
+/*
+Expert: return the IndexCommit that this reader has opened.
+*/
+func (r *StandardDirectoryReader) IndexCommit() IndexCommit {
+	r.ensureOpen()
+	return newReaderCommit(r.segmentInfos, r.directory)
 }
 
 func (r *StandardDirectoryReader) doClose() error {
@@ -192,10 +374,42 @@ func (r *StandardDirectoryReader) doClose() error {
 	}
 
 	if w := r.writer; w != nil {
-		panic("not implemented yet")
 		// Since we just closed, writer may now be able to delete unused files:
-		w.deletePendingFiles()
+		w.decRefDeleter(r.segmentInfos)
 	}
 
 	return firstErr
 }
+
+type ReaderCommit struct {
+	segmentsFileName string
+	files            []string
+	dir              store.Directory
+	generation       int64
+	userData         map[string]string
+	segmentCount     int
+}
+
+func newReaderCommit(infos *SegmentInfos, dir store.Directory) *ReaderCommit {
+	return &ReaderCommit{
+		segmentsFileName: infos.SegmentsFileName(),
+		dir:              dir,
+		userData:         infos.userData,
+		files:            infos.files(dir, true),
+		generation:       infos.generation,
+		segmentCount:     len(infos.Segments),
+	}
+}
+
+func (c *ReaderCommit) String() string {
+	return fmt.Sprintf("DirectoryReader.ReaderCommit(%v)", c.segmentsFileName)
+}
+
+func (c *ReaderCommit) SegmentCount() int           { return c.segmentCount }
+func (c *ReaderCommit) SegmentsFileName() string    { return c.segmentsFileName }
+func (c *ReaderCommit) FileNames() []string         { return c.files }
+func (c *ReaderCommit) Directory() store.Directory  { return c.dir }
+func (c *ReaderCommit) Generation() int64           { return c.generation }
+func (c *ReaderCommit) IsDeleted() bool             { return false }
+func (c *ReaderCommit) UserData() map[string]string { return c.userData }
+func (c *ReaderCommit) Delete()                     { panic("This IndexCommit does not support deletions") }
@@ -337,6 +337,59 @@ func (dw *DocumentsWriter) updateDocument(doc []model.IndexableField,
 	return dw.postUpdate(flushingDWPT, hasEvents)
 }
 
+// L394
+func (dw *DocumentsWriter) updateDocuments(docs [][]model.IndexableField,
+	analyzer analysis.Analyzer, delTerm *Term) (bool, error) {
+
+	hasEvents, err := dw.preUpdate()
+	if err != nil {
+		return false, err
+	}
+
+	flushingDWPT, err := func() (*DocumentsWriterPerThread, error) {
+		perThread := dw.flushControl.obtainAndLock()
+		defer dw.flushControl.perThreadPool.release(perThread)
+
+		if !perThread.isActive {
+			dw.ensureOpen()
+			panic("perThread is not active but we are still open")
+		}
+		dw.ensureInitialized(perThread)
+		assert(perThread.dwpt != nil)
+		dwpt := perThread.dwpt
+		dwptNuMDocs := dwpt.numDocsInRAM
+
+		err := func() error {
+			defer func() {
+				// the docs of a failed block are still counted, as
+				// they're added (but marked deleted)
+				atomic.AddInt32(&dw.numDocsInRAM, int32(dwpt.numDocsInRAM-dwptNuMDocs))
+				if dwpt.checkAndResetHasAborted() {
+					if len(dwpt.filesToDelete) > 0 {
+						dw.putEvent(newDeleteNewFilesEvent(dwpt.filesToDelete))
+					}
+					dw.subtractFlushedNumDocs(dwptNuMDocs)
+					dw.flushControl.doOnAbort(perThread)
+				}
+			}()
+
+			_, err := dwpt.updateDocuments(docs, analyzer, delTerm)
+			return err
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		isUpdate := delTerm != nil
+		return dw.flushControl.doAfterDocument(perThread, isUpdate), nil
+	}()
+	if err != nil {
+		return false, err
+	}
+
+	return dw.postUpdate(flushingDWPT, hasEvents)
+}
+
 func (dw *DocumentsWriter) doFlush(flushingDWPT *DocumentsWriterPerThread) (bool, error) {
 	var hasEvents = false
 	for flushingDWPT != nil {
@@ -120,6 +120,9 @@ func newDocumentsWriterPerThread(segmentName string,
 	}
 	ans.docState = newDocState(ans, infoStream)
 	ans.docState.similarity = indexWriterConfig.Similarity()
+	if schema := indexWriterConfig.Schema(); schema != nil {
+		ans.docState.similarity = schema.Similarity(ans.docState.similarity)
+	}
 	assert2(ans.numDocsInRAM == 0, "num docs %v", ans.numDocsInRAM)
 	if DWPT_VERBOSE && infoStream.IsEnabled("DWPT") {
 		infoStream.Message("DWPT", "init seg=%v delQueue=%v", segmentName, deleteQueue)
@@ -228,11 +231,71 @@ func (dwpt *DocumentsWriterPerThread) updateDocument(doc []IndexableField,
 	return nil
 }
 
-func (dwpt *DocumentsWriterPerThread) updateDocuments(doc []IndexableField,
-	analyzer analysis.Analyzer, delTerm *Term) error {
+func (dwpt *DocumentsWriterPerThread) updateDocuments(docs [][]IndexableField,
+	analyzer analysis.Analyzer, delTerm *Term) (docCount int, err error) {
 
-	dwpt.testPoint("DocumentsWriterPerThread addDocument start")
-	panic("not implemented yet")
+	dwpt.testPoint("DocumentsWriterPerThread addDocuments start")
+	assert(dwpt.deleteQueue != nil)
+	dwpt.docState.analyzer = analyzer
+	if DWPT_VERBOSE && dwpt.infoStream.IsEnabled("DWPT") {
+		dwpt.infoStream.Message("DWPT", "update delTerm=%v docID=%v seg=%v ",
+			delTerm, dwpt.docState.docID, dwpt.segmentInfo.Name)
+	}
+	var allDocsIndexed = false
+	defer func() {
+		if !allDocsIndexed && !dwpt.aborting {
+			// a document hit a non-aborting error; go and mark all docs
+			// from this block as deleted
+			docID := dwpt.numDocsInRAM - 1
+			endDocID := docID - docCount
+			for docID > endDocID {
+				dwpt.deleteDocID(docID)
+				docID--
+			}
+		}
+		dwpt.docState.clear()
+	}()
+
+	for _, doc := range docs {
+		// Even on error, the document is still added (but marked
+		// deleted), so we don't need to un-reserve at that point.
+		dwpt.reserveDoc()
+		dwpt.docState.doc = doc
+		dwpt.docState.docID = dwpt.numDocsInRAM
+		docCount++
+
+		if err = func() error {
+			var success = false
+			defer func() {
+				if !success {
+					if !dwpt.aborting {
+						// incr here because finishDocument will not be called
+						dwpt.numDocsInRAM++
+					} else {
+						dwpt.abort(dwpt.filesToDelete)
+					}
+				}
+			}()
+			if err := dwpt.consumer.processDocument(); err != nil {
+				return err
+			}
+			success = true
+			return nil
+		}(); err != nil {
+			return
+		}
+		dwpt.finishDocument(nil)
+	}
+	allDocsIndexed = true
+
+	// Apply delTerm only after all indexing has succeeded, but apply it
+	// only to docs prior to when this batch started:
+	if delTerm != nil {
+		dwpt.deleteQueue.add(delTerm, dwpt.deleteSlice)
+		assertn(dwpt.deleteSlice.isTailItem(delTerm), "expected the delete term as the tail item")
+		dwpt.deleteSlice.apply(dwpt.pendingUpdates, dwpt.numDocsInRAM-docCount)
+	}
+	return
 }
 
 func (w *DocumentsWriterPerThread) finishDocument(delTerm *Term) {
@@ -163,7 +163,7 @@ func newIndexFileDeleter(directory store.Directory, policy IndexDeletionPolicy,
 						// aborted "future" commit, so suppress exc in this case
 						sis = nil
 					} else { // sis != nil
-						commitPoint := newCommitPoint(fd.commitsToDelete, directory, sis)
+						commitPoint := newCommitPoint(&fd.commitsToDelete, directory, sis)
 						if sis.generation == segmentInfos.generation {
 							currentCommitPoint = commitPoint
 						}
@@ -196,7 +196,7 @@ func newIndexFileDeleter(directory store.Directory, policy IndexDeletionPolicy,
 			infoStream.Message("IFD", "forced open of current segments file %v",
 				segmentInfos.SegmentsFileName())
 		}
-		currentCommitPoint = newCommitPoint(fd.commitsToDelete, directory, sis)
+		currentCommitPoint = newCommitPoint(&fd.commitsToDelete, directory, sis)
 		fd.commits = append(fd.commits, currentCommitPoint)
 		fd.incRef(sis, true)
 	}
@@ -314,13 +314,13 @@ func (fd *IndexFileDeleter) deleteCommits() {
 
 		// Now compact commits to remove deleted ones (preserving the sort):
 		var writeTo = 0
-		for readFrom, commit := range fd.commits {
-			if !commit.IsDeleted() && readFrom != writeTo {
+		for _, commit := range fd.commits {
+			if !commit.IsDeleted() {
 				fd.commits[writeTo] = commit
 				writeTo++
 			}
 		}
-		for i, _ := range fd.commits[writeTo:] {
+		for i := writeTo; i < len(fd.commits); i++ {
 			fd.commits[i] = nil
 		}
 		fd.commits = fd.commits[:writeTo]
@@ -367,6 +367,29 @@ func (fd *IndexFileDeleter) refresh(segmentName string) error {
 	return nil
 }
 
+/*
+Revisits the IndexDeletionPolicy by calling its onCommit() again with
+the known commits. This is useful in cases where a deletion policy
+which holds onto index commits is used. The application may know that
+some commits are not held by the deletion policy anymore and call
+IndexWriter.DeleteUnusedFiles(), which will attempt to delete the
+unused commits again.
+*/
+func (fd *IndexFileDeleter) revisitPolicy() error {
+	// assert locked()
+	if fd.infoStream.IsEnabled("IFD") {
+		fd.infoStream.Message("IFD", "now revisitPolicy")
+	}
+
+	if len(fd.commits) > 0 {
+		if err := fd.policy.onCommit(fd.commits); err != nil {
+			return err
+		}
+		fd.deleteCommits()
+	}
+	return nil
+}
+
 func (fd *IndexFileDeleter) refreshList() error {
 	// set to nil so that we regenerate the list of pending files;
 	// else we can accumulate some file more than once
@@ -446,7 +469,7 @@ func (fd *IndexFileDeleter) checkpoint(segmentInfos *SegmentInfos, isCommit bool
 
 	if isCommit {
 		// Append to our commits list:
-		fd.commits = append(fd.commits, newCommitPoint(fd.commitsToDelete, fd.directory, segmentInfos))
+		fd.commits = append(fd.commits, newCommitPoint(&fd.commitsToDelete, fd.directory, segmentInfos))
 
 		// Tell policy so it can remove commits:
 		err := fd.policy.onCommit(fd.commits)
@@ -644,13 +667,13 @@ type CommitPoint struct {
 	segmentsFileName string
 	deleted          bool
 	directory        store.Directory
-	commitsToDelete  []*CommitPoint
+	commitsToDelete  *[]*CommitPoint // shared with the IndexFileDeleter
 	generation       int64
 	userData         map[string]string
 	segmentCount     int
 }
 
-func newCommitPoint(commitsToDelete []*CommitPoint, directory store.Directory,
+func newCommitPoint(commitsToDelete *[]*CommitPoint, directory store.Directory,
 	segmentInfos *SegmentInfos) *CommitPoint {
 	return &CommitPoint{
 		directory:        directory,
@@ -694,7 +717,7 @@ func (cp *CommitPoint) UserData() map[string]string {
 func (cp *CommitPoint) Delete() {
 	if !cp.deleted {
 		cp.deleted = true
-		cp.commitsToDelete = append(cp.commitsToDelete, cp)
+		*cp.commitsToDelete = append(*cp.commitsToDelete, cp)
 	}
 }
 
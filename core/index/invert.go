@@ -36,6 +36,21 @@ func newFieldInvertState(name string) *FieldInvertState {
 	return &FieldInvertState{name: name}
 }
 
+/*
+Creates FieldInvertState for the specified field name and values for
+all fields.
+*/
+func NewFieldInvertState(name string, position, length, numOverlap, offset int, boost float32) *FieldInvertState {
+	return &FieldInvertState{
+		name:       name,
+		position:   position,
+		length:     length,
+		numOverlap: numOverlap,
+		offset:     offset,
+		boost:      boost,
+	}
+}
+
 /* Re-initialize the state */
 func (st *FieldInvertState) reset() {
 	st.position = -1
@@ -56,8 +71,11 @@ func (st *FieldInvertState) setAttributeSource(attributeSource *util.AttributeSo
 		st.termAttribute = attributeSource.Get("TermToBytesRefAttribute").(TermToBytesRefAttribute)
 		st.posIncrAttribute = attributeSource.Add("PositionIncrementAttribute").(PositionIncrementAttribute)
 		st.offsetAttribute = attributeSource.Add("OffsetAttribute").(OffsetAttribute)
-		// st.payloadAttribute = attributeSource.Get("PayloadAttribute").(PayloadAttribute)
-		st.payloadAttribute = nil
+		if attributeSource.Has("PayloadAttribute") {
+			st.payloadAttribute = attributeSource.Get("PayloadAttribute").(PayloadAttribute)
+		} else {
+			st.payloadAttribute = nil
+		}
 	}
 }
 
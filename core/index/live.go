@@ -29,6 +29,8 @@ type LiveIndexWriterConfig interface {
 	InfoStream() util.InfoStream
 	indexerThreadPool() *DocumentsWriterPerThreadPool
 	UseCompoundFile() bool
+	WriteLockTimeout() int64
+	Schema() *Schema
 }
 
 type LiveIndexWriterConfigImpl struct {
@@ -94,6 +96,9 @@ type LiveIndexWriterConfigImpl struct {
 
 	// True if merging should check integrity of segments before merge
 	checkIntegrityAtMerge bool // volatile
+
+	// Optional declaration of the fields documents may have.
+	schema *Schema
 }
 
 // used by IndexWriterConfig
@@ -245,6 +250,11 @@ func (conf *LiveIndexWriterConfigImpl) Similarity() Similarity {
 	return conf.similarity
 }
 
+/* Returns the Schema documents are validated against, or nil. */
+func (conf *LiveIndexWriterConfigImpl) Schema() *Schema {
+	return conf.schema
+}
+
 /* Returns the current Codec. */
 func (conf *LiveIndexWriterConfigImpl) Codec() Codec {
 	return conf.codec
@@ -256,6 +266,19 @@ func (conf *LiveIndexWriterConfigImpl) MergePolicy() MergePolicy {
 	return conf.mergePolicy
 }
 
+/*
+Returns the IndexCommit as specified in SetIndexCommit() or the
+default, nil which specifies to open the latest index commit point.
+*/
+func (conf *LiveIndexWriterConfigImpl) IndexCommit() IndexCommit {
+	return conf.commit
+}
+
+/* Returns the IndexDeletionPolicy specified in SetIndexDeletionPolicy(). */
+func (conf *LiveIndexWriterConfigImpl) IndexDeletionPolicy() IndexDeletionPolicy {
+	return conf.delPolicy
+}
+
 /* Returns the configured DocumentsWriterPerThreadPool instance. */
 func (conf *LiveIndexWriterConfigImpl) indexerThreadPool() *DocumentsWriterPerThreadPool {
 	return conf._indexerThreadPool
@@ -297,6 +320,14 @@ func (conf *LiveIndexWriterConfigImpl) UseCompoundFile() bool {
 	return conf.useCompoundFile
 }
 
+/*
+Returns allowed timeout when acquiring the write lock, in
+milliseconds.
+*/
+func (conf *LiveIndexWriterConfigImpl) WriteLockTimeout() int64 {
+	return conf.writeLockTimeout
+}
+
 func (conf *LiveIndexWriterConfigImpl) String() string {
 	return fmt.Sprintf(`matchVersion=%v
 analyzer=%v
@@ -31,7 +31,7 @@ func NewFieldInfo(name string, indexed bool, number int32,
 	indexOptions IndexOptions, docValues, normsType DocValuesType,
 	dvGen int64, attributes map[string]string) *FieldInfo {
 
-	assert(indexOptions > 0)
+	assert(!indexed || indexOptions > 0)
 	assert(indexOptions <= INDEX_OPT_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS)
 
 	fi := &FieldInfo{Name: name, indexed: indexed, Number: number, docValueType: docValues}
@@ -100,8 +100,16 @@ func (info *FieldInfo) update(indexed, storeTermVector, omitNorms, storePayloads
 	info.checkConsistency()
 }
 
+/* Called by the indexing chain once any payload was seen for this field. */
+func (info *FieldInfo) SetStorePayloads() {
+	if info.indexed && info.indexOptions >= INDEX_OPT_DOCS_AND_FREQS_AND_POSITIONS {
+		info.storePayloads = true
+	}
+	info.checkConsistency()
+}
+
 func (info *FieldInfo) SetDocValueType(v DocValuesType) {
-	assert2(int(info.docValueType) != 0 && info.docValueType != v,
+	assert2(int(info.docValueType) == 0 || info.docValueType == v,
 		"cannot change DocValues type from %v to %v for field '%v'",
 		info.docValueType, v, info.Name)
 	info.docValueType = v
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
@@ -15,6 +16,8 @@ type FieldInfos struct {
 	HasVectors   bool
 	HasNorms     bool
 	HasDocValues bool
+	// True if any field has dense vectors (not term vectors)
+	HasVectorValues bool
 
 	byNumber map[int32]*FieldInfo
 	byName   map[string]*FieldInfo
@@ -44,6 +47,7 @@ func NewFieldInfos(infos []*FieldInfo) FieldInfos {
 		self.HasNorms = self.HasNorms || info.normType != 0
 		self.HasDocValues = self.HasDocValues || info.docValueType != 0
 		self.HasPayloads = self.HasPayloads || info.storePayloads
+		self.HasVectorValues = self.HasVectorValues || info.HasVectorValues()
 	}
 
 	sort.Sort(Int32Slice(numbers))
@@ -81,8 +85,9 @@ hasOffsets = %v
 hasVectors = %v
 hasNorms = %v
 hasDocValues = %v
+hasVectorValues = %v
 %v`, fis.HasFreq, fis.HasProx, fis.HasPayloads, fis.HasOffsets,
-		fis.HasVectors, fis.HasNorms, fis.HasDocValues, fis.Values)
+		fis.HasVectors, fis.HasNorms, fis.HasDocValues, fis.HasVectorValues, fis.Values)
 }
 
 type FieldNumbers struct {
@@ -92,6 +97,8 @@ type FieldNumbers struct {
 	// We use this to enforce that a given field never changes DV type,
 	// even across segments / IndexWriter sessions:
 	docValuesType map[string]DocValuesType
+	// Same for the dimension and similarity function of dense vectors:
+	vectorAttributes map[string]vectorAttributes
 	// TODO: we should similarly catch an attempt to turn norms back on
 	// after they were already ommitted; today we silently discard the
 	// norm but this is badly trappy
@@ -104,11 +111,16 @@ func NewFieldNumbers() *FieldNumbers {
 		nameToNumber:                make(map[string]int),
 		numberToName:                make(map[int]string),
 		docValuesType:               make(map[string]DocValuesType),
+		vectorAttributes:            make(map[string]vectorAttributes),
 		lowestUnassignedFieldNumber: -1,
 	}
 }
 
 func (fn *FieldNumbers) AddOrGet(info *FieldInfo) int {
+	if info.HasVectorValues() {
+		err := fn.setVectorAttributes(info.Name, info.VectorDimension(), info.VectorSimilarity())
+		assert2(err == nil, "%v", err)
+	}
 	return fn.addOrGet(info.Name, int(info.Number), info.docValueType)
 }
 
@@ -155,6 +167,48 @@ func (fn *FieldNumbers) addOrGet(name string, preferredNumber int, dv DocValuesT
 	return number
 }
 
+/*
+Sets the DocValuesType of the given field, verifying it's consistent
+with what was previously recorded.
+*/
+func (fn *FieldNumbers) setDocValuesType(number int, name string, dv DocValuesType) {
+	fn.Lock()
+	defer fn.Unlock()
+
+	assert2(fn.nameToNumber[name] == number,
+		"field number %v for field '%v' is inconsistent", number, name)
+	if currentDv, ok := fn.docValuesType[name]; ok && currentDv != 0 {
+		assert2(currentDv == dv,
+			"cannot change DocValues type from %v to %v for field '%v'",
+			currentDv, dv, name)
+	}
+	fn.docValuesType[name] = dv
+}
+
+type vectorAttributes struct {
+	dimension int
+	sim       VectorSimilarityFunction
+}
+
+/*
+Records the dimension and similarity function of the dense vectors of
+the given field, returning an error if they're inconsistent with what
+was previously recorded.
+*/
+func (fn *FieldNumbers) setVectorAttributes(name string, dimension int, sim VectorSimilarityFunction) error {
+	fn.Lock()
+	defer fn.Unlock()
+
+	attrs := vectorAttributes{dimension, sim}
+	if current, ok := fn.vectorAttributes[name]; ok && current != attrs {
+		return errors.New(fmt.Sprintf(
+			"cannot change vector dimension/similarity from %v/%v to %v/%v for field '%v'",
+			current.dimension, current.sim, dimension, sim, name))
+	}
+	fn.vectorAttributes[name] = attrs
+	return nil
+}
+
 type FieldInfosBuilder struct {
 	byName             map[string]*FieldInfo
 	globalFieldNumbers *FieldNumbers
@@ -200,7 +254,19 @@ func (b *FieldInfosBuilder) addOrUpdateInternal(name string,
 	docValues DocValuesType, normType DocValuesType) *FieldInfo {
 
 	if fi, ok := b.byName[name]; ok {
-		panic("not implemented yet")
+		fi.update(isIndexed, storeTermVector, omitNorms, storePayloads, indexOptions)
+		if docValues != 0 {
+			// only pay the synchronization cost if fi does not already
+			// have a DVType
+			updateGlobal := !fi.HasDocValues()
+			fi.SetDocValueType(docValues) // this will also perform the consistency check.
+			if updateGlobal {
+				b.globalFieldNumbers.setDocValuesType(int(fi.Number), name, docValues)
+			}
+		}
+		if !fi.omitNorms && normType != 0 {
+			fi.SetNormValueType(normType)
+		}
 		return fi
 	} else {
 		// This field wasn't yet added to this in-RAM segment's
@@ -215,6 +281,39 @@ func (b *FieldInfosBuilder) addOrUpdateInternal(name string,
 	}
 }
 
+/*
+Adds the given FieldInfo, or merges it into the FieldInfo already
+known under the same name. The field number is reused if possible, so
+field numbers stay consistent across segments. The dimension and
+similarity function of dense vectors are carried over too.
+*/
+func (b *FieldInfosBuilder) Add(fi *FieldInfo) *FieldInfo {
+	ans := b.addOrUpdateInternal(fi.Name, int(fi.Number), fi.IsIndexed(),
+		fi.HasVectors(), fi.OmitsNorms(), fi.HasPayloads(),
+		fi.IndexOptions(), fi.DocValuesType(), fi.NormType())
+	if fi.HasVectorValues() {
+		ans.SetVectorAttributes(fi.VectorDimension(), fi.VectorSimilarity())
+	}
+	return ans
+}
+
+/*
+Records the dimension and similarity function of the dense vectors of
+the given field, verifying they're consistent with those of the field
+in this and other segments.
+*/
+func (b *FieldInfosBuilder) SetVectorAttributes(fi *FieldInfo, dimension int, sim VectorSimilarityFunction) error {
+	if dimension <= 0 {
+		return errors.New(fmt.Sprintf(
+			"vector dimension must be > 0, got %v for field '%v'", dimension, fi.Name))
+	}
+	if err := b.globalFieldNumbers.setVectorAttributes(fi.Name, dimension, sim); err != nil {
+		return err
+	}
+	fi.SetVectorAttributes(dimension, sim)
+	return nil
+}
+
 func (b *FieldInfosBuilder) Finish() FieldInfos {
 	var infos []*FieldInfo
 	for _, v := range b.byName {
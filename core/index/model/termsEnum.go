@@ -33,7 +33,7 @@ type TermsEnum interface {
 	term was found, or EOF was hit. The target term may
 	be before or after the current term. If this returns
 	SeekStatus.END, then enum is unpositioned. */
-	SeekCeil(text []byte) SeekStatus
+	SeekCeil(text []byte) (SeekStatus, error)
 	/* Seeks to the specified term by ordinal (position) as
 	previously returned by ord. The target ord
 	may be before or after the current ord, and must be
@@ -126,7 +126,8 @@ func (e *TermsEnumImpl) Attributes() *util.AttributeSource {
 }
 
 func (e *TermsEnumImpl) SeekExact(text []byte) (ok bool, err error) {
-	return e.SeekCeil(text) == SEEK_STATUS_FOUND, nil
+	status, err := e.SeekCeil(text)
+	return status == SEEK_STATUS_FOUND, err
 }
 
 func (e *TermsEnumImpl) SeekExactFromLast(text []byte, state TermState) error {
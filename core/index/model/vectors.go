@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// index/VectorSimilarityFunction.java
+
+/*
+Vector similarity function, used to compare dense vectors of a field
+in kNN search. Scores are always non-negative, and higher scores mean
+nearer vectors.
+*/
+type VectorSimilarityFunction int
+
+const (
+	// 1 / (1 + squared euclidean distance)
+	VECTOR_SIMILARITY_EUCLIDEAN = VectorSimilarityFunction(1)
+	// (1 + dot product) / 2; vectors are expected to be of unit length
+	VECTOR_SIMILARITY_DOT_PRODUCT = VectorSimilarityFunction(2)
+	// (1 + cosine) / 2
+	VECTOR_SIMILARITY_COSINE = VectorSimilarityFunction(3)
+)
+
+/* Returns the similarity score of two vectors of the same dimension. */
+func (f VectorSimilarityFunction) Compare(v1, v2 []float32) float32 {
+	assert2(len(v1) == len(v2), "vector dimensions differ: %v != %v", len(v1), len(v2))
+	switch f {
+	case VECTOR_SIMILARITY_EUCLIDEAN:
+		var sum float32
+		for i, v := range v1 {
+			diff := v - v2[i]
+			sum += diff * diff
+		}
+		return 1 / (1 + sum)
+	case VECTOR_SIMILARITY_DOT_PRODUCT:
+		var dot float32
+		for i, v := range v1 {
+			dot += v * v2[i]
+		}
+		return float32(math.Max(0, float64(1+dot)/2))
+	case VECTOR_SIMILARITY_COSINE:
+		var dot, norm1, norm2 float64
+		for i, v := range v1 {
+			dot += float64(v) * float64(v2[i])
+			norm1 += float64(v) * float64(v)
+			norm2 += float64(v2[i]) * float64(v2[i])
+		}
+		if norm1 == 0 || norm2 == 0 {
+			return 0.5
+		}
+		return float32((1 + dot/math.Sqrt(norm1*norm2)) / 2)
+	}
+	panic(fmt.Sprintf("unknown vector similarity function: %v", int(f)))
+}
+
+func (f VectorSimilarityFunction) String() string {
+	switch f {
+	case VECTOR_SIMILARITY_EUCLIDEAN:
+		return "EUCLIDEAN"
+	case VECTOR_SIMILARITY_DOT_PRODUCT:
+		return "DOT_PRODUCT"
+	case VECTOR_SIMILARITY_COSINE:
+		return "COSINE"
+	}
+	return fmt.Sprintf("VectorSimilarityFunction(%v)", int(f))
+}
+
+// index/VectorValues.java
+
+/*
+Random access to the dense vectors of a field in a segment. Vectors
+are numbered by ordinal, from 0 to Size()-1, in increasing docID order.
+*/
+type VectorValues interface {
+	// Dimension of the vectors
+	Dimension() int
+	// Number of documents having a vector
+	Size() int
+	// Returns the docID of the vector of the given ordinal
+	Doc(ord int) int
+	// Returns the vector of the given ordinal, which must not be modified
+	Vector(ord int) []float32
+}
+
+/*
+Implemented by fields holding a dense vector to index for kNN search.
+A document can hold at most one vector per field, and all vectors of
+a field must share their dimension and similarity function.
+*/
+type IndexableVectorField interface {
+	IndexableField
+	VectorValue() []float32
+	VectorSimilarity() VectorSimilarityFunction
+}
+
+/*
+Attributes keeping the dimension and similarity function of a field
+with dense vectors in its FieldInfo.
+*/
+const (
+	VECTOR_DIMENSION_KEY  = "KnnVector.dimension"
+	VECTOR_SIMILARITY_KEY = "KnnVector.similarity"
+)
+
+/* Returns the dimension of the field's vectors, or 0 if it has none. */
+func (info *FieldInfo) VectorDimension() int {
+	n, _ := strconv.Atoi(info.Attribute(VECTOR_DIMENSION_KEY))
+	return n
+}
+
+/* Returns the similarity function of the field's vectors, or 0 if it has none. */
+func (info *FieldInfo) VectorSimilarity() VectorSimilarityFunction {
+	n, _ := strconv.Atoi(info.Attribute(VECTOR_SIMILARITY_KEY))
+	return VectorSimilarityFunction(n)
+}
+
+/* Returns true if this field has dense vectors. */
+func (info *FieldInfo) HasVectorValues() bool {
+	return info.VectorDimension() > 0
+}
+
+/*
+Records the dimension and similarity function of the field's vectors,
+verifying they are consistent with what was previously recorded.
+*/
+func (info *FieldInfo) SetVectorAttributes(dimension int, sim VectorSimilarityFunction) {
+	assert2(dimension > 0, "vector dimension must be > 0, got %v for field '%v'", dimension, info.Name)
+	if current := info.VectorDimension(); current != 0 {
+		assert2(current == dimension && info.VectorSimilarity() == sim,
+			"cannot change vector dimension/similarity from %v/%v to %v/%v for field '%v'",
+			current, info.VectorSimilarity(), dimension, sim, info.Name)
+		return
+	}
+	info.PutAttribute(VECTOR_DIMENSION_KEY, strconv.Itoa(dimension))
+	info.PutAttribute(VECTOR_SIMILARITY_KEY, strconv.Itoa(int(sim)))
+}
@@ -0,0 +1,290 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/codec"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/core/util"
+	"strconv"
+	"strings"
+)
+
+// index/PersistentSnapshotDeletionPolicy.java
+
+/* Prefix used for the save file. */
+const SNAPSHOTS_PREFIX = "snapshots_"
+
+const (
+	SNAPSHOTS_CODEC_NAME      = "snapshots"
+	SNAPSHOTS_VERSION_START   = 0
+	SNAPSHOTS_VERSION_CURRENT = SNAPSHOTS_VERSION_START
+)
+
+/*
+A SnapshotDeletionPolicy which adds a persistence layer so that
+snapshots can be maintained across the life of an application. The
+snapshots are persisted in a Directory and are committed as soon as
+Snapshot() or Release() is called.
+
+NOTE: Sharing PersistentSnapshotDeletionPolicy instances that write
+to the same directory across IndexWriters will corrupt snapshots. You
+should make sure every IndexWriter has its own
+PersistentSnapshotDeletionPolicy and that they all write to a
+different Directory. It is OK to use the same Directory that holds
+the index.
+
+This class adds a Release(gen) method to release commits from a
+previous snapshot's generation.
+*/
+type PersistentSnapshotDeletionPolicy struct {
+	*SnapshotDeletionPolicy
+	// The index of the next file name to write.
+	nextWriteGen int64
+	dir          store.Directory
+}
+
+/*
+PersistentSnapshotDeletionPolicy wraps another IndexDeletionPolicy to
+enable flexible snapshotting, passing OPEN_MODE_CREATE_OR_APPEND by
+default.
+*/
+func NewPersistentSnapshotDeletionPolicy(primary IndexDeletionPolicy,
+	dir store.Directory) (*PersistentSnapshotDeletionPolicy, error) {
+	return NewPersistentSnapshotDeletionPolicyWithMode(primary, dir, OPEN_MODE_CREATE_OR_APPEND)
+}
+
+/*
+PersistentSnapshotDeletionPolicy wraps another IndexDeletionPolicy to
+enable flexible snapshotting.
+
+If mode is OPEN_MODE_CREATE, all prior snapshots in dir are removed;
+if mode is OPEN_MODE_APPEND, an error is returned when dir holds no
+prior snapshots.
+*/
+func NewPersistentSnapshotDeletionPolicyWithMode(primary IndexDeletionPolicy,
+	dir store.Directory, mode OpenMode) (*PersistentSnapshotDeletionPolicy, error) {
+
+	p := &PersistentSnapshotDeletionPolicy{
+		SnapshotDeletionPolicy: NewSnapshotDeletionPolicy(primary),
+		dir:                    dir,
+	}
+	if mode == OPEN_MODE_CREATE {
+		if err := p.clearPriorSnapshots(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.loadPriorSnapshots(); err != nil {
+		return nil, err
+	}
+	if mode == OPEN_MODE_APPEND && p.nextWriteGen == 0 {
+		return nil, errors.New("no snapshots stored in this directory")
+	}
+	return p, nil
+}
+
+/*
+Snapshots the last commit. Once this method returns, the snapshot
+information is persisted in the directory.
+*/
+func (p *PersistentSnapshotDeletionPolicy) Snapshot() (IndexCommit, error) {
+	p.Lock()
+	defer p.Unlock()
+	ic, err := p.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.persist(); err != nil {
+		p.releaseGen(ic.Generation())
+		return nil, err
+	}
+	return ic, nil
+}
+
+/*
+Deletes a snapshotted commit. Once this method returns, the snapshot
+information is persisted in the directory.
+*/
+func (p *PersistentSnapshotDeletionPolicy) Release(commit IndexCommit) error {
+	p.Lock()
+	defer p.Unlock()
+	if err := p.releaseGen(commit.Generation()); err != nil {
+		return err
+	}
+	if err := p.persist(); err != nil {
+		p.incRef(commit)
+		return err
+	}
+	return nil
+}
+
+/*
+Deletes a snapshotted commit by generation. Once this method returns,
+the snapshot information is persisted in the directory.
+*/
+func (p *PersistentSnapshotDeletionPolicy) ReleaseGen(gen int64) error {
+	p.Lock()
+	defer p.Unlock()
+	if err := p.releaseGen(gen); err != nil {
+		return err
+	}
+	return p.persist()
+}
+
+func (p *PersistentSnapshotDeletionPolicy) persist() (err error) {
+	fileName := fmt.Sprintf("%v%v", SNAPSHOTS_PREFIX, p.nextWriteGen)
+	out, err := p.dir.CreateOutput(fileName, store.IO_CONTEXT_DEFAULT)
+	if err != nil {
+		return err
+	}
+	if err = func() error {
+		if err := codec.WriteHeader(out, SNAPSHOTS_CODEC_NAME, SNAPSHOTS_VERSION_CURRENT); err != nil {
+			return err
+		}
+		if err := out.WriteVInt(int32(len(p.refCounts))); err != nil {
+			return err
+		}
+		for gen, refCount := range p.refCounts {
+			if err := out.WriteVLong(gen); err != nil {
+				return err
+			}
+			if err := out.WriteVInt(int32(refCount)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}(); err != nil {
+		util.CloseWhileSuppressingError(out)
+		util.DeleteFilesIgnoringErrors(p.dir, fileName)
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	if err = p.dir.Sync([]string{fileName}); err != nil {
+		return err
+	}
+
+	if p.nextWriteGen > 0 {
+		lastSaveFile := fmt.Sprintf("%v%v", SNAPSHOTS_PREFIX, p.nextWriteGen-1)
+		// Ignore error: we don't care if the file couldn't be deleted,
+		// the next load will clean it up
+		p.dir.DeleteFile(lastSaveFile)
+	}
+
+	p.nextWriteGen++
+	return nil
+}
+
+func (p *PersistentSnapshotDeletionPolicy) clearPriorSnapshots() error {
+	files, err := p.dir.ListAll()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if strings.HasPrefix(file, SNAPSHOTS_PREFIX) {
+			if err = p.dir.DeleteFile(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Returns the file name the snapshots are currently saved to, or "" if
+no snapshots have been saved.
+*/
+func (p *PersistentSnapshotDeletionPolicy) LastSaveFile() string {
+	if p.nextWriteGen == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v%v", SNAPSHOTS_PREFIX, p.nextWriteGen-1)
+}
+
+/*
+Reads the snapshots information from the given Directory. This method
+can be used if the snapshots information is needed, however you
+cannot instantiate the deletion policy (because e.g., some other
+process keeps a lock on the snapshots directory).
+*/
+func (p *PersistentSnapshotDeletionPolicy) loadPriorSnapshots() error {
+	files, err := p.dir.ListAll()
+	if err != nil {
+		return err
+	}
+	var genLoaded int64 = -1
+	var firstErr error
+	var snapshotFiles []string
+	for _, file := range files {
+		if !strings.HasPrefix(file, SNAPSHOTS_PREFIX) {
+			continue
+		}
+		gen, err := strconv.ParseInt(file[len(SNAPSHOTS_PREFIX):], 10, 64)
+		if err != nil {
+			continue
+		}
+		if genLoaded == -1 || gen > genLoaded {
+			snapshotFiles = append(snapshotFiles, file)
+			m, err := p.readSnapshots(file)
+			if err != nil {
+				// Save first error & return it in the end
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			genLoaded = gen
+			p.refCounts = m
+		}
+	}
+
+	if genLoaded == -1 {
+		// Nothing was loaded...
+		return firstErr
+	}
+	if len(snapshotFiles) > 1 {
+		// Remove any broken / old snapshot files:
+		curFileName := fmt.Sprintf("%v%v", SNAPSHOTS_PREFIX, genLoaded)
+		for _, file := range snapshotFiles {
+			if file != curFileName {
+				if err = p.dir.DeleteFile(file); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	p.nextWriteGen = genLoaded + 1
+	return nil
+}
+
+func (p *PersistentSnapshotDeletionPolicy) readSnapshots(file string) (m map[int64]int, err error) {
+	in, err := p.dir.OpenInput(file, store.IO_CONTEXT_DEFAULT)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = mergeError(err, in.Close())
+	}()
+	if _, err = codec.CheckHeader(in, SNAPSHOTS_CODEC_NAME, SNAPSHOTS_VERSION_START, SNAPSHOTS_VERSION_START); err != nil {
+		return nil, err
+	}
+	count, err := in.ReadVInt()
+	if err != nil {
+		return nil, err
+	}
+	m = make(map[int64]int)
+	for i := int32(0); i < count; i++ {
+		commitGen, err := in.ReadVLong()
+		if err != nil {
+			return nil, err
+		}
+		refCount, err := in.ReadVInt()
+		if err != nil {
+			return nil, err
+		}
+		m[commitGen] = int(refCount)
+	}
+	return m, nil
+}
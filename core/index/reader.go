@@ -16,6 +16,10 @@ import (
 
 type IndexReader interface {
 	io.Closer
+	IncRef()
+	TryIncRef() bool
+	DecRef() error
+	RefCount() int
 	decRef() error
 	ensureOpen()
 	registerParentReader(r IndexReader)
@@ -49,6 +53,9 @@ type IndexReader interface {
 	// Document returned here contains that class not
 	//model.IndexableField
 	Document(docID int) (doc *docu.Document, err error)
+	// Like Document() but only loads the specified fields. Note that
+	// this is simply sugar for DocumentStoredFieldVisitor.
+	DocumentFields(docID int, fieldNames ...string) (doc *docu.Document, err error)
 	doClose() error
 	Context() IndexReaderContext
 	Leaves() []*AtomicReaderContext
@@ -113,6 +120,56 @@ func (r *IndexReaderImpl) decRef() error {
 	return nil
 }
 
+/* Expert: returns the current refCount for this reader */
+func (r *IndexReaderImpl) RefCount() int {
+	// NOTE: don't ensureOpen, so that callers can see refCount is 0
+	// (reader is closed)
+	return int(atomic.LoadInt32(&r.refCount))
+}
+
+/*
+Expert: increments the refCount of this IndexReader instance.
+RefCounts are used to determine when a reader can be closed safely,
+i.e. as soon as there are no more references. Be sure to always call
+a corresponding DecRef(), in a defer block; otherwise the reader may
+never be closed. Note that Close() simply calls DecRef(), which means
+that the IndexReader will not really be closed until DecRef() has
+been called for all outstanding references.
+
+It panics if the reader is already closed.
+*/
+func (r *IndexReaderImpl) IncRef() {
+	if !r.TryIncRef() {
+		r.ensureOpen()
+	}
+}
+
+/*
+Expert: increments the refCount of this IndexReader instance only if
+the IndexReader has not been closed yet and returns true iff the
+refCount was successfully incremented, otherwise false. If this
+method returns false the reader is either already closed or is
+currently being closed. Either way this reader instance shouldn't be
+used by an application unless true is returned.
+*/
+func (r *IndexReaderImpl) TryIncRef() bool {
+	for count := atomic.LoadInt32(&r.refCount); count > 0; count = atomic.LoadInt32(&r.refCount) {
+		if atomic.CompareAndSwapInt32(&r.refCount, count, count+1) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Expert: decreases the refCount of this IndexReader instance. If the
+refCount drops to 0, then this reader is closed. If an error is hit,
+the refCount is unchanged.
+*/
+func (r *IndexReaderImpl) DecRef() error {
+	return r.decRef()
+}
+
 func (r *IndexReaderImpl) ensureOpen() {
 	if atomic.LoadInt32(&r.refCount) <= 0 {
 		panic("this IndexReader is closed")
@@ -184,6 +241,18 @@ func (r *IndexReaderImpl) Document(docID int) (doc *docu.Document, err error) {
 	return visitor.Document(), nil
 }
 
+/*
+Like Document() but only loads the specified fields. The other stored
+fields are skipped by the codec without being materialized.
+*/
+func (r *IndexReaderImpl) DocumentFields(docID int, fieldNames ...string) (doc *docu.Document, err error) {
+	visitor := docu.NewDocumentStoredFieldVisitorFor(fieldNames...)
+	if err = r.VisitDocument(docID, visitor); err != nil {
+		return nil, err
+	}
+	return visitor.Document(), nil
+}
+
 /*
 Returns true if any documents have been deleted. Implementers should
 consider overriding this method if maxDoc() or numDocs() are not
@@ -249,6 +318,16 @@ type ARFieldsReader interface {
 	 *  were indexed. The returned instance should only be
 	 *  used by a single thread. */
 	NormValues(field string) (ndv NumericDocValues, err error)
+	// Get the FieldInfos describing all fields in this reader.
+	FieldInfos() FieldInfos
+	// Returns the dense vectors of a field, or nil if it has none.
+	VectorValues(field string) VectorValues
+	// Returns the docIDs and scores of the (approximately) k nearest
+	// vectors of field to target, best first, among acceptDocs if it's
+	// not nil. At most visitedLimit vectors are compared to target;
+	// complete is false if the limit was hit before the search ended.
+	SearchNearestVectors(field string, target []float32, k int, acceptDocs util.Bits,
+		visitedLimit int) (docs []int, scores []float32, complete bool, err error)
 }
 
 type AtomicReader interface {
@@ -277,6 +356,47 @@ func newAtomicReader(spi AtomicReaderImplSPI) *AtomicReaderImpl {
 	return r
 }
 
+/*
+The part of an AtomicReader to be provided by implementations outside
+this package, e.g. readers over in-memory data, which are then wrapped
+by NewAtomicReader(). Such readers are not composite, have no
+deletions to track, and are closed with the returned reader, calling
+Close() on the SPI if it implements io.Closer.
+*/
+type AtomicReaderSPI interface {
+	NumDocs() int
+	MaxDoc() int
+	VisitDocument(docID int, visitor StoredFieldVisitor) error
+	ARFieldsReader
+}
+
+/* Returns a top-level AtomicReader reading through spi. */
+func NewAtomicReader(spi AtomicReaderSPI) *AtomicReaderImpl {
+	adapter := &atomicReaderAdapter{AtomicReaderSPI: spi}
+	adapter.owner = newAtomicReader(adapter)
+	return adapter.owner
+}
+
+type atomicReaderAdapter struct {
+	AtomicReaderSPI
+	owner *AtomicReaderImpl
+}
+
+func (r *atomicReaderAdapter) doClose() error {
+	if c, ok := r.AtomicReaderSPI.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (r *atomicReaderAdapter) Context() IndexReaderContext {
+	return r.owner.Context()
+}
+
+func (r *atomicReaderAdapter) DocFreq(term *Term) (int, error) {
+	return r.owner.DocFreq(term)
+}
+
 func (r *AtomicReaderImpl) Context() IndexReaderContext {
 	r.ensureOpen()
 	return r.readerContext
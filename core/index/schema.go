@@ -0,0 +1,346 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/analysis"
+	docu "github.com/jtejido/golucene/core/document"
+	. "github.com/jtejido/golucene/core/index/model"
+	"io"
+	"reflect"
+	"sort"
+)
+
+/*
+Optional declaration of the fields of an index. Once registered with
+IndexWriterConfig.SetSchema(), IndexWriter rejects documents with
+undeclared fields, or whose fields are indexed differently than
+declared, instead of silently merging the inconsistencies into
+FieldInfos. Declared analyzers and similarities are used in place of
+the writer's for their fields.
+
+A document field may carry only part of a declaration, e.g. a field
+declared indexed and stored may be added as an indexed field and a
+separate StoredField, but when indexed, it must be indexed exactly as
+declared.
+
+The schema is persisted in the commit user data under
+SCHEMA_COMMIT_KEY, so that readers and query parsers can discover it
+with ReadSchema(). A writer refuses to open an existing index whose
+persisted schema declares a field with another type. Analyzers and
+similarities are only recorded by their type names.
+*/
+type Schema struct {
+	fields map[string]*SchemaField
+}
+
+// Commit user data key of the persisted schema
+const SCHEMA_COMMIT_KEY = "schema"
+
+type SchemaField struct {
+	Name string
+	Type IndexableFieldType
+	// optional, the writer's (or query parser's) one is used if nil
+	Analyzer   analysis.Analyzer
+	Similarity Similarity
+	// type names, as persisted
+	AnalyzerName   string
+	SimilarityName string
+}
+
+func NewSchema() *Schema {
+	return &Schema{fields: make(map[string]*SchemaField)}
+}
+
+/*
+Declares field name with the given type. analyzer and similarity may
+be nil.
+*/
+func (s *Schema) AddField(name string, ft IndexableFieldType,
+	analyzer analysis.Analyzer, similarity Similarity) *Schema {
+
+	assert2(name != "", "field name must not be empty")
+	assert2(ft != nil, "field type must not be nil")
+	_, ok := s.fields[name]
+	assert2(!ok, "field %v is already declared", name)
+	f := &SchemaField{
+		Name:       name,
+		Type:       ft,
+		Analyzer:   analyzer,
+		Similarity: similarity,
+	}
+	if analyzer != nil {
+		f.AnalyzerName = reflect.TypeOf(analyzer).String()
+	}
+	if similarity != nil {
+		f.SimilarityName = reflect.TypeOf(similarity).String()
+	}
+	s.fields[name] = f
+	return s
+}
+
+/* Returns the declaration of field name, or nil if not declared. */
+func (s *Schema) Field(name string) *SchemaField {
+	return s.fields[name]
+}
+
+/* Returns the declared fields, sorted by name. */
+func (s *Schema) Fields() []*SchemaField {
+	ans := make([]*SchemaField, 0, len(s.fields))
+	for _, f := range s.fields {
+		ans = append(ans, f)
+	}
+	sort.Sort(schemaFieldsByName(ans))
+	return ans
+}
+
+type schemaFieldsByName []*SchemaField
+
+func (a schemaFieldsByName) Len() int           { return len(a) }
+func (a schemaFieldsByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a schemaFieldsByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+/*
+Checks all fields of doc are declared, and consistent with their
+declaration.
+*/
+func (s *Schema) Validate(doc []IndexableField) error {
+	for _, field := range doc {
+		declared, ok := s.fields[field.Name()]
+		if !ok {
+			return errors.New(fmt.Sprintf("field %v is not declared in the schema", field.Name()))
+		}
+		actual, expected := newSchemaFieldType(field.FieldType()), newSchemaFieldType(declared.Type)
+		if actual.Indexed {
+			if !expected.Indexed {
+				return errors.New(fmt.Sprintf("field %v is declared not indexed", field.Name()))
+			}
+			if actual.indexing() != expected.indexing() {
+				return errors.New(fmt.Sprintf(
+					"field %v is indexed as [%v], but declared as [%v]",
+					field.Name(), field.FieldType(), declared.Type))
+			}
+		}
+		if actual.Stored && !expected.Stored {
+			return errors.New(fmt.Sprintf("field %v is declared not stored", field.Name()))
+		}
+		if actual.DocValueType != 0 && actual.DocValueType != expected.DocValueType {
+			return errors.New(fmt.Sprintf(
+				"field %v has doc values type %v, but declared %v",
+				field.Name(), actual.DocValueType, expected.DocValueType))
+		}
+	}
+	return nil
+}
+
+/*
+Returns an Analyzer which delegates to the declared analyzers, and to
+defaultAnalyzer for the fields without one.
+*/
+func (s *Schema) Analyzer(defaultAnalyzer analysis.Analyzer) analysis.Analyzer {
+	return &schemaAnalyzer{s, defaultAnalyzer}
+}
+
+/*
+Returns a Similarity which delegates to the declared similarities,
+and to defaultSimilarity for the fields without one.
+*/
+func (s *Schema) Similarity(defaultSimilarity Similarity) Similarity {
+	return &schemaSimilarity{s, defaultSimilarity}
+}
+
+/*
+Checks each field declared in both schemas has the same type in
+both.
+*/
+func (s *Schema) checkCompatible(other *Schema) error {
+	for name, f := range s.fields {
+		if o, ok := other.fields[name]; ok {
+			if newSchemaFieldType(f.Type) != newSchemaFieldType(o.Type) {
+				return errors.New(fmt.Sprintf(
+					"field %v is declared as [%v], but as [%v] in the index",
+					name, f.Type, o.Type))
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) String() string {
+	return s.encode()
+}
+
+type schemaAnalyzer struct {
+	schema   *Schema
+	fallback analysis.Analyzer
+}
+
+func (a *schemaAnalyzer) analyzer(field string) analysis.Analyzer {
+	if f, ok := a.schema.fields[field]; ok && f.Analyzer != nil {
+		return f.Analyzer
+	}
+	return a.fallback
+}
+
+func (a *schemaAnalyzer) TokenStreamForReader(field string, reader io.RuneReader) (analysis.TokenStream, error) {
+	return a.analyzer(field).TokenStreamForReader(field, reader)
+}
+
+func (a *schemaAnalyzer) TokenStreamForString(field, text string) (analysis.TokenStream, error) {
+	return a.analyzer(field).TokenStreamForString(field, text)
+}
+
+func (a *schemaAnalyzer) PositionIncrementGap(field string) int {
+	return a.analyzer(field).PositionIncrementGap(field)
+}
+
+func (a *schemaAnalyzer) OffsetGap(field string) int {
+	return a.analyzer(field).OffsetGap(field)
+}
+
+type schemaSimilarity struct {
+	schema   *Schema
+	fallback Similarity
+}
+
+func (sim *schemaSimilarity) ComputeNorm(state *FieldInvertState) int64 {
+	if f, ok := sim.schema.fields[state.Name()]; ok && f.Similarity != nil {
+		return f.Similarity.ComputeNorm(state)
+	}
+	return sim.fallback.ComputeNorm(state)
+}
+
+// persistence
+
+/*
+Snapshot of an IndexableFieldType, comparable, and as persisted. It
+also serves as the type of the fields of a schema read back from a
+commit.
+*/
+type schemaFieldType struct {
+	Indexed                  bool             `json:"indexed,omitempty"`
+	Stored                   bool             `json:"stored,omitempty"`
+	Tokenized                bool             `json:"tokenized,omitempty"`
+	StoreTermVectors         bool             `json:"termVectors,omitempty"`
+	StoreTermVectorOffsets   bool             `json:"termVectorOffsets,omitempty"`
+	StoreTermVectorPositions bool             `json:"termVectorPositions,omitempty"`
+	StoreTermVectorPayloads  bool             `json:"termVectorPayloads,omitempty"`
+	OmitNorms                bool             `json:"omitNorms,omitempty"`
+	IndexOptions             IndexOptions     `json:"indexOptions,omitempty"`
+	NumericType              docu.NumericType `json:"numericType,omitempty"`
+	DocValueType             DocValuesType    `json:"docValuesType,omitempty"`
+}
+
+func newSchemaFieldType(ft IndexableFieldType) schemaFieldType {
+	ans := schemaFieldType{
+		Indexed:      ft.Indexed(),
+		Stored:       ft.Stored(),
+		DocValueType: ft.DocValueType(),
+	}
+	if ans.Indexed {
+		ans.Tokenized = ft.Tokenized()
+		ans.StoreTermVectors = ft.StoreTermVectors()
+		ans.StoreTermVectorOffsets = ft.StoreTermVectorOffsets()
+		ans.StoreTermVectorPositions = ft.StoreTermVectorPositions()
+		ans.StoreTermVectorPayloads = ft.StoreTermVectorPayloads()
+		ans.OmitNorms = ft.OmitNorms()
+		ans.IndexOptions = ft.IndexOptions()
+		if nt, ok := ft.(interface {
+			NumericType() docu.NumericType
+		}); ok {
+			ans.NumericType = nt.NumericType()
+		}
+	}
+	return ans
+}
+
+/* Returns the attributes which must be identical for indexed fields. */
+func (ft schemaFieldType) indexing() schemaFieldType {
+	ft.Stored, ft.DocValueType = false, 0
+	return ft
+}
+
+type persistedFieldType struct {
+	schemaFieldType
+}
+
+func (ft *persistedFieldType) Indexed() bool          { return ft.schemaFieldType.Indexed }
+func (ft *persistedFieldType) Stored() bool           { return ft.schemaFieldType.Stored }
+func (ft *persistedFieldType) Tokenized() bool        { return ft.schemaFieldType.Tokenized }
+func (ft *persistedFieldType) StoreTermVectors() bool { return ft.schemaFieldType.StoreTermVectors }
+func (ft *persistedFieldType) StoreTermVectorOffsets() bool {
+	return ft.schemaFieldType.StoreTermVectorOffsets
+}
+func (ft *persistedFieldType) StoreTermVectorPositions() bool {
+	return ft.schemaFieldType.StoreTermVectorPositions
+}
+func (ft *persistedFieldType) StoreTermVectorPayloads() bool {
+	return ft.schemaFieldType.StoreTermVectorPayloads
+}
+func (ft *persistedFieldType) OmitNorms() bool               { return ft.schemaFieldType.OmitNorms }
+func (ft *persistedFieldType) IndexOptions() IndexOptions    { return ft.schemaFieldType.IndexOptions }
+func (ft *persistedFieldType) NumericType() docu.NumericType { return ft.schemaFieldType.NumericType }
+func (ft *persistedFieldType) DocValueType() DocValuesType   { return ft.schemaFieldType.DocValueType }
+
+func (ft *persistedFieldType) String() string {
+	return fmt.Sprintf("%+v", ft.schemaFieldType)
+}
+
+type persistedSchemaField struct {
+	Name string `json:"name"`
+	schemaFieldType
+	Analyzer   string `json:"analyzer,omitempty"`
+	Similarity string `json:"similarity,omitempty"`
+}
+
+func (s *Schema) encode() string {
+	fields := s.Fields()
+	persisted := make([]persistedSchemaField, len(fields))
+	for i, f := range fields {
+		persisted[i] = persistedSchemaField{
+			Name:            f.Name,
+			schemaFieldType: newSchemaFieldType(f.Type),
+			Analyzer:        f.AnalyzerName,
+			Similarity:      f.SimilarityName,
+		}
+	}
+	data, err := json.Marshal(persisted)
+	assert2(err == nil, "cannot encode schema: %v", err)
+	return string(data)
+}
+
+func decodeSchema(data string) (*Schema, error) {
+	var persisted []persistedSchemaField
+	if err := json.Unmarshal([]byte(data), &persisted); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid schema: %v", err))
+	}
+	s := NewSchema()
+	for _, p := range persisted {
+		s.AddField(p.Name, &persistedFieldType{p.schemaFieldType}, nil, nil)
+		f := s.fields[p.Name]
+		f.AnalyzerName, f.SimilarityName = p.Analyzer, p.Similarity
+	}
+	return s, nil
+}
+
+/*
+Returns the schema persisted with the given commit user data, or nil
+if there is none.
+*/
+func SchemaFromCommitData(userData map[string]string) (*Schema, error) {
+	if data, ok := userData[SCHEMA_COMMIT_KEY]; ok {
+		return decodeSchema(data)
+	}
+	return nil, nil
+}
+
+/*
+Returns the schema persisted with commit, e.g. from
+DirectoryReader.IndexCommit(), or nil if the index has none.
+Declarations read back have no Analyzer nor Similarity, only their
+names.
+*/
+func ReadSchema(commit IndexCommit) (*Schema, error) {
+	return SchemaFromCommitData(commit.UserData())
+}
@@ -0,0 +1,113 @@
+package index_test
+
+import (
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search/similarities"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+type countingSimilarity struct {
+	index.Similarity
+	norms int
+}
+
+func (sim *countingSimilarity) ComputeNorm(state *index.FieldInvertState) int64 {
+	sim.norms++
+	return sim.Similarity.ComputeNorm(state)
+}
+
+func TestSchema(t *testing.T) {
+	dir := testindex.NewDirectory(t)
+
+	titleSim := &countingSimilarity{Similarity: similarities.NewDefaultSimilarity()}
+	schema := index.NewSchema().
+		AddField("id", docu.STRING_FIELD_TYPE_STORED, nil, nil).
+		AddField("title", docu.TEXT_FIELD_TYPE_STORED, nil, titleSim).
+		AddField("size", docu.STORED_FIELD_TYPE, nil, nil)
+	conf := testindex.NewConfig()
+	conf.SetSchema(schema)
+	w, err := index.NewIndexWriter(dir, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := docu.NewDocument()
+	d.Add(docu.NewFieldFromString("id", "1", docu.STRING_FIELD_TYPE_STORED))
+	d.Add(docu.NewTextFieldFromString("title", "a b", docu.STORE_YES))
+	d.Add(docu.NewStoredFieldFromLong("size", 42))
+	if err = w.AddDocument(d.Fields()); err != nil {
+		t.Fatal(err)
+	}
+	// a declared field may be indexed and stored separately
+	d = docu.NewDocument()
+	d.Add(docu.NewTextFieldFromString("title", "c", docu.STORE_NO))
+	d.Add(docu.NewStoredFieldFromString("title", "c"))
+	if err = w.AddDocument(d.Fields()); err != nil {
+		t.Fatal(err)
+	}
+	if titleSim.norms != 2 {
+		t.Errorf("expected the title similarity to be used twice, but %v", titleSim.norms)
+	}
+
+	for _, f := range []model.IndexableField{
+		docu.NewFieldFromString("unknown", "x", docu.STRING_FIELD_TYPE_NOT_STORED),
+		docu.NewFieldFromString("title", "x", docu.STRING_FIELD_TYPE_NOT_STORED), // not tokenized
+		docu.NewTextFieldFromString("id", "x", docu.STORE_NO),                    // tokenized
+		docu.NewTextFieldFromString("size", "x", docu.STORE_NO),                  // not indexed
+	} {
+		if err = w.AddDocument([]model.IndexableField{f}); err == nil {
+			t.Errorf("expected %v to be rejected", f)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := index.OpenDirectoryReader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if n := r.NumDocs(); n != 2 {
+		t.Errorf("expected 2 docs, but %v", n)
+	}
+	persisted, err := index.ReadSchema(r.IndexCommit())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted == nil {
+		t.Fatal("schema was not persisted")
+	}
+	if n := len(persisted.Fields()); n != 3 {
+		t.Errorf("expected 3 fields, but %v", n)
+	}
+	title := persisted.Field("title")
+	if title == nil || !title.Type.Indexed() || !title.Type.Tokenized() || !title.Type.Stored() {
+		t.Errorf("unexpected title declaration %v", title)
+	} else if title.SimilarityName != "*index_test.countingSimilarity" {
+		t.Errorf("unexpected similarity name %v", title.SimilarityName)
+	}
+	if id := persisted.Field("id"); id == nil || id.Type.Tokenized() {
+		t.Errorf("unexpected id declaration %v", id)
+	}
+
+	// a conflicting schema cannot open the index
+	conf = testindex.NewConfig()
+	conf.SetSchema(index.NewSchema().AddField("id", docu.TEXT_FIELD_TYPE_STORED, nil, nil))
+	if w, err = index.NewIndexWriter(dir, conf); err == nil {
+		w.Close()
+		t.Error("expected a conflicting schema to be rejected")
+	}
+	// while a compatible one can
+	conf = testindex.NewConfig()
+	conf.SetSchema(index.NewSchema().AddField("id", docu.STRING_FIELD_TYPE_STORED, nil, nil))
+	if w, err = index.NewIndexWriter(dir, conf); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -327,6 +327,25 @@ func (sis *SegmentInfos) SegmentsFileName() string {
 	return util.FileNameFromGeneration(util.SEGMENTS, "", sis.lastGeneration)
 }
 
+/* Returns current generation. */
+func (sis *SegmentInfos) Generation() int64 {
+	return sis.generation
+}
+
+/* Return userData saved with this commit. */
+func (sis *SegmentInfos) UserData() map[string]string {
+	return sis.userData
+}
+
+/* Sets the commit data. */
+func (sis *SegmentInfos) SetUserData(data map[string]string) {
+	if data == nil {
+		sis.userData = make(map[string]string)
+	} else {
+		sis.userData = data
+	}
+}
+
 func GenerationFromSegmentsFileName(fileName string) int64 {
 	switch {
 	case fileName == INDEX_FILENAME_SEGMENTS:
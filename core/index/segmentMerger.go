@@ -0,0 +1,642 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"github.com/jtejido/golucene/core/codec"
+	. "github.com/jtejido/golucene/core/codec/spi"
+	. "github.com/jtejido/golucene/core/index/model"
+	sm "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/core/util"
+	"sort"
+)
+
+// index/MergeState.java
+
+/* Holds common state used during segment merging. */
+type MergeState struct {
+	// SegmentInfo of the newly merged segment.
+	SegmentInfo *SegmentInfo
+	// FieldInfos of the newly merged segment.
+	FieldInfos FieldInfos
+	// Readers being merged.
+	Readers []AtomicReader
+	// Maps docIDs around deletions.
+	DocMaps []*DocMap
+	// New docID base per reader.
+	DocBase []int
+	// InfoStream for debugging messages.
+	InfoStream util.InfoStream
+	// Holds the CheckAbort instance, which is invoked periodically to
+	// see if the merge has been aborted.
+	checkAbort CheckAbort
+}
+
+func newMergeState(readers []AtomicReader, segmentInfo *SegmentInfo,
+	infoStream util.InfoStream, checkAbort CheckAbort) *MergeState {
+	return &MergeState{
+		SegmentInfo: segmentInfo,
+		Readers:     readers,
+		InfoStream:  infoStream,
+		checkAbort:  checkAbort,
+	}
+}
+
+/* Remaps docIDs around deletes during merge */
+type DocMap struct {
+	docMap  []int // nil if the reader has no deletions
+	maxDoc  int
+	numDocs int
+}
+
+/* Creates a DocMap instance appropriate for this reader. */
+func newDocMap(reader AtomicReader) *DocMap {
+	maxDoc := reader.MaxDoc()
+	liveDocs := reader.LiveDocs()
+	if liveDocs == nil {
+		return &DocMap{maxDoc: maxDoc, numDocs: maxDoc}
+	}
+	docMap := make([]int, maxDoc)
+	del := 0
+	for i := range docMap {
+		if liveDocs.At(i) {
+			docMap[i] = i - del
+		} else {
+			docMap[i] = -1
+			del++
+		}
+	}
+	return &DocMap{docMap, maxDoc, maxDoc - del}
+}
+
+/*
+Returns the mapped docID corresponding to the provided one, or -1 if
+it was deleted.
+*/
+func (m *DocMap) Get(docID int) int {
+	if m.docMap == nil {
+		return docID
+	}
+	return m.docMap[docID]
+}
+
+/* Returns the total number of documents, ignoring deletions. */
+func (m *DocMap) MaxDoc() int {
+	return m.maxDoc
+}
+
+/* Returns the number of not-deleted documents. */
+func (m *DocMap) NumDocs() int {
+	return m.numDocs
+}
+
+/* Returns true if there are any deletions. */
+func (m *DocMap) HasDeletions() bool {
+	return m.numDocs < m.maxDoc
+}
+
+// index/SegmentMerger.java
+
+/*
+Combines two or more segments, represented by AtomicReaders, into a
+single segment written through the codec of the target SegmentInfo.
+Deleted documents are dropped, and the docIDs of the others compacted.
+
+NOTE: doc values and term vectors are not merged yet and an error is
+returned if any reader has them. The graphs of dense vectors are
+rebuilt from the merged vectors.
+*/
+type SegmentMerger struct {
+	directory         store.Directory
+	termIndexInterval int
+	codec             Codec
+	context           store.IOContext
+	mergeState        *MergeState
+	fieldInfosBuilder *FieldInfosBuilder
+}
+
+func newSegmentMerger(readers []AtomicReader, segmentInfo *SegmentInfo,
+	infoStream util.InfoStream, dir store.Directory, termIndexInterval int,
+	checkAbort CheckAbort, fieldNumbers *FieldNumbers,
+	context store.IOContext) *SegmentMerger {
+
+	return &SegmentMerger{
+		directory:         dir,
+		termIndexInterval: termIndexInterval,
+		codec:             segmentInfo.Codec().(Codec),
+		context:           context,
+		mergeState:        newMergeState(readers, segmentInfo, infoStream, checkAbort),
+		fieldInfosBuilder: NewFieldInfosBuilder(fieldNumbers),
+	}
+}
+
+/* True if any merging should happen */
+func (m *SegmentMerger) shouldMerge() bool {
+	return m.mergeState.SegmentInfo.DocCount() > 0
+}
+
+/*
+Merges the readers into the directory passed to the constructor.
+Returns the MergeState describing the new segment.
+*/
+func (m *SegmentMerger) merge() (*MergeState, error) {
+	assert2(m.shouldMerge(), "Merge would result in 0 document segment")
+	m.mergeFieldInfos()
+	if m.mergeState.FieldInfos.HasDocValues {
+		return nil, errors.New("merging doc values is not supported yet")
+	}
+	if m.mergeState.FieldInfos.HasVectors {
+		return nil, errors.New("merging term vectors is not supported yet")
+	}
+
+	m.setDocMaps()
+
+	numMerged, err := m.mergeFields()
+	if err != nil {
+		return nil, err
+	}
+	assert2(numMerged == m.mergeState.SegmentInfo.DocCount(),
+		"numMerged=%v vs mergeState.segmentInfo.getDocCount()=%v",
+		numMerged, m.mergeState.SegmentInfo.DocCount())
+
+	segmentWriteState := NewSegmentWriteState(m.mergeState.InfoStream,
+		m.directory, m.mergeState.SegmentInfo, m.mergeState.FieldInfos,
+		m.termIndexInterval, nil, m.context)
+	if err = m.mergeTerms(segmentWriteState); err != nil {
+		return nil, err
+	}
+	if m.mergeState.FieldInfos.HasNorms {
+		if err = m.mergeNorms(segmentWriteState); err != nil {
+			return nil, err
+		}
+	}
+	if m.mergeState.FieldInfos.HasVectorValues {
+		if err = m.mergeVectors(segmentWriteState); err != nil {
+			return nil, err
+		}
+	}
+
+	// write the merged infos
+	infosWriter := m.codec.FieldInfosFormat().FieldInfosWriter()
+	err = infosWriter(m.directory, m.mergeState.SegmentInfo.Name, "",
+		m.mergeState.FieldInfos, m.context)
+	if err != nil {
+		return nil, err
+	}
+	return m.mergeState, nil
+}
+
+func (m *SegmentMerger) mergeFieldInfos() {
+	for _, reader := range m.mergeState.Readers {
+		for _, fi := range reader.FieldInfos().Values {
+			m.fieldInfosBuilder.Add(fi)
+		}
+	}
+	m.mergeState.FieldInfos = m.fieldInfosBuilder.Finish()
+}
+
+/* Computes the doc maps and doc bases of the readers. */
+func (m *SegmentMerger) setDocMaps() {
+	numReaders := len(m.mergeState.Readers)
+	m.mergeState.DocMaps = make([]*DocMap, numReaders)
+	m.mergeState.DocBase = make([]int, numReaders)
+	docBase := 0
+	for i, reader := range m.mergeState.Readers {
+		m.mergeState.DocBase[i] = docBase
+		docMap := newDocMap(reader)
+		m.mergeState.DocMaps[i] = docMap
+		docBase += docMap.NumDocs()
+	}
+}
+
+/* Merges stored fields; returns the number of documents merged. */
+func (m *SegmentMerger) mergeFields() (docCount int, err error) {
+	var fieldsWriter StoredFieldsWriter
+	if fieldsWriter, err = m.codec.StoredFieldsFormat().FieldsWriter(
+		m.directory, m.mergeState.SegmentInfo, m.context); err != nil {
+		return 0, err
+	}
+	var success = false
+	defer func() {
+		if success {
+			err = mergeError(err, util.Close(fieldsWriter))
+		} else {
+			util.CloseWhileSuppressingError(fieldsWriter)
+		}
+	}()
+
+	for _, reader := range m.mergeState.Readers {
+		maxDoc := reader.MaxDoc()
+		liveDocs := reader.LiveDocs()
+		for docID := 0; docID < maxDoc; docID++ {
+			if liveDocs != nil && !liveDocs.At(docID) {
+				// skip deleted docs
+				continue
+			}
+			doc, err := reader.Document(docID)
+			if err != nil {
+				return 0, err
+			}
+			if err = fieldsWriter.StartDocument(); err != nil {
+				return 0, err
+			}
+			for _, field := range doc.Fields() {
+				fi := m.mergeState.FieldInfos.FieldInfoByName(field.Name())
+				if err = fieldsWriter.WriteField(fi, field); err != nil {
+					return 0, err
+				}
+			}
+			if err = fieldsWriter.FinishDocument(); err != nil {
+				return 0, err
+			}
+			docCount++
+		}
+		if err = m.mergeState.checkAbort.work(300 * float64(maxDoc)); err != nil {
+			return 0, err
+		}
+	}
+	if err = fieldsWriter.Finish(m.mergeState.FieldInfos, docCount); err != nil {
+		return 0, err
+	}
+	success = true
+	return docCount, nil
+}
+
+func (m *SegmentMerger) mergeNorms(state *SegmentWriteState) (err error) {
+	var consumer DocValuesConsumer
+	if consumer, err = m.codec.NormsFormat().NormsConsumer(state); err != nil {
+		return err
+	}
+	var success = false
+	defer func() {
+		if success {
+			err = mergeError(err, util.Close(consumer))
+		} else {
+			util.CloseWhileSuppressingError(consumer)
+		}
+	}()
+
+	for _, fi := range m.mergeState.FieldInfos.Values {
+		if !fi.HasNorms() {
+			continue
+		}
+		norms := make([]NumericDocValues, len(m.mergeState.Readers))
+		for i, reader := range m.mergeState.Readers {
+			if norms[i], err = reader.NormValues(fi.Name); err != nil {
+				return err
+			}
+		}
+		if err = consumer.AddNumericField(fi, func() func() (interface{}, bool) {
+			return m.newNormsIterator(norms)
+		}); err != nil {
+			return err
+		}
+	}
+	success = true
+	return nil
+}
+
+/*
+Iterates over the norms of all live documents in doc order; readers
+without norms for the field contribute 0 for each of their documents.
+*/
+func (m *SegmentMerger) newNormsIterator(norms []NumericDocValues) func() (interface{}, bool) {
+	readerUpto, docIDUpto := 0, 0
+	return func() (interface{}, bool) {
+		for readerUpto < len(m.mergeState.Readers) {
+			reader := m.mergeState.Readers[readerUpto]
+			if docIDUpto >= reader.MaxDoc() {
+				readerUpto++
+				docIDUpto = 0
+				continue
+			}
+			if m.mergeState.DocMaps[readerUpto].Get(docIDUpto) != -1 {
+				break
+			}
+			docIDUpto++
+		}
+		if readerUpto == len(m.mergeState.Readers) {
+			return nil, false
+		}
+		var value int64
+		if norms[readerUpto] != nil {
+			value = norms[readerUpto](docIDUpto)
+		}
+		docIDUpto++
+		return value, true
+	}
+}
+
+func (m *SegmentMerger) mergeVectors(state *SegmentWriteState) (err error) {
+	format := KnnVectorsFormatOf(m.codec)
+	if format == nil {
+		return errors.New("codec " + m.codec.Name() + " does not support dense vectors")
+	}
+	var writer KnnVectorsWriter
+	if writer, err = format.VectorsWriter(state); err != nil {
+		return err
+	}
+	var success = false
+	defer func() {
+		if success {
+			err = mergeError(err, util.Close(writer))
+		} else {
+			util.CloseWhileSuppressingError(writer)
+		}
+	}()
+
+	for _, fi := range m.mergeState.FieldInfos.Values {
+		if !fi.HasVectorValues() {
+			continue
+		}
+		merged := &mergedVectorValues{dimension: fi.VectorDimension()}
+		for i, reader := range m.mergeState.Readers {
+			values := reader.VectorValues(fi.Name)
+			if values == nil {
+				continue
+			}
+			docMap := m.mergeState.DocMaps[i]
+			for ord := 0; ord < values.Size(); ord++ {
+				doc := docMap.Get(values.Doc(ord))
+				if doc == -1 {
+					// deleted
+					continue
+				}
+				merged.docs = append(merged.docs, m.mergeState.DocBase[i]+doc)
+				merged.vectors = append(merged.vectors, values.Vector(ord))
+			}
+		}
+		if merged.Size() == 0 {
+			continue
+		}
+		if err = writer.AddField(fi, merged); err != nil {
+			return err
+		}
+		if err = m.mergeState.checkAbort.work(300 * float64(merged.Size())); err != nil {
+			return err
+		}
+	}
+	success = true
+	return nil
+}
+
+/* The vectors of a field of all readers, in doc order. */
+type mergedVectorValues struct {
+	dimension int
+	docs      []int
+	vectors   [][]float32
+}
+
+func (v *mergedVectorValues) Dimension() int           { return v.dimension }
+func (v *mergedVectorValues) Size() int                { return len(v.docs) }
+func (v *mergedVectorValues) Doc(ord int) int          { return v.docs[ord] }
+func (v *mergedVectorValues) Vector(ord int) []float32 { return v.vectors[ord] }
+
+func (m *SegmentMerger) mergeTerms(state *SegmentWriteState) (err error) {
+	var consumer FieldsConsumer
+	if consumer, err = m.codec.PostingsFormat().FieldsConsumer(state); err != nil {
+		return err
+	}
+	var success = false
+	defer func() {
+		if success {
+			err = mergeError(err, util.Close(consumer))
+		} else {
+			util.CloseWhileSuppressingError(consumer)
+		}
+	}()
+
+	// the terms dictionary takes the fields in name order
+	var fields []*FieldInfo
+	for _, fi := range m.mergeState.FieldInfos.Values {
+		if fi.IsIndexed() {
+			fields = append(fields, fi)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	for _, fi := range fields {
+		if err = m.mergeField(fi, consumer); err != nil {
+			return err
+		}
+	}
+	success = true
+	return nil
+}
+
+/* Per reader state while merging the terms of one field. */
+type termsMergeSub struct {
+	termsEnum TermsEnum
+	term      []byte
+	liveDocs  util.Bits
+	docMap    *DocMap
+	docBase   int
+}
+
+/* Advances to the next term; returns false once exhausted. */
+func (sub *termsMergeSub) next() (bool, error) {
+	term, err := sub.termsEnum.Next()
+	if err != nil || term == nil {
+		return false, err
+	}
+	sub.term = append([]byte(nil), term...)
+	return true, nil
+}
+
+func (m *SegmentMerger) mergeField(fi *FieldInfo, consumer FieldsConsumer) error {
+	var subs []*termsMergeSub
+	for i, reader := range m.mergeState.Readers {
+		terms := reader.Terms(fi.Name)
+		if terms == nil {
+			continue
+		}
+		sub := &termsMergeSub{
+			termsEnum: terms.Iterator(nil),
+			liveDocs:  reader.LiveDocs(),
+			docMap:    m.mergeState.DocMaps[i],
+			docBase:   m.mergeState.DocBase[i],
+		}
+		ok, err := sub.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	termsConsumer, err := consumer.AddField(fi)
+	if err != nil {
+		return err
+	}
+	termComp := termsConsumer.Comparator()
+	writeTermFreq := fi.IndexOptions() >= INDEX_OPT_DOCS_AND_FREQS
+
+	visitedDocs := util.NewFixedBitSetOf(m.mergeState.SegmentInfo.DocCount())
+	sumTotalTermFreq := int64(0)
+	sumDocFreq := int64(0)
+
+	for len(subs) > 0 {
+		// Subs are kept in reader order, so the docIDs of the smallest
+		// term are delivered in increasing order:
+		term := subs[0].term
+		for _, sub := range subs[1:] {
+			if termComp(sub.term, term) {
+				term = sub.term
+			}
+		}
+
+		postingsConsumer, err := termsConsumer.StartTerm(term)
+		if err != nil {
+			return err
+		}
+		docFreq := 0
+		totalTermFreq := int64(0)
+		remaining := subs[:0]
+		for _, sub := range subs {
+			if bytes.Equal(sub.term, term) {
+				df, ttf, err := m.mergePostings(fi, sub, postingsConsumer, visitedDocs)
+				if err != nil {
+					return err
+				}
+				docFreq += df
+				totalTermFreq += ttf
+				ok, err := sub.next()
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			remaining = append(remaining, sub)
+		}
+		subs = remaining
+
+		if docFreq == 0 {
+			// all its documents were deleted
+			continue
+		}
+		if !writeTermFreq {
+			totalTermFreq = -1
+		}
+		if err = termsConsumer.FinishTerm(term, codec.NewTermStats(docFreq, totalTermFreq)); err != nil {
+			return err
+		}
+		sumTotalTermFreq += totalTermFreq
+		sumDocFreq += int64(docFreq)
+	}
+
+	if !writeTermFreq {
+		sumTotalTermFreq = -1
+	}
+	return termsConsumer.Finish(sumTotalTermFreq, sumDocFreq, visitedDocs.Cardinality())
+}
+
+/*
+Copies the postings of the sub's current term, skipping deleted
+documents, with docIDs remapped by the sub's doc map and doc base.
+*/
+func (m *SegmentMerger) mergePostings(fi *FieldInfo, sub *termsMergeSub,
+	postingsConsumer codec.PostingsConsumer, visitedDocs *util.FixedBitSet) (docFreq int, totalTermFreq int64, err error) {
+
+	indexOptions := fi.IndexOptions()
+	writeTermFreq := indexOptions >= INDEX_OPT_DOCS_AND_FREQS
+	writePositions := indexOptions >= INDEX_OPT_DOCS_AND_FREQS_AND_POSITIONS
+	writeOffsets := indexOptions >= INDEX_OPT_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+
+	if !writePositions {
+		flags := DOCS_ENUM_FLAG_NONE
+		if writeTermFreq {
+			flags = DOCS_ENUM_FLAG_FREQS
+		}
+		var docsEnum DocsEnum
+		if docsEnum, err = sub.termsEnum.DocsByFlags(sub.liveDocs, nil, flags); err != nil {
+			return
+		}
+		for {
+			var doc int
+			if doc, err = docsEnum.NextDoc(); err != nil || doc == sm.NO_MORE_DOCS {
+				return
+			}
+			doc = sub.docMap.Get(doc)
+			freq := -1
+			if writeTermFreq {
+				if freq, err = docsEnum.Freq(); err != nil {
+					return
+				}
+				totalTermFreq += int64(freq)
+			}
+			visitedDocs.Set(sub.docBase + doc)
+			if err = postingsConsumer.StartDoc(sub.docBase+doc, freq); err != nil {
+				return
+			}
+			if err = postingsConsumer.FinishDoc(); err != nil {
+				return
+			}
+			docFreq++
+		}
+	}
+
+	flags := 0
+	if fi.HasPayloads() {
+		flags |= DOCS_POSITIONS_ENUM_FLAG_PAYLOADS
+	}
+	if writeOffsets {
+		flags |= DOCS_POSITIONS_ENUM_FLAG_OFF_SETS
+	}
+	var postingsEnum DocsAndPositionsEnum
+	if postingsEnum, err = sub.termsEnum.DocsAndPositionsByFlags(sub.liveDocs, nil, flags); err != nil {
+		return
+	}
+	for {
+		var doc, freq int
+		if doc, err = postingsEnum.NextDoc(); err != nil || doc == sm.NO_MORE_DOCS {
+			return
+		}
+		doc = sub.docMap.Get(doc)
+		if freq, err = postingsEnum.Freq(); err != nil {
+			return
+		}
+		visitedDocs.Set(sub.docBase + doc)
+		if err = postingsConsumer.StartDoc(sub.docBase+doc, freq); err != nil {
+			return
+		}
+		for j := 0; j < freq; j++ {
+			var position int
+			if position, err = postingsEnum.NextPosition(); err != nil {
+				return
+			}
+			var payload []byte
+			if fi.HasPayloads() {
+				var bytesRef *util.BytesRef
+				if bytesRef, err = postingsEnum.Payload(); err != nil {
+					return
+				}
+				if bytesRef != nil {
+					payload = bytesRef.ToBytes()
+				}
+			}
+			startOffset, endOffset := -1, -1
+			if writeOffsets {
+				if startOffset, err = postingsEnum.StartOffset(); err != nil {
+					return
+				}
+				if endOffset, err = postingsEnum.EndOffset(); err != nil {
+					return
+				}
+			}
+			if err = postingsConsumer.AddPosition(position, payload, startOffset, endOffset); err != nil {
+				return
+			}
+		}
+		if err = postingsConsumer.FinishDoc(); err != nil {
+			return
+		}
+		totalTermFreq += int64(freq)
+		docFreq++
+	}
+}
@@ -0,0 +1,242 @@
+package index
+
+import (
+	"fmt"
+	ac "github.com/jtejido/golucene/analysis/core"
+	_ "github.com/jtejido/golucene/core/codec/lucene410"
+	docu "github.com/jtejido/golucene/core/document"
+	. "github.com/jtejido/golucene/core/index/model"
+	sm "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/core/util"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/* Hides the documents not set in liveDocs of the wrapped reader. */
+type deletesReader struct {
+	AtomicReader
+	liveDocs *util.FixedBitSet
+}
+
+func (r *deletesReader) LiveDocs() util.Bits {
+	return r.liveDocs
+}
+
+func (r *deletesReader) NumDocs() int {
+	return r.liveDocs.Cardinality()
+}
+
+func (r *deletesReader) Leaves() []*AtomicReaderContext {
+	return []*AtomicReaderContext{newAtomicReaderContextFromReader(r)}
+}
+
+/* Reports term vectors in the wrapped reader. */
+type termVectorsReader struct {
+	AtomicReader
+}
+
+func (r *termVectorsReader) FieldInfos() FieldInfos {
+	infos := r.AtomicReader.FieldInfos()
+	infos.HasVectors = true
+	return infos
+}
+
+func (r *termVectorsReader) Leaves() []*AtomicReaderContext {
+	return []*AtomicReaderContext{newAtomicReaderContextFromReader(r)}
+}
+
+/*
+Encodes the field length as the norm. The similarities package
+imports this one, so its DefaultSimilarity can't be used here.
+*/
+type lengthSimilarity struct{}
+
+func (lengthSimilarity) ComputeNorm(state *FieldInvertState) int64 {
+	return int64(state.Length())
+}
+
+func TestMergeSkipsDeletedDocs(t *testing.T) {
+	defaultSimilarity := DefaultSimilarity
+	DefaultSimilarity = func() Similarity { return lengthSimilarity{} }
+	defer func() { DefaultSimilarity = defaultSimilarity }()
+
+	newWriter := func(dir store.Directory) *IndexWriter {
+		w, err := NewIndexWriter(dir, NewIndexWriterConfig(util.VERSION_LATEST, ac.NewWhitespaceAnalyzer()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	newDirectory := func() store.Directory {
+		dir, err := store.OpenFSDirectory(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { dir.Close() })
+		return dir
+	}
+	openLeaf := func(dir store.Directory) (DirectoryReader, AtomicReader) {
+		r, err := OpenDirectoryReader(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Leaves()) != 1 {
+			t.Fatalf("expected one segment, but %v", len(r.Leaves()))
+		}
+		return r, r.Leaves()[0].Reader().(AtomicReader)
+	}
+
+	src := newDirectory()
+	w := newWriter(src)
+	for i := 0; i < 6; i++ {
+		d := docu.NewDocument()
+		d.Add(docu.NewFieldFromString("id", fmt.Sprintf("%v", i), docu.STRING_FIELD_TYPE_STORED))
+		// a longer body for each doc, so that their norms differ
+		body := fmt.Sprintf("shared only%v%v", i, strings.Repeat(" pad", i*i))
+		d.Add(docu.NewTextFieldFromString("body", body, docu.STORE_NO))
+		if err := w.AddDocument(d.Fields()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	srcReader, srcLeaf := openLeaf(src)
+	defer srcReader.Close()
+
+	live := util.NewFixedBitSetOf(6)
+	for _, doc := range []int{0, 2, 3, 5} {
+		live.Set(doc)
+	}
+	dest := newDirectory()
+	w = newWriter(dest)
+	if err := w.AddIndexesFromReaders(&deletesReader{srcLeaf, live}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, leaf := openLeaf(dest)
+	defer r.Close()
+
+	if n := r.MaxDoc(); n != 4 {
+		t.Fatalf("expected 4 docs, but %v", n)
+	}
+	var ids []string
+	for doc := 0; doc < 4; doc++ {
+		d, err := r.Document(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, d.Get("id"))
+	}
+	if expected := []string{"0", "2", "3", "5"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected ids %v, but %v", expected, ids)
+	}
+
+	postings := func(term string) (docs []int) {
+		termsEnum := leaf.Terms("body").Iterator(nil)
+		ok, err := termsEnum.SeekExact([]byte(term))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			return nil
+		}
+		docsEnum, err := termsEnum.DocsByFlags(nil, nil, DOCS_ENUM_FLAG_FREQS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for doc, err := docsEnum.NextDoc(); doc != sm.NO_MORE_DOCS; doc, err = docsEnum.NextDoc() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs = append(docs, doc)
+		}
+		return docs
+	}
+	for term, expected := range map[string][]int{
+		"shared": {0, 1, 2, 3},
+		"only0":  {0},
+		"only1":  nil,
+		"only3":  {2},
+		"only4":  nil,
+		"only5":  {3},
+		"pad":    {1, 2, 3},
+	} {
+		if docs := postings(term); !reflect.DeepEqual(docs, expected) {
+			t.Errorf("%v: expected docs %v, but %v", term, expected, docs)
+		}
+	}
+
+	srcNorms, err := srcLeaf.NormValues("body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	norms, err := leaf.NormValues("body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for doc, srcDoc := range []int{0, 2, 3, 5} {
+		if norms(doc) != srcNorms(srcDoc) {
+			t.Errorf("doc %v: expected norm %v of source doc %v, but %v",
+				doc, srcNorms(srcDoc), srcDoc, norms(doc))
+		}
+	}
+}
+
+func TestAddIndexesFromReadersWithTermVectors(t *testing.T) {
+	defaultSimilarity := DefaultSimilarity
+	DefaultSimilarity = func() Similarity { return lengthSimilarity{} }
+	defer func() { DefaultSimilarity = defaultSimilarity }()
+
+	dir, err := store.OpenFSDirectory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+	w, err := NewIndexWriter(dir, NewIndexWriterConfig(util.VERSION_LATEST, ac.NewWhitespaceAnalyzer()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	d := docu.NewDocument()
+	d.Add(docu.NewTextFieldFromString("body", "a b", docu.STORE_NO))
+	if err = w.AddDocument(d.Fields()); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenDirectoryReader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// buffered, to be flushed by AddIndexesFromReaders
+	if err = w.AddDocument(d.Fields()); err != nil {
+		t.Fatal(err)
+	}
+	before, err := dir.ListAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	segmentName := w.segmentInfos.counter
+	err = w.AddIndexesFromReaders(&termVectorsReader{r.Leaves()[0].Reader().(AtomicReader)})
+	if err == nil || !strings.Contains(err.Error(), "term vectors") {
+		t.Fatalf("expected term vectors to be rejected, but %v", err)
+	}
+	after, err := dir.ListAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("expected nothing to be flushed or written, but %v became %v", before, after)
+	}
+	if w.segmentInfos.counter != segmentName {
+		t.Error("expected no segment name to be allocated")
+	}
+}
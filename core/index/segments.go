@@ -5,6 +5,7 @@ import (
 )
 
 import (
+	"errors"
 	"fmt"
 	// docu "github.com/jtejido/golucene/core/document"
 	. "github.com/jtejido/golucene/core/codec/spi"
@@ -144,7 +145,7 @@ func (r *SegmentReader) LiveDocs() util.Bits {
 }
 
 func (r *SegmentReader) doClose() error {
-	panic("not implemented yet")
+	// TODO release per-segment doc values once field updates are supported
 	r.core.decRef()
 	return nil
 }
@@ -220,6 +221,22 @@ func (r *SegmentReader) CoreCacheKey() interface{} {
 	return r.core
 }
 
+/*
+Expert: adds a CoreClosedListener to this reader's shared core. It is
+invoked, with CoreCacheKey(), once all readers sharing the core are
+closed.
+*/
+func (r *SegmentReader) AddCoreClosedListener(listener CoreClosedListener) {
+	r.ensureOpen()
+	r.core.addListener <- listener
+}
+
+/* Expert: removes a CoreClosedListener from this reader's shared core. */
+func (r *SegmentReader) RemoveCoreClosedListener(listener CoreClosedListener) {
+	r.ensureOpen()
+	r.core.removeListener <- listener
+}
+
 func (r *SegmentReader) CombinedCoreAndDeletesKey() interface{} {
 	return r
 }
@@ -253,8 +270,36 @@ func (r *SegmentReader) NormValues(field string) (v NumericDocValues, err error)
 	return r.core.normValues(r.fieldInfos, field)
 }
 
+func (r *SegmentReader) VectorValues(field string) VectorValues {
+	r.ensureOpen()
+	if r.core.vectorsReader == nil {
+		return nil
+	}
+	return r.core.vectorsReader.VectorValues(field)
+}
+
+func (r *SegmentReader) SearchNearestVectors(field string, target []float32, k int,
+	acceptDocs util.Bits, visitedLimit int) (docs []int, scores []float32, complete bool, err error) {
+
+	r.ensureOpen()
+	if r.core.vectorsReader == nil {
+		return nil, nil, true, nil
+	}
+	return r.core.vectorsReader.Search(field, target, k, acceptDocs, visitedLimit)
+}
+
+/*
+Called when the shared core for a SegmentReader is closed.
+
+This listener is called only once all SegmentReaders sharing the same
+core are closed. At this point it is safe for apps to evict this
+reader from any caches keyed on CoreCacheKey(). This is the same
+interface that FieldCache uses, internally, to evict entries.
+*/
 type CoreClosedListener interface {
-	onClose(r interface{})
+	// Invoked when the shared core of the original SegmentReader has
+	// closed.
+	OnClose(ownerCoreCacheKey interface{})
 }
 
 // index/SegmentCoreReaders.java
@@ -264,6 +309,7 @@ type SegmentCoreReaders struct {
 
 	fields        FieldsProducer
 	normsProducer DocValuesProducer
+	vectorsReader KnnVectorsReader
 
 	termsIndexDivisor int
 
@@ -286,6 +332,7 @@ type SegmentCoreReaders struct {
 	addListener    chan CoreClosedListener
 	removeListener chan CoreClosedListener
 	notifyListener chan bool
+	listenersDone  chan bool
 }
 
 func newSegmentCoreReaders(owner *SegmentReader, dir store.Directory, si *SegmentCommitInfo,
@@ -309,6 +356,7 @@ func newSegmentCoreReaders(owner *SegmentReader, dir store.Directory, si *Segmen
 	self.addListener = make(chan CoreClosedListener)
 	self.removeListener = make(chan CoreClosedListener)
 	self.notifyListener = make(chan bool)
+	self.listenersDone = make(chan bool)
 	// TODO re-enable later
 	go func() { // ensure listners are synchronized
 		coreClosedListeners := make([]CoreClosedListener, 0)
@@ -334,8 +382,9 @@ func newSegmentCoreReaders(owner *SegmentReader, dir store.Directory, si *Segmen
 				fmt.Println("Shutting down SegmentCoreReaders...")
 				isRunning = false
 				for _, v := range coreClosedListeners {
-					v.onClose(self)
+					v.OnClose(self)
 				}
+				close(self.listenersDone)
 			}
 		}
 		fmt.Println("Listeners are done.")
@@ -404,6 +453,17 @@ func newSegmentCoreReaders(owner *SegmentReader, dir store.Directory, si *Segmen
 		}
 	}
 
+	if fieldInfos.HasVectorValues {
+		format := KnnVectorsFormatOf(codec)
+		if format == nil {
+			return nil, errors.New(fmt.Sprintf(
+				"codec %v does not support dense vectors (segment=%v)", codec.Name(), si.Info.Name))
+		}
+		if self.vectorsReader, err = format.VectorsReader(segmentReadState); err != nil {
+			return nil, err
+		}
+	}
+
 	// fmt.Println("Success")
 	success = true
 
@@ -429,7 +489,8 @@ func (r *SegmentCoreReaders) decRef() {
 		fmt.Println("--- closing core readers")
 		util.Close( /*self.termVectorsLocal, self.fieldsReaderLocal,  r.normsLocal,*/
 			r.fields, r.termVectorsReaderOrig, r.fieldsReaderOrig,
-			r.cfsReader, r.normsProducer)
+			r.cfsReader, r.normsProducer, r.vectorsReader)
 		r.notifyListener <- true
+		<-r.listenersDone
 	}
 }
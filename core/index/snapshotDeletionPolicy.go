@@ -0,0 +1,249 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/store"
+	"sync"
+)
+
+// index/SnapshotDeletionPolicy.java
+
+/*
+An IndexDeletionPolicy that wraps any other IndexDeletionPolicy and
+adds the ability to hold and later release snapshots of an index.
+While a snapshot is held, the IndexWriter will not remove any files
+associated with it even if the index is otherwise being actively,
+arbitrarily changed. Because we wrap another arbitrary
+IndexDeletionPolicy, this gives you the freedom to continue using
+whatever IndexDeletionPolicy you would normally want to use with your
+index.
+
+This class maintains all snapshots in-memory, and so the information
+is not persisted and not protected against system failures. If
+persistence is important, you can use PersistentSnapshotDeletionPolicy.
+*/
+type SnapshotDeletionPolicy struct {
+	sync.Locker
+	// Records how many snapshots are held against each commit
+	// generation
+	refCounts map[int64]int
+	// Used to map gen to IndexCommit.
+	indexCommits map[int64]IndexCommit
+	// Wrapped IndexDeletionPolicy
+	primary IndexDeletionPolicy
+	// Most recently committed IndexCommit.
+	lastCommit IndexCommit
+	// Used to detect misuse
+	initCalled bool
+}
+
+/* Sole constructor, taking the incoming IndexDeletionPolicy to wrap. */
+func NewSnapshotDeletionPolicy(primary IndexDeletionPolicy) *SnapshotDeletionPolicy {
+	return &SnapshotDeletionPolicy{
+		Locker:       &sync.Mutex{},
+		refCounts:    make(map[int64]int),
+		indexCommits: make(map[int64]IndexCommit),
+		primary:      primary,
+	}
+}
+
+func (p *SnapshotDeletionPolicy) onCommit(commits []IndexCommit) error {
+	p.Lock()
+	defer p.Unlock()
+	if err := p.primary.onCommit(p.wrapCommits(commits)); err != nil {
+		return err
+	}
+	p.lastCommit = commits[len(commits)-1]
+	return nil
+}
+
+func (p *SnapshotDeletionPolicy) onInit(commits []IndexCommit) error {
+	p.Lock()
+	defer p.Unlock()
+	p.initCalled = true
+	if err := p.primary.onInit(p.wrapCommits(commits)); err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		if _, ok := p.refCounts[commit.Generation()]; ok {
+			p.indexCommits[commit.Generation()] = commit
+		}
+	}
+	if len(commits) > 0 {
+		p.lastCommit = commits[len(commits)-1]
+	}
+	return nil
+}
+
+func (p *SnapshotDeletionPolicy) ensureInit() error {
+	if !p.initCalled {
+		return errors.New("this instance is not being used by IndexWriter; be sure to use the instance returned from writer.Config().IndexDeletionPolicy()")
+	}
+	return nil
+}
+
+/*
+Release a snapshotted commit.
+*/
+func (p *SnapshotDeletionPolicy) Release(commit IndexCommit) error {
+	p.Lock()
+	defer p.Unlock()
+	return p.releaseGen(commit.Generation())
+}
+
+/* Release a snapshot by generation. */
+func (p *SnapshotDeletionPolicy) releaseGen(gen int64) error {
+	if err := p.ensureInit(); err != nil {
+		return err
+	}
+	refCount, ok := p.refCounts[gen]
+	if !ok {
+		return errors.New(fmt.Sprintf("commit gen=%v is not currently snapshotted", gen))
+	}
+	assert(refCount > 0)
+	if refCount--; refCount == 0 {
+		delete(p.refCounts, gen)
+		delete(p.indexCommits, gen)
+	} else {
+		p.refCounts[gen] = refCount
+	}
+	return nil
+}
+
+/* Increments the refCount for this IndexCommit. */
+func (p *SnapshotDeletionPolicy) incRef(ic IndexCommit) {
+	gen := ic.Generation()
+	refCount, ok := p.refCounts[gen]
+	if !ok {
+		p.indexCommits[gen] = p.lastCommit
+	}
+	p.refCounts[gen] = refCount + 1
+}
+
+/*
+Snapshots the last commit and returns it. Once a commit is
+'snapshotted,' it is protected from deletion (as long as this
+IndexDeletionPolicy is used). The snapshot can be removed by calling
+Release() followed by a call to IndexWriter.DeleteUnusedFiles().
+
+NOTE: while the snapshot is held, the files it references will not be
+deleted, which will consume additional disk space in your index. If
+you take a snapshot at a particularly bad time (say just before you
+call ForceMerge()) then in the worst case this could consume an extra
+1X of your total index size, until you release the snapshot.
+*/
+func (p *SnapshotDeletionPolicy) Snapshot() (IndexCommit, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.snapshot()
+}
+
+func (p *SnapshotDeletionPolicy) snapshot() (IndexCommit, error) {
+	if err := p.ensureInit(); err != nil {
+		return nil, err
+	}
+	if p.lastCommit == nil {
+		// No commit yet, eg this is a new IndexWriter:
+		return nil, errors.New("No index commit to snapshot")
+	}
+	p.incRef(p.lastCommit)
+	return p.lastCommit, nil
+}
+
+/* Returns all IndexCommits held by at least one snapshot. */
+func (p *SnapshotDeletionPolicy) Snapshots() []IndexCommit {
+	p.Lock()
+	defer p.Unlock()
+	ans := make([]IndexCommit, 0, len(p.indexCommits))
+	for _, commit := range p.indexCommits {
+		ans = append(ans, commit)
+	}
+	return ans
+}
+
+/* Returns the total number of snapshots currently held. */
+func (p *SnapshotDeletionPolicy) SnapshotCount() int {
+	p.Lock()
+	defer p.Unlock()
+	total := 0
+	for _, refCount := range p.refCounts {
+		total += refCount
+	}
+	return total
+}
+
+/*
+Retrieve an IndexCommit from its generation; returns nil if this
+IndexCommit is not currently snapshotted
+*/
+func (p *SnapshotDeletionPolicy) IndexCommit(gen int64) IndexCommit {
+	p.Lock()
+	defer p.Unlock()
+	return p.indexCommits[gen]
+}
+
+/* Wraps each IndexCommit as a SnapshotCommitPoint. */
+func (p *SnapshotDeletionPolicy) wrapCommits(commits []IndexCommit) []IndexCommit {
+	ans := make([]IndexCommit, len(commits))
+	for i, commit := range commits {
+		ans[i] = &snapshotCommitPoint{commit, p}
+	}
+	return ans
+}
+
+/* Wraps a provided IndexCommit and prevents it from being deleted. */
+type snapshotCommitPoint struct {
+	IndexCommit
+	owner *SnapshotDeletionPolicy
+}
+
+func (cp *snapshotCommitPoint) String() string {
+	return fmt.Sprintf("SnapshotDeletionPolicy.SnapshotCommitPoint(%v)", cp.IndexCommit)
+}
+
+func (cp *snapshotCommitPoint) Delete() {
+	// Suppress the delete request if this commit point is currently
+	// snapshotted. Called from onInit()/onCommit(), which already hold
+	// the owner's lock.
+	if _, ok := cp.owner.refCounts[cp.Generation()]; !ok {
+		cp.IndexCommit.Delete()
+	}
+}
+
+/*
+Copies the files of the given commit into dest, skipping files that
+already exist there, and returns the names of the files copied.
+
+Index files are write-once, so repeatedly backing up snapshots of the
+same index into the same directory only transfers the files that are
+new since the previous backup. The segments_N file is copied last, so
+that an interrupted backup never leaves dest with a commit pointing
+to missing files.
+*/
+func BackupCommit(commit IndexCommit, dest store.Directory) (copied []string, err error) {
+	src := commit.Directory()
+	segmentsFileName := commit.SegmentsFileName()
+	for _, name := range commit.FileNames() {
+		if name == segmentsFileName || dest.FileExists(name) {
+			continue
+		}
+		if err = src.Copy(dest, name, name, store.IO_CONTEXT_READONCE); err != nil {
+			return copied, err
+		}
+		copied = append(copied, name)
+	}
+	if err = dest.Sync(copied); err != nil {
+		return copied, err
+	}
+	if !dest.FileExists(segmentsFileName) {
+		if err = src.Copy(dest, segmentsFileName, segmentsFileName, store.IO_CONTEXT_READONCE); err != nil {
+			return copied, err
+		}
+		if err = dest.Sync([]string{segmentsFileName}); err != nil {
+			return copied, err
+		}
+		copied = append(copied, segmentsFileName)
+	}
+	return copied, nil
+}
@@ -0,0 +1,115 @@
+package index_test
+
+import (
+	_ "github.com/jtejido/golucene/core/codec/lucene410"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestSnapshotDeletionPolicy(t *testing.T) {
+	dir := testindex.NewDirectory(t)
+	backup := testindex.NewDirectory(t)
+
+	policy, err := index.NewPersistentSnapshotDeletionPolicy(index.DEFAULT_DELETION_POLICY, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := testindex.NewConfig()
+	conf.SetIndexDeletionPolicy(policy)
+	writer, err := index.NewIndexWriter(dir, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addAndCommit := func(text string) {
+		d := docu.NewDocument()
+		d.Add(docu.NewTextFieldFromString("body", text, docu.STORE_NO))
+		if err := writer.AddDocument(d.Fields()); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	numDocs := func(d store.Directory) int {
+		r, err := index.OpenDirectoryReader(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		return r.NumDocs()
+	}
+
+	addAndCommit("a")
+	first, err := policy.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// indexing continues while the snapshot is being copied
+	addAndCommit("b")
+	copied, err := index.BackupCommit(first, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) != len(first.FileNames()) {
+		t.Errorf("expected %v files copied, but %v", len(first.FileNames()), copied)
+	}
+	if n := numDocs(backup); n != 1 {
+		t.Errorf("expected 1 doc in backup, but %v", n)
+	}
+
+	second, err := policy.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := policy.SnapshotCount(); n != 2 {
+		t.Errorf("expected 2 snapshots, but %v", n)
+	}
+	// incremental backup only copies the new segment and segments_N
+	copied, err = index.BackupCommit(second, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) >= len(second.FileNames()) {
+		t.Errorf("expected an incremental backup, but copied %v", copied)
+	}
+
+	// the snapshots survive a new policy instance
+	reloaded, err := index.NewPersistentSnapshotDeletionPolicyWithMode(index.DEFAULT_DELETION_POLICY, dir, index.OPEN_MODE_APPEND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := reloaded.SnapshotCount(); n != 2 {
+		t.Errorf("expected 2 persisted snapshots, but %v", n)
+	}
+
+	if err = policy.Release(first); err != nil {
+		t.Fatal(err)
+	}
+	if err = policy.Release(first); err == nil {
+		t.Error("expected an error releasing a released snapshot")
+	}
+	if !dir.FileExists(first.SegmentsFileName()) {
+		t.Errorf("%v should survive until unused files are deleted", first.SegmentsFileName())
+	}
+	if err = writer.DeleteUnusedFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if dir.FileExists(first.SegmentsFileName()) {
+		t.Errorf("%v should be deleted once released", first.SegmentsFileName())
+	}
+	if !dir.FileExists(second.SegmentsFileName()) {
+		t.Errorf("%v is still snapshotted", second.SegmentsFileName())
+	}
+	if err = policy.Release(second); err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := numDocs(backup); n != 2 {
+		t.Errorf("expected 2 docs in backup, but %v", n)
+	}
+}
@@ -0,0 +1,93 @@
+package index_test
+
+import (
+	"bytes"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestBinaryStoredFields(t *testing.T) {
+	dir := testindex.NewDirectory(t)
+
+	random := rand.New(rand.NewSource(7))
+	// small blobs, and one larger than two chunks
+	blobs := make([][]byte, 5)
+	for i := range blobs {
+		size := 100 * (i + 1)
+		if i == 3 {
+			size = 100000
+		}
+		blobs[i] = make([]byte, size)
+		random.Read(blobs[i])
+	}
+
+	conf := testindex.NewConfig()
+	w, err := index.NewIndexWriter(dir, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, blob := range blobs {
+		d := docu.NewDocument()
+		d.Add(docu.NewTextFieldFromString("title", "photo", docu.STORE_YES))
+		d.Add(docu.NewStoredFieldFromBytes("thumbnail", blob))
+		if i%2 == 0 {
+			d.Add(docu.NewStoredFieldFromLong("size", int64(len(blob))))
+			d.Add(docu.NewStoredFieldFromDouble("ratio", 0.5))
+		}
+		if err = w.AddDocument(d.Fields()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := index.OpenDirectoryReader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i, blob := range blobs {
+		d, err := r.Document(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := d.Get("title"); v != "photo" {
+			t.Errorf("doc %v: expected title photo, but %q", i, v)
+		}
+		if v := d.GetBinary("thumbnail"); !bytes.Equal(v, blob) {
+			t.Errorf("doc %v: thumbnail mismatch (%v bytes read, %v expected)", i, len(v), len(blob))
+		}
+		if i%2 == 0 {
+			if v := d.Get("size"); v != strconv.Itoa(len(blob)) {
+				t.Errorf("doc %v: expected size %v, but %v", i, len(blob), v)
+			}
+			if n := len(d.Fields()); n != 4 {
+				t.Errorf("doc %v: expected 4 fields, but %v", i, n)
+			}
+		}
+
+		// only the requested fields are loaded
+		d, err = r.DocumentFields(i, "title")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(d.Fields()); n != 1 || d.Get("title") != "photo" {
+			t.Errorf("doc %v: expected only the title, but %v", i, d.Fields())
+		}
+		d, err = r.DocumentFields(i, "ratio")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 && d.Get("ratio") != "0.5" {
+			t.Errorf("doc %v: expected ratio 0.5, but %v", i, d.Fields())
+		}
+		if d.GetBinary("thumbnail") != nil {
+			t.Errorf("doc %v: thumbnail should not be loaded", i)
+		}
+	}
+}
@@ -113,7 +113,7 @@ func NewTermContextFromTerm(ctx IndexReaderContext, t *Term) (tc *TermContext, e
 					if err != nil {
 						return nil, err
 					}
-					perReaderTermState.register(termState, leaf.Ord, df, tf)
+					perReaderTermState.Register(termState, leaf.Ord, df, tf)
 				}
 			}
 		}
@@ -121,7 +121,11 @@ func NewTermContextFromTerm(ctx IndexReaderContext, t *Term) (tc *TermContext, e
 	return perReaderTermState, nil
 }
 
-func (tc *TermContext) register(state TermState, ord, docFreq int, totalTermFreq int64) {
+/*
+Registers and associates a TermState with a leaf ordinal. The leaf
+ordinal should be derived from an IndexReaderContext's leaf ord.
+*/
+func (tc *TermContext) Register(state TermState, ord, docFreq int, totalTermFreq int64) {
 	assert2(state != nil, "state must not be nil")
 	assert(ord >= 0 && ord < len(tc.states))
 	assert2(tc.states[ord] == nil, "state for ord: %v already registered", ord)
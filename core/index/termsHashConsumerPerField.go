@@ -77,7 +77,9 @@ func (c *TermVectorsConsumerPerField) start(field IndexableField, first bool) bo
 				field.Name())
 		}
 	} else {
-		panic("not implemented yet")
+		assert2(c.doVectors == t.StoreTermVectors(),
+			"all instances of a given field name must have the same term vectors settings (storeTermVectors changed for field='%v')",
+			field.Name())
 	}
 
 	if c.doVectors {
@@ -222,8 +224,7 @@ func newFreqProxTermsWriterPerField(invertState *FieldInvertState,
 func (w *FreqProxTermsWriterPerField) finish() error {
 	err := w.TermsHashPerFieldImpl.finish()
 	if err == nil && w.sawPayloads {
-		panic("not implemented yet")
-		// w.fieldInfo.SetStorePayloads()
+		w.fieldInfo.SetStorePayloads()
 	}
 	return err
 }
@@ -271,7 +272,10 @@ func (w *FreqProxTermsWriterPerField) writeProx(termId, proxCode int) {
 	} else {
 		payload := w.payloadAttribute.Payload()
 		if len(payload) > 0 {
-			panic("not implemented yet")
+			w.writeVInt(1, (proxCode<<1)|1)
+			w.writeVInt(1, len(payload))
+			w.writeBytes(1, payload)
+			w.sawPayloads = true
 		} else {
 			w.writeVInt(1, proxCode<<1)
 		}
@@ -322,7 +326,15 @@ func (w *FreqProxTermsWriterPerField) addTerm(termId int) {
 	assert(!w.hasFreq || postings.termFreqs[termId] > 0)
 
 	if !w.hasFreq {
-		panic("not implemented yet")
+		assert(postings.termFreqs == nil)
+		if w.docState.docID != postings.lastDocIDs[termId] {
+			// New document; now encode docCode for previous doc:
+			assert(w.docState.docID > postings.lastDocIDs[termId])
+			w.writeVInt(0, postings.lastDocCodes[termId])
+			postings.lastDocCodes[termId] = w.docState.docID - postings.lastDocIDs[termId]
+			postings.lastDocIDs[termId] = w.docState.docID
+			w.fieldState.uniqueTermCount++
+		}
 	} else if w.docState.docID != postings.lastDocIDs[termId] {
 		assert2(w.docState.docID > postings.lastDocIDs[termId],
 			"id: %v postings ID: %v termID: %v",
@@ -509,6 +521,7 @@ func (w *FreqProxTermsWriterPerField) flush(fieldName string,
 	postings := w.freqProxPostingsArray
 	freq := newByteSliceReader()
 	prox := newByteSliceReader()
+	var payload []byte
 
 	visitedDocs := util.NewFixedBitSetOf(state.SegmentInfo.DocCount())
 	sumTotalTermFreq := int64(0)
@@ -635,7 +648,18 @@ func (w *FreqProxTermsWriterPerField) flush(fieldName string,
 						position += int(uint(code) >> 1)
 
 						if (code & 1) != 0 {
-							panic("not implemented yet")
+							// This position has a payload
+							payloadLength, err := prox.ReadVInt()
+							if err != nil {
+								return err
+							}
+							if cap(payload) < int(payloadLength) {
+								payload = make([]byte, payloadLength)
+							}
+							thisPayload = payload[:payloadLength]
+							if err = prox.ReadBytes(thisPayload); err != nil {
+								return err
+							}
 						}
 
 						if readOffsets {
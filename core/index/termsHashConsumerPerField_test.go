@@ -0,0 +1,61 @@
+package index_test
+
+import (
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	search "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"reflect"
+	"testing"
+)
+
+func TestDocsOnlyPostings(t *testing.T) {
+	ft := docu.NewFieldTypeFrom(docu.TEXT_FIELD_TYPE_NOT_STORED)
+	ft.SetIndexOptions(model.INDEX_OPT_DOCS_ONLY)
+	ft.Freeze()
+
+	var docs [][]model.IndexableField
+	// terms repeated within a document are recorded once
+	for _, text := range []string{"a a b", "c", "a c c", "b a"} {
+		d := docu.NewDocument()
+		d.Add(docu.NewFieldFromString("body", text, ft))
+		docs = append(docs, d.Fields())
+	}
+	r := testindex.NewReader(t, docs...)
+	defer r.Close()
+
+	leaves := r.Context().Leaves()
+	if len(leaves) != 1 {
+		t.Fatalf("expected one segment, but %v", len(leaves))
+	}
+	termsEnum := leaves[0].Reader().(index.AtomicReader).Fields().Terms("body").Iterator(nil)
+	postings := make(map[string][]int)
+	for {
+		term, err := termsEnum.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if term == nil {
+			break
+		}
+		docsEnum, err := termsEnum.DocsByFlags(nil, nil, model.DOCS_ENUM_FLAG_NONE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			doc, err := docsEnum.NextDoc()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc == search.NO_MORE_DOCS {
+				break
+			}
+			postings[string(term)] = append(postings[string(term)], doc)
+		}
+	}
+	expected := map[string][]int{"a": {0, 2, 3}, "b": {0, 3}, "c": {1, 2}}
+	if !reflect.DeepEqual(postings, expected) {
+		t.Errorf("expected %v, but %v", expected, postings)
+	}
+}
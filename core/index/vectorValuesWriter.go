@@ -0,0 +1,47 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/jtejido/golucene/core/codec/spi"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// index/VectorValuesWriter.java
+
+/*
+Buffers up the dense vectors of a field, then flushes them, and
+their graph, when the segment flushes.
+*/
+type VectorValuesWriter struct {
+	fieldInfo   *FieldInfo
+	iwBytesUsed util.Counter
+	docs        []int
+	vectors     [][]float32
+}
+
+func newVectorValuesWriter(fieldInfo *FieldInfo, iwBytesUsed util.Counter) *VectorValuesWriter {
+	return &VectorValuesWriter{fieldInfo: fieldInfo, iwBytesUsed: iwBytesUsed}
+}
+
+func (w *VectorValuesWriter) addValue(docID int, vector []float32) error {
+	if n := len(w.docs); n > 0 && w.docs[n-1] >= docID {
+		return errors.New(fmt.Sprintf(
+			"KnnVectorField '%v' appears more than once in this document (only one value is allowed per field)",
+			w.fieldInfo.Name))
+	}
+	w.docs = append(w.docs, docID)
+	w.vectors = append(w.vectors, append([]float32(nil), vector...))
+	w.iwBytesUsed.AddAndGet(int64(util.NUM_BYTES_INT + util.NUM_BYTES_FLOAT*len(vector)))
+	return nil
+}
+
+func (w *VectorValuesWriter) flush(state *SegmentWriteState, writer KnnVectorsWriter) error {
+	return writer.AddField(w.fieldInfo, w)
+}
+
+func (w *VectorValuesWriter) Dimension() int           { return w.fieldInfo.VectorDimension() }
+func (w *VectorValuesWriter) Size() int                { return len(w.docs) }
+func (w *VectorValuesWriter) Doc(ord int) int          { return w.docs[ord] }
+func (w *VectorValuesWriter) Vector(ord int) []float32 { return w.vectors[ord] }
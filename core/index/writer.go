@@ -9,6 +9,7 @@ import (
 	. "github.com/jtejido/golucene/core/index/model"
 	"github.com/jtejido/golucene/core/store"
 	"github.com/jtejido/golucene/core/util"
+	"io"
 	"log"
 	"math"
 	"os"
@@ -148,6 +149,9 @@ const WRITE_LOCK_NAME = "write.lock"
 /* Source of a segment which results from a flush. */
 const SOURCE_FLUSH = "flush"
 
+/* Source of a segment which results from a call to AddIndexesFromReaders(). */
+const SOURCE_ADDINDEXES_READERS = "addIndexes(IndexReader...)"
+
 /*
 Absolute hard maximum length for a term, in bytes once encoded as
 UTF8. If a term arrives from the analyzer longer than this length,
@@ -188,6 +192,13 @@ type IndexWriter struct {
 	directory store.Directory   // where this index resides
 	analyzer  analysis.Analyzer // how to analyze text
 
+	// wraps directory to rate limit the write IO of merges, if the
+	// merge scheduler provides a rate limiter
+	mergeDirectory *store.RateLimitedDirectoryWrapper
+
+	// the configured schema, as persisted in the commit user data
+	schemaData string
+
 	changeCount           int64 // volatile, increments every time a change is completed
 	lastCommitChangeCount int64 // volatile, last changeCount that was committed
 
@@ -317,6 +328,13 @@ func NewIndexWriter(d store.Directory, conf *IndexWriterConfig) (w *IndexWriter,
 	ans.readerPool = newReaderPool(ans)
 	ans.MergeControl = newMergeControl(conf.infoStream, ans.readerPool)
 
+	ans.mergeDirectory = store.NewRateLimitedDirectoryWrapper(d)
+	if ms, ok := conf.mergeScheduler.(interface {
+		MergeRateLimiter() store.RateLimiter
+	}); ok {
+		ans.mergeDirectory.SetRateLimiter(ms.MergeRateLimiter(), store.IO_CONTEXT_TYPE_MERGE)
+	}
+
 	conf.setIndexWriter(ans)
 
 	// obtain write lock
@@ -388,10 +406,24 @@ func NewIndexWriter(d store.Directory, conf *IndexWriterConfig) (w *IndexWriter,
 			assert2(commit.Directory() == d,
 				"IndexCommit's directory doesn't match my directory")
 			oldInfos := &SegmentInfos{}
+			if err = oldInfos.Read(d, commit.SegmentsFileName()); err != nil {
+				return
+			}
 			ans.segmentInfos.replace(oldInfos)
+			// the commit's user data describes its content, so it is
+			// carried along to the next commit
+			ans.segmentInfos.SetUserData(oldInfos.userData)
 			ans.changed()
-			ans.infoStream.Message("IW", "init: loaded commit '%v'",
-				commit.SegmentsFileName())
+			if ans.infoStream.IsEnabled("IW") {
+				ans.infoStream.Message("IW", "init: loaded commit '%v'",
+					commit.SegmentsFileName())
+			}
+		}
+	}
+
+	if schema := conf.schema; schema != nil {
+		if err = ans.initSchema(schema, create); err != nil {
+			return
 		}
 	}
 
@@ -430,6 +462,37 @@ func NewIndexWriter(d store.Directory, conf *IndexWriterConfig) (w *IndexWriter,
 	return ans, nil
 }
 
+/*
+Checks schema against the one persisted in the index, if any, and
+records it in the commit user data.
+*/
+func (w *IndexWriter) initSchema(schema *Schema, create bool) error {
+	userData := w.segmentInfos.UserData()
+	if !create {
+		persisted, err := SchemaFromCommitData(userData)
+		if err != nil {
+			return err
+		}
+		if persisted != nil {
+			if err = schema.checkCompatible(persisted); err != nil {
+				return err
+			}
+		}
+	}
+	w.schemaData = schema.encode()
+	if userData[SCHEMA_COMMIT_KEY] == w.schemaData {
+		return nil
+	}
+	data := make(map[string]string)
+	for k, v := range userData {
+		data[k] = v
+	}
+	data[SCHEMA_COMMIT_KEY] = w.schemaData
+	w.segmentInfos.SetUserData(data)
+	w.changeCount++
+	return nil
+}
+
 // func (w *IndexWriter) fieldInfos(info *SegmentInfo) (infos FieldInfos, err error) {
 // 	var cfsDir store.Directory
 // 	if info.IsCompoundFile() {
@@ -486,7 +549,7 @@ and closes all associate files.
 
 Note that:
 	1. If you called prepare Commit but failed to call commit, this
-	method will panic and the IndexWriter will not be closed.
+	method will return an error and the IndexWriter will not be closed.
 	2. If this method throws any other exception, the IndexWriter will
 	be closed, but changes may have been lost.
 
@@ -498,11 +561,12 @@ NOTE: You must ensure no other threads are still making changes at
 the same time that this method is invoked.
 */
 func (w *IndexWriter) Close() error {
-	assert2(w.pendingCommit == nil,
-		"cannot close: prepareCommit was already called with no corresponding call to commit")
 	// Ensure that only one goroutine actaully gets to do the closing
 	w.commitLock.Lock()
 	defer w.commitLock.Unlock()
+	if w.pendingCommit != nil {
+		return errors.New("cannot close: prepareCommit was already called with no corresponding call to commit")
+	}
 	return w.close(func() (ok bool, err error) {
 		defer func() {
 			if !ok { // be certain to close the index on any error
@@ -594,6 +658,13 @@ func (w *IndexWriter) UpdateDocument(term *Term, doc []IndexableField, analyzer
 		}
 	}()
 
+	if schema := w.config.Schema(); schema != nil {
+		if err := schema.Validate(doc); err != nil {
+			return err
+		}
+		analyzer = schema.Analyzer(analyzer)
+	}
+
 	ok, err := w.docWriter.updateDocument(doc, analyzer, term)
 	if err != nil {
 		return err
@@ -608,6 +679,88 @@ func (w *IndexWriter) UpdateDocument(term *Term, doc []IndexableField, analyzer
 	return nil
 }
 
+// L1287
+/*
+Atomically adds a block of documents with sequentially assigned
+document IDs, such that an external reader will see all or none of
+the documents.
+
+WARNING: the index does not currently record which documents were
+added as a block. Today this is fine, because merging will preserve
+a block. The order of documents within a segment will be preserved,
+even when child documents within a block are deleted. Most search
+features (like result grouping and block joining) require you to mark
+documents; when these documents are deleted these search features
+will not work as expected. Obviously adding documents to an existing
+block will require you the reindex the entire block.
+
+However it's possible that in the future Lucene may merge more
+aggressively re-order documents (for example, perhaps to obtain
+better index compression), in which case you may need to fully
+re-index your documents at that time.
+
+See AddDocument() for details on index and IndexWriter state after an
+error, and flushing/merging temporary free space requirements.
+
+NOTE: tools that do offline splitting of an index (for example,
+IndexSplitter in contrib) or re-sorting of documents (for example,
+IndexSorter in contrib) are not aware of these atomically added
+documents and will likely break them up. Use such tools at your own
+risk!
+*/
+func (w *IndexWriter) AddDocuments(docs [][]IndexableField) error {
+	return w.AddDocumentsWithAnalyzer(docs, w.analyzer)
+}
+
+/*
+Atomically adds a block of documents, analyzed using the provided
+analyzer, with sequentially assigned document IDs, such that an
+external reader will see all or none of the documents.
+*/
+func (w *IndexWriter) AddDocumentsWithAnalyzer(docs [][]IndexableField, analyzer analysis.Analyzer) error {
+	return w.UpdateDocuments(nil, docs, analyzer)
+}
+
+// L1348
+/*
+Atomically deletes documents matching the provided delTerm and adds a
+block of documents, analyzed using the provided analyzer, with
+sequentially assigned document IDs, such that an external reader
+will see all or none of the documents.
+*/
+func (w *IndexWriter) UpdateDocuments(delTerm *Term, docs [][]IndexableField, analyzer analysis.Analyzer) error {
+	w.ensureOpen()
+	var success = false
+	defer func() {
+		if !success {
+			if w.infoStream.IsEnabled("IW") {
+				w.infoStream.Message("IW", "hit error updating document")
+			}
+		}
+	}()
+
+	if schema := w.config.Schema(); schema != nil {
+		for _, doc := range docs {
+			if err := schema.Validate(doc); err != nil {
+				return err
+			}
+		}
+		analyzer = schema.Analyzer(analyzer)
+	}
+
+	ok, err := w.docWriter.updateDocuments(docs, analyzer, delTerm)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if _, err = w.docWriter.processEvents(w, true, false); err != nil {
+			return err
+		}
+	}
+	success = true
+	return nil
+}
+
 func (w *IndexWriter) newSegmentName() string {
 	// Cannot synchronize on IndexWriter because that causes deadlook
 	// Ian: but why?
@@ -788,6 +941,338 @@ func (w *IndexWriter) hasPendingMerges() bool {
 	return w.pendingMerges.Len() > 0
 }
 
+func (w *IndexWriter) noDupDirs(dirs []store.Directory) error {
+	dups := make(map[store.Directory]bool)
+	for _, dir := range dirs {
+		if dups[dir] {
+			return errors.New(fmt.Sprintf("Directory %v appears more than once", dir))
+		}
+		if dir == w.directory {
+			return errors.New("Cannot add directory to itself")
+		}
+		dups[dir] = true
+	}
+	return nil
+}
+
+/* Acquires write locks on all the directories; be sure to match with a call to util.Close() in a defer. */
+func (w *IndexWriter) acquireWriteLocks(dirs []store.Directory) (locks []io.Closer, err error) {
+	for _, dir := range dirs {
+		lock := dir.MakeLock(WRITE_LOCK_NAME)
+		ok, err := lock.ObtainWithin(w.config.WriteLockTimeout())
+		if err == nil && !ok {
+			err = errors.New(fmt.Sprintf("Index locked for write: %v", lock))
+		}
+		if err != nil {
+			// Release all previously acquired locks:
+			util.CloseWhileSuppressingError(locks...)
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+/*
+Adds all segments from an array of indexes into this index.
+
+This may be used to parallelize batch indexing. A large document
+collection can be broken into sub-collections. Each sub-collection
+can be indexed in parallel, on a different thread, process or
+machine. The complete index can then be created by merging
+sub-collection indexes with this method.
+
+NOTE: this method acquires the write lock in each directory, to
+ensure that no IndexWriter is currently open or tries to open while
+this is running.
+
+This method is transactional in how errors are handled: it does not
+commit a new segments_N file until all indexes are added. This means
+if an error occurs (for example disk full), then either no indexes
+will have been added or they all will have been.
+
+Note that this requires temporary free space in the Directory up to
+2X the sum of all input indexes (including the starting index). If
+readers/searchers are open against the starting index, then temporary
+free space required will be higher by the size of the starting index.
+
+This requires this index not be among those to be added.
+
+NOTE: the segments are copied as-is under new segment names, keeping
+their codec and compound file format; they are not merged.
+*/
+func (w *IndexWriter) AddIndexesFromDirectories(dirs ...store.Directory) (err error) {
+	w.ensureOpen()
+	if err = w.noDupDirs(dirs); err != nil {
+		return err
+	}
+
+	locks, err := w.acquireWriteLocks(dirs)
+	if err != nil {
+		return err
+	}
+	var successTop = false
+	defer func() {
+		if successTop {
+			err = mergeError(err, util.Close(locks...))
+		} else {
+			util.CloseWhileSuppressingError(locks...)
+		}
+	}()
+
+	if w.infoStream.IsEnabled("IW") {
+		w.infoStream.Message("IW", "flush at addIndexes(Directory...)")
+	}
+	if err = w.flush(false, true); err != nil {
+		return err
+	}
+
+	var infos []*SegmentCommitInfo
+	var success = false
+	defer func() {
+		if !success {
+			for _, sipc := range infos {
+				for _, file := range sipc.Files() {
+					w.directory.DeleteFile(file) // ignore error
+				}
+			}
+		}
+	}()
+
+	for _, dir := range dirs {
+		if w.infoStream.IsEnabled("IW") {
+			w.infoStream.Message("IW", "addIndexes: process directory %v", dir)
+		}
+		sis := &SegmentInfos{} // read infos from dir
+		if err = sis.ReadAll(dir); err != nil {
+			return err
+		}
+		copiedFiles := make(map[string]bool)
+		for _, info := range sis.Segments {
+			newSegName := w.newSegmentName()
+			if w.infoStream.IsEnabled("IW") {
+				w.infoStream.Message("IW", "addIndexes: process segment origName=%v newName=%v info=%v",
+					info.Info.Name, newSegName, info)
+			}
+
+			size, err := info.SizeInBytes()
+			if err != nil {
+				return err
+			}
+			context := store.NewIOContextForMerge(&store.MergeInfo{
+				TotalDocCount:       info.Info.DocCount(),
+				EstimatedMergeBytes: size,
+				IsExternal:          true,
+				MergeMaxNumSegments: -1,
+			})
+
+			fis, err := ReadFieldInfos(info)
+			if err != nil {
+				return err
+			}
+			for _, fi := range fis.Values {
+				w.globalFieldNumberMap.AddOrGet(fi)
+			}
+
+			newInfo, err := w.copySegmentAsIs(info, newSegName, context, copiedFiles)
+			if err != nil {
+				return err
+			}
+			infos = append(infos, newInfo)
+		}
+	}
+
+	w.Lock() // synchronized
+	defer w.Unlock()
+	w.ensureOpen()
+	success = true
+	w.segmentInfos.Segments = append(w.segmentInfos.Segments, infos...)
+	if err = w._checkpoint(); err != nil {
+		return err
+	}
+	successTop = true
+	return nil
+}
+
+/* Copies the segment files as-is into this IndexWriter's directory. */
+func (w *IndexWriter) copySegmentAsIs(info *SegmentCommitInfo, segName string,
+	context store.IOContext, copiedFiles map[string]bool) (*SegmentCommitInfo, error) {
+
+	// Same SI as before but we change directory and name
+	newInfo := NewSegmentInfo(w.directory, info.Info.Version(), segName,
+		info.Info.DocCount(), info.Info.IsCompoundFile(), info.Info.Codec(),
+		info.Info.Diagnostics())
+	newInfoPerCommit := NewSegmentCommitInfo(newInfo, info.DelCount(),
+		info.DelGen(), info.FieldInfosGen(), info.DocValuesGen())
+
+	// Build up new segment's file names. Must do this before writing
+	// SegmentInfo:
+	segFiles := make(map[string]bool)
+	for _, file := range info.Files() {
+		segFiles[segName+util.StripSegmentName(file)] = true
+	}
+	newInfo.SetFiles(segFiles)
+
+	// We must rewrite the SI file because it references segment name
+	// (its own name):
+	trackingDir := store.NewTrackingDirectoryWrapper(w.directory)
+	err := newInfo.Codec().(Codec).SegmentInfoFormat().SegmentInfoWriter().Write(
+		trackingDir, newInfo, FieldInfos{}, context)
+	if err != nil {
+		return nil, err
+	}
+
+	var success = false
+	defer func() {
+		if !success {
+			for file, _ := range newInfo.Files() {
+				w.directory.DeleteFile(file) // ignore error
+			}
+		}
+	}()
+
+	// Copy the segment's files
+	for _, file := range info.Files() {
+		newFileName := segName + util.StripSegmentName(file)
+		if trackingDir.ContainsFile(newFileName) {
+			// We already rewrote this above
+			continue
+		}
+
+		assert2(!copiedFiles[file], "file '%v' is being copied more than once", file)
+		copiedFiles[file] = true
+		if err = info.Info.Dir.Copy(w.directory, file, newFileName, context); err != nil {
+			return nil, err
+		}
+	}
+	success = true
+	return newInfoPerCommit, nil
+}
+
+/*
+Merges the provided indexes into this index.
+
+The provided IndexReaders are not closed.
+
+See AddIndexesFromDirectories() for details on transactional
+semantics, temporary free space required in the Directory, and non-CFS
+segments on an error.
+
+NOTE: unlike AddIndexesFromDirectories(), this rewrites all documents
+into a single new segment, using this writer's codec and global field
+numbers. Readers with doc values or term vectors are not supported
+yet: SegmentMerger can't merge them, so an error is returned before
+anything is flushed or written.
+*/
+func (w *IndexWriter) AddIndexesFromReaders(readers ...IndexReader) (err error) {
+	w.ensureOpen()
+
+	for _, reader := range readers {
+		for _, ctx := range reader.Leaves() {
+			infos := ctx.Reader().(AtomicReader).FieldInfos()
+			if infos.HasDocValues {
+				return errors.New(fmt.Sprintf("cannot add %v: merging doc values is not supported yet", ctx.Reader()))
+			}
+			if infos.HasVectors {
+				return errors.New(fmt.Sprintf("cannot add %v: merging term vectors is not supported yet", ctx.Reader()))
+			}
+		}
+	}
+
+	if w.infoStream.IsEnabled("IW") {
+		w.infoStream.Message("IW", "flush at addIndexes(IndexReader...)")
+	}
+	if err = w.flush(false, true); err != nil {
+		return err
+	}
+
+	mergedName := w.newSegmentName()
+	numDocs := 0
+	var mergeReaders []AtomicReader
+	for _, reader := range readers {
+		numDocs += reader.NumDocs()
+		for _, ctx := range reader.Leaves() {
+			mergeReaders = append(mergeReaders, ctx.Reader().(AtomicReader))
+		}
+	}
+	context := store.NewIOContextForMerge(&store.MergeInfo{
+		TotalDocCount:       numDocs,
+		EstimatedMergeBytes: -1,
+		IsExternal:          true,
+		MergeMaxNumSegments: -1,
+	})
+
+	// TODO: somehow we should fix this merge so it's abortable so that
+	// IW.close(false) is able to stop it
+	trackingDir := store.NewTrackingDirectoryWrapper(w.mergeDirectory)
+
+	info := NewSegmentInfo(w.directory, util.VERSION_LATEST, mergedName, numDocs,
+		false, w.codec, nil)
+
+	merger := newSegmentMerger(mergeReaders, info, w.infoStream, trackingDir,
+		w.config.TermIndexInterval(), CheckAbortNone(0), w.globalFieldNumberMap,
+		context)
+	if !merger.shouldMerge() {
+		return nil
+	}
+
+	var success = false
+	defer func() {
+		if !success {
+			w.Lock()
+			defer w.Unlock()
+			w.deleter.refresh(info.Name) // ignore error
+		}
+	}()
+
+	mergeState, err := merger.merge() // merge 'em
+	if err != nil {
+		return err
+	}
+
+	infoPerCommit := NewSegmentCommitInfo(info, 0, -1, -1, -1)
+	files := make(map[string]bool)
+	trackingDir.EachCreatedFiles(func(name string) {
+		files[name] = true
+	})
+	info.SetFiles(files)
+	setDiagnostics(info, SOURCE_ADDINDEXES_READERS)
+
+	// Now create the compound file if needed
+	if w.config.UseCompoundFile() {
+		filesToDelete := infoPerCommit.Files()
+		_, err = createCompoundFile(w.infoStream, w.mergeDirectory, CheckAbortNone(0), info, context)
+		// delete new non cfs files directly: they were never registered
+		// with IFD
+		w.deleteNewFiles(filesToDelete)
+		if err != nil {
+			return err
+		}
+		info.SetUseCompoundFile(true)
+	}
+
+	// Have codec write SegmentInfo. Must do this after creating CFS so
+	// that 1) .si isn't slurped into CFS, and 2) .si reflects
+	// useCompoundFile=true change above:
+	siDir := store.NewTrackingDirectoryWrapper(w.directory)
+	err = w.codec.SegmentInfoFormat().SegmentInfoWriter().Write(
+		siDir, info, mergeState.FieldInfos, context)
+	if err != nil {
+		return err
+	}
+	siDir.EachCreatedFiles(func(name string) {
+		info.AddFile(name)
+	})
+
+	// Register the new segment
+	w.Lock() // synchronized
+	defer w.Unlock()
+	w.ensureOpen()
+	success = true
+	w.segmentInfos.Segments = append(w.segmentInfos.Segments, infoPerCommit)
+	return w._checkpoint()
+}
+
 /*
 Close the IndexWriter without committing any changes that have
 occurred since the last commit (or since it was opened, if commit
@@ -1027,7 +1512,9 @@ func (w *IndexWriter) prepareCommitInternal(mergePolicy MergePolicy) error {
 	}
 
 	assert2(w.tragedy == nil, "this writer hit an unrecoverable error; cannot commit\n%v", w.tragedy)
-	assert2(w.pendingCommit == nil, "prepareCommit was already called with no corresponding call to commit")
+	if w.pendingCommit != nil {
+		return errors.New("prepareCommit was already called with no corresponding call to commit")
+	}
 
 	err := w.doBeforeFlush()
 	if err != nil {
@@ -1135,6 +1622,65 @@ func (w *IndexWriter) prepareCommitInternal(mergePolicy MergePolicy) error {
 	return nil
 }
 
+/*
+Expert: prepare for commit. This does the first phase of 2-phase
+commit. This method does all steps necessary to commit changes since
+this writer was opened: flushes pending added and deleted docs, syncs
+the index files, writes most of next segments_N file. After calling
+this you must call either Commit() to finish the commit, or
+Rollback() to revert the commit and undo all changes done since the
+writer was opened.
+
+You can also just call Commit() directly without PrepareCommit()
+first in which case that method will internally call PrepareCommit().
+*/
+func (w *IndexWriter) PrepareCommit() error {
+	w.ensureOpen()
+	w.commitLock.Lock()
+	defer w.commitLock.Unlock()
+	return w.prepareCommitInternal(w.config.MergePolicy())
+}
+
+/*
+Sets the commit user data map. That method is considered a
+transaction by IndexWriter and will be committed (Commit() even if no
+other changes were made to the writer instance. Note that you must
+call this method before PrepareCommit(), or otherwise it won't be
+included in the follow-on Commit().
+
+NOTE: the map is cloned internally, therefore altering the map's
+contents after calling this method has no effect.
+*/
+func (w *IndexWriter) SetCommitData(commitUserData map[string]string) {
+	w.Lock()
+	defer w.Unlock()
+	data := make(map[string]string)
+	for k, v := range commitUserData {
+		data[k] = v
+	}
+	if w.schemaData != "" {
+		data[SCHEMA_COMMIT_KEY] = w.schemaData
+	}
+	w.segmentInfos.SetUserData(data)
+	w.changeCount++
+}
+
+/*
+Returns the commit user data map that was last committed, or the one
+that was set on SetCommitData().
+
+NOTE: the map is a copy, therefore altering it has no effect.
+*/
+func (w *IndexWriter) CommitData() map[string]string {
+	w.Lock()
+	defer w.Unlock()
+	data := make(map[string]string)
+	for k, v := range w.segmentInfos.UserData() {
+		data[k] = v
+	}
+	return data
+}
+
 /*
 Commits all pending changes (added & deleted documents, segment
 merges, added indexes, etc.) to the index, and syncs all referenced
@@ -1516,6 +2062,7 @@ func (w *IndexWriter) startCommit(toSync *SegmentInfos) error {
 		w.infoStream.Message("IW", "startCommit(): start")
 	}
 
+	var skip bool
 	if err := func() error {
 		w.Lock()
 		defer w.Unlock()
@@ -1528,6 +2075,7 @@ func (w *IndexWriter) startCommit(toSync *SegmentInfos) error {
 			}
 			w.deleter.decRefFiles(w.filesToCommit)
 			w.filesToCommit = nil
+			skip = true
 			return nil
 		}
 
@@ -1537,7 +2085,7 @@ func (w *IndexWriter) startCommit(toSync *SegmentInfos) error {
 		}
 
 		return w.assertFilesExist(toSync)
-	}(); err != nil {
+	}(); err != nil || skip {
 		return err
 	}
 
@@ -1641,11 +2189,139 @@ func (w *IndexWriter) testPoint(message string) {
 
 // L4356
 
+/*
+Flushes all pending documents, without committing, and opens a
+reader on the resulting segments. Unchanged segments share the
+readers of oldReaders, if any. The files of the segments are
+incRef'd, so that they're kept until the reader is closed.
+*/
+func (w *IndexWriter) getReader(applyAllDeletes bool, oldReaders []IndexReader) (r *StandardDirectoryReader, err error) {
+	w.ensureOpen()
+	if w.infoStream.IsEnabled("IW") {
+		w.infoStream.Message("IW", "flush at getReader")
+	}
+	if err = w.doBeforeFlush(); err != nil {
+		return nil, err
+	}
+
+	anySegmentFlushed, err := func() (anySegmentFlushed bool, err error) {
+		w.fullFlushLock.Lock()
+		defer w.fullFlushLock.Unlock()
+
+		flushSuccess := false
+		defer func() {
+			// Done: finish the full flush!
+			w.docWriter.finishFullFlush(flushSuccess)
+			w.docWriter.processEvents(w, false, true)
+		}()
+		if anySegmentFlushed, err = w.docWriter.flushAllThreads(w); err != nil {
+			if w.infoStream.IsEnabled("IW") {
+				w.infoStream.Message("IW", "hit error during NRT reader")
+			}
+			return
+		}
+		if !anySegmentFlushed {
+			// prevent double increment since docWriter.doFlush
+			// increments the flushCount if we flushed anything.
+			atomic.AddInt32(&w.flushCount, 1)
+		}
+		flushSuccess = true
+
+		w.Lock()
+		defer w.Unlock()
+		if err = w._maybeApplyDeletes(applyAllDeletes); err != nil {
+			return
+		}
+		infos := w.segmentInfos.Clone()
+		w.deleter.incRef(infos, false)
+		if r, err = openStandardDirectoryReaderReusing(w.directory, infos,
+			oldReaders, DEFAULT_TERMS_INDEX_DIVISOR); err != nil {
+			w.deleter.decRefInfos(infos)
+			return
+		}
+		r.writer, r.applyAllDeletes = w, applyAllDeletes
+		if w.infoStream.IsEnabled("IW") {
+			w.infoStream.Message("IW", "return reader version=%v reader=%v", r.Version(), r)
+		}
+		return
+	}()
+	if err != nil {
+		return nil, err
+	}
+	if err = w.doAfterFlush(); err == nil && anySegmentFlushed {
+		err = w.maybeMerge(w.config.MergePolicy(), MERGE_TRIGGER_FULL_FLUSH, UNBOUNDED_MAX_MERGE_SEGMENTS)
+	}
+	if err != nil {
+		util.CloseWhileSuppressingError(r)
+		return nil, err
+	}
+	return r, nil
+}
+
+/*
+Returns true if no document was added or deleted since the NRT reader
+on infos was opened.
+*/
+func (w *IndexWriter) nrtIsCurrent(infos *SegmentInfos) bool {
+	w.Lock()
+	defer w.Unlock()
+	w.ensureOpen()
+	return infos.version == w.segmentInfos.version &&
+		!w.docWriter.anyChanges() && !w.bufferedUpdatesStream.any()
+}
+
+func (w *IndexWriter) isClosed() bool {
+	return w.ClosingControl._closed
+}
+
+/*
+Called by the NRT readers when closed, to release the files of their
+segments. Files of a closed writer are left as is.
+*/
+func (w *IndexWriter) decRefDeleter(infos *SegmentInfos) {
+	w.Lock()
+	defer w.Unlock()
+	if w.isClosed() {
+		return
+	}
+	w.deleter.decRefInfos(infos)
+	w.deleter.deletePendingFiles()
+}
+
 /* Called by DirectoryReader.doClose() */
 func (w *IndexWriter) deletePendingFiles() {
 	w.deleter.deletePendingFiles()
 }
 
+/*
+Expert: remove any index files that are no longer used.
+
+IndexWriter normally deletes unused files itself, during indexing.
+However, on Windows, which disallows deletion of open files, if there
+is a reader open on the index then those files cannot be deleted.
+This is fine, because IndexWriter will periodically retry the
+deletion.
+
+However, IndexWriter doesn't try that often: only on open, close,
+flushing a new segment, and finishing a merge. If you don't do any of
+these actions with your IndexWriter, you'll see the unused files
+linger. If that's a problem, call this method to delete them (once
+you've closed the open readers that were preventing their deletion).
+
+In addition, you can call this method to delete unreferenced index
+commits. This might be useful if you are using an IndexDeletionPolicy
+which holds onto index commits until some criteria are met, but those
+commits are no longer needed. Otherwise, those commits will be
+deleted the next time Commit() is called.
+*/
+func (w *IndexWriter) DeleteUnusedFiles() error {
+	w.Lock()
+	defer w.Unlock()
+	w.ClosingControl.ensureOpen(false)
+	w.deleter.deletePendingFiles()
+	return w.deleter.revisitPolicy()
+}
+
 /*
 NOTE: this method creates a compound file for all files returned by
 info.files(). While, generally, this may include separate norms and
@@ -1718,10 +2394,10 @@ func createCompoundFile(infoStream util.InfoStream,
 }
 
 // Tries to delete the given files if unreferenced.
-func (w *IndexWriter) deleteNewFiles(files []string) error {
+func (w *IndexWriter) deleteNewFiles(files []string) {
 	w.Lock() // synchronized
 	defer w.Unlock()
-	panic("not implemented yet")
+	w.deleter.deleteNewFiles(files)
 }
 
 /* Cleans up residuals from a segment that could not be entirely flushed due to an error */
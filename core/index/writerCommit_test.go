@@ -0,0 +1,158 @@
+package index_test
+
+import (
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestTwoPhaseCommitAndUserData(t *testing.T) {
+	dir := testindex.NewDirectory(t)
+
+	openWriter := func(commit index.IndexCommit) *index.IndexWriter {
+		conf := testindex.NewConfig()
+		conf.SetIndexDeletionPolicy(index.NO_DELETION_POLICY)
+		conf.SetIndexCommit(commit)
+		writer, err := index.NewIndexWriter(dir, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writer
+	}
+	addDoc := func(writer *index.IndexWriter) {
+		d := docu.NewDocument()
+		d.Add(docu.NewTextFieldFromString("body", "a", docu.STORE_NO))
+		if err := writer.AddDocument(d.Fields()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	numDocs := func(commit index.IndexCommit) int {
+		r, err := index.OpenDirectoryReaderFromCommit(commit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		return r.NumDocs()
+	}
+	listCommits := func(d store.Directory, expected int) []index.IndexCommit {
+		commits, err := index.ListCommits(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(commits) != expected {
+			t.Fatalf("expected %v commits, but %v", expected, len(commits))
+		}
+		return commits
+	}
+
+	writer := openWriter(nil)
+	addDoc(writer)
+	writer.SetCommitData(map[string]string{"offset": "1"})
+	if err := writer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a prepared commit that is rolled back leaves no trace
+	addDoc(writer)
+	writer.SetCommitData(map[string]string{"offset": "2"})
+	if err := writer.PrepareCommit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	commits := listCommits(dir, 1)
+	if offset := commits[0].UserData()["offset"]; offset != "1" {
+		t.Errorf("expected offset 1, but %v", offset)
+	}
+
+	writer = openWriter(nil)
+	if offset := writer.CommitData()["offset"]; offset != "1" {
+		t.Errorf("expected offset 1 from the last commit, but %v", offset)
+	}
+	addDoc(writer)
+	writer.SetCommitData(map[string]string{"offset": "2"})
+	if err := writer.PrepareCommit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	commits = listCommits(dir, 2)
+	if offset := commits[1].UserData()["offset"]; offset != "2" {
+		t.Errorf("expected offset 2, but %v", offset)
+	}
+	if n := numDocs(commits[0]); n != 1 {
+		t.Errorf("expected 1 doc in the first commit, but %v", n)
+	}
+	if n := numDocs(commits[1]); n != 2 {
+		t.Errorf("expected 2 docs in the last commit, but %v", n)
+	}
+	infos := &index.SegmentInfos{}
+	if err := infos.ReadAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if offset := infos.UserData()["offset"]; offset != "2" {
+		t.Errorf("expected offset 2 in SegmentInfos, but %v", offset)
+	}
+
+	// go back in time to the first commit
+	writer = openWriter(commits[0])
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	commits = listCommits(dir, 3)
+	if n := numDocs(commits[2]); n != 1 {
+		t.Errorf("expected 1 doc after reverting, but %v", n)
+	}
+	if offset := commits[2].UserData()["offset"]; offset != "1" {
+		t.Errorf("expected offset 1 after reverting, but %v", offset)
+	}
+}
+
+func TestPrepareCommitErrors(t *testing.T) {
+	dir := testindex.NewDirectory(t)
+	writer := testindex.NewWriter(t, dir, nil)
+	d := docu.NewDocument()
+	d.Add(docu.NewTextFieldFromString("body", "a", docu.STORE_NO))
+	if err := writer.AddDocument(d.Fields()); err != nil {
+		t.Fatal(err)
+	}
+	writer.SetCommitData(map[string]string{"offset": "1"})
+	data := writer.CommitData()
+	data["offset"] = "2"
+	if offset := writer.CommitData()["offset"]; offset != "1" {
+		t.Errorf("expected the commit data to be copied, but offset %v", offset)
+	}
+
+	if err := writer.PrepareCommit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.PrepareCommit(); err == nil {
+		t.Error("expected an error when preparing a commit twice")
+	}
+	if err := writer.Close(); err == nil {
+		t.Error("expected an error when closing with a prepared commit")
+	}
+
+	// the writer is still open: finish the prepared commit
+	if err := writer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	commits, err := index.ListCommits(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset := commits[len(commits)-1].UserData()["offset"]; offset != "1" {
+		t.Errorf("expected offset 1, but %v", offset)
+	}
+}
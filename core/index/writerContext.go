@@ -0,0 +1,135 @@
+package index
+
+import (
+	"context"
+	"github.com/jtejido/golucene/core/analysis"
+	. "github.com/jtejido/golucene/core/index/model"
+)
+
+/*
+Context-aware variants of the IndexWriter operations. Each returns
+ctx.Err() once the context is cancelled or its deadline passes.
+Cancellation is checked between the phases of an operation; a phase
+that is already running (e.g. flushing a segment) completes first,
+and the index is always left consistent.
+*/
+
+/* Like AddDocument(), but gives up before indexing if ctx is done. */
+func (w *IndexWriter) AddDocumentContext(ctx context.Context, doc []IndexableField) error {
+	return w.UpdateDocumentContext(ctx, nil, doc, w.analyzer)
+}
+
+/* Like UpdateDocument(), but gives up before indexing if ctx is done. */
+func (w *IndexWriter) UpdateDocumentContext(ctx context.Context, term *Term,
+	doc []IndexableField, analyzer analysis.Analyzer) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return w.UpdateDocument(term, doc, analyzer)
+}
+
+/* Like AddDocuments(), but gives up before indexing if ctx is done. */
+func (w *IndexWriter) AddDocumentsContext(ctx context.Context, docs [][]IndexableField) error {
+	return w.UpdateDocumentsContext(ctx, nil, docs, w.analyzer)
+}
+
+/* Like UpdateDocuments(), but gives up before indexing if ctx is done. */
+func (w *IndexWriter) UpdateDocumentsContext(ctx context.Context, delTerm *Term,
+	docs [][]IndexableField, analyzer analysis.Analyzer) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return w.UpdateDocuments(delTerm, docs, analyzer)
+}
+
+/*
+Like Commit(), but checks ctx before flushing and again before the
+new segments file is published. If ctx is done after the flush, the
+prepared commit is rolled back, so the index still reflects the last
+successful commit, while the flushed segments remain in the writer
+for a later commit.
+*/
+func (w *IndexWriter) CommitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.ensureOpen()
+	w.commitLock.Lock()
+	defer w.commitLock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if w.pendingCommit == nil {
+		if err := w.prepareCommitInternal(w.config.MergePolicy()); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		w.abortPendingCommit()
+		return err
+	}
+	return w.finishCommit()
+}
+
+/* Drops a prepared commit, releasing the files it referenced. */
+func (w *IndexWriter) abortPendingCommit() {
+	w.Lock()
+	defer w.Unlock()
+	if w.pendingCommit == nil {
+		return
+	}
+	if w.infoStream.IsEnabled("IW") {
+		w.infoStream.Message("IW", "commit: abort pending commit")
+	}
+	w.pendingCommit.rollbackCommit(w.directory)
+	w.deleter.decRefFilesWhileSuppressingError(w.filesToCommit)
+	w.filesToCommit = nil
+	w.pendingCommit = nil
+}
+
+/*
+Asks the MergePolicy whether any merges are necessary now and, if
+so, hands them to the MergeScheduler. It does nothing if ctx is
+already done.
+*/
+func (w *IndexWriter) MaybeMergeContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.ensureOpen()
+	return w.maybeMerge(w.config.MergePolicy(), MERGE_TRIGGER_EXPLICIT, UNBOUNDED_MAX_MERGE_SEGMENTS)
+}
+
+/*
+Waits for all pending and running merges to finish, like the wait
+done by Close(), but returns ctx.Err() as soon as ctx is done. The
+merges themselves keep running in the background.
+*/
+func (mc *MergeControl) WaitForMergesContext(ctx context.Context) error {
+	mc.Lock() // synchronized
+	defer mc.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// wake up the waiter below so it can observe ctx
+			mc.Lock()
+			mc.mergeSignal.Broadcast()
+			mc.Unlock()
+		case <-done:
+		}
+	}()
+
+	for mc.pendingMerges.Len() > 0 || len(mc.runningMerges) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		mc.mergeSignal.Wait()
+	}
+	return nil
+}
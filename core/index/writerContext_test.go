@@ -0,0 +1,33 @@
+package index
+
+import (
+	"context"
+	"github.com/jtejido/golucene/core/util"
+	"testing"
+	"time"
+)
+
+func TestWaitForMergesContext(t *testing.T) {
+	mc := newMergeControl(util.NO_OUTPUT, nil)
+	if err := mc.WaitForMergesContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	merge := &OneMerge{}
+	mc.runningMerges[merge] = true
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := mc.WaitForMergesContext(ctx); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+
+	// finishing the merge wakes up the waiter
+	time.AfterFunc(10*time.Millisecond, func() {
+		mc.Lock()
+		defer mc.Unlock()
+		mc.mergeFinish(merge)
+	})
+	if err := mc.WaitForMergesContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
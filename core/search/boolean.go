@@ -36,6 +36,11 @@ func (q *BooleanQuery) AddClause(clause *BooleanClause) {
 	q.clauses = append(q.clauses, clause)
 }
 
+/* Returns the list of clauses in this query. */
+func (q *BooleanQuery) Clauses() []*BooleanClause {
+	return q.clauses
+}
+
 type BooleanWeight struct {
 	*WeightImpl
 	owner        *BooleanQuery
@@ -63,7 +68,7 @@ func newBooleanWeight(owner *BooleanQuery,
 			w.maxCoord++
 		}
 	}
-	w.WeightImpl = newWeightImpl(w)
+	w.WeightImpl = NewWeightImpl(w)
 	return w, nil
 }
 
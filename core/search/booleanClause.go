@@ -32,6 +32,10 @@ func NewBooleanClause(query Query, occur Occur) *BooleanClause {
 	}
 }
 
+func (c *BooleanClause) Query() Query {
+	return c.query
+}
+
 func (c *BooleanClause) IsProhibited() bool {
 	return c.occur == MUST_NOT
 }
@@ -0,0 +1,42 @@
+package search
+
+import (
+	"context"
+	"github.com/jtejido/golucene/core/index"
+)
+
+/*
+A Collector which wraps another Collector and aborts the search once
+the given context is cancelled or its deadline passes. The context is
+checked before every collected document and the search stops with
+ctx.Err().
+*/
+type CancellableCollector struct {
+	ctx       context.Context
+	collector Collector
+}
+
+func NewCancellableCollector(ctx context.Context, collector Collector) *CancellableCollector {
+	return &CancellableCollector{ctx: ctx, collector: collector}
+}
+
+func (c *CancellableCollector) Collect(doc int) error {
+	select {
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	default:
+		return c.collector.Collect(doc)
+	}
+}
+
+func (c *CancellableCollector) SetNextReader(ctx *index.AtomicReaderContext) {
+	c.collector.SetNextReader(ctx)
+}
+
+func (c *CancellableCollector) SetScorer(s Scorer) {
+	c.collector.SetScorer(s)
+}
+
+func (c *CancellableCollector) AcceptsDocsOutOfOrder() bool {
+	return c.collector.AcceptsDocsOutOfOrder()
+}
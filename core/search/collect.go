@@ -15,7 +15,7 @@ type ScoreDoc struct {
 	 * @see IndexSearcher#doc(int) */
 	Doc int
 	/** Only set by {@link TopDocs#merge} */
-	shardIndex int
+	ShardIndex int
 }
 
 func newScoreDoc(doc int, score float32) *ScoreDoc {
@@ -27,7 +27,7 @@ func newShardedScoreDoc(doc int, score float32, shardIndex int) *ScoreDoc {
 }
 
 func (d *ScoreDoc) String() string {
-	return fmt.Sprintf("doc=%v score=%v shardIndex=%v", d.Doc, d.Score, d.shardIndex)
+	return fmt.Sprintf("doc=%v score=%v shardIndex=%v", d.Doc, d.Score, d.ShardIndex)
 }
 
 type PriorityQueue struct {
@@ -0,0 +1,65 @@
+package search_test
+
+import (
+	"context"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestCollectors(t *testing.T) {
+	reader := testindex.NewReader(t, testindex.TextDocs("body", "a b", "a", "b", "c a")...)
+	searcher := search.NewIndexSearcher(reader)
+	q := search.NewTermQuery(index.NewTerm("body", "a"))
+
+	counter := search.NewTotalHitCountCollector()
+	top := search.NewTopScoreDocCollector(10, nil, true)
+	multi := search.WrapCollectors(nil, counter, top)
+	if multi.AcceptsDocsOutOfOrder() {
+		t.Error("expected in-order collection when any collector requires it")
+	}
+	if err := searcher.SearchCollector(q, nil, multi); err != nil {
+		t.Fatal(err)
+	}
+	if n := counter.TotalHits(); n != 3 {
+		t.Errorf("expected 3 hits, but %v", n)
+	}
+	if n := top.TopDocs().TotalHits; n != 3 {
+		t.Errorf("expected 3 top docs, but %v", n)
+	}
+
+	zero := search.NewTermQuery(index.NewTerm("body", "a"))
+	zero.SetBoost(0)
+	counter = search.NewTotalHitCountCollector()
+	if err := searcher.SearchCollector(zero, nil, search.NewPositiveScoresOnlyCollector(counter)); err != nil {
+		t.Fatal(err)
+	}
+	if n := counter.TotalHits(); n != 0 {
+		t.Errorf("expected zero scored docs to be skipped, but %v hits", n)
+	}
+
+	clock := util.NewCounter()
+	counter = search.NewTotalHitCountCollector()
+	limited := search.NewTimeLimitingCollector(counter, clock, 10)
+	limited.SetBaseline(0)
+	limited.SetGreedy(true)
+	clock.AddAndGet(11)
+	err := searcher.SearchCollector(q, nil, limited)
+	if te, ok := err.(*search.TimeExceededError); !ok {
+		t.Errorf("expected a TimeExceededError, but %v", err)
+	} else if te.LastDocCollected != 0 || counter.TotalHits() != 1 {
+		t.Errorf("expected greedy collection of doc 0 only, but %v (%v hits)", te, counter.TotalHits())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	counter = search.NewTotalHitCountCollector()
+	if err := searcher.SearchCollector(q, nil, search.NewCancellableCollector(ctx, counter)); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+	if n := counter.TotalHits(); n != 0 {
+		t.Errorf("expected no hits after cancellation, but %v", n)
+	}
+}
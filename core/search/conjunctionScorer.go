@@ -135,5 +135,6 @@ func docsAndFreqs(scorer Scorer) DocsAndFreqs {
 	return DocsAndFreqs{
 		scorer: scorer,
 		cost:   scorer.Cost(),
+		doc:    -1,
 	}
 }
@@ -0,0 +1,230 @@
+package search
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/ConstantScoreQuery.java
+
+/*
+A query that wraps another query or a filter and simply returns a
+constant score equal to the query boost for every document that
+matches the filter or query. For queries it therefore simply strips
+of all scores and returns a constant one.
+*/
+type ConstantScoreQuery struct {
+	*AbstractQuery
+	filter Filter
+	query  Query
+}
+
+/*
+Creates a new ConstantScoreQuery that wraps the given query. The
+score of each matching document equals the boost of the query.
+*/
+func NewConstantScoreQuery(query Query) *ConstantScoreQuery {
+	assert2(query != nil, "Query may not be nil")
+	ans := &ConstantScoreQuery{query: query}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+/*
+Creates a new ConstantScoreQuery that wraps the given filter. Only
+documents accepted by the filter match, each with a score equal to
+the boost of the query.
+*/
+func NewConstantScoreQueryWithFilter(filter Filter) *ConstantScoreQuery {
+	assert2(filter != nil, "Filter may not be nil")
+	ans := &ConstantScoreQuery{filter: filter}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+/* Returns the encapsulated filter, nil if a query is wrapped. */
+func (q *ConstantScoreQuery) Filter() Filter {
+	return q.filter
+}
+
+/* Returns the encapsulated query, nil if a filter is wrapped. */
+func (q *ConstantScoreQuery) Query() Query {
+	return q.query
+}
+
+func (q *ConstantScoreQuery) Rewrite(reader index.IndexReader) Query {
+	if q.query != nil {
+		if rewritten := q.query.Rewrite(reader); rewritten != q.query {
+			ans := NewConstantScoreQuery(rewritten)
+			ans.SetBoost(q.Boost())
+			return ans
+		}
+	}
+	return q
+}
+
+func (q *ConstantScoreQuery) Clone() Query {
+	ans := &ConstantScoreQuery{filter: q.filter, query: q.query}
+	if q.query != nil {
+		ans.query = q.query.Clone()
+	}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *ConstantScoreQuery) CreateWeight(searcher *IndexSearcher) (Weight, error) {
+	return newConstantWeight(q, searcher)
+}
+
+func (q *ConstantScoreQuery) ToString(field string) string {
+	var inner string
+	if q.query == nil {
+		inner = fmt.Sprintf("%v", q.filter)
+	} else {
+		inner = q.query.ToString(field)
+	}
+	if q.Boost() != 1 {
+		return fmt.Sprintf("ConstantScore(%v)^%v", inner, q.Boost())
+	}
+	return fmt.Sprintf("ConstantScore(%v)", inner)
+}
+
+type ConstantWeight struct {
+	*WeightImpl
+	owner       *ConstantScoreQuery
+	innerWeight Weight
+	queryNorm   float32
+	queryWeight float32
+}
+
+func newConstantWeight(owner *ConstantScoreQuery, searcher *IndexSearcher) (w *ConstantWeight, err error) {
+	w = &ConstantWeight{owner: owner}
+	if owner.query != nil {
+		if w.innerWeight, err = owner.query.CreateWeight(searcher); err != nil {
+			return nil, err
+		}
+	}
+	w.WeightImpl = NewWeightImpl(w)
+	return w, nil
+}
+
+func (w *ConstantWeight) ValueForNormalization() float32 {
+	// we calculate sumOfSquaredWeights of the inner weight, but ignore it (just to initialize everything)
+	if w.innerWeight != nil {
+		w.innerWeight.ValueForNormalization()
+	}
+	w.queryWeight = w.owner.Boost()
+	return w.queryWeight * w.queryWeight
+}
+
+func (w *ConstantWeight) Normalize(norm, topLevelBoost float32) {
+	w.queryNorm = norm * topLevelBoost
+	w.queryWeight *= w.queryNorm
+	// we normalize the inner weight, but ignore it (just to initialize everything)
+	if w.innerWeight != nil {
+		w.innerWeight.Normalize(norm, topLevelBoost)
+	}
+}
+
+func (w *ConstantWeight) IsScoresDocsOutOfOrder() bool {
+	if w.innerWeight != nil {
+		return w.innerWeight.IsScoresDocsOutOfOrder()
+	}
+	return false
+}
+
+func (w *ConstantWeight) Scorer(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (Scorer, error) {
+	var disi DocIdSetIterator
+	if w.owner.filter != nil {
+		assert(w.owner.query == nil)
+		dis, err := w.owner.filter.DocIdSet(ctx, acceptDocs)
+		if err != nil || dis == nil {
+			return nil, err
+		}
+		if disi, err = dis.Iterator(); err != nil {
+			return nil, err
+		}
+	} else {
+		assert(w.owner.query != nil && w.innerWeight != nil)
+		scorer, err := w.innerWeight.Scorer(ctx, acceptDocs)
+		if err != nil {
+			return nil, err
+		}
+		if scorer != nil {
+			disi = scorer
+		}
+	}
+	if disi == nil {
+		return nil, nil
+	}
+	return newConstantScorer(disi, w, w.queryWeight), nil
+}
+
+func (w *ConstantWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanation, error) {
+	cs, err := w.Scorer(ctx, ctx.Reader().(index.AtomicReader).LiveDocs())
+	if err != nil {
+		return nil, err
+	}
+	exists := false
+	if cs != nil {
+		var target int
+		if target, err = cs.Advance(doc); err != nil {
+			return nil, err
+		}
+		exists = target == doc
+	}
+
+	if exists {
+		ans := NewComplexExplanation(true, w.queryWeight,
+			fmt.Sprintf("%v, product of:", w.owner))
+		ans.AddDetail(NewExplanation(w.owner.Boost(), "boost"))
+		ans.AddDetail(NewExplanation(w.queryNorm, "queryNorm"))
+		return ans, nil
+	}
+	return NewComplexExplanation(false, 0,
+		fmt.Sprintf("%v doesn't match id %v", w.owner, doc)), nil
+}
+
+/* Scores every document of the wrapped iterator with the same score. */
+type ConstantScorer struct {
+	abstractScorer
+	docIdSetIterator DocIdSetIterator
+	theScore         float32
+}
+
+func newConstantScorer(docIdSetIterator DocIdSetIterator, w Weight, theScore float32) *ConstantScorer {
+	ans := &ConstantScorer{
+		docIdSetIterator: docIdSetIterator,
+		theScore:         theScore,
+	}
+	ans.weight = w
+	return ans
+}
+
+func (s *ConstantScorer) NextDoc() (int, error) {
+	return s.docIdSetIterator.NextDoc()
+}
+
+func (s *ConstantScorer) DocId() int {
+	return s.docIdSetIterator.DocId()
+}
+
+func (s *ConstantScorer) Score() (float32, error) {
+	assert(s.docIdSetIterator.DocId() != NO_MORE_DOCS)
+	return s.theScore, nil
+}
+
+func (s *ConstantScorer) Freq() (int, error) {
+	return 1, nil
+}
+
+func (s *ConstantScorer) Advance(target int) (int, error) {
+	return s.docIdSetIterator.Advance(target)
+}
+
+func (s *ConstantScorer) Cost() int64 {
+	return s.docIdSetIterator.Cost()
+}
@@ -0,0 +1,90 @@
+package search_test
+
+import (
+	ac "github.com/jtejido/golucene/analysis/core"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	sm "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestMatchAllAndConstantScore(t *testing.T) {
+	directory := testindex.NewDirectory(t)
+	analyzer := ac.NewWhitespaceAnalyzer()
+	writer, err := index.NewIndexWriter(directory, index.NewIndexWriterConfig(util.VERSION_LATEST, analyzer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDoc := func(id, text string) []model.IndexableField {
+		d := docu.NewDocument()
+		d.Add(docu.NewFieldFromString("id", id, docu.STRING_FIELD_TYPE_STORED))
+		d.Add(docu.NewTextFieldFromString("body", text, docu.STORE_NO))
+		return d.Fields()
+	}
+	for i, text := range []string{"a b", "a a a c", "c", "a"} {
+		if err := writer.AddDocument(newDoc(string('0'+rune(i)), text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader := testindex.OpenReader(t, directory)
+	searcher := search.NewIndexSearcher(reader)
+
+	all := search.NewMatchAllDocsQuery()
+	assertOrder(t, searcher, all, 0, 1, 2, 3)
+	// only the accepted docs, e.g. the live docs, are matched
+	w, err := searcher.CreateNormalizedWeight(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptDocs := util.NewFixedBitSetOf(4)
+	acceptDocs.Set(1)
+	acceptDocs.Set(3)
+	scorer, err := w.Scorer(reader.Context().Leaves()[0], acceptDocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []int{1, 3, sm.NO_MORE_DOCS} {
+		if doc, _ := scorer.NextDoc(); doc != expected {
+			t.Errorf("expected doc %v, but %v", expected, doc)
+		}
+	}
+
+	a := search.NewTermQuery(index.NewTerm("body", "a"))
+	csq := search.NewConstantScoreQuery(a)
+	csq.SetBoost(2)
+	docs, err := searcher.SearchTop(csq, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs.TotalHits != 3 {
+		t.Fatalf("expected 3 hits, but %v", docs.TotalHits)
+	}
+	for _, hit := range docs.ScoreDocs {
+		if hit.Score != docs.ScoreDocs[0].Score {
+			t.Errorf("expected a constant score, but %v", docs.ScoreDocs)
+		}
+	}
+
+	filtered := search.NewConstantScoreQueryWithFilter(search.NewQueryWrapperFilter(a))
+	assertOrder(t, searcher, filtered, 0, 1, 3)
+
+	exp, err := searcher.Explain(filtered, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.IsMatch() {
+		t.Errorf("expected doc 2 not to match, but %v", exp)
+	}
+	if exp, err = searcher.Explain(csq, 1); err != nil {
+		t.Fatal(err)
+	} else if !exp.IsMatch() || exp.Value() != docs.ScoreDocs[0].Score {
+		t.Errorf("expected a match with score %v, but %v", docs.ScoreDocs[0].Score, exp)
+	}
+}
@@ -0,0 +1,268 @@
+package search
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"math"
+	"sync"
+	"time"
+)
+
+// search/ControlledRealTimeReopenThread.java
+
+/*
+Utility goroutine that keeps reopening the searchers of a
+ReferenceManager, allowing callers to wait until a specific indexing
+change becomes visible to search.
+
+Make all changes through a TrackingIndexWriter; each change returns a
+generation which can be passed to WaitForGeneration(). When no
+goroutine is waiting, the manager is refreshed every targetMaxStale;
+while at least one goroutine waits on a generation that is not yet
+searchable, it is refreshed every targetMinStale.
+
+If a refresh fails, the goroutines waiting on a generation get the
+error; the loop keeps refreshing.
+*/
+type ControlledRealTimeReopenGoroutine struct {
+	manager        *ReferenceManager
+	writer         *index.TrackingIndexWriter
+	targetMaxStale time.Duration
+	targetMinStale time.Duration
+
+	lock            sync.Mutex
+	searchingGen    int64
+	refreshStartGen int64
+	waitingGen      int64
+	finish          bool
+	started         bool
+	listener        *handleRefresh
+	// set while the reopen loop refreshes: the generation is only
+	// searchable once the refresh succeeded
+	reopening  bool
+	pendingGen int64
+	refreshErr error
+	// closed and replaced every time searchingGen moves forward
+	genChanged chan struct{}
+
+	// wakes up the reopen loop early
+	wake chan struct{}
+	done chan struct{}
+}
+
+/*
+Create ControlledRealTimeReopenGoroutine, to periodically reopen the
+ReferenceManager. Call Start() to begin reopening.
+
+targetMaxStale is the maximum time before a new reopen, even if no
+goroutine is waiting. targetMinStale is the minimum time between
+reopens, used when some goroutine is waiting for a specific
+generation.
+*/
+func NewControlledRealTimeReopenGoroutine(writer *index.TrackingIndexWriter,
+	manager *ReferenceManager, targetMaxStale, targetMinStale time.Duration) *ControlledRealTimeReopenGoroutine {
+
+	assert2(targetMaxStale >= targetMinStale,
+		"targetMaxStale (= %v) < targetMinStale (= %v)", targetMaxStale, targetMinStale)
+	ans := &ControlledRealTimeReopenGoroutine{
+		manager:        manager,
+		writer:         writer,
+		targetMaxStale: targetMaxStale,
+		targetMinStale: targetMinStale,
+		genChanged:     make(chan struct{}),
+		wake:           make(chan struct{}, 1),
+		done:           make(chan struct{}),
+	}
+	ans.listener = &handleRefresh{ans}
+	manager.AddListener(ans.listener)
+	return ans
+}
+
+type handleRefresh struct {
+	owner *ControlledRealTimeReopenGoroutine
+}
+
+func (h *handleRefresh) BeforeRefresh() error {
+	// Save the gen as of when we started the reopen; AfterRefresh()
+	// copies this to searchingGen once the reopen completes:
+	h.owner.lock.Lock()
+	defer h.owner.lock.Unlock()
+	h.owner.refreshStartGen = h.owner.writer.GetAndIncrementGeneration()
+	return nil
+}
+
+func (h *handleRefresh) AfterRefresh(didRefresh bool) error {
+	h.owner.lock.Lock()
+	defer h.owner.lock.Unlock()
+	if h.owner.reopening {
+		h.owner.pendingGen = h.owner.refreshStartGen
+		return nil
+	}
+	h.owner.refreshDone(h.owner.refreshStartGen, nil)
+	return nil
+}
+
+/* Wakes up the waiting goroutines. Must be called with the lock held. */
+func (g *ControlledRealTimeReopenGoroutine) refreshDone(gen int64, err error) {
+	if err == nil && gen > g.searchingGen {
+		g.searchingGen = gen
+	}
+	g.refreshErr = err
+	close(g.genChanged)
+	g.genChanged = make(chan struct{})
+}
+
+func (g *ControlledRealTimeReopenGoroutine) signal() {
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+/* Starts the background reopen loop. */
+func (g *ControlledRealTimeReopenGoroutine) Start() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	assert2(!g.started, "ControlledRealTimeReopenGoroutine is already started")
+	g.started = true
+	go g.run()
+}
+
+func (g *ControlledRealTimeReopenGoroutine) run() {
+	defer close(g.done)
+	lastReopenStart := time.Now()
+
+	for {
+		for {
+			g.lock.Lock()
+			if g.finish {
+				g.lock.Unlock()
+				return
+			}
+			hasWaiting := g.waitingGen > g.searchingGen
+			g.lock.Unlock()
+
+			stale := g.targetMaxStale
+			if hasWaiting {
+				stale = g.targetMinStale
+			}
+			sleep := lastReopenStart.Add(stale).Sub(time.Now())
+			if sleep <= 0 {
+				break
+			}
+			timer := time.NewTimer(sleep)
+			select {
+			case <-g.wake:
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+
+		lastReopenStart = time.Now()
+		g.lock.Lock()
+		g.reopening, g.pendingGen = true, 0
+		g.lock.Unlock()
+		err := g.manager.MaybeRefreshBlocking()
+		g.lock.Lock()
+		g.reopening = false
+		g.refreshDone(g.pendingGen, err)
+		g.lock.Unlock()
+	}
+}
+
+/*
+Waits for the target generation to become visible in the searcher.
+If the current searcher is older than the target generation, this
+method will block until the searcher is reopened, by another
+goroutine, or until the reopen goroutine is closed. Returns the error
+of the reopen, if it failed meanwhile.
+*/
+func (g *ControlledRealTimeReopenGoroutine) WaitForGeneration(targetGen int64) error {
+	_, err := g.WaitForGenerationTimeout(targetGen, -1)
+	return err
+}
+
+/*
+Waits for the target generation to become visible in the searcher,
+up to a maximum specified duration. A negative duration waits
+indefinitely. Returns true if the targetGen is now available, or
+false if the wait timed out or the reopen failed, with its error.
+*/
+func (g *ControlledRealTimeReopenGoroutine) WaitForGenerationTimeout(targetGen int64, maxWait time.Duration) (bool, error) {
+	curGen := g.writer.Generation()
+	assert2(targetGen <= curGen,
+		"targetGen=%v was never returned by the ReferenceManager instance (current gen=%v)",
+		targetGen, curGen)
+
+	var deadline <-chan time.Time
+	if maxWait >= 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if targetGen <= g.searchingGen {
+		return true, nil
+	}
+	if targetGen > g.waitingGen {
+		g.waitingGen = targetGen
+	}
+	g.signal()
+
+	for {
+		changed := g.genChanged
+		g.lock.Unlock()
+		select {
+		case <-changed:
+		case <-deadline:
+			g.lock.Lock()
+			return false, nil
+		}
+		g.lock.Lock()
+		if targetGen <= g.searchingGen {
+			return true, nil
+		}
+		// only errors of refreshes done while waiting are reported
+		if g.refreshErr != nil {
+			return false, g.refreshErr
+		}
+	}
+}
+
+/* Returns which generation the current searcher is guaranteed to include. */
+func (g *ControlledRealTimeReopenGoroutine) SearchingGen() int64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.searchingGen
+}
+
+/*
+Stops the reopen loop and waits for it to exit. Goroutines still
+waiting on a generation are released.
+*/
+func (g *ControlledRealTimeReopenGoroutine) Close() error {
+	g.manager.RemoveListener(g.listener)
+
+	g.lock.Lock()
+	if g.finish {
+		g.lock.Unlock()
+		return nil
+	}
+	g.finish = true
+	started := g.started
+	g.lock.Unlock()
+	g.signal()
+
+	if started {
+		<-g.done
+	}
+
+	// Max it out so any waiting search goroutines will return:
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.searchingGen = math.MaxInt64
+	close(g.genChanged)
+	g.genChanged = make(chan struct{})
+	return nil
+}
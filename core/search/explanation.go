@@ -101,7 +101,7 @@ func newEmptyComplexExplanation() *ComplexExplanation {
 	return ans
 }
 
-func newComplexExplanation(match bool, value float32, desc string) *ComplexExplanation {
+func NewComplexExplanation(match bool, value float32, desc string) *ComplexExplanation {
 	ans := new(ComplexExplanation)
 	ans.ExplanationImpl = NewExplanation(value, desc)
 	ans.spi = ans
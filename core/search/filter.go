@@ -1,4 +1,141 @@
 package search
 
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/Filter.java
+
+/* Abstract base class for restricting which documents may be returned during searching. */
 type Filter interface {
+	/*
+		Creates a DocIdSet enumerating the documents that should be
+		permitted in search results. NOTE: nil can be returned if no
+		documents are accepted by this Filter.
+
+		Note: This method will be called once per segment in the index
+		during searching. The returned DocIdSet must refer to document
+		IDs for that segment, not for the top-level reader.
+
+		acceptDocs are the Bits that represent the allowable docs to
+		match (typically deleted docs but possibly filtering other
+		documents).
+	*/
+	DocIdSet(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (DocIdSet, error)
+}
+
+// search/DocIdSet.java
+
+/*
+A DocIdSet contains a set of doc ids. Implementing types must only
+implement Iterator() to provide access to the set.
+*/
+type DocIdSet interface {
+	// Provides a DocIdSetIterator to access the set. This
+	// implementation can return nil if there are no docs that match.
+	Iterator() (DocIdSetIterator, error)
+}
+
+// search/DocIdBitSet.java
+
+/* Simple DocIdSet and DocIdSetIterator backed by a FixedBitSet. */
+type DocIdBitSet struct {
+	bits *util.FixedBitSet
+}
+
+func NewDocIdBitSet(bits *util.FixedBitSet) *DocIdBitSet {
+	return &DocIdBitSet{bits}
+}
+
+func (s *DocIdBitSet) Iterator() (DocIdSetIterator, error) {
+	return &docIdBitSetIterator{bits: s.bits, docId: -1}, nil
+}
+
+/* Returns the underlying FixedBitSet. */
+func (s *DocIdBitSet) BitSet() *util.FixedBitSet {
+	return s.bits
+}
+
+type docIdBitSetIterator struct {
+	bits  *util.FixedBitSet
+	docId int
+}
+
+func (it *docIdBitSetIterator) DocId() int {
+	return it.docId
+}
+
+func (it *docIdBitSetIterator) NextDoc() (int, error) {
+	return it.Advance(it.docId + 1)
+}
+
+func (it *docIdBitSetIterator) Advance(target int) (int, error) {
+	if target >= it.bits.Length() {
+		it.docId = NO_MORE_DOCS
+		return it.docId, nil
+	}
+	if it.docId = it.bits.NextSetBit(target); it.docId == -1 {
+		it.docId = NO_MORE_DOCS
+	}
+	return it.docId, nil
+}
+
+func (it *docIdBitSetIterator) Cost() int64 {
+	// upper bound
+	return int64(it.bits.Length())
+}
+
+// search/QueryWrapperFilter.java
+
+/*
+Constrains search results to only match those which also match a
+provided query.
+
+This could be used, for example, with a NumericRangeQuery on a
+suitably formatted date field to implement date filtering. One could
+re-use a single CachingWrapperFilter(QueryWrapperFilter) that matches,
+e.g., only documents modified within the last week.
+*/
+type QueryWrapperFilter struct {
+	query Query
+}
+
+/* Constructs a filter which only matches documents matching query. */
+func NewQueryWrapperFilter(query Query) *QueryWrapperFilter {
+	assert2(query != nil, "Query may not be nil")
+	return &QueryWrapperFilter{query}
+}
+
+/* Returns the inner Query */
+func (f *QueryWrapperFilter) Query() Query {
+	return f.query
+}
+
+func (f *QueryWrapperFilter) DocIdSet(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (DocIdSet, error) {
+	// get a private context that is used to rewrite, createWeight and score eventually
+	privateContext := ctx.Reader().Context().(*index.AtomicReaderContext)
+	weight, err := NewIndexSearcher(privateContext.Reader()).CreateNormalizedWeight(f.query)
+	if err != nil {
+		return nil, err
+	}
+	return queryWrapperDocIdSet(func() (DocIdSetIterator, error) {
+		scorer, err := weight.Scorer(privateContext, acceptDocs)
+		if err != nil || scorer == nil {
+			return nil, err
+		}
+		return scorer, nil
+	}), nil
+}
+
+func (f *QueryWrapperFilter) String() string {
+	return fmt.Sprintf("QueryWrapperFilter(%v)", f.query)
+}
+
+type queryWrapperDocIdSet func() (DocIdSetIterator, error)
+
+func (s queryWrapperDocIdSet) Iterator() (DocIdSetIterator, error) {
+	return s()
 }
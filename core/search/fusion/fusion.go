@@ -0,0 +1,163 @@
+package fusion
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/search"
+	"sort"
+)
+
+/*
+A ranked list to fuse: the hits of a query over the fused searcher,
+scored with the given Similarity, or the searcher's own if nil. The
+contributions of the source are multiplied by its weight.
+*/
+type Source struct {
+	Name       string
+	Query      search.Query
+	Similarity search.Similarity
+	Weight     float32
+}
+
+func NewSource(name string, query search.Query, similarity search.Similarity) *Source {
+	assert2(name != "", "name cannot be empty")
+	return &Source{name, query, similarity, 1}
+}
+
+/*
+Hybrid ranking: runs several sources over the same IndexSearcher and
+fuses their ranked lists, with a Method such as reciprocal rank fusion
+or CombSUM/CombMNZ over normalized scores.
+
+Unlike MultiSimilarity, which sums raw scores of different scales, the
+sources' scores are combined through their ranks, or once normalized.
+Each source's top depth hits are fused, so a document only counts for
+the sources which ranked it that high.
+*/
+type Fuser struct {
+	method Method
+	depth  int
+}
+
+func NewFuser(method Method, depth int) *Fuser {
+	assert2(depth > 0, "depth must be > 0, got %v", depth)
+	return &Fuser{method, depth}
+}
+
+/*
+Returns the top n fused hits. TotalHits is the number of distinct
+documents among the sources' top depth hits.
+*/
+func (f *Fuser) Search(searcher *search.IndexSearcher, sources []*Source, n int) (*FusedTopDocs, error) {
+	assert2(len(sources) > 0, "no source to fuse")
+	assert2(n > 0, "n must be > 0, got %v", n)
+
+	ans := &FusedTopDocs{
+		method:    f.method,
+		sources:   sources,
+		searchers: make([]*search.IndexSearcher, len(sources)),
+		hits:      make(map[int][]*sourceHit),
+	}
+	for i, source := range sources {
+		// each source gets its own searcher, so that the caller's one
+		// keeps its similarity
+		ss := search.NewIndexSearcherFromContext(searcher.TopReaderContext())
+		if source.Similarity != nil {
+			ss.SetSimilarity(source.Similarity)
+		} else {
+			ss.SetSimilarity(searcher.Similarity())
+		}
+		ans.searchers[i] = ss
+
+		topDocs, err := ss.SearchTop(source.Query, f.depth)
+		if err != nil {
+			return nil, err
+		}
+		contributions := f.method.Contributions(topDocs.ScoreDocs)
+		for rank, hit := range topDocs.ScoreDocs {
+			ans.hits[hit.Doc] = append(ans.hits[hit.Doc], &sourceHit{
+				source:       i,
+				rank:         rank + 1,
+				score:        hit.Score,
+				contribution: contributions[rank] * source.Weight,
+			})
+		}
+	}
+
+	hits := make([]*search.ScoreDoc, 0, len(ans.hits))
+	for doc, sourceHits := range ans.hits {
+		hits = append(hits, search.NewScoreDoc(doc, ans.fuse(sourceHits)))
+	}
+	sort.Sort(search.ScoreDocsByScore(hits))
+	if len(hits) > n {
+		hits = hits[:n]
+	}
+	maxScore := float32(0)
+	if len(hits) > 0 {
+		maxScore = hits[0].Score
+	}
+	ans.TopDocs = search.NewTopDocs(len(ans.hits), hits, maxScore)
+	return ans, nil
+}
+
+/* A hit of a source, in its ranked list. */
+type sourceHit struct {
+	source       int
+	rank         int // from 1
+	score        float32
+	contribution float32
+}
+
+/*
+The fused hits of Fuser.Search, which can explain how each of the
+sources ranked a document.
+*/
+type FusedTopDocs struct {
+	search.TopDocs
+	method    Method
+	sources   []*Source
+	searchers []*search.IndexSearcher
+	hits      map[int][]*sourceHit // by doc, in source order
+}
+
+func (td *FusedTopDocs) fuse(hits []*sourceHit) float32 {
+	contributions := make([]float32, len(hits))
+	for i, hit := range hits {
+		contributions[i] = hit.contribution
+	}
+	return td.method.Combine(contributions)
+}
+
+/*
+Explains the fused score of a document: the rank, score and
+contribution it got from each source, with the source's own
+explanation of its score.
+*/
+func (td *FusedTopDocs) Explain(doc int) (search.Explanation, error) {
+	hits := td.hits[doc]
+	if len(hits) == 0 {
+		return search.NewComplexExplanation(false, 0,
+			fmt.Sprintf("%v: not within the top hits of any source", td.method)), nil
+	}
+	ans := search.NewComplexExplanation(true, td.fuse(hits),
+		fmt.Sprintf("%v of %v/%v sources:", td.method, len(hits), len(td.sources)))
+	next := 0
+	for i, source := range td.sources {
+		if next == len(hits) || hits[next].source != i {
+			ans.AddDetail(search.NewExplanation(0,
+				fmt.Sprintf("source '%v': not within the top hits", source.Name)))
+			continue
+		}
+		hit := hits[next]
+		next++
+		detail := search.NewExplanation(hit.contribution,
+			fmt.Sprintf("source '%v': contribution of rank %v, score %v, weight %v",
+				source.Name, hit.rank, hit.score, source.Weight))
+		sourceExp, err := td.searchers[i].Explain(source.Query, doc)
+		if err != nil {
+			return nil, err
+		}
+		detail.AddDetail(sourceExp)
+		ans.AddDetail(detail)
+	}
+	return ans, nil
+}
@@ -0,0 +1,112 @@
+package fusion
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/search/similarities"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"math"
+	"testing"
+)
+
+func TestNormalization(t *testing.T) {
+	for _, c := range []struct {
+		norm     Normalization
+		scores   []float32
+		expected []float32
+	}{
+		{NORMALIZATION_NONE, []float32{3, 1, 2}, []float32{3, 1, 2}},
+		{NORMALIZATION_MIN_MAX, []float32{3, 1, 2}, []float32{1, 0, 0.5}},
+		{NORMALIZATION_MIN_MAX, []float32{2, 2}, []float32{1, 1}},
+		{NORMALIZATION_Z_SCORE, []float32{3, 2, 1}, []float32{2.4494898, 1.2247449, 0}},
+		{NORMALIZATION_Z_SCORE, []float32{2, 2}, []float32{1, 1}},
+	} {
+		normalized := c.norm.Normalize(c.scores)
+		for i, v := range normalized {
+			if math.Abs(float64(v-c.expected[i])) > 1e-6 {
+				t.Errorf("%v of %v: expected %v, but %v", c.norm, c.scores, c.expected, normalized)
+				break
+			}
+		}
+	}
+}
+
+func TestFuser(t *testing.T) {
+	r := testindex.NewReader(t, testindex.TextDocs("body",
+		"apple banana",
+		"apple apple cherry",
+		"banana cherry",
+		"cherry date",
+		"apple date date",
+	)...)
+	searcher := search.NewIndexSearcher(r)
+	similarity := searcher.Similarity()
+
+	// docs 0, 1 and 4 have apple, docs 3 and 4 have date
+	sources := []*Source{
+		NewSource("bm25", search.NewTermQuery(index.NewTerm("body", "apple")),
+			similarities.NewDefaultBM25Similarity()),
+		NewSource("lm", search.NewTermQuery(index.NewTerm("body", "date")),
+			similarities.NewDefaultLMDirichletSimilarity()),
+	}
+	for _, method := range []Method{
+		NewRRF(DEFAULT_RRF_K),
+		NewCombSUM(NORMALIZATION_MIN_MAX),
+		NewCombMNZ(NORMALIZATION_MIN_MAX),
+		// doc 4 is last of the apple hits: it must not be penalized
+		// for being returned by the bm25 source
+		NewCombSUM(NORMALIZATION_Z_SCORE),
+		NewCombMNZ(NORMALIZATION_Z_SCORE),
+	} {
+		hits, err := NewFuser(method, 10).Search(searcher, sources, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hits.TotalHits != 4 || len(hits.ScoreDocs) != 3 {
+			t.Fatalf("%v: expected 3 of 4 hits, but %v of %v", method, len(hits.ScoreDocs), hits.TotalHits)
+		}
+		// the only doc both sources return comes first
+		if hits.ScoreDocs[0].Doc != 4 || hits.MaxScore() != hits.ScoreDocs[0].Score {
+			t.Errorf("%v: expected doc 4 first, but %v", method, hits.ScoreDocs)
+		}
+		for _, hit := range hits.ScoreDocs {
+			exp, err := hits.Explain(hit.Doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !exp.IsMatch() || exp.Value() != hit.Score {
+				t.Errorf("%v: explanation doesn't match score %v: %v", method, hit.Score, exp)
+			}
+		}
+		if exp, err := hits.Explain(2); err != nil || exp.IsMatch() {
+			t.Errorf("%v: expected doc 2 not to match, but %v %v", method, exp, err)
+		}
+	}
+
+	// contributions of RRF only depend on ranks and weights
+	sources[1].Weight = 2
+	hits, err := NewFuser(NewRRF(0), 10).Search(searcher, sources, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected float32
+	for _, source := range sources {
+		ss := search.NewIndexSearcher(r)
+		ss.SetSimilarity(source.Similarity)
+		topDocs, err := ss.SearchTop(source.Query, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rank, hit := range topDocs.ScoreDocs {
+			if hit.Doc == 4 {
+				expected += source.Weight / float32(rank+1)
+			}
+		}
+	}
+	if hit := hits.ScoreDocs[0]; hit.Doc != 4 || hit.Score != expected {
+		t.Errorf("expected doc 4 scored %v, but %v", expected, hit)
+	}
+	if searcher.Similarity() != similarity {
+		t.Errorf("the searcher's similarity changed")
+	}
+}
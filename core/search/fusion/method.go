@@ -0,0 +1,119 @@
+package fusion
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/search"
+)
+
+/*
+Combines the ranked lists of several sources into a single score per
+document: each list gives its hits a contribution, then the
+contributions a document received are combined.
+*/
+type Method interface {
+	// Returns the contributions of a ranked list of hits, best first.
+	Contributions(hits []*search.ScoreDoc) []float32
+	// Combines the contributions a document received from the sources
+	// which returned it.
+	Combine(contributions []float32) float32
+	String() string
+}
+
+/*
+Reciprocal rank fusion: a hit at rank r (from 1) contributes
+1 / (k + r), and contributions are summed. Only ranks matter, so the
+sources' scores don't have to be comparable.
+*/
+type RRF struct {
+	k int
+}
+
+/* The usual rank constant, which dampens the weight of the top ranks. */
+const DEFAULT_RRF_K = 60
+
+func NewRRF(k int) *RRF {
+	assert2(k >= 0, "k must be >= 0, got %v", k)
+	return &RRF{k}
+}
+
+func (m *RRF) Contributions(hits []*search.ScoreDoc) []float32 {
+	ans := make([]float32, len(hits))
+	for i := range hits {
+		ans[i] = 1 / float32(m.k+i+1)
+	}
+	return ans
+}
+
+func (m *RRF) Combine(contributions []float32) float32 {
+	return sum(contributions)
+}
+
+func (m *RRF) String() string {
+	return fmt.Sprintf("RRF(k=%v)", m.k)
+}
+
+/* CombSUM: sums the normalized scores of a document. */
+type CombSUM struct {
+	norm Normalization
+}
+
+func NewCombSUM(norm Normalization) *CombSUM {
+	return &CombSUM{norm}
+}
+
+func (m *CombSUM) Contributions(hits []*search.ScoreDoc) []float32 {
+	return m.norm.Normalize(scores(hits))
+}
+
+func (m *CombSUM) Combine(contributions []float32) float32 {
+	return sum(contributions)
+}
+
+func (m *CombSUM) String() string {
+	return fmt.Sprintf("CombSUM(%v)", m.norm)
+}
+
+/*
+CombMNZ: sums the normalized scores of a document, times the number of
+sources which returned it, favoring documents most sources agree on.
+*/
+type CombMNZ struct {
+	norm Normalization
+}
+
+func NewCombMNZ(norm Normalization) *CombMNZ {
+	return &CombMNZ{norm}
+}
+
+func (m *CombMNZ) Contributions(hits []*search.ScoreDoc) []float32 {
+	return m.norm.Normalize(scores(hits))
+}
+
+func (m *CombMNZ) Combine(contributions []float32) float32 {
+	return sum(contributions) * float32(len(contributions))
+}
+
+func (m *CombMNZ) String() string {
+	return fmt.Sprintf("CombMNZ(%v)", m.norm)
+}
+
+func scores(hits []*search.ScoreDoc) []float32 {
+	ans := make([]float32, len(hits))
+	for i, hit := range hits {
+		ans[i] = hit.Score
+	}
+	return ans
+}
+
+func sum(values []float32) (ans float32) {
+	for _, v := range values {
+		ans += v
+	}
+	return
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
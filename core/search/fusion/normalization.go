@@ -0,0 +1,86 @@
+package fusion
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+Maps the scores of a ranked list to a common scale, so that lists
+scored by different queries or similarities can be summed.
+*/
+type Normalization int
+
+const (
+	// raw scores
+	NORMALIZATION_NONE = Normalization(iota)
+	// (s - min) / (max - min), so that scores are in [0, 1]
+	NORMALIZATION_MIN_MAX
+	// (s - mean) / standard deviation, shifted so that the lowest score
+	// is 0: a negative contribution would rank a document below those
+	// the source did not return
+	NORMALIZATION_Z_SCORE
+)
+
+/*
+Returns the normalized scores. A list whose scores are all equal is
+normalized to 1 by both min-max and z-score.
+*/
+func (n Normalization) Normalize(scores []float32) []float32 {
+	ans := make([]float32, len(scores))
+	if len(scores) == 0 {
+		return ans
+	}
+	switch n {
+	case NORMALIZATION_NONE:
+		copy(ans, scores)
+	case NORMALIZATION_MIN_MAX:
+		min, max := scores[0], scores[0]
+		for _, s := range scores {
+			min = float32(math.Min(float64(min), float64(s)))
+			max = float32(math.Max(float64(max), float64(s)))
+		}
+		for i, s := range scores {
+			if max > min {
+				ans[i] = (s - min) / (max - min)
+			} else {
+				ans[i] = 1
+			}
+		}
+	case NORMALIZATION_Z_SCORE:
+		var sum, sumSquares float64
+		for _, s := range scores {
+			sum += float64(s)
+			sumSquares += float64(s) * float64(s)
+		}
+		mean := sum / float64(len(scores))
+		std := math.Sqrt(math.Max(0, sumSquares/float64(len(scores))-mean*mean))
+		min := float64(scores[0])
+		for _, s := range scores {
+			min = math.Min(min, float64(s))
+		}
+		for i, s := range scores {
+			if std > 0 {
+				// (s - mean) / std - (min - mean) / std
+				ans[i] = float32((float64(s) - min) / std)
+			} else {
+				ans[i] = 1
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unknown normalization: %v", int(n)))
+	}
+	return ans
+}
+
+func (n Normalization) String() string {
+	switch n {
+	case NORMALIZATION_NONE:
+		return "none"
+	case NORMALIZATION_MIN_MAX:
+		return "min-max"
+	case NORMALIZATION_Z_SCORE:
+		return "z-score"
+	}
+	return fmt.Sprintf("Normalization(%v)", int(n))
+}
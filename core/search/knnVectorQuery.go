@@ -0,0 +1,297 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+	"sort"
+)
+
+// search/KnnVectorQuery.java
+
+/*
+Matches the k documents whose dense vectors in a field are the nearest
+to a target vector, scored by the similarity function of the field
+(see KnnVectorField).
+
+Each segment is searched through its HNSW graph, so the results are
+approximate, then the k best hits of all segments are kept. The search
+is done once, when the query's Weight is created; the query can then
+be combined with others, e.g. in a BooleanQuery, as any query matching
+these k documents.
+
+If a filter is given, only the documents it accepts are considered, so
+that the query still finds k hits, if there are as many. A segment is
+searched exactly, comparing the target to all vectors accepted by the
+filter, if they're at most k or if the graph search would compare the
+target to more vectors than that.
+*/
+type KnnVectorQuery struct {
+	*AbstractQuery
+	field  string
+	target []float32
+	k      int
+	filter Filter
+}
+
+func NewKnnVectorQuery(field string, target []float32, k int) *KnnVectorQuery {
+	return NewKnnVectorQueryWithFilter(field, target, k, nil)
+}
+
+func NewKnnVectorQueryWithFilter(field string, target []float32, k int, filter Filter) *KnnVectorQuery {
+	assert2(field != "", "field cannot be empty")
+	assert2(len(target) > 0, "target cannot be empty")
+	assert2(k > 0, "k must be > 0, got %v", k)
+	ans := &KnnVectorQuery{field: field, target: target, k: k, filter: filter}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+func (q *KnnVectorQuery) Field() string     { return q.field }
+func (q *KnnVectorQuery) Target() []float32 { return q.target }
+func (q *KnnVectorQuery) K() int            { return q.k }
+func (q *KnnVectorQuery) Filter() Filter    { return q.filter }
+
+func (q *KnnVectorQuery) CreateWeight(searcher *IndexSearcher) (Weight, error) {
+	var hits []*ScoreDoc
+	for _, ctx := range searcher.TopReaderContext().Leaves() {
+		leafHits, err := q.searchLeaf(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range leafHits {
+			hit.Doc += ctx.DocBase
+		}
+		hits = append(hits, leafHits...)
+	}
+	return newKnnVectorWeight(q, topScoreDocs(hits, q.k)), nil
+}
+
+/* Returns the k nearest hits of a segment, with segment docIDs. */
+func (q *KnnVectorQuery) searchLeaf(ctx *index.AtomicReaderContext) ([]*ScoreDoc, error) {
+	reader := ctx.Reader().(index.AtomicReader)
+	fi := reader.FieldInfos().FieldInfoByName(q.field)
+	if fi == nil || !fi.HasVectorValues() {
+		return nil, nil
+	}
+	if fi.VectorDimension() != len(q.target) {
+		return nil, errors.New(fmt.Sprintf(
+			"vector query dimension: %v differs from field dimension: %v (field '%v')",
+			len(q.target), fi.VectorDimension(), q.field))
+	}
+
+	liveDocs := reader.LiveDocs()
+	if q.filter == nil {
+		docs, scores, _, err := reader.SearchNearestVectors(q.field, q.target, q.k, liveDocs, math.MaxInt32)
+		return newScoreDocs(docs, scores), err
+	}
+
+	set, err := q.filter.DocIdSet(ctx, liveDocs)
+	if err != nil || set == nil {
+		return nil, err
+	}
+	it, err := set.Iterator()
+	if err != nil || it == nil {
+		return nil, err
+	}
+	accepted := util.NewFixedBitSetOf(reader.MaxDoc())
+	for {
+		doc, err := it.NextDoc()
+		if err != nil {
+			return nil, err
+		}
+		if doc == NO_MORE_DOCS {
+			break
+		}
+		accepted.Set(doc)
+	}
+	cost := accepted.Cardinality()
+	if cost > q.k {
+		docs, scores, complete, err := reader.SearchNearestVectors(q.field, q.target, q.k, accepted, cost)
+		if err != nil || complete {
+			return newScoreDocs(docs, scores), err
+		}
+	}
+	return q.exactSearch(reader, fi, accepted), nil
+}
+
+/* Compares the target to all vectors of the accepted docs. */
+func (q *KnnVectorQuery) exactSearch(reader index.AtomicReader, fi *FieldInfo, accepted util.Bits) []*ScoreDoc {
+	values := reader.VectorValues(q.field)
+	if values == nil {
+		return nil
+	}
+	sim := fi.VectorSimilarity()
+	var hits []*ScoreDoc
+	for ord := 0; ord < values.Size(); ord++ {
+		if doc := values.Doc(ord); accepted.At(doc) {
+			hits = append(hits, newScoreDoc(doc, sim.Compare(q.target, values.Vector(ord))))
+		}
+	}
+	return topScoreDocs(hits, q.k)
+}
+
+func newScoreDocs(docs []int, scores []float32) []*ScoreDoc {
+	ans := make([]*ScoreDoc, len(docs))
+	for i, doc := range docs {
+		ans[i] = newScoreDoc(doc, scores[i])
+	}
+	return ans
+}
+
+/* Returns the n best hits, best first; ties are broken by docID. */
+func topScoreDocs(hits []*ScoreDoc, n int) []*ScoreDoc {
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score || hits[i].Score == hits[j].Score && hits[i].Doc < hits[j].Doc
+	})
+	if len(hits) > n {
+		hits = hits[:n]
+	}
+	return hits
+}
+
+func (q *KnnVectorQuery) Clone() Query {
+	ans := NewKnnVectorQueryWithFilter(q.field, q.target, q.k, q.filter)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *KnnVectorQuery) ToString(field string) string {
+	ans := fmt.Sprintf("KnnVectorQuery(%v[%v,...][%v]", q.field, q.target[0], q.k)
+	if q.filter != nil {
+		ans += fmt.Sprintf(", filter=%v", q.filter)
+	}
+	ans += ")"
+	if q.Boost() != 1 {
+		ans += fmt.Sprintf("^%v", q.Boost())
+	}
+	return ans
+}
+
+type knnVectorWeight struct {
+	*WeightImpl
+	owner       *KnnVectorQuery
+	hits        []*ScoreDoc // sorted by docID
+	queryWeight float32
+	queryNorm   float32
+}
+
+func newKnnVectorWeight(owner *KnnVectorQuery, hits []*ScoreDoc) *knnVectorWeight {
+	sorted := append([]*ScoreDoc(nil), hits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Doc < sorted[j].Doc })
+	ans := &knnVectorWeight{owner: owner, hits: sorted}
+	ans.WeightImpl = NewWeightImpl(ans)
+	return ans
+}
+
+func (w *knnVectorWeight) String() string {
+	return fmt.Sprintf("weight(%v)", w.owner)
+}
+
+func (w *knnVectorWeight) ValueForNormalization() float32 {
+	w.queryWeight = w.owner.Boost()
+	return w.queryWeight * w.queryWeight
+}
+
+func (w *knnVectorWeight) Normalize(queryNorm, topLevelBoost float32) {
+	w.queryNorm = queryNorm * topLevelBoost
+	w.queryWeight *= w.queryNorm
+}
+
+func (w *knnVectorWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+/* Returns the hits of a segment, sorted by docID. */
+func (w *knnVectorWeight) leafHits(ctx *index.AtomicReaderContext) []*ScoreDoc {
+	maxDoc := ctx.Reader().MaxDoc()
+	from := sort.Search(len(w.hits), func(i int) bool { return w.hits[i].Doc >= ctx.DocBase })
+	to := sort.Search(len(w.hits), func(i int) bool { return w.hits[i].Doc >= ctx.DocBase+maxDoc })
+	return w.hits[from:to]
+}
+
+func (w *knnVectorWeight) Scorer(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (Scorer, error) {
+	hits := w.leafHits(ctx)
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	return newKnnVectorScorer(w, hits, ctx.DocBase, acceptDocs), nil
+}
+
+func (w *knnVectorWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanation, error) {
+	for _, hit := range w.leafHits(ctx) {
+		if hit.Doc-ctx.DocBase == doc {
+			ans := NewComplexExplanation(true, hit.Score*w.queryWeight,
+				fmt.Sprintf("within top %v of %v, product of:", w.owner.k, w.owner.ToString("")))
+			ans.AddDetail(NewExplanation(hit.Score, "vector similarity"))
+			ans.AddDetail(NewExplanation(w.queryWeight, "queryWeight"))
+			return ans, nil
+		}
+	}
+	return NewComplexExplanation(false, 0,
+		fmt.Sprintf("not within top %v of %v", w.owner.k, w.owner.ToString(""))), nil
+}
+
+/* Iterates over the hits of a segment. */
+type knnVectorScorer struct {
+	abstractScorer
+	hits       []*ScoreDoc
+	docBase    int
+	acceptDocs util.Bits
+	upto       int
+	doc        int
+	boost      float32
+}
+
+func newKnnVectorScorer(w *knnVectorWeight, hits []*ScoreDoc, docBase int, acceptDocs util.Bits) *knnVectorScorer {
+	ans := &knnVectorScorer{
+		hits:       hits,
+		docBase:    docBase,
+		acceptDocs: acceptDocs,
+		upto:       -1,
+		doc:        -1,
+		boost:      w.queryWeight,
+	}
+	ans.weight = w
+	return ans
+}
+
+func (s *knnVectorScorer) DocId() int {
+	return s.doc
+}
+
+func (s *knnVectorScorer) NextDoc() (int, error) {
+	for s.upto++; s.upto < len(s.hits); s.upto++ {
+		s.doc = s.hits[s.upto].Doc - s.docBase
+		if s.acceptDocs == nil || s.acceptDocs.At(s.doc) {
+			return s.doc, nil
+		}
+	}
+	s.doc = NO_MORE_DOCS
+	return s.doc, nil
+}
+
+func (s *knnVectorScorer) Advance(target int) (int, error) {
+	for {
+		doc, err := s.NextDoc()
+		if err != nil || doc >= target {
+			return doc, err
+		}
+	}
+}
+
+func (s *knnVectorScorer) Score() (float32, error) {
+	return s.hits[s.upto].Score * s.boost, nil
+}
+
+func (s *knnVectorScorer) Freq() (int, error) {
+	return 1, nil
+}
+
+func (s *knnVectorScorer) Cost() int64 {
+	return int64(len(s.hits))
+}
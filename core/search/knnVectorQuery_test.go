@@ -0,0 +1,195 @@
+package search_test
+
+import (
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestKnnVectorQuery(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	randomVector := func() []float32 {
+		v := make([]float32, 8)
+		for i := range v {
+			v[i] = rnd.Float32()*2 - 1
+		}
+		return v
+	}
+	vectors := make([][]float32, 400)
+	for i := range vectors {
+		vectors[i] = randomVector()
+	}
+	// less than a block of postings per group, see lucene41
+	group := func(id int) string {
+		return strconv.Itoa(id % 4)
+	}
+
+	for _, sim := range []model.VectorSimilarityFunction{
+		model.VECTOR_SIMILARITY_COSINE, model.VECTOR_SIMILARITY_EUCLIDEAN,
+	} {
+		// two segments
+		dir := testindex.NewDirectory(t)
+		w := testindex.NewWriter(t, dir, nil)
+		for id, v := range vectors {
+			d := docu.NewDocument()
+			d.Add(docu.NewFieldFromString("id", strconv.Itoa(id), docu.STRING_FIELD_TYPE_STORED))
+			d.Add(docu.NewFieldFromString("group", group(id), docu.STRING_FIELD_TYPE_NOT_STORED))
+			d.Add(docu.NewKnnVectorField("vector", v, sim))
+			if err := w.AddDocument(d.Fields()); err != nil {
+				t.Fatal(err)
+			}
+			if id == len(vectors)/2 {
+				if err := w.Commit(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		reader := testindex.OpenReader(t, dir)
+
+		// the same index, merged in a single segment
+		mergedDir := testindex.NewDirectory(t)
+		w = testindex.NewWriter(t, mergedDir, nil)
+		if err := w.AddIndexesFromReaders(reader); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		merged := testindex.OpenReader(t, mergedDir)
+		if n := len(merged.Leaves()); n != 1 {
+			t.Fatalf("expected 1 merged segment, but %v", n)
+		}
+
+		// ids of the k nearest vectors to target, among the accepted ones
+		exact := func(target []float32, k int, accept func(id int) bool) []int {
+			var ids []int
+			for id := range vectors {
+				if accept(id) {
+					ids = append(ids, id)
+				}
+			}
+			sort.SliceStable(ids, func(i, j int) bool {
+				return sim.Compare(target, vectors[ids[i]]) > sim.Compare(target, vectors[ids[j]])
+			})
+			return ids[:k]
+		}
+		all := func(id int) bool { return true }
+		first := func(id int) bool { return id%4 == 0 }
+		second := func(id int) bool { return id%4 == 1 }
+
+		for _, r := range []index.IndexReader{reader, merged} {
+			searcher := search.NewIndexSearcher(r)
+			// returns the ids of the hits and checks they're sorted by score
+			hitIds := func(q search.Query, k int) []int {
+				docs, err := searcher.SearchTop(q, k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ids []int
+				for i, hit := range docs.ScoreDocs {
+					d, err := r.Document(hit.Doc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					id, _ := strconv.Atoi(d.Get("id"))
+					ids = append(ids, id)
+					if i > 0 && hit.Score > docs.ScoreDocs[i-1].Score {
+						t.Errorf("%v: hits out of order: %v", q, docs.ScoreDocs)
+					}
+				}
+				return ids
+			}
+			found, total := 0, 0
+			recall := func(q search.Query, expected []int, accept func(id int) bool) {
+				ids := hitIds(q, len(expected))
+				for _, id := range ids {
+					if !accept(id) {
+						t.Errorf("%v: unexpected hit %v", q, id)
+					}
+				}
+				set := make(map[int]bool)
+				for _, id := range ids {
+					set[id] = true
+				}
+				for _, id := range expected {
+					if set[id] {
+						found++
+					}
+				}
+				total += len(expected)
+			}
+
+			for i := 0; i < 20; i++ {
+				target := randomVector()
+				q := search.NewKnnVectorQuery("vector", target, 10)
+				recall(q, exact(target, 10, all), all)
+
+				// filtered, down to a few docs searched exactly
+				filter := search.NewQueryWrapperFilter(search.NewTermQuery(index.NewTerm("group", "0")))
+				recall(search.NewKnnVectorQueryWithFilter("vector", target, 10, filter), exact(target, 10, first), first)
+				few := search.NewBooleanQuery()
+				for _, id := range []int{3, 17, 250} {
+					few.Add(search.NewTermQuery(index.NewTerm("id", strconv.Itoa(id))), search.SHOULD)
+				}
+				filter = search.NewQueryWrapperFilter(few)
+				if ids := hitIds(search.NewKnnVectorQueryWithFilter("vector", target, 10, filter), 10); len(ids) != 3 {
+					t.Errorf("expected the 3 filtered docs, but %v", ids)
+				}
+
+				// combined with a term query
+				bq := search.NewBooleanQuery()
+				bq.Add(q, search.MUST)
+				bq.Add(search.NewTermQuery(index.NewTerm("group", "1")), search.MUST)
+				expected := 0
+				for _, id := range exact(target, 10, all) {
+					if second(id) {
+						expected++
+					}
+				}
+				ids := hitIds(bq, 10)
+				for _, id := range ids {
+					if !second(id) {
+						t.Errorf("%v: unexpected hit %v", bq, id)
+					}
+				}
+				if len(ids) > expected+2 || len(ids) < expected-2 {
+					t.Errorf("%v: expected about %v hits, but %v", bq, expected, ids)
+				}
+			}
+			if float64(found) < 0.9*float64(total) {
+				t.Errorf("%v %v segments: recall too low: %v/%v", sim, len(r.Leaves()), found, total)
+			}
+
+			if _, err := searcher.SearchTop(search.NewKnnVectorQuery("vector", []float32{1}, 10), 10); err == nil {
+				t.Errorf("expected error searching a vector of another dimension")
+			}
+			hits, err := searcher.SearchTop(search.NewKnnVectorQuery("none", vectors[0], 10), 10)
+			if err != nil || hits.TotalHits != 0 {
+				t.Errorf("expected no hit on a field without vectors, but %v %v", hits.TotalHits, err)
+			}
+		}
+	}
+
+	// a field keeps the dimension of its first vector
+	w := testindex.NewWriter(t, testindex.NewDirectory(t), nil)
+	defer w.Close()
+	for _, v := range [][]float32{{1, 2, 3}, {1, 2}} {
+		d := docu.NewDocument()
+		d.Add(docu.NewKnnVectorField("vector", v, model.VECTOR_SIMILARITY_DOT_PRODUCT))
+		err := w.AddDocument(d.Fields())
+		if len(v) == 3 && err != nil {
+			t.Fatal(err)
+		} else if len(v) == 2 && err == nil {
+			t.Errorf("expected error adding a vector of another dimension")
+		}
+	}
+}
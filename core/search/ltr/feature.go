@@ -0,0 +1,241 @@
+package ltr
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+)
+
+/*
+A Feature extracts a single float value from a document that was
+matched by the first-pass query. Features are identified by name, and
+a Model refers to the features it needs by those names.
+*/
+type Feature interface {
+	Name() string
+	// Creates the searcher dependent state of this feature.
+	CreateWeight(searcher *search.IndexSearcher) (FeatureWeight, error)
+}
+
+type FeatureWeight interface {
+	// Returns the scorer for the given segment. Documents are always
+	// asked in increasing order.
+	Scorer(ctx *index.AtomicReaderContext) (FeatureScorer, error)
+}
+
+type FeatureScorer interface {
+	// Returns the feature value of the segment local doc, given the
+	// score the hit was assigned by the first-pass query.
+	Value(doc int, firstPassScore float32) (float32, error)
+}
+
+// Original score
+
+/* A feature whose value is the score assigned by the first-pass query. */
+type OriginalScoreFeature struct {
+	name string
+}
+
+func NewOriginalScoreFeature(name string) *OriginalScoreFeature {
+	return &OriginalScoreFeature{name}
+}
+
+func (f *OriginalScoreFeature) Name() string { return f.name }
+
+func (f *OriginalScoreFeature) CreateWeight(searcher *search.IndexSearcher) (FeatureWeight, error) {
+	return f, nil
+}
+
+func (f *OriginalScoreFeature) Scorer(ctx *index.AtomicReaderContext) (FeatureScorer, error) {
+	return f, nil
+}
+
+func (f *OriginalScoreFeature) Value(doc int, firstPassScore float32) (float32, error) {
+	return firstPassScore, nil
+}
+
+func (f *OriginalScoreFeature) String() string {
+	return fmt.Sprintf("OriginalScoreFeature(%v)", f.name)
+}
+
+// Query based features
+
+/*
+Shared by features which are computed from a query Scorer positioned
+on the requested document. Documents not matched by the query get a
+value of 0.
+*/
+type queryFeatureWeight struct {
+	weight search.Weight
+	value  func(scorer search.Scorer) (float32, error)
+}
+
+func (w *queryFeatureWeight) Scorer(ctx *index.AtomicReaderContext) (FeatureScorer, error) {
+	scorer, err := w.weight.Scorer(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &queryFeatureScorer{scorer, w.value}, nil
+}
+
+type queryFeatureScorer struct {
+	scorer search.Scorer
+	value  func(scorer search.Scorer) (float32, error)
+}
+
+func (s *queryFeatureScorer) Value(doc int, firstPassScore float32) (float32, error) {
+	if s.scorer == nil {
+		return 0, nil
+	}
+	actual := s.scorer.DocId()
+	if actual < doc {
+		var err error
+		if actual, err = s.scorer.Advance(doc); err != nil {
+			return 0, err
+		}
+	}
+	if actual != doc {
+		return 0, nil
+	}
+	return s.value(s.scorer)
+}
+
+func scoreOf(scorer search.Scorer) (float32, error) {
+	return scorer.Score()
+}
+
+func freqOf(scorer search.Scorer) (float32, error) {
+	freq, err := scorer.Freq()
+	return float32(freq), err
+}
+
+/*
+A feature whose value is the score of a query computed with the given
+Similarity, e.g. one of the models in core/search/similarities. If no
+Similarity is given, the one of the searcher is used.
+*/
+type SimilarityFeature struct {
+	name       string
+	query      search.Query
+	similarity search.Similarity
+}
+
+func NewSimilarityFeature(name string, query search.Query, similarity search.Similarity) *SimilarityFeature {
+	return &SimilarityFeature{name, query, similarity}
+}
+
+func (f *SimilarityFeature) Name() string { return f.name }
+
+func (f *SimilarityFeature) CreateWeight(searcher *search.IndexSearcher) (FeatureWeight, error) {
+	if f.similarity != nil {
+		ss := search.NewIndexSearcherFromContext(searcher.TopReaderContext())
+		ss.SetSimilarity(f.similarity)
+		searcher = ss
+	}
+	weight, err := searcher.CreateNormalizedWeight(f.query)
+	if err != nil {
+		return nil, err
+	}
+	return &queryFeatureWeight{weight, scoreOf}, nil
+}
+
+func (f *SimilarityFeature) String() string {
+	return fmt.Sprintf("SimilarityFeature(%v, %v, %v)", f.name, f.query, f.similarity)
+}
+
+/*
+A feature whose value is the number of times the exact phrase occurs
+in the field of the document.
+*/
+type PhraseMatchFeature struct {
+	name  string
+	field string
+	terms []string
+}
+
+func NewPhraseMatchFeature(name, field string, terms ...string) *PhraseMatchFeature {
+	return &PhraseMatchFeature{name, field, terms}
+}
+
+func (f *PhraseMatchFeature) Name() string { return f.name }
+
+func (f *PhraseMatchFeature) CreateWeight(searcher *search.IndexSearcher) (FeatureWeight, error) {
+	var query search.Query
+	if len(f.terms) == 1 {
+		query = search.NewTermQuery(index.NewTerm(f.field, f.terms[0]))
+	} else {
+		pq := search.NewPhraseQuery()
+		for _, term := range f.terms {
+			pq.Add(index.NewTerm(f.field, term))
+		}
+		query = pq
+	}
+	weight, err := searcher.CreateNormalizedWeight(query)
+	if err != nil {
+		return nil, err
+	}
+	return &queryFeatureWeight{weight, freqOf}, nil
+}
+
+func (f *PhraseMatchFeature) String() string {
+	return fmt.Sprintf("PhraseMatchFeature(%v, %v:%v)", f.name, f.field, f.terms)
+}
+
+// Field length
+
+/*
+A feature whose value is the length of the field in number of terms,
+as decoded from the norms of the field. Since norms are encoded in a
+single byte, the value is an approximation. Documents without norms
+for the field get a value of 0.
+*/
+type FieldLengthFeature struct {
+	name  string
+	field string
+}
+
+func NewFieldLengthFeature(name, field string) *FieldLengthFeature {
+	return &FieldLengthFeature{name, field}
+}
+
+func (f *FieldLengthFeature) Name() string { return f.name }
+
+func (f *FieldLengthFeature) CreateWeight(searcher *search.IndexSearcher) (FeatureWeight, error) {
+	return f, nil
+}
+
+func (f *FieldLengthFeature) Scorer(ctx *index.AtomicReaderContext) (FeatureScorer, error) {
+	norms, err := ctx.Reader().(index.AtomicReader).NormValues(f.field)
+	if err != nil {
+		return nil, err
+	}
+	return fieldLengthScorer(func(doc int) float32 {
+		if norms == nil {
+			return 0
+		}
+		return decodeFieldLength(byte(norms(doc)))
+	}), nil
+}
+
+func (f *FieldLengthFeature) String() string {
+	return fmt.Sprintf("FieldLengthFeature(%v, %v)", f.name, f.field)
+}
+
+type fieldLengthScorer func(doc int) float32
+
+func (s fieldLengthScorer) Value(doc int, firstPassScore float32) (float32, error) {
+	return s(doc), nil
+}
+
+/*
+Reverses the norm encoding shared by the similarities in this port,
+i.e. 1/sqrt(length) compressed by SmallFloat.
+*/
+func decodeFieldLength(norm byte) float32 {
+	f := util.Byte315ToFloat(norm)
+	if f == 0 {
+		return 0
+	}
+	return 1 / (f * f)
+}
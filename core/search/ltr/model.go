@@ -0,0 +1,263 @@
+package ltr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/search"
+	"io/ioutil"
+)
+
+/*
+A Model maps the feature vector of a document to its new score. The
+values are passed in the order of Features().
+*/
+type Model interface {
+	// The names of the features this model needs, in vector order.
+	Features() []string
+	Score(values []float32) float32
+	Explain(values []float32) search.Explanation
+}
+
+/*
+Loads a Model from a JSON file. The file describes the kind of model,
+the names of the features it consumes and its parameters, e.g.
+
+	{
+	  "type": "linear",
+	  "features": ["bm25", "titleLength"],
+	  "params": {"bias": 0.5, "weights": {"bm25": 1.0, "titleLength": -0.1}}
+	}
+
+or
+
+	{
+	  "type": "ensemble",
+	  "features": ["bm25", "phrase"],
+	  "params": {"trees": [{"weight": 1.0, "root": {
+	    "feature": "phrase", "threshold": 0.5,
+	    "left": {"value": 0.1},
+	    "right": {"value": 2.0}}}]}
+	}
+
+Internal tree nodes go left when the feature value is less than or
+equal to the threshold, and right otherwise.
+*/
+func LoadModel(path string) (Model, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseModel(data)
+}
+
+/* Parses a Model from its JSON form. See LoadModel. */
+func ParseModel(data []byte) (Model, error) {
+	var def struct {
+		Type     string          `json:"type"`
+		Features []string        `json:"features"`
+		Params   json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, err
+	}
+	if len(def.Features) == 0 {
+		return nil, errors.New("model must declare at least one feature")
+	}
+	seen := make(map[string]int)
+	for i, name := range def.Features {
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("feature '%v' is declared twice", name)
+		}
+		seen[name] = i
+	}
+
+	switch def.Type {
+	case "linear":
+		return parseLinearModel(def.Features, seen, def.Params)
+	case "ensemble":
+		return parseTreeEnsembleModel(def.Features, seen, def.Params)
+	}
+	return nil, fmt.Errorf("unknown model type '%v'", def.Type)
+}
+
+// Linear model
+
+/* Scores a document by the weighted sum of its features plus a bias. */
+type LinearModel struct {
+	features []string
+	weights  []float32
+	bias     float32
+}
+
+func NewLinearModel(features []string, weights []float32, bias float32) *LinearModel {
+	assert2(len(features) == len(weights), "%v features but %v weights", len(features), len(weights))
+	return &LinearModel{features, weights, bias}
+}
+
+func parseLinearModel(features []string, ords map[string]int, data json.RawMessage) (*LinearModel, error) {
+	var params struct {
+		Bias    float32            `json:"bias"`
+		Weights map[string]float32 `json:"weights"`
+	}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+	weights := make([]float32, len(features))
+	for name, weight := range params.Weights {
+		ord, ok := ords[name]
+		if !ok {
+			return nil, fmt.Errorf("weight given for undeclared feature '%v'", name)
+		}
+		weights[ord] = weight
+	}
+	return NewLinearModel(features, weights, params.Bias), nil
+}
+
+func (m *LinearModel) Features() []string { return m.features }
+
+func (m *LinearModel) Score(values []float32) float32 {
+	score := m.bias
+	for i, v := range values {
+		score += m.weights[i] * v
+	}
+	return score
+}
+
+func (m *LinearModel) Explain(values []float32) search.Explanation {
+	ans := search.NewExplanation(m.Score(values), "LinearModel, sum of:")
+	for i, v := range values {
+		ans.AddDetail(search.NewExplanation(m.weights[i]*v,
+			fmt.Sprintf("%v * %v=%v", m.weights[i], m.features[i], v)))
+	}
+	if m.bias != 0 {
+		ans.AddDetail(search.NewExplanation(m.bias, "bias"))
+	}
+	return ans
+}
+
+// Tree ensemble model
+
+type treeNode struct {
+	// leaf
+	value float32
+	// split
+	feature     int
+	threshold   float32
+	left, right *treeNode
+}
+
+func (n *treeNode) isLeaf() bool {
+	return n.left == nil
+}
+
+type jsonTreeNode struct {
+	Value     float32       `json:"value"`
+	Feature   string        `json:"feature"`
+	Threshold float32       `json:"threshold"`
+	Left      *jsonTreeNode `json:"left"`
+	Right     *jsonTreeNode `json:"right"`
+}
+
+func (n *jsonTreeNode) build(ords map[string]int) (*treeNode, error) {
+	if n.Left == nil && n.Right == nil {
+		return &treeNode{value: n.Value}, nil
+	}
+	if n.Left == nil || n.Right == nil {
+		return nil, fmt.Errorf("split on '%v' must have both a left and a right branch", n.Feature)
+	}
+	ord, ok := ords[n.Feature]
+	if !ok {
+		return nil, fmt.Errorf("split on undeclared feature '%v'", n.Feature)
+	}
+	left, err := n.Left.build(ords)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.Right.build(ords)
+	if err != nil {
+		return nil, err
+	}
+	return &treeNode{feature: ord, threshold: n.Threshold, left: left, right: right}, nil
+}
+
+type regressionTree struct {
+	weight float32
+	root   *treeNode
+}
+
+func (t *regressionTree) leaf(values []float32) *treeNode {
+	n := t.root
+	for !n.isLeaf() {
+		if values[n.feature] <= n.threshold {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return n
+}
+
+/*
+Scores a document by the weighted sum of the outputs of additive
+regression trees, as produced by gradient boosting (e.g. LambdaMART).
+*/
+type TreeEnsembleModel struct {
+	features []string
+	trees    []*regressionTree
+}
+
+func parseTreeEnsembleModel(features []string, ords map[string]int, data json.RawMessage) (*TreeEnsembleModel, error) {
+	var params struct {
+		Trees []struct {
+			Weight *float32      `json:"weight"`
+			Root   *jsonTreeNode `json:"root"`
+		} `json:"trees"`
+	}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+	if len(params.Trees) == 0 {
+		return nil, errors.New("ensemble must contain at least one tree")
+	}
+	ans := &TreeEnsembleModel{features: features}
+	for i, t := range params.Trees {
+		if t.Root == nil {
+			return nil, fmt.Errorf("tree %v has no root", i)
+		}
+		root, err := t.Root.build(ords)
+		if err != nil {
+			return nil, fmt.Errorf("tree %v: %v", i, err)
+		}
+		weight := float32(1)
+		if t.Weight != nil {
+			weight = *t.Weight
+		}
+		ans.trees = append(ans.trees, &regressionTree{weight, root})
+	}
+	return ans, nil
+}
+
+func (m *TreeEnsembleModel) Features() []string { return m.features }
+
+func (m *TreeEnsembleModel) Score(values []float32) (score float32) {
+	for _, t := range m.trees {
+		score += t.weight * t.leaf(values).value
+	}
+	return
+}
+
+func (m *TreeEnsembleModel) Explain(values []float32) search.Explanation {
+	ans := search.NewExplanation(m.Score(values), "TreeEnsembleModel, sum of:")
+	for i, t := range m.trees {
+		ans.AddDetail(search.NewExplanation(t.weight*t.leaf(values).value,
+			fmt.Sprintf("tree %v, weight=%v", i, t.weight)))
+	}
+	return ans
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
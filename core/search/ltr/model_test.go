@@ -0,0 +1,84 @@
+package ltr
+
+import (
+	"testing"
+)
+
+func TestParseLinearModel(t *testing.T) {
+	m, err := ParseModel([]byte(`{
+		"type": "linear",
+		"features": ["bm25", "length"],
+		"params": {"bias": 0.5, "weights": {"bm25": 2, "length": -0.25}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(m.Features()); n != 2 {
+		t.Fatalf("Expected 2 features, but %v", n)
+	}
+	if score := m.Score([]float32{1.5, 4}); score != 2.5 {
+		t.Errorf("Expected score 2.5, but %v", score)
+	}
+	if exp := m.Explain([]float32{1.5, 4}); exp.Value() != 2.5 {
+		t.Errorf("Explanation doesn't match score: %v", exp.Value())
+	}
+}
+
+func TestParseTreeEnsembleModel(t *testing.T) {
+	m, err := ParseModel([]byte(`{
+		"type": "ensemble",
+		"features": ["bm25", "phrase"],
+		"params": {"trees": [
+			{"weight": 0.5, "root": {"feature": "phrase", "threshold": 0.5,
+				"left": {"value": 1},
+				"right": {"feature": "bm25", "threshold": 2,
+					"left": {"value": 2},
+					"right": {"value": 4}}}},
+			{"root": {"value": 1}}
+		]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		values []float32
+		score  float32
+	}{
+		{[]float32{3, 0}, 1.5},
+		{[]float32{1, 1}, 2},
+		{[]float32{2.5, 1}, 3},
+	} {
+		if score := m.Score(c.values); score != c.score {
+			t.Errorf("Expected score %v for %v, but %v", c.score, c.values, score)
+		}
+	}
+}
+
+func TestParseInvalidModels(t *testing.T) {
+	for _, data := range []string{
+		`{"type": "svm", "features": ["a"]}`,
+		`{"type": "linear", "features": []}`,
+		`{"type": "linear", "features": ["a", "a"]}`,
+		`{"type": "linear", "features": ["a"], "params": {"weights": {"b": 1}}}`,
+		`{"type": "ensemble", "features": ["a"], "params": {"trees": []}}`,
+		`{"type": "ensemble", "features": ["a"], "params": {"trees": [
+			{"root": {"feature": "b", "threshold": 1, "left": {"value": 1}, "right": {"value": 2}}}]}}`,
+		`{"type": "ensemble", "features": ["a"], "params": {"trees": [
+			{"root": {"feature": "a", "threshold": 1, "left": {"value": 1}}}]}}`,
+	} {
+		if _, err := ParseModel([]byte(data)); err == nil {
+			t.Errorf("Expected error for %v", data)
+		}
+	}
+}
+
+func TestRescorerNeedsAllModelFeatures(t *testing.T) {
+	m := NewLinearModel([]string{"score", "length"}, []float32{1, 1}, 0)
+	if _, err := NewLTRRescorer(m, NewOriginalScoreFeature("score")); err == nil {
+		t.Error("Expected error for missing feature")
+	}
+	if _, err := NewLTRRescorer(m, NewOriginalScoreFeature("score"),
+		NewFieldLengthFeature("length", "body")); err != nil {
+		t.Error(err)
+	}
+}
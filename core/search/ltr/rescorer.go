@@ -0,0 +1,157 @@
+package ltr
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"sort"
+)
+
+/*
+A Rescorer which extracts the features needed by a learning-to-rank
+Model from each first-pass hit, and replaces the hit's score with the
+score the model assigns to that feature vector.
+*/
+type LTRRescorer struct {
+	model    Model
+	features []Feature // in model order
+}
+
+/*
+Creates an LTRRescorer for the model. Every feature the model refers
+to must be supplied by name; features the model does not use are
+ignored.
+*/
+func NewLTRRescorer(model Model, features ...Feature) (*LTRRescorer, error) {
+	byName := make(map[string]Feature)
+	for _, f := range features {
+		byName[f.Name()] = f
+	}
+	ans := &LTRRescorer{model: model}
+	for _, name := range model.Features() {
+		f, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("model needs feature '%v' which was not supplied", name)
+		}
+		ans.features = append(ans.features, f)
+	}
+	return ans, nil
+}
+
+func (r *LTRRescorer) createWeights(searcher *search.IndexSearcher) ([]FeatureWeight, error) {
+	weights := make([]FeatureWeight, len(r.features))
+	for i, f := range r.features {
+		w, err := f.CreateWeight(searcher)
+		if err != nil {
+			return nil, err
+		}
+		weights[i] = w
+	}
+	return weights, nil
+}
+
+func createScorers(weights []FeatureWeight, ctx *index.AtomicReaderContext) ([]FeatureScorer, error) {
+	scorers := make([]FeatureScorer, len(weights))
+	for i, w := range weights {
+		s, err := w.Scorer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		scorers[i] = s
+	}
+	return scorers, nil
+}
+
+func extract(scorers []FeatureScorer, doc int, firstPassScore float32, values []float32) error {
+	for i, s := range scorers {
+		v, err := s.Value(doc, firstPassScore)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	return nil
+}
+
+func (r *LTRRescorer) Rescore(searcher *search.IndexSearcher, firstPassTopDocs search.TopDocs, topN int) (search.TopDocs, error) {
+	hits := make([]*search.ScoreDoc, len(firstPassTopDocs.ScoreDocs))
+	for i, hit := range firstPassTopDocs.ScoreDocs {
+		hits[i] = &search.ScoreDoc{Score: hit.Score, Doc: hit.Doc, ShardIndex: hit.ShardIndex}
+	}
+	sort.Sort(search.ScoreDocsByDoc(hits))
+
+	weights, err := r.createWeights(searcher)
+	if err != nil {
+		return search.TopDocs{}, err
+	}
+
+	leaves := searcher.TopReaderContext().Leaves()
+	values := make([]float32, len(r.features))
+	var scorers []FeatureScorer
+	endDoc, docBase := 0, 0
+	readerUpto := -1
+	for _, hit := range hits {
+		var readerContext *index.AtomicReaderContext
+		for hit.Doc >= endDoc {
+			readerUpto++
+			readerContext = leaves[readerUpto]
+			endDoc = readerContext.DocBase + readerContext.Reader().MaxDoc()
+		}
+		if readerContext != nil {
+			docBase = readerContext.DocBase
+			if scorers, err = createScorers(weights, readerContext); err != nil {
+				return search.TopDocs{}, err
+			}
+		}
+
+		if err = extract(scorers, hit.Doc-docBase, hit.Score, values); err != nil {
+			return search.TopDocs{}, err
+		}
+		hit.Score = r.model.Score(values)
+	}
+
+	return search.TopRescoredDocs(firstPassTopDocs.TotalHits, hits, topN), nil
+}
+
+func (r *LTRRescorer) Explain(searcher *search.IndexSearcher,
+	firstPassExplanation search.Explanation, docID int) (search.Explanation, error) {
+
+	values, err := r.ExtractFeatures(searcher, docID, firstPassExplanation.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	ans := search.NewExplanation(r.model.Score(values), "LTR score, computed from:")
+	ans.AddDetail(r.model.Explain(values))
+	extracted := search.NewExplanation(0, "extracted features:")
+	for i, f := range r.features {
+		extracted.AddDetail(search.NewExplanation(values[i], f.Name()))
+	}
+	ans.AddDetail(extracted)
+	first := search.NewExplanation(firstPassExplanation.Value(), "first pass score")
+	first.AddDetail(firstPassExplanation)
+	ans.AddDetail(first)
+	return ans, nil
+}
+
+/*
+Returns the feature vector of a single document, in model order. This
+is mostly useful to log training data for the model.
+*/
+func (r *LTRRescorer) ExtractFeatures(searcher *search.IndexSearcher,
+	docID int, firstPassScore float32) ([]float32, error) {
+
+	weights, err := r.createWeights(searcher)
+	if err != nil {
+		return nil, err
+	}
+	leaves := searcher.TopReaderContext().Leaves()
+	ctx := leaves[index.SubIndex(docID, leaves)]
+	scorers, err := createScorers(weights, ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float32, len(r.features))
+	err = extract(scorers, docID-ctx.DocBase, firstPassScore, values)
+	return values, err
+}
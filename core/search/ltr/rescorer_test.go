@@ -0,0 +1,74 @@
+package ltr
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"math"
+	"testing"
+)
+
+func TestLTRRescorer(t *testing.T) {
+	// "c" only occurs in the second segment
+	dir := testindex.NewDirectory(t)
+	w := testindex.NewWriter(t, dir, nil)
+	for i, doc := range testindex.TextDocs("body", "a b", "a", "a c c", "a c", "b c") {
+		if err := w.AddDocument(doc); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			if err := w.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	searcher := search.NewIndexSearcher(testindex.OpenReader(t, dir))
+
+	first := search.NewTermQuery(index.NewTerm("body", "a"))
+	firstPass, err := searcher.SearchTop(first, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hit := range firstPass.ScoreDocs {
+		hit.ShardIndex = hit.Doc + 10
+	}
+	model := NewLinearModel([]string{"orig", "c"}, []float32{1, 10}, 0)
+	rescorer, err := NewLTRRescorer(model,
+		NewOriginalScoreFeature("orig"),
+		NewSimilarityFeature("c", search.NewTermQuery(index.NewTerm("body", "c")), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rescored, err := rescorer.Rescore(searcher, firstPass, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rescored.ScoreDocs) != 4 {
+		t.Fatalf("expected 4 hits, but %v", rescored.ScoreDocs)
+	}
+	// the docs with "c" first
+	for _, hit := range rescored.ScoreDocs[:2] {
+		if hit.Doc != 2 && hit.Doc != 3 {
+			t.Errorf("expected docs 2 and 3 first, but %v", rescored.ScoreDocs)
+		}
+	}
+	for _, hit := range rescored.ScoreDocs {
+		if hit.ShardIndex != hit.Doc+10 {
+			t.Errorf("lost the shardIndex of %v", hit)
+		}
+		firstExp, err := searcher.Explain(first, hit.Doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp, err := rescorer.Explain(searcher, firstExp, hit.Doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(float64(exp.Value()-hit.Score)) > 1e-5 {
+			t.Errorf("explanation %v doesn't match score of %v", exp.Value(), hit)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/MatchAllDocsQuery.java
+
+/* A query that matches all documents. */
+type MatchAllDocsQuery struct {
+	*AbstractQuery
+}
+
+func NewMatchAllDocsQuery() *MatchAllDocsQuery {
+	ans := new(MatchAllDocsQuery)
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+func (q *MatchAllDocsQuery) CreateWeight(searcher *IndexSearcher) (Weight, error) {
+	return newMatchAllDocsWeight(q), nil
+}
+
+func (q *MatchAllDocsQuery) Clone() Query {
+	ans := NewMatchAllDocsQuery()
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *MatchAllDocsQuery) ToString(field string) string {
+	if q.Boost() != 1 {
+		return fmt.Sprintf("*:*^%v", q.Boost())
+	}
+	return "*:*"
+}
+
+type matchAllDocsWeight struct {
+	*WeightImpl
+	owner       *MatchAllDocsQuery
+	queryWeight float32
+	queryNorm   float32
+}
+
+func newMatchAllDocsWeight(owner *MatchAllDocsQuery) *matchAllDocsWeight {
+	ans := &matchAllDocsWeight{owner: owner}
+	ans.WeightImpl = NewWeightImpl(ans)
+	return ans
+}
+
+func (w *matchAllDocsWeight) String() string {
+	return fmt.Sprintf("weight(%v)", w.owner)
+}
+
+func (w *matchAllDocsWeight) ValueForNormalization() float32 {
+	w.queryWeight = w.owner.Boost()
+	return w.queryWeight * w.queryWeight
+}
+
+func (w *matchAllDocsWeight) Normalize(queryNorm, topLevelBoost float32) {
+	w.queryNorm = queryNorm * topLevelBoost
+	w.queryWeight *= w.queryNorm
+}
+
+func (w *matchAllDocsWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *matchAllDocsWeight) Scorer(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (Scorer, error) {
+	return newMatchAllScorer(w, ctx.Reader().MaxDoc(), acceptDocs, w.queryWeight), nil
+}
+
+func (w *matchAllDocsWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanation, error) {
+	// explain query weight
+	queryExpl := NewComplexExplanation(true, w.queryWeight, "MatchAllDocsQuery, product of:")
+	if w.owner.Boost() != 1 {
+		queryExpl.AddDetail(NewExplanation(w.owner.Boost(), "boost"))
+	}
+	queryExpl.AddDetail(NewExplanation(w.queryNorm, "queryNorm"))
+	return queryExpl, nil
+}
+
+type matchAllScorer struct {
+	abstractScorer
+	score      float32
+	doc        int
+	maxDoc     int
+	acceptDocs util.Bits
+}
+
+func newMatchAllScorer(w Weight, maxDoc int, acceptDocs util.Bits, score float32) *matchAllScorer {
+	ans := &matchAllScorer{
+		score:      score,
+		doc:        -1,
+		maxDoc:     maxDoc,
+		acceptDocs: acceptDocs,
+	}
+	ans.weight = w
+	return ans
+}
+
+func (s *matchAllScorer) DocId() int {
+	return s.doc
+}
+
+func (s *matchAllScorer) NextDoc() (int, error) {
+	s.doc++
+	for s.acceptDocs != nil && s.doc < s.maxDoc && !s.acceptDocs.At(s.doc) {
+		s.doc++
+	}
+	if s.doc >= s.maxDoc {
+		s.doc = NO_MORE_DOCS
+	}
+	return s.doc, nil
+}
+
+func (s *matchAllScorer) Score() (float32, error) {
+	return s.score, nil
+}
+
+func (s *matchAllScorer) Freq() (int, error) {
+	return 1, nil
+}
+
+func (s *matchAllScorer) Advance(target int) (int, error) {
+	s.doc = target - 1
+	return s.NextDoc()
+}
+
+func (s *matchAllScorer) Cost() int64 {
+	return int64(s.maxDoc)
+}
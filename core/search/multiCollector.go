@@ -0,0 +1,83 @@
+package search
+
+import (
+	"github.com/jtejido/golucene/core/index"
+)
+
+// search/MultiCollector.java
+
+/*
+A Collector which allows running a search with several Collectors.
+It offers a static WrapCollectors() method which accepts a list of
+collectors and wraps them with MultiCollector, while filtering out
+the nil ones.
+*/
+type MultiCollector struct {
+	collectors []Collector
+}
+
+/*
+Wraps a list of Collectors with a MultiCollector. This method works
+as follows:
+  - Filters out the nil collectors, so they are not used during
+    search time.
+  - If the input contains 1 real collector (i.e. non-nil), it is
+    returned.
+  - Otherwise the method returns a MultiCollector which wraps the
+    non-nil ones.
+
+It panics if either 0 collectors were input, or all collectors are
+nil.
+*/
+func WrapCollectors(collectors ...Collector) Collector {
+	var colls []Collector
+	for _, c := range collectors {
+		if c != nil {
+			colls = append(colls, c)
+		}
+	}
+	assert2(len(colls) > 0, "At least 1 collector must not be nil")
+	if len(colls) == 1 {
+		return colls[0]
+	}
+	return &MultiCollector{colls}
+}
+
+/*
+Accepts docs out of order only if all of the wrapped collectors do;
+otherwise the searcher must deliver documents in order.
+*/
+func (c *MultiCollector) AcceptsDocsOutOfOrder() bool {
+	for _, coll := range c.collectors {
+		if !coll.AcceptsDocsOutOfOrder() {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *MultiCollector) Collect(doc int) error {
+	for _, coll := range c.collectors {
+		if err := coll.Collect(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *MultiCollector) SetNextReader(ctx *index.AtomicReaderContext) {
+	for _, coll := range c.collectors {
+		coll.SetNextReader(ctx)
+	}
+}
+
+func (c *MultiCollector) SetScorer(s Scorer) {
+	// the wrapped collectors may each ask for the score of the same
+	// doc, so make sure it is only computed once
+	if _, ok := s.(*ScoreCachingWrappingScorer); !ok {
+		s = NewScoreCachingWrappingScorer(s)
+	}
+	for _, coll := range c.collectors {
+		coll.SetScorer(s)
+	}
+}
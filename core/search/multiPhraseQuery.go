@@ -0,0 +1,499 @@
+package search
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"sort"
+	"strconv"
+)
+
+// search/MultiPhraseQuery.java
+
+/*
+MultiPhraseQuery is a generalized version of PhraseQuery, with an
+added method Add(...*index.Term). To use this class, to search for
+the phrase "Microsoft app*" first use Add() on the term "Microsoft",
+then find all terms that have "app" as prefix using
+IndexReader.Terms(), and use AddTerms(terms...) to add them to the
+query.
+*/
+type MultiPhraseQuery struct {
+	*AbstractQuery
+	field      string
+	termArrays [][]*index.Term
+	positions  []int32
+	slop       int
+}
+
+func NewMultiPhraseQuery() *MultiPhraseQuery {
+	ans := new(MultiPhraseQuery)
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+/*
+Sets the phrase slop for this query.
+See PhraseQuery.SetSlop().
+*/
+func (q *MultiPhraseQuery) SetSlop(s int) {
+	assert2(s >= 0, "slop value cannot be negative")
+	q.slop = s
+}
+
+/*
+Gets the phrase slop for this query.
+See PhraseQuery.Slop().
+*/
+func (q *MultiPhraseQuery) Slop() int {
+	return q.slop
+}
+
+/*
+Add a single term at the next position in the phrase.
+See PhraseQuery.Add().
+*/
+func (q *MultiPhraseQuery) Add(term *index.Term) {
+	q.AddTerms(term)
+}
+
+/*
+Add multiple terms at the next position in the phrase. Any of the
+terms may match.
+*/
+func (q *MultiPhraseQuery) AddTerms(terms ...*index.Term) {
+	position := int32(0)
+	if len(q.positions) > 0 {
+		position = q.positions[len(q.positions)-1] + 1
+	}
+	q.AddTermsWithPosition(terms, position)
+}
+
+/*
+Allows to specify the relative position of terms within the phrase.
+*/
+func (q *MultiPhraseQuery) AddTermsWithPosition(terms []*index.Term, position int32) {
+	assert2(len(terms) > 0, "terms may not be empty")
+	if len(q.termArrays) == 0 {
+		q.field = terms[0].Field
+	}
+	for _, term := range terms {
+		if term.Field != q.field {
+			panic(fmt.Sprintf("All phrase terms must be in the same field (%v): %v", q.field, term))
+		}
+	}
+	q.termArrays = append(q.termArrays, terms)
+	q.positions = append(q.positions, position)
+}
+
+/* Returns the terms added at each position. */
+func (q *MultiPhraseQuery) TermArrays() [][]*index.Term {
+	return q.termArrays
+}
+
+/* Returns the relative positions of terms in this phrase. */
+func (q *MultiPhraseQuery) Positions() []int32 {
+	return q.positions
+}
+
+func (q *MultiPhraseQuery) CreateWeight(searcher *IndexSearcher) (Weight, error) {
+	return newMultiPhraseWeight(q, searcher)
+}
+
+func (q *MultiPhraseQuery) Rewrite(reader index.IndexReader) Query {
+	if len(q.termArrays) == 0 {
+		bq := NewBooleanQuery()
+		bq.SetBoost(q.Boost())
+		return bq
+	} else if len(q.termArrays) == 1 { // optimize one-term case
+		bq := NewBooleanQueryDisableCoord(true)
+		for _, term := range q.termArrays[0] {
+			bq.Add(NewTermQuery(term), SHOULD)
+		}
+		bq.SetBoost(q.Boost())
+		return bq
+	}
+	return q
+}
+
+func (q *MultiPhraseQuery) Clone() Query {
+	ans := &MultiPhraseQuery{
+		field:      q.field,
+		termArrays: append([][]*index.Term(nil), q.termArrays...),
+		positions:  append([]int32(nil), q.positions...),
+		slop:       q.slop,
+	}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *MultiPhraseQuery) ToString(f string) string {
+	var buf bytes.Buffer
+	if q.field == "" || q.field != f {
+		buf.WriteString(q.field)
+		buf.WriteRune(':')
+	}
+
+	buf.WriteRune('"')
+	lastPos := int32(-1)
+	for k, terms := range q.termArrays {
+		position := q.positions[k]
+		if k > 0 {
+			buf.WriteRune(' ')
+			for j := int32(1); j < position-lastPos; j++ {
+				buf.WriteString("? ")
+			}
+		}
+		if len(terms) > 1 {
+			buf.WriteRune('(')
+			for j, term := range terms {
+				if j > 0 {
+					buf.WriteRune(' ')
+				}
+				buf.Write(term.Bytes)
+			}
+			buf.WriteRune(')')
+		} else {
+			buf.Write(terms[0].Bytes)
+		}
+		lastPos = position
+	}
+	buf.WriteRune('"')
+
+	if q.slop != 0 {
+		buf.WriteRune('~')
+		buf.WriteString(strconv.Itoa(q.slop))
+	}
+	if q.Boost() != 1 {
+		fmt.Fprintf(&buf, "^%v", q.Boost())
+	}
+	return buf.String()
+}
+
+type MultiPhraseWeight struct {
+	*WeightImpl
+	owner        *MultiPhraseQuery
+	similarity   Similarity
+	stats        SimWeight
+	termContexts map[string]*index.TermContext
+}
+
+func newMultiPhraseWeight(owner *MultiPhraseQuery, searcher *IndexSearcher) (w *MultiPhraseWeight, err error) {
+	w = &MultiPhraseWeight{
+		owner:        owner,
+		similarity:   searcher.similarity,
+		termContexts: make(map[string]*index.TermContext),
+	}
+	context := searcher.TopReaderContext()
+
+	// compute idf
+	var allTermStats []TermStatistics
+	for _, terms := range owner.termArrays {
+		for _, term := range terms {
+			termContext, ok := w.termContexts[term.String()]
+			if !ok {
+				if termContext, err = index.NewTermContextFromTerm(context, term); err != nil {
+					return nil, err
+				}
+				w.termContexts[term.String()] = termContext
+			}
+			allTermStats = append(allTermStats, searcher.TermStatistics(term, termContext))
+		}
+	}
+	w.stats = w.similarity.ComputeWeight(owner.Boost(),
+		searcher.CollectionStatistics(owner.field), allTermStats...)
+	w.WeightImpl = NewWeightImpl(w)
+	return w, nil
+}
+
+func (w *MultiPhraseWeight) ValueForNormalization() float32 {
+	return w.stats.ValueForNormalization()
+}
+
+func (w *MultiPhraseWeight) Normalize(norm, topLevelBoost float32) {
+	w.stats.Normalize(norm, topLevelBoost)
+}
+
+func (w *MultiPhraseWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *MultiPhraseWeight) Scorer(context *index.AtomicReaderContext, acceptDocs util.Bits) (Scorer, error) {
+	assert(len(w.owner.termArrays) != 0)
+	reader := context.Reader().(index.AtomicReader)
+	liveDocs := acceptDocs
+
+	postingsFreqs := make([]*PostingsAndFreq, len(w.owner.termArrays))
+
+	fieldTerms := reader.Terms(w.owner.field)
+	if fieldTerms == nil {
+		return nil, nil
+	}
+
+	// Reuse single TermsEnum below:
+	te := fieldTerms.Iterator(nil)
+
+	for pos, terms := range w.owner.termArrays {
+		var postingsEnum model.DocsAndPositionsEnum
+		var docFreq int
+		var err error
+
+		if len(terms) > 1 {
+			if postingsEnum, err = newUnionDocsAndPositionsEnum(liveDocs, context, terms, w.termContexts, te); err != nil {
+				return nil, err
+			}
+
+			// coarse -- this overcounts since a given doc can
+			// have more than one term:
+			for _, term := range terms {
+				state := w.termContexts[term.String()].State(context.Ord)
+				if state == nil {
+					continue // Term not in reader
+				}
+				if err = te.SeekExactFromLast(term.Bytes, state); err != nil {
+					return nil, err
+				}
+				df, err := te.DocFreq()
+				if err != nil {
+					return nil, err
+				}
+				docFreq += df
+			}
+
+			if docFreq == 0 {
+				return nil, nil // None of the terms are in this reader
+			}
+		} else {
+			term := terms[0]
+			state := w.termContexts[term.String()].State(context.Ord)
+			if state == nil {
+				return nil, nil // Term not in reader
+			}
+			if err = te.SeekExactFromLast(term.Bytes, state); err != nil {
+				return nil, err
+			}
+			if postingsEnum, err = te.DocsAndPositionsByFlags(liveDocs, nil, model.DOCS_ENUM_FLAG_NONE); err != nil {
+				return nil, err
+			}
+			if postingsEnum == nil {
+				// term does exist, but has no positions
+				return nil, fmt.Errorf("field \"%v\" was indexed without position data; cannot run PhraseQuery (term=%v)", term.Field, string(term.Bytes))
+			}
+			if docFreq, err = te.DocFreq(); err != nil {
+				return nil, err
+			}
+		}
+
+		postingsFreqs[pos] = newPostingsAndFreq(postingsEnum, docFreq, w.owner.positions[pos], terms...)
+	}
+
+	// sort by increasing docFreq order
+	if w.owner.slop == 0 {
+		util.TimSort(PostingsAndFreqSorter(postingsFreqs))
+	}
+
+	ss, err := w.similarity.SimScorer(w.stats, context)
+	if err != nil {
+		return nil, err
+	}
+	if w.owner.slop == 0 {
+		return newExactPhraseScorer(w, postingsFreqs, ss)
+	}
+	return newSloppyPhraseScorer(w, postingsFreqs, w.owner.slop, ss), nil
+}
+
+func (w *MultiPhraseWeight) Explain(context *index.AtomicReaderContext, doc int) (Explanation, error) {
+	scorer, err := w.Scorer(context, context.Reader().(index.AtomicReader).LiveDocs())
+	if err != nil {
+		return nil, err
+	}
+	if scorer != nil {
+		newDoc, err := scorer.Advance(doc)
+		if err != nil {
+			return nil, err
+		}
+		if newDoc == doc {
+			var freq float32
+			if sloppy, ok := scorer.(*SloppyPhraseScorer); ok {
+				freq = sloppy.SloppyFreq()
+			} else {
+				n, err := scorer.Freq()
+				if err != nil {
+					return nil, err
+				}
+				freq = float32(n)
+			}
+			docScorer, err := w.similarity.SimScorer(w.stats, context)
+			if err != nil {
+				return nil, err
+			}
+			scoreExplanation := docScorer.Explain(doc,
+				NewExplanation(freq, fmt.Sprintf("phraseFreq=%v", freq)))
+			ans := NewComplexExplanation(true, scoreExplanation.Value(),
+				fmt.Sprintf("weight(%v in %v), result of:", w.owner, doc))
+			ans.AddDetail(scoreExplanation)
+			return ans, nil
+		}
+	}
+	return NewComplexExplanation(false, 0, "no matching term"), nil
+}
+
+/*
+Takes the logical union of multiple DocsAndPositionsEnum iterators.
+Positions of all sub-enums positioned on the current doc are merged
+and returned in increasing order.
+*/
+type unionDocsAndPositionsEnum struct {
+	queue   *docsQueue
+	posList []int
+	posUpto int
+	doc     int
+	freq    int
+	cost    int64
+}
+
+func newUnionDocsAndPositionsEnum(liveDocs util.Bits, context *index.AtomicReaderContext,
+	terms []*index.Term, termContexts map[string]*index.TermContext,
+	te model.TermsEnum) (*unionDocsAndPositionsEnum, error) {
+
+	ans := &unionDocsAndPositionsEnum{queue: new(docsQueue), doc: -1}
+	for _, term := range terms {
+		state := termContexts[term.String()].State(context.Ord)
+		if state == nil {
+			continue // Term doesn't exist in reader
+		}
+		if err := te.SeekExactFromLast(term.Bytes, state); err != nil {
+			return nil, err
+		}
+		postings, err := te.DocsAndPositionsByFlags(liveDocs, nil, model.DOCS_ENUM_FLAG_NONE)
+		if err != nil {
+			return nil, err
+		}
+		if postings == nil {
+			// term does exist, but has no positions
+			return nil, fmt.Errorf("field \"%v\" was indexed without position data; cannot run PhraseQuery (term=%v)", term.Field, string(term.Bytes))
+		}
+		ans.cost += postings.Cost()
+		doc, err := postings.NextDoc()
+		if err != nil {
+			return nil, err
+		}
+		if doc != NO_MORE_DOCS {
+			*ans.queue = append(*ans.queue, postings)
+		}
+	}
+	heap.Init(ans.queue)
+	return ans, nil
+}
+
+func (e *unionDocsAndPositionsEnum) NextDoc() (int, error) {
+	if e.queue.Len() == 0 {
+		e.doc = NO_MORE_DOCS
+		return e.doc, nil
+	}
+
+	// TODO: move this init into positions(): if the search
+	// doesn't need the positions for this doc then don't
+	// waste CPU merging them:
+	e.posList = e.posList[:0]
+	e.posUpto = 0
+	e.doc = e.queue.top().DocId()
+
+	// merge sort all positions together
+	for e.queue.Len() > 0 && e.queue.top().DocId() == e.doc {
+		postings := e.queue.top()
+		freq, err := postings.Freq()
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < freq; i++ {
+			pos, err := postings.NextPosition()
+			if err != nil {
+				return 0, err
+			}
+			e.posList = append(e.posList, pos)
+		}
+
+		doc, err := postings.NextDoc()
+		if err != nil {
+			return 0, err
+		}
+		if doc != NO_MORE_DOCS {
+			heap.Fix(e.queue, 0)
+		} else {
+			heap.Pop(e.queue)
+		}
+	}
+
+	sort.Ints(e.posList)
+	e.freq = len(e.posList)
+	return e.doc, nil
+}
+
+func (e *unionDocsAndPositionsEnum) NextPosition() (int, error) {
+	pos := e.posList[e.posUpto]
+	e.posUpto++
+	return pos, nil
+}
+
+func (e *unionDocsAndPositionsEnum) StartOffset() (int, error) {
+	return -1, nil
+}
+
+func (e *unionDocsAndPositionsEnum) EndOffset() (int, error) {
+	return -1, nil
+}
+
+func (e *unionDocsAndPositionsEnum) Payload() (*util.BytesRef, error) {
+	return nil, nil
+}
+
+func (e *unionDocsAndPositionsEnum) Advance(target int) (int, error) {
+	for e.queue.Len() > 0 && target > e.queue.top().DocId() {
+		postings := heap.Pop(e.queue).(model.DocsAndPositionsEnum)
+		doc, err := postings.Advance(target)
+		if err != nil {
+			return 0, err
+		}
+		if doc != NO_MORE_DOCS {
+			heap.Push(e.queue, postings)
+		}
+	}
+	return e.NextDoc()
+}
+
+func (e *unionDocsAndPositionsEnum) Freq() (int, error) {
+	return e.freq, nil
+}
+
+func (e *unionDocsAndPositionsEnum) DocId() int {
+	return e.doc
+}
+
+func (e *unionDocsAndPositionsEnum) Cost() int64 {
+	return e.cost
+}
+
+/* Priority queue of sub-enums ordered by their current doc. */
+type docsQueue []model.DocsAndPositionsEnum
+
+func (q docsQueue) Len() int           { return len(q) }
+func (q docsQueue) Less(i, j int) bool { return q[i].DocId() < q[j].DocId() }
+func (q docsQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *docsQueue) Push(x interface{}) {
+	*q = append(*q, x.(model.DocsAndPositionsEnum))
+}
+func (q *docsQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	ans := old[n-1]
+	*q = old[:n-1]
+	return ans
+}
+
+func (q docsQueue) top() model.DocsAndPositionsEnum { return q[0] }
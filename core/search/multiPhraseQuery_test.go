@@ -0,0 +1,38 @@
+package search_test
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestMultiPhraseQuery(t *testing.T) {
+	reader := testindex.NewReader(t, testindex.TextDocs("body",
+		"quick fox", "quick foxes", "quick brown fox", "fox quick", "slow fox")...)
+	searcher := search.NewIndexSearcher(reader)
+
+	q := search.NewMultiPhraseQuery()
+	q.Add(index.NewTerm("body", "quick"))
+	q.AddTerms(index.NewTerm("body", "fox"), index.NewTerm("body", "foxes"))
+	if s := q.ToString("body"); s != `"quick (fox foxes)"` {
+		t.Errorf("unexpected query string %v", s)
+	}
+	assertOrder(t, searcher, q, 0, 1)
+
+	q.SetSlop(1)
+	assertOrder(t, searcher, q, 0, 1, 2)
+	exp, err := searcher.Explain(q, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exp.IsMatch() {
+		t.Errorf("expected doc 2 to match, but %v", exp)
+	}
+
+	pq := search.NewPhraseQuery()
+	pq.Add(index.NewTerm("body", "fox"))
+	pq.Add(index.NewTerm("body", "quick"))
+	pq.SetSlop(2)
+	assertOrder(t, searcher, pq, 3, 0)
+}
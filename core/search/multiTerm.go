@@ -1,8 +1,11 @@
 package search
 
 import (
+	"context"
+	"github.com/jtejido/golucene/core/analysis/tokenattributes"
 	"github.com/jtejido/golucene/core/index"
 	. "github.com/jtejido/golucene/core/index/model"
+	. "github.com/jtejido/golucene/core/search/model"
 	"github.com/jtejido/golucene/core/util"
 )
 
@@ -11,6 +14,15 @@ type RewriteMethod interface {
 	TermsEnum(query MultiTermQuery, terms Terms, atts *util.AttributeSource) (TermsEnum, error)
 }
 
+/*
+Implemented by rewrite methods that can be cancelled while
+enumerating terms, typically by collecting them with
+AbstractTermCollectingRewrite.CollectTermsContext().
+*/
+type ContextRewriteMethod interface {
+	RewriteContext(ctx context.Context, reader index.IndexReader, query MultiTermQuery) (Query, error)
+}
+
 type RewriteMethodImpl struct {
 	spi RewriteMethod
 }
@@ -59,19 +71,50 @@ type AbstractMultiTermQuery struct {
 	rewriteMethod RewriteMethod
 }
 
-func newAbstractMultiTermQuery(spi MultiTermQuerySPI, field string) *AbstractMultiTermQuery {
+/*
+Constructs a query matching terms that cannot be represented with a
+single Term. It is rewritten with CONSTANT_SCORE_FILTER_REWRITE,
+unless SetRewriteMethod() is called.
+*/
+func NewAbstractMultiTermQuery(spi MultiTermQuerySPI, field string) *AbstractMultiTermQuery {
 	assert(field != "")
 	ans := &AbstractMultiTermQuery{
-		spi:   spi,
-		field: field,
+		spi:           spi,
+		field:         field,
+		rewriteMethod: CONSTANT_SCORE_FILTER_REWRITE,
 	}
 
-	ans.AbstractQuery = NewAbstractQuery(ans)
+	ans.AbstractQuery = NewAbstractQuery(spi)
 	return ans
 }
 
 func (q *AbstractMultiTermQuery) Rewrite(r index.IndexReader) Query {
-	return q.rewriteMethod.Rewrite(r, q)
+	return q.rewriteMethod.Rewrite(r, q.outer())
+}
+
+/*
+Returns the query embedding this one, so that rewrite methods see
+its ToString(), Clone() and so on.
+*/
+func (q *AbstractMultiTermQuery) outer() MultiTermQuery {
+	if mtq, ok := q.spi.(MultiTermQuery); ok {
+		return mtq
+	}
+	return q
+}
+
+/*
+Like Rewrite(), but lets rewrite methods that enumerate terms stop
+with ctx.Err() once ctx is done. See ContextRewriteMethod.
+*/
+func (q *AbstractMultiTermQuery) RewriteContext(ctx context.Context, r index.IndexReader) (Query, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if crm, ok := q.rewriteMethod.(ContextRewriteMethod); ok {
+		return crm.RewriteContext(ctx, r, q.outer())
+	}
+	return q.Rewrite(r), nil
 }
 
 func (q *AbstractMultiTermQuery) TermsEnum(terms Terms, atts *util.AttributeSource) (TermsEnum, error) {
@@ -85,3 +128,130 @@ func (q *AbstractMultiTermQuery) Field() string {
 func (q *AbstractMultiTermQuery) RewriteMethod() RewriteMethod {
 	return q.rewriteMethod
 }
+
+/* Sets the rewrite method to be used when executing the query. */
+func (q *AbstractMultiTermQuery) SetRewriteMethod(method RewriteMethod) {
+	q.rewriteMethod = method
+}
+
+/*
+A rewrite method that first creates a private Filter, by visiting
+each term in sequence and marking all docs for that term. Matching
+documents are assigned a constant score equal to the query's boost.
+
+Rewritten with RewriteContext(), the filter stops with ctx.Err() once
+ctx is done, checking it every CANCELLATION_CHECK_TERM_INTERVAL terms.
+*/
+var CONSTANT_SCORE_FILTER_REWRITE = RewriteMethod(constantScoreFilterRewrite{})
+
+type constantScoreFilterRewrite struct{}
+
+func (r constantScoreFilterRewrite) Rewrite(reader index.IndexReader, query MultiTermQuery) Query {
+	ans, _ := r.RewriteContext(context.Background(), reader, query)
+	return ans
+}
+
+func (r constantScoreFilterRewrite) RewriteContext(ctx context.Context,
+	reader index.IndexReader, query MultiTermQuery) (Query, error) {
+
+	filter := NewMultiTermQueryWrapperFilter(query)
+	filter.ctx = ctx
+	ans := NewConstantScoreQueryWithFilter(filter)
+	ans.SetBoost(query.Boost())
+	return ans, nil
+}
+
+func (r constantScoreFilterRewrite) TermsEnum(query MultiTermQuery, terms Terms, atts *util.AttributeSource) (TermsEnum, error) {
+	return query.TermsEnum(terms, atts)
+}
+
+// search/MultiTermQueryWrapperFilter.java
+
+/*
+A wrapper for MultiTermQuery, that exposes its functionality as a
+Filter. It marks the documents of every term the query's TermsEnum
+enumerates.
+*/
+type MultiTermQueryWrapperFilter struct {
+	query MultiTermQuery
+	ctx   context.Context // checked while enumerating terms
+}
+
+/* Wrap a MultiTermQuery as a Filter. */
+func NewMultiTermQueryWrapperFilter(query MultiTermQuery) *MultiTermQueryWrapperFilter {
+	assert2(query != nil, "Query may not be nil")
+	return &MultiTermQueryWrapperFilter{query, context.Background()}
+}
+
+/* Returns the field name for this query */
+func (f *MultiTermQueryWrapperFilter) Field() string {
+	return f.query.Field()
+}
+
+func (f *MultiTermQueryWrapperFilter) String() string {
+	// query.ToString should be ok for the filter, too, if the query
+	// boost is 1.0
+	return f.query.ToString("")
+}
+
+func (f *MultiTermQueryWrapperFilter) DocIdSet(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (DocIdSet, error) {
+	reader := ctx.Reader().(index.AtomicReader)
+	fields := reader.Fields()
+	if fields == nil {
+		// reader has no fields
+		return nil, nil
+	}
+
+	terms := fields.Terms(f.query.Field())
+	if terms == nil {
+		// field does not exist
+		return nil, nil
+	}
+
+	termsEnum, err := f.query.TermsEnum(terms,
+		util.NewAttributeSourceWith(tokenattributes.DEFAULT_ATTRIBUTE_FACTORY))
+	if err != nil {
+		return nil, err
+	}
+	assert(termsEnum != nil)
+	if termsEnum == EMPTY_TERMS_ENUM {
+		return nil, nil
+	}
+
+	// fill into a FixedBitSet
+	bitSet := util.NewFixedBitSetOf(reader.MaxDoc())
+	var docsEnum DocsEnum
+	found := false
+	for count := 0; ; count++ {
+		if count%CANCELLATION_CHECK_TERM_INTERVAL == 0 {
+			if err := f.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		term, err := termsEnum.Next()
+		if err != nil {
+			return nil, err
+		}
+		if term == nil {
+			break
+		}
+		if docsEnum, err = termsEnum.DocsByFlags(acceptDocs, docsEnum, DOCS_ENUM_FLAG_NONE); err != nil {
+			return nil, err
+		}
+		for {
+			doc, err := docsEnum.NextDoc()
+			if err != nil {
+				return nil, err
+			}
+			if doc == NO_MORE_DOCS {
+				break
+			}
+			bitSet.Set(doc)
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return NewDocIdBitSet(bitSet), nil
+}
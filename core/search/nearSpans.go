@@ -0,0 +1,727 @@
+package search
+
+import (
+	"container/heap"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+	"sort"
+)
+
+// search/spans/NearSpansOrdered.java
+
+/*
+A Spans that is formed from the ordered subspans of a SpanNearQuery
+where the subspans do not overlap and have a maximum slop between
+them.
+
+The formed spans only contains minimum slop matches. The matching
+slop is computed from the distance(s) between the non overlapping
+matching Spans.
+
+Successive matches are always formed from the successive Spans of
+the SpanNearQuery.
+
+The formed spans may contain overlaps when the slop is at least 1.
+For example, when querying using
+
+	t1 t2 t3
+
+with slop at least 1, the fragment:
+
+	t1 t2 t1 t3 t2 t3
+
+matches twice:
+
+	t1 t2 .. t3
+	      t1 .. t2 t3
+*/
+type nearSpansOrdered struct {
+	allowedSlop int
+	firstTime   bool
+	more        bool
+
+	// The spans in the same order as the SpanNearQuery
+	subSpans []Spans
+
+	// Indicates that all subSpans have same doc()
+	inSameDoc bool
+
+	matchDoc     int
+	matchStart   int
+	matchEnd     int
+	matchPayload [][]byte
+
+	subSpansByDoc []Spans
+
+	query           *SpanNearQuery
+	collectPayloads bool
+}
+
+func newNearSpansOrdered(spanNearQuery *SpanNearQuery, ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits, termContexts map[string]*index.TermContext,
+	collectPayloads bool) (*nearSpansOrdered, error) {
+
+	clauses := spanNearQuery.Clauses()
+	if len(clauses) < 2 {
+		return nil, fmt.Errorf("Less than 2 clauses: %v", spanNearQuery)
+	}
+	ans := &nearSpansOrdered{
+		allowedSlop:     spanNearQuery.Slop(),
+		firstTime:       true,
+		subSpans:        make([]Spans, len(clauses)),
+		subSpansByDoc:   make([]Spans, len(clauses)),
+		matchDoc:        -1,
+		matchStart:      -1,
+		matchEnd:        -1,
+		query:           spanNearQuery, // kept for String() only.
+		collectPayloads: collectPayloads,
+	}
+	for i, clause := range clauses {
+		spans, err := clause.Spans(ctx, acceptDocs, termContexts)
+		if err != nil {
+			return nil, err
+		}
+		ans.subSpans[i] = spans
+		ans.subSpansByDoc[i] = spans // used in toSameDoc()
+	}
+	return ans, nil
+}
+
+func (s *nearSpansOrdered) Doc() int   { return s.matchDoc }
+func (s *nearSpansOrdered) Start() int { return s.matchStart }
+func (s *nearSpansOrdered) End() int   { return s.matchEnd }
+
+func (s *nearSpansOrdered) SubSpans() []Spans {
+	return s.subSpans
+}
+
+/*
+NOTE: This may return an empty slice when the payloads of the
+subspans were not collected.
+*/
+func (s *nearSpansOrdered) Payload() ([][]byte, error) {
+	return s.matchPayload, nil
+}
+
+func (s *nearSpansOrdered) IsPayloadAvailable() (bool, error) {
+	return len(s.matchPayload) > 0, nil
+}
+
+func (s *nearSpansOrdered) Cost() int64 {
+	minCost := int64(math.MaxInt64)
+	for _, spans := range s.subSpans {
+		if cost := spans.Cost(); cost < minCost {
+			minCost = cost
+		}
+	}
+	return minCost
+}
+
+func (s *nearSpansOrdered) Next() (bool, error) {
+	if s.firstTime {
+		s.firstTime = false
+		for _, spans := range s.subSpans {
+			ok, err := spans.Next()
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				s.more = false
+				return false, nil
+			}
+		}
+		s.more = true
+	}
+	if s.collectPayloads {
+		s.matchPayload = s.matchPayload[:0]
+	}
+	return s.advanceAfterOrdered()
+}
+
+func (s *nearSpansOrdered) SkipTo(target int) (bool, error) {
+	if s.firstTime {
+		s.firstTime = false
+		for _, spans := range s.subSpans {
+			ok, err := spans.SkipTo(target)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				s.more = false
+				return false, nil
+			}
+		}
+		s.more = true
+	} else if s.more && s.subSpans[0].Doc() < target {
+		ok, err := s.subSpans[0].SkipTo(target)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			s.more = false
+			return false, nil
+		}
+		s.inSameDoc = false
+	}
+	if s.collectPayloads {
+		s.matchPayload = s.matchPayload[:0]
+	}
+	return s.advanceAfterOrdered()
+}
+
+/*
+Advances the subSpans to just after an ordered match with a minimum
+slop that is smaller than the slop allowed by the SpanNearQuery.
+Returns true iff there is such a match.
+*/
+func (s *nearSpansOrdered) advanceAfterOrdered() (bool, error) {
+	for s.more {
+		if !s.inSameDoc {
+			ok, err := s.toSameDoc()
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		ok, err := s.stretchToOrder()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			if ok, err = s.shrinkToAfterShortestMatch(); err != nil {
+				return false, err
+			} else if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil // no more matches
+}
+
+type spansByDoc []Spans
+
+func (s spansByDoc) Len() int           { return len(s) }
+func (s spansByDoc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s spansByDoc) Less(i, j int) bool { return s[i].Doc() < s[j].Doc() }
+
+/* Advance the subSpans to the same document */
+func (s *nearSpansOrdered) toSameDoc() (bool, error) {
+	sort.Stable(spansByDoc(s.subSpansByDoc))
+	firstIndex := 0
+	maxDoc := s.subSpansByDoc[len(s.subSpansByDoc)-1].Doc()
+	for s.subSpansByDoc[firstIndex].Doc() != maxDoc {
+		ok, err := s.subSpansByDoc[firstIndex].SkipTo(maxDoc)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			s.more = false
+			s.inSameDoc = false
+			return false, nil
+		}
+		maxDoc = s.subSpansByDoc[firstIndex].Doc()
+		if firstIndex++; firstIndex == len(s.subSpansByDoc) {
+			firstIndex = 0
+		}
+	}
+	for _, spans := range s.subSpansByDoc {
+		assert2(spans.Doc() == maxDoc,
+			"NearSpansOrdered.toSameDoc() spans %v\n  at doc %v, but should be at %v",
+			s.subSpansByDoc[0], spans.Doc(), maxDoc)
+	}
+	s.inSameDoc = true
+	return true, nil
+}
+
+/*
+Check whether two Spans in the same document are ordered. Returns
+true iff spans1 starts before spans2 or the spans start at the same
+position, and spans1 ends before spans2.
+*/
+func docSpansOrdered(spans1, spans2 Spans) bool {
+	assert2(spans1.Doc() == spans2.Doc(), "doc1 %v != doc2 %v", spans1.Doc(), spans2.Doc())
+	start1, start2 := spans1.Start(), spans2.Start()
+	// Do not call docSpansOrderedRange() to avoid invoking End():
+	if start1 == start2 {
+		return spans1.End() < spans2.End()
+	}
+	return start1 < start2
+}
+
+/*
+Like docSpansOrdered(), but use the spans starts and ends as
+parameters.
+*/
+func docSpansOrderedRange(start1, end1, start2, end2 int) bool {
+	if start1 == start2 {
+		return end1 < end2
+	}
+	return start1 < start2
+}
+
+/*
+Order the subSpans within the same document by advancing all later
+spans after the previous one.
+*/
+func (s *nearSpansOrdered) stretchToOrder() (bool, error) {
+	s.matchDoc = s.subSpans[0].Doc()
+	for i := 1; s.inSameDoc && i < len(s.subSpans); i++ {
+		for !docSpansOrdered(s.subSpans[i-1], s.subSpans[i]) {
+			ok, err := s.subSpans[i].Next()
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				s.inSameDoc = false
+				s.more = false
+				break
+			} else if s.matchDoc != s.subSpans[i].Doc() {
+				s.inSameDoc = false
+				break
+			}
+		}
+	}
+	return s.inSameDoc, nil
+}
+
+/*
+The subSpans are ordered in the same doc, so there is a possible
+match. Compute the slop while making the match as short as possible
+by advancing all subSpans except the last one in reverse order.
+*/
+func (s *nearSpansOrdered) shrinkToAfterShortestMatch() (bool, error) {
+	last := s.subSpans[len(s.subSpans)-1]
+	s.matchStart = last.Start()
+	s.matchEnd = last.End()
+	var possibleMatchPayloads [][]byte
+	if ok, err := last.IsPayloadAvailable(); err != nil {
+		return false, err
+	} else if ok {
+		payload, err := last.Payload()
+		if err != nil {
+			return false, err
+		}
+		possibleMatchPayloads = append(possibleMatchPayloads, payload...)
+	}
+
+	var possiblePayload [][]byte
+
+	matchSlop := 0
+	lastStart := s.matchStart
+	lastEnd := s.matchEnd
+	for i := len(s.subSpans) - 2; i >= 0; i-- {
+		prevSpans := s.subSpans[i]
+		if s.collectPayloads {
+			if ok, err := prevSpans.IsPayloadAvailable(); err != nil {
+				return false, err
+			} else if ok {
+				if possiblePayload, err = prevSpans.Payload(); err != nil {
+					return false, err
+				}
+			}
+		}
+
+		prevStart := prevSpans.Start()
+		prevEnd := prevSpans.End()
+		for { // Advance prevSpans until after (lastStart, lastEnd)
+			ok, err := prevSpans.Next()
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				s.inSameDoc = false
+				s.more = false
+				break // Check remaining subSpans for final match.
+			} else if s.matchDoc != prevSpans.Doc() {
+				s.inSameDoc = false // The last subSpans is not advanced here.
+				break               // Check remaining subSpans for last match in this document.
+			}
+			ppStart := prevSpans.Start()
+			ppEnd := prevSpans.End() // Cannot avoid invoking End()
+			if !docSpansOrderedRange(ppStart, ppEnd, lastStart, lastEnd) {
+				break // Check remaining subSpans.
+			}
+			// prevSpans still before (lastStart, lastEnd)
+			prevStart = ppStart
+			prevEnd = ppEnd
+			if s.collectPayloads {
+				if ok, err = prevSpans.IsPayloadAvailable(); err != nil {
+					return false, err
+				} else if ok {
+					if possiblePayload, err = prevSpans.Payload(); err != nil {
+						return false, err
+					}
+				}
+			}
+		}
+
+		if s.collectPayloads && possiblePayload != nil {
+			possibleMatchPayloads = append(possibleMatchPayloads, possiblePayload...)
+		}
+
+		assert(prevStart <= s.matchStart)
+		if s.matchStart > prevEnd { // Only non overlapping spans add to slop.
+			matchSlop += s.matchStart - prevEnd
+		}
+
+		// Do not break on (matchSlop > allowedSlop) here to make sure
+		// that subSpans[0] is advanced after the match, if any.
+		s.matchStart = prevStart
+		lastStart = prevStart
+		lastEnd = prevEnd
+	}
+
+	match := matchSlop <= s.allowedSlop
+
+	if s.collectPayloads && match && len(possibleMatchPayloads) > 0 {
+		s.matchPayload = append(s.matchPayload, possibleMatchPayloads...)
+	}
+
+	return match, nil // ordered and allowed slop
+}
+
+func (s *nearSpansOrdered) String() string {
+	if s.firstTime {
+		return fmt.Sprintf("NearSpansOrdered(%v)@START", s.query)
+	} else if s.more {
+		return fmt.Sprintf("NearSpansOrdered(%v)@%v:%v-%v", s.query, s.Doc(), s.Start(), s.End())
+	}
+	return fmt.Sprintf("NearSpansOrdered(%v)@END", s.query)
+}
+
+// search/spans/NearSpansUnordered.java
+
+/* Similar to nearSpansOrdered, but for the unordered case. */
+type nearSpansUnordered struct {
+	query *SpanNearQuery
+
+	ordered  []*spansCell // spans in query order
+	subSpans []Spans
+	slop     int // from query
+
+	first       *spansCell // linked list of spans
+	last        *spansCell // sorted by doc only
+	totalLength int        // sum of current lengths
+
+	queue *cellQueue // sorted queue of spans
+	max   *spansCell // max element in queue
+
+	more      bool // true iff not done
+	firstTime bool // true before first Next()
+}
+
+/* Sorts the cells by doc, then by position. */
+type cellQueue []*spansCell
+
+func (q cellQueue) Len() int      { return len(q) }
+func (q cellQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q cellQueue) Less(i, j int) bool {
+	spans1, spans2 := q[i], q[j]
+	if spans1.Doc() == spans2.Doc() {
+		return docSpansOrdered(spans1, spans2)
+	}
+	return spans1.Doc() < spans2.Doc()
+}
+func (q *cellQueue) Push(x interface{}) { *q = append(*q, x.(*spansCell)) }
+func (q *cellQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	ans := old[n-1]
+	*q = old[:n-1]
+	return ans
+}
+
+/* Wraps a Spans, and can be used to form a linked list. */
+type spansCell struct {
+	owner  *nearSpansUnordered
+	spans  Spans
+	next   *spansCell
+	length int
+	index  int
+}
+
+func (c *spansCell) Next() (bool, error) {
+	ok, err := c.spans.Next()
+	if err != nil {
+		return false, err
+	}
+	return c.adjust(ok), nil
+}
+
+func (c *spansCell) SkipTo(target int) (bool, error) {
+	ok, err := c.spans.SkipTo(target)
+	if err != nil {
+		return false, err
+	}
+	return c.adjust(ok), nil
+}
+
+func (c *spansCell) adjust(condition bool) bool {
+	o := c.owner
+	if c.length != -1 {
+		o.totalLength -= c.length // subtract old length
+	}
+	if condition {
+		c.length = c.End() - c.Start()
+		o.totalLength += c.length // add new length
+
+		if o.max == nil || c.Doc() > o.max.Doc() ||
+			c.Doc() == o.max.Doc() && c.End() > o.max.End() {
+			o.max = c
+		}
+	}
+	o.more = condition
+	return condition
+}
+
+func (c *spansCell) Doc() int   { return c.spans.Doc() }
+func (c *spansCell) Start() int { return c.spans.Start() }
+func (c *spansCell) End() int   { return c.spans.End() }
+
+func (c *spansCell) Payload() ([][]byte, error) {
+	payload, err := c.spans.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return append([][]byte(nil), payload...), nil
+}
+
+func (c *spansCell) IsPayloadAvailable() (bool, error) {
+	return c.spans.IsPayloadAvailable()
+}
+
+func (c *spansCell) Cost() int64 {
+	return c.spans.Cost()
+}
+
+func (c *spansCell) String() string {
+	return fmt.Sprintf("%v#%v", c.spans, c.index)
+}
+
+func newNearSpansUnordered(query *SpanNearQuery, ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits, termContexts map[string]*index.TermContext) (*nearSpansUnordered, error) {
+
+	clauses := query.Clauses()
+	ans := &nearSpansUnordered{
+		query:     query,
+		slop:      query.Slop(),
+		subSpans:  make([]Spans, len(clauses)),
+		queue:     new(cellQueue),
+		more:      true,
+		firstTime: true,
+	}
+	for i, clause := range clauses {
+		spans, err := clause.Spans(ctx, acceptDocs, termContexts)
+		if err != nil {
+			return nil, err
+		}
+		cell := &spansCell{owner: ans, spans: spans, length: -1, index: i}
+		ans.ordered = append(ans.ordered, cell)
+		ans.subSpans[i] = cell.spans
+	}
+	return ans, nil
+}
+
+func (s *nearSpansUnordered) SubSpans() []Spans {
+	return s.subSpans
+}
+
+func (s *nearSpansUnordered) Next() (ok bool, err error) {
+	if s.firstTime {
+		if err = s.initList(true); err != nil {
+			return false, err
+		}
+		s.listToQueue() // initialize queue
+		s.firstTime = false
+	} else if s.more {
+		if ok, err = s.min().Next(); err != nil { // trigger further scanning
+			return false, err
+		} else if ok {
+			heap.Fix(s.queue, 0) // maintain queue
+		} else {
+			s.more = false
+		}
+	}
+
+	for s.more {
+		queueStale := false
+
+		if s.min().Doc() != s.max.Doc() { // maintain list
+			s.queueToList()
+			queueStale = true
+		}
+
+		// skip to doc w/ all clauses
+
+		for s.more && s.first.Doc() < s.last.Doc() {
+			if s.more, err = s.first.SkipTo(s.last.Doc()); err != nil { // skip first upto last
+				return false, err
+			}
+			s.firstToLast() // and move it to the end
+			queueStale = true
+		}
+
+		if !s.more {
+			return false, nil
+		}
+
+		// found doc w/ all clauses
+
+		if queueStale { // maintain the queue
+			s.listToQueue()
+			queueStale = false
+		}
+
+		if s.atMatch() {
+			return true, nil
+		}
+
+		if s.more, err = s.min().Next(); err != nil {
+			return false, err
+		} else if s.more {
+			heap.Fix(s.queue, 0) // maintain queue
+		}
+	}
+	return false, nil // no more matches
+}
+
+func (s *nearSpansUnordered) SkipTo(target int) (ok bool, err error) {
+	if s.firstTime { // initialize
+		if err = s.initList(false); err != nil {
+			return false, err
+		}
+		for cell := s.first; s.more && cell != nil; cell = cell.next {
+			if s.more, err = cell.SkipTo(target); err != nil { // skip all
+				return false, err
+			}
+		}
+		if s.more {
+			s.listToQueue()
+		}
+		s.firstTime = false
+	} else { // normal case
+		for s.more && s.min().Doc() < target { // skip as needed
+			if ok, err = s.min().SkipTo(target); err != nil {
+				return false, err
+			} else if ok {
+				heap.Fix(s.queue, 0)
+			} else {
+				s.more = false
+			}
+		}
+	}
+	if !s.more {
+		return false, nil
+	}
+	if s.atMatch() {
+		return true, nil
+	}
+	return s.Next()
+}
+
+func (s *nearSpansUnordered) min() *spansCell {
+	return (*s.queue)[0]
+}
+
+func (s *nearSpansUnordered) Doc() int   { return s.min().Doc() }
+func (s *nearSpansUnordered) Start() int { return s.min().Start() }
+func (s *nearSpansUnordered) End() int   { return s.max.End() }
+
+/*
+WARNING: The List is not necessarily in order of the the positions.
+*/
+func (s *nearSpansUnordered) Payload() ([][]byte, error) {
+	var matchPayload [][]byte
+	for cell := s.first; cell != nil; cell = cell.next {
+		if ok, err := cell.IsPayloadAvailable(); err != nil {
+			return nil, err
+		} else if ok {
+			payload, err := cell.Payload()
+			if err != nil {
+				return nil, err
+			}
+			matchPayload = append(matchPayload, payload...)
+		}
+	}
+	return matchPayload, nil
+}
+
+func (s *nearSpansUnordered) IsPayloadAvailable() (bool, error) {
+	for pointer := s.min(); pointer != nil; pointer = pointer.next {
+		if ok, err := pointer.IsPayloadAvailable(); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (s *nearSpansUnordered) Cost() int64 {
+	minCost := int64(math.MaxInt64)
+	for _, spans := range s.subSpans {
+		if cost := spans.Cost(); cost < minCost {
+			minCost = cost
+		}
+	}
+	return minCost
+}
+
+func (s *nearSpansUnordered) String() string {
+	if s.firstTime {
+		return fmt.Sprintf("NearSpansUnordered(%v)@START", s.query)
+	} else if s.more {
+		return fmt.Sprintf("NearSpansUnordered(%v)@%v:%v-%v", s.query, s.Doc(), s.Start(), s.End())
+	}
+	return fmt.Sprintf("NearSpansUnordered(%v)@END", s.query)
+}
+
+func (s *nearSpansUnordered) initList(next bool) (err error) {
+	for i := 0; s.more && i < len(s.ordered); i++ {
+		cell := s.ordered[i]
+		if next {
+			if s.more, err = cell.Next(); err != nil { // move to first entry
+				return err
+			}
+		}
+		if s.more {
+			s.addToList(cell) // add to list
+		}
+	}
+	return nil
+}
+
+func (s *nearSpansUnordered) addToList(cell *spansCell) {
+	if s.last != nil { // add next to end of list
+		s.last.next = cell
+	} else {
+		s.first = cell
+	}
+	s.last = cell
+	cell.next = nil
+}
+
+func (s *nearSpansUnordered) firstToLast() {
+	s.last.next = s.first // move first to end of list
+	s.last = s.first
+	s.first = s.first.next
+	s.last.next = nil
+}
+
+func (s *nearSpansUnordered) queueToList() {
+	s.last, s.first = nil, nil
+	for s.queue.Len() > 0 {
+		s.addToList(heap.Pop(s.queue).(*spansCell))
+	}
+}
+
+func (s *nearSpansUnordered) listToQueue() {
+	*s.queue = (*s.queue)[:0] // rebuild queue
+	for cell := s.first; cell != nil; cell = cell.next {
+		heap.Push(s.queue, cell) // add to queue from list
+	}
+}
+
+func (s *nearSpansUnordered) atMatch() bool {
+	return s.min().Doc() == s.max.Doc() &&
+		s.max.End()-s.min().Start()-s.totalLength <= s.slop
+}
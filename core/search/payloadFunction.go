@@ -0,0 +1,134 @@
+package search
+
+import (
+	"math"
+)
+
+// search/payloads/PayloadFunction.java
+
+/*
+An abstract class that defines a way for Payload*Query instances to
+transform the cumulative effects of payload scores for a document.
+
+This interface is experimental and subject to change.
+*/
+type PayloadFunction interface {
+	// Calculate the score up to this point for this doc and field.
+	CurrentScore(docId int, field string, start, end, numPayloadsSeen int,
+		currentScore, currentPayloadScore float32) float32
+	// Calculate the final score for all the payloads seen so far for
+	// this doc/field.
+	DocScore(docId int, field string, numPayloadsSeen int, payloadScore float32) float32
+	Explain(docId int, field string, numPayloadsSeen int, payloadScore float32) Explanation
+}
+
+// search/payloads/AveragePayloadFunction.java
+
+/*
+Calculate the final score as the average score of all payloads seen.
+
+Is thread safe and completely reusable.
+*/
+type AveragePayloadFunction struct{}
+
+func NewAveragePayloadFunction() *AveragePayloadFunction {
+	return &AveragePayloadFunction{}
+}
+
+func (f *AveragePayloadFunction) CurrentScore(docId int, field string,
+	start, end, numPayloadsSeen int, currentScore, currentPayloadScore float32) float32 {
+	return currentPayloadScore + currentScore
+}
+
+func (f *AveragePayloadFunction) DocScore(docId int, field string,
+	numPayloadsSeen int, payloadScore float32) float32 {
+	if numPayloadsSeen > 0 {
+		return payloadScore / float32(numPayloadsSeen)
+	}
+	return 1
+}
+
+func (f *AveragePayloadFunction) Explain(docId int, field string,
+	numPayloadsSeen int, payloadScore float32) Explanation {
+	return NewExplanation(f.DocScore(docId, field, numPayloadsSeen, payloadScore),
+		"AveragePayloadFunction.docScore()")
+}
+
+func (f *AveragePayloadFunction) String() string {
+	return "AveragePayloadFunction"
+}
+
+// search/payloads/MaxPayloadFunction.java
+
+/*
+Returns the maximum payload score seen, else 1 if there are no
+payloads on the doc.
+
+Is thread safe and completely reusable.
+*/
+type MaxPayloadFunction struct{}
+
+func NewMaxPayloadFunction() *MaxPayloadFunction {
+	return &MaxPayloadFunction{}
+}
+
+func (f *MaxPayloadFunction) CurrentScore(docId int, field string,
+	start, end, numPayloadsSeen int, currentScore, currentPayloadScore float32) float32 {
+	if numPayloadsSeen == 0 {
+		return currentPayloadScore
+	}
+	return float32(math.Max(float64(currentPayloadScore), float64(currentScore)))
+}
+
+func (f *MaxPayloadFunction) DocScore(docId int, field string,
+	numPayloadsSeen int, payloadScore float32) float32 {
+	if numPayloadsSeen > 0 {
+		return payloadScore
+	}
+	return 1
+}
+
+func (f *MaxPayloadFunction) Explain(docId int, field string,
+	numPayloadsSeen int, payloadScore float32) Explanation {
+	return NewExplanation(f.DocScore(docId, field, numPayloadsSeen, payloadScore),
+		"MaxPayloadFunction.docScore()")
+}
+
+func (f *MaxPayloadFunction) String() string {
+	return "MaxPayloadFunction"
+}
+
+// search/payloads/MinPayloadFunction.java
+
+/* Calculates the minimum payload seen */
+type MinPayloadFunction struct{}
+
+func NewMinPayloadFunction() *MinPayloadFunction {
+	return &MinPayloadFunction{}
+}
+
+func (f *MinPayloadFunction) CurrentScore(docId int, field string,
+	start, end, numPayloadsSeen int, currentScore, currentPayloadScore float32) float32 {
+	if numPayloadsSeen == 0 {
+		return currentPayloadScore
+	}
+	return float32(math.Min(float64(currentPayloadScore), float64(currentScore)))
+}
+
+func (f *MinPayloadFunction) DocScore(docId int, field string,
+	numPayloadsSeen int, payloadScore float32) float32 {
+	if numPayloadsSeen > 0 {
+		return payloadScore
+	}
+	return 1
+}
+
+func (f *MinPayloadFunction) Explain(docId int, field string,
+	numPayloadsSeen int, payloadScore float32) Explanation {
+	return NewExplanation(f.DocScore(docId, field, numPayloadsSeen, payloadScore),
+		"MinPayloadFunction.docScore()")
+}
+
+func (f *MinPayloadFunction) String() string {
+	return "MinPayloadFunction"
+}
@@ -0,0 +1,255 @@
+package search
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/util"
+	"reflect"
+)
+
+// search/payloads/PayloadNearQuery.java
+
+/*
+This class is very similar to SpanNearQuery except that it factors
+in the value of the payloads located at each of the positions where
+the TermSpans occurs.
+
+NOTE: In order to take advantage of this with the default scoring
+implementation (DefaultSimilarity), you must override
+scorePayload(), which returns 1 by default; see PayloadSimilarity.
+
+Payload scores are aggregated using a pluggable PayloadFunction.
+*/
+type PayloadNearQuery struct {
+	*SpanNearQuery
+	fieldName string
+	function  PayloadFunction
+}
+
+/* Uses AveragePayloadFunction to aggregate the payloads. */
+func NewPayloadNearQuery(clauses []SpanQuery, slop int, inOrder bool) *PayloadNearQuery {
+	return NewPayloadNearQueryWithFunction(clauses, slop, inOrder, NewAveragePayloadFunction())
+}
+
+func NewPayloadNearQueryWithFunction(clauses []SpanQuery, slop int,
+	inOrder bool, function PayloadFunction) *PayloadNearQuery {
+
+	ans := &PayloadNearQuery{
+		SpanNearQuery: new(SpanNearQuery),
+		function:      function,
+	}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	ans.init(clauses, slop, inOrder, true)
+	if len(clauses) > 0 {
+		ans.fieldName = clauses[0].Field()
+	}
+	return ans
+}
+
+func (q *PayloadNearQuery) CreateWeight(ss *IndexSearcher) (Weight, error) {
+	return newPayloadNearSpanWeight(q, ss)
+}
+
+func (q *PayloadNearQuery) Rewrite(reader index.IndexReader) Query {
+	if clauses, ok := q.rewriteClauses(reader); ok {
+		clone := q.Clone().(*PayloadNearQuery)
+		clone.clauses = clauses
+		return clone // some clauses rewrote
+	}
+	return q // no clauses rewrote
+}
+
+func (q *PayloadNearQuery) Clone() Query {
+	clauses := make([]SpanQuery, len(q.clauses))
+	for i, clause := range q.clauses {
+		clauses[i] = clause.Clone().(SpanQuery)
+	}
+	ans := NewPayloadNearQueryWithFunction(clauses, q.slop, q.inOrder, q.function)
+	ans.boost = q.boost
+	return ans
+}
+
+func (q *PayloadNearQuery) ToString(field string) string {
+	return q.toString("payloadNear", field)
+}
+
+type payloadNearSpanWeight struct {
+	*SpanWeight
+	owner *PayloadNearQuery
+}
+
+func newPayloadNearSpanWeight(owner *PayloadNearQuery, ss *IndexSearcher) (*payloadNearSpanWeight, error) {
+	w, err := newSpanWeight(owner, ss)
+	if err != nil {
+		return nil, err
+	}
+	ans := &payloadNearSpanWeight{SpanWeight: w, owner: owner}
+	ans.WeightImpl = NewWeightImpl(ans)
+	return ans, nil
+}
+
+func (w *payloadNearSpanWeight) Scorer(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (Scorer, error) {
+	s, err := w.payloadScorer(ctx, acceptDocs)
+	if s == nil || err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (w *payloadNearSpanWeight) payloadScorer(ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits) (*payloadNearSpanScorer, error) {
+
+	if w.stats == nil {
+		return nil, nil
+	}
+	spans, err := w.query.Spans(ctx, acceptDocs, w.termContexts)
+	if err != nil {
+		return nil, err
+	}
+	docScorer, err := w.similarity.SimScorer(w.stats, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newPayloadNearSpanScorer(spans, w, docScorer)
+}
+
+func (w *payloadNearSpanWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanation, error) {
+	scorer, err := w.payloadScorer(ctx, ctx.Reader().(index.AtomicReader).LiveDocs())
+	if err != nil {
+		return nil, err
+	}
+	if scorer != nil {
+		newDoc, err := scorer.Advance(doc)
+		if err != nil {
+			return nil, err
+		}
+		if newDoc == doc {
+			freq := scorer.SloppyFreq()
+			docScorer, err := w.similarity.SimScorer(w.stats, ctx)
+			if err != nil {
+				return nil, err
+			}
+			scoreExplanation := docScorer.Explain(doc,
+				NewExplanation(freq, fmt.Sprintf("phraseFreq=%v", freq)))
+			expl := NewExplanation(scoreExplanation.Value(),
+				fmt.Sprintf("weight(%v in %v) [%v], result of:",
+					w.owner, doc, reflect.TypeOf(w.similarity)))
+			expl.AddDetail(scoreExplanation)
+			// now the payloads part
+			payloadExpl := w.owner.function.Explain(doc, w.owner.fieldName,
+				scorer.payloadsSeen, scorer.payloadSum)
+			result := NewComplexExplanation(true, expl.Value()*payloadExpl.Value(),
+				"PayloadNearQuery, product of:")
+			result.details = []Explanation{expl, payloadExpl}
+			return result, nil
+		}
+	}
+	return NewComplexExplanation(false, 0, "no matching term"), nil
+}
+
+type payloadNearSpanScorer struct {
+	*SpanScorer
+	owner        *payloadNearSpanWeight
+	scratch      *util.BytesRef
+	payloadSum   float32
+	payloadsSeen int
+}
+
+func newPayloadNearSpanScorer(spans Spans, weight *payloadNearSpanWeight,
+	docScorer SimScorer) (*payloadNearSpanScorer, error) {
+
+	s, err := newSpanScorer(spans, weight, docScorer)
+	if err != nil {
+		return nil, err
+	}
+	ans := &payloadNearSpanScorer{
+		SpanScorer: s,
+		owner:      weight,
+		scratch:    util.NewEmptyBytesRef(),
+	}
+	ans.spi = ans
+	return ans, nil
+}
+
+/* Get the payloads associated with all underlying subspans */
+func (s *payloadNearSpanScorer) collectPayloads(subSpans []Spans) error {
+	for _, spans := range subSpans {
+		var nested []Spans
+		switch near := spans.(type) {
+		case *nearSpansOrdered:
+			nested = near.SubSpans()
+		case *nearSpansUnordered:
+			nested = near.SubSpans()
+		default:
+			continue
+		}
+		if ok, err := spans.IsPayloadAvailable(); err != nil {
+			return err
+		} else if ok {
+			payloads, err := spans.Payload()
+			if err != nil {
+				return err
+			}
+			s.processPayloads(payloads, spans.Start(), spans.End())
+		}
+		if err := s.collectPayloads(nested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+By default, uses the PayloadFunction to score the payloads, but can
+be overridden to do other things.
+*/
+func (s *payloadNearSpanScorer) processPayloads(payloads [][]byte, start, end int) {
+	function := s.owner.owner.function
+	for _, payload := range payloads {
+		if payload == nil {
+			continue
+		}
+		s.scratch.Bytes = payload
+		s.scratch.Offset = 0
+		s.scratch.Length = len(payload)
+		s.payloadSum = function.CurrentScore(s.doc, s.owner.owner.fieldName, start, end,
+			s.payloadsSeen, s.payloadSum,
+			s.docScorer.ComputePayloadFactor(s.doc, s.spans.Start(), s.spans.End(), s.scratch))
+		s.payloadsSeen++
+	}
+}
+
+func (s *payloadNearSpanScorer) setFreqCurrentDoc() (ok bool, err error) {
+	if !s.more {
+		return false, nil
+	}
+	s.doc = s.spans.Doc()
+	s.freq = 0
+	s.numMatches = 0
+	s.payloadSum = 0
+	s.payloadsSeen = 0
+	for {
+		matchLength := s.spans.End() - s.spans.Start()
+		s.freq += s.docScorer.ComputeSlopFactor(matchLength)
+		s.numMatches++
+		if err = s.collectPayloads([]Spans{s.spans}); err != nil {
+			return false, err
+		}
+		if s.more, err = s.spans.Next(); err != nil {
+			return false, err
+		}
+		if !s.more || s.doc != s.spans.Doc() {
+			break
+		}
+	}
+	return true, nil
+}
+
+func (s *payloadNearSpanScorer) Score() (float32, error) {
+	score, err := s.SpanScorer.Score()
+	if err != nil {
+		return 0, err
+	}
+	return score * s.owner.owner.function.DocScore(s.doc, s.owner.owner.fieldName,
+		s.payloadsSeen, s.payloadSum), nil
+}
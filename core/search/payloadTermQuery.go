@@ -0,0 +1,242 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/util"
+	"reflect"
+)
+
+// search/payloads/PayloadTermQuery.java
+
+/*
+This class is very similar to SpanTermQuery except that it factors
+in the value of the payload located at each of the positions where
+the Term occurs.
+
+NOTE: In order to take advantage of this with the default scoring
+implementation (DefaultSimilarity), you must override
+scorePayload(), which returns 1 by default; see PayloadSimilarity.
+
+Payload scores are aggregated using a pluggable PayloadFunction.
+*/
+type PayloadTermQuery struct {
+	*SpanTermQuery
+	function         PayloadFunction
+	includeSpanScore bool
+}
+
+func NewPayloadTermQuery(term *index.Term, function PayloadFunction) *PayloadTermQuery {
+	return NewPayloadTermQueryWithSpanScore(term, function, true)
+}
+
+/*
+If includeSpanScore is false, the score of the query is the payload
+score only; otherwise it is multiplied by the span score.
+*/
+func NewPayloadTermQueryWithSpanScore(term *index.Term,
+	function PayloadFunction, includeSpanScore bool) *PayloadTermQuery {
+
+	ans := &PayloadTermQuery{
+		SpanTermQuery:    &SpanTermQuery{term: term},
+		function:         function,
+		includeSpanScore: includeSpanScore,
+	}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+func (q *PayloadTermQuery) CreateWeight(ss *IndexSearcher) (Weight, error) {
+	return newPayloadTermWeight(q, ss)
+}
+
+func (q *PayloadTermQuery) Clone() Query {
+	ans := NewPayloadTermQueryWithSpanScore(q.term, q.function, q.includeSpanScore)
+	ans.boost = q.boost
+	return ans
+}
+
+func (q *PayloadTermQuery) ToString(field string) string {
+	var buf bytes.Buffer
+	buf.WriteString("payloadTerm(")
+	buf.WriteString(q.SpanTermQuery.ToString(field))
+	buf.WriteString(fmt.Sprintf(", %v, %v)", q.function, q.includeSpanScore))
+	return buf.String()
+}
+
+type payloadTermWeight struct {
+	*SpanWeight
+	owner *PayloadTermQuery
+}
+
+func newPayloadTermWeight(owner *PayloadTermQuery, ss *IndexSearcher) (*payloadTermWeight, error) {
+	w, err := newSpanWeight(owner, ss)
+	if err != nil {
+		return nil, err
+	}
+	ans := &payloadTermWeight{SpanWeight: w, owner: owner}
+	ans.WeightImpl = NewWeightImpl(ans)
+	return ans, nil
+}
+
+func (w *payloadTermWeight) Scorer(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (Scorer, error) {
+	s, err := w.payloadScorer(ctx, acceptDocs)
+	if s == nil || err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (w *payloadTermWeight) payloadScorer(ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits) (*payloadTermSpanScorer, error) {
+
+	if w.stats == nil {
+		return nil, nil
+	}
+	spans, err := w.query.Spans(ctx, acceptDocs, w.termContexts)
+	if err != nil {
+		return nil, err
+	}
+	docScorer, err := w.similarity.SimScorer(w.stats, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newPayloadTermSpanScorer(spans, w, docScorer)
+}
+
+func (w *payloadTermWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanation, error) {
+	scorer, err := w.payloadScorer(ctx, ctx.Reader().(index.AtomicReader).LiveDocs())
+	if err != nil {
+		return nil, err
+	}
+	if scorer != nil {
+		newDoc, err := scorer.Advance(doc)
+		if err != nil {
+			return nil, err
+		}
+		if newDoc == doc {
+			freq := scorer.SloppyFreq()
+			docScorer, err := w.similarity.SimScorer(w.stats, ctx)
+			if err != nil {
+				return nil, err
+			}
+			scoreExplanation := docScorer.Explain(doc,
+				NewExplanation(freq, fmt.Sprintf("phraseFreq=%v", freq)))
+			expl := NewExplanation(scoreExplanation.Value(),
+				fmt.Sprintf("weight(%v in %v) [%v], result of:",
+					w.owner, doc, reflect.TypeOf(w.similarity)))
+			expl.AddDetail(scoreExplanation)
+			// now the payloads part
+			payloadExpl := w.owner.function.Explain(doc, w.owner.Field(),
+				scorer.payloadsSeen, scorer.payloadSum)
+			// combined
+			var result *ComplexExplanation
+			if w.owner.includeSpanScore {
+				result = NewComplexExplanation(true, expl.Value()*payloadExpl.Value(),
+					"btq, product of:")
+				result.details = []Explanation{expl, payloadExpl}
+			} else {
+				result = NewComplexExplanation(true, payloadExpl.Value(),
+					"btq(includeSpanScore=false), result of:")
+				result.details = []Explanation{payloadExpl}
+			}
+			return result, nil
+		}
+	}
+	return NewComplexExplanation(false, 0, "no matching term"), nil
+}
+
+type payloadTermSpanScorer struct {
+	*SpanScorer
+	owner        *payloadTermWeight
+	termSpans    *TermSpans
+	payloadSum   float32
+	payloadsSeen int
+}
+
+func newPayloadTermSpanScorer(spans Spans, weight *payloadTermWeight,
+	docScorer SimScorer) (*payloadTermSpanScorer, error) {
+
+	s, err := newSpanScorer(spans, weight, docScorer)
+	if err != nil {
+		return nil, err
+	}
+	ans := &payloadTermSpanScorer{SpanScorer: s, owner: weight}
+	ans.termSpans, _ = spans.(*TermSpans)
+	ans.spi = ans
+	return ans, nil
+}
+
+func (s *payloadTermSpanScorer) setFreqCurrentDoc() (bool, error) {
+	if !s.more {
+		return false, nil
+	}
+	s.doc = s.spans.Doc()
+	s.freq = 0
+	s.numMatches = 0
+	s.payloadSum = 0
+	s.payloadsSeen = 0
+	for s.more && s.doc == s.spans.Doc() {
+		matchLength := s.spans.End() - s.spans.Start()
+
+		s.freq += s.docScorer.ComputeSlopFactor(matchLength)
+		s.numMatches++
+		if err := s.processPayload(); err != nil {
+			return false, err
+		}
+
+		var err error
+		// this moves positions to the next match in this document
+		if s.more, err = s.spans.Next(); err != nil {
+			return false, err
+		}
+	}
+	return s.more || s.freq != 0, nil
+}
+
+func (s *payloadTermSpanScorer) processPayload() error {
+	if s.termSpans == nil {
+		return nil
+	}
+	ok, err := s.termSpans.IsPayloadAvailable()
+	if err != nil || !ok {
+		return err
+	}
+	payload, err := s.termSpans.Postings().Payload()
+	if err != nil {
+		return err
+	}
+	factor := float32(1)
+	if payload != nil {
+		factor = s.docScorer.ComputePayloadFactor(s.doc, s.spans.Start(), s.spans.End(), payload)
+	}
+	s.payloadSum = s.owner.owner.function.CurrentScore(s.doc, s.owner.owner.Field(),
+		s.spans.Start(), s.spans.End(), s.payloadsSeen, s.payloadSum, factor)
+	s.payloadsSeen++
+	return nil
+}
+
+/*
+Returns the span score multiplied by the payload score, or the
+payload score only if the query does not include the span score.
+*/
+func (s *payloadTermSpanScorer) Score() (float32, error) {
+	if s.owner.owner.includeSpanScore {
+		spanScore, err := s.SpanScorer.Score()
+		if err != nil {
+			return 0, err
+		}
+		return spanScore * s.payloadScore(), nil
+	}
+	return s.payloadScore(), nil
+}
+
+/*
+The score for the payload, as computed by the PayloadFunction's
+DocScore().
+*/
+func (s *payloadTermSpanScorer) payloadScore() float32 {
+	return s.owner.owner.function.DocScore(s.doc, s.owner.owner.Field(),
+		s.payloadsSeen, s.payloadSum)
+}
@@ -0,0 +1,83 @@
+package search_test
+
+import (
+	ac "github.com/jtejido/golucene/analysis/core"
+	"github.com/jtejido/golucene/analysis/payloads"
+	. "github.com/jtejido/golucene/core/analysis"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/search/similarities"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"io"
+	"testing"
+)
+
+type payloadAnalyzer struct {
+	*AnalyzerImpl
+}
+
+func newPayloadAnalyzer() *payloadAnalyzer {
+	ans := new(payloadAnalyzer)
+	ans.AnalyzerImpl = NewAnalyzer()
+	ans.Spi = ans
+	return ans
+}
+
+func (a *payloadAnalyzer) CreateComponents(fieldName string, reader io.RuneReader) *TokenStreamComponents {
+	source := ac.NewWhitespaceTokenizer(reader)
+	return NewTokenStreamComponents(source, payloads.NewDelimitedPayloadTokenFilter(
+		source, payloads.DEFAULT_DELIMITER, payloads.NewFloatEncoder()))
+}
+
+func newPayloadSearcher(t *testing.T, texts ...string) *search.IndexSearcher {
+	directory := testindex.NewDirectory(t)
+	conf := index.NewIndexWriterConfig(util.VERSION_LATEST, newPayloadAnalyzer()).
+		SetSimilarity(similarities.NewPayloadSimilarity())
+	writer := testindex.NewWriter(t, directory, conf)
+	for _, doc := range testindex.TextDocs("body", texts...) {
+		if err := writer.AddDocument(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader := testindex.OpenReader(t, directory)
+	searcher := search.NewIndexSearcher(reader)
+	searcher.SetSimilarity(similarities.NewPayloadSimilarity())
+	return searcher
+}
+
+func assertOrder(t *testing.T, searcher *search.IndexSearcher, q search.Query, expected ...int) {
+	docs, err := searcher.SearchTop(q, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs.TotalHits != len(expected) {
+		t.Fatalf("%v: expected %v hits, but %v", q, len(expected), docs.TotalHits)
+	}
+	for i, doc := range expected {
+		if got := docs.ScoreDocs[i].Doc; got != doc {
+			t.Errorf("%v: expected doc %v at rank %v, but %v", q, doc, i, got)
+		}
+	}
+}
+
+func TestPayloadQueries(t *testing.T) {
+	searcher := newPayloadSearcher(t,
+		"red|1.0 fox", "red|5.0 fox", "red|3.0 fox|2.0", "blue|9.0 fox|9.0")
+
+	red := index.NewTerm("body", "red")
+	fox := index.NewTerm("body", "fox")
+
+	assertOrder(t, searcher, search.NewPayloadTermQuery(red, search.NewMaxPayloadFunction()), 1, 2, 0)
+	assertOrder(t, searcher, search.NewPayloadTermQuery(fox, search.NewMinPayloadFunction()), 3, 2, 0, 1)
+
+	clauses := []search.SpanQuery{search.NewSpanTermQuery(red), search.NewSpanTermQuery(fox)}
+	// averages: 1 (fox has no payload), 5, (3+2)/2
+	assertOrder(t, searcher, search.NewPayloadNearQuery(clauses, 0, true), 1, 2, 0)
+	assertOrder(t, searcher, search.NewPayloadNearQuery(clauses, 0, false), 1, 2, 0)
+	// the plain span query ignores the payloads
+	assertOrder(t, searcher, search.NewSpanNearQuery(clauses, 0, true), 0, 1, 2)
+}
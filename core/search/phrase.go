@@ -30,6 +30,37 @@ func NewPhraseQuery() *PhraseQuery {
 	return ans
 }
 
+/*
+Sets the number of other words permitted between words in query
+phrase. If zero, then this is an exact phrase search. For larger
+values this works like a WITHIN or NEAR operator.
+
+The slop is in fact an edit-distance, where the units correspond to
+moves of terms in the query phrase out of position. For example, to
+switch the order of two words requires two moves (the first move
+places the words atop one another), so to permit re-orderings of
+phrases, the slop must be at least two.
+
+More exact matches are scored higher than sloppier matches, thus
+search results are sorted by exactness.
+
+The slop is zero by default, requiring exact matches.
+*/
+func (q *PhraseQuery) SetSlop(s int) {
+	assert2(s >= 0, "slop value cannot be negative")
+	q.slop = s
+}
+
+/* Returns the slop. See SetSlop(). */
+func (q *PhraseQuery) Slop() int {
+	return q.slop
+}
+
+/* Returns the set of terms in this phrase. */
+func (q *PhraseQuery) Terms() []*index.Term {
+	return q.terms
+}
+
 func (q *PhraseQuery) Add(term *index.Term) {
 	position := int32(0)
 	if len(q.positions) > 0 {
@@ -79,7 +110,7 @@ func newPhraseWeight(owner *PhraseQuery, searcher *IndexSearcher) (w *PhraseWeig
 		termStats[i] = searcher.TermStatistics(term, w.states[i])
 	}
 	w.stats = w.similarity.ComputeWeight(owner.Boost(), searcher.CollectionStatistics(owner.field), termStats...)
-	w.WeightImpl = newWeightImpl(w)
+	w.WeightImpl = NewWeightImpl(w)
 	return w, nil
 }
 
@@ -160,9 +191,7 @@ func (w *PhraseWeight) Scorer(context *index.AtomicReaderContext, acceptDocs uti
 		return newExactPhraseScorer(w, postingsFreqs, ss)
 	}
 
-	panic("niy")
-	return nil, nil
-	// return newSloppyPhraseScorer(w, postingsFreqs, w.owner.slop, ss)
+	return newSloppyPhraseScorer(w, postingsFreqs, w.owner.slop, ss), nil
 }
 
 type PostingsAndFreq struct {
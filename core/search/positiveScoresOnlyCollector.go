@@ -0,0 +1,46 @@
+package search
+
+import (
+	"github.com/jtejido/golucene/core/index"
+)
+
+// search/PositiveScoresOnlyCollector.java
+
+/*
+A Collector implementation which wraps another Collector and makes
+sure only documents with scores > 0 are collected.
+*/
+type PositiveScoresOnlyCollector struct {
+	c      Collector
+	scorer Scorer
+}
+
+func NewPositiveScoresOnlyCollector(c Collector) *PositiveScoresOnlyCollector {
+	return &PositiveScoresOnlyCollector{c: c}
+}
+
+func (c *PositiveScoresOnlyCollector) Collect(doc int) error {
+	score, err := c.scorer.Score()
+	if err != nil {
+		return err
+	}
+	if score > 0 {
+		return c.c.Collect(doc)
+	}
+	return nil
+}
+
+func (c *PositiveScoresOnlyCollector) SetNextReader(ctx *index.AtomicReaderContext) {
+	c.c.SetNextReader(ctx)
+}
+
+func (c *PositiveScoresOnlyCollector) SetScorer(s Scorer) {
+	// Set a ScoreCachingWrappingScorer in case the wrapped Collector
+	// will call Score() also.
+	c.scorer = NewScoreCachingWrappingScorer(s)
+	c.c.SetScorer(c.scorer)
+}
+
+func (c *PositiveScoresOnlyCollector) AcceptsDocsOutOfOrder() bool {
+	return c.c.AcceptsDocsOutOfOrder()
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/PrefixQuery.java
+
+/*
+A Query that matches documents containing terms with a specified
+prefix. It is rewritten with CONSTANT_SCORE_FILTER_REWRITE, unless
+SetRewriteMethod() is called.
+*/
+type PrefixQuery struct {
+	*AbstractMultiTermQuery
+	prefix *index.Term
+}
+
+/* Constructs a query for terms starting with prefix. */
+func NewPrefixQuery(prefix *index.Term) *PrefixQuery {
+	ans := &PrefixQuery{prefix: prefix}
+	ans.AbstractMultiTermQuery = NewAbstractMultiTermQuery(ans, prefix.Field)
+	return ans
+}
+
+/* Returns the prefix of this query. */
+func (q *PrefixQuery) Prefix() *index.Term {
+	return q.prefix
+}
+
+func (q *PrefixQuery) TermsEnum(terms Terms, atts *util.AttributeSource) (TermsEnum, error) {
+	tenum := terms.Iterator(nil)
+	if len(q.prefix.Bytes) == 0 {
+		// no prefix -- match all terms for this field
+		return tenum, nil
+	}
+	return newPrefixTermsEnum(tenum, q.prefix.Bytes), nil
+}
+
+func (q *PrefixQuery) Clone() Query {
+	ans := NewPrefixQuery(q.prefix)
+	ans.SetRewriteMethod(q.RewriteMethod())
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *PrefixQuery) ToString(field string) string {
+	var buf bytes.Buffer
+	if q.Field() != field {
+		buf.WriteString(q.Field())
+		buf.WriteRune(':')
+	}
+	buf.Write(q.prefix.Bytes)
+	buf.WriteRune('*')
+	if q.Boost() != 1.0 {
+		buf.WriteString(fmt.Sprintf("^%v", q.Boost()))
+	}
+	return buf.String()
+}
+
+// search/PrefixTermsEnum.java
+
+/*
+Subclass of FilteredTermsEnum for enumerating all terms that match
+the specified prefix filter term.
+*/
+type prefixTermsEnum struct {
+	TermsEnum
+	prefix  []byte
+	started bool
+}
+
+func newPrefixTermsEnum(tenum TermsEnum, prefix []byte) *prefixTermsEnum {
+	return &prefixTermsEnum{TermsEnum: tenum, prefix: prefix}
+}
+
+func (e *prefixTermsEnum) Next() ([]byte, error) {
+	var term []byte
+	if !e.started {
+		e.started = true
+		status, err := e.TermsEnum.SeekCeil(e.prefix)
+		if err != nil || status == SEEK_STATUS_END {
+			return nil, err
+		}
+		term = e.TermsEnum.Term()
+	} else {
+		var err error
+		if term, err = e.TermsEnum.Next(); err != nil || term == nil {
+			return nil, err
+		}
+	}
+	if !bytes.HasPrefix(term, e.prefix) {
+		// terms are sorted: no more matches
+		return nil, nil
+	}
+	return term, nil
+}
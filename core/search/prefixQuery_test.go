@@ -0,0 +1,124 @@
+package search_test
+
+import (
+	"context"
+	"fmt"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestPrefixQuery(t *testing.T) {
+	// without norms, see lucene49
+	var docs [][]model.IndexableField
+	for i := -1; i < 2000; i++ {
+		text := fmt.Sprintf("t%04d", i)
+		if i < 0 {
+			text = "other"
+		}
+		d := docu.NewDocument()
+		d.Add(docu.NewFieldFromString("body", text, docu.STRING_FIELD_TYPE_NOT_STORED))
+		docs = append(docs, d.Fields())
+	}
+	searcher := search.NewIndexSearcher(testindex.NewReader(t, docs...))
+	totalHits := func(q search.Query) (int, error) {
+		docs, err := searcher.SearchTop(q, 10)
+		return docs.TotalHits, err
+	}
+
+	for _, c := range []struct {
+		prefix string
+		hits   int
+	}{{"t1", 1000}, {"t19", 100}, {"o", 1}, {"x", 0}, {"", 2001}} {
+		q := search.NewPrefixQuery(index.NewTerm("body", c.prefix))
+		if n, err := totalHits(q); err != nil || n != c.hits {
+			t.Errorf("%v: expected %v hits, but %v %v", q, c.hits, n, err)
+		}
+		q.SetRewriteMethod(search.SCORING_BOOLEAN_QUERY_REWRITE)
+		n, err := totalHits(q)
+		if c.hits > 1024 {
+			if err == nil {
+				t.Errorf("%v: expected too many clauses", q)
+			}
+		} else if err != nil || n != c.hits {
+			t.Errorf("%v: expected %v scored hits, but %v %v", q, c.hits, n, err)
+		}
+	}
+	if s := search.NewPrefixQuery(index.NewTerm("body", "t1")).ToString("body"); s != "t1*" {
+		t.Errorf("unexpected ToString() %v", s)
+	}
+
+	// cancelled while enumerating the terms
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := newCancellingQuery(search.NewPrefixQuery(index.NewTerm("body", "t")), cancel, 10)
+	if _, err := searcher.RewriteContext(ctx, q); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+	if q.enumerated >= 2000 {
+		t.Errorf("expected the rewrite to stop early, but %v terms enumerated", q.enumerated)
+	}
+
+	// cancelled while the default filter rewrite marks documents
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	q = newCancellingQuery(search.NewPrefixQuery(index.NewTerm("body", "t")), cancel, 10)
+	q.SetRewriteMethod(search.CONSTANT_SCORE_FILTER_REWRITE)
+	if _, err := searcher.SearchContext(ctx, q, nil, 10); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+	if max := 10 + search.CANCELLATION_CHECK_TERM_INTERVAL; q.enumerated > max {
+		t.Errorf("expected the filter to stop within %v terms, but %v terms enumerated", max, q.enumerated)
+	}
+
+	// too many clauses for a nested scoring rewrite fail the search
+	broad := search.NewPrefixQuery(index.NewTerm("body", "t"))
+	broad.SetRewriteMethod(search.SCORING_BOOLEAN_QUERY_REWRITE)
+	bq := search.NewBooleanQuery()
+	bq.Add(broad, search.SHOULD)
+	bq.Add(search.NewTermQuery(index.NewTerm("body", "other")), search.SHOULD)
+	if _, err := totalHits(bq); err == nil {
+		t.Errorf("%v: expected too many clauses", bq)
+	}
+}
+
+/* Wraps a prefix query to cancel the search after n enumerated terms. */
+type cancellingQuery struct {
+	*search.AbstractMultiTermQuery
+	prefix     *search.PrefixQuery
+	cancel     context.CancelFunc
+	n          int
+	enumerated int
+}
+
+func newCancellingQuery(prefix *search.PrefixQuery, cancel context.CancelFunc, n int) *cancellingQuery {
+	ans := &cancellingQuery{prefix: prefix, cancel: cancel, n: n}
+	ans.AbstractMultiTermQuery = search.NewAbstractMultiTermQuery(ans, prefix.Field())
+	ans.SetRewriteMethod(search.SCORING_BOOLEAN_QUERY_REWRITE)
+	return ans
+}
+
+func (q *cancellingQuery) TermsEnum(terms model.Terms, atts *util.AttributeSource) (model.TermsEnum, error) {
+	tenum, err := q.prefix.TermsEnum(terms, atts)
+	return &cancellingTermsEnum{tenum, q}, err
+}
+
+func (q *cancellingQuery) ToString(field string) string {
+	return q.prefix.ToString(field)
+}
+
+type cancellingTermsEnum struct {
+	model.TermsEnum
+	owner *cancellingQuery
+}
+
+func (e *cancellingTermsEnum) Next() ([]byte, error) {
+	if e.owner.enumerated++; e.owner.enumerated == e.owner.n {
+		e.owner.cancel()
+	}
+	return e.TermsEnum.Next()
+}
@@ -0,0 +1,303 @@
+package search
+
+import (
+	"errors"
+	"sync"
+)
+
+// search/ReferenceManager.java
+
+/*
+Define service that a concrete ReferenceManager must provide to
+manage the reference counting of the instances it hands out.
+*/
+type ReferenceManagerSPI interface {
+	// Decrement reference counting on the given reference.
+	DecRef(ref interface{}) error
+	/*
+		Refresh the given reference if needed. Returns nil if no refresh
+		was needed, otherwise a new refreshed reference.
+	*/
+	RefreshIfNeeded(referenceToRefresh interface{}) (interface{}, error)
+	/*
+		Try to increment reference counting on the given reference.
+		Returns true if the operation was successful.
+	*/
+	TryIncRef(ref interface{}) bool
+	// Returns the current reference count of the given reference.
+	RefCount(ref interface{}) int
+}
+
+/*
+Use to receive notification when a refresh has finished. See
+ReferenceManager.AddListener().
+*/
+type RefreshListener interface {
+	/*
+		Called right before a refresh attempt starts.
+	*/
+	BeforeRefresh() error
+	/*
+		Called after the attempted refresh; if the refresh did open a new
+		reference then didRefresh will be true and Acquire() is
+		guaranteed to return the new reference.
+	*/
+	AfterRefresh(didRefresh bool) error
+}
+
+var ErrReferenceManagerClosed = errors.New("this ReferenceManager is closed")
+
+/*
+Utility class to safely share instances of a certain type across
+multiple goroutines, while periodically refreshing them. This class
+ensures each reference is closed only once all goroutines have
+finished using it. It is recommended to consult the documentation of
+ReferenceManager implementations for their MaybeRefresh() semantics.
+*/
+type ReferenceManager struct {
+	spi ReferenceManagerSPI
+
+	currentLock sync.RWMutex
+	current     interface{}
+
+	// a semaphore of size 1, so that MaybeRefresh() can give up
+	// without blocking when another refresh is in progress
+	refreshLock chan struct{}
+
+	listenersLock sync.Mutex
+	listeners     []RefreshListener
+}
+
+func newReferenceManager(spi ReferenceManagerSPI, current interface{}) *ReferenceManager {
+	return &ReferenceManager{
+		spi:         spi,
+		current:     current,
+		refreshLock: make(chan struct{}, 1),
+	}
+}
+
+func (m *ReferenceManager) ensureOpen() (interface{}, error) {
+	m.currentLock.RLock()
+	defer m.currentLock.RUnlock()
+	if m.current == nil {
+		return nil, ErrReferenceManagerClosed
+	}
+	return m.current, nil
+}
+
+func (m *ReferenceManager) swapReference(newReference interface{}) error {
+	m.currentLock.Lock()
+	if m.current == nil && newReference != nil {
+		m.currentLock.Unlock()
+		if err := m.release(newReference); err != nil {
+			return err
+		}
+		return ErrReferenceManagerClosed
+	}
+	oldReference := m.current
+	m.current = newReference
+	m.currentLock.Unlock()
+	if oldReference != nil {
+		return m.release(oldReference)
+	}
+	return nil
+}
+
+/*
+Obtain the current reference. You must match every call to Acquire()
+with one call to Release(); it's best to do so in a defer block. This
+must not be closed directly, only released.
+*/
+func (m *ReferenceManager) Acquire() (interface{}, error) {
+	for {
+		ref, err := m.ensureOpen()
+		if err != nil {
+			return nil, err
+		}
+		if m.spi.TryIncRef(ref) {
+			return ref, nil
+		}
+		if m.spi.RefCount(ref) == 0 && func() bool {
+			m.currentLock.RLock()
+			defer m.currentLock.RUnlock()
+			return m.current == ref
+		}() {
+			// this should never happen: the current reference must
+			// always hold at least one reference of its own
+			return nil, errors.New("the managed reference has already closed - this is likely a bug when the reference count is modified outside of the ReferenceManager")
+		}
+	}
+}
+
+/*
+Closes this ReferenceManager to prevent future acquiring. A reference
+manager should be closed if the reference to the managed resource
+should be disposed or the application using the ReferenceManager is
+shutting down. The managed resource might not be released immediately,
+if the ReferenceManager user is holding on to a previously acquired
+reference. The resource will be released once the when the last
+reference is released.
+
+NOTE: If the underlying reference implementation's DecRef() returns
+an error this call will return it.
+*/
+func (m *ReferenceManager) Close() error {
+	m.currentLock.RLock()
+	current := m.current
+	m.currentLock.RUnlock()
+	if current != nil {
+		// make sure we can call this more than once
+		// closeable javadoc says:
+		//   if this is already closed then invoking this method has no effect.
+		return m.swapReference(nil)
+	}
+	return nil
+}
+
+func (m *ReferenceManager) doMaybeRefresh() (err error) {
+	// it's ok to call lock() here (blocking) because we're supposed
+	// to get here from either MaybeRefresh() or MaybeRefreshBlocking(),
+	// after the lock has already been obtained. Doing that protects us
+	// from an accidental bug where this method will be called outside
+	// the scope of a lock (and possibly by several goroutines).
+	reference, err := m.Acquire()
+	if err != nil {
+		return err
+	}
+	refreshed := false
+	defer func() {
+		if err2 := m.Release(reference); err == nil {
+			err = err2
+		}
+		if err2 := m.notifyRefreshListenersRefreshed(refreshed); err == nil {
+			err = err2
+		}
+	}()
+
+	if err = m.notifyRefreshListenersBefore(); err != nil {
+		return err
+	}
+	newReference, err := m.spi.RefreshIfNeeded(reference)
+	if err != nil {
+		return err
+	}
+	if newReference != nil {
+		assert2(newReference != reference, "refreshIfNeeded should return nil if refresh wasn't needed")
+		if err = m.swapReference(newReference); err != nil {
+			return err
+		}
+		refreshed = true
+	}
+	return nil
+}
+
+/*
+You must call this (or MaybeRefreshBlocking()), periodically, if you
+want that Acquire() will return refreshed instances.
+
+Goroutines: it's fine for more than one goroutine to call this at
+once. Only the first goroutine will attempt the refresh; subsequent
+goroutines will see that another goroutine is already handling
+refresh and will return immediately. Note that this means if another
+goroutine is already refreshing then subsequent goroutines will
+return right away without waiting for the refresh to complete.
+
+If this method returns true it means the calling goroutine either
+refreshed or that there were no changes to refresh. If it returns
+false it means another goroutine is currently refreshing.
+*/
+func (m *ReferenceManager) MaybeRefresh() (bool, error) {
+	if _, err := m.ensureOpen(); err != nil {
+		return false, err
+	}
+
+	// Ensure only 1 goroutine does refresh at once; other goroutines
+	// just return immediately:
+	select {
+	case m.refreshLock <- struct{}{}:
+		defer func() { <-m.refreshLock }()
+		return true, m.doMaybeRefresh()
+	default:
+		return false, nil
+	}
+}
+
+/*
+You must call this (or MaybeRefresh()), periodically, if you want
+that Acquire() will return refreshed instances.
+
+Goroutines: unlike MaybeRefresh(), if another goroutine is currently
+refreshing, this method blocks until that goroutine completes. It is
+useful if you want to guarantee that the next call to Acquire() will
+return a refreshed instance. Otherwise, consider using the
+non-blocking MaybeRefresh().
+*/
+func (m *ReferenceManager) MaybeRefreshBlocking() error {
+	if _, err := m.ensureOpen(); err != nil {
+		return err
+	}
+
+	// Ensure only 1 goroutine does refresh at once
+	m.refreshLock <- struct{}{}
+	defer func() { <-m.refreshLock }()
+	return m.doMaybeRefresh()
+}
+
+/*
+Release the reference previously obtained via Acquire().
+
+NOTE: it's safe to call this after Close().
+*/
+func (m *ReferenceManager) Release(reference interface{}) error {
+	return m.release(reference)
+}
+
+func (m *ReferenceManager) release(reference interface{}) error {
+	assert(reference != nil)
+	return m.spi.DecRef(reference)
+}
+
+func (m *ReferenceManager) notifyRefreshListenersBefore() error {
+	m.listenersLock.Lock()
+	listeners := append([]RefreshListener(nil), m.listeners...)
+	m.listenersLock.Unlock()
+	for _, l := range listeners {
+		if err := l.BeforeRefresh(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ReferenceManager) notifyRefreshListenersRefreshed(didRefresh bool) error {
+	m.listenersLock.Lock()
+	listeners := append([]RefreshListener(nil), m.listeners...)
+	m.listenersLock.Unlock()
+	for _, l := range listeners {
+		if err := l.AfterRefresh(didRefresh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* Adds a listener, to be notified when a reference is refreshed/swapped. */
+func (m *ReferenceManager) AddListener(listener RefreshListener) {
+	assert2(listener != nil, "Listener cannot be nil")
+	m.listenersLock.Lock()
+	defer m.listenersLock.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+/* Remove a listener added with AddListener(). */
+func (m *ReferenceManager) RemoveListener(listener RefreshListener) {
+	assert2(listener != nil, "Listener cannot be nil")
+	m.listenersLock.Lock()
+	defer m.listenersLock.Unlock()
+	for i, l := range m.listeners {
+		if l == listener {
+			m.listeners = append(m.listeners[:i], m.listeners[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package search
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"math"
+	"sort"
+)
+
+// search/Rescorer.java
+
+/*
+Re-scores the topN results (TopDocs) from an original query. See
+QueryRescorer for an actual implementation. Typically, you run a
+low-cost first-pass query across the entire index, collecting the top
+few hundred hits perhaps, and then use this class to mix in a more
+costly second pass scoring.
+
+The first pass hits are never re-ordered in place; a new TopDocs is
+returned, sorted by the new score and truncated to topN.
+*/
+type Rescorer interface {
+	// Rescore the hits in firstPassTopDocs, returning a new TopDocs
+	// with the topN hits sorted by the new score.
+	Rescore(searcher *IndexSearcher, firstPassTopDocs TopDocs, topN int) (TopDocs, error)
+	// Explains how the score for the specified document was computed.
+	Explain(searcher *IndexSearcher, firstPassExplanation Explanation, docID int) (Explanation, error)
+}
+
+/* Creates a new TopDocs with the given hits. */
+func NewTopDocs(totalHits int, scoreDocs []*ScoreDoc, maxScore float32) TopDocs {
+	return TopDocs{totalHits, scoreDocs, float64(maxScore)}
+}
+
+/* Returns the maximum score value encountered, or NaN if unknown. */
+func (td TopDocs) MaxScore() float32 {
+	return float32(td.maxScore)
+}
+
+func NewScoreDoc(doc int, score float32) *ScoreDoc {
+	return newScoreDoc(doc, score)
+}
+
+// Sorts hits by increasing docID, so that they can be visited leaf by leaf.
+type ScoreDocsByDoc []*ScoreDoc
+
+func (s ScoreDocsByDoc) Len() int           { return len(s) }
+func (s ScoreDocsByDoc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s ScoreDocsByDoc) Less(i, j int) bool { return s[i].Doc < s[j].Doc }
+
+// Sorts hits by decreasing score, breaking ties by increasing docID.
+type ScoreDocsByScore []*ScoreDoc
+
+func (s ScoreDocsByScore) Len() int      { return len(s) }
+func (s ScoreDocsByScore) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ScoreDocsByScore) Less(i, j int) bool {
+	if s[i].Score != s[j].Score {
+		return s[i].Score > s[j].Score
+	}
+	return s[i].Doc < s[j].Doc
+}
+
+/*
+Sorts the given hits by decreasing score and keeps only the first
+topN of them. It is shared by all Rescorer implementations.
+*/
+func TopRescoredDocs(totalHits int, hits []*ScoreDoc, topN int) TopDocs {
+	sort.Sort(ScoreDocsByScore(hits))
+	if topN < len(hits) {
+		hits = hits[:topN]
+	}
+	maxScore := float32(math.NaN())
+	if len(hits) > 0 {
+		maxScore = hits[0].Score
+	}
+	return NewTopDocs(totalHits, hits, maxScore)
+}
+
+// search/QueryRescorer.java
+
+type QueryRescorerSPI interface {
+	/*
+		Implement this in a subclass to combine the first pass and second
+		pass scores. If secondPassMatches is false then the second pass
+		query failed to match a hit from the first pass query, and you
+		should ignore the secondPassScore.
+	*/
+	Combine(firstPassScore float32, secondPassMatches bool, secondPassScore float32) float32
+}
+
+/*
+A Rescorer that uses a provided Query to assign scores to the
+first-pass hits.
+*/
+type QueryRescorer struct {
+	spi   QueryRescorerSPI
+	query Query
+}
+
+/* Sole constructor, passing the 2nd pass query to assign scores to the 1st pass hits. */
+func NewQueryRescorer(spi QueryRescorerSPI, query Query) *QueryRescorer {
+	return &QueryRescorer{spi, query}
+}
+
+/*
+Creates a QueryRescorer which adds weight times the second pass score
+to the first pass score, for hits matched by the second pass query.
+*/
+func NewLinearQueryRescorer(query Query, weight float32) *QueryRescorer {
+	return NewQueryRescorer(linearCombiner(weight), query)
+}
+
+type linearCombiner float32
+
+func (weight linearCombiner) Combine(firstPassScore float32, secondPassMatches bool, secondPassScore float32) float32 {
+	score := firstPassScore
+	if secondPassMatches {
+		score += float32(weight) * secondPassScore
+	}
+	return score
+}
+
+func (r *QueryRescorer) Rescore(searcher *IndexSearcher, firstPassTopDocs TopDocs, topN int) (TopDocs, error) {
+	hits := make([]*ScoreDoc, len(firstPassTopDocs.ScoreDocs))
+	for i, hit := range firstPassTopDocs.ScoreDocs {
+		hits[i] = newShardedScoreDoc(hit.Doc, hit.Score, hit.ShardIndex)
+	}
+	sort.Sort(ScoreDocsByDoc(hits))
+
+	leaves := searcher.TopReaderContext().Leaves()
+	weight, err := searcher.CreateNormalizedWeight(r.query)
+	if err != nil {
+		return TopDocs{}, err
+	}
+
+	// Now merge sort docIDs from hits, with reader's leaves:
+	var scorer Scorer
+	endDoc, docBase := 0, 0
+	readerUpto := -1
+	for _, hit := range hits {
+		docID := hit.Doc
+		var readerContext *index.AtomicReaderContext
+		for docID >= endDoc {
+			readerUpto++
+			readerContext = leaves[readerUpto]
+			endDoc = readerContext.DocBase + readerContext.Reader().MaxDoc()
+		}
+
+		if readerContext != nil {
+			// We advanced to another segment:
+			docBase = readerContext.DocBase
+			if scorer, err = weight.Scorer(readerContext, nil); err != nil {
+				return TopDocs{}, err
+			}
+		}
+
+		if scorer == nil {
+			// Query did not match any doc of this segment:
+			hit.Score = r.spi.Combine(hit.Score, false, 0)
+			continue
+		}
+
+		targetDoc := docID - docBase
+		actualDoc := scorer.DocId()
+		if actualDoc < targetDoc {
+			if actualDoc, err = scorer.Advance(targetDoc); err != nil {
+				return TopDocs{}, err
+			}
+		}
+
+		if actualDoc == targetDoc {
+			// Query did match this doc:
+			score, err := scorer.Score()
+			if err != nil {
+				return TopDocs{}, err
+			}
+			hit.Score = r.spi.Combine(hit.Score, true, score)
+		} else {
+			// Query did not match this doc:
+			assert(actualDoc > targetDoc)
+			hit.Score = r.spi.Combine(hit.Score, false, 0)
+		}
+	}
+
+	return TopRescoredDocs(firstPassTopDocs.TotalHits, hits, topN), nil
+}
+
+func (r *QueryRescorer) Explain(searcher *IndexSearcher, firstPassExplanation Explanation, docID int) (Explanation, error) {
+	secondPassExplanation, err := searcher.Explain(r.query, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	var secondPassScore float32
+	if secondPassExplanation.IsMatch() {
+		secondPassScore = secondPassExplanation.Value()
+	}
+
+	var score float32
+	if secondPassExplanation.IsMatch() {
+		score = r.spi.Combine(firstPassExplanation.Value(), true, secondPassScore)
+	} else {
+		score = r.spi.Combine(firstPassExplanation.Value(), false, 0)
+	}
+
+	result := NewExplanation(score, fmt.Sprintf("combined first and second pass score using %T", r.spi))
+
+	first := NewExplanation(firstPassExplanation.Value(), "first pass score")
+	first.AddDetail(firstPassExplanation)
+	result.AddDetail(first)
+
+	var second *ExplanationImpl
+	if secondPassExplanation.IsMatch() {
+		second = NewExplanation(secondPassScore, "second pass score")
+		second.AddDetail(secondPassExplanation)
+	} else {
+		second = NewExplanation(0, "no second pass score")
+	}
+	result.AddDetail(second)
+
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package search_test
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"math"
+	"testing"
+)
+
+/* Two segments: "c" only occurs in the second one. */
+func newRescoreSearcher(t *testing.T) *search.IndexSearcher {
+	dir := testindex.NewDirectory(t)
+	w := testindex.NewWriter(t, dir, nil)
+	for i, doc := range testindex.TextDocs("body", "a b", "a", "a c c", "b c") {
+		if err := w.AddDocument(doc); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			if err := w.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return search.NewIndexSearcher(testindex.OpenReader(t, dir))
+}
+
+func TestQueryRescorer(t *testing.T) {
+	searcher := newRescoreSearcher(t)
+	if n := len(searcher.IndexReader().Leaves()); n != 2 {
+		t.Fatalf("expected 2 segments, but %v", n)
+	}
+	first := search.NewTermQuery(index.NewTerm("body", "a"))
+	firstPass, err := searcher.SearchTop(first, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstScores := make(map[int]float32)
+	for _, hit := range firstPass.ScoreDocs {
+		firstScores[hit.Doc] = hit.Score
+		hit.ShardIndex = hit.Doc + 10
+	}
+
+	rescorer := search.NewLinearQueryRescorer(search.NewTermQuery(index.NewTerm("body", "c")), 2)
+	rescored, err := rescorer.Rescore(searcher, firstPass, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rescored.TotalHits != 3 || len(rescored.ScoreDocs) != 2 {
+		t.Fatalf("expected top 2 of 3 hits, but %v", rescored.ScoreDocs)
+	}
+	if hit := rescored.ScoreDocs[0]; hit.Doc != 2 || hit.Score <= firstScores[2] {
+		t.Errorf("expected doc 2 boosted first, but %v", rescored.ScoreDocs)
+	}
+	for _, hit := range rescored.ScoreDocs {
+		if hit.ShardIndex != hit.Doc+10 {
+			t.Errorf("lost the shardIndex of %v", hit)
+		}
+		firstExp, err := searcher.Explain(first, hit.Doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp, err := rescorer.Explain(searcher, firstExp, hit.Doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(float64(exp.Value()-hit.Score)) > 1e-6 {
+			t.Errorf("explanation %v doesn't match score of %v", exp.Value(), hit)
+		}
+	}
+
+	// a second pass query matching nothing keeps the first pass scores
+	missing := search.NewLinearQueryRescorer(search.NewTermQuery(index.NewTerm("body", "zzz")), 2)
+	if rescored, err = missing.Rescore(searcher, firstPass, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(rescored.ScoreDocs) != 3 {
+		t.Fatalf("expected 3 hits, but %v", rescored.ScoreDocs)
+	}
+	for _, hit := range rescored.ScoreDocs {
+		if hit.Score != firstScores[hit.Doc] {
+			t.Errorf("expected first pass score %v, but %v", firstScores[hit.Doc], hit)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package search
+
+// search/ScoreCachingWrappingScorer.java
+
+/*
+A Scorer which wraps another scorer and caches the score of the
+current document. Successive calls to Score() will return the same
+result and will not invoke the wrapped Scorer's Score() method,
+unless the current document has changed.
+
+This class might be useful due to the changes done to the Collector
+interface, in which the score is not computed for a document by
+default, only if the collector requests it. Some collectors may need
+to use the score in several places, however all they have in hand is
+a Scorer object, and might end up computing the score of a document
+more than once.
+*/
+type ScoreCachingWrappingScorer struct {
+	*FilterScorer
+	curDoc   int
+	curScore float32
+}
+
+/* Creates a new instance by wrapping the given scorer. */
+func NewScoreCachingWrappingScorer(scorer Scorer) *ScoreCachingWrappingScorer {
+	return &ScoreCachingWrappingScorer{
+		FilterScorer: newFilterScorer(scorer),
+		curDoc:       -1,
+	}
+}
+
+func (s *ScoreCachingWrappingScorer) Score() (float32, error) {
+	if doc := s.in.DocId(); doc != s.curDoc {
+		score, err := s.in.Score()
+		if err != nil {
+			return 0, err
+		}
+		s.curScore, s.curDoc = score, doc
+	}
+	return s.curScore, nil
+}
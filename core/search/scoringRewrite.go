@@ -1,14 +1,222 @@
 package search
 
+import (
+	"context"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/ScoringRewrite.java
+
+/*
+Base rewrite method that translates each term into a query, and keeps
+the scores as computed by the query.
+*/
 type ScoringRewrite interface {
 	TermCollectingRewrite
+	ContextRewriteMethod
 	CheckMaxClauseCount(count int) error
 }
 
 type ScoringRewriteSPI interface {
 	TopLevelQuery() Query
+	AddClauseWithContext(topLevel Query, term *index.Term, docCount int, boost float32, states *index.TermContext)
+	CheckMaxClauseCount(count int) error
 }
 
 type AbstractScoringRewrite struct {
+	*AbstractTermCollectingRewrite
+	*RewriteMethodImpl
 	spi ScoringRewriteSPI
 }
+
+func newAbstractScoringRewrite(spi ScoringRewriteSPI) *AbstractScoringRewrite {
+	ans := &AbstractScoringRewrite{spi: spi}
+	ans.RewriteMethodImpl = newRewriteMethodImpl(nil)
+	ans.AbstractTermCollectingRewrite = newAbstractTermCollectingRewrite(ans)
+	return ans
+}
+
+func (r *AbstractScoringRewrite) TopLevelQuery() Query {
+	return r.spi.TopLevelQuery()
+}
+
+func (r *AbstractScoringRewrite) AddClauseWithContext(topLevel Query, term *index.Term,
+	docCount int, boost float32, states *index.TermContext) {
+
+	r.spi.AddClauseWithContext(topLevel, term, docCount, boost, states)
+}
+
+func (r *AbstractScoringRewrite) CheckMaxClauseCount(count int) error {
+	return r.spi.CheckMaxClauseCount(count)
+}
+
+/*
+Rewrite() has no way to return errors, e.g. too many clauses: the
+query it returns then fails with the error when weighted, so that
+searching returns it. IndexSearcher rewrites with RewriteContext(),
+which returns it directly.
+*/
+func (r *AbstractScoringRewrite) Rewrite(reader index.IndexReader, query MultiTermQuery) Query {
+	ans, err := r.RewriteContext(context.Background(), reader, query)
+	if err != nil {
+		return newFailedRewriteQuery(query, err)
+	}
+	return ans
+}
+
+func (r *AbstractScoringRewrite) RewriteContext(ctx context.Context,
+	reader index.IndexReader, query MultiTermQuery) (Query, error) {
+
+	result := r.spi.TopLevelQuery()
+	col := newParallelArraysTermCollector(r)
+	if err := r.CollectTermsContext(ctx, reader, query, col); err != nil {
+		return nil, err
+	}
+	if col.err != nil {
+		return nil, col.err
+	}
+	if size := col.terms.Size(); size > 0 {
+		sort := col.terms.Sort(util.UTF8SortedAsUnicodeLess)
+		for _, pos := range sort[:size] {
+			term := index.NewTermFromBytes(query.Field(), col.terms.Get(pos, util.NewEmptyBytesRef()).ToBytes())
+			r.spi.AddClauseWithContext(result, term, col.termStates[pos].DocFreq,
+				query.Boost(), col.termStates[pos])
+		}
+	}
+	return result, nil
+}
+
+/* Stands for a query whose rewrite failed: weighting it returns the error. */
+type failedRewriteQuery struct {
+	*AbstractQuery
+	query Query
+	err   error
+}
+
+func newFailedRewriteQuery(query Query, err error) *failedRewriteQuery {
+	ans := &failedRewriteQuery{query: query, err: err}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+func (q *failedRewriteQuery) CreateWeight(ss *IndexSearcher) (Weight, error) {
+	return nil, q.err
+}
+
+func (q *failedRewriteQuery) Clone() Query {
+	ans := newFailedRewriteQuery(q.query, q.err)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *failedRewriteQuery) ToString(field string) string {
+	return q.query.ToString(field)
+}
+
+/*
+Collects the terms, with their TermState in each segment, so that
+the term queries need not look them up again.
+*/
+type parallelArraysTermCollector struct {
+	*AbstractTermCollector
+	owner      *AbstractScoringRewrite
+	terms      *util.BytesRefHash
+	termStates []*index.TermContext
+	termsEnum  TermsEnum
+	err        error
+}
+
+func newParallelArraysTermCollector(owner *AbstractScoringRewrite) *parallelArraysTermCollector {
+	ans := &parallelArraysTermCollector{
+		owner: owner,
+		terms: util.NewDefaultBytesRefHash(),
+	}
+	ans.AbstractTermCollector = newAbstractTermCollector(ans)
+	return ans
+}
+
+func (c *parallelArraysTermCollector) SetNextEnum(termsEnum TermsEnum) {
+	c.termsEnum = termsEnum
+}
+
+func (c *parallelArraysTermCollector) Collect(bytes *util.BytesRef) bool {
+	if c.err = c.collect(bytes); c.err != nil {
+		return false
+	}
+	return true
+}
+
+func (c *parallelArraysTermCollector) collect(bytes *util.BytesRef) error {
+	e, err := c.terms.Add(bytes.ToBytes())
+	if err != nil {
+		return err
+	}
+	state, err := c.termsEnum.TermState()
+	if err != nil {
+		return err
+	}
+	assert2(state != nil, "no TermState for %v", bytes)
+	docFreq, err := c.termsEnum.DocFreq()
+	if err != nil {
+		return err
+	}
+	totalTermFreq, err := c.termsEnum.TotalTermFreq()
+	if err != nil {
+		return err
+	}
+	if e < 0 {
+		// duplicate term: update docFreq
+		c.termStates[-e-1].Register(state, c.readerContext.Ord, docFreq, totalTermFreq)
+		return nil
+	}
+	// new entry: we populate the entry initially
+	assert(e == len(c.termStates))
+	termStates := index.NewTermContext(c.topReaderContext)
+	termStates.Register(state, c.readerContext.Ord, docFreq, totalTermFreq)
+	c.termStates = append(c.termStates, termStates)
+	return c.owner.CheckMaxClauseCount(c.terms.Size())
+}
+
+/*
+A rewrite method that first translates each term into a SHOULD
+clause in a BooleanQuery, and keeps the scores as computed by the
+query. Note that typically such scores are meaningless to the user,
+and require non-trivial CPU to compute, so it's almost always better
+to use CONSTANT_SCORE_FILTER_REWRITE instead.
+
+Returns an error if the number of terms exceeds the maximum number of
+clauses of a BooleanQuery.
+*/
+var SCORING_BOOLEAN_QUERY_REWRITE = ScoringRewrite(newScoringBooleanQueryRewrite())
+
+type scoringBooleanQueryRewrite struct {
+	*AbstractScoringRewrite
+}
+
+func newScoringBooleanQueryRewrite() *scoringBooleanQueryRewrite {
+	ans := new(scoringBooleanQueryRewrite)
+	ans.AbstractScoringRewrite = newAbstractScoringRewrite(ans)
+	return ans
+}
+
+func (r *scoringBooleanQueryRewrite) TopLevelQuery() Query {
+	return NewBooleanQueryDisableCoord(true)
+}
+
+func (r *scoringBooleanQueryRewrite) AddClauseWithContext(topLevel Query, term *index.Term,
+	docCount int, boost float32, states *index.TermContext) {
+
+	tq := NewTermQueryWithStates(term, states)
+	tq.SetBoost(boost)
+	topLevel.(*BooleanQuery).Add(tq, SHOULD)
+}
+
+func (r *scoringBooleanQueryRewrite) CheckMaxClauseCount(count int) error {
+	if count > maxClauseCount {
+		return fmt.Errorf("maxClauseCount is set to %v", maxClauseCount)
+	}
+	return nil
+}
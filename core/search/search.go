@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"fmt"
 	"github.com/jtejido/golucene/core/index"
 	"log"
@@ -42,6 +43,16 @@ func (ss *IndexSearcher) SetSimilarity(similarity Similarity) {
 	ss.similarity = similarity
 }
 
+/* Expert: returns the Similarity implementation used by this IndexSearcher. */
+func (ss *IndexSearcher) Similarity() Similarity {
+	return ss.similarity
+}
+
+/* Returns the IndexReader this searches. */
+func (ss *IndexSearcher) IndexReader() index.IndexReader {
+	return ss.reader
+}
+
 func (ss *IndexSearcher) SearchTop(q Query, n int) (topDocs TopDocs, err error) {
 	return ss.Search(q, nil, n)
 }
@@ -51,7 +62,23 @@ func (ss *IndexSearcher) Search(q Query, f Filter, n int) (topDocs TopDocs, err
 	if err != nil {
 		return TopDocs{}, err
 	}
-	return ss.searchWSI(w, nil, n), nil
+	return ss.searchWSI(w, nil, n)
+}
+
+/*
+Lower-level search API.
+
+Collector.Collect() is called for every matching document. If a
+non-nil filter is given, only documents accepted by it are
+collected. An error returned by the collector aborts the search and
+is returned.
+*/
+func (ss *IndexSearcher) SearchCollector(q Query, f Filter, c Collector) error {
+	w, err := ss.spi.CreateNormalizedWeight(ss.spi.WrapFilter(q, f))
+	if err != nil {
+		return err
+	}
+	return ss.spi.SearchLWC(ss.leafContexts, w, c)
 }
 
 /** Expert: Low-level search implementation.  Finds the top <code>n</code>
@@ -62,7 +89,7 @@ func (ss *IndexSearcher) Search(q Query, f Filter, n int) (topDocs TopDocs, err
  * @throws BooleanQuery.TooManyClauses If a query would exceed
  *         {@link BooleanQuery#getMaxClauseCount()} clauses.
  */
-func (ss *IndexSearcher) searchWSI(w Weight, after *ScoreDoc, nDocs int) TopDocs {
+func (ss *IndexSearcher) searchWSI(w Weight, after *ScoreDoc, nDocs int) (TopDocs, error) {
 	// TODO support concurrent search
 	return ss.searchLWSI(ss.leafContexts, w, after, nDocs)
 }
@@ -76,7 +103,7 @@ func (ss *IndexSearcher) searchWSI(w Weight, after *ScoreDoc, nDocs int) TopDocs
  *         {@link BooleanQuery#getMaxClauseCount()} clauses.
  */
 func (ss *IndexSearcher) searchLWSI(leaves []*index.AtomicReaderContext,
-	w Weight, after *ScoreDoc, nDocs int) TopDocs {
+	w Weight, after *ScoreDoc, nDocs int) (TopDocs, error) {
 	// single thread
 	limit := ss.reader.MaxDoc()
 	if limit == 0 {
@@ -86,8 +113,10 @@ func (ss *IndexSearcher) searchLWSI(leaves []*index.AtomicReaderContext,
 		nDocs = limit
 	}
 	collector := NewTopScoreDocCollector(nDocs, after, !w.IsScoresDocsOutOfOrder())
-	ss.spi.SearchLWC(leaves, w, collector)
-	return collector.TopDocs()
+	if err := ss.spi.SearchLWC(leaves, w, collector); err != nil {
+		return TopDocs{}, err
+	}
+	return collector.TopDocs(), nil
 }
 
 func (ss *IndexSearcher) SearchLWC(leaves []*index.AtomicReaderContext, w Weight, c Collector) (err error) {
@@ -104,10 +133,13 @@ func (ss *IndexSearcher) SearchLWC(leaves []*index.AtomicReaderContext, w Weight
 			return err
 		}
 		if scorer != nil {
-			err = scorer.ScoreAndCollect(c)
-		} // TODO catch CollectionTerminatedException
+			// TODO catch CollectionTerminatedException
+			if err = scorer.ScoreAndCollect(c); err != nil {
+				return err
+			}
+		}
 	}
-	return
+	return nil
 }
 
 func (ss *IndexSearcher) WrapFilter(q Query, f Filter) Query {
@@ -170,12 +202,8 @@ func (ss *IndexSearcher) CreateNormalizedWeight(q Query) (w Weight, err error) {
 
 func (ss *IndexSearcher) Rewrite(q Query) (Query, error) {
 	log.Printf("Rewriting '%v'...", q)
-	after := q.Rewrite(ss.reader)
-	for after != q {
-		q = after
-		after = q.Rewrite(ss.reader)
-	}
-	return q, nil
+	// queries implementing ContextRewriter return their errors
+	return ss.RewriteContext(context.Background(), q)
 }
 
 // Returns this searhcers the top-level IndexReaderContext
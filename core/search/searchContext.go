@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/search/model"
+	"math"
+)
+
+/*
+Context-aware variants of the IndexSearcher API. Cancellation is
+checked while rewriting, between segments, every
+CANCELLATION_CHECK_INTERVAL docs inside a segment, every
+CANCELLATION_CHECK_TERM_INTERVAL terms a MultiTermQuery filter marks
+the documents of, and before each collected hit. A cancelled search
+returns ctx.Err() and no partial results.
+*/
+
+/* Number of doc ids scored between two cancellation checks. */
+const CANCELLATION_CHECK_INTERVAL = 4096
+
+/*
+Number of terms a MultiTermQueryWrapperFilter enumerates between two
+cancellation checks.
+*/
+const CANCELLATION_CHECK_TERM_INTERVAL = 64
+
+/*
+Implemented by queries, e.g. MultiTermQuery, whose rewrite can take
+long enough to be worth cancelling.
+*/
+type ContextRewriter interface {
+	RewriteContext(ctx context.Context, reader index.IndexReader) (Query, error)
+}
+
+/* Like Search(), but aborts with ctx.Err() once ctx is done. */
+func (ss *IndexSearcher) SearchContext(ctx context.Context, q Query, f Filter, n int) (TopDocs, error) {
+	w, err := ss.createNormalizedWeightContext(ctx, ss.spi.WrapFilter(q, f))
+	if err != nil {
+		return TopDocs{}, err
+	}
+	limit := ss.reader.MaxDoc()
+	if limit == 0 {
+		limit = 1
+	}
+	if n > limit {
+		n = limit
+	}
+	collector := NewTopScoreDocCollector(n, nil, !w.IsScoresDocsOutOfOrder())
+	if err = ss.SearchLWCContext(ctx, ss.leafContexts, w, collector); err != nil {
+		return TopDocs{}, err
+	}
+	return collector.TopDocs(), nil
+}
+
+/* Like SearchCollector(), but aborts with ctx.Err() once ctx is done. */
+func (ss *IndexSearcher) SearchCollectorContext(ctx context.Context, q Query, f Filter, c Collector) error {
+	w, err := ss.createNormalizedWeightContext(ctx, ss.spi.WrapFilter(q, f))
+	if err != nil {
+		return err
+	}
+	return ss.SearchLWCContext(ctx, ss.leafContexts, w, c)
+}
+
+/*
+Like SearchLWC(), but checks ctx before each segment and while
+scoring it.
+*/
+func (ss *IndexSearcher) SearchLWCContext(ctx context.Context,
+	leaves []*index.AtomicReaderContext, w Weight, c Collector) error {
+
+	c = NewCancellableCollector(ctx, c)
+	for _, leaf := range leaves { // search each subreader
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.SetNextReader(leaf)
+
+		scorer, err := w.BulkScorer(leaf, !c.AcceptsDocsOutOfOrder(),
+			leaf.Reader().(index.AtomicReader).LiveDocs())
+		if err != nil {
+			return err
+		}
+		if scorer != nil {
+			if err = scoreContext(ctx, scorer, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Scores a segment in windows of CANCELLATION_CHECK_INTERVAL doc ids so
+that ctx is also checked across long runs of non-matching docs.
+Only DefaultBulkScorer supports arbitrary windows; other bulk scorers
+score the whole segment and rely on the cancellable collector.
+*/
+func scoreContext(ctx context.Context, scorer BulkScorer, c Collector) error {
+	if _, ok := scorer.(*DefaultBulkScorer); !ok {
+		return scorer.ScoreAndCollect(c)
+	}
+	for max := CANCELLATION_CHECK_INTERVAL; ; max += CANCELLATION_CHECK_INTERVAL {
+		if max > NO_MORE_DOCS-CANCELLATION_CHECK_INTERVAL {
+			// stay below NO_MORE_DOCS, which would restart scoring from
+			// the next doc instead of the current one
+			max = NO_MORE_DOCS - 1
+		}
+		more, err := scorer.ScoreAndCollectUpto(c, max)
+		if err != nil || !more {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+/*
+Like Rewrite(), but checks ctx between rewrite rounds and lets
+queries implementing ContextRewriter check it while rewriting.
+*/
+func (ss *IndexSearcher) RewriteContext(ctx context.Context, q Query) (Query, error) {
+	rewrite := func(q Query) (Query, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if cr, ok := q.(ContextRewriter); ok {
+			return cr.RewriteContext(ctx, ss.reader)
+		}
+		return q.Rewrite(ss.reader), nil
+	}
+	after, err := rewrite(q)
+	for err == nil && after != q {
+		q = after
+		after, err = rewrite(q)
+	}
+	return after, err
+}
+
+func (ss *IndexSearcher) createNormalizedWeightContext(ctx context.Context, q Query) (Weight, error) {
+	q, err := ss.RewriteContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	w, err := q.CreateWeight(ss)
+	if err != nil {
+		return nil, err
+	}
+	v := w.ValueForNormalization()
+	norm := ss.similarity.QueryNorm(v)
+	if math.IsInf(float64(norm), 1) || math.IsNaN(float64(norm)) {
+		norm = 1.0
+	}
+	w.Normalize(norm, 1.0)
+	return w, nil
+}
@@ -0,0 +1,83 @@
+package search_test
+
+import (
+	"context"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestSearchAndCommitContext(t *testing.T) {
+	directory := testindex.NewDirectory(t)
+	writer := testindex.NewWriter(t, directory, nil)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	newDoc := func(text string) []model.IndexableField {
+		d := docu.NewDocument()
+		d.Add(docu.NewTextFieldFromString("body", text, docu.STORE_NO))
+		return d.Fields()
+	}
+
+	if err := writer.AddDocumentContext(cancelled, newDoc("a b")); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+	for _, text := range []string{"a b", "b", "a"} {
+		if err := writer.AddDocumentContext(context.Background(), newDoc(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.CommitContext(cancelled); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+	if _, err := index.OpenDirectoryReader(directory); err == nil {
+		t.Error("expected no commit after cancellation")
+	}
+	// done once the commit is prepared: it is rolled back
+	if err := writer.CommitContext(&countdownContext{context.Background(), 2}); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+	if _, err := index.OpenDirectoryReader(directory); err == nil {
+		t.Error("expected no commit after cancellation")
+	}
+	if err := writer.CommitContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := testindex.OpenReader(t, directory)
+	if n := reader.NumDocs(); n != 3 {
+		t.Errorf("expected 3 docs, but %v", n)
+	}
+	searcher := search.NewIndexSearcher(reader)
+	q := search.NewTermQuery(index.NewTerm("body", "a"))
+
+	if _, err := searcher.SearchContext(cancelled, q, nil, 10); err != context.Canceled {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+	docs, err := searcher.SearchContext(context.Background(), q, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs.TotalHits != 2 {
+		t.Errorf("expected 2 hits, but %v", docs.TotalHits)
+	}
+}
+
+/* A context that is done after n calls to Err(). */
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
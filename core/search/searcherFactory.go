@@ -0,0 +1,33 @@
+package search
+
+import (
+	"github.com/jtejido/golucene/core/index"
+)
+
+// search/SearcherFactory.java
+
+/*
+Factory class used by SearcherManager to create new IndexSearchers.
+The default implementation just creates an IndexSearcher with no
+custom behavior, but implementations can set a custom Similarity or
+warm the searcher before it is made visible, e.g. by running
+representative queries against it.
+
+NOTE: the returned searcher must wrap exactly the provided reader;
+SearcherManager verifies this.
+*/
+type SearcherFactory interface {
+	// Returns a new IndexSearcher over the given reader.
+	NewSearcher(reader index.IndexReader) (*IndexSearcher, error)
+}
+
+type defaultSearcherFactory struct{}
+
+/* Returns a SearcherFactory that creates plain IndexSearchers. */
+func NewSearcherFactory() SearcherFactory {
+	return defaultSearcherFactory{}
+}
+
+func (f defaultSearcherFactory) NewSearcher(reader index.IndexReader) (*IndexSearcher, error) {
+	return NewIndexSearcher(reader), nil
+}
@@ -0,0 +1,166 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/store"
+)
+
+// search/SearcherManager.java
+
+/*
+Utility class to safely share IndexSearcher instances across multiple
+goroutines, while periodically reopening. This class ensures each
+searcher is closed only once all goroutines have finished using it.
+
+Use Acquire() to obtain the current searcher, and Release() to
+release it, like this:
+
+	s, err := manager.Acquire()
+	if err != nil {
+		return err
+	}
+	defer manager.Release(s)
+	// Do searching, doc retrieval, etc. with s
+
+In addition you should periodically call MaybeRefresh(). While it's
+possible to call this just before running each query, this is
+discouraged since it penalizes the unlucky queries that do the
+reopen. It's better to use a separate background goroutine, that
+periodically calls MaybeRefresh(). Finally, be sure to call Close()
+once you are done.
+*/
+type SearcherManager struct {
+	*ReferenceManager
+	factory SearcherFactory
+}
+
+/*
+Creates and returns a new SearcherManager from the given Directory.
+If factory is nil, NewSearcherFactory() is used.
+*/
+func NewSearcherManager(dir store.Directory, factory SearcherFactory) (*SearcherManager, error) {
+	reader, err := index.OpenDirectoryReader(dir)
+	if err != nil {
+		return nil, err
+	}
+	return newSearcherManager(reader, factory)
+}
+
+/*
+Creates and returns a new SearcherManager from the given IndexWriter.
+Searchers are opened on near real time readers of the writer, which
+see the changes indexed so far without committing them.
+
+If applyAllDeletes is true, all buffered deletes are applied (made
+visible) in the searchers. If factory is nil, NewSearcherFactory()
+is used.
+*/
+func NewSearcherManagerFromWriter(writer *index.IndexWriter, applyAllDeletes bool,
+	factory SearcherFactory) (*SearcherManager, error) {
+
+	reader, err := index.OpenDirectoryReaderFromWriter(writer, applyAllDeletes)
+	if err != nil {
+		return nil, err
+	}
+	return newSearcherManager(reader, factory)
+}
+
+func newSearcherManager(reader index.IndexReader, factory SearcherFactory) (*SearcherManager, error) {
+	if factory == nil {
+		factory = NewSearcherFactory()
+	}
+	searcher, err := getSearcher(factory, reader)
+	if err != nil {
+		return nil, err
+	}
+	ans := &SearcherManager{factory: factory}
+	ans.ReferenceManager = newReferenceManager(ans, searcher)
+	return ans, nil
+}
+
+/*
+Expert: creates a searcher from the provided IndexReader using the
+provided SearcherFactory. NOTE: this decRefs incoming reader on
+error.
+*/
+func getSearcher(factory SearcherFactory, reader index.IndexReader) (searcher *IndexSearcher, err error) {
+	defer func() {
+		if err != nil {
+			reader.DecRef()
+		}
+	}()
+	if searcher, err = factory.NewSearcher(reader); err != nil {
+		return nil, err
+	}
+	if searcher.IndexReader() != reader {
+		return nil, errors.New(fmt.Sprintf(
+			"SearcherFactory must wrap exactly the provided reader (got %v but expected %v)",
+			searcher.IndexReader(), reader))
+	}
+	return searcher, nil
+}
+
+/*
+Obtain the current IndexSearcher. You must match every call to
+Acquire() with one call to Release().
+*/
+func (m *SearcherManager) Acquire() (*IndexSearcher, error) {
+	ref, err := m.ReferenceManager.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	return ref.(*IndexSearcher), nil
+}
+
+/* Release the searcher previously obtained via Acquire(). */
+func (m *SearcherManager) Release(searcher *IndexSearcher) error {
+	return m.ReferenceManager.Release(searcher)
+}
+
+func (m *SearcherManager) DecRef(ref interface{}) error {
+	return ref.(*IndexSearcher).IndexReader().DecRef()
+}
+
+func (m *SearcherManager) TryIncRef(ref interface{}) bool {
+	return ref.(*IndexSearcher).IndexReader().TryIncRef()
+}
+
+func (m *SearcherManager) RefCount(ref interface{}) int {
+	return ref.(*IndexSearcher).IndexReader().RefCount()
+}
+
+func (m *SearcherManager) RefreshIfNeeded(referenceToRefresh interface{}) (interface{}, error) {
+	r := referenceToRefresh.(*IndexSearcher).IndexReader()
+	dr, ok := r.(index.DirectoryReader)
+	assert2(ok, "searcher's IndexReader should be a DirectoryReader, but got %v", r)
+	newReader, err := index.OpenDirectoryReaderIfChanged(dr)
+	if err != nil {
+		return nil, err
+	}
+	if newReader == nil {
+		return nil, nil
+	}
+	searcher, err := getSearcher(m.factory, newReader)
+	if err != nil {
+		return nil, err
+	}
+	return searcher, nil
+}
+
+/*
+Returns true if no changes have occured since this searcher ie.
+reader was opened, otherwise false.
+*/
+func (m *SearcherManager) IsSearcherCurrent() (bool, error) {
+	searcher, err := m.Acquire()
+	if err != nil {
+		return false, err
+	}
+	defer m.Release(searcher)
+	r := searcher.IndexReader()
+	dr, ok := r.(index.DirectoryReader)
+	assert2(ok, "searcher's IndexReader should be a DirectoryReader, but got %v", r)
+	return dr.IsCurrent(), nil
+}
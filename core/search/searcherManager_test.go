@@ -0,0 +1,144 @@
+package search_test
+
+import (
+	"errors"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSearcherManager(t *testing.T) {
+	directory := testindex.NewDirectory(t)
+	writer := testindex.NewWriter(t, directory, nil)
+	defer writer.Close()
+	tracking := index.NewTrackingIndexWriter(writer)
+	add := func(text string) int64 {
+		d := docu.NewDocument()
+		d.Add(docu.NewTextFieldFromString("body", text, docu.STORE_NO))
+		gen, err := tracking.AddDocument(d.Fields())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gen
+	}
+	q := search.NewTermQuery(index.NewTerm("body", "a"))
+	assertHits := func(s *search.IndexSearcher, expected int) {
+		docs, err := s.SearchTop(q, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if docs.TotalHits != expected {
+			t.Errorf("expected %v hits, but %v", expected, docs.TotalHits)
+		}
+	}
+
+	add("a b")
+	factory := &failingFactory{}
+	manager, err := search.NewSearcherManagerFromWriter(writer, true, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := manager.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHits(old, 1)
+
+	refreshed := false
+	manager.AddListener(refreshListener(func(didRefresh bool) { refreshed = didRefresh }))
+	add("a")
+	if err := manager.MaybeRefreshBlocking(); err != nil {
+		t.Fatal(err)
+	}
+	if !refreshed {
+		t.Error("expected a refresh")
+	}
+	current, err := manager.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHits(current, 2)
+	// near real time: nothing was committed
+	if r, err := index.OpenDirectoryReader(directory); err == nil {
+		t.Errorf("expected no commit, but %v", r)
+		r.Close()
+	}
+	// the old searcher stays usable until released
+	assertHits(old, 1)
+	manager.Release(old)
+	if n := old.IndexReader().RefCount(); n != 0 {
+		t.Errorf("expected the old reader to be closed, but refCount=%v", n)
+	}
+	manager.Release(current)
+
+	reopen := search.NewControlledRealTimeReopenGoroutine(tracking, manager.ReferenceManager, time.Hour, 0)
+	reopen.Start()
+	gen := add("a c")
+	if ok, err := reopen.WaitForGenerationTimeout(gen, 10*time.Second); err != nil || !ok {
+		t.Fatalf("generation %v never became searchable: %v", gen, err)
+	}
+	if current, err = manager.Acquire(); err != nil {
+		t.Fatal(err)
+	}
+	assertHits(current, 3)
+	manager.Release(current)
+
+	// refresh failures are reported to the waiting goroutines
+	factory.setErr(errors.New("warming failed"))
+	gen = add("a d")
+	if ok, err := reopen.WaitForGenerationTimeout(gen, 10*time.Second); ok || err == nil {
+		t.Errorf("expected the refresh error, but %v %v", ok, err)
+	}
+	factory.setErr(nil)
+	if err := reopen.WaitForGeneration(gen); err != nil {
+		t.Fatal(err)
+	}
+	if current, err = manager.Acquire(); err != nil {
+		t.Fatal(err)
+	}
+	assertHits(current, 4)
+	manager.Release(current)
+	if err := reopen.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Acquire(); err != search.ErrReferenceManagerClosed {
+		t.Errorf("expected %v, but %v", search.ErrReferenceManagerClosed, err)
+	}
+}
+
+type refreshListener func(didRefresh bool)
+
+func (f refreshListener) BeforeRefresh() error { return nil }
+func (f refreshListener) AfterRefresh(didRefresh bool) error {
+	f(didRefresh)
+	return nil
+}
+
+// fails to create searchers while err is set
+type failingFactory struct {
+	sync.Mutex
+	err error
+}
+
+func (f *failingFactory) setErr(err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.err = err
+}
+
+func (f *failingFactory) NewSearcher(reader index.IndexReader) (*search.IndexSearcher, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return search.NewIndexSearcher(reader), nil
+}
@@ -6,6 +6,13 @@ import (
 	"github.com/jtejido/golucene/core/util"
 )
 
+/*
+Implements the CombSUM method for combining evidence from multiple
+similarity values described in: Joseph A. Shaw, Edward A. Fox. In Text
+REtrieval Conference (1993), pp. 243-252. The raw scores are summed, so
+the similarities should score on the same scale; see package
+search/fusion to combine the ranked lists of similarities which don't.
+*/
 type MultiSimilarity struct {
 	*similarityImpl
 	sims []Similarity
@@ -0,0 +1,38 @@
+package similarities
+
+import (
+	"encoding/binary"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+)
+
+/*
+A DefaultSimilarity that scores payloads by their value, decoded as
+a big-endian float (the encoding of analysis/payloads' FloatEncoder
+and NumericPayloadTokenFilter). Positions without a payload, or with
+a payload of a different length, are scored 1.
+
+Use it with PayloadTermQuery and PayloadNearQuery so that index-time
+term weights influence scoring.
+*/
+type PayloadSimilarity struct {
+	*DefaultSimilarity
+}
+
+func NewPayloadSimilarity() *PayloadSimilarity {
+	ans := &PayloadSimilarity{NewDefaultSimilarity()}
+	ans.owner = ans
+	return ans
+}
+
+func (ps *PayloadSimilarity) scorePayload(doc, start, end int, payload *util.BytesRef) float32 {
+	if payload == nil || payload.Length != 4 {
+		return 1
+	}
+	bits := binary.BigEndian.Uint32(payload.Bytes[payload.Offset : payload.Offset+4])
+	return math.Float32frombits(bits)
+}
+
+func (ps *PayloadSimilarity) String() string {
+	return "PayloadSimilarity"
+}
@@ -38,6 +38,28 @@ func NewTFIDFSimilarity(owner TFIDFSimilarity) *TFIDFSimilarityImpl {
 	return &TFIDFSimilarityImpl{owner: owner}
 }
 
+/*
+Computes a score factor based on a term or phrase's frequency in a
+document, as tf() of the concrete similarity.
+*/
+func (ts *TFIDFSimilarityImpl) Tf(freq float32) float32 {
+	return ts.owner.tf(freq)
+}
+
+/*
+Computes a score factor based on a term's document frequency (the
+number of documents which contain the term), as idf() of the
+concrete similarity.
+*/
+func (ts *TFIDFSimilarityImpl) Idf(docFreq, numDocs int64) float32 {
+	return ts.owner.idf(docFreq, numDocs)
+}
+
+/* Decodes a normalization factor stored in an index. */
+func (ts *TFIDFSimilarityImpl) DecodeNormValue(norm int64) float32 {
+	return ts.owner.decodeNormValue(norm)
+}
+
 func (ts *TFIDFSimilarityImpl) idfExplainTerm(collectionStats search.CollectionStatistics, termStats search.TermStatistics) search.Explanation {
 	df, max := termStats.DocFreq, collectionStats.MaxDoc()
 	idf := ts.owner.idf(df, max)
@@ -0,0 +1,695 @@
+package search
+
+import (
+	"container/heap"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	. "github.com/jtejido/golucene/core/search/model"
+	"math"
+	"sort"
+)
+
+// search/PhrasePositions.java
+
+/* Position of a term in a document that takes into account the term offset within the phrase. */
+type PhrasePositions struct {
+	doc      int                        // current doc
+	position int                        // position in doc
+	count    int                        // remaining pos in this doc
+	offset   int                        // position in phrase
+	ord      int                        // unique across all PhrasePositions instances
+	postings model.DocsAndPositionsEnum // stream of docs & positions
+	next     *PhrasePositions           // used to make lists
+	rptGroup int                        // >=0 indicates that this is a repeating PP
+	rptInd   int                        // index in the rptGroup
+	terms    []*index.Term              // for repetitions initialization
+}
+
+func newPhrasePositions(postings model.DocsAndPositionsEnum, o, ord int, terms []*index.Term) *PhrasePositions {
+	return &PhrasePositions{
+		postings: postings,
+		offset:   o,
+		ord:      ord,
+		terms:    terms,
+		rptGroup: -1,
+	}
+}
+
+func (pp *PhrasePositions) nextDoc() (ok bool, err error) { // increments to next doc
+	if pp.doc, err = pp.postings.NextDoc(); err != nil {
+		return false, err
+	}
+	return pp.doc != NO_MORE_DOCS, nil
+}
+
+func (pp *PhrasePositions) skipTo(target int) (ok bool, err error) {
+	if pp.doc, err = pp.postings.Advance(target); err != nil {
+		return false, err
+	}
+	return pp.doc != NO_MORE_DOCS, nil
+}
+
+func (pp *PhrasePositions) firstPosition() (err error) {
+	if pp.count, err = pp.postings.Freq(); err != nil { // read first pos
+		return err
+	}
+	_, err = pp.nextPosition()
+	return err
+}
+
+/*
+Go to next location of this term current document, and set position
+as location - offset, so that a matching exact phrase is easily
+identified when all PhrasePositions have exactly the same position.
+*/
+func (pp *PhrasePositions) nextPosition() (bool, error) {
+	if pp.count > 0 { // read subsequent pos's
+		pp.count--
+		pos, err := pp.postings.NextPosition()
+		if err != nil {
+			return false, err
+		}
+		pp.position = pos - pp.offset
+		return true, nil
+	}
+	return false, nil
+}
+
+// search/PhraseQueue.java
+
+type phraseQueue []*PhrasePositions
+
+func (pq phraseQueue) Len() int      { return len(pq) }
+func (pq phraseQueue) Swap(i, j int) { pq[i], pq[j] = pq[j], pq[i] }
+func (pq phraseQueue) Less(i, j int) bool {
+	pp1, pp2 := pq[i], pq[j]
+	if pp1.doc == pp2.doc {
+		if pp1.position == pp2.position {
+			// same doc and pp.position, so decide by actual term positions.
+			// rely on: pp.position == tp.position - offset.
+			if pp1.offset == pp2.offset {
+				return pp1.ord < pp2.ord
+			}
+			return pp1.offset < pp2.offset
+		}
+		return pp1.position < pp2.position
+	}
+	return pp1.doc < pp2.doc
+}
+func (pq *phraseQueue) Push(x interface{}) { *pq = append(*pq, x.(*PhrasePositions)) }
+func (pq *phraseQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	ans := old[n-1]
+	*pq = old[:n-1]
+	return ans
+}
+
+func (pq phraseQueue) top() *PhrasePositions { return pq[0] }
+func (pq *phraseQueue) clear()               { *pq = (*pq)[:0] }
+
+// search/SloppyPhraseScorer.java
+
+type SloppyPhraseScorer struct {
+	abstractScorer
+	min, max *PhrasePositions
+
+	sloppyFreq float32 // phrase frequency in current doc as computed by phraseFreq().
+
+	docScorer SimScorer
+
+	slop        int
+	numPostings int
+	pq          *phraseQueue // for advancing min position
+
+	end int // current largest phrase position
+
+	hasRpts          bool // flag indicating that there are repetitions (as checked in first candidate doc)
+	checkedRpts      bool // flag to only check for repetitions in first candidate doc
+	hasMultiTermRpts bool
+	rptGroups        [][]*PhrasePositions // in each group are PPs that repeats each other (i.e. same term), sorted by (query) offset
+	rptStack         []*PhrasePositions   // temporary stack for switching colliding repeating pps
+
+	numMatches int
+	cost       int64
+}
+
+func newSloppyPhraseScorer(weight Weight, postings []*PostingsAndFreq,
+	slop int, docScorer SimScorer) *SloppyPhraseScorer {
+
+	ans := &SloppyPhraseScorer{
+		docScorer:   docScorer,
+		slop:        slop,
+		numPostings: len(postings),
+		pq:          new(phraseQueue),
+		cost:        postings[0].postings.Cost(),
+	}
+	ans.weight = weight
+	// convert tps to a list of phrase positions.
+	// note: phrase-position differs from term-position in that its position
+	// reflects the phrase offset: pp.pos = tp.pos - offset.
+	// this allows to easily identify a matching (exact) phrase
+	// when all PhrasePositions have exactly the same position.
+	if len(postings) > 0 {
+		ans.min = newPhrasePositions(postings[0].postings, int(postings[0].position), 0, postings[0].terms)
+		ans.max = ans.min
+		ans.max.doc = -1
+		for i := 1; i < len(postings); i++ {
+			pp := newPhrasePositions(postings[i].postings, int(postings[i].position), i, postings[i].terms)
+			ans.max.next = pp
+			ans.max = pp
+			ans.max.doc = -1
+		}
+		ans.max.next = ans.min // make it cyclic for easier manipulation
+	}
+	return ans
+}
+
+/*
+Score a candidate doc for all slop-valid position-combinations
+(matches) encountered while traversing/hopping the PhrasePositions.
+
+The score contribution of a match depends on the distance:
+  - highest score for distance=0 (exact match).
+  - score gets lower as distance gets higher.
+
+Example: for query "a b"~2, a document "x a b a y" can be scored
+twice: once for "a b" (distance=0), and once for "b a" (distance=2).
+
+Possibly not all valid combinations are encountered, because for
+efficiency we always propagate the least PhrasePosition. This allows
+to base on PriorityQueue and move forward faster. As result, for
+example, document "a b c b a" would score differently for queries
+"a b c"~4 and "c b a"~4, although they really are equivalent.
+Similarly, for doc "a b c b a f g", query "c b"~2 would get same
+score as "g f"~2, although "c b"~2 could be matched twice. We may
+want to fix this in the future (currently not, for performance
+reasons).
+*/
+func (s *SloppyPhraseScorer) phraseFreq() (float32, error) {
+	if ok, err := s.initPhrasePositions(); err != nil || !ok {
+		return 0, err
+	}
+	var freq float32
+	s.numMatches = 0
+	pp := heap.Pop(s.pq).(*PhrasePositions)
+	matchLength := s.end - pp.position
+	next := s.pq.top().position
+	for {
+		ok, err := s.advancePP(pp)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		if s.hasRpts {
+			if ok, err = s.advanceRpts(pp); err != nil {
+				return 0, err
+			} else if !ok {
+				break // pps exhausted
+			}
+		}
+		if pp.position > next { // done minimizing current match-length
+			if matchLength <= s.slop {
+				freq += s.docScorer.ComputeSlopFactor(matchLength) // score match
+				s.numMatches++
+			}
+			heap.Push(s.pq, pp)
+			pp = heap.Pop(s.pq).(*PhrasePositions)
+			next = s.pq.top().position
+			matchLength = s.end - pp.position
+		} else if matchLength2 := s.end - pp.position; matchLength2 < matchLength {
+			matchLength = matchLength2
+		}
+	}
+	if matchLength <= s.slop {
+		freq += s.docScorer.ComputeSlopFactor(matchLength) // score match
+		s.numMatches++
+	}
+	return freq, nil
+}
+
+/* advance a PhrasePosition and update 'end', return false if exhausted */
+func (s *SloppyPhraseScorer) advancePP(pp *PhrasePositions) (bool, error) {
+	if ok, err := pp.nextPosition(); err != nil || !ok {
+		return false, err
+	}
+	if pp.position > s.end {
+		s.end = pp.position
+	}
+	return true, nil
+}
+
+/*
+pp was just advanced. If that caused a repeater collision, resolve by
+advancing the lesser of the two colliding pps. Note that there can
+only be one collision, as by the initialization there were no
+collisions before pp was advanced.
+*/
+func (s *SloppyPhraseScorer) advanceRpts(pp *PhrasePositions) (bool, error) {
+	if pp.rptGroup < 0 {
+		return true, nil // not a repeater
+	}
+	rg := s.rptGroups[pp.rptGroup]
+	bits := make([]bool, len(rg)) // for re-queuing after collisions are resolved
+	numBits := 0
+	k0 := pp.rptInd
+	for k := s.collide(pp); k >= 0; k = s.collide(pp) {
+		pp = s.lesser(pp, rg[k]) // always advance the lesser of the (only) two colliding pps
+		if ok, err := s.advancePP(pp); err != nil || !ok {
+			return false, err // exhausted
+		}
+		if k != k0 && !bits[k] { // careful: mark only those currently in the queue
+			bits[k] = true // mark that pp2 need to be re-queued
+			numBits++
+		}
+	}
+	// collisions resolved, now re-queue
+	// empty (partially) the queue until seeing all pps advanced for resolving collisions
+	n := 0
+	for numBits > 0 {
+		pp2 := heap.Pop(s.pq).(*PhrasePositions)
+		s.rptStack[n] = pp2
+		n++
+		if pp2.rptGroup >= 0 && pp2.rptInd < len(bits) && bits[pp2.rptInd] {
+			bits[pp2.rptInd] = false
+			numBits--
+		}
+	}
+	// add back to queue
+	for i := n - 1; i >= 0; i-- {
+		heap.Push(s.pq, s.rptStack[i])
+	}
+	return true, nil
+}
+
+/* compare two pps, but only by position and offset */
+func (s *SloppyPhraseScorer) lesser(pp, pp2 *PhrasePositions) *PhrasePositions {
+	if pp.position < pp2.position ||
+		(pp.position == pp2.position && pp.offset < pp2.offset) {
+		return pp
+	}
+	return pp2
+}
+
+/* index of a pp2 colliding with pp, or -1 if none */
+func (s *SloppyPhraseScorer) collide(pp *PhrasePositions) int {
+	tpPos := s.tpPos(pp)
+	for _, pp2 := range s.rptGroups[pp.rptGroup] {
+		if pp2 != pp && s.tpPos(pp2) == tpPos {
+			return pp2.rptInd
+		}
+	}
+	return -1
+}
+
+/*
+Initialize PhrasePositions in place. A one time initialization for
+this scorer (on first doc matching all terms):
+  - Check if there are repetitions
+  - If there are, find groups of repetitions.
+
+Examples:
+ 1. no repetitions: "ho my"~2
+ 2. repetitions: "ho my my"~2
+ 3. repetitions: "my ho my"~2
+*/
+func (s *SloppyPhraseScorer) initPhrasePositions() (bool, error) {
+	s.end = math.MinInt32
+	if !s.checkedRpts {
+		return s.initFirstTime()
+	}
+	if !s.hasRpts {
+		return true, s.initSimple() // PPs available
+	}
+	return s.initComplex()
+}
+
+/* no repeats: simplest case, and most common. It is important to keep this piece of the code simple and efficient */
+func (s *SloppyPhraseScorer) initSimple() error {
+	s.pq.clear()
+	// position pps and build queue from list
+	for pp, prev := s.min, (*PhrasePositions)(nil); prev != s.max; pp, prev = pp.next, pp { // iterate cyclic list: done once handled max
+		if err := pp.firstPosition(); err != nil {
+			return err
+		}
+		if pp.position > s.end {
+			s.end = pp.position
+		}
+		heap.Push(s.pq, pp)
+	}
+	return nil
+}
+
+/* with repeats: not so simple. */
+func (s *SloppyPhraseScorer) initComplex() (bool, error) {
+	if err := s.placeFirstPositions(); err != nil {
+		return false, err
+	}
+	if ok, err := s.advanceRepeatGroups(); err != nil || !ok {
+		return false, err // PPs exhausted
+	}
+	s.fillQueue()
+	return true, nil // PPs available
+}
+
+/* move all PPs to their first position */
+func (s *SloppyPhraseScorer) placeFirstPositions() error {
+	for pp, prev := s.min, (*PhrasePositions)(nil); prev != s.max; pp, prev = pp.next, pp { // iterate cyclic list: done once handled max
+		if err := pp.firstPosition(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* Fill the queue (all pps are already placed) */
+func (s *SloppyPhraseScorer) fillQueue() {
+	s.pq.clear()
+	for pp, prev := s.min, (*PhrasePositions)(nil); prev != s.max; pp, prev = pp.next, pp { // iterate cyclic list: done once handled max
+		if pp.position > s.end {
+			s.end = pp.position
+		}
+		heap.Push(s.pq, pp)
+	}
+}
+
+/*
+At initialization (each doc), each repetition group is sorted by
+(query) offset. This provides the start condition: no collisions.
+
+Case 1: no multi-term repeats
+It is sufficient to advance each pp in the group by one less than
+its group index. So lesser pp is not advanced, 2nd one advance once,
+3rd one advanced twice, etc.
+
+Case 2: multi-term repeats
+*/
+func (s *SloppyPhraseScorer) advanceRepeatGroups() (bool, error) {
+	for _, rg := range s.rptGroups {
+		if s.hasMultiTermRpts {
+			// more involved, some may not collide
+			var incr int
+			for i := 0; i < len(rg); i += incr {
+				incr = 1
+				pp := rg[i]
+				for k := s.collide(pp); k >= 0; k = s.collide(pp) {
+					pp2 := s.lesser(pp, rg[k])
+					if ok, err := s.advancePP(pp2); err != nil || !ok { // at initialization always advance pp with higher offset
+						return false, err // exhausted
+					}
+					if pp2.rptInd < i { // should not happen?
+						incr = 0
+						break
+					}
+				}
+			}
+		} else {
+			// simpler, we know exactly how much to advance
+			for j := 1; j < len(rg); j++ {
+				for k := 0; k < j; k++ {
+					if ok, err := rg[j].nextPosition(); err != nil || !ok {
+						return false, err // PPs exhausted
+					}
+				}
+			}
+		}
+	}
+	return true, nil // PPs available
+}
+
+/*
+Initialize with checking for repeats. Heavy work, but done only for
+the first candidate doc.
+
+If there are repetitions, check if multi-term postings (MTP) are
+involved.
+
+Without MTP, once PPs are placed in the first candidate doc, repeats
+(and groups) are visible.
+
+With MTP, a more complex check is needed, up-front, as there may be
+"hidden collisions".
+
+For example P1 has {A,B}, P1 has {B,C}, and the first doc is:
+"A C B". At start, P1 would point to "A", p2 to "C", and it will not
+be identified that P1 and P2 are repetitions of each other.
+
+The more complex initialization has two parts:
+
+	(1) identification of repetition groups.
+	(2) advancing repeat groups at the start of the doc.
+
+For (1), a possible solution is to just create a single repetition
+group, made of all repeating pps. But this would slow down the check
+for collisions, as all pps would need to be checked. Instead, we
+compute "connected regions" on the bipartite graph of postings and
+terms.
+*/
+func (s *SloppyPhraseScorer) initFirstTime() (bool, error) {
+	s.checkedRpts = true
+	if err := s.placeFirstPositions(); err != nil {
+		return false, err
+	}
+
+	rptTerms, rptOrder := s.repeatingTerms()
+	s.hasRpts = len(rptTerms) > 0
+
+	if s.hasRpts {
+		s.rptStack = make([]*PhrasePositions, s.numPostings) // needed with repetitions
+		rgs := s.gatherRptGroups(rptTerms, rptOrder)
+		s.sortRptGroups(rgs)
+		if ok, err := s.advanceRepeatGroups(); err != nil || !ok {
+			return false, err // PPs exhausted
+		}
+	}
+
+	s.fillQueue()
+	return true, nil // PPs available
+}
+
+type phrasePositionsByOffset []*PhrasePositions
+
+func (s phrasePositionsByOffset) Len() int           { return len(s) }
+func (s phrasePositionsByOffset) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s phrasePositionsByOffset) Less(i, j int) bool { return s[i].offset < s[j].offset }
+
+/*
+sort each repetition group by (query) offset. Done only once (at
+first doc) and allows to initialize faster for each doc.
+*/
+func (s *SloppyPhraseScorer) sortRptGroups(rgs [][]*PhrasePositions) {
+	s.rptGroups = make([][]*PhrasePositions, len(rgs))
+	for i, rg := range rgs {
+		sort.Sort(phrasePositionsByOffset(rg))
+		s.rptGroups[i] = rg
+		for j, pp := range rg {
+			pp.rptInd = j // we use this index for efficient re-queuing
+		}
+	}
+}
+
+/* Detect repetition groups. Done once - for first doc */
+func (s *SloppyPhraseScorer) gatherRptGroups(rptTerms map[string]int, rptOrder []string) [][]*PhrasePositions {
+	rpp := s.repeatingPPs(rptTerms)
+	var res [][]*PhrasePositions
+	if !s.hasMultiTermRpts {
+		// simpler - no multi-terms - can base on positions in first doc
+		for i, pp := range rpp {
+			if pp.rptGroup >= 0 {
+				continue // already marked as a repetition
+			}
+			tpPos := s.tpPos(pp)
+			for _, pp2 := range rpp[i+1:] {
+				if pp2.rptGroup >= 0 || // already marked as a repetition
+					pp2.offset == pp.offset || // not a repetition: two PPs are originally in same offset in the query!
+					s.tpPos(pp2) != tpPos { // not a repetition
+					continue
+				}
+				// a repetition
+				g := pp.rptGroup
+				if g < 0 {
+					g = len(res)
+					pp.rptGroup = g
+					res = append(res, []*PhrasePositions{pp})
+				}
+				pp2.rptGroup = g
+				res[g] = append(res[g], pp2)
+			}
+		}
+	} else {
+		// more involved - has multi-terms
+		bb := s.ppTermsBitSets(rpp, rptTerms)
+		bb = s.unionTermGroups(bb)
+		tg := s.termGroups(rptOrder, bb)
+		res = make([][]*PhrasePositions, len(bb))
+		for _, pp := range rpp {
+			for _, t := range pp.terms {
+				if _, ok := rptTerms[t.String()]; ok {
+					g := tg[t.String()]
+					assert(pp.rptGroup == -1 || pp.rptGroup == g)
+					if pp.rptGroup == -1 {
+						res[g] = append(res[g], pp)
+					}
+					pp.rptGroup = g
+				}
+			}
+		}
+	}
+	return res
+}
+
+/* Actual position in doc of a PhrasePosition, relies on that position = tpPos - offset) */
+func (s *SloppyPhraseScorer) tpPos(pp *PhrasePositions) int {
+	return pp.position + pp.offset
+}
+
+/* find repeating terms and assign them ordinal values */
+func (s *SloppyPhraseScorer) repeatingTerms() (map[string]int, []string) {
+	tord := make(map[string]int)
+	var order []string
+	tcnt := make(map[string]int)
+	for pp, prev := s.min, (*PhrasePositions)(nil); prev != s.max; pp, prev = pp.next, pp { // iterate cyclic list: done once handled max
+		for _, t := range pp.terms {
+			key := t.String()
+			tcnt[key]++
+			if tcnt[key] == 2 {
+				tord[key] = len(order)
+				order = append(order, key)
+			}
+		}
+	}
+	return tord, order
+}
+
+/* find repeating pps, and for each, if has multi-terms, update this.hasMultiTermRpts */
+func (s *SloppyPhraseScorer) repeatingPPs(rptTerms map[string]int) []*PhrasePositions {
+	var rp []*PhrasePositions
+	for pp, prev := s.min, (*PhrasePositions)(nil); prev != s.max; pp, prev = pp.next, pp { // iterate cyclic list: done once handled max
+		for _, t := range pp.terms {
+			if _, ok := rptTerms[t.String()]; ok {
+				rp = append(rp, pp)
+				s.hasMultiTermRpts = s.hasMultiTermRpts || len(pp.terms) > 1
+				break
+			}
+		}
+	}
+	return rp
+}
+
+/* bit-sets - for each repeating pp, for each of its repeating terms, the term ordinal values is set */
+func (s *SloppyPhraseScorer) ppTermsBitSets(rpp []*PhrasePositions, tord map[string]int) [][]bool {
+	bb := make([][]bool, len(rpp))
+	for i, pp := range rpp {
+		b := make([]bool, len(tord))
+		for _, t := range pp.terms {
+			if ord, ok := tord[t.String()]; ok {
+				b[ord] = true
+			}
+		}
+		bb[i] = b
+	}
+	return bb
+}
+
+/* union (term group) bit-sets until they are disjoint (O(n^^2)), and each group have different terms */
+func (s *SloppyPhraseScorer) unionTermGroups(bb [][]bool) [][]bool {
+	intersects := func(a, b []bool) bool {
+		for i := range a {
+			if a[i] && b[i] {
+				return true
+			}
+		}
+		return false
+	}
+	var incr int
+	for i := 0; i < len(bb)-1; i += incr {
+		incr = 1
+		j := i + 1
+		for j < len(bb) {
+			if intersects(bb[i], bb[j]) {
+				for k, set := range bb[j] {
+					bb[i][k] = bb[i][k] || set
+				}
+				bb = append(bb[:j], bb[j+1:]...)
+				incr = 0
+			} else {
+				j++
+			}
+		}
+	}
+	return bb
+}
+
+/* map each term to the single group that contains it */
+func (s *SloppyPhraseScorer) termGroups(tord []string, bb [][]bool) map[string]int {
+	tg := make(map[string]int)
+	for i, bits := range bb { // i is the group no.
+		for ord, set := range bits {
+			if set {
+				tg[tord[ord]] = i
+			}
+		}
+	}
+	return tg
+}
+
+func (s *SloppyPhraseScorer) Freq() (int, error) {
+	return s.numMatches, nil
+}
+
+func (s *SloppyPhraseScorer) SloppyFreq() float32 {
+	return s.sloppyFreq
+}
+
+func (s *SloppyPhraseScorer) DocId() int {
+	return s.max.doc
+}
+
+func (s *SloppyPhraseScorer) NextDoc() (int, error) {
+	return s.Advance(s.max.doc + 1) // unpositioned -> 0
+}
+
+func (s *SloppyPhraseScorer) Score() (float32, error) {
+	return s.docScorer.Score(s.max.doc, s.sloppyFreq), nil
+}
+
+func (s *SloppyPhraseScorer) Advance(target int) (int, error) {
+	assert(target > s.DocId())
+	for {
+		if ok, err := s.advanceMin(target); err != nil || !ok {
+			return NO_MORE_DOCS, err
+		}
+		for s.min.doc < s.max.doc {
+			if ok, err := s.advanceMin(s.max.doc); err != nil || !ok {
+				return NO_MORE_DOCS, err
+			}
+		}
+		// found a doc with all of the terms
+		var err error
+		if s.sloppyFreq, err = s.phraseFreq(); err != nil { // check for phrase
+			return 0, err
+		}
+		target = s.min.doc + 1 // next target in case sloppyFreq is still 0
+		if s.sloppyFreq != 0 {
+			break
+		}
+	}
+	// found a match
+	return s.max.doc, nil
+}
+
+func (s *SloppyPhraseScorer) advanceMin(target int) (bool, error) {
+	if ok, err := s.min.skipTo(target); err != nil || !ok {
+		s.max.doc = NO_MORE_DOCS // for further calls to DocId()
+		return false, err
+	}
+	s.min = s.min.next // cyclic
+	s.max = s.max.next // cyclic
+	return true, nil
+}
+
+func (s *SloppyPhraseScorer) Cost() int64 {
+	return s.cost
+}
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/spans/SpanNearQuery.java
+
+/*
+Matches spans which are near one another. One can specify slop, the
+maximum number of intervening unmatched positions, as well as
+whether matches are required to be in-order.
+*/
+type SpanNearQuery struct {
+	*AbstractQuery
+	clauses         []SpanQuery
+	slop            int
+	inOrder         bool
+	field           string
+	collectPayloads bool
+}
+
+/*
+Construct a SpanNearQuery. Matches spans matching a span from each
+clause, with up to slop total unmatched positions between them. When
+inOrder is true, the spans from each clause must be ordered as in
+clauses.
+*/
+func NewSpanNearQuery(clauses []SpanQuery, slop int, inOrder bool) *SpanNearQuery {
+	return NewSpanNearQueryWithPayloads(clauses, slop, inOrder, true)
+}
+
+func NewSpanNearQueryWithPayloads(clauses []SpanQuery, slop int,
+	inOrder, collectPayloads bool) *SpanNearQuery {
+
+	ans := new(SpanNearQuery)
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	ans.init(clauses, slop, inOrder, collectPayloads)
+	return ans
+}
+
+func (q *SpanNearQuery) init(clauses []SpanQuery, slop int, inOrder, collectPayloads bool) {
+	// copy clauses array into a slice
+	q.clauses = make([]SpanQuery, 0, len(clauses))
+	for _, clause := range clauses {
+		if q.field == "" { // check field
+			q.field = clause.Field()
+		} else if f := clause.Field(); f != "" && f != q.field {
+			panic("Clauses must have same field.")
+		}
+		q.clauses = append(q.clauses, clause)
+	}
+	q.collectPayloads = collectPayloads
+	q.slop = slop
+	q.inOrder = inOrder
+}
+
+/* Return the clauses whose spans are matched. */
+func (q *SpanNearQuery) Clauses() []SpanQuery {
+	return q.clauses
+}
+
+/* Return the maximum number of intervening unmatched positions permitted. */
+func (q *SpanNearQuery) Slop() int {
+	return q.slop
+}
+
+/* Return true if matches are required to be in-order. */
+func (q *SpanNearQuery) IsInOrder() bool {
+	return q.inOrder
+}
+
+func (q *SpanNearQuery) Field() string {
+	return q.field
+}
+
+func (q *SpanNearQuery) ExtractTerms(terms map[string]*index.Term) {
+	for _, clause := range q.clauses {
+		clause.ExtractTerms(terms)
+	}
+}
+
+func (q *SpanNearQuery) CreateWeight(ss *IndexSearcher) (Weight, error) {
+	return NewSpanWeight(q, ss)
+}
+
+func (q *SpanNearQuery) Spans(ctx *index.AtomicReaderContext, acceptDocs util.Bits,
+	termContexts map[string]*index.TermContext) (Spans, error) {
+
+	switch len(q.clauses) {
+	case 0: // optimize 0-clause case
+		return emptySpans{}, nil
+	case 1: // optimize 1-clause case
+		return q.clauses[0].Spans(ctx, acceptDocs, termContexts)
+	}
+	if q.inOrder {
+		return newNearSpansOrdered(q, ctx, acceptDocs, termContexts, q.collectPayloads)
+	}
+	return newNearSpansUnordered(q, ctx, acceptDocs, termContexts)
+}
+
+func (q *SpanNearQuery) Rewrite(reader index.IndexReader) Query {
+	if clauses, ok := q.rewriteClauses(reader); ok {
+		clone := q.Clone().(*SpanNearQuery)
+		clone.clauses = clauses
+		return clone // some clauses rewrote
+	}
+	return q // no clauses rewrote
+}
+
+/*
+Returns the rewritten clauses, and true if any of them rewrote.
+*/
+func (q *SpanNearQuery) rewriteClauses(reader index.IndexReader) ([]SpanQuery, bool) {
+	clauses := make([]SpanQuery, len(q.clauses))
+	changed := false
+	for i, c := range q.clauses {
+		clauses[i] = c.Rewrite(reader).(SpanQuery)
+		changed = changed || clauses[i] != c
+	}
+	return clauses, changed
+}
+
+func (q *SpanNearQuery) Clone() Query {
+	clauses := make([]SpanQuery, len(q.clauses))
+	for i, clause := range q.clauses {
+		clauses[i] = clause.Clone().(SpanQuery)
+	}
+	ans := NewSpanNearQueryWithPayloads(clauses, q.slop, q.inOrder, q.collectPayloads)
+	ans.boost = q.boost
+	return ans
+}
+
+func (q *SpanNearQuery) ToString(field string) string {
+	return q.toString("spanNear", field)
+}
+
+func (q *SpanNearQuery) toString(name, field string) string {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	buf.WriteString("([")
+	for i, clause := range q.clauses {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(clause.ToString(field))
+	}
+	buf.WriteString(fmt.Sprintf("], %v, %v)", q.slop, q.inOrder))
+	if q.boost != 1.0 {
+		buf.WriteString(fmt.Sprintf("^%v", q.boost))
+	}
+	return buf.String()
+}
@@ -0,0 +1,209 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/spans/SpanTermQuery.java
+
+/*
+Matches spans containing a term. This should not be used for terms
+that are indexed at position int max.
+*/
+type SpanTermQuery struct {
+	*AbstractQuery
+	term *index.Term
+}
+
+/* Construct a SpanTermQuery matching the named term's spans. */
+func NewSpanTermQuery(term *index.Term) *SpanTermQuery {
+	ans := &SpanTermQuery{term: term}
+	ans.AbstractQuery = NewAbstractQuery(ans)
+	return ans
+}
+
+/* Return the term whose spans are matched. */
+func (q *SpanTermQuery) Term() *index.Term {
+	return q.term
+}
+
+func (q *SpanTermQuery) Field() string {
+	return q.term.Field
+}
+
+func (q *SpanTermQuery) ExtractTerms(terms map[string]*index.Term) {
+	terms[q.term.String()] = q.term
+}
+
+func (q *SpanTermQuery) CreateWeight(ss *IndexSearcher) (Weight, error) {
+	return NewSpanWeight(q, ss)
+}
+
+func (q *SpanTermQuery) Clone() Query {
+	ans := NewSpanTermQuery(q.term)
+	ans.boost = q.boost
+	return ans
+}
+
+func (q *SpanTermQuery) ToString(field string) string {
+	var buf bytes.Buffer
+	if q.term.Field == field {
+		buf.WriteString(string(q.term.Bytes))
+	} else {
+		buf.WriteString(q.term.String())
+	}
+	if q.boost != 1.0 {
+		buf.WriteString(fmt.Sprintf("^%v", q.boost))
+	}
+	return buf.String()
+}
+
+func (q *SpanTermQuery) Spans(ctx *index.AtomicReaderContext, acceptDocs util.Bits,
+	termContexts map[string]*index.TermContext) (Spans, error) {
+
+	reader := ctx.Reader().(index.AtomicReader)
+	terms := reader.Terms(q.term.Field)
+	if terms == nil {
+		return emptySpans{}, nil
+	}
+	termsEnum := terms.Iterator(nil)
+
+	if termContext, ok := termContexts[q.term.String()]; ok {
+		state := termContext.State(ctx.Ord)
+		if state == nil { // term is not present in that reader
+			return emptySpans{}, nil
+		}
+		if err := termsEnum.SeekExactFromLast(q.term.Bytes, state); err != nil {
+			return nil, err
+		}
+	} else {
+		// the term was not collected by the weight, so we seek to the
+		// term now in this segment
+		found, err := termsEnum.SeekExact(q.term.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return emptySpans{}, nil
+		}
+	}
+
+	postings, err := termsEnum.DocsAndPositionsByFlags(acceptDocs, nil,
+		DOCS_POSITIONS_ENUM_FLAG_PAYLOADS)
+	if err != nil {
+		return nil, err
+	}
+	if postings == nil {
+		// term does exist, but has no positions
+		return nil, fmt.Errorf(
+			"field \"%v\" was indexed without position data; cannot run SpanTermQuery (term=%v)",
+			q.term.Field, string(q.term.Bytes))
+	}
+	return newTermSpans(postings, q.term), nil
+}
+
+// search/spans/TermSpans.java
+
+/*
+Expert: Public for extension only. This does not work correctly for
+terms that indexed at position int max.
+*/
+type TermSpans struct {
+	postings    DocsAndPositionsEnum
+	term        *index.Term
+	doc         int
+	freq        int
+	count       int
+	position    int
+	readPayload bool
+}
+
+func newTermSpans(postings DocsAndPositionsEnum, term *index.Term) *TermSpans {
+	return &TermSpans{
+		postings: postings,
+		term:     term,
+		doc:      -1,
+	}
+}
+
+func (s *TermSpans) Next() (ok bool, err error) {
+	if s.count == s.freq {
+		if s.doc, err = s.postings.NextDoc(); err != nil || s.doc == NO_MORE_DOCS {
+			return false, err
+		}
+		if s.freq, err = s.postings.Freq(); err != nil {
+			return false, err
+		}
+		s.count = 0
+	}
+	return s.nextPosition()
+}
+
+func (s *TermSpans) SkipTo(target int) (ok bool, err error) {
+	assert(target > s.doc)
+	if s.doc, err = s.postings.Advance(target); err != nil || s.doc == NO_MORE_DOCS {
+		return false, err
+	}
+	if s.freq, err = s.postings.Freq(); err != nil {
+		return false, err
+	}
+	s.count = 0
+	return s.nextPosition()
+}
+
+func (s *TermSpans) nextPosition() (ok bool, err error) {
+	if s.position, err = s.postings.NextPosition(); err != nil {
+		return false, err
+	}
+	s.count++
+	s.readPayload = false
+	return true, nil
+}
+
+func (s *TermSpans) Doc() int   { return s.doc }
+func (s *TermSpans) Start() int { return s.position }
+func (s *TermSpans) End() int   { return s.position + 1 }
+
+func (s *TermSpans) Cost() int64 {
+	return s.postings.Cost()
+}
+
+func (s *TermSpans) Payload() ([][]byte, error) {
+	payload, err := s.postings.Payload()
+	if err != nil {
+		return nil, err
+	}
+	s.readPayload = true
+	var bytes []byte
+	if payload != nil {
+		bytes = make([]byte, payload.Length)
+		copy(bytes, payload.Bytes[payload.Offset:payload.Offset+payload.Length])
+	}
+	return [][]byte{bytes}, nil
+}
+
+func (s *TermSpans) IsPayloadAvailable() (bool, error) {
+	if s.readPayload {
+		return false, nil
+	}
+	payload, err := s.postings.Payload()
+	return payload != nil, err
+}
+
+func (s *TermSpans) Postings() DocsAndPositionsEnum {
+	return s.postings
+}
+
+func (s *TermSpans) String() string {
+	if s.doc == -1 {
+		return fmt.Sprintf("spans(%v)@START", s.term)
+	} else if s.doc == NO_MORE_DOCS {
+		return fmt.Sprintf("spans(%v)@END", s.term)
+	}
+	return fmt.Sprintf("spans(%v)@%v-%v", s.term, s.doc, s.position)
+}
@@ -0,0 +1,330 @@
+package search
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"reflect"
+	"sort"
+)
+
+// search/spans/Spans.java
+
+/*
+Expert: an enumeration of span matches. Used to implement span
+searching. Each span represents a range of term positions within a
+document. Matches are enumerated in order, by increasing document
+number, within that by increasing start position and finally by
+increasing end position.
+*/
+type Spans interface {
+	// Move to the next match, returning true iff any such exists.
+	Next() (bool, error)
+	// Skips to the first match beyond the current, whose document
+	// number is greater than or equal to target.
+	//
+	// The behavior of this method is undefined when called with
+	// target <= current, or after the iterator has exhausted. Both
+	// cases may result in unpredicted behavior.
+	//
+	// Returns true iff there is such a match.
+	SkipTo(target int) (bool, error)
+	// Returns the document number of the current match. Initially
+	// invalid.
+	Doc() int
+	// Returns the start position of the current match. Initially
+	// invalid.
+	Start() int
+	// Returns the end position of the current match. Initially
+	// invalid.
+	End() int
+	// Returns the payload data for the current span. This is invalid
+	// until Next() is called for the first time. This method must not
+	// be called more than once after each call of Next(). However,
+	// most payloads are loaded lazily, so if the payload data for the
+	// current position is not needed, this method may not be called
+	// at all for performance reasons. An ordered SpanQuery does not
+	// lazy load, so if you have payloads in your index and you do not
+	// want ordered SpanNearQuerys to collect payloads, you can disable
+	// collection with a constructor option.
+	//
+	// Note that the return type is a slice, thus the ordering should
+	// not be relied upon.
+	Payload() ([][]byte, error)
+	// Checks if a payload can be loaded at this position.
+	//
+	// Payloads can only be loaded once per call to Next().
+	IsPayloadAvailable() (bool, error)
+	// Returns the estimated cost of this spans.
+	//
+	// This is generally an upper bound of the number of documents this
+	// iterator might match, but may be a rough heuristic, hardcoded
+	// value, or otherwise completely inaccurate.
+	Cost() int64
+}
+
+/* A Spans without any match. */
+type emptySpans struct{}
+
+func (s emptySpans) Next() (bool, error)               { return false, nil }
+func (s emptySpans) SkipTo(target int) (bool, error)   { return false, nil }
+func (s emptySpans) Doc() int                          { return NO_MORE_DOCS }
+func (s emptySpans) Start() int                        { return -1 }
+func (s emptySpans) End() int                          { return -1 }
+func (s emptySpans) Payload() ([][]byte, error)        { return nil, nil }
+func (s emptySpans) IsPayloadAvailable() (bool, error) { return false, nil }
+func (s emptySpans) Cost() int64                       { return 0 }
+
+// search/spans/SpanQuery.java
+
+/* Base interface for span-based queries. */
+type SpanQuery interface {
+	Query
+	// Expert: Returns the matches for this query in an index. Used
+	// internally to search for spans.
+	Spans(ctx *index.AtomicReaderContext, acceptDocs util.Bits,
+		termContexts map[string]*index.TermContext) (Spans, error)
+	// Returns the name of the field matched by this query.
+	//
+	// Note that this may return "" if the query matches no terms.
+	Field() string
+	// Expert: adds all terms occurring in this query to the terms
+	// map, keyed by Term.String().
+	ExtractTerms(terms map[string]*index.Term)
+}
+
+// search/spans/SpanWeight.java
+
+/* Expert-only. Public for use by other weight implementations. */
+type SpanWeight struct {
+	*WeightImpl
+	similarity   Similarity
+	termContexts map[string]*index.TermContext
+	query        SpanQuery
+	stats        SimWeight
+}
+
+func NewSpanWeight(query SpanQuery, searcher *IndexSearcher) (*SpanWeight, error) {
+	ans, err := newSpanWeight(query, searcher)
+	if err != nil {
+		return nil, err
+	}
+	ans.WeightImpl = NewWeightImpl(ans)
+	return ans, nil
+}
+
+/*
+Builds the term statistics of the span query. Callers must set
+WeightImpl to the outermost weight themselves.
+*/
+func newSpanWeight(query SpanQuery, searcher *IndexSearcher) (*SpanWeight, error) {
+	ans := &SpanWeight{
+		similarity:   searcher.similarity,
+		termContexts: make(map[string]*index.TermContext),
+		query:        query,
+	}
+	terms := make(map[string]*index.Term)
+	query.ExtractTerms(terms)
+	keys := make([]string, 0, len(terms))
+	for key := range terms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	context := searcher.TopReaderContext()
+	termStats := make([]TermStatistics, len(keys))
+	for i, key := range keys {
+		term := terms[key]
+		state, err := index.NewTermContextFromTerm(context, term)
+		if err != nil {
+			return nil, err
+		}
+		termStats[i] = searcher.TermStatistics(term, state)
+		ans.termContexts[key] = state
+	}
+	if field := query.Field(); field != "" {
+		ans.stats = ans.similarity.ComputeWeight(query.Boost(),
+			searcher.CollectionStatistics(field), termStats...)
+	}
+	return ans, nil
+}
+
+func (w *SpanWeight) String() string {
+	return fmt.Sprintf("weight(%v)", w.query)
+}
+
+func (w *SpanWeight) ValueForNormalization() float32 {
+	if w.stats == nil {
+		return 1
+	}
+	return w.stats.ValueForNormalization()
+}
+
+func (w *SpanWeight) Normalize(norm, topLevelBoost float32) {
+	if w.stats != nil {
+		w.stats.Normalize(norm, topLevelBoost)
+	}
+}
+
+func (w *SpanWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *SpanWeight) Scorer(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (Scorer, error) {
+	if w.stats == nil {
+		return nil, nil
+	}
+	spans, err := w.query.Spans(ctx, acceptDocs, w.termContexts)
+	if err != nil {
+		return nil, err
+	}
+	docScorer, err := w.similarity.SimScorer(w.stats, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewSpanScorer(spans, w, docScorer)
+}
+
+func (w *SpanWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanation, error) {
+	s, err := w.Scorer(ctx, ctx.Reader().(index.AtomicReader).LiveDocs())
+	if err != nil {
+		return nil, err
+	}
+	if scorer, ok := s.(*SpanScorer); ok {
+		newDoc, err := scorer.Advance(doc)
+		if err != nil {
+			return nil, err
+		}
+		if newDoc == doc {
+			freq := scorer.SloppyFreq()
+			docScorer, err := w.similarity.SimScorer(w.stats, ctx)
+			if err != nil {
+				return nil, err
+			}
+			scoreExplanation := docScorer.Explain(doc,
+				NewExplanation(freq, fmt.Sprintf("phraseFreq=%v", freq)))
+			ans := NewComplexExplanation(true, scoreExplanation.Value(),
+				fmt.Sprintf("weight(%v in %v) [%v], result of:",
+					w.query, doc, reflect.TypeOf(w.similarity)))
+			ans.details = []Explanation{scoreExplanation}
+			return ans, nil
+		}
+	}
+	return NewComplexExplanation(false, 0, "no matching term"), nil
+}
+
+// search/spans/SpanScorer.java
+
+type spanScorerSPI interface {
+	setFreqCurrentDoc() (bool, error)
+}
+
+/* Public for extension only. */
+type SpanScorer struct {
+	abstractScorer
+	spi        spanScorerSPI
+	spans      Spans
+	more       bool
+	doc        int
+	freq       float32
+	numMatches int
+	docScorer  SimScorer
+}
+
+func NewSpanScorer(spans Spans, weight Weight, docScorer SimScorer) (*SpanScorer, error) {
+	ans, err := newSpanScorer(spans, weight, docScorer)
+	if err != nil {
+		return nil, err
+	}
+	ans.spi = ans
+	return ans, nil
+}
+
+func newSpanScorer(spans Spans, weight Weight, docScorer SimScorer) (*SpanScorer, error) {
+	more, err := spans.Next()
+	if err != nil {
+		return nil, err
+	}
+	ans := &SpanScorer{
+		spans:     spans,
+		more:      more,
+		doc:       -1,
+		docScorer: docScorer,
+	}
+	ans.weight = weight
+	return ans, nil
+}
+
+func (s *SpanScorer) NextDoc() (int, error) {
+	ok, err := s.spi.setFreqCurrentDoc()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		s.doc = NO_MORE_DOCS
+	}
+	return s.doc, nil
+}
+
+func (s *SpanScorer) Advance(target int) (int, error) {
+	if !s.more {
+		s.doc = NO_MORE_DOCS
+		return s.doc, nil
+	}
+	if s.spans.Doc() < target { // setFreqCurrentDoc() leaves spans.doc() ahead
+		var err error
+		if s.more, err = s.spans.SkipTo(target); err != nil {
+			return 0, err
+		}
+	}
+	return s.NextDoc()
+}
+
+func (s *SpanScorer) setFreqCurrentDoc() (ok bool, err error) {
+	if !s.more {
+		return false, nil
+	}
+	s.doc = s.spans.Doc()
+	s.freq = 0
+	s.numMatches = 0
+	for {
+		matchLength := s.spans.End() - s.spans.Start()
+		s.freq += s.docScorer.ComputeSlopFactor(matchLength)
+		s.numMatches++
+		if s.more, err = s.spans.Next(); err != nil {
+			return false, err
+		}
+		if !s.more || s.doc != s.spans.Doc() {
+			break
+		}
+	}
+	return true, nil
+}
+
+func (s *SpanScorer) DocId() int {
+	return s.doc
+}
+
+func (s *SpanScorer) Score() (float32, error) {
+	return s.docScorer.Score(s.doc, s.freq), nil
+}
+
+func (s *SpanScorer) Freq() (int, error) {
+	return s.numMatches, nil
+}
+
+/*
+Returns the intermediate "sloppy freq" adjusted for edit distance.
+*/
+func (s *SpanScorer) SloppyFreq() float32 {
+	return s.freq
+}
+
+func (s *SpanScorer) Cost() int64 {
+	return s.spans.Cost()
+}
+
+func (s *SpanScorer) String() string {
+	return fmt.Sprintf("scorer(%v)", s.weight)
+}
@@ -28,6 +28,23 @@ func NewTermQueryWithDocFreq(t *index.Term, docFreq int) *TermQuery {
 	return ans
 }
 
+/*
+Expert: constructs a TermQuery that will use the provided docFreq
+and term states, instead of looking up the docFreq against the
+searcher.
+*/
+func NewTermQueryWithStates(t *index.Term, states *index.TermContext) *TermQuery {
+	assert(states != nil)
+	ans := NewTermQueryWithDocFreq(t, states.DocFreq)
+	ans.perReaderTermState = states
+	return ans
+}
+
+/* Returns the term of this query. */
+func (q *TermQuery) Term() *index.Term {
+	return q.term
+}
+
 func (q *TermQuery) CreateWeight(ss *IndexSearcher) (w Weight, err error) {
 	ctx := ss.TopReaderContext()
 	var termState *index.TermContext
@@ -98,7 +115,7 @@ func NewTermWeight(owner *TermQuery, ss *IndexSearcher, termStates *index.TermCo
 			ss.TermStatistics(owner.term, termStates)),
 		termStates: termStates,
 	}
-	ans.WeightImpl = newWeightImpl(ans)
+	ans.WeightImpl = NewWeightImpl(ans)
 	return ans
 }
 
@@ -181,7 +198,7 @@ func (tw *TermWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanat
 			}
 			scoreExplanation := docScorer.Explain(doc,
 				NewExplanation(float32(freq), fmt.Sprintf("termFreq=%v", freq)))
-			ans := newComplexExplanation(true,
+			ans := NewComplexExplanation(true,
 				scoreExplanation.(*ExplanationImpl).value,
 				fmt.Sprintf("weight(%v in %v) [%v], result of:",
 					tw.TermQuery, doc, reflect.TypeOf(tw.similarity)))
@@ -189,5 +206,5 @@ func (tw *TermWeight) Explain(ctx *index.AtomicReaderContext, doc int) (Explanat
 			return ans, nil
 		}
 	}
-	return newComplexExplanation(false, 0, "no matching term"), nil
+	return NewComplexExplanation(false, 0, "no matching term"), nil
 }
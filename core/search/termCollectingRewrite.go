@@ -1,7 +1,9 @@
 package search
 
 import (
+	"context"
 	"fmt"
+	"github.com/jtejido/golucene/core/analysis/tokenattributes"
 	"github.com/jtejido/golucene/core/index"
 	. "github.com/jtejido/golucene/core/index/model"
 	"github.com/jtejido/golucene/core/util"
@@ -35,10 +37,27 @@ func (a *AbstractTermCollectingRewrite) AddClause(topLevel Query, term *index.Te
 	a.spi.AddClauseWithContext(topLevel, term, docCount, boost, nil)
 }
 
+/* Number of terms enumerated between two cancellation checks. */
+const TERMS_CANCELLATION_CHECK_INTERVAL = 1024
+
 func (a *AbstractTermCollectingRewrite) CollectTerms(reader index.IndexReader, query MultiTermQuery, collector TermCollector) error {
+	return a.CollectTermsContext(context.Background(), reader, query, collector)
+}
+
+/*
+Like CollectTerms(), but returns ctx.Err() once ctx is done. It is
+checked before each segment and every
+TERMS_CANCELLATION_CHECK_INTERVAL enumerated terms.
+*/
+func (a *AbstractTermCollectingRewrite) CollectTermsContext(ctx context.Context,
+	reader index.IndexReader, query MultiTermQuery, collector TermCollector) error {
+
 	topReaderContext := reader.Context()
 	var lastTermComp sort.Interface
 	for _, context := range topReaderContext.Leaves() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		fields := context.Reader().(index.AtomicReader).Fields()
 		if fields == nil {
 			// reader has no fields
@@ -70,7 +89,12 @@ func (a *AbstractTermCollectingRewrite) CollectTerms(reader index.IndexReader, q
 		collector.SetReaderContext(topReaderContext, context)
 		collector.SetNextEnum(termsEnum)
 
-		for {
+		for n := 1; ; n++ {
+			if n%TERMS_CANCELLATION_CHECK_INTERVAL == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
 			bytes, err := termsEnum.Next()
 			if err != nil {
 				return err
@@ -117,6 +141,14 @@ func (c *AbstractTermCollector) SetReaderContext(topReaderContext index.IndexRea
 	c.topReaderContext = topReaderContext
 }
 
+/* Returns the attributes shared by the collector and the TermsEnum. */
+func (c *AbstractTermCollector) Attributes() *util.AttributeSource {
+	if c.attributes == nil {
+		c.attributes = util.NewAttributeSourceWith(tokenattributes.DEFAULT_ATTRIBUTE_FACTORY)
+	}
+	return c.attributes
+}
+
 func (c *AbstractTermCollector) SetNextEnum(termsEnum TermsEnum) {
 	c.spi.SetNextEnum(termsEnum)
 }
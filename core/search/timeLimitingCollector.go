@@ -0,0 +1,238 @@
+package search
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// search/TimeLimitingCollector.java
+
+/*
+The TimeLimitingCollector is used to timeout search requests that
+take longer than the maximum allowed search time limit. After this
+time is exceeded, the search is stopped and a TimeExceededError is
+returned.
+*/
+type TimeLimitingCollector struct {
+	t0, timeout  int64
+	collector    Collector
+	clock        util.Counter
+	ticksAllowed int64
+	greedy       bool
+	docBase      int
+}
+
+/*
+Create a TimeLimitedCollector wrapper over another Collector with a
+specified timeout.
+
+The clock is usually GlobalCounter(), which is advanced by a shared
+timer goroutine every DEFAULT_TIMER_RESOLUTION milliseconds, so
+ticksAllowed is the allowed search time in milliseconds.
+*/
+func NewTimeLimitingCollector(collector Collector, clock util.Counter, ticksAllowed int64) *TimeLimitingCollector {
+	return &TimeLimitingCollector{
+		t0:           math.MinInt64,
+		collector:    collector,
+		clock:        clock,
+		ticksAllowed: ticksAllowed,
+	}
+}
+
+/*
+Sets the baseline for this collector. By default the collector's
+baseline is initialized once the first reader is passed to the
+collector. To include operations executed in prior to the actual
+document collection set the baseline through this method in your
+prelude.
+*/
+func (c *TimeLimitingCollector) SetBaseline(clockTime int64) {
+	c.t0 = clockTime
+	c.timeout = c.t0 + c.ticksAllowed
+}
+
+/* Syntactic sugar for SetBaseline() using the current clock time. */
+func (c *TimeLimitingCollector) SetBaselineNow() {
+	c.SetBaseline(c.clock.Get())
+}
+
+/*
+Checks if this time limited collector is greedy in collecting the
+last hit. A non greedy collector, upon a timeout, would return a
+TimeExceededError without allowing the wrapped collector to collect
+current doc. A greedy one would first allow the wrapped hit collector
+to collect current doc and only then return the error.
+*/
+func (c *TimeLimitingCollector) IsGreedy() bool {
+	return c.greedy
+}
+
+/* Sets whether this time limited collector is greedy. See IsGreedy(). */
+func (c *TimeLimitingCollector) SetGreedy(greedy bool) {
+	c.greedy = greedy
+}
+
+/*
+Calls Collect() on the decorated Collector unless the allowed time
+has passed, in which case it returns a TimeExceededError.
+*/
+func (c *TimeLimitingCollector) Collect(doc int) error {
+	if t := c.clock.Get(); c.timeout < t {
+		if c.greedy {
+			if err := c.collector.Collect(doc); err != nil {
+				return err
+			}
+		}
+		return &TimeExceededError{
+			TimeAllowed:      c.timeout - c.t0,
+			TimeElapsed:      t - c.t0,
+			LastDocCollected: c.docBase + doc,
+		}
+	}
+	return c.collector.Collect(doc)
+}
+
+func (c *TimeLimitingCollector) SetNextReader(ctx *index.AtomicReaderContext) {
+	c.collector.SetNextReader(ctx)
+	c.docBase = ctx.DocBase
+	if c.t0 == math.MinInt64 {
+		c.SetBaselineNow()
+	}
+}
+
+func (c *TimeLimitingCollector) SetScorer(s Scorer) {
+	c.collector.SetScorer(s)
+}
+
+func (c *TimeLimitingCollector) AcceptsDocsOutOfOrder() bool {
+	return c.collector.AcceptsDocsOutOfOrder()
+}
+
+/*
+This is so the same timer can be used with a multi-phase search
+process such as grouping. We don't want to create a new
+TimeLimitingCollector for each phase because that would reset the
+timer for each phase. Once time is up subsequent phases need to
+timeout quickly.
+*/
+func (c *TimeLimitingCollector) SetCollector(collector Collector) {
+	c.collector = collector
+}
+
+/* Returned from Collect() when the allowed search time has passed. */
+type TimeExceededError struct {
+	TimeAllowed      int64
+	TimeElapsed      int64
+	LastDocCollected int // last doc (absolute doc id) collected before the timeout
+}
+
+func (e *TimeExceededError) Error() string {
+	return fmt.Sprintf("Elapsed time: %v. Exceeded allowed search time: %v ms.",
+		e.TimeElapsed, e.TimeAllowed)
+}
+
+/* Default timer resolution in milliseconds. */
+const DEFAULT_TIMER_RESOLUTION = 20
+
+var globalTimer struct {
+	sync.Once
+	*TimerGoroutine
+}
+
+/*
+Returns the global TimerGoroutine's Counter, the clock most
+TimeLimitingCollectors are driven by. The timer goroutine is started
+on first use.
+*/
+func GlobalCounter() util.Counter {
+	return GlobalTimerGoroutine().Counter()
+}
+
+/* Returns the global TimerGoroutine, starting it on first use. */
+func GlobalTimerGoroutine() *TimerGoroutine {
+	globalTimer.Do(func() {
+		globalTimer.TimerGoroutine = NewTimerGoroutine(util.NewAtomicCounter(), DEFAULT_TIMER_RESOLUTION)
+		globalTimer.TimerGoroutine.Start()
+	})
+	return globalTimer.TimerGoroutine
+}
+
+/*
+Timer goroutine, that periodically increments a counter. It is
+shared by all TimeLimitingCollectors so that a time check is only an
+atomic read, instead of a (comparably expensive) system call for each
+collected document.
+*/
+type TimerGoroutine struct {
+	counter    util.Counter
+	resolution int64 // in milliseconds
+	stop       chan struct{}
+	stopOnce   sync.Once
+	running    int32
+}
+
+func NewTimerGoroutine(counter util.Counter, resolution int64) *TimerGoroutine {
+	return &TimerGoroutine{
+		counter:    counter,
+		resolution: resolution,
+		stop:       make(chan struct{}),
+	}
+}
+
+/* Starts advancing the counter; calling it on a running timer is a no-op. */
+func (t *TimerGoroutine) Start() {
+	if !atomic.CompareAndSwapInt32(&t.running, 0, 1) {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(atomic.LoadInt64(&t.resolution)) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.counter.AddAndGet(atomic.LoadInt64(&t.resolution))
+			}
+		}
+	}()
+}
+
+/* Get the timer value in milliseconds. */
+func (t *TimerGoroutine) Milliseconds() int64 {
+	return t.counter.Get()
+}
+
+/* Returns the counter advanced by this timer. */
+func (t *TimerGoroutine) Counter() util.Counter {
+	return t.counter
+}
+
+/* Stops the timer goroutine. */
+func (t *TimerGoroutine) StopTimer() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+/* Return the timer resolution, in milliseconds. */
+func (t *TimerGoroutine) Resolution() int64 {
+	return atomic.LoadInt64(&t.resolution)
+}
+
+/*
+Set the timer resolution, in milliseconds. The default timer
+resolution is 20 milliseconds. This means that a search required to
+take no longer than 800 milliseconds may be stopped after 780 to 820
+milliseconds. Values below 5 are raised to 5. It only takes effect
+for timers started afterwards.
+*/
+func (t *TimerGoroutine) SetResolution(resolution int64) {
+	if resolution < 5 {
+		resolution = 5 // about the minimum reasonable tick
+	}
+	atomic.StoreInt64(&t.resolution, resolution)
+}
@@ -0,0 +1,34 @@
+package search
+
+import (
+	"github.com/jtejido/golucene/core/index"
+)
+
+// search/TotalHitCountCollector.java
+
+/* Just counts the total number of hits. */
+type TotalHitCountCollector struct {
+	totalHits int
+}
+
+func NewTotalHitCountCollector() *TotalHitCountCollector {
+	return new(TotalHitCountCollector)
+}
+
+/* Returns how many hits matched the search. */
+func (c *TotalHitCountCollector) TotalHits() int {
+	return c.totalHits
+}
+
+func (c *TotalHitCountCollector) SetScorer(s Scorer) {}
+
+func (c *TotalHitCountCollector) Collect(doc int) error {
+	c.totalHits++
+	return nil
+}
+
+func (c *TotalHitCountCollector) SetNextReader(ctx *index.AtomicReaderContext) {}
+
+func (c *TotalHitCountCollector) AcceptsDocsOutOfOrder() bool {
+	return true
+}
@@ -83,7 +83,7 @@ type WeightImpl struct {
 	spi WeightImplSPI
 }
 
-func newWeightImpl(spi WeightImplSPI) *WeightImpl {
+func NewWeightImpl(spi WeightImplSPI) *WeightImpl {
 	return &WeightImpl{spi}
 }
 
@@ -144,7 +144,9 @@ func (s *DefaultBulkScorer) scoreRange(collector Collector,
 func (s *DefaultBulkScorer) scoreAll(collector Collector, scorer Scorer) (err error) {
 	var doc int
 	for doc, err = scorer.NextDoc(); doc != NO_MORE_DOCS && err == nil; doc, err = scorer.NextDoc() {
-		err = collector.Collect(doc)
+		if err = collector.Collect(doc); err != nil {
+			return
+		}
 	}
 	return
 }
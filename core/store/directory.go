@@ -319,11 +319,11 @@ func (d *DirectoryImpl) Copy(to Directory, src, dest string, ctx IOContext) (err
 			err = util.Close(os, is)
 		} else {
 			util.CloseWhileSuppressingError(os, is)
+			defer func() {
+				recover() // ignore panic
+			}()
+			to.DeleteFile(dest) // ignore error
 		}
-		defer func() {
-			recover() // ignore panic
-		}()
-		to.DeleteFile(dest) // ignore error
 	}()
 
 	os, err = to.CreateOutput(dest, ctx)
@@ -40,7 +40,6 @@ type FSDirectory struct {
 	chunkSize      int
 }
 
-// TODO support lock factory
 func newFSDirectory(spi FSDirectorySPI, path string) (d *FSDirectory, err error) {
 	d = &FSDirectory{
 		Locker:         &sync.Mutex{},
@@ -57,8 +56,7 @@ func newFSDirectory(spi FSDirectorySPI, path string) (d *FSDirectory, err error)
 		return d, newNoSuchDirectoryError(fmt.Sprintf("file '%v' exists but is not a directory", path))
 	}
 
-	// TODO default to native lock factory
-	d.SetLockFactory(NewSimpleFSLockFactory(path))
+	d.SetLockFactory(newDefaultLockFactory(path))
 	return d, nil
 }
 
@@ -77,7 +75,14 @@ func (d *FSDirectory) SetLockFactory(lockFactory LockFactory) {
 	// for filesystem based LockFactory, delete the lockPrefix, if the locks are placed
 	// in index dir. If no index dir is given, set ourselves
 	// TODO change FSDirectory to interface
-	if lf, ok := lockFactory.(*SimpleFSLockFactory); ok {
+	var lf *FSLockFactory
+	switch v := lockFactory.(type) {
+	case *SimpleFSLockFactory:
+		lf = v.FSLockFactory
+	case *NativeFSLockFactory:
+		lf = v.FSLockFactory
+	}
+	if lf != nil {
 		if lf.lockDir == "" {
 			lf.lockDir = d.path
 			lf.lockPrefix = ""
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// store/NativeFSLockFactory.java
+
+/*
+Implements LockFactory using native OS file locks (flock(2) on Unix).
+The lock file itself is never deleted, only locked, and the OS releases
+the lock when the holding process exits, even abnormally. So unlike
+SimpleFSLockFactory, a stale write.lock left behind by a crashed
+process does not prevent later IndexWriters from being opened.
+
+Native locks are held per process; NativeFSLockFactory additionally
+tracks the lock files held in this process, so two Lock instances in
+the same process cannot both obtain the same lock either.
+
+This is the default LockFactory for FSDirectory on platforms that
+support native locks.
+
+If you suspect that this or any other LockFactory is not working
+properly in your environment, you can easily test it by using
+VerifyingLockFactory, LockVerifyServer and LockStressTest.
+*/
+type NativeFSLockFactory struct {
+	*FSLockFactory
+}
+
+/*
+Create a NativeFSLockFactory instance, storing lock files into the
+specified lockDir. If lockDir is empty, it is set by the FSDirectory
+this factory is used with.
+*/
+func NewNativeFSLockFactory(lockDir string) *NativeFSLockFactory {
+	ans := &NativeFSLockFactory{}
+	ans.FSLockFactory = newFSLockFactory()
+	if lockDir != "" {
+		ans.setLockDir(lockDir)
+	}
+	return ans
+}
+
+func (f *NativeFSLockFactory) Make(name string) Lock {
+	if f.lockPrefix != "" {
+		name = fmt.Sprintf("%v-%v", f.lockPrefix, name)
+	}
+	return newNativeFSLock(f.lockDir, name)
+}
+
+/*
+Note that this does not delete the lock file: a native lock is
+released by closing it, and the OS already released any lock held by a
+dead process.
+*/
+func (f *NativeFSLockFactory) Clear(name string) error {
+	return f.Make(name).Close()
+}
+
+func (f *NativeFSLockFactory) String() string {
+	return fmt.Sprintf("NativeFSLockFactory@%v", f.lockDir)
+}
+
+/* Lock files held by this process, by absolute path. */
+var locksHeld = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: make(map[string]bool)}
+
+func markLockHeld(path string) bool {
+	locksHeld.Lock()
+	defer locksHeld.Unlock()
+	if locksHeld.paths[path] {
+		return false
+	}
+	locksHeld.paths[path] = true
+	return true
+}
+
+func clearLockHeld(path string) {
+	locksHeld.Lock()
+	defer locksHeld.Unlock()
+	delete(locksHeld.paths, path)
+}
+
+type NativeFSLock struct {
+	*LockImpl
+	sync.Locker
+	dir, path string
+	file      *os.File // non-nil while this instance holds the lock
+}
+
+func newNativeFSLock(lockDir, lockFileName string) *NativeFSLock {
+	ans := &NativeFSLock{
+		Locker: &sync.Mutex{},
+		dir:    lockDir,
+		path:   filepath.Join(lockDir, lockFileName),
+	}
+	ans.LockImpl = NewLockImpl(ans)
+	return ans
+}
+
+func (lock *NativeFSLock) Obtain() (ok bool, err error) {
+	lock.Lock() // synchronized
+	defer lock.Unlock()
+
+	if lock.file != nil {
+		// Our instance is already locked:
+		return false, nil
+	}
+
+	// Ensure that lockDir exists and is a directory.
+	if err = os.MkdirAll(lock.dir, 0755); err != nil {
+		return false, err
+	}
+	path, err := filepath.Abs(lock.path)
+	if err != nil {
+		return false, err
+	}
+	if !markLockHeld(path) {
+		// Someone else in this process holds the lock:
+		return false, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		clearLockHeld(path)
+		return false, err
+	}
+	if ok, err = tryLockFile(f); ok {
+		lock.file = f
+		return true, nil
+	}
+	f.Close()
+	clearLockHeld(path)
+	// At least on OS X, we will sometimes get an error when the lock
+	// file is on NFS; record it as the reason the lock failed:
+	lock.failureReason = err
+	return false, nil
+}
+
+func (lock *NativeFSLock) Close() error {
+	lock.Lock() // synchronized
+	defer lock.Unlock()
+
+	if lock.file == nil {
+		return nil
+	}
+	f := lock.file
+	lock.file = nil
+	defer func() {
+		if path, err := filepath.Abs(lock.path); err == nil {
+			clearLockHeld(path)
+		}
+	}()
+	err := unlockFile(f)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (lock *NativeFSLock) IsLocked() bool {
+	// The test for IsLocked is not directly possible with native file
+	// locks. First a shortcut, if a lock is held by this instance:
+	lock.Lock()
+	held := lock.file != nil
+	lock.Unlock()
+	if held {
+		return true
+	}
+
+	// Look if lock file is present; if not, there can definitely be no
+	// lock!
+	if _, err := os.Stat(lock.path); os.IsNotExist(err) {
+		return false
+	}
+
+	// Try to obtain and release (if was locked) the lock
+	obtained, err := lock.Obtain()
+	if err != nil {
+		return false
+	}
+	if obtained {
+		lock.Close()
+	}
+	return !obtained
+}
+
+func (lock *NativeFSLock) String() string {
+	return fmt.Sprintf("NativeFSLock@%v", lock.path)
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNativeFSLockFactory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("native locks are not supported on windows")
+	}
+	// helper process for the crash test below: holds the lock until killed
+	if dir := os.Getenv("GOLUCENE_LOCK_HOLDER"); dir != "" {
+		lock := NewNativeFSLockFactory(dir).Make("write.lock")
+		if ok, err := lock.Obtain(); !ok || err != nil {
+			fmt.Println("failed", err)
+			os.Exit(1)
+		}
+		fmt.Println("locked")
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+
+	path, err := ioutil.TempDir("", "nativelock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	d, err := OpenFSDirectory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, ok := d.LockFactory().(*NativeFSLockFactory); !ok {
+		t.Fatalf("expected NativeFSLockFactory by default, but %v", d.LockFactory())
+	}
+
+	// a stale lock file left behind does not block
+	if err = ioutil.WriteFile(filepath.Join(path, "write.lock"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	l1 := d.MakeLock("write.lock")
+	if ok, err := l1.Obtain(); !ok || err != nil {
+		t.Fatalf("expected to obtain the lock: %v", err)
+	}
+	l2 := NewNativeFSLockFactory(path).Make("write.lock")
+	if ok, _ := l2.Obtain(); ok {
+		t.Fatal("obtained the lock twice")
+	}
+	if !l2.IsLocked() {
+		t.Error("expected lock to be reported as held")
+	}
+	if err = l1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := l2.Obtain(); !ok || err != nil {
+		t.Fatalf("expected to obtain the released lock: %v", err)
+	}
+	l2.Close()
+
+	// the OS releases the lock of a process that dies
+	cmd := exec.Command(os.Args[0], "-test.run=^TestNativeFSLockFactory$")
+	cmd.Env = append(os.Environ(), "GOLUCENE_LOCK_HOLDER="+path)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if line, _ := bufio.NewReader(out).ReadString('\n'); line != "locked\n" {
+		cmd.Process.Kill()
+		cmd.Wait()
+		t.Fatalf("helper process failed to lock: %q", line)
+	}
+	if ok, _ := l1.Obtain(); ok {
+		t.Error("obtained the lock held by another process")
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+	if ok, err := l1.Obtain(); !ok || err != nil {
+		t.Fatalf("expected to obtain the lock after the holder died: %v", err)
+	}
+	l1.Close()
+}
+
+func TestVerifyingLockFactory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("native locks are not supported on windows")
+	}
+	path, err := ioutil.TempDir("", "verifylock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	server, err := NewLockVerifyServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const clients = 3
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.Serve(clients) }()
+
+	var wg sync.WaitGroup
+	errs := make([]error, clients)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			errs[id] = LockStressTest(int32(id), server.Addr(),
+				NewNativeFSLockFactory(path), "test.lock", time.Millisecond, 20)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("client %v: %v", i, err)
+		}
+	}
+	if err = <-serverErr; err != nil {
+		t.Error(err)
+	}
+}
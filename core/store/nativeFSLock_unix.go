@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package store
+
+import (
+	"os"
+	"syscall"
+)
+
+/*
+Tries to acquire an exclusive flock(2) on the file without blocking.
+Returns false if another open file holds the lock.
+*/
+func tryLockFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+/* Native locks are the default when available. */
+func newDefaultLockFactory(path string) LockFactory {
+	return NewNativeFSLockFactory(path)
+}
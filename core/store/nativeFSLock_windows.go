@@ -0,0 +1,19 @@
+package store
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLockFile(f *os.File) (bool, error) {
+	return false, errors.New("native file locks are not supported on this platform yet")
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
+
+/* Native locks are not ported to Windows yet, so keep lock files. */
+func newDefaultLockFactory(path string) LockFactory {
+	return NewSimpleFSLockFactory(path)
+}
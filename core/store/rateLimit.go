@@ -1,7 +1,11 @@
 package store
 
 import (
+	"fmt"
 	"math"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // store/RateLimiter.java
@@ -25,59 +29,88 @@ type RateLimiter interface {
 		Note: the implementation is thread-safe
 	*/
 	Pause(bytes int64) int64
+	// How many bytes caller should add up itself before invoking Pause().
+	MinPauseCheckBytes() int64
 }
 
+// Minimum time to pause for, in milliseconds, used to compute
+// MinPauseCheckBytes().
+const MIN_PAUSE_CHECK_MSEC = 5
+
 // Simple class to rate limit IO
-// Ian: volatile is not supported
 type SimpleRateLimiter struct {
-	mbPerSec           float64 // volatile
-	minPauseCheckBytes int64   // volatile
+	sync.Locker
+	mbPerSec           float64
+	nsPerByte          float64
+	minPauseCheckBytes int64 // atomic, read on every write
 	lastNS             int64
 }
 
 // mbPerSec is the MB/sec max IO rate
-func newSimpleRateLimiter(mbPerSec float64) *SimpleRateLimiter {
-	ans := &SimpleRateLimiter{}
+func NewSimpleRateLimiter(mbPerSec float64) *SimpleRateLimiter {
+	ans := &SimpleRateLimiter{Locker: &sync.Mutex{}}
 	ans.SetMbPerSec(mbPerSec)
 	return ans
 }
 
 func (srl *SimpleRateLimiter) SetMbPerSec(mbPerSec float64) {
+	srl.Lock() // synchronized
+	defer srl.Unlock()
 	srl.mbPerSec = mbPerSec
-	panic("not implemented yet")
+	if mbPerSec > 0 {
+		srl.nsPerByte = 1000000000.0 / (1024 * 1024 * mbPerSec)
+	} else {
+		srl.nsPerByte = 0
+	}
+	// Only pause once at least this many bytes were written, so that
+	// the pause lasts about MIN_PAUSE_CHECK_MSEC:
+	minPauseCheckBytes := int64(math.MaxInt64)
+	if bytes := float64(MIN_PAUSE_CHECK_MSEC) / 1000 * mbPerSec * 1024 * 1024; bytes < math.MaxInt64 {
+		minPauseCheckBytes = int64(bytes)
+	}
+	atomic.StoreInt64(&srl.minPauseCheckBytes, minPauseCheckBytes)
 }
 
 func (srl *SimpleRateLimiter) MbPerSec() float64 {
+	srl.Lock() // synchronized
+	defer srl.Unlock()
 	return srl.mbPerSec
 }
 
+func (srl *SimpleRateLimiter) MinPauseCheckBytes() int64 {
+	return atomic.LoadInt64(&srl.minPauseCheckBytes)
+}
+
 /*
 Pause, if necessary, to keep the instantaneous IO rate at or below
 the target. Be sure to only call this method when bytes >
-minPauseCheckBytes(), otherwise it will pause way too long!
+MinPauseCheckBytes(), otherwise it will pause way too long!
+
+Returns the pause time in nano seconds.
 */
 func (srl *SimpleRateLimiter) Pause(bytes int64) int64 {
-	panic("not implemented yet")
-	// if bytes == 1 {
-	// 	return 0
-	// }
-
-	// // TODO: this is purely instantaneous rate; maybe we
-	// // should also offer decayed recent history one?
-	// srl.lastNS += int64(float64(bytes) * srl.nsPerByte)
-	// targetNS := srl.lastNS
-	// startNS := time.Now().UnixNano()
-	// curNS := startNS
-	// if srl.lastNS < curNS {
-	// 	srl.lastNS = curNS
-	// }
-
-	// // While loop because sleep doesn't always sleep enough:
-	// for pauseNS := targetNS - curNS; pauseNS > 0; pauseNS = targetNS - curNS {
-	// 	time.Sleep(time.Duration(pauseNS * int64(time.Nanosecond)))
-	// 	curNS = time.Now().UnixNano()
-	// }
-	// return curNS - startNS
+	if bytes == 1 {
+		return 0
+	}
+
+	// TODO: this is purely instantaneous rate; maybe we
+	// should also offer decayed recent history one?
+	srl.Lock()
+	srl.lastNS += int64(float64(bytes) * srl.nsPerByte)
+	targetNS := srl.lastNS
+	startNS := time.Now().UnixNano()
+	if srl.lastNS < startNS {
+		srl.lastNS = startNS
+	}
+	srl.Unlock()
+
+	// While loop because sleep doesn't always sleep enough:
+	curNS := startNS
+	for pauseNS := targetNS - curNS; pauseNS > 0; pauseNS = targetNS - curNS {
+		time.Sleep(time.Duration(pauseNS))
+		curNS = time.Now().UnixNano()
+	}
+	return curNS - startNS
 }
 
 // store/RateLimitedDirectoryWrapper.java
@@ -86,20 +119,20 @@ func (srl *SimpleRateLimiter) Pause(bytes int64) int64 {
 // IO context specific rate limiters.
 type RateLimitedDirectoryWrapper struct {
 	Directory
-	// we need to be volatile here to make sure we see all the values
-	// that are set / modified concurrently
-	// Ian: volatile is not supported
-	contextRateLimiters []RateLimiter // volatile
+	// guards contextRateLimiters and isOpen, which are set / modified
+	// concurrently
+	sync.Locker
+	contextRateLimiters []RateLimiter
 	isOpen              bool
 }
 
 func NewRateLimitedDirectoryWrapper(wrapped Directory) *RateLimitedDirectoryWrapper {
-	panic("not implemented yet")
-	// return &RateLimitedDirectoryWrapper{
-	// 	Directory:           wrapped,
-	// 	contextRateLimiters: make([]RateLimiter, 4), // TODO magic number
-	// 	isOpen:              true,
-	// }
+	return &RateLimitedDirectoryWrapper{
+		Directory:           wrapped,
+		Locker:              &sync.Mutex{},
+		contextRateLimiters: make([]RateLimiter, IO_CONTEXT_TYPE_DEFAULT),
+		isOpen:              true,
+	}
 }
 
 func (w *RateLimitedDirectoryWrapper) CreateOutput(name string, ctx IOContext) (IndexOutput, error) {
@@ -113,20 +146,33 @@ func (w *RateLimitedDirectoryWrapper) CreateOutput(name string, ctx IOContext) (
 	return output, err
 }
 
-// func (w *RateLimitedDirectoryWrapper) Close() error {
-// 	w.isOpen = false
-// 	return w.Directory.Close()
-// }
+func (w *RateLimitedDirectoryWrapper) Close() error {
+	w.Lock()
+	w.isOpen = false
+	w.Unlock()
+	return w.Directory.Close()
+}
 
-// func (w *RateLimitedDirectoryWrapper) String() string {
-// 	return fmt.Sprintf("RateLimitedDirectoryWrapper(%v)", w.Directory)
-// }
+func (w *RateLimitedDirectoryWrapper) String() string {
+	return fmt.Sprintf("RateLimitedDirectoryWrapper(%v)", w.Directory)
+}
 
 func (w *RateLimitedDirectoryWrapper) rateLimiter(ctx IOContextType) RateLimiter {
 	assert(int(ctx) != 0)
+	w.Lock()
+	defer w.Unlock()
 	return w.contextRateLimiters[int(ctx)-1]
 }
 
+func (w *RateLimitedDirectoryWrapper) ensureWrapperOpen(context int) {
+	if !w.isOpen {
+		panic("this Directory is closed")
+	}
+	if context == 0 {
+		panic("Context must not be nil")
+	}
+}
+
 /*
 Sets the maximum (approx) MB/sec allowed by all write IO performed by
 IndexOutput created with the given context. Pass non-positve value to
@@ -141,66 +187,72 @@ Directory implementations. Currently only buffered Directory
 implementations use rate-limiting.
 */
 func (w *RateLimitedDirectoryWrapper) SetMaxWriteMBPerSec(mbPerSec float64, context int) {
-	if !w.isOpen {
-		panic("this Directory is closed")
-	}
-	if context == 0 {
-		panic("Context must not be nil")
-	}
+	w.Lock()
+	defer w.Unlock()
+	w.ensureWrapperOpen(context)
 	ord := context - 1
 	limiter := w.contextRateLimiters[ord]
 	if mbPerSec <= 0 {
 		if limiter != nil {
 			limiter.SetMbPerSec(math.MaxFloat64)
 			w.contextRateLimiters[ord] = nil
-			// atomic.StorePointer(&(w.contextRateLimiters[ord]), nil)
 		}
 	} else if limiter != nil {
 		limiter.SetMbPerSec(mbPerSec)
-		// atomic.StorePointer(&(w.contextRateLimiters[ord]), limiter) // cross the mem barrier again
 	} else {
-		w.contextRateLimiters[ord] = newSimpleRateLimiter(mbPerSec)
-		// atomic.StorePointer(&(w.contextRateLimiters[ord]), newSimpleRateLimiter(mbPerSec))
+		w.contextRateLimiters[ord] = NewSimpleRateLimiter(mbPerSec)
 	}
 }
 
 /*
 Sets the rate limiter to be used to limit (approx) MB/sec allowed by
-all IO performed with the given context. Pass non-positive to have no
-limit.
+all IO performed with the given context. Pass nil to have no limit.
 
 Passing an instance of rate limiter compared to settng it using
-setMaxWriteMBPersec() allows to use the same limiter instance across
+SetMaxWriteMBPerSec() allows to use the same limiter instance across
 several directories globally limiting IO across them.
 */
-func (w *RateLimitedDirectoryWrapper) setRateLimiter(mergeWriteRateLimiter RateLimiter, context int) {
-	panic("not implemented yet")
+func (w *RateLimitedDirectoryWrapper) SetRateLimiter(mergeWriteRateLimiter RateLimiter, context int) {
+	w.Lock()
+	defer w.Unlock()
+	w.ensureWrapperOpen(context)
+	w.contextRateLimiters[context-1] = mergeWriteRateLimiter
 }
 
-func (w *RateLimitedDirectoryWrapper) MaxWriteMBPerSec(context int) {
-	panic("not implemented yet")
+/*
+See SetMaxWriteMBPerSec(). Returns 0 if there is no limit for the
+given context.
+*/
+func (w *RateLimitedDirectoryWrapper) MaxWriteMBPerSec(context int) float64 {
+	w.Lock()
+	defer w.Unlock()
+	w.ensureWrapperOpen(context)
+	if limiter := w.contextRateLimiters[context-1]; limiter != nil {
+		return limiter.MbPerSec()
+	}
+	return 0
 }
 
 // store/RateLimitedIndexOutput.java
 
-type flushBuffer interface {
-	FlushBuffer(buf []byte) error
-}
-
 /* A rate limiting IndexOutput */
 type RateLimitedIndexOutput struct {
 	*IndexOutputImpl
 	delegate    IndexOutput
 	rateLimiter RateLimiter
+
+	// How many bytes we've written since we last called
+	// rateLimiter.Pause.
+	bytesSinceLastPause int64
 }
 
 func newRateLimitedIndexOutput(rateLimiter RateLimiter, delegate IndexOutput) *RateLimitedIndexOutput {
-	panic("not implemented yet")
-	// ans := &RateLimitedIndexOutput{}
-	// ans.BufferedIndexOutput = NewBufferedIndexOutput(DEFAULT_BUFFER_SIZE, ans)
-	// ans.delegate = delegate
-	// ans.rateLimiter = rateLimiter
-	// return ans
+	ans := &RateLimitedIndexOutput{
+		delegate:    delegate,
+		rateLimiter: rateLimiter,
+	}
+	ans.IndexOutputImpl = NewIndexOutput(ans)
+	return ans
 }
 
 func (out *RateLimitedIndexOutput) Close() error {
@@ -208,7 +260,7 @@ func (out *RateLimitedIndexOutput) Close() error {
 }
 
 func (out *RateLimitedIndexOutput) FilePointer() int64 {
-	panic("not implementd yet")
+	return out.delegate.FilePointer()
 }
 
 func (out *RateLimitedIndexOutput) Checksum() int64 {
@@ -216,22 +268,29 @@ func (out *RateLimitedIndexOutput) Checksum() int64 {
 }
 
 func (out *RateLimitedIndexOutput) WriteByte(b byte) error {
-	panic("not implemented yet")
+	out.bytesSinceLastPause++
+	out.checkRate()
+	return out.delegate.WriteByte(b)
 }
 
 func (out *RateLimitedIndexOutput) WriteBytes(p []byte) error {
-	panic("not implemented yet")
+	out.bytesSinceLastPause += int64(len(p))
+	out.checkRate()
+	return out.delegate.WriteBytes(p)
 }
 
-// func (out *RateLimitedIndexOutput) FlushBuffer(buf []byte) error {
-// 	out.rateLimiter.Pause(int64(len(buf)))
-// 	if v, ok := out.delegate.(flushBuffer); ok {
-// 		return v.FlushBuffer(buf)
-// 	}
-// 	panic("double check if flushBuffer interface is satisfied")
-// 	return out.delegate.WriteBytes(buf)
-// }
+/*
+The limiter's MinPauseCheckBytes() is read on each check, not cached,
+as the rate of a shared limiter may change while writing, e.g. the
+merge rate limit of ConcurrentMergeScheduler.
+*/
+func (out *RateLimitedIndexOutput) checkRate() {
+	if out.bytesSinceLastPause > out.rateLimiter.MinPauseCheckBytes() {
+		out.rateLimiter.Pause(out.bytesSinceLastPause)
+		out.bytesSinceLastPause = 0
+	}
+}
 
-// func (out *RateLimitedIndexOutput) Length() (int64, error) {
-// 	return out.delegate.Length()
-// }
+func (out *RateLimitedIndexOutput) String() string {
+	return fmt.Sprintf("RateLimitedIndexOutput(%v)", out.delegate)
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitedDirectoryWrapper(t *testing.T) {
+	dir := NewRateLimitedDirectoryWrapper(NewRAMDirectory())
+	defer dir.Close()
+	if v := dir.MaxWriteMBPerSec(IO_CONTEXT_TYPE_MERGE); v != 0 {
+		t.Fatalf("expected no limit by default, but %v", v)
+	}
+
+	// without a limit, outputs are not wrapped
+	out, err := dir.CreateOutput("flush", NewIOContextForFlush(&FlushInfo{1, 1024}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.(*RateLimitedIndexOutput); ok {
+		t.Error("flush output should not be rate limited")
+	}
+	out.Close()
+
+	dir.SetMaxWriteMBPerSec(1, IO_CONTEXT_TYPE_MERGE)
+	if v := dir.MaxWriteMBPerSec(IO_CONTEXT_TYPE_MERGE); v != 1 {
+		t.Fatalf("expected 1 MB/sec, but %v", v)
+	}
+	out, err = dir.CreateOutput("merge", NewIOContextForMerge(&MergeInfo{1, 1024, false, -1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.(*RateLimitedIndexOutput); !ok {
+		t.Fatalf("expected a rate limited output, but %v", out)
+	}
+
+	// writing 200 KB at 1 MB/sec takes about 200 ms
+	start := time.Now()
+	buf := make([]byte, 1024)
+	for i := 0; i < 200; i++ {
+		if err = out.WriteBytes(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = out.WriteInt(42); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("expected writes to be throttled, but took %v", elapsed)
+	}
+	if fp := out.FilePointer(); fp != 200*1024+4 {
+		t.Errorf("expected file pointer %v, but %v", 200*1024+4, fp)
+	}
+	if err = out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a shared limiter applies across directories; non-positive limit
+	// removes it
+	limiter := NewSimpleRateLimiter(3)
+	dir.SetRateLimiter(limiter, IO_CONTEXT_TYPE_MERGE)
+	limiter.SetMbPerSec(4)
+	if v := dir.MaxWriteMBPerSec(IO_CONTEXT_TYPE_MERGE); v != 4 {
+		t.Errorf("expected 4 MB/sec, but %v", v)
+	}
+	dir.SetMaxWriteMBPerSec(0, IO_CONTEXT_TYPE_MERGE)
+	if v := dir.MaxWriteMBPerSec(IO_CONTEXT_TYPE_MERGE); v != 0 {
+		t.Errorf("expected no limit, but %v", v)
+	}
+}
+
+/* A RateLimiter that counts pauses instead of pausing. */
+type countingRateLimiter struct {
+	minPauseCheckBytes int64
+	pauses             int
+}
+
+func (l *countingRateLimiter) SetMbPerSec(mbPerSec float64) {}
+func (l *countingRateLimiter) MbPerSec() float64            { return 0 }
+func (l *countingRateLimiter) MinPauseCheckBytes() int64    { return l.minPauseCheckBytes }
+
+func (l *countingRateLimiter) Pause(bytes int64) int64 {
+	l.pauses++
+	return 0
+}
+
+func TestRateLimitedIndexOutputRateChange(t *testing.T) {
+	dir := NewRAMDirectory()
+	defer dir.Close()
+	delegate, err := dir.CreateOutput("merge", IO_CONTEXT_DEFAULT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := &countingRateLimiter{minPauseCheckBytes: 1 << 20}
+	out := newRateLimitedIndexOutput(limiter, delegate)
+	defer out.Close()
+
+	if err = out.WriteBytes(make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.pauses != 0 {
+		t.Fatalf("expected no pause below %v bytes, but %v", limiter.minPauseCheckBytes, limiter.pauses)
+	}
+
+	// the rate is lowered while writing: the next write must pause
+	limiter.minPauseCheckBytes = 10
+	if err = out.WriteByte(1); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.pauses != 1 {
+		t.Errorf("expected a pause once the rate is lowered, but %v", limiter.pauses)
+	}
+}
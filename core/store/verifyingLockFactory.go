@@ -0,0 +1,275 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// store/VerifyingLockFactory.java
+
+const (
+	lockVerifyReleased = byte(0)
+	lockVerifyObtained = byte(1)
+	lockVerifyStart    = byte(43)
+)
+
+/*
+A LockFactory that wraps another LockFactory and verifies that each
+lock obtain/release is "correct" (never results in two processes
+holding the lock at the same time). It does this by contacting an
+external server (LockVerifyServer) to assert that at most one process
+holds the lock at a time. To use this, you should also run
+LockVerifyServer and LockStressTest.
+*/
+type VerifyingLockFactory struct {
+	LockFactory
+	sync.Locker
+	in  io.Reader
+	out io.Writer
+}
+
+/*
+Creates a VerifyingLockFactory wrapping lf, exchanging verification
+messages with a LockVerifyServer through in and out.
+*/
+func NewVerifyingLockFactory(lf LockFactory, in io.Reader, out io.Writer) *VerifyingLockFactory {
+	return &VerifyingLockFactory{
+		LockFactory: lf,
+		Locker:      &sync.Mutex{},
+		in:          in,
+		out:         out,
+	}
+}
+
+func (f *VerifyingLockFactory) Make(name string) Lock {
+	f.Lock() // synchronized
+	defer f.Unlock()
+	ans := &checkedLock{factory: f, lock: f.LockFactory.Make(name)}
+	ans.LockImpl = NewLockImpl(ans)
+	return ans
+}
+
+func (f *VerifyingLockFactory) verify(message byte) error {
+	f.Lock() // synchronized
+	defer f.Unlock()
+	if _, err := f.out.Write([]byte{message}); err != nil {
+		return err
+	}
+	var ret [1]byte
+	if _, err := io.ReadFull(f.in, ret[:]); err != nil {
+		return errors.New("Lock server died because of locking error.")
+	}
+	if ret[0] != message {
+		return errors.New("Protocol violation.")
+	}
+	return nil
+}
+
+func (f *VerifyingLockFactory) String() string {
+	return fmt.Sprintf("VerifyingLockFactory@%v", f.LockFactory)
+}
+
+type checkedLock struct {
+	*LockImpl
+	factory  *VerifyingLockFactory
+	lock     Lock
+	obtained bool
+}
+
+func (lock *checkedLock) Obtain() (ok bool, err error) {
+	if ok, err = lock.lock.Obtain(); ok && err == nil {
+		lock.obtained = true
+		err = lock.factory.verify(lockVerifyObtained)
+	}
+	return
+}
+
+func (lock *checkedLock) IsLocked() bool {
+	return lock.lock.IsLocked()
+}
+
+func (lock *checkedLock) Close() error {
+	if !lock.obtained {
+		return lock.lock.Close()
+	}
+	lock.obtained = false
+	if err := lock.factory.verify(lockVerifyReleased); err != nil {
+		return err
+	}
+	return lock.lock.Close()
+}
+
+func (lock *checkedLock) String() string {
+	return fmt.Sprintf("CheckedLock@%v", lock.lock)
+}
+
+// store/LockVerifyServer.java
+
+/*
+Simple standalone server that must be running when you use
+VerifyingLockFactory. This server simply verifies at most one process
+holds the lock at a time.
+*/
+type LockVerifyServer struct {
+	listener net.Listener
+	sync.Locker
+	lockedID int32 // -1 if nobody holds the lock
+	err      error // first locking error found
+}
+
+/* Listens on the given TCP address, e.g. "127.0.0.1:0". */
+func NewLockVerifyServer(addr string) (*LockVerifyServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &LockVerifyServer{
+		listener: listener,
+		Locker:   &sync.Mutex{},
+		lockedID: -1,
+	}, nil
+}
+
+/* Returns the address clients should connect to. */
+func (s *LockVerifyServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+/*
+Waits for maxClients clients to connect, starts them all at once and
+verifies their lock messages until they disconnect. Returns the first
+locking error found, if any. The server is closed afterwards.
+*/
+func (s *LockVerifyServer) Serve(maxClients int) error {
+	defer s.listener.Close()
+
+	conns := make([]net.Conn, 0, maxClients)
+	ids := make([]int32, 0, maxClients)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for len(conns) < maxClients {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		var id int32
+		if err = binary.Read(conn, binary.BigEndian, &id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		if _, err := conn.Write([]byte{lockVerifyStart}); err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func(conn net.Conn, id int32) {
+			defer wg.Done()
+			s.handle(conn, id)
+		}(conn, ids[i])
+	}
+	wg.Wait()
+
+	s.Lock()
+	defer s.Unlock()
+	return s.err
+}
+
+func (s *LockVerifyServer) handle(conn net.Conn, id int32) {
+	var command [1]byte
+	for {
+		if _, err := io.ReadFull(conn, command[:]); err != nil {
+			return // client is done
+		}
+		if err := s.apply(id, command[0]); err != nil {
+			s.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.Unlock()
+			// closing the connection fails the client's verification
+			conn.Close()
+			return
+		}
+		if _, err := conn.Write(command[:]); err != nil {
+			return
+		}
+	}
+}
+
+func (s *LockVerifyServer) apply(id int32, command byte) error {
+	s.Lock() // synchronized
+	defer s.Unlock()
+	switch command {
+	case lockVerifyObtained:
+		if s.lockedID != -1 {
+			return fmt.Errorf("%v got lock, but %v already holds the lock", id, s.lockedID)
+		}
+		s.lockedID = id
+	case lockVerifyReleased:
+		if s.lockedID != id {
+			return fmt.Errorf("%v released the lock, but %v is the lock holder", id, s.lockedID)
+		}
+		s.lockedID = -1
+	default:
+		return fmt.Errorf("Unrecognized command: %v", command)
+	}
+	return nil
+}
+
+// store/LockStressTest.java
+
+/*
+Simple stress test client for a LockFactory: connects to the
+LockVerifyServer at verifierAddr as client myID, and then repeatedly
+obtains and releases the named lock through a VerifyingLockFactory
+wrapping lockFactory, count times, holding it for sleepTime each time.
+Run it from several processes (or goroutines, each with its own
+LockFactory) against the same lock directory.
+*/
+func LockStressTest(myID int32, verifierAddr string, lockFactory LockFactory,
+	lockName string, sleepTime time.Duration, count int) error {
+
+	conn, err := net.Dial("tcp", verifierAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = binary.Write(conn, binary.BigEndian, myID); err != nil {
+		return err
+	}
+	var start [1]byte
+	if _, err = io.ReadFull(conn, start[:]); err != nil {
+		return err
+	}
+	if start[0] != lockVerifyStart {
+		return errors.New("Protocol violation.")
+	}
+
+	verifyLF := NewVerifyingLockFactory(lockFactory, conn, conn)
+	for i := 0; i < count; i++ {
+		lock := verifyLF.Make(lockName)
+		obtained, err := lock.Obtain()
+		if err != nil {
+			return err
+		}
+		if obtained {
+			time.Sleep(sleepTime)
+		}
+		if err = lock.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -32,7 +32,7 @@ func TestDeterminizeSimple(t *testing.T) {
 	a2 := NewRegExpWithFlag("ݖ|+", NONE).ToAutomaton()
 	a := concatenate(a1, a2)
 	a = removeDeadStates(a)
-	a = determinize(a)
+	a = determinize(a, DEFAULT_MAX_DETERMINIZED_STATES)
 	assert(a.numStates() == 4)
 }
 
@@ -0,0 +1,153 @@
+package automaton
+
+import (
+	"unicode"
+)
+
+// util/automaton/LevenshteinAutomata.java
+
+// Maximum edit distance this package can generate an automaton for.
+const MAXIMUM_SUPPORTED_DISTANCE = 2
+
+/*
+Class to construct DFAs that match a word within some edit distance.
+
+Implements the algorithm described in: Schulz and Mihov: Fast String
+Correction with Levenshtein Automata.
+
+Instead of the precomputed parametric descriptions of the original,
+the DFA is obtained by determinizing the (small) Levenshtein NFA of
+the word, which is cheap for the supported distances.
+*/
+type LevenshteinAutomata struct {
+	// the input word, as code points
+	word []int
+
+	withTranspositions bool
+}
+
+/*
+Create a new LevenshteinAutomata for some input string. Optionally
+count transpositions as a primitive edit.
+*/
+func NewLevenshteinAutomata(input string, withTranspositions bool) *LevenshteinAutomata {
+	return &LevenshteinAutomata{
+		word:               codePoints(input),
+		withTranspositions: withTranspositions,
+	}
+}
+
+func codePoints(s string) []int {
+	ans := make([]int, 0, len(s))
+	for _, r := range s {
+		ans = append(ans, int(r))
+	}
+	return ans
+}
+
+/*
+Compute a DFA that accepts all strings within an edit distance of n.
+
+All automata have the following properties:
+
+  - They are deterministic (DFA).
+  - There are no transitions to dead states.
+
+Returns nil if n is larger than MAXIMUM_SUPPORTED_DISTANCE.
+*/
+func (la *LevenshteinAutomata) ToAutomaton(n int) *Automaton {
+	return la.ToAutomatonWithPrefix(n, "")
+}
+
+/*
+Compute a DFA that accepts all strings within an edit distance of n,
+matching the specified exact prefix first. The prefix is not counted
+as part of the word the edits are applied to.
+*/
+func (la *LevenshteinAutomata) ToAutomatonWithPrefix(n int, prefix string) *Automaton {
+	assert2(n >= 0, "n must be >= 0 (got %v)", n)
+	if n > MAXIMUM_SUPPORTED_DISTANCE {
+		return nil
+	}
+	a := la.buildNFA(n, codePoints(prefix))
+	if !a.deterministic {
+		a = determinize(a, DEFAULT_MAX_DETERMINIZED_STATES)
+	}
+	return removeDeadStates(a)
+}
+
+/*
+Builds the NFA. After the states matching the prefix, state (i, e)
+means that the first i code points of the word were consumed using e
+edits. Deletions are epsilon moves from (i, e) to (i+1, e+1); they
+are folded into the transitions of the source state, so the resulting
+automaton has no epsilons. With transpositions, an extra state per
+(i, e) remembers that word[i+1] was read in place of word[i].
+*/
+func (la *LevenshteinAutomata) buildNFA(n int, prefix []int) *Automaton {
+	a := newEmptyAutomaton()
+	for range prefix {
+		a.createState()
+	}
+
+	m := len(la.word)
+	offset := a.numStates()
+	state := func(i, e int) int { return offset + i*(n+1) + e }
+	for i := 0; i <= m; i++ {
+		for e := 0; e <= n; e++ {
+			a.createState()
+		}
+	}
+	var transposed []int
+	if la.withTranspositions && n > 0 {
+		transposed = make([]int, m*n)
+		for i := 0; i+1 < m; i++ {
+			for e := 0; e < n; e++ {
+				transposed[i*n+e] = a.createState()
+			}
+		}
+	}
+
+	// the exact prefix
+	for i, c := range prefix {
+		a.addTransition(i, i+1, c)
+	}
+
+	for i := 0; i <= m; i++ {
+		for e := 0; e <= n; e++ {
+			s := state(i, e)
+			// follow the deletions
+			for k := 0; i+k <= m && e+k <= n; k++ {
+				i2, e2 := i+k, e+k
+				if i2 == m {
+					a.setAccept(s, true)
+				} else {
+					a.addTransition(s, state(i2+1, e2), la.word[i2])
+				}
+				if e2 == n {
+					continue
+				}
+				// insertion
+				a.addTransitionRange(s, state(i2, e2+1), MIN_CODE_POINT, unicode.MaxRune)
+				if i2 < m {
+					// substitution
+					a.addTransitionRange(s, state(i2+1, e2+1), MIN_CODE_POINT, unicode.MaxRune)
+				}
+				if transposed != nil && i2+1 < m && la.word[i2] != la.word[i2+1] {
+					a.addTransition(s, transposed[i2*n+e2], la.word[i2+1])
+				}
+			}
+		}
+	}
+	if transposed != nil {
+		for i := 0; i+1 < m; i++ {
+			for e := 0; e < n; e++ {
+				if la.word[i] != la.word[i+1] {
+					a.addTransition(transposed[i*n+e], state(i+2, e+1), la.word[i])
+				}
+			}
+		}
+	}
+	a.finishState()
+	return a
+}
@@ -21,7 +21,7 @@ func minimizeHopcroft(a *Automaton) *Automaton {
 		// fastmatch for common case
 		return newEmptyAutomaton()
 	}
-	a = determinize(a)
+	a = determinize(a, DEFAULT_MAX_DETERMINIZED_STATES)
 	if a.numTransitions(0) == 1 {
 		t := newTransition()
 		a.transition(0, 0, t)
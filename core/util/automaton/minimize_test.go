@@ -54,7 +54,7 @@ func TestMinimize(t *testing.T) {
 	num := AtLeast(200)
 	for i := 0; i < num; i++ {
 		a := randomAutomaton(Random())
-		la := determinize(removeDeadStates(a))
+		la := determinize(removeDeadStates(a), DEFAULT_MAX_DETERMINIZED_STATES)
 		lb := minimize(a)
 		It(t).Should("have same language for %v and %v from %v", la, lb, a).
 			Verify(sameLanguage(la, lb))
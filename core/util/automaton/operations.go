@@ -188,7 +188,7 @@ the language of the given automaton.
 Complexity: linear in number of states (if already deterministic).
 */
 func complement(a *Automaton) *Automaton {
-	a = totalize(determinize(a))
+	a = totalize(determinize(a, DEFAULT_MAX_DETERMINIZED_STATES))
 	numStates := a.numStates()
 	for p := 0; p < numStates; p++ {
 		a.setAccept(p, !a.IsAccept(p))
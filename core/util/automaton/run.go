@@ -99,11 +99,8 @@ func NewCharacterRunAutomatonWithMaxDeterminedStates(a *Automaton, maxDeterminiz
 
 func (ca *CharacterRunAutomaton) Run(s string) bool {
 	p := ca.initial
-	l := len(s)
-	for i, cp := 0, 0; i < l; i++ {
-		cp = int(s[i])
-		p = ca.step(p, cp)
-
+	for _, cp := range s {
+		p = ca.step(p, int(cp))
 		if p == -1 {
 			return false
 		}
@@ -114,7 +111,7 @@ func (ca *CharacterRunAutomaton) Run(s string) bool {
 /**
  * Returns true if the given string is accepted by this automaton
  */
-func (ca *CharacterRunAutomaton) RunChars(s []rune) {
+func (ca *CharacterRunAutomaton) RunChars(s []rune) bool {
 	p := ca.initial
 	l := len(s)
 	for i, cp := 0, 0; i < l; i++ {
@@ -169,6 +169,18 @@ func (h *BytesRefHash) Sort(comp func(a, b []byte) bool) []int {
 	return compact
 }
 
+/*
+Populates and returns a BytesRef with the bytes for the given
+bytesID. Note: the given bytesID must be a positive integer less than
+the current size (Size())
+*/
+func (h *BytesRefHash) Get(bytesId int, ref *BytesRef) *BytesRef {
+	assert2(h.bytesStart != nil, "bytesStart is null - not initialized")
+	assert2(bytesId < len(h.bytesStart), "bytesID exceeds byteStart len: %v", len(h.bytesStart))
+	h.pool.SetBytesRef(ref, h.bytesStart[bytesId])
+	return ref
+}
+
 func (h *BytesRefHash) equals(id int, b []byte) bool {
 	h.pool.SetBytesRef(h.scratch1, h.bytesStart[id])
 	return h.scratch1.bytesEquals(b)
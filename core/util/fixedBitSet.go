@@ -1,5 +1,9 @@
 package util
 
+import (
+	"math/bits"
+)
+
 /*
 BitSet of fixed length (numBits), backed by accessible bits() []int64,
 accessed with an int index, implementing Bits and DocIdSet. Unlike
@@ -68,12 +72,65 @@ func (b *FixedBitSet) Cardinality() int {
 }
 
 func (b *FixedBitSet) At(index int) bool {
-	panic("not implemented yet")
+	assert2(index >= 0 && index < b.numBits, "index=%v, numBits=%v", index, b.numBits)
+	i := index >> 6 // div 64
+	// signed shift will keep a negative index and force an
+	// array-index-out-of-bounds-exception, removing the need for an
+	// explicit check.
+	bitmask := int64(1 << uint(index&0x3f))
+	return (b.bits[i] & bitmask) != 0
 }
 
 func (b *FixedBitSet) Set(index int) {
 	assert2(index >= 0 && index < b.numBits, "index=%v, numBits=%v", index, b.numBits)
 	wordNum := index >> 6 // div 64
-	bitmask := int64(1 << uint(index))
+	bitmask := int64(1 << uint(index&0x3f))
 	b.bits[wordNum] |= bitmask
 }
+
+/*
+Returns the index of the first set bit starting at the index
+specified. -1 is returned if there are no more set bits.
+*/
+func (b *FixedBitSet) NextSetBit(index int) int {
+	assert2(index >= 0 && index < b.numBits, "index=%v, numBits=%v", index, b.numBits)
+	i := index >> 6
+	subIndex := uint(index & 0x3f)               // index within the word
+	word := int64(uint64(b.bits[i]) >> subIndex) // skip all the bits to the right of index
+
+	if word != 0 {
+		return index + int(NumberOfTrailingZeros(word))
+	}
+
+	for i++; i < b.numWords; i++ {
+		word = b.bits[i]
+		if word != 0 {
+			return (i << 6) + int(NumberOfTrailingZeros(word))
+		}
+	}
+
+	return -1
+}
+
+/*
+Returns the index of the last set bit before or on the index
+specified. -1 is returned if there are no more set bits.
+*/
+func (b *FixedBitSet) PrevSetBit(index int) int {
+	assert2(index >= 0 && index < b.numBits, "index=%v, numBits=%v", index, b.numBits)
+	i := index >> 6
+	subIndex := uint(index & 0x3f)               // index within the word
+	word := uint64(b.bits[i]) << (63 - subIndex) // skip all the bits to the left of index
+
+	if word != 0 {
+		return (i << 6) + int(subIndex) - bits.LeadingZeros64(word)
+	}
+
+	for i--; i >= 0; i-- {
+		if word = uint64(b.bits[i]); word != 0 {
+			return (i << 6) + 63 - bits.LeadingZeros64(word)
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestFixedBitSetSet(t *testing.T) {
+	b := NewFixedBitSetOf(200)
+	set := []int{0, 1, 63, 64, 65, 127, 128, 130, 199}
+	for _, i := range set {
+		b.Set(i)
+	}
+	if n := b.Cardinality(); n != len(set) {
+		t.Errorf("expected %v bits set, but %v", len(set), n)
+	}
+	next := 0
+	for i := 0; i < 200; i++ {
+		expected := next < len(set) && set[next] == i
+		if expected {
+			next++
+		}
+		if b.At(i) != expected {
+			t.Errorf("bit %v: expected %v", i, expected)
+		}
+	}
+	for i, prev := 0, -1; i < 200; i++ {
+		if b.At(i) {
+			prev = i
+		}
+		if p := b.PrevSetBit(i); p != prev {
+			t.Errorf("PrevSetBit(%v): expected %v, but %v", i, prev, p)
+		}
+	}
+}
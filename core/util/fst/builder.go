@@ -103,7 +103,6 @@ func (b *Builder) compileNode(nodeIn *UnCompiledNode, tailLength int) (*Compiled
 			node, err = b.fst.addNode(b, nodeIn)
 		} else {
 			node, err = b.dedupHash.add(b, nodeIn)
-			b.lastFrozenNode = node
 		}
 	} else {
 		node, err = b.fst.addNode(b, nodeIn)
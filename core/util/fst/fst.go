@@ -70,7 +70,7 @@ type Arc struct {
 	numArcs         int
 }
 
-func (arc *Arc) copyFrom(other *Arc) *Arc {
+func (arc *Arc) CopyFrom(other *Arc) *Arc {
 	arc.Label = other.Label
 	arc.target = other.target
 	arc.flags = other.flags
@@ -90,7 +90,7 @@ func (arc *Arc) flag(flag byte) bool {
 	return hasFlag(arc.flags, flag)
 }
 
-func (arc *Arc) isLast() bool {
+func (arc *Arc) IsLast() bool {
 	return arc.flag(FST_BIT_LAST_ARC)
 }
 
@@ -306,11 +306,11 @@ func (t *FST) cacheRootArcs() error {
 		for {
 			assert(arc.Label != FST_END_LABEL)
 			if arc.Label < len(arcs) {
-				arcs[arc.Label] = (&Arc{}).copyFrom(arc)
+				arcs[arc.Label] = (&Arc{}).CopyFrom(arc)
 			} else {
 				break
 			}
-			if arc.isLast() {
+			if arc.IsLast() {
 				break
 			}
 
@@ -754,7 +754,7 @@ func (t *FST) readLastTargetArc(follow, arc *Arc, in BytesReader) (*Arc, error)
 		// non-array: linear scan
 		arc.bytesPerArc = 0
 		//System.out.println("  scan");
-		for !arc.isLast() {
+		for !arc.IsLast() {
 			// skip this arc:
 			t.readLabel(in)
 			if arc.flag(FST_BIT_ARC_HAS_OUTPUT) {
@@ -778,7 +778,7 @@ func (t *FST) readLastTargetArc(follow, arc *Arc, in BytesReader) (*Arc, error)
 		arc.nextArc = in.getPosition()
 	}
 	t.readNextRealArc(arc, in)
-	assert(arc.isLast())
+	assert(arc.IsLast())
 	return arc, nil
 
 }
@@ -787,7 +787,11 @@ func (t *FST) readUnpackedNodeTarget(in BytesReader) (target int64, err error) {
 	return in.ReadVLong()
 }
 
-func (t *FST) readFirstTargetArc(follow, arc *Arc, in BytesReader) (*Arc, error) {
+/*
+Follow the follow arc and read the first arc of its target; this
+changes the provided arc (2nd arg) in-place and returns it.
+*/
+func (t *FST) ReadFirstTargetArc(follow, arc *Arc, in BytesReader) (*Arc, error) {
 	if follow.IsFinal() {
 		// insert "fake" final first arc:
 		arc.Label = FST_END_LABEL
@@ -851,10 +855,11 @@ func (t *FST) isExpandedTarget(follow *Arc, in BytesReader) bool {
 	return flags == FST_ARCS_AS_ARRAY_PACKED || flags == FST_ARCS_AS_ARRAY_WITH_GAPS
 }
 
-func (t *FST) readNextArc(arc *Arc, in BytesReader) (*Arc, error) {
+/* In-place read; returns the arc. */
+func (t *FST) ReadNextArc(arc *Arc, in BytesReader) (*Arc, error) {
 	if arc.Label == FST_END_LABEL {
 		// this was a fake inserted "final" arc
-		assert2(arc.nextArc > 0, "cannot readNextArc when arc.isLast()=true")
+		assert2(arc.nextArc > 0, "cannot ReadNextArc when arc.IsLast()=true")
 		return t.readFirstRealTargetArc(arc.nextArc, arc, in)
 	} else {
 		return t.readNextRealArc(arc, in)
@@ -862,9 +867,9 @@ func (t *FST) readNextArc(arc *Arc, in BytesReader) (*Arc, error) {
 }
 
 /** Peeks at next arc's label; does not alter arc.  Do
- *  not call this if arc.isLast()! */
+ *  not call this if arc.IsLast()! */
 func (t *FST) readNextArcLabel(arc *Arc, in BytesReader) (int, error) {
-	assert(!arc.isLast())
+	assert(!arc.IsLast())
 
 	if arc.Label == FST_END_LABEL {
 		//System.out.println("    nextArc fake " +
@@ -956,7 +961,7 @@ func (t *FST) readArcByIndex(arc *Arc, in BytesReader, idx int) (*Arc, error) {
 }
 
 /** Never returns null, but you should never call this if
- *  arc.isLast() is true. */
+ *  arc.IsLast() is true. */
 func (t *FST) readNextRealArc(arc *Arc, in BytesReader) (*Arc, error) {
 	var err error
 	// TODO: can't assert this because we call from readFirstArc
@@ -1148,7 +1153,7 @@ func (t *FST) findTargetArc(labelToMatch int, follow *Arc, arc *Arc, in BytesRea
 		// modified previously returned cached root-arcs:
 		t.assertRootCachedArc(labelToMatch, result)
 		if result != nil {
-			arc.copyFrom(result)
+			arc.CopyFrom(result)
 			return arc, nil
 		}
 		return nil, nil
@@ -1167,15 +1172,16 @@ func (t *FST) findTargetArc(labelToMatch int, follow *Arc, arc *Arc, in BytesRea
 		return nil, err
 	}
 
-	arc.numArcs, err = AsInt(in.ReadVInt())
-	if err != nil {
-		return nil, err
-	}
-	arc.bytesPerArc, err = AsInt(in.ReadInt())
-	if err != nil {
-		return nil, err
+	if b == FST_ARCS_AS_ARRAY_PACKED || b == FST_ARCS_AS_ARRAY_WITH_GAPS {
+		if arc.numArcs, err = AsInt(in.ReadVInt()); err == nil {
+			if arc.bytesPerArc, err = AsInt(in.ReadVInt()); err == nil {
+				arc.posArcsStart = in.getPosition()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
-	arc.posArcsStart = in.getPosition()
 
 	if b == FST_ARCS_AS_ARRAY_WITH_GAPS {
 
@@ -1211,7 +1217,7 @@ func (t *FST) findTargetArc(labelToMatch int, follow *Arc, arc *Arc, in BytesRea
 	} else if b == FST_ARCS_AS_ARRAY_PACKED {
 		// Arcs are full array; do binary search:
 
-		for low, high := 0, arc.numArcs-1; low < high; {
+		for low, high := 0, arc.numArcs-1; low <= high; {
 			// log.Println("    cycle")
 			mid := int(uint(low+high) / 2)
 			in.setPosition(arc.posArcsStart)
@@ -1251,7 +1257,7 @@ func (t *FST) findTargetArc(labelToMatch int, follow *Arc, arc *Arc, in BytesRea
 			return arc, nil
 		} else if arc.Label > labelToMatch {
 			return nil, nil
-		} else if arc.isLast() {
+		} else if arc.IsLast() {
 			return nil, nil
 		} else {
 			_, err = t.readNextRealArc(arc, in)
@@ -1330,6 +1336,9 @@ func equals(a, b interface{}) bool {
 			return false
 		}
 		return a.(int64) == b.(int64)
+	} else if p1, ok := a.(*Pair); ok {
+		p2, ok := b.(*Pair)
+		return ok && equals(p1.Output1, p2.Output1) && equals(p1.Output2, p2.Output2)
 	} else if a == nil && b == nil {
 		return true
 	} else if sameType && a == b {
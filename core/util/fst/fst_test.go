@@ -0,0 +1,47 @@
+package fst
+
+import (
+	"github.com/jtejido/golucene/core/util"
+	"testing"
+)
+
+func TestFindTargetArcInArray(t *testing.T) {
+	outputs := PositiveIntOutputsSingleton()
+	b := NewDefaultBuilder(INPUT_TYPE_BYTE1, outputs)
+	// enough arcs at the root to be written as a fixed array
+	labels := "bcdefghijklm"
+	scratch := util.NewIntsRefBuilder()
+	for i, label := range []byte(labels) {
+		if err := b.Add(ToIntsRef([]byte{label}, scratch), outputs.Get(int64(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fst, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := fst.BytesReader()
+	root := fst.FirstArc(&Arc{})
+	for i, label := range []byte(labels) {
+		arc, err := fst.FindTargetArc(int(label), root, &Arc{}, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if arc == nil {
+			t.Errorf("%c: arc not found", label)
+			continue
+		}
+		if arc.Label != int(label) {
+			t.Errorf("%c: found arc %c", label, arc.Label)
+		}
+		if v := outputs.Value(outputs.Add(arc.Output, arc.NextFinalOutput)); v != int64(i+1) {
+			t.Errorf("%c: expected output %v, but %v", label, i+1, v)
+		}
+	}
+	for _, label := range []byte("anz") {
+		if arc, err := fst.FindTargetArc(int(label), root, &Arc{}, in); err != nil || arc != nil {
+			t.Errorf("%c: expected no arc, but %v %v", label, arc, err)
+		}
+	}
+}
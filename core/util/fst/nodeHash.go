@@ -35,14 +35,14 @@ func (nh *NodeHash) nodesEqual(node *UnCompiledNode, address int64) (bool, error
 	}
 	for arcUpto := 0; arcUpto < node.NumArcs; arcUpto++ {
 		if arc := node.Arcs[arcUpto]; arc.label != nh.scratchArc.Label ||
-			arc.output != nh.scratchArc.Output ||
+			!equals(arc.output, nh.scratchArc.Output) ||
 			arc.Target.(*CompiledNode).node != nh.scratchArc.target ||
-			arc.nextFinalOutput != nh.scratchArc.NextFinalOutput ||
+			!equals(arc.nextFinalOutput, nh.scratchArc.NextFinalOutput) ||
 			arc.isFinal != nh.scratchArc.IsFinal() {
 			return false, nil
 		}
 
-		if nh.scratchArc.isLast() {
+		if nh.scratchArc.IsLast() {
 			return arcUpto == node.NumArcs-1, nil
 		}
 		if _, err = nh.fst.readNextRealArc(nh.scratchArc, nh.in); err != nil {
@@ -96,7 +96,7 @@ func (nh *NodeHash) hashFrozen(node int64) (int64, error) {
 		if nh.scratchArc.IsFinal() {
 			h += 17
 		}
-		if nh.scratchArc.isLast() {
+		if nh.scratchArc.IsLast() {
 			break
 		}
 		if _, err = nh.fst.readNextRealArc(nh.scratchArc, nh.in); err != nil {
@@ -109,8 +109,15 @@ func (nh *NodeHash) hashFrozen(node int64) (int64, error) {
 
 func hashPtr(obj interface{}) (h int64) {
 	if obj != nil && obj != NO_OUTPUT {
-		for _, b := range obj.([]byte) {
-			h = PRIME*h + int64(b)
+		switch v := obj.(type) {
+		case []byte:
+			for _, b := range v {
+				h = PRIME*h + int64(b)
+			}
+		case int64:
+			h = v ^ (v >> 32)
+		case *Pair:
+			h = PRIME*hashPtr(v.Output1) + hashPtr(v.Output2)
 		}
 	}
 	return
@@ -139,7 +146,9 @@ func (nh *NodeHash) add(builder *Builder, nodeIn *UnCompiledNode) (int64, error)
 			nh.table.Set(pos, node)
 			// rehash at 2/3 occupancy:
 			if nh.count > 2*nh.table.Size()/3 {
-				panic("not implemented yet")
+				if err = nh.rehash(); err != nil {
+					return 0, err
+				}
 			}
 			return node, nil
 		} else {
@@ -158,3 +167,37 @@ func (nh *NodeHash) add(builder *Builder, nodeIn *UnCompiledNode) (int64, error)
 		pos = (pos + c) & nh.mask
 	}
 }
+
+/* called only by rehash */
+func (nh *NodeHash) addNew(address int64) error {
+	h, err := nh.hashFrozen(address)
+	if err != nil {
+		return err
+	}
+	pos := h & nh.mask
+	c := int64(0)
+	for {
+		if nh.table.Get(pos) == 0 {
+			nh.table.Set(pos, address)
+			return nil
+		}
+		// quadratic probe
+		c++
+		pos = (pos + c) & nh.mask
+	}
+}
+
+func (nh *NodeHash) rehash() error {
+	oldTable := nh.table
+	nh.table = packed.NewPagedGrowableWriter(2*oldTable.Size(), 1<<30,
+		packed.BitsRequired(nh.count), packed.PackedInts.COMPACT)
+	nh.mask = nh.table.Size() - 1
+	for idx := int64(0); idx < oldTable.Size(); idx++ {
+		if address := oldTable.Get(idx); address != 0 {
+			if err := nh.addNew(address); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
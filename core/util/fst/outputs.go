@@ -223,6 +223,9 @@ func (out *ByteSequenceOutputs) Add(_prefix interface{}, _output interface{}) in
 
 func (o *ByteSequenceOutputs) Write(obj interface{}, out util.DataOutput) error {
 	assert(obj != nil)
+	if obj == NO_OUTPUT {
+		return out.WriteVInt(0)
+	}
 	prefix, ok := obj.([]byte)
 	assert(ok)
 	err := out.WriteVInt(int32(len(prefix)))
@@ -266,6 +269,227 @@ func (out *ByteSequenceOutputs) ramBytesUsed(output interface{}) int64 {
 	return BASE_NUM_BYTES + util.SizeOf(output.([]byte))
 }
 
+// fst/PositiveIntOutputs.java
+
+/*
+An FST Outputs implementation where each output is a non-negative
+int64 value. Outputs are stored as int64, except 0 which is always
+represented by NO_OUTPUT.
+*/
+type PositiveIntOutputs struct {
+	*abstractOutputs
+}
+
+var onePositiveIntOutputs *PositiveIntOutputs
+
+func PositiveIntOutputsSingleton() *PositiveIntOutputs {
+	if onePositiveIntOutputs == nil {
+		onePositiveIntOutputs = &PositiveIntOutputs{}
+		onePositiveIntOutputs.abstractOutputs = &abstractOutputs{onePositiveIntOutputs}
+	}
+	return onePositiveIntOutputs
+}
+
+/* Returns the output representing v, which must not be negative. */
+func (out *PositiveIntOutputs) Get(v int64) interface{} {
+	assert2(v >= 0, "output must be >= 0, got %v", v)
+	if v == 0 {
+		return NO_OUTPUT
+	}
+	return v
+}
+
+/* Returns the value of an output of this Outputs. */
+func (out *PositiveIntOutputs) Value(output interface{}) int64 {
+	if output == NO_OUTPUT {
+		return 0
+	}
+	return output.(int64)
+}
+
+func (out *PositiveIntOutputs) Common(output1, output2 interface{}) interface{} {
+	assert(out.valid(output1))
+	assert(out.valid(output2))
+	if output1 == NO_OUTPUT || output2 == NO_OUTPUT {
+		return NO_OUTPUT
+	}
+	if v1, v2 := output1.(int64), output2.(int64); v2 < v1 {
+		return v2
+	} else {
+		return v1
+	}
+}
+
+func (out *PositiveIntOutputs) Subtract(output, inc interface{}) interface{} {
+	assert(out.valid(output))
+	assert(out.valid(inc))
+	assert(out.Value(output) >= out.Value(inc))
+	if inc == NO_OUTPUT {
+		return output
+	}
+	return out.Get(output.(int64) - inc.(int64))
+}
+
+func (out *PositiveIntOutputs) Add(prefix, output interface{}) interface{} {
+	assert(out.valid(prefix))
+	assert(out.valid(output))
+	if prefix == NO_OUTPUT {
+		return output
+	} else if output == NO_OUTPUT {
+		return prefix
+	}
+	return prefix.(int64) + output.(int64)
+}
+
+func (out *PositiveIntOutputs) Write(output interface{}, o util.DataOutput) error {
+	assert(out.valid(output))
+	return o.WriteVLong(out.Value(output))
+}
+
+func (out *PositiveIntOutputs) Read(in util.DataInput) (interface{}, error) {
+	v, err := in.ReadVLong()
+	if err != nil {
+		return nil, err
+	}
+	return out.Get(v), nil
+}
+
+func (out *PositiveIntOutputs) valid(o interface{}) bool {
+	if o == NO_OUTPUT {
+		return true
+	}
+	v, ok := o.(int64)
+	return ok && v > 0
+}
+
+func (out *PositiveIntOutputs) NoOutput() interface{} {
+	return NO_OUTPUT
+}
+
+func (out *PositiveIntOutputs) outputToString(output interface{}) string {
+	return fmt.Sprintf("%v", out.Value(output))
+}
+
+func (out *PositiveIntOutputs) ramBytesUsed(output interface{}) int64 {
+	return 8
+}
+
+func (out *PositiveIntOutputs) String() string {
+	return "PositiveIntOutputs"
+}
+
+// fst/PairOutputs.java
+
+/* Holds a single pair of two outputs. */
+type Pair struct {
+	Output1, Output2 interface{}
+}
+
+func (p *Pair) String() string {
+	return fmt.Sprintf("Pair(%v,%v)", p.Output1, p.Output2)
+}
+
+/*
+An FST Outputs implementation, holding two other outputs. The pair
+with no output on both sides is represented by NO_OUTPUT.
+*/
+type PairOutputs struct {
+	*abstractOutputs
+	outputs1, outputs2 Outputs
+}
+
+func NewPairOutputs(outputs1, outputs2 Outputs) *PairOutputs {
+	ans := &PairOutputs{outputs1: outputs1, outputs2: outputs2}
+	ans.abstractOutputs = &abstractOutputs{ans}
+	return ans
+}
+
+/* Create a new Pair */
+func (out *PairOutputs) NewPair(a, b interface{}) interface{} {
+	if equals(a, out.outputs1.NoOutput()) {
+		a = out.outputs1.NoOutput()
+	}
+	if equals(b, out.outputs2.NoOutput()) {
+		b = out.outputs2.NoOutput()
+	}
+	if a == out.outputs1.NoOutput() && b == out.outputs2.NoOutput() {
+		return NO_OUTPUT
+	}
+	return &Pair{a, b}
+}
+
+/* Returns the two outputs held by an output of this Outputs. */
+func (out *PairOutputs) Split(output interface{}) (a, b interface{}) {
+	if output == NO_OUTPUT {
+		return out.outputs1.NoOutput(), out.outputs2.NoOutput()
+	}
+	p := output.(*Pair)
+	return p.Output1, p.Output2
+}
+
+func (out *PairOutputs) Common(pair1, pair2 interface{}) interface{} {
+	a1, b1 := out.Split(pair1)
+	a2, b2 := out.Split(pair2)
+	return out.NewPair(out.outputs1.Common(a1, a2), out.outputs2.Common(b1, b2))
+}
+
+func (out *PairOutputs) Subtract(output, inc interface{}) interface{} {
+	a1, b1 := out.Split(output)
+	a2, b2 := out.Split(inc)
+	return out.NewPair(out.outputs1.Subtract(a1, a2), out.outputs2.Subtract(b1, b2))
+}
+
+func (out *PairOutputs) Add(prefix, output interface{}) interface{} {
+	a1, b1 := out.Split(prefix)
+	a2, b2 := out.Split(output)
+	return out.NewPair(out.outputs1.Add(a1, a2), out.outputs2.Add(b1, b2))
+}
+
+func (out *PairOutputs) Write(output interface{}, o util.DataOutput) error {
+	a, b := out.Split(output)
+	if err := out.outputs1.Write(a, o); err != nil {
+		return err
+	}
+	return out.outputs2.Write(b, o)
+}
+
+func (out *PairOutputs) Read(in util.DataInput) (interface{}, error) {
+	a, err := out.outputs1.Read(in)
+	if err != nil {
+		return nil, err
+	}
+	b, err := out.outputs2.Read(in)
+	if err != nil {
+		return nil, err
+	}
+	return out.NewPair(a, b), nil
+}
+
+func (out *PairOutputs) NoOutput() interface{} {
+	return NO_OUTPUT
+}
+
+func (out *PairOutputs) outputToString(output interface{}) string {
+	a, b := out.Split(output)
+	return fmt.Sprintf("<pair:%v,%v>", out.outputs1.outputToString(a), out.outputs2.outputToString(b))
+}
+
+func (out *PairOutputs) ramBytesUsed(output interface{}) int64 {
+	a, b := out.Split(output)
+	var n int64
+	if a != NO_OUTPUT {
+		n += out.outputs1.ramBytesUsed(a)
+	}
+	if b != NO_OUTPUT {
+		n += out.outputs2.ramBytesUsed(b)
+	}
+	return n
+}
+
+func (out *PairOutputs) String() string {
+	return fmt.Sprintf("PairOutputs<%v,%v>", out.outputs1, out.outputs2)
+}
+
 // util/fst/Util.java
 
 /** Looks up the output for this input, or null if the
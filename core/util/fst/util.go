@@ -1,7 +1,9 @@
 package fst
 
 import (
+	"fmt"
 	"github.com/jtejido/golucene/core/util"
+	"sort"
 )
 
 // fst/Util.java
@@ -14,3 +16,262 @@ func ToIntsRef(input []byte, scratch *util.IntsRefBuilder) *util.IntsRef {
 	}
 	return scratch.Get()
 }
+
+/* Represents a path in TopNSearcher. */
+type FSTPath struct {
+	Arc   *Arc
+	Cost  interface{}
+	Input *util.IntsRefBuilder
+}
+
+func newFSTPath(cost interface{}, arc *Arc, input *util.IntsRefBuilder) *FSTPath {
+	return &FSTPath{new(Arc).CopyFrom(arc), cost, input}
+}
+
+func (p *FSTPath) String() string {
+	return fmt.Sprintf("input=%v cost=%v", p.Input.Get().Value(), p.Cost)
+}
+
+/*
+Utility class to find top N shortest paths from start point(s). The
+comparator orders outputs by cost; it returns a negative number, zero
+or a positive number when the first output costs less, the same or
+more than the second. Ties are broken by the inputs.
+*/
+type TopNSearcher struct {
+	fst           *FST
+	bytesReader   BytesReader
+	topN          int
+	maxQueueDepth int
+	scratchArc    *Arc
+	comparator    func(a, b interface{}) int
+
+	// sorted by cost then input; nil once no longer needed
+	queue []*FSTPath
+
+	// Optional check of a completed path; a rejected path doesn't
+	// count toward the topN results.
+	AcceptResult func(input *util.IntsRef, output interface{}) bool
+}
+
+/*
+Creates an unbounded TopNSearcher. maxQueueDepth is the maximum size
+of the queue of possible top entries; it should be at least topN,
+and larger if AcceptResult may reject paths.
+*/
+func NewTopNSearcher(fst *FST, topN, maxQueueDepth int,
+	comparator func(a, b interface{}) int) *TopNSearcher {
+
+	return &TopNSearcher{
+		fst:           fst,
+		bytesReader:   fst.BytesReader(),
+		topN:          topN,
+		maxQueueDepth: maxQueueDepth,
+		scratchArc:    new(Arc),
+		comparator:    comparator,
+		queue:         make([]*FSTPath, 0, maxQueueDepth+1),
+	}
+}
+
+func (s *TopNSearcher) less(a, b *FSTPath) bool {
+	if cmp := s.comparator(a.Cost, b.Cost); cmp != 0 {
+		return cmp < 0
+	}
+	return a.Input.Get().Less(b.Input.Get())
+}
+
+// If back plus this arc is competitive then add to queue:
+func (s *TopNSearcher) addIfCompetitive(path *FSTPath) {
+	assert(s.queue != nil)
+
+	cost := s.fst.Outputs.Add(path.Cost, path.Arc.Output)
+
+	// copy over the current input to the new input and add the
+	// arc.label to the end
+	newInput := util.NewIntsRefBuilder()
+	newInput.CopyInts(path.Input.Get())
+	newInput.Append(path.Arc.Label)
+	newPath := newFSTPath(cost, path.Arc, newInput)
+
+	if len(s.queue) == s.maxQueueDepth {
+		if bottom := s.queue[len(s.queue)-1]; !s.less(newPath, bottom) {
+			return
+		}
+		// competes
+	} // else queue isn't full yet, so any path we hit competes
+
+	pos := sort.Search(len(s.queue), func(i int) bool {
+		return s.less(newPath, s.queue[i])
+	})
+	s.queue = append(s.queue, nil)
+	copy(s.queue[pos+1:], s.queue[pos:])
+	s.queue[pos] = newPath
+
+	if len(s.queue) == s.maxQueueDepth+1 {
+		s.queue = s.queue[:s.maxQueueDepth]
+	}
+}
+
+/*
+Adds all leaving arcs, including 'finished' arc, if the node is
+final, from this node into the queue.
+*/
+func (s *TopNSearcher) AddStartPaths(node *Arc, startOutput interface{},
+	allowEmptyString bool, input *util.IntsRefBuilder) (err error) {
+
+	// De-dup NO_OUTPUT since it must be a singleton:
+	if equals(startOutput, s.fst.Outputs.NoOutput()) {
+		startOutput = s.fst.Outputs.NoOutput()
+	}
+	if input == nil {
+		input = util.NewIntsRefBuilder()
+	}
+
+	path := newFSTPath(startOutput, node, input)
+	if _, err = s.fst.ReadFirstTargetArc(node, path.Arc, s.bytesReader); err != nil {
+		return
+	}
+
+	// Bootstrap: find the min starting arc
+	for {
+		if allowEmptyString || path.Arc.Label != FST_END_LABEL {
+			s.addIfCompetitive(path)
+		}
+		if path.Arc.IsLast() {
+			break
+		}
+		if _, err = s.fst.ReadNextArc(path.Arc, s.bytesReader); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+func (s *TopNSearcher) Search() (*TopResults, error) {
+	var results []*Result
+
+	fstReader := s.fst.BytesReader()
+	noOutput := s.fst.Outputs.NoOutput()
+
+	// TODO: we could enable FST to sorting arcs by weight as it
+	// freezes... can easily do this on first pass (w/o requiring
+	// rewrite)
+
+	rejectCount := 0
+
+	// For each top N path:
+	for len(results) < s.topN {
+		if len(s.queue) == 0 {
+			break
+		}
+
+		// Remove top path since we are now going to pursue it:
+		path := s.queue[0]
+		s.queue = s.queue[1:]
+
+		if path.Arc.Label == FST_END_LABEL {
+			// Empty string!
+			path.Input.Get().Length--
+			results = append(results, &Result{path.Input.Get(), path.Cost})
+			continue
+		}
+
+		if len(results) == s.topN-1 && s.maxQueueDepth == s.topN {
+			// Last path -- don't bother w/ queue anymore:
+			s.queue = nil
+		}
+
+		// We take path and find its "0 output completion", ie, just
+		// keep traversing the first arc with NO_OUTPUT that we can
+		// find, since this must lead to the minimum path that
+		// completes from path.arc.
+
+		// For each input letter:
+		for {
+			if _, err := s.fst.ReadFirstTargetArc(path.Arc, path.Arc, fstReader); err != nil {
+				return nil, err
+			}
+
+			// For each arc leaving this node:
+			foundZero := false
+			for {
+				// tricky: instead of comparing output == 0, we must express
+				// it via the comparator compare(output, 0) == 0
+				if s.comparator(noOutput, path.Arc.Output) == 0 {
+					if s.queue == nil {
+						foundZero = true
+						break
+					} else if !foundZero {
+						s.scratchArc.CopyFrom(path.Arc)
+						foundZero = true
+					} else {
+						s.addIfCompetitive(path)
+					}
+				} else if s.queue != nil {
+					s.addIfCompetitive(path)
+				}
+				if path.Arc.IsLast() {
+					break
+				}
+				if _, err := s.fst.ReadNextArc(path.Arc, fstReader); err != nil {
+					return nil, err
+				}
+			}
+
+			assert(foundZero)
+
+			if s.queue != nil {
+				// TODO: maybe we can save this CopyFrom if we are more
+				// clever above... eg on finding the first NO_OUTPUT arc
+				// we'd switch to using scratchArc
+				path.Arc.CopyFrom(s.scratchArc)
+			}
+
+			path.Cost = s.fst.Outputs.Add(path.Cost, path.Arc.Output)
+			if path.Arc.Label == FST_END_LABEL {
+				// Add final output:
+				if s.AcceptResult == nil || s.AcceptResult(path.Input.Get(), path.Cost) {
+					results = append(results, &Result{path.Input.Get(), path.Cost})
+				} else {
+					rejectCount++
+				}
+				break
+			}
+			path.Input.Append(path.Arc.Label)
+		}
+	}
+	return &TopResults{rejectCount+s.topN <= s.maxQueueDepth, results}, nil
+}
+
+/* Holds a single input (IntsRef) + output, returned by ShortestPaths(). */
+type Result struct {
+	Input  *util.IntsRef
+	Output interface{}
+}
+
+/* Holds the results for a top N search using TopNSearcher */
+type TopResults struct {
+	// true iff this is a complete result ie. if the specified queue
+	// size was large enough to find the complete list of results.
+	// This might be false if AcceptResult rejected too many results.
+	IsComplete bool
+	// The top results
+	TopN []*Result
+}
+
+/* Starting from node, find the top N min cost completions to a final node. */
+func ShortestPaths(fst *FST, fromNode *Arc, startOutput interface{},
+	comparator func(a, b interface{}) int, topN int,
+	allowEmptyString bool) (*TopResults, error) {
+
+	// All paths are kept, so we can pass topN for maxQueueDepth and
+	// the pruning is admissible:
+	searcher := NewTopNSearcher(fst, topN, topN, comparator)
+
+	// since this search is initialized with a single start node it is
+	// okay to start with an empty input path here
+	if err := searcher.AddStartPaths(fromNode, startOutput, allowEmptyString, nil); err != nil {
+		return nil, err
+	}
+	return searcher.Search()
+}
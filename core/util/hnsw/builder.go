@@ -0,0 +1,168 @@
+package hnsw
+
+import (
+	. "github.com/jtejido/golucene/core/index/model"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// util/hnsw/HnswGraphBuilder.java
+
+const (
+	// Default number of neighbors of a node on upper levels; nodes have
+	// twice as many on level 0.
+	DEFAULT_MAX_CONN = 16
+	// Default number of candidate neighbors explored when adding a node.
+	DEFAULT_BEAM_WIDTH = 100
+	// Default seed of the random levels of nodes, so that graphs are
+	// reproducible.
+	DEFAULT_SEED = 42
+)
+
+/* Builds a Graph over all vectors of a VectorValues. */
+type Builder struct {
+	vectors   VectorValues
+	sim       VectorSimilarityFunction
+	maxConn   int
+	beamWidth int
+	ml        float64 // normalization factor of the random levels
+	random    *rand.Rand
+	graph     *Graph
+}
+
+func NewBuilder(vectors VectorValues, sim VectorSimilarityFunction,
+	maxConn, beamWidth int, seed int64) *Builder {
+
+	assert2(maxConn > 1, "maxConn must be > 1, got %v", maxConn)
+	assert2(beamWidth > 0, "beamWidth must be > 0, got %v", beamWidth)
+	return &Builder{
+		vectors:   vectors,
+		sim:       sim,
+		maxConn:   maxConn,
+		beamWidth: beamWidth,
+		ml:        1 / math.Log(float64(maxConn)),
+		random:    rand.New(rand.NewSource(seed)),
+		graph:     &Graph{entryNode: -1},
+	}
+}
+
+/* Adds all vectors to the graph, in ordinal order, and returns it. */
+func (b *Builder) Build() *Graph {
+	for node := b.graph.Size(); node < b.vectors.Size(); node++ {
+		b.addNode(node)
+	}
+	return b.graph
+}
+
+func (b *Builder) randomLevel() int {
+	return int(-math.Log(1-b.random.Float64()) * b.ml)
+}
+
+func (b *Builder) addNode(node int) {
+	level := b.randomLevel()
+	b.graph.neighbors = append(b.graph.neighbors, make([][]int, level+1))
+	if b.graph.entryNode < 0 {
+		b.graph.entryNode = node
+		return
+	}
+
+	s := &searcher{
+		target:       b.vectors.Vector(node),
+		vectors:      b.vectors,
+		sim:          b.sim,
+		graph:        b.graph,
+		visitedLimit: math.MaxInt32,
+	}
+	top := b.graph.Levels(b.graph.entryNode) - 1
+	entryPoints := s.descend(top, level)
+	for l := minInt(level, top); l >= 0; l-- {
+		candidates := s.searchLevel(entryPoints, b.beamWidth, l, nil).drain()
+		b.connect(node, l, candidates)
+		entryPoints = entryPoints[:0]
+		for _, c := range candidates {
+			entryPoints = append(entryPoints, c.Node)
+		}
+	}
+	if level > top {
+		b.graph.entryNode = node
+	}
+}
+
+/*
+Links node to a diverse selection of its candidate neighbors on a
+level, and back, pruning the neighbors of the latter if they got too
+many.
+*/
+func (b *Builder) connect(node, level int, candidates []Neighbor) {
+	maxConn := b.maxConn
+	if level == 0 {
+		maxConn *= 2
+	}
+	selected := b.diverse(candidates, maxConn)
+	b.graph.neighbors[node][level] = nodesOf(selected)
+
+	for _, n := range selected {
+		neighbors := append(b.graph.neighbors[n.Node][level], node)
+		if len(neighbors) > maxConn {
+			base := b.vectors.Vector(n.Node)
+			scored := make([]Neighbor, len(neighbors))
+			for i, m := range neighbors {
+				scored[i] = Neighbor{m, b.sim.Compare(base, b.vectors.Vector(m))}
+			}
+			sort.Slice(scored, func(i, j int) bool { return nearer(scored[i], scored[j]) })
+			neighbors = nodesOf(b.diverse(scored, maxConn))
+		}
+		b.graph.neighbors[n.Node][level] = neighbors
+	}
+}
+
+/*
+Selects up to max of the candidates, given nearest first: a candidate
+is diverse if it's nearer to the base node than to any candidate
+selected before it. The slots left are filled with the nearest
+candidates that weren't diverse, to keep the graph well connected.
+*/
+func (b *Builder) diverse(candidates []Neighbor, max int) []Neighbor {
+	var selected, rejected []Neighbor
+	for _, c := range candidates {
+		if len(selected) >= max {
+			break
+		}
+		v := b.vectors.Vector(c.Node)
+		ok := true
+		for _, s := range selected {
+			if b.sim.Compare(v, b.vectors.Vector(s.Node)) > c.Score {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			selected = append(selected, c)
+		} else {
+			rejected = append(rejected, c)
+		}
+	}
+	for _, c := range rejected {
+		if len(selected) >= max {
+			break
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+func nodesOf(neighbors []Neighbor) []int {
+	ans := make([]int, len(neighbors))
+	for i, n := range neighbors {
+		ans[i] = n.Node
+	}
+	return ans
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
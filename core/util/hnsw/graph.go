@@ -0,0 +1,245 @@
+package hnsw
+
+import (
+	"container/heap"
+	"fmt"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// util/hnsw/HnswGraph.java
+
+/*
+Hierarchical Navigable Small World graph over the vectors of a field,
+as described by Malkov and Yashunin, "Efficient and robust approximate
+nearest neighbor search using Hierarchical Navigable Small World
+graphs" (2016).
+
+Nodes are the ordinals of the vectors. Every node is on level 0, and
+on each upper level with an exponentially decreasing probability. A
+search starts from the entry node, on the top level, and greedily
+moves to nearer nodes, level by level.
+*/
+type Graph struct {
+	entryNode int
+	neighbors [][][]int // by node, then by level
+}
+
+/*
+Returns a graph given the neighbors of each node by level, and the
+entry node, which must be on the top level. The entry node is ignored
+if there's no node.
+*/
+func NewGraph(entryNode int, neighbors [][][]int) *Graph {
+	if len(neighbors) == 0 {
+		entryNode = -1
+	} else {
+		assert2(entryNode >= 0 && entryNode < len(neighbors), "invalid entry node: %v", entryNode)
+		for node, levels := range neighbors {
+			assert2(len(levels) > 0, "node %v has no level", node)
+			assert2(len(levels) <= len(neighbors[entryNode]),
+				"node %v is above the entry node %v", node, entryNode)
+		}
+	}
+	return &Graph{entryNode, neighbors}
+}
+
+/* Number of nodes */
+func (g *Graph) Size() int {
+	return len(g.neighbors)
+}
+
+/* Returns the node every search starts from, or -1 if the graph is empty. */
+func (g *Graph) EntryNode() int {
+	return g.entryNode
+}
+
+/* Returns the number of levels the node is on. */
+func (g *Graph) Levels(node int) int {
+	return len(g.neighbors[node])
+}
+
+/* Returns the neighbors of a node on a level it's on. */
+func (g *Graph) Neighbors(node, level int) []int {
+	return g.neighbors[node][level]
+}
+
+func (g *Graph) String() string {
+	levels := 0
+	if g.entryNode >= 0 {
+		levels = g.Levels(g.entryNode)
+	}
+	return fmt.Sprintf("HnswGraph(size=%v, levels=%v)", g.Size(), levels)
+}
+
+/* A node of a graph, scored against a search target. */
+type Neighbor struct {
+	Node  int
+	Score float32
+}
+
+/*
+Returns true if a is nearer than b to the target; ties are broken by
+node, so that results are deterministic.
+*/
+func nearer(a, b Neighbor) bool {
+	return a.Score > b.Score || a.Score == b.Score && a.Node < b.Node
+}
+
+/* Heap of neighbors, with the nearest on top if nearestFirst, the farthest otherwise. */
+type neighborQueue struct {
+	neighbors    []Neighbor
+	nearestFirst bool
+}
+
+func (q *neighborQueue) Len() int { return len(q.neighbors) }
+
+func (q *neighborQueue) Less(i, j int) bool {
+	if q.nearestFirst {
+		return nearer(q.neighbors[i], q.neighbors[j])
+	}
+	return nearer(q.neighbors[j], q.neighbors[i])
+}
+
+func (q *neighborQueue) Swap(i, j int) {
+	q.neighbors[i], q.neighbors[j] = q.neighbors[j], q.neighbors[i]
+}
+
+func (q *neighborQueue) Push(x interface{}) {
+	q.neighbors = append(q.neighbors, x.(Neighbor))
+}
+
+func (q *neighborQueue) Pop() interface{} {
+	n := len(q.neighbors) - 1
+	ans := q.neighbors[n]
+	q.neighbors = q.neighbors[:n]
+	return ans
+}
+
+func (q *neighborQueue) top() Neighbor {
+	return q.neighbors[0]
+}
+
+/* Empties the queue, returning its neighbors nearest first. */
+func (q *neighborQueue) drain() []Neighbor {
+	ans := make([]Neighbor, q.Len())
+	for i := range ans {
+		if q.nearestFirst {
+			ans[i] = heap.Pop(q).(Neighbor)
+		} else {
+			ans[len(ans)-1-i] = heap.Pop(q).(Neighbor)
+		}
+	}
+	return ans
+}
+
+// util/hnsw/HnswGraphSearcher.java
+
+type searcher struct {
+	target       []float32
+	vectors      VectorValues
+	sim          VectorSimilarityFunction
+	graph        *Graph
+	visited      int
+	visitedLimit int
+	incomplete   bool
+}
+
+func (s *searcher) score(node int) Neighbor {
+	return Neighbor{node, s.sim.Compare(s.target, s.vectors.Vector(node))}
+}
+
+/*
+Returns the ef nearest nodes to the target on a level, among those
+accepted, exploring the graph from the entry points. Nodes not
+accepted are still explored, so that they don't disconnect the graph.
+*/
+func (s *searcher) searchLevel(entryPoints []int, ef, level int, accept util.Bits) *neighborQueue {
+	results := &neighborQueue{}
+	candidates := &neighborQueue{nearestFirst: true}
+	visited := make(map[int]bool)
+
+	add := func(node int) {
+		visited[node] = true
+		if s.visited >= s.visitedLimit {
+			s.incomplete = true
+			return
+		}
+		s.visited++
+		n := s.score(node)
+		if results.Len() < ef || nearer(n, results.top()) {
+			heap.Push(candidates, n)
+			if accept == nil || accept.At(node) {
+				heap.Push(results, n)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	for _, node := range entryPoints {
+		if !visited[node] && !s.incomplete {
+			add(node)
+		}
+	}
+	for candidates.Len() > 0 && !s.incomplete {
+		c := heap.Pop(candidates).(Neighbor)
+		if results.Len() >= ef && nearer(results.top(), c) {
+			break
+		}
+		for _, node := range s.graph.Neighbors(c.Node, level) {
+			if !visited[node] {
+				if add(node); s.incomplete {
+					break
+				}
+			}
+		}
+	}
+	return results
+}
+
+/*
+Returns the (approximately) k nearest vectors to target, nearest
+first, among the ordinals in acceptOrds, or all of them if it's nil.
+At most visitedLimit vectors are compared to target; complete is false
+if the search was stopped by this limit, in which case the results so
+far are returned.
+*/
+func Search(target []float32, k int, vectors VectorValues, sim VectorSimilarityFunction,
+	graph *Graph, acceptOrds util.Bits, visitedLimit int) (results []Neighbor, complete bool) {
+
+	assert2(len(target) == vectors.Dimension(),
+		"target dimension %v differs from the vectors dimension %v", len(target), vectors.Dimension())
+	if k <= 0 || graph.Size() == 0 {
+		return nil, true
+	}
+	s := &searcher{target: target, vectors: vectors, sim: sim, graph: graph, visitedLimit: visitedLimit}
+	entryPoints := s.descend(graph.Levels(graph.EntryNode())-1, 0)
+	if s.incomplete {
+		return nil, false
+	}
+	q := s.searchLevel(entryPoints, k, 0, acceptOrds)
+	return q.drain(), !s.incomplete
+}
+
+/*
+Greedily moves from the entry node to the node nearest to the target
+on each level from top down to above bottom; returns the entry points
+of level bottom.
+*/
+func (s *searcher) descend(top, bottom int) []int {
+	entryPoints := []int{s.graph.EntryNode()}
+	for level := top; level > bottom && !s.incomplete; level-- {
+		if q := s.searchLevel(entryPoints, 1, level, nil); q.Len() > 0 {
+			entryPoints = []int{q.top().Node}
+		}
+	}
+	return entryPoints
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
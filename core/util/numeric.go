@@ -1,9 +1,187 @@
 package util
 
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
 // util/NumericUtils.java
 
 const (
 	// The default precision step used by LongField, DoubleField,
 	// NumericTokenStream, NumericRangeQuery, and NumericRangeFilter.
 	NUMERIC_PRECISION_STEP_DEFAULT = 16
+
+	// Longs are stored at lower precision by shifting off lower bits.
+	// The shift count is stored as SHIFT_START_LONG+shift in the first
+	// byte
+	SHIFT_START_LONG = 0x20
+	// The maximum term length (used for byte buffer allocation) for
+	// encoding long values.
+	BUF_SIZE_LONG = 63/7 + 2
+
+	// Integers are stored at lower precision by shifting off lower
+	// bits. The shift count is stored as SHIFT_START_INT+shift in the
+	// first byte
+	SHIFT_START_INT = 0x60
+	// The maximum term length (used for byte buffer allocation) for
+	// encoding int values.
+	BUF_SIZE_INT = 31/7 + 2
 )
+
+/*
+Returns prefix coded bits after reducing the precision by shift bits.
+This method is used by NumericTokenStream. After encoding, bytes
+contains the encoded value. Only 7 bits are used per byte, so the
+encoded terms are also valid UTF-8 (and ASCII), and sort in the
+order of the values.
+*/
+func LongToPrefixCoded(val int64, shift int, bytes *BytesRefBuilder) {
+	assert2(shift&^0x3f == 0, "Illegal shift value, must be 0..63")
+	nChars := (((63 - shift) * 37) >> 8) + 1 // i/7 is the same as (i*37)>>8 for i in 0..63
+	bytes.SetLength(nChars + 1)              // one extra for the byte that contains the shift info
+	bytes.Grow(BUF_SIZE_LONG)
+	bytes.Set(0, byte(SHIFT_START_LONG+shift))
+	sortableBits := uint64(val) ^ 0x8000000000000000
+	sortableBits >>= uint(shift)
+	for nChars > 0 {
+		// Store 7 bits per byte for compatibility
+		// with UTF-8 encoding of terms
+		bytes.Set(nChars, byte(sortableBits&0x7f))
+		nChars--
+		sortableBits >>= 7
+	}
+}
+
+/*
+Returns prefix coded bits after reducing the precision by shift bits.
+This method is used by NumericTokenStream. After encoding, bytes
+contains the encoded value.
+*/
+func IntToPrefixCoded(val int32, shift int, bytes *BytesRefBuilder) {
+	assert2(shift&^0x1f == 0, "Illegal shift value, must be 0..31")
+	nChars := (((31 - shift) * 37) >> 8) + 1 // i/7 is the same as (i*37)>>8 for i in 0..63
+	bytes.SetLength(nChars + 1)              // one extra for the byte that contains the shift info
+	bytes.Grow(BUF_SIZE_LONG)                // use the max
+	bytes.Set(0, byte(SHIFT_START_INT+shift))
+	sortableBits := uint32(val) ^ 0x80000000
+	sortableBits >>= uint(shift)
+	for nChars > 0 {
+		// Store 7 bits per byte for compatibility
+		// with UTF-8 encoding of terms
+		bytes.Set(nChars, byte(sortableBits&0x7f))
+		nChars--
+		sortableBits >>= 7
+	}
+}
+
+/* Returns the shift value from a prefix encoded long. */
+func PrefixCodedLongShift(val []byte) (int, error) {
+	if len(val) == 0 {
+		return 0, errors.New("Invalid prefixCoded numerical value representation (empty)")
+	}
+	shift := int(val[0]) - SHIFT_START_LONG
+	if shift > 63 || shift < 0 {
+		return 0, errors.New(fmt.Sprintf(
+			"Invalid shift value (%v) in prefixCoded bytes (is encoded value really a LONG?)", shift))
+	}
+	return shift, nil
+}
+
+/* Returns the shift value from a prefix encoded int. */
+func PrefixCodedIntShift(val []byte) (int, error) {
+	if len(val) == 0 {
+		return 0, errors.New("Invalid prefixCoded numerical value representation (empty)")
+	}
+	shift := int(val[0]) - SHIFT_START_INT
+	if shift > 31 || shift < 0 {
+		return 0, errors.New(fmt.Sprintf(
+			"Invalid shift value (%v) in prefixCoded bytes (is encoded value really an INT?)", shift))
+	}
+	return shift, nil
+}
+
+/*
+Returns a long from prefixCoded bytes. Rightmost bits will be zero
+for lower precision codes.
+*/
+func PrefixCodedToLong(val []byte) (int64, error) {
+	shift, err := PrefixCodedLongShift(val)
+	if err != nil {
+		return 0, err
+	}
+	var sortableBits uint64
+	for _, b := range val[1:] {
+		if b&0x80 != 0 {
+			return 0, errors.New(fmt.Sprintf(
+				"Invalid prefixCoded numerical value representation (byte %x is invalid)", b))
+		}
+		sortableBits = sortableBits<<7 | uint64(b)
+	}
+	return int64((sortableBits << uint(shift)) ^ 0x8000000000000000), nil
+}
+
+/*
+Returns an int from prefixCoded bytes. Rightmost bits will be zero
+for lower precision codes.
+*/
+func PrefixCodedToInt(val []byte) (int32, error) {
+	shift, err := PrefixCodedIntShift(val)
+	if err != nil {
+		return 0, err
+	}
+	var sortableBits uint32
+	for _, b := range val[1:] {
+		if b&0x80 != 0 {
+			return 0, errors.New(fmt.Sprintf(
+				"Invalid prefixCoded numerical value representation (byte %x is invalid)", b))
+		}
+		sortableBits = sortableBits<<7 | uint32(b)
+	}
+	return int32((sortableBits << uint(shift)) ^ 0x80000000), nil
+}
+
+/*
+Converts a float64 value to a sortable signed int64. The value is
+converted by getting their IEEE 754 floating-point "double format"
+bit layout and then some bits are swapped, to be able to compare the
+result as int64. By this the precision is not reduced, but the value
+can easily be used as an int64. The sort order (including NaN) is
+defined by math.Float64bits(); NaN is greater than positive infinity.
+*/
+func DoubleToSortableLong(val float64) int64 {
+	f := int64(math.Float64bits(val))
+	if f < 0 {
+		f ^= 0x7fffffffffffffff
+	}
+	return f
+}
+
+/* Converts a sortable int64 back to a float64. */
+func SortableLongToDouble(val int64) float64 {
+	if val < 0 {
+		val ^= 0x7fffffffffffffff
+	}
+	return math.Float64frombits(uint64(val))
+}
+
+/*
+Converts a float32 value to a sortable signed int32.
+See DoubleToSortableLong().
+*/
+func FloatToSortableInt(val float32) int32 {
+	f := int32(math.Float32bits(val))
+	if f < 0 {
+		f ^= 0x7fffffff
+	}
+	return f
+}
+
+/* Converts a sortable int32 back to a float32. */
+func SortableIntToFloat(val int32) float32 {
+	if val < 0 {
+		val ^= 0x7fffffff
+	}
+	return math.Float32frombits(uint32(val))
+}
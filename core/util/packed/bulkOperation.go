@@ -2,6 +2,7 @@ package packed
 
 import (
 	"fmt"
+	"math"
 )
 
 // util/packed/BulkOperation.java
@@ -432,7 +433,7 @@ func (op *BulkOperationImpl) computeIterations(valueCount, ramBudget int) int {
 		return 1
 	} else if (iterations-1)*op.ByteValueCount() >= valueCount {
 		// don't allocate for more than the size of the reader
-		panic("not implemented yet")
+		return int(math.Ceil(float64(valueCount) / float64(op.ByteValueCount())))
 	} else {
 		return iterations
 	}
@@ -61,7 +61,9 @@ func (r *Packed16ThreeBlocks) fill(from, to int, val int64) {
 }
 
 func (r *Packed16ThreeBlocks) Clear() {
-	panic("niy")
+	for i := range r.blocks {
+		r.blocks[i] = 0
+	}
 }
 
 func (p *Packed16ThreeBlocks) RamBytesUsed() int64 {
@@ -192,5 +192,7 @@ func (p *Packed64) fill(from, to int, val int64) {
 }
 
 func (p *Packed64) Clear() {
-	panic("niy")
+	for i := range p.blocks {
+		p.blocks[i] = 0
+	}
 }
@@ -56,7 +56,9 @@ func (r *Packed8ThreeBlocks) fill(from, to int, val int64) {
 }
 
 func (r *Packed8ThreeBlocks) Clear() {
-	panic("niy")
+	for i := range r.blocks {
+		r.blocks[i] = 0
+	}
 }
 
 func (r *Packed8ThreeBlocks) RamBytesUsed() int64 {
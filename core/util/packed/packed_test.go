@@ -59,3 +59,25 @@ func TestUnsignedBitsRequired(t *testing.T) {
 		t.Errorf("-158146830731166066 -> 64bit (got %v)", n)
 	}
 }
+
+func TestComputeIterations(t *testing.T) {
+	for bpv := uint32(1); bpv <= 64; bpv++ {
+		op := newBulkOperation(PackedFormat(PACKED), bpv)
+		msg := fmt.Sprintf("bpv=%v", bpv)
+		if n := op.computeIterations(1000, 0); n != 1 {
+			t.Errorf("%v: expected at least 1 iteration, but %v", msg, n)
+		}
+		// a large budget is capped by the number of values
+		valueCount := 10
+		n := op.computeIterations(valueCount, 1024*1024)
+		if n*op.ByteValueCount() < valueCount || (n-1)*op.ByteValueCount() >= valueCount {
+			t.Errorf("%v: expected %v iterations of %v values to cover %v values, but %v",
+				msg, n, op.ByteValueCount(), valueCount, n*op.ByteValueCount())
+		}
+		// else the budget decides
+		ramBudget := 10 * (op.ByteBlockCount() + 8*op.ByteValueCount())
+		if n := op.computeIterations(math.MaxInt32, ramBudget); n != 10 {
+			t.Errorf("%v: expected 10 iterations, but %v", msg, n)
+		}
+	}
+}
@@ -402,6 +402,10 @@ func ceilLog2(n int) int {
 
 func (s *IntroSorter) Sort(from, to int) {
 	s.checkRange(from, to)
+	if to-from <= 1 {
+		// nothing to sort
+		return
+	}
 	s.quicksort(from, to, ceilLog2(to-from))
 }
 
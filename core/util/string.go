@@ -50,7 +50,15 @@ func StartsWith(ref, prefix []byte) bool {
 }
 
 func sliceEquals(sliceToTest, other []byte, pos int) bool {
-	panic("niy")
+	if pos < 0 || len(sliceToTest)-pos < len(other) {
+		return false
+	}
+	for j, b := range other {
+		if sliceToTest[pos+j] != b {
+			return false
+		}
+	}
+	return true
 }
 
 /*
@@ -0,0 +1,201 @@
+package join
+
+import (
+	"fmt"
+	ac "github.com/jtejido/golucene/analysis/core"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"sort"
+	"testing"
+)
+
+var skus = []string{"widget", "gadget", "gizmo", "doohickey"}
+
+const numOrders = 40
+
+/* Order i has (i%3)+1 line items; item j has sku skus[(i+j)%4]. */
+func orderBlock(i int) [][]model.IndexableField {
+	var block [][]model.IndexableField
+	for j := 0; j < i%3+1; j++ {
+		d := docu.NewDocument()
+		d.Add(docu.NewFieldFromString("docType", "item", docu.STRING_FIELD_TYPE_NOT_STORED))
+		d.Add(docu.NewFieldFromString("sku", skus[(i+j)%len(skus)], docu.STRING_FIELD_TYPE_STORED))
+		block = append(block, d.Fields())
+	}
+	d := docu.NewDocument()
+	d.Add(docu.NewFieldFromString("docType", "order", docu.STRING_FIELD_TYPE_NOT_STORED))
+	d.Add(docu.NewFieldFromString("id", fmt.Sprintf("order-%v", i), docu.STRING_FIELD_TYPE_STORED))
+	return append(block, d.Fields())
+}
+
+func hasSku(i int, sku string) bool {
+	for j := 0; j < i%3+1; j++ {
+		if skus[(i+j)%len(skus)] == sku {
+			return true
+		}
+	}
+	return false
+}
+
+func termQuery(field, text string) search.Query {
+	return search.NewTermQuery(index.NewTerm(field, text))
+}
+
+func openBlockIndex(t *testing.T) index.IndexReader {
+	dir := testindex.NewDirectory(t)
+	w := testindex.NewWriter(t, dir, nil)
+	for i := 0; i < numOrders; i++ {
+		if err := w.AddDocuments(orderBlock(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a block without a delete term is simply appended
+	if err := w.UpdateDocuments(nil, orderBlock(numOrders), ac.NewWhitespaceAnalyzer()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return testindex.OpenReader(t, dir)
+}
+
+func storedValue(t *testing.T, r index.IndexReader, doc int, field string) string {
+	d, err := r.Document(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.Get(field)
+}
+
+func TestBlockJoin(t *testing.T) {
+	r := openBlockIndex(t)
+	searcher := search.NewIndexSearcher(r)
+	parents := NewFixedBitSetCachingWrapperFilter(
+		search.NewQueryWrapperFilter(termQuery("docType", "order")))
+
+	expected := make(map[string]int)
+	for i := 0; i <= numOrders; i++ {
+		if hasSku(i, "widget") {
+			expected[fmt.Sprintf("order-%v", i)]++
+		}
+	}
+
+	// to parent
+	parentQuery := NewToParentBlockJoinQuery(termQuery("sku", "widget"), parents, SCORE_MODE_MAX)
+	c := NewToParentBlockJoinCollector(100)
+	if err := searcher.SearchCollector(parentQuery, nil, c); err != nil {
+		t.Fatal(err)
+	}
+	groups := c.TopGroups(parentQuery, 0, 0)
+	if groups == nil {
+		t.Fatal("expected parent hits")
+	}
+	actual := make(map[string]int)
+	for _, group := range groups.Groups {
+		actual[storedValue(t, r, group.GroupValue, "id")]++
+		if group.TotalHits != 1 || len(group.ScoreDocs) != 1 {
+			t.Errorf("expected one widget per order, but %v", group.TotalHits)
+			continue
+		}
+		child := group.ScoreDocs[0]
+		if sku := storedValue(t, r, child.Doc, "sku"); sku != "widget" {
+			t.Errorf("expected a widget child, but %v", sku)
+		}
+		if child.Doc >= group.GroupValue || group.Score != child.Score {
+			t.Errorf("unexpected child hit %v of parent %v", child, group)
+		}
+	}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected parents %v, but %v", expected, actual)
+	}
+	if groups.TotalHitCount != len(groups.Groups) {
+		t.Errorf("expected %v parent hits, but %v", len(groups.Groups), groups.TotalHitCount)
+	}
+
+	// top 3 only, but still counting all hits
+	c = NewToParentBlockJoinCollector(3)
+	if err := searcher.SearchCollector(parentQuery, nil, c); err != nil {
+		t.Fatal(err)
+	}
+	if top := c.TopGroups(parentQuery, 1, 0); top == nil || len(top.Groups) != 2 ||
+		top.TotalHitCount != groups.TotalHitCount {
+		t.Errorf("unexpected top groups %v", top)
+	}
+
+	// the same parents through a regular search
+	docs, err := searcher.SearchTop(parentQuery, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs.TotalHits != len(groups.Groups) {
+		t.Errorf("expected %v hits, but %v", len(groups.Groups), docs.TotalHits)
+	}
+
+	// to child
+	childQuery := NewToChildBlockJoinQuery(termQuery("id", "order-38"), parents, true)
+	if docs, err = searcher.SearchTop(childQuery, 100); err != nil {
+		t.Fatal(err)
+	}
+	var childSkus []string
+	for _, hit := range docs.ScoreDocs {
+		childSkus = append(childSkus, storedValue(t, r, hit.Doc, "sku"))
+		if hit.Score <= 0 {
+			t.Errorf("expected the parent score, but %v", hit.Score)
+		}
+	}
+	sort.Strings(childSkus)
+	if s := fmt.Sprint(childSkus); s != "[doohickey gizmo widget]" {
+		t.Errorf("unexpected children of order-38 %v", s)
+	}
+
+	// to child and back to parent
+	roundTrip := NewToParentBlockJoinQuery(
+		NewToChildBlockJoinQuery(termQuery("id", "order-38"), parents, false),
+		parents, SCORE_MODE_NONE)
+	if docs, err = searcher.SearchTop(roundTrip, 10); err != nil {
+		t.Fatal(err)
+	}
+	if docs.TotalHits != 1 || storedValue(t, r, docs.ScoreDocs[0].Doc, "id") != "order-38" {
+		t.Errorf("expected order-38, but %v", docs.ScoreDocs)
+	}
+
+	// a parent query matching children is rejected
+	badQuery := NewToChildBlockJoinQuery(termQuery("sku", "widget"), parents, false)
+	if _, err := searcher.SearchTop(badQuery, 10); err == nil {
+		t.Error("expected an error for a parent query matching child docs")
+	}
+}
+
+func TestParentsFilterEviction(t *testing.T) {
+	r := openBlockIndex(t)
+	parents := NewFixedBitSetCachingWrapperFilter(
+		search.NewQueryWrapperFilter(termQuery("docType", "order")))
+
+	leaves := r.Leaves()
+	for _, ctx := range leaves {
+		first, err := parents.DocIdSet(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := parents.DocIdSet(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != second {
+			t.Error("expected the cached set to be reused")
+		}
+	}
+	if n := len(parents.cache); n != len(leaves) {
+		t.Errorf("expected %v cached segments, but %v", len(leaves), n)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(parents.cache); n != 0 {
+		t.Errorf("expected the cache to be emptied when the reader closes, but %v entries", n)
+	}
+}
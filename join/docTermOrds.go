@@ -0,0 +1,151 @@
+package join
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	. "github.com/jtejido/golucene/core/search/model"
+	"sync"
+)
+
+// index/DocTermOrds.java
+
+/*
+The indexed terms of a field of a segment, uninverted so that the
+terms of a document can be looked up by its docID. Terms are numbered
+(their ord) in index order, and the ords of a document are ascending.
+
+Like the DocTermOrds of Lucene's FieldCache, the terms of deleted
+documents are kept, and the uninverted fields are cached per segment
+core in termOrdsCache.
+*/
+type docTermOrds struct {
+	terms  [][]byte // by ord
+	starts []int32  // the ords of doc are ords[starts[doc]:starts[doc+1]]
+	ords   []int32
+}
+
+func newDocTermOrds(reader index.AtomicReader, field string) (*docTermOrds, error) {
+	ans := &docTermOrds{starts: make([]int32, reader.MaxDoc()+1)}
+	terms := reader.Terms(field)
+	if terms == nil {
+		// field does not exist
+		return ans, nil
+	}
+
+	// postings in term order, counting the terms of each doc
+	var docs, ords []int32
+	termsEnum := terms.Iterator(nil)
+	var docsEnum DocsEnum
+	for {
+		term, err := termsEnum.Next()
+		if err != nil {
+			return nil, err
+		}
+		if term == nil {
+			break
+		}
+		ord := int32(len(ans.terms))
+		ans.terms = append(ans.terms, append([]byte(nil), term...))
+		if docsEnum, err = termsEnum.DocsByFlags(nil, docsEnum, DOCS_ENUM_FLAG_NONE); err != nil {
+			return nil, err
+		}
+		for {
+			doc, err := docsEnum.NextDoc()
+			if err != nil {
+				return nil, err
+			}
+			if doc == NO_MORE_DOCS {
+				break
+			}
+			docs = append(docs, int32(doc))
+			ords = append(ords, ord)
+			ans.starts[doc+1]++
+		}
+	}
+
+	// regroup the postings by doc
+	for i := 1; i < len(ans.starts); i++ {
+		ans.starts[i] += ans.starts[i-1]
+	}
+	next := append([]int32(nil), ans.starts[:len(ans.starts)-1]...)
+	ans.ords = make([]int32, len(docs))
+	for i, doc := range docs {
+		ans.ords[next[doc]] = ords[i]
+		next[doc]++
+	}
+	return ans, nil
+}
+
+/* Returns the ords of the terms doc holds. */
+func (o *docTermOrds) docOrds(doc int) []int32 {
+	return o.ords[o.starts[doc]:o.starts[doc+1]]
+}
+
+/* Returns the term of ord. */
+func (o *docTermOrds) term(ord int32) []byte {
+	return o.terms[ord]
+}
+
+/*
+Caches the uninverted fields by segment core and field name. Entries
+are shared by reopened readers, and evicted once their core is closed.
+*/
+type docTermOrdsCache struct {
+	sync.Mutex
+	cache map[interface{}]map[string]*docTermOrds
+}
+
+var termOrdsCache = &docTermOrdsCache{
+	cache: make(map[interface{}]map[string]*docTermOrds),
+}
+
+/* Returns the uninverted field of reader, uninverting it on first use. */
+func (c *docTermOrdsCache) get(reader index.AtomicReader, field string) (*docTermOrds, error) {
+	segment, ok := reader.(*index.SegmentReader)
+	if !ok {
+		// no core to key on, nor to be told of its close
+		return newDocTermOrds(reader, field)
+	}
+	key := segment.CoreCacheKey()
+
+	c.Lock()
+	fields, listening := c.cache[key]
+	ans, ok := fields[field]
+	c.Unlock()
+	if ok {
+		return ans, nil
+	}
+	if !listening {
+		// Before publishing, so that the entry of a core closed meanwhile
+		// is evicted, and outside of the lock: registering waits on the
+		// core's listener routine, which takes the lock to evict.
+		segment.AddCoreClosedListener(c)
+	}
+	ans, err := newDocTermOrds(reader, field)
+	if err != nil {
+		return nil, err
+	}
+	c.Lock()
+	defer c.Unlock()
+	if segment.RefCount() <= 0 {
+		// the core was closed, and its entries evicted, meanwhile
+		return ans, nil
+	}
+	if fields, listening = c.cache[key]; !listening {
+		fields = make(map[string]*docTermOrds)
+		c.cache[key] = fields
+	}
+	if cached, ok := fields[field]; ok {
+		// uninverted concurrently
+		return cached, nil
+	}
+	fields[field] = ans
+	return ans, nil
+}
+
+/* Evicts the fields of a closed segment core. */
+func (c *docTermOrdsCache) OnClose(ownerCoreCacheKey interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.cache, ownerCoreCacheKey)
+}
@@ -0,0 +1,154 @@
+package join
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"sync"
+)
+
+// search/join/FixedBitSetCachingWrapperFilter.java
+
+/*
+A Filter wrapper that caches the documents accepted by the wrapped
+filter, per segment, as a FixedBitSet. This is the filter the block
+join queries expect to identify parent documents, e.g.
+
+	parents := NewFixedBitSetCachingWrapperFilter(
+		search.NewQueryWrapperFilter(search.NewTermQuery(index.NewTerm("docType", "order"))))
+
+Deleted documents are not removed from the cached sets: the layout of
+a block does not change when some of its documents are deleted.
+Entries are keyed on the segment core, so they are shared by reopened
+readers, and evicted once the core is closed.
+*/
+type FixedBitSetCachingWrapperFilter struct {
+	sync.Mutex
+	filter search.Filter
+	cache  map[interface{}]*search.DocIdBitSet
+}
+
+func NewFixedBitSetCachingWrapperFilter(filter search.Filter) *FixedBitSetCachingWrapperFilter {
+	assert2(filter != nil, "filter must not be nil")
+	return &FixedBitSetCachingWrapperFilter{
+		filter: filter,
+		cache:  make(map[interface{}]*search.DocIdBitSet),
+	}
+}
+
+/*
+Returns the cached set of ctx's segment, computing it on first use.
+acceptDocs are applied to neither the wrapped filter nor the result.
+*/
+func (f *FixedBitSetCachingWrapperFilter) DocIdSet(ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits) (search.DocIdSet, error) {
+
+	ans, err := f.bitSet(ctx)
+	if ans == nil || err != nil {
+		return nil, err
+	}
+	return ans, nil
+}
+
+func (f *FixedBitSetCachingWrapperFilter) bitSet(ctx *index.AtomicReaderContext) (*search.DocIdBitSet, error) {
+	reader, ok := ctx.Reader().(*index.SegmentReader)
+	if !ok {
+		// no core to key on, nor to be told of its close
+		return f.compute(ctx)
+	}
+	key := reader.CoreCacheKey()
+
+	f.Lock()
+	ans, ok := f.cache[key]
+	f.Unlock()
+	if ok {
+		return ans, nil
+	}
+	// Before publishing, so that the set of a core closed meanwhile is
+	// evicted, and outside of the lock: registering waits on the core's
+	// listener routine, which takes the lock to evict.
+	reader.AddCoreClosedListener(f)
+	ans, err := f.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.Lock()
+	defer f.Unlock()
+	if reader.RefCount() <= 0 {
+		// the core was closed, and its set evicted, meanwhile
+		return ans, nil
+	}
+	if cached, ok := f.cache[key]; ok {
+		// computed concurrently
+		return cached, nil
+	}
+	f.cache[key] = ans
+	return ans, nil
+}
+
+/* Evicts the sets of a closed segment core. */
+func (f *FixedBitSetCachingWrapperFilter) OnClose(ownerCoreCacheKey interface{}) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.cache, ownerCoreCacheKey)
+}
+
+func (f *FixedBitSetCachingWrapperFilter) compute(ctx *index.AtomicReaderContext) (*search.DocIdBitSet, error) {
+	reader := ctx.Reader()
+	set, err := f.filter.DocIdSet(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	var ans *search.DocIdBitSet
+	if set != nil {
+		if bits, ok := set.(*search.DocIdBitSet); ok {
+			ans = bits
+		} else {
+			it, err := set.Iterator()
+			if err != nil {
+				return nil, err
+			}
+			if it != nil {
+				bits := util.NewFixedBitSetOf(reader.MaxDoc())
+				for doc, err := it.NextDoc(); doc != NO_MORE_DOCS; doc, err = it.NextDoc() {
+					if err != nil {
+						return nil, err
+					}
+					bits.Set(doc)
+				}
+				ans = search.NewDocIdBitSet(bits)
+			}
+		}
+	}
+	return ans, nil
+}
+
+func (f *FixedBitSetCachingWrapperFilter) String() string {
+	return fmt.Sprintf("FixedBitSetCachingWrapperFilter(%v)", f.filter)
+}
+
+/*
+Returns the parents of ctx's segment as a FixedBitSet, or nil if the
+segment has none.
+*/
+func parentBits(parentsFilter search.Filter, ctx *index.AtomicReaderContext) (*util.FixedBitSet, error) {
+	set, err := parentsFilter.DocIdSet(ctx, nil)
+	if set == nil || err != nil {
+		return nil, err
+	}
+	bits, ok := set.(*search.DocIdBitSet)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf(
+			"parentFilter must return FixedBitSet; got %v", set))
+	}
+	return bits.BitSet(), nil
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
@@ -0,0 +1,45 @@
+package join
+
+import (
+	"github.com/jtejido/golucene/core/search"
+)
+
+// search/join/JoinUtil.java
+
+/*
+Method for query time joining.
+
+Execute the returned query with an IndexSearcher to retrieve all
+documents that have the same terms in the to field that match with
+documents matching the specified fromQuery and have the same terms in
+the from field. fromSearcher and the searcher of the returned query
+may search different indexes.
+
+A "from" document may hold several terms in fromField, all of which
+are joined. With a ScoreMode other than SCORE_MODE_NONE, each term is
+scored by aggregating the scores of the "from" documents holding it,
+and the "to" documents get the score of their joined term.
+
+This join has certain restrictions and notes:
+
+1. No documents in the fromSearcher are joined until the query is
+created: the "from" side is searched right away.
+2. The "to" side only supports indexed terms; fromField may be
+indexed with any analysis, as its indexed terms are joined.
+*/
+func CreateJoinQuery(fromField, toField string, fromQuery search.Query,
+	fromSearcher *search.IndexSearcher, scoreMode ScoreMode) (search.Query, error) {
+
+	c := newTermsCollector(fromField, scoreMode)
+	if err := fromSearcher.SearchCollector(fromQuery, nil, c); err != nil {
+		return nil, err
+	}
+	if err := c.finish(); err != nil {
+		return nil, err
+	}
+
+	if scoreMode == SCORE_MODE_NONE {
+		return newTermsQuery(toField, fromQuery, c.terms), nil
+	}
+	return newTermsIncludingScoreQuery(toField, fromQuery, c.terms, c.scoresPerTerm()), nil
+}
@@ -0,0 +1,179 @@
+package join
+
+import (
+	"fmt"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func book(title string, authors ...string) []model.IndexableField {
+	d := docu.NewDocument()
+	d.Add(docu.NewTextFieldFromString("title", title, docu.STORE_YES))
+	for _, author := range authors {
+		d.Add(docu.NewFieldFromString("author", author, docu.STRING_FIELD_TYPE_STORED))
+	}
+	return d.Fields()
+}
+
+func author(id, country string) []model.IndexableField {
+	d := docu.NewDocument()
+	d.Add(docu.NewFieldFromString("id", id, docu.STRING_FIELD_TYPE_STORED))
+	d.Add(docu.NewFieldFromString("country", country, docu.STRING_FIELD_TYPE_NOT_STORED))
+	return d.Fields()
+}
+
+/* Returns the hits of query by stored field value. */
+func searchJoin(t *testing.T, searcher *search.IndexSearcher, query search.Query, field string) map[string]float32 {
+	docs, err := searcher.SearchTop(query, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ans := make(map[string]float32)
+	for _, hit := range docs.ScoreDocs {
+		ans[storedValue(t, searcher.IndexReader(), hit.Doc, field)] = hit.Score
+	}
+	return ans
+}
+
+func TestQueryTimeJoin(t *testing.T) {
+	books := testindex.NewReader(t,
+		book("go go lucene", "a1"),
+		book("go search", "a1", "a2"),
+		book("java search", "a3"),
+		book("go", "a5"),
+	)
+	authors := testindex.NewReader(t,
+		author("a1", "fr"),
+		author("a2", "us"),
+		author("a3", "fr"),
+		author("a4", "fr"),
+	)
+	bookSearcher := search.NewIndexSearcher(books)
+	authorSearcher := search.NewIndexSearcher(authors)
+
+	// books of french authors
+	query, err := CreateJoinQuery("id", "author", termQuery("country", "fr"),
+		authorSearcher, SCORE_MODE_NONE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := searchJoin(t, bookSearcher, query, "title")
+	if len(hits) != 3 || hits["go go lucene"] == 0 || hits["go search"] == 0 || hits["java search"] == 0 {
+		t.Errorf("unexpected books of french authors %v", hits)
+	}
+	if hits["go go lucene"] != hits["java search"] {
+		t.Errorf("expected constant scores, but %v", hits)
+	}
+
+	// authors of books about go, scored by their books
+	fromQuery := termQuery("title", "go")
+	bookScores := searchJoin(t, bookSearcher, fromQuery, "title")
+	s1, s2 := bookScores["go go lucene"], bookScores["go search"]
+	for _, test := range []struct {
+		scoreMode ScoreMode
+		a1        float32
+	}{
+		{SCORE_MODE_AVG, (s1 + s2) / 2},
+		{SCORE_MODE_MAX, s1},
+		{SCORE_MODE_TOTAL, s1 + s2},
+		{SCORE_MODE_NONE, 1},
+	} {
+		query, err = CreateJoinQuery("author", "id", fromQuery, bookSearcher, test.scoreMode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits = searchJoin(t, authorSearcher, query, "id")
+		expected := map[string]float32{"a1": test.a1, "a2": s2}
+		if test.scoreMode == SCORE_MODE_NONE {
+			expected["a2"] = 1
+		}
+		if fmt.Sprint(hits) != fmt.Sprint(expected) {
+			t.Errorf("%v: expected %v, but %v", test.scoreMode, expected, hits)
+		}
+	}
+
+	// the join query composes with other queries
+	query, err = CreateJoinQuery("author", "id", fromQuery, bookSearcher, SCORE_MODE_MAX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bq := search.NewBooleanQuery()
+	bq.Add(query, search.MUST)
+	bq.Add(termQuery("country", "us"), search.MUST)
+	if hits = searchJoin(t, authorSearcher, bq, "id"); len(hits) != 1 || hits["a2"] == 0 {
+		t.Errorf("expected a2, but %v", hits)
+	}
+	exp, err := authorSearcher.Explain(query, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exp.IsMatch() || exp.Value() != s1 {
+		t.Errorf("unexpected explanation %v", exp)
+	}
+
+	// nothing to join
+	query, err = CreateJoinQuery("author", "id", termQuery("title", "rust"), bookSearcher, SCORE_MODE_AVG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits = searchJoin(t, authorSearcher, query, "id"); len(hits) != 0 {
+		t.Errorf("expected no hits, but %v", hits)
+	}
+}
+
+func TestDocTermOrds(t *testing.T) {
+	books := testindex.NewReader(t,
+		book("go go lucene", "a2", "a1"),
+		book("untitled"),
+		book("java search", "a3", "a1"),
+	)
+	expected := map[string]string{
+		"go go lucene": "[a1 a2]",
+		"untitled":     "[]",
+		"java search":  "[a1 a3]",
+	}
+	leaves := books.Leaves()
+	for _, ctx := range leaves {
+		reader := ctx.Reader().(index.AtomicReader)
+		ords, err := termOrdsCache.get(reader, "author")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for doc := 0; doc < reader.MaxDoc(); doc++ {
+			var terms []string
+			for _, ord := range ords.docOrds(doc) {
+				terms = append(terms, string(ords.term(ord)))
+			}
+			title := storedValue(t, reader, doc, "title")
+			if fmt.Sprint(terms) != expected[title] {
+				t.Errorf("%v: expected terms %v, but %v", title, expected[title], terms)
+			}
+		}
+		again, err := termOrdsCache.get(reader, "author")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != ords {
+			t.Error("expected the uninverted field to be reused")
+		}
+	}
+
+	keys := make([]interface{}, len(leaves))
+	for i, ctx := range leaves {
+		keys[i] = ctx.Reader().(*index.SegmentReader).CoreCacheKey()
+	}
+	if err := books.Close(); err != nil {
+		t.Fatal(err)
+	}
+	termOrdsCache.Lock()
+	defer termOrdsCache.Unlock()
+	for _, key := range keys {
+		if _, ok := termOrdsCache.cache[key]; ok {
+			t.Error("expected the cache to be emptied when the reader closes")
+		}
+	}
+}
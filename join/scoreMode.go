@@ -0,0 +1,31 @@
+package join
+
+// search/join/ScoreMode.java
+
+/* How to aggregate multiple child hit scores into a single parent score. */
+type ScoreMode int
+
+const (
+	// Do no scoring.
+	SCORE_MODE_NONE = ScoreMode(0)
+	// Parent hit's score is the average of all child scores.
+	SCORE_MODE_AVG = ScoreMode(1)
+	// Parent hit's score is the max of all child scores.
+	SCORE_MODE_MAX = ScoreMode(2)
+	// Parent hit's score is the sum of all child scores.
+	SCORE_MODE_TOTAL = ScoreMode(3)
+)
+
+func (mode ScoreMode) String() string {
+	switch mode {
+	case SCORE_MODE_NONE:
+		return "None"
+	case SCORE_MODE_AVG:
+		return "Avg"
+	case SCORE_MODE_MAX:
+		return "Max"
+	case SCORE_MODE_TOTAL:
+		return "Total"
+	}
+	panic("should not be here")
+}
@@ -0,0 +1,122 @@
+package join
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/join/TermsCollector.java
+// search/join/TermsWithScoreCollector.java
+
+/*
+A collector that collects all terms from a specified field matching
+the query. Unless the ScoreMode is SCORE_MODE_NONE, the scores of the
+documents holding each term are aggregated as well.
+
+The terms of each collected document are looked up in the field
+uninverted by termOrdsCache, so a document may hold any number of
+terms of the field.
+*/
+type termsCollector struct {
+	field     string
+	scoreMode ScoreMode
+
+	terms       *util.BytesRefHash
+	scoreSums   []float32
+	scoreCounts []int
+
+	ords   *docTermOrds
+	err    error // from uninverting the current segment
+	scorer search.Scorer
+}
+
+func newTermsCollector(field string, scoreMode ScoreMode) *termsCollector {
+	return &termsCollector{
+		field:     field,
+		scoreMode: scoreMode,
+		terms:     util.NewDefaultBytesRefHash(),
+	}
+}
+
+func (c *termsCollector) SetScorer(scorer search.Scorer) {
+	c.scorer = scorer
+}
+
+func (c *termsCollector) SetNextReader(ctx *index.AtomicReaderContext) {
+	c.ords, c.err = termOrdsCache.get(ctx.Reader().(index.AtomicReader), c.field)
+}
+
+func (c *termsCollector) Collect(doc int) error {
+	if c.err != nil {
+		return c.err
+	}
+	ords := c.ords.docOrds(doc)
+	if len(ords) == 0 {
+		return nil
+	}
+	var score float32
+	if c.scoreMode != SCORE_MODE_NONE {
+		var err error
+		if score, err = c.scorer.Score(); err != nil {
+			return err
+		}
+	}
+	for _, ord := range ords {
+		id, err := c.addTerm(c.ords.term(ord))
+		if err != nil {
+			return err
+		}
+		if c.scoreMode != SCORE_MODE_NONE {
+			c.addScore(id, score)
+		}
+	}
+	return nil
+}
+
+func (c *termsCollector) AcceptsDocsOutOfOrder() bool {
+	return true
+}
+
+/* Completes the aggregated scores once the search is done. */
+func (c *termsCollector) finish() error {
+	c.ords = nil
+	if c.scoreMode == SCORE_MODE_AVG {
+		for i, count := range c.scoreCounts {
+			c.scoreSums[i] /= float32(count)
+		}
+	}
+	return nil
+}
+
+func (c *termsCollector) addTerm(term []byte) (int, error) {
+	ord, err := c.terms.Add(term)
+	if err != nil {
+		return 0, err
+	}
+	if ord < 0 {
+		return -ord - 1, nil
+	}
+	if c.scoreMode != SCORE_MODE_NONE {
+		c.scoreSums = append(c.scoreSums, 0)
+		c.scoreCounts = append(c.scoreCounts, 0)
+	}
+	return ord, nil
+}
+
+func (c *termsCollector) addScore(ord int, score float32) {
+	switch c.scoreMode {
+	case SCORE_MODE_MAX:
+		if c.scoreCounts[ord] == 0 || score > c.scoreSums[ord] {
+			c.scoreSums[ord] = score
+		}
+	default:
+		c.scoreSums[ord] += score
+	}
+	c.scoreCounts[ord]++
+}
+
+/* Returns the aggregated score of each collected term, by term ID. */
+func (c *termsCollector) scoresPerTerm() []float32 {
+	return c.scoreSums
+}
@@ -0,0 +1,212 @@
+package join
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/join/TermsIncludingScoreQuery.java
+
+/*
+A query matching the documents holding any of the collected terms in
+field, each scored with the aggregated "from" score of its term. A
+document holding several of the terms is scored by the smallest of
+them in term order.
+*/
+type termsIncludingScoreQuery struct {
+	*search.AbstractQuery
+	field string
+	terms *util.BytesRefHash
+	// aggregated score by term ID
+	scores []float32
+	// term IDs, sorted by term
+	ords []int
+	// only used for ToString()
+	fromQuery search.Query
+}
+
+/* terms and scores must not be modified after this query has been created. */
+func newTermsIncludingScoreQuery(field string, fromQuery search.Query,
+	terms *util.BytesRefHash, scores []float32) *termsIncludingScoreQuery {
+
+	return newTermsIncludingScoreQueryWithOrds(field, fromQuery, terms, scores,
+		terms.Sort(util.UTF8SortedAsUnicodeLess)[:terms.Size()])
+}
+
+func newTermsIncludingScoreQueryWithOrds(field string, fromQuery search.Query,
+	terms *util.BytesRefHash, scores []float32, ords []int) *termsIncludingScoreQuery {
+
+	ans := &termsIncludingScoreQuery{
+		field:     field,
+		terms:     terms,
+		scores:    scores,
+		ords:      ords,
+		fromQuery: fromQuery,
+	}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+func (q *termsIncludingScoreQuery) CreateWeight(searcher *search.IndexSearcher) (search.Weight, error) {
+	ans := &termsIncludingScoreWeight{query: q}
+	ans.WeightImpl = search.NewWeightImpl(ans)
+	return ans, nil
+}
+
+func (q *termsIncludingScoreQuery) Clone() search.Query {
+	ans := newTermsIncludingScoreQueryWithOrds(q.field, q.fromQuery, q.terms, q.scores, q.ords)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *termsIncludingScoreQuery) ToString(field string) string {
+	return fmt.Sprintf("TermsIncludingScoreQuery{field=%v;fromQuery=%v}",
+		q.field, q.fromQuery.ToString(field))
+}
+
+type termsIncludingScoreWeight struct {
+	*search.WeightImpl
+	query       *termsIncludingScoreQuery
+	queryNorm   float32
+	queryWeight float32
+}
+
+func (w *termsIncludingScoreWeight) ValueForNormalization() float32 {
+	w.queryWeight = w.query.Boost()
+	return w.queryWeight * w.queryWeight
+}
+
+func (w *termsIncludingScoreWeight) Normalize(norm, topLevelBoost float32) {
+	w.queryNorm = norm * topLevelBoost
+	w.queryWeight *= w.queryNorm
+}
+
+func (w *termsIncludingScoreWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *termsIncludingScoreWeight) Scorer(ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits) (search.Scorer, error) {
+
+	reader := ctx.Reader().(index.AtomicReader)
+	terms := reader.Terms(w.query.field)
+	if terms == nil {
+		return nil, nil
+	}
+
+	docs := util.NewFixedBitSetOf(reader.MaxDoc())
+	scores := make([]float32, reader.MaxDoc())
+	cost := 0
+	err := w.visitMatches(terms, acceptDocs, func(doc, ord int) {
+		if !docs.At(doc) {
+			docs.Set(doc)
+			scores[doc] = w.query.scores[ord]
+			cost++
+		}
+	})
+	if err != nil || cost == 0 {
+		return nil, err
+	}
+	return &termsIncludingScoreScorer{
+		weight:      w,
+		docs:        docs,
+		scores:      scores,
+		queryWeight: w.queryWeight,
+		doc:         -1,
+		cost:        cost,
+	}, nil
+}
+
+/* Calls f for each document holding each of the terms, in term order. */
+func (w *termsIncludingScoreWeight) visitMatches(terms Terms, acceptDocs util.Bits,
+	f func(doc, ord int)) error {
+
+	termsEnum := terms.Iterator(nil)
+	spare := util.NewEmptyBytesRef()
+	var docsEnum DocsEnum
+	for _, ord := range w.query.ords {
+		ok, err := termsEnum.SeekExact(w.query.terms.Get(ord, spare).ToBytes())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if docsEnum, err = termsEnum.DocsByFlags(acceptDocs, docsEnum, DOCS_ENUM_FLAG_NONE); err != nil {
+			return err
+		}
+		for {
+			doc, err := docsEnum.NextDoc()
+			if err != nil {
+				return err
+			}
+			if doc == NO_MORE_DOCS {
+				break
+			}
+			f(doc, ord)
+		}
+	}
+	return nil
+}
+
+func (w *termsIncludingScoreWeight) Explain(ctx *index.AtomicReaderContext, doc int) (search.Explanation, error) {
+	reader := ctx.Reader().(index.AtomicReader)
+	if terms := reader.Terms(w.query.field); terms != nil {
+		matched := -1
+		err := w.visitMatches(terms, reader.LiveDocs(), func(d, ord int) {
+			if d == doc && matched == -1 {
+				matched = ord
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if matched != -1 {
+			term := w.query.terms.Get(matched, util.NewEmptyBytesRef()).ToBytes()
+			ans := search.NewComplexExplanation(true, w.query.scores[matched]*w.queryWeight,
+				fmt.Sprintf("Score based on join value %v, product of:", string(term)))
+			ans.AddDetail(search.NewExplanation(w.query.scores[matched], "join score"))
+			ans.AddDetail(search.NewExplanation(w.query.Boost(), "boost"))
+			ans.AddDetail(search.NewExplanation(w.queryNorm, "queryNorm"))
+			return ans, nil
+		}
+	}
+	return search.NewComplexExplanation(false, 0, "Not a match"), nil
+}
+
+type termsIncludingScoreScorer struct {
+	weight      search.Weight
+	docs        *util.FixedBitSet
+	scores      []float32
+	queryWeight float32
+	doc         int
+	cost        int
+}
+
+func (s *termsIncludingScoreScorer) Weight() search.Weight { return s.weight }
+func (s *termsIncludingScoreScorer) DocId() int            { return s.doc }
+func (s *termsIncludingScoreScorer) Freq() (int, error)    { return 1, nil }
+func (s *termsIncludingScoreScorer) Cost() int64           { return int64(s.cost) }
+
+func (s *termsIncludingScoreScorer) Score() (float32, error) {
+	return s.scores[s.doc] * s.queryWeight, nil
+}
+
+func (s *termsIncludingScoreScorer) NextDoc() (int, error) {
+	return s.Advance(s.doc + 1)
+}
+
+func (s *termsIncludingScoreScorer) Advance(target int) (int, error) {
+	if target >= s.docs.Length() {
+		s.doc = NO_MORE_DOCS
+		return s.doc, nil
+	}
+	if s.doc = s.docs.NextSetBit(target); s.doc == -1 {
+		s.doc = NO_MORE_DOCS
+	}
+	return s.doc, nil
+}
@@ -0,0 +1,98 @@
+package join
+
+import (
+	"fmt"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/join/TermsQuery.java
+
+/*
+A query that has an array of terms from a specific field. This query
+will match documents that contain one or more of the specified
+terms.
+*/
+type termsQuery struct {
+	*search.AbstractMultiTermQuery
+	terms *util.BytesRefHash
+	// term IDs, sorted by term
+	ords []int
+	// only used for ToString()
+	fromQuery search.Query
+}
+
+/* terms must not be modified after this query has been created. */
+func newTermsQuery(field string, fromQuery search.Query, terms *util.BytesRefHash) *termsQuery {
+	return newTermsQueryWithOrds(field, fromQuery, terms,
+		terms.Sort(util.UTF8SortedAsUnicodeLess)[:terms.Size()])
+}
+
+func newTermsQueryWithOrds(field string, fromQuery search.Query,
+	terms *util.BytesRefHash, ords []int) *termsQuery {
+
+	ans := &termsQuery{
+		terms:     terms,
+		ords:      ords,
+		fromQuery: fromQuery,
+	}
+	ans.AbstractMultiTermQuery = search.NewAbstractMultiTermQuery(ans, field)
+	return ans
+}
+
+func (q *termsQuery) TermsEnum(terms Terms, atts *util.AttributeSource) (TermsEnum, error) {
+	if len(q.ords) == 0 {
+		return EMPTY_TERMS_ENUM, nil
+	}
+	return newSeekingTermSetTermsEnum(terms.Iterator(nil), q.terms, q.ords), nil
+}
+
+func (q *termsQuery) Clone() search.Query {
+	ans := newTermsQueryWithOrds(q.Field(), q.fromQuery, q.terms, q.ords)
+	ans.SetRewriteMethod(q.RewriteMethod())
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *termsQuery) ToString(field string) string {
+	return fmt.Sprintf("TermsQuery{field=%v;fromQuery=%v}", q.Field(), q.fromQuery.ToString(field))
+}
+
+/*
+A TermsEnum positioned on each of the collected terms existing in
+the enumerated field, in term order.
+*/
+type seekingTermSetTermsEnum struct {
+	TermsEnum
+	terms *util.BytesRefHash
+	ords  []int
+	upto  int
+	spare *util.BytesRef
+}
+
+func newSeekingTermSetTermsEnum(tenum TermsEnum, terms *util.BytesRefHash,
+	ords []int) *seekingTermSetTermsEnum {
+
+	return &seekingTermSetTermsEnum{
+		TermsEnum: tenum,
+		terms:     terms,
+		ords:      ords,
+		spare:     util.NewEmptyBytesRef(),
+	}
+}
+
+func (e *seekingTermSetTermsEnum) Next() ([]byte, error) {
+	for e.upto < len(e.ords) {
+		term := e.terms.Get(e.ords[e.upto], e.spare).ToBytes()
+		e.upto++
+		ok, err := e.TermsEnum.SeekExact(term)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return e.TermsEnum.Term(), nil
+		}
+	}
+	return nil, nil
+}
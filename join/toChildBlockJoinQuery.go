@@ -0,0 +1,307 @@
+package join
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/join/ToChildBlockJoinQuery.java
+
+/*
+Just like ToParentBlockJoinQuery, except this query joins in reverse:
+you provide a Query matching parent documents and it joins down to
+child documents.
+*/
+type ToChildBlockJoinQuery struct {
+	*search.AbstractQuery
+	parentsFilter search.Filter
+	parentQuery   search.Query
+	// If we are rewritten, this is the original parentQuery we were
+	// passed
+	origParentQuery search.Query
+	doScores        bool
+}
+
+// Message thrown from ToChildBlockJoinScorer.validateParentDoc on mis-use,
+// when the parent query incorrectly returns child docs.
+const INVALID_QUERY_MESSAGE = "Parent query yields document which is not matched by parents filter, docID="
+
+/*
+Create a ToChildBlockJoinQuery.
+
+parentQuery is a Query that matches parent documents; parentsFilter
+is a Filter (must produce FixedBitSet per-segment, like
+FixedBitSetCachingWrapperFilter) identifying the parent documents;
+doScores is true if parent scores should be calculated and given to
+the children.
+*/
+func NewToChildBlockJoinQuery(parentQuery search.Query, parentsFilter search.Filter,
+	doScores bool) *ToChildBlockJoinQuery {
+
+	return newToChildBlockJoinQuery(parentQuery, parentQuery, parentsFilter, doScores)
+}
+
+func newToChildBlockJoinQuery(origParentQuery, parentQuery search.Query,
+	parentsFilter search.Filter, doScores bool) *ToChildBlockJoinQuery {
+
+	assert2(parentQuery != nil, "parentQuery must not be nil")
+	assert2(parentsFilter != nil, "parentsFilter must not be nil")
+	ans := &ToChildBlockJoinQuery{
+		parentsFilter:   parentsFilter,
+		parentQuery:     parentQuery,
+		origParentQuery: origParentQuery,
+		doScores:        doScores,
+	}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+/* Return our parent query. */
+func (q *ToChildBlockJoinQuery) ParentQuery() search.Query {
+	return q.parentQuery
+}
+
+func (q *ToChildBlockJoinQuery) CreateWeight(searcher *search.IndexSearcher) (search.Weight, error) {
+	parentWeight, err := q.parentQuery.CreateWeight(searcher)
+	if err != nil {
+		return nil, err
+	}
+	ans := &toChildBlockJoinWeight{
+		joinQuery:     q,
+		parentWeight:  parentWeight,
+		parentsFilter: q.parentsFilter,
+		doScores:      q.doScores,
+	}
+	ans.WeightImpl = search.NewWeightImpl(ans)
+	return ans, nil
+}
+
+func (q *ToChildBlockJoinQuery) Rewrite(reader index.IndexReader) search.Query {
+	if parentRewrite := q.parentQuery.Rewrite(reader); parentRewrite != q.parentQuery {
+		ans := newToChildBlockJoinQuery(q.origParentQuery, parentRewrite, q.parentsFilter, q.doScores)
+		ans.SetBoost(q.Boost())
+		return ans
+	}
+	return q
+}
+
+func (q *ToChildBlockJoinQuery) Clone() search.Query {
+	ans := newToChildBlockJoinQuery(q.origParentQuery.Clone(), q.parentQuery.Clone(),
+		q.parentsFilter, q.doScores)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *ToChildBlockJoinQuery) ToString(field string) string {
+	return fmt.Sprintf("ToChildBlockJoinQuery (%v)", q.parentQuery.ToString(field))
+}
+
+type toChildBlockJoinWeight struct {
+	*search.WeightImpl
+	joinQuery     *ToChildBlockJoinQuery
+	parentWeight  search.Weight
+	parentsFilter search.Filter
+	doScores      bool
+}
+
+func (w *toChildBlockJoinWeight) ValueForNormalization() float32 {
+	boost := w.joinQuery.Boost()
+	return w.parentWeight.ValueForNormalization() * boost * boost
+}
+
+func (w *toChildBlockJoinWeight) Normalize(norm, topLevelBoost float32) {
+	w.parentWeight.Normalize(norm, topLevelBoost*w.joinQuery.Boost())
+}
+
+func (w *toChildBlockJoinWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+// NOTE: acceptDocs applies (and is checked) only in the child document space
+func (w *toChildBlockJoinWeight) Scorer(ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits) (search.Scorer, error) {
+
+	parentScorer, err := w.parentWeight.Scorer(ctx, nil)
+	if parentScorer == nil || err != nil {
+		// No matches
+		return nil, err
+	}
+
+	// NOTE: we cannot pass acceptDocs here because this will (most
+	// likely, depending on the filter) prevent us from finding
+	// parents of deleted children
+	parents, err := parentBits(w.parentsFilter, ctx)
+	if parents == nil || err != nil {
+		// No parents
+		return nil, err
+	}
+	return &toChildBlockJoinScorer{
+		weight:       w,
+		parentScorer: parentScorer,
+		parentBits:   parents,
+		doScores:     w.doScores,
+		acceptDocs:   acceptDocs,
+		childDoc:     -1,
+	}, nil
+}
+
+func (w *toChildBlockJoinWeight) Explain(ctx *index.AtomicReaderContext, doc int) (search.Explanation, error) {
+	// TODO
+	return nil, errors.New(fmt.Sprintf("%T cannot explain match on parent document", w))
+}
+
+type toChildBlockJoinScorer struct {
+	weight       search.Weight
+	parentScorer search.Scorer
+	parentBits   *util.FixedBitSet
+	doScores     bool
+	acceptDocs   util.Bits
+	parentScore  float32
+	parentFreq   int
+	childDoc     int
+	parentDoc    int
+}
+
+func (s *toChildBlockJoinScorer) Weight() search.Weight { return s.weight }
+func (s *toChildBlockJoinScorer) DocId() int            { return s.childDoc }
+func (s *toChildBlockJoinScorer) Freq() (int, error)    { return s.parentFreq, nil }
+func (s *toChildBlockJoinScorer) Cost() int64           { return s.parentScorer.Cost() }
+
+func (s *toChildBlockJoinScorer) Score() (float32, error) {
+	return s.parentScore, nil
+}
+
+func (s *toChildBlockJoinScorer) NextDoc() (int, error) {
+	var err error
+nextChildDoc:
+	for {
+		if s.childDoc+1 == s.parentDoc {
+			// OK, we are done iterating through all children matching
+			// this one parent doc, so we now nextDoc() the parent. Use a
+			// for loop because we may have to skip over some number of
+			// parents w/ no children:
+			for {
+				if err = s.nextParentDoc(); err != nil {
+					return 0, err
+				}
+				if s.parentDoc == 0 {
+					// Degenerate but allowed: first parent doc has no children
+					if err = s.nextParentDoc(); err != nil {
+						return 0, err
+					}
+				}
+
+				if s.parentDoc == NO_MORE_DOCS {
+					s.childDoc = NO_MORE_DOCS
+					return s.childDoc, nil
+				}
+
+				// Go to first child for this next parentDoc:
+				s.childDoc = 1 + s.parentBits.PrevSetBit(s.parentDoc-1)
+
+				if s.childDoc == s.parentDoc {
+					// This parent has no children; continue parent loop so
+					// we move to next parent
+					continue
+				}
+
+				if err = s.scoreParent(); err != nil {
+					return 0, err
+				}
+				if s.acceptDocs != nil && !s.acceptDocs.At(s.childDoc) {
+					continue nextChildDoc
+				}
+				return s.childDoc, nil
+			}
+		}
+
+		assert(s.childDoc < s.parentDoc)
+		s.childDoc++
+		if s.acceptDocs != nil && !s.acceptDocs.At(s.childDoc) {
+			continue
+		}
+		return s.childDoc, nil
+	}
+}
+
+func (s *toChildBlockJoinScorer) nextParentDoc() (err error) {
+	if s.parentDoc, err = s.parentScorer.NextDoc(); err != nil {
+		return
+	}
+	return s.validateParentDoc()
+}
+
+/* Detect mis-use, where provided parent query in fact sometimes returns child documents. */
+func (s *toChildBlockJoinScorer) validateParentDoc() error {
+	if s.parentDoc != NO_MORE_DOCS && !s.parentBits.At(s.parentDoc) {
+		return errors.New(fmt.Sprintf("%v%v", INVALID_QUERY_MESSAGE, s.parentDoc))
+	}
+	return nil
+}
+
+func (s *toChildBlockJoinScorer) scoreParent() (err error) {
+	if s.doScores {
+		if s.parentScore, err = s.parentScorer.Score(); err != nil {
+			return
+		}
+		s.parentFreq, err = s.parentScorer.Freq()
+	}
+	return
+}
+
+func (s *toChildBlockJoinScorer) Advance(childTarget int) (int, error) {
+	var err error
+	if childTarget >= s.parentDoc {
+		if childTarget == NO_MORE_DOCS {
+			s.childDoc, s.parentDoc = NO_MORE_DOCS, NO_MORE_DOCS
+			return s.childDoc, nil
+		}
+		if s.parentDoc, err = s.parentScorer.Advance(childTarget + 1); err != nil {
+			return 0, err
+		}
+		if err = s.validateParentDoc(); err != nil {
+			return 0, err
+		}
+
+		if s.parentDoc == NO_MORE_DOCS {
+			s.childDoc = NO_MORE_DOCS
+			return s.childDoc, nil
+		}
+
+		// scan to the first parent that has children
+		for {
+			firstChild := s.parentBits.PrevSetBit(s.parentDoc-1) + 1
+			if firstChild != s.parentDoc {
+				// this parent has children
+				if firstChild > childTarget {
+					childTarget = firstChild
+				}
+				break
+			}
+			// parent with no children, move to the next one
+			if err = s.nextParentDoc(); err != nil {
+				return 0, err
+			}
+			if s.parentDoc == NO_MORE_DOCS {
+				s.childDoc = NO_MORE_DOCS
+				return s.childDoc, nil
+			}
+		}
+
+		if err = s.scoreParent(); err != nil {
+			return 0, err
+		}
+	}
+
+	assert(childTarget < s.parentDoc)
+	assert(!s.parentBits.At(childTarget))
+	s.childDoc = childTarget
+	if s.acceptDocs != nil && !s.acceptDocs.At(s.childDoc) {
+		return s.NextDoc()
+	}
+	return s.childDoc, nil
+}
@@ -0,0 +1,211 @@
+package join
+
+import (
+	"container/heap"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"math"
+	"sort"
+)
+
+// search/join/ToParentBlockJoinCollector.java
+
+/*
+Collects parent document hits for a Query containing one
+ToParentBlockJoinQuery, and the matching child documents of each
+parent hit. Call TopGroups() after searching to retrieve the parent
+hits, by decreasing score, each grouping its child hits.
+
+The ToParentBlockJoinQuery must be the top-level query of the search;
+child hits are only tracked for the join scorer handed to SetScorer().
+To restrict the parents, use a ToParentBlockJoinQuery child query,
+rather than a filter or an enclosing BooleanQuery.
+*/
+type ToParentBlockJoinCollector struct {
+	numParentHits int
+	docBase       int
+	scorer        *blockJoinScorer
+	query         *ToParentBlockJoinQuery
+	queue         parentHitQueue
+	totalHitCount int
+	maxScore      float32
+}
+
+/*
+Creates a ToParentBlockJoinCollector retaining the numParentHits best
+scored parent hits.
+*/
+func NewToParentBlockJoinCollector(numParentHits int) *ToParentBlockJoinCollector {
+	assert2(numParentHits > 0, "numParentHits must be > 0")
+	return &ToParentBlockJoinCollector{
+		numParentHits: numParentHits,
+		maxScore:      float32(math.NaN()),
+	}
+}
+
+func (c *ToParentBlockJoinCollector) SetNextReader(ctx *index.AtomicReaderContext) {
+	c.docBase = ctx.DocBase
+	c.scorer = nil
+}
+
+func (c *ToParentBlockJoinCollector) SetScorer(scorer search.Scorer) {
+	if s, ok := scorer.(*blockJoinScorer); ok {
+		s.trackPendingChildHits()
+		c.scorer = s
+		c.query = s.weight.(*toParentBlockJoinWeight).joinQuery
+	}
+}
+
+func (c *ToParentBlockJoinCollector) AcceptsDocsOutOfOrder() bool {
+	return false
+}
+
+func (c *ToParentBlockJoinCollector) Collect(parentDoc int) error {
+	assert2(c.scorer != nil, "the ToParentBlockJoinQuery must be the top-level query")
+	score, err := c.scorer.Score()
+	if err != nil {
+		return err
+	}
+	c.totalHitCount++
+	if c.totalHitCount == 1 || score > c.maxScore {
+		c.maxScore = score
+	}
+
+	hit := &parentHit{doc: c.docBase + parentDoc, score: score}
+	if len(c.queue) == c.numParentHits {
+		if !c.queue[0].lessThan(hit) {
+			// this hit is no better than the worst hit in the queue
+			return nil
+		}
+		heap.Pop(&c.queue)
+	}
+	childDocs, childScores := c.scorer.childDocs()
+	hit.childDocs = make([]int, len(childDocs))
+	for i, doc := range childDocs {
+		hit.childDocs[i] = c.docBase + doc
+	}
+	if childScores != nil {
+		hit.childScores = append([]float32(nil), childScores...)
+	}
+	heap.Push(&c.queue, hit)
+	return nil
+}
+
+/* A parent hit, grouping its matching child hits. */
+type GroupDocs struct {
+	// The parent document.
+	GroupValue int
+	// The parent score.
+	Score float32
+	// Max score of the child hits, or NaN if they are not scored.
+	MaxScore float32
+	// Total number of matching children.
+	TotalHits int
+	// The top child hits, by decreasing score then doc ID, or by doc ID
+	// if they are not scored.
+	ScoreDocs []*search.ScoreDoc
+}
+
+/* The parent hits of a block join search, with their child hits. */
+type TopGroups struct {
+	// Number of matching parents.
+	TotalHitCount int
+	// Number of matching children of all returned groups.
+	TotalGroupedHitCount int
+	// Highest parent score, or NaN if there are no hits.
+	MaxScore float32
+	Groups   []*GroupDocs
+}
+
+/*
+Returns the TopGroups for the specified ToParentBlockJoinQuery. The
+groups are sorted by decreasing parent score. offset is the number of
+parent hits to skip, and maxDocsPerGroup the maximum number of child
+hits per group, all if 0.
+
+Returns nil if no parent matched query, or if query was not the
+searched query. This method may be called several times.
+*/
+func (c *ToParentBlockJoinCollector) TopGroups(query *ToParentBlockJoinQuery,
+	offset, maxDocsPerGroup int) *TopGroups {
+
+	if c.query != query || len(c.queue) <= offset {
+		return nil
+	}
+	hits := make([]*parentHit, len(c.queue))
+	copy(hits, c.queue)
+	sort.Sort(sort.Reverse(parentHitQueue(hits)))
+	hits = hits[offset:]
+
+	ans := &TopGroups{
+		TotalHitCount: c.totalHitCount,
+		MaxScore:      c.maxScore,
+		Groups:        make([]*GroupDocs, len(hits)),
+	}
+	for i, hit := range hits {
+		children := make([]*search.ScoreDoc, len(hit.childDocs))
+		group := &GroupDocs{
+			GroupValue: hit.doc,
+			Score:      hit.score,
+			MaxScore:   float32(math.NaN()),
+			TotalHits:  len(children),
+		}
+		for j, doc := range hit.childDocs {
+			score := float32(math.NaN())
+			if hit.childScores != nil {
+				score = hit.childScores[j]
+				if j == 0 || score > group.MaxScore {
+					group.MaxScore = score
+				}
+			}
+			children[j] = search.NewScoreDoc(doc, score)
+		}
+		if hit.childScores != nil {
+			sort.Stable(childHitsByScore(children))
+		}
+		if maxDocsPerGroup > 0 && len(children) > maxDocsPerGroup {
+			children = children[:maxDocsPerGroup]
+		}
+		group.ScoreDocs = children
+		ans.TotalGroupedHitCount += group.TotalHits
+		ans.Groups[i] = group
+	}
+	return ans
+}
+
+type parentHit struct {
+	doc         int
+	score       float32
+	childDocs   []int
+	childScores []float32
+}
+
+/* Returns true if h is less competitive than hit. */
+func (h *parentHit) lessThan(hit *parentHit) bool {
+	if h.score != hit.score {
+		return h.score < hit.score
+	}
+	return h.doc > hit.doc
+}
+
+/* A min-heap of the best parent hits; the worst one on top. */
+type parentHitQueue []*parentHit
+
+func (q parentHitQueue) Len() int            { return len(q) }
+func (q parentHitQueue) Less(i, j int) bool  { return q[i].lessThan(q[j]) }
+func (q parentHitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *parentHitQueue) Push(x interface{}) { *q = append(*q, x.(*parentHit)) }
+func (q *parentHitQueue) Pop() interface{} {
+	old := *q
+	ans := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ans
+}
+
+// child hits are collected in doc order, so a stable sort breaks
+// ties by doc ID
+type childHitsByScore []*search.ScoreDoc
+
+func (a childHitsByScore) Len() int           { return len(a) }
+func (a childHitsByScore) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a childHitsByScore) Less(i, j int) bool { return a[i].Score > a[j].Score }
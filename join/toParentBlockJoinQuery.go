@@ -0,0 +1,389 @@
+package join
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+)
+
+// search/join/ToParentBlockJoinQuery.java
+
+/*
+This query requires that you index children and parent docs as a
+single block, using IndexWriter.AddDocuments() or UpdateDocuments()
+API. In each block, the child documents must appear first, ending
+with the parent document. At search time you provide a Filter
+identifying the parents, however this Filter must provide a
+FixedBitSet per sub-reader (see FixedBitSetCachingWrapperFilter).
+
+Once the block index is built, use this query to wrap any sub-query
+matching only child docs and join matches in that child document
+space up to the parent document space. You can then use this query
+as a clause with other queries in the parent document space.
+
+See ToChildBlockJoinQuery if you need to join in the reverse order.
+
+The child documents must be orthogonal to the parent documents: the
+wrapped child query must never return a parent document.
+
+If you'd like to retrieve the child documents of each parent hit, run
+this query as the top-level query with a ToParentBlockJoinCollector.
+
+Each parent hit is scored from the scores of its matching children,
+as set by the ScoreMode.
+*/
+type ToParentBlockJoinQuery struct {
+	*search.AbstractQuery
+	parentsFilter search.Filter
+	childQuery    search.Query
+	// If we are rewritten, this is the original childQuery we were
+	// passed
+	origChildQuery search.Query
+	scoreMode      ScoreMode
+}
+
+/*
+Create a ToParentBlockJoinQuery.
+
+childQuery is a Query matching child documents; parentsFilter is a
+Filter (must produce FixedBitSet per-segment, like
+FixedBitSetCachingWrapperFilter) identifying the parent documents;
+scoreMode is how to aggregate multiple child scores into a single
+parent score.
+*/
+func NewToParentBlockJoinQuery(childQuery search.Query, parentsFilter search.Filter,
+	scoreMode ScoreMode) *ToParentBlockJoinQuery {
+
+	return newToParentBlockJoinQuery(childQuery, childQuery, parentsFilter, scoreMode)
+}
+
+func newToParentBlockJoinQuery(origChildQuery, childQuery search.Query,
+	parentsFilter search.Filter, scoreMode ScoreMode) *ToParentBlockJoinQuery {
+
+	assert2(childQuery != nil, "childQuery must not be nil")
+	assert2(parentsFilter != nil, "parentsFilter must not be nil")
+	ans := &ToParentBlockJoinQuery{
+		parentsFilter:  parentsFilter,
+		childQuery:     childQuery,
+		origChildQuery: origChildQuery,
+		scoreMode:      scoreMode,
+	}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+/* Return our child query. */
+func (q *ToParentBlockJoinQuery) ChildQuery() search.Query {
+	return q.childQuery
+}
+
+func (q *ToParentBlockJoinQuery) CreateWeight(searcher *search.IndexSearcher) (search.Weight, error) {
+	childWeight, err := q.childQuery.CreateWeight(searcher)
+	if err != nil {
+		return nil, err
+	}
+	ans := &toParentBlockJoinWeight{
+		joinQuery:     q,
+		childWeight:   childWeight,
+		parentsFilter: q.parentsFilter,
+		scoreMode:     q.scoreMode,
+	}
+	ans.WeightImpl = search.NewWeightImpl(ans)
+	return ans, nil
+}
+
+func (q *ToParentBlockJoinQuery) Rewrite(reader index.IndexReader) search.Query {
+	if childRewrite := q.childQuery.Rewrite(reader); childRewrite != q.childQuery {
+		ans := newToParentBlockJoinQuery(q.origChildQuery, childRewrite, q.parentsFilter, q.scoreMode)
+		ans.SetBoost(q.Boost())
+		return ans
+	}
+	return q
+}
+
+func (q *ToParentBlockJoinQuery) Clone() search.Query {
+	ans := newToParentBlockJoinQuery(q.origChildQuery.Clone(), q.childQuery.Clone(),
+		q.parentsFilter, q.scoreMode)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *ToParentBlockJoinQuery) ToString(field string) string {
+	return fmt.Sprintf("ToParentBlockJoinQuery (%v)", q.childQuery.ToString(field))
+}
+
+type toParentBlockJoinWeight struct {
+	*search.WeightImpl
+	joinQuery     *ToParentBlockJoinQuery
+	childWeight   search.Weight
+	parentsFilter search.Filter
+	scoreMode     ScoreMode
+}
+
+func (w *toParentBlockJoinWeight) ValueForNormalization() float32 {
+	boost := w.joinQuery.Boost()
+	return w.childWeight.ValueForNormalization() * boost * boost
+}
+
+func (w *toParentBlockJoinWeight) Normalize(norm, topLevelBoost float32) {
+	w.childWeight.Normalize(norm, topLevelBoost*w.joinQuery.Boost())
+}
+
+func (w *toParentBlockJoinWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+// NOTE: acceptDocs applies (and is checked) only in the parent document space
+func (w *toParentBlockJoinWeight) Scorer(ctx *index.AtomicReaderContext,
+	acceptDocs util.Bits) (search.Scorer, error) {
+
+	childScorer, err := w.childWeight.Scorer(ctx, ctx.Reader().(index.AtomicReader).LiveDocs())
+	if childScorer == nil || err != nil {
+		// No matches
+		return nil, err
+	}
+	firstChildDoc, err := childScorer.NextDoc()
+	if err != nil {
+		return nil, err
+	}
+	if firstChildDoc == NO_MORE_DOCS {
+		// No matches
+		return nil, nil
+	}
+
+	// NOTE: we cannot pass acceptDocs here because this will (most
+	// likely, depending on the filter) prevent us from finding
+	// parents of deleted children
+	parents, err := parentBits(w.parentsFilter, ctx)
+	if parents == nil || err != nil {
+		// No matches
+		return nil, err
+	}
+	return newBlockJoinScorer(w, childScorer, parents, firstChildDoc, w.scoreMode, acceptDocs), nil
+}
+
+func (w *toParentBlockJoinWeight) Explain(ctx *index.AtomicReaderContext, doc int) (search.Explanation, error) {
+	scorer, err := w.Scorer(ctx, ctx.Reader().(index.AtomicReader).LiveDocs())
+	if err != nil {
+		return nil, err
+	}
+	if scorer != nil {
+		target, err := scorer.Advance(doc)
+		if err != nil {
+			return nil, err
+		}
+		if target == doc {
+			return scorer.(*blockJoinScorer).explain(ctx.DocBase)
+		}
+	}
+	return search.NewComplexExplanation(false, 0, "Not a match"), nil
+}
+
+/*
+Scores parent documents from their matching children, which must
+precede them in their block.
+*/
+type blockJoinScorer struct {
+	weight       search.Weight
+	childScorer  search.Scorer
+	parentBits   *util.FixedBitSet
+	scoreMode    ScoreMode
+	acceptDocs   util.Bits
+	parentDoc    int
+	parentScore  float32
+	parentFreq   int
+	nextChildDoc int
+	// child hits of the current parent, if tracked
+	pendingChildDocs   []int
+	pendingChildScores []float32
+	childDocUpto       int
+}
+
+func newBlockJoinScorer(weight search.Weight, childScorer search.Scorer, parentBits *util.FixedBitSet,
+	firstChildDoc int, scoreMode ScoreMode, acceptDocs util.Bits) *blockJoinScorer {
+
+	return &blockJoinScorer{
+		weight:       weight,
+		childScorer:  childScorer,
+		parentBits:   parentBits,
+		scoreMode:    scoreMode,
+		acceptDocs:   acceptDocs,
+		parentDoc:    -1,
+		nextChildDoc: firstChildDoc,
+	}
+}
+
+func (s *blockJoinScorer) Weight() search.Weight { return s.weight }
+func (s *blockJoinScorer) DocId() int            { return s.parentDoc }
+func (s *blockJoinScorer) Freq() (int, error)    { return s.parentFreq, nil }
+func (s *blockJoinScorer) Cost() int64           { return s.childScorer.Cost() }
+
+func (s *blockJoinScorer) Score() (float32, error) {
+	return s.parentScore, nil
+}
+
+/* Records the matching children of each parent, see childDocs(). */
+func (s *blockJoinScorer) trackPendingChildHits() {
+	s.pendingChildDocs = make([]int, 0, 5)
+	if s.scoreMode != SCORE_MODE_NONE {
+		s.pendingChildScores = make([]float32, 0, 5)
+	}
+}
+
+/*
+Returns the matching children of the current parent, and their scores
+unless the score mode is SCORE_MODE_NONE. Only valid if child hits
+are tracked.
+*/
+func (s *blockJoinScorer) childDocs() ([]int, []float32) {
+	return s.pendingChildDocs, s.pendingChildScores
+}
+
+func (s *blockJoinScorer) orthogonalityError(doc int) error {
+	return errors.New(fmt.Sprintf(
+		"child query must only match non-parent docs, but parent docID=%v matched childScorer=%T",
+		doc, s.childScorer))
+}
+
+func (s *blockJoinScorer) NextDoc() (int, error) {
+	for {
+		if s.nextChildDoc == NO_MORE_DOCS {
+			s.parentDoc = NO_MORE_DOCS
+			return s.parentDoc, nil
+		}
+
+		// Gather all children sharing the same parent as nextChildDoc
+		if s.parentDoc = s.parentBits.NextSetBit(s.nextChildDoc); s.parentDoc == -1 {
+			// children after the last parent; not a valid block
+			s.parentDoc = NO_MORE_DOCS
+			return s.parentDoc, nil
+		}
+
+		// Parent & child docs are supposed to be orthogonal:
+		if s.nextChildDoc == s.parentDoc {
+			return 0, s.orthogonalityError(s.parentDoc)
+		}
+
+		var err error
+		if s.acceptDocs != nil && !s.acceptDocs.At(s.parentDoc) {
+			// Parent doc not accepted; skip child docs until we hit a new
+			// parent doc:
+			for s.nextChildDoc < s.parentDoc {
+				if s.nextChildDoc, err = s.childScorer.NextDoc(); err != nil {
+					return 0, err
+				}
+			}
+			// Parent & child docs are supposed to be orthogonal:
+			if s.nextChildDoc == s.parentDoc {
+				return 0, s.orthogonalityError(s.parentDoc)
+			}
+			continue
+		}
+
+		var totalScore float32
+		maxScore := float32(math.Inf(-1))
+		s.childDocUpto = 0
+		s.parentFreq = 0
+		if s.pendingChildDocs != nil {
+			s.pendingChildDocs = s.pendingChildDocs[:0]
+		}
+		if s.pendingChildScores != nil {
+			s.pendingChildScores = s.pendingChildScores[:0]
+		}
+		for s.nextChildDoc < s.parentDoc {
+			if s.pendingChildDocs != nil {
+				s.pendingChildDocs = append(s.pendingChildDocs, s.nextChildDoc)
+			}
+			if s.scoreMode != SCORE_MODE_NONE {
+				// TODO: specialize this into dedicated classes per-scoreMode
+				childScore, err := s.childScorer.Score()
+				if err != nil {
+					return 0, err
+				}
+				childFreq, err := s.childScorer.Freq()
+				if err != nil {
+					return 0, err
+				}
+				if s.pendingChildScores != nil {
+					s.pendingChildScores = append(s.pendingChildScores, childScore)
+				}
+				if childScore > maxScore {
+					maxScore = childScore
+				}
+				totalScore += childScore
+				s.parentFreq += childFreq
+			}
+			s.childDocUpto++
+			if s.nextChildDoc, err = s.childScorer.NextDoc(); err != nil {
+				return 0, err
+			}
+		}
+
+		// Parent & child docs are supposed to be orthogonal:
+		if s.nextChildDoc == s.parentDoc {
+			return 0, s.orthogonalityError(s.parentDoc)
+		}
+
+		switch s.scoreMode {
+		case SCORE_MODE_AVG:
+			s.parentScore = totalScore / float32(s.childDocUpto)
+		case SCORE_MODE_MAX:
+			s.parentScore = maxScore
+		case SCORE_MODE_TOTAL:
+			s.parentScore = totalScore
+		case SCORE_MODE_NONE:
+		}
+		return s.parentDoc, nil
+	}
+}
+
+func (s *blockJoinScorer) Advance(parentTarget int) (int, error) {
+	if parentTarget == NO_MORE_DOCS {
+		s.parentDoc = NO_MORE_DOCS
+		return s.parentDoc, nil
+	}
+
+	if parentTarget == 0 {
+		// Callers should only be passing in a docID from the parent
+		// space, so this means this parent has no children (it got
+		// docID 0), so it cannot possibly match. We must handle this
+		// case separately otherwise we pass invalid -1 to PrevSetBit
+		// below:
+		return s.NextDoc()
+	}
+
+	prevParentDoc := s.parentBits.PrevSetBit(parentTarget - 1)
+	assert(prevParentDoc >= s.parentDoc)
+	if prevParentDoc > s.nextChildDoc {
+		var err error
+		if s.nextChildDoc, err = s.childScorer.Advance(prevParentDoc); err != nil {
+			return 0, err
+		}
+	}
+
+	// Parent & child docs are supposed to be orthogonal:
+	if s.nextChildDoc == prevParentDoc {
+		return 0, s.orthogonalityError(prevParentDoc)
+	}
+	return s.NextDoc()
+}
+
+func (s *blockJoinScorer) explain(docBase int) (search.Explanation, error) {
+	start := docBase
+	if s.parentDoc > 0 {
+		start += s.parentBits.PrevSetBit(s.parentDoc-1) + 1 // +1 b/c prevParentDoc is previous parent doc
+	}
+	end := docBase + s.parentDoc - 1 // -1 b/c parentDoc is parent doc
+	return search.NewComplexExplanation(true, s.parentScore,
+		fmt.Sprintf("Score based on child doc range from %v to %v", start, end)), nil
+}
+
+func assert(ok bool) {
+	if !ok {
+		panic("assert fail")
+	}
+}
@@ -0,0 +1,568 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/analysis"
+	ta "github.com/jtejido/golucene/core/analysis/tokenattributes"
+	"github.com/jtejido/golucene/core/codec/spi"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"sort"
+)
+
+// index/memory/MemoryIndex.java
+
+/*
+High-performance single-document main memory index.
+
+A MemoryIndex holds the fields of one document, analyzed into terms,
+positions, (optionally) offsets, payloads and norms, and exposes them
+as an AtomicReader, so that any Query can be run against the document
+with a regular IndexSearcher, without writing anything to a
+Directory. This suits matching a stream of incoming documents against
+many stored queries (see the monitor package), classification, or
+highlighting.
+
+Typical usage:
+
+	mi := memory.NewMemoryIndex(false)
+	mi.AddField("content", "Readings about Salmons and other select Alaska fishing Manuals", analyzer)
+	mi.AddField("author", "Tales of James", analyzer)
+	score, err := mi.Search(query)
+	if score > 0 {
+		// it's a match
+	}
+
+A field may be added several times; its values are then separated by
+the analyzer's position increment and offset gaps.
+
+A MemoryIndex is not safe for concurrent use. Fields must not be
+added while a reader or searcher created from it is in use.
+*/
+type MemoryIndex struct {
+	fields       map[string]*memoryField
+	storeOffsets bool
+	similarity   index.Similarity // to compute norms
+}
+
+/*
+Constructs an empty instance. If storeOffsets is true, the start and
+end character offsets of tokens are stored, as needed e.g. for
+highlighting.
+*/
+func NewMemoryIndex(storeOffsets bool) *MemoryIndex {
+	return &MemoryIndex{
+		fields:       make(map[string]*memoryField),
+		storeOffsets: storeOffsets,
+	}
+}
+
+/*
+Sets the Similarity used to compute norms. If it is also a
+search.Similarity, searchers created by CreateSearcher() use it to
+score. The default is index.DefaultSimilarity().
+*/
+func (mi *MemoryIndex) SetSimilarity(similarity index.Similarity) {
+	mi.similarity = similarity
+}
+
+/*
+Convenience method; tokenizes the given field text with analyzer and
+adds the resulting terms to the index.
+*/
+func (mi *MemoryIndex) AddField(fieldName, text string, analyzer analysis.Analyzer) error {
+	assert2(analyzer != nil, "analyzer must not be nil")
+	stream, err := analyzer.TokenStreamForString(fieldName, text)
+	if err != nil {
+		return err
+	}
+	return mi.AddTokenStream(fieldName, stream, 1,
+		analyzer.PositionIncrementGap(fieldName), analyzer.OffsetGap(fieldName))
+}
+
+/*
+Adds the terms of field, as IndexWriter would index them: tokenized
+fields are analyzed with analyzer, other indexed fields are added as
+a single term, and fields not indexed are ignored.
+*/
+func (mi *MemoryIndex) AddIndexableField(field IndexableField, analyzer analysis.Analyzer) error {
+	if !field.FieldType().Indexed() {
+		return nil
+	}
+	stream, err := field.TokenStream(analyzer, nil)
+	if err != nil {
+		return err
+	}
+	posIncGap, offsetGap := 0, 1
+	if analyzer != nil {
+		posIncGap = analyzer.PositionIncrementGap(field.Name())
+		offsetGap = analyzer.OffsetGap(field.Name())
+	}
+	return mi.AddTokenStream(field.Name(), stream, field.Boost(), posIncGap, offsetGap)
+}
+
+/*
+Iterates over the given token stream and adds the resulting terms to
+the index, with the given boost. If the field was already added, the
+positions and offsets of the new tokens are shifted by
+positionIncrementGap and offsetGap respectively. The stream is closed.
+*/
+func (mi *MemoryIndex) AddTokenStream(fieldName string, stream analysis.TokenStream,
+	boost float32, positionIncrementGap, offsetGap int) (err error) {
+
+	assert2(fieldName != "", "fieldName must not be empty")
+	assert2(stream != nil, "token stream must not be nil")
+	assert2(boost > 0, "boost factor must be greater than 0.0")
+	defer func() {
+		if e := stream.Close(); err == nil {
+			err = e
+		}
+	}()
+
+	info, ok := mi.fields[fieldName]
+	pos, offset := -1, 0
+	if ok {
+		pos = info.lastPosition + positionIncrementGap
+		offset = info.lastOffset + offsetGap
+	} else {
+		info = &memoryField{
+			name:  fieldName,
+			terms: make(map[string][]*occurrence),
+			boost: 1,
+		}
+	}
+
+	atts := stream.Attributes()
+	termAtt, ok := atts.Get("TermToBytesRefAttribute").(ta.TermToBytesRefAttribute)
+	if !ok {
+		return errors.New(fmt.Sprintf("token stream of field %v has no term attribute", fieldName))
+	}
+	posIncrAtt := atts.Add("PositionIncrementAttribute").(ta.PositionIncrementAttribute)
+	offsetAtt := atts.Add("OffsetAttribute").(ta.OffsetAttribute)
+	var payloadAtt ta.PayloadAttribute
+	if atts.Has("PayloadAttribute") {
+		payloadAtt = atts.Get("PayloadAttribute").(ta.PayloadAttribute)
+	}
+	bytesRef := termAtt.BytesRef()
+
+	if err = stream.Reset(); err != nil {
+		return err
+	}
+	numTokens := 0
+	for {
+		var more bool
+		if more, err = stream.IncrementToken(); err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+		termAtt.FillBytesRef()
+		posIncr := posIncrAtt.PositionIncrement()
+		if posIncr == 0 {
+			info.numOverlapTokens++
+		}
+		pos += posIncr
+		numTokens++
+
+		occ := &occurrence{position: pos, startOffset: -1, endOffset: -1}
+		if mi.storeOffsets {
+			occ.startOffset = offset + offsetAtt.StartOffset()
+			occ.endOffset = offset + offsetAtt.EndOffset()
+		}
+		if payloadAtt != nil {
+			if payload := payloadAtt.Payload(); len(payload) > 0 {
+				occ.payload = append([]byte(nil), payload...)
+				info.hasPayloads = true
+			}
+		}
+		term := string(bytesRef.ToBytes())
+		info.terms[term] = append(info.terms[term], occ)
+	}
+	if err = stream.End(); err != nil {
+		return err
+	}
+
+	// ensure infos.numTokens > 0 invariant; needed for correct operation
+	if numTokens > 0 {
+		info.numTokens += numTokens
+		info.boost *= boost
+		info.lastPosition = pos
+		info.lastOffset = offset + offsetAtt.EndOffset()
+		info.sortedTerms = nil
+		mi.fields[fieldName] = info
+	}
+	return nil
+}
+
+/*
+Creates and returns a reader over the current content of this index.
+*/
+func (mi *MemoryIndex) CreateReader() *index.AtomicReaderImpl {
+	similarity := mi.similarity
+	if similarity == nil {
+		similarity = index.DefaultSimilarity()
+	}
+
+	names := make([]string, 0, len(mi.fields))
+	for name := range mi.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r := &memoryIndexReader{
+		fields: make(map[string]*memoryField),
+		norms:  make(map[string]int64),
+	}
+	infos := make([]*FieldInfo, len(names))
+	for i, name := range names {
+		info := mi.fields[name]
+		info.sortTerms()
+		r.fields[name] = info
+		indexOptions := INDEX_OPT_DOCS_AND_FREQS_AND_POSITIONS
+		if mi.storeOffsets {
+			indexOptions = INDEX_OPT_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+		}
+		infos[i] = NewFieldInfo(name, true, int32(i), false, false, info.hasPayloads,
+			indexOptions, 0, DOC_VALUES_TYPE_NUMERIC, -1, nil)
+		r.norms[name] = similarity.ComputeNorm(index.NewFieldInvertState(
+			name, info.numTokens, info.numTokens, info.numOverlapTokens, 0, info.boost))
+	}
+	r.fieldInfos = NewFieldInfos(infos)
+	return index.NewAtomicReader(r)
+}
+
+/*
+Creates and returns a searcher that can be used to execute arbitrary
+queries against the current content of this index.
+*/
+func (mi *MemoryIndex) CreateSearcher() *search.IndexSearcher {
+	searcher := search.NewIndexSearcher(mi.CreateReader())
+	if similarity, ok := mi.similarity.(search.Similarity); ok {
+		searcher.SetSimilarity(similarity)
+	}
+	return searcher
+}
+
+/*
+Convenience method that efficiently returns the relevance score by
+matching this index against the given query. Returns a score > 0 if
+the query matches, 0 otherwise.
+*/
+func (mi *MemoryIndex) Search(query search.Query) (float32, error) {
+	assert2(query != nil, "query must not be nil")
+	topDocs, err := mi.CreateSearcher().SearchTop(query, 1)
+	if err != nil {
+		return 0, err
+	}
+	if topDocs.TotalHits == 0 {
+		return 0, nil
+	}
+	return topDocs.ScoreDocs[0].Score, nil
+}
+
+/* Resets the index to an empty state, ready to accept new fields. */
+func (mi *MemoryIndex) Reset() {
+	mi.fields = make(map[string]*memoryField)
+}
+
+/*
+Returns a string representation of the index data, for debugging
+purposes.
+*/
+func (mi *MemoryIndex) String() string {
+	names := make([]string, 0, len(mi.fields))
+	for name := range mi.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		info := mi.fields[name]
+		info.sortTerms()
+		fmt.Fprintf(&buf, "%v:\n", name)
+		for _, term := range info.sortedTerms {
+			fmt.Fprintf(&buf, "\t'%v':%v:[", term, len(info.terms[term]))
+			for i, occ := range info.terms[term] {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				fmt.Fprintf(&buf, "%v", occ.position)
+				if mi.storeOffsets {
+					fmt.Fprintf(&buf, "(%v..%v)", occ.startOffset, occ.endOffset)
+				}
+			}
+			buf.WriteString("]\n")
+		}
+		fmt.Fprintf(&buf, "\tterms=%v, positions=%v\n", len(info.sortedTerms), info.numTokens)
+	}
+	return buf.String()
+}
+
+type occurrence struct {
+	position, startOffset, endOffset int
+	payload                          []byte
+}
+
+/* Per-field information. */
+type memoryField struct {
+	name        string
+	terms       map[string][]*occurrence
+	sortedTerms []string // nil until sortTerms()
+
+	// number of added tokens, and of those at the same position
+	numTokens, numOverlapTokens int
+	boost                       float32
+	// for fields added several times
+	lastPosition, lastOffset int
+	hasPayloads              bool
+}
+
+func (f *memoryField) sortTerms() {
+	if f.sortedTerms == nil {
+		f.sortedTerms = make([]string, 0, len(f.terms))
+		for term := range f.terms {
+			f.sortedTerms = append(f.sortedTerms, term)
+		}
+		// byte-wise, as the terms of any other reader
+		sort.Strings(f.sortedTerms)
+	}
+}
+
+func (f *memoryField) sumTotalTermFreq() int64 {
+	n := 0
+	for _, occs := range f.terms {
+		n += len(occs)
+	}
+	return int64(n)
+}
+
+// Search support for the MemoryIndex: a reader over its single
+// document.
+
+type memoryIndexReader struct {
+	fields     map[string]*memoryField
+	norms      map[string]int64
+	fieldInfos FieldInfos
+}
+
+func (r *memoryIndexReader) NumDocs() int { return 1 }
+func (r *memoryIndexReader) MaxDoc() int  { return 1 }
+
+func (r *memoryIndexReader) VisitDocument(docID int, visitor spi.StoredFieldVisitor) error {
+	return nil // no stored fields
+}
+
+func (r *memoryIndexReader) Fields() Fields {
+	return r
+}
+
+/* Implements Fields */
+func (r *memoryIndexReader) Terms(field string) Terms {
+	if info, ok := r.fields[field]; ok {
+		return &memoryTerms{info}
+	}
+	return nil
+}
+
+func (r *memoryIndexReader) LiveDocs() util.Bits {
+	return nil // no deletions
+}
+
+func (r *memoryIndexReader) NormValues(field string) (spi.NumericDocValues, error) {
+	norm, ok := r.norms[field]
+	if !ok {
+		return nil, nil
+	}
+	return func(docID int) int64 {
+		assert(docID == 0)
+		return norm
+	}, nil
+}
+
+func (r *memoryIndexReader) FieldInfos() FieldInfos {
+	return r.fieldInfos
+}
+
+func (r *memoryIndexReader) VectorValues(field string) VectorValues {
+	return nil // dense vectors aren't indexed in memory
+}
+
+func (r *memoryIndexReader) SearchNearestVectors(field string, target []float32, k int,
+	acceptDocs util.Bits, visitedLimit int) ([]int, []float32, bool, error) {
+	return nil, nil, true, nil
+}
+
+type memoryTerms struct {
+	info *memoryField
+}
+
+func (t *memoryTerms) Iterator(reuse TermsEnum) TermsEnum {
+	return newMemoryTermsEnum(t.info)
+}
+
+func (t *memoryTerms) DocCount() int {
+	return 1
+}
+
+func (t *memoryTerms) SumTotalTermFreq() int64 {
+	return t.info.sumTotalTermFreq()
+}
+
+func (t *memoryTerms) SumDocFreq() int64 {
+	// each term has df=1
+	return int64(len(t.info.sortedTerms))
+}
+
+type memoryTermsEnum struct {
+	*TermsEnumImpl
+	info     *memoryField
+	termUpto int
+}
+
+func newMemoryTermsEnum(info *memoryField) *memoryTermsEnum {
+	ans := &memoryTermsEnum{info: info, termUpto: -1}
+	ans.TermsEnumImpl = NewTermsEnumImpl(ans)
+	return ans
+}
+
+func (e *memoryTermsEnum) Next() ([]byte, error) {
+	if e.termUpto+1 >= len(e.info.sortedTerms) {
+		e.termUpto = len(e.info.sortedTerms)
+		return nil, nil
+	}
+	e.termUpto++
+	return []byte(e.info.sortedTerms[e.termUpto]), nil
+}
+
+func (e *memoryTermsEnum) SeekCeil(text []byte) (SeekStatus, error) {
+	term := string(text)
+	e.termUpto = sort.SearchStrings(e.info.sortedTerms, term)
+	if e.termUpto == len(e.info.sortedTerms) {
+		return SEEK_STATUS_END, nil
+	}
+	if e.info.sortedTerms[e.termUpto] == term {
+		return SEEK_STATUS_FOUND, nil
+	}
+	return SEEK_STATUS_NOT_FOUND, nil
+}
+
+func (e *memoryTermsEnum) SeekExactByPosition(ord int64) error {
+	assert(ord >= 0 && ord < int64(len(e.info.sortedTerms)))
+	e.termUpto = int(ord)
+	return nil
+}
+
+func (e *memoryTermsEnum) Term() []byte {
+	return []byte(e.info.sortedTerms[e.termUpto])
+}
+
+func (e *memoryTermsEnum) Ord() int64 {
+	return int64(e.termUpto)
+}
+
+func (e *memoryTermsEnum) DocFreq() (int, error) {
+	return 1, nil
+}
+
+func (e *memoryTermsEnum) TotalTermFreq() (int64, error) {
+	return int64(len(e.occurrences())), nil
+}
+
+func (e *memoryTermsEnum) occurrences() []*occurrence {
+	return e.info.terms[e.info.sortedTerms[e.termUpto]]
+}
+
+func (e *memoryTermsEnum) DocsByFlags(liveDocs util.Bits, reuse DocsEnum, flags int) (DocsEnum, error) {
+	return newMemoryDocsAndPositionsEnum(liveDocs, e.occurrences()), nil
+}
+
+func (e *memoryTermsEnum) DocsAndPositionsByFlags(liveDocs util.Bits,
+	reuse DocsAndPositionsEnum, flags int) (DocsAndPositionsEnum, error) {
+
+	return newMemoryDocsAndPositionsEnum(liveDocs, e.occurrences()), nil
+}
+
+type memoryDocsAndPositionsEnum struct {
+	liveDocs    util.Bits
+	occurrences []*occurrence
+	doc         int
+	posUpto     int
+}
+
+func newMemoryDocsAndPositionsEnum(liveDocs util.Bits, occurrences []*occurrence) *memoryDocsAndPositionsEnum {
+	return &memoryDocsAndPositionsEnum{
+		liveDocs:    liveDocs,
+		occurrences: occurrences,
+		doc:         -1,
+	}
+}
+
+func (e *memoryDocsAndPositionsEnum) DocId() int {
+	return e.doc
+}
+
+func (e *memoryDocsAndPositionsEnum) NextDoc() (int, error) {
+	if e.doc == -1 && (e.liveDocs == nil || e.liveDocs.At(0)) {
+		e.doc = 0
+	} else {
+		e.doc = NO_MORE_DOCS
+	}
+	e.posUpto = 0
+	return e.doc, nil
+}
+
+func (e *memoryDocsAndPositionsEnum) Advance(target int) (int, error) {
+	if target > 0 {
+		e.doc = NO_MORE_DOCS
+		return e.doc, nil
+	}
+	return e.NextDoc()
+}
+
+func (e *memoryDocsAndPositionsEnum) Cost() int64 {
+	return 1
+}
+
+func (e *memoryDocsAndPositionsEnum) Freq() (int, error) {
+	return len(e.occurrences), nil
+}
+
+func (e *memoryDocsAndPositionsEnum) NextPosition() (int, error) {
+	assert(e.posUpto < len(e.occurrences))
+	e.posUpto++
+	return e.occurrences[e.posUpto-1].position, nil
+}
+
+func (e *memoryDocsAndPositionsEnum) StartOffset() (int, error) {
+	return e.occurrences[e.posUpto-1].startOffset, nil
+}
+
+func (e *memoryDocsAndPositionsEnum) EndOffset() (int, error) {
+	return e.occurrences[e.posUpto-1].endOffset, nil
+}
+
+func (e *memoryDocsAndPositionsEnum) Payload() (*util.BytesRef, error) {
+	if payload := e.occurrences[e.posUpto-1].payload; payload != nil {
+		return util.NewBytesRefFrom(payload), nil
+	}
+	return nil, nil
+}
+
+func assert(ok bool) {
+	if !ok {
+		panic("assert fail")
+	}
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
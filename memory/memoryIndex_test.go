@@ -0,0 +1,170 @@
+package memory_test
+
+import (
+	ac "github.com/jtejido/golucene/analysis/core"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/memory"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"math"
+	"strings"
+	"testing"
+)
+
+var memoryTestDoc = map[string]string{
+	"title":   "readings about salmons",
+	"content": "readings about salmons and other select alaska fishing manuals about salmons",
+}
+
+func memoryTestQueries() []search.Query {
+	term := func(field, text string) search.Query {
+		return search.NewTermQuery(index.NewTerm(field, text))
+	}
+	phrase := func(field string, slop int, words ...string) search.Query {
+		q := search.NewPhraseQuery()
+		for _, w := range words {
+			q.Add(index.NewTerm(field, w))
+		}
+		q.SetSlop(slop)
+		return q
+	}
+	conj := search.NewBooleanQuery()
+	conj.Add(term("content", "alaska"), search.MUST)
+	conj.Add(term("title", "salmons"), search.MUST)
+	disj := search.NewBooleanQuery()
+	disj.Add(term("content", "fishing"), search.SHOULD)
+	disj.Add(term("content", "hunting"), search.SHOULD)
+	neg := search.NewBooleanQuery()
+	neg.Add(term("content", "fishing"), search.MUST)
+	neg.Add(term("content", "manuals"), search.MUST_NOT)
+	return []search.Query{
+		term("content", "salmons"),
+		term("content", "trout"),
+		term("unknown", "salmons"),
+		phrase("content", 0, "about", "salmons"),
+		phrase("content", 0, "salmons", "about"),
+		phrase("content", 2, "alaska", "manuals"),
+		conj,
+		disj,
+		neg,
+	}
+}
+
+func TestMemoryIndexAgainstDirectory(t *testing.T) {
+	analyzer := ac.NewWhitespaceAnalyzer()
+
+	d := docu.NewDocument()
+	mi := memory.NewMemoryIndex(true)
+	for field, text := range memoryTestDoc {
+		d.Add(docu.NewTextFieldFromString(field, text, docu.STORE_NO))
+		if err := mi.AddField(field, text, analyzer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := testindex.NewReader(t, d.Fields())
+	searcher := search.NewIndexSearcher(r)
+
+	matches := 0
+	for _, q := range memoryTestQueries() {
+		docs, err := searcher.SearchTop(q, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected float32
+		if docs.TotalHits > 0 {
+			expected = docs.ScoreDocs[0].Score
+			matches++
+		}
+		score, err := mi.Search(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(float64(score-expected)) > 1e-6 {
+			t.Errorf("%v: expected score %v, but %v", q, expected, score)
+		}
+	}
+	if matches != 6 {
+		t.Errorf("expected 6 matching queries, but %v", matches)
+	}
+}
+
+func TestMemoryIndexPostings(t *testing.T) {
+	testindex.UseDefaultSimilarity()
+	analyzer := ac.NewWhitespaceAnalyzer()
+	mi := memory.NewMemoryIndex(true)
+	if err := mi.AddField("f", "a b a", analyzer); err != nil {
+		t.Fatal(err)
+	}
+	// a second value continues after the gaps
+	if err := mi.AddField("f", "c a", analyzer); err != nil {
+		t.Fatal(err)
+	}
+
+	r := mi.CreateReader()
+	if n := r.FieldInfos().Size(); n != 1 {
+		t.Fatalf("expected 1 field, but %v", n)
+	}
+	terms := r.Terms("f")
+	if terms == nil {
+		t.Fatal("missing terms")
+	}
+	if n := terms.SumTotalTermFreq(); n != 5 {
+		t.Errorf("expected 5 tokens, but %v", n)
+	}
+	var seen []string
+	te := terms.Iterator(nil)
+	for {
+		term, err := te.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if term == nil {
+			break
+		}
+		seen = append(seen, string(term))
+	}
+	if s := strings.Join(seen, ","); s != "a,b,c" {
+		t.Errorf("expected terms a,b,c, but %v", s)
+	}
+
+	ok, err := te.SeekExact([]byte("a"))
+	if err != nil || !ok {
+		t.Fatalf("term a not found: %v", err)
+	}
+	dp, err := te.DocsAndPositions(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, _ := dp.NextDoc(); doc != 0 {
+		t.Fatalf("expected doc 0, but %v", doc)
+	}
+	if freq, _ := dp.Freq(); freq != 3 {
+		t.Fatalf("expected freq 3, but %v", freq)
+	}
+	// "a b a" then, after the offset gap of 1, "c a"
+	for _, expected := range [][3]int{{0, 0, 1}, {2, 4, 5}, {4, 8, 9}} {
+		pos, _ := dp.NextPosition()
+		start, _ := dp.StartOffset()
+		end, _ := dp.EndOffset()
+		if got := [3]int{pos, start, end}; got != expected {
+			t.Errorf("expected position/offsets %v, but %v", expected, got)
+		}
+	}
+
+	norms, err := r.NormValues("f")
+	if err != nil || norms == nil {
+		t.Fatalf("missing norms: %v", err)
+	}
+	if norms(0) == 0 {
+		t.Error("expected a non-zero norm")
+	}
+	if r.Terms("g") != nil {
+		t.Error("expected no terms for unknown field")
+	}
+
+	mi.Reset()
+	if score, err := mi.Search(search.NewTermQuery(index.NewTerm("f", "a"))); err != nil || score != 0 {
+		t.Errorf("expected no match after reset, but %v (%v)", score, err)
+	}
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+)
+
+/*
+Returns terms such that any document matched by query contains at
+least one of them, or false if no such set can be determined, in
+which case query must be run against every document.
+
+Where all of several terms are required (phrases, conjunctions), only
+the most selective ones are returned: the longest term of a phrase,
+and the required clause with the fewest terms.
+*/
+func extractTerms(query search.Query) ([]*index.Term, bool) {
+	switch q := query.(type) {
+	case *search.BooleanQuery:
+		return extractBooleanTerms(q)
+	case *search.PhraseQuery:
+		return longestTerm(q.Terms()), true
+	case *search.MultiPhraseQuery:
+		// any position is required; pick the one with fewest alternatives
+		var ans []*index.Term
+		for _, terms := range q.TermArrays() {
+			if ans == nil || len(terms) < len(ans) {
+				ans = terms
+			}
+		}
+		return ans, true
+	case *search.ConstantScoreQuery:
+		if q.Query() != nil {
+			return extractTerms(q.Query())
+		}
+		return nil, false
+	case interface {
+		Term() *index.Term
+	}: // TermQuery, SpanTermQuery, PayloadTermQuery
+		return []*index.Term{q.Term()}, true
+	case interface {
+		Clauses() []search.SpanQuery
+	}: // SpanNearQuery, PayloadNearQuery
+		var ans []*index.Term
+		for _, clause := range q.Clauses() {
+			terms, ok := extractTerms(clause)
+			if ok && (ans == nil || len(terms) < len(ans)) {
+				ans = terms
+			}
+		}
+		return ans, ans != nil
+	}
+	return nil, false
+}
+
+func extractBooleanTerms(q *search.BooleanQuery) ([]*index.Term, bool) {
+	var required, optional []*index.Term
+	var mustMatch bool
+	hasRequired, hasOptional, optionalOK := false, false, true
+	for _, clause := range q.Clauses() {
+		switch clause.Occur() {
+		case search.MUST:
+			mustMatch = true
+			if terms, ok := extractTerms(clause.Query()); ok &&
+				(!hasRequired || len(terms) < len(required)) {
+				required, hasRequired = terms, true
+			}
+		case search.SHOULD:
+			hasOptional = true
+			terms, ok := extractTerms(clause.Query())
+			optional = append(optional, terms...)
+			optionalOK = optionalOK && ok
+		}
+	}
+	if mustMatch {
+		// optional clauses need not match then
+		return required, hasRequired
+	}
+	if hasOptional && optionalOK {
+		return optional, true
+	}
+	// only prohibited clauses, or an optional one without terms
+	return nil, false
+}
+
+func longestTerm(terms []*index.Term) []*index.Term {
+	var ans *index.Term
+	for _, term := range terms {
+		if ans == nil || len(term.Bytes) > len(ans.Bytes) {
+			ans = term
+		}
+	}
+	if ans == nil {
+		return nil
+	}
+	return []*index.Term{ans}
+}
@@ -0,0 +1,248 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/analysis"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/memory"
+	"sort"
+	"sync"
+)
+
+// monitor/Monitor.java
+
+/* A query registered with a Monitor, identified by ID. */
+type MonitorQuery struct {
+	ID    string
+	Query search.Query
+}
+
+/* A registered query matching a document, with its score. */
+type QueryMatch struct {
+	ID    string
+	Score float32
+}
+
+/*
+Matches documents against a set of registered queries, the reverse of
+a regular search (also known as percolation or prospective search).
+
+Each document is loaded into a MemoryIndex, and only the queries
+which may match it are run against it: on registration, the terms a
+query needs are extracted and indexed, so that a document is only
+checked against the queries sharing at least one term with it.
+Queries whose terms cannot be extracted (e.g. filters, or queries
+with only negative clauses) are run against every document.
+
+A Monitor is safe for concurrent use.
+*/
+type Monitor struct {
+	sync.RWMutex
+	analyzer   analysis.Analyzer
+	similarity index.Similarity
+
+	queries map[string]search.Query
+	// field and term to the IDs of the queries requiring it
+	terms map[termKey]map[string]bool
+	// IDs of the queries run against any document
+	anyQueries map[string]bool
+	// IDs to the terms they were indexed under
+	queryTerms map[string][]termKey
+}
+
+/*
+Creates an empty Monitor, analyzing the tokenized fields of matched
+documents with analyzer.
+*/
+func NewMonitor(analyzer analysis.Analyzer) *Monitor {
+	assert2(analyzer != nil, "analyzer must not be nil")
+	return &Monitor{
+		analyzer:   analyzer,
+		queries:    make(map[string]search.Query),
+		terms:      make(map[termKey]map[string]bool),
+		anyQueries: make(map[string]bool),
+		queryTerms: make(map[string][]termKey),
+	}
+}
+
+/*
+Sets the Similarity used to compute the norms and scores of matched
+documents. The default is index.DefaultSimilarity().
+*/
+func (m *Monitor) SetSimilarity(similarity index.Similarity) {
+	m.Lock()
+	defer m.Unlock()
+	m.similarity = similarity
+}
+
+/*
+Registers queries, replacing any registered query with the same ID.
+*/
+func (m *Monitor) Register(queries ...MonitorQuery) {
+	m.Lock()
+	defer m.Unlock()
+	for _, q := range queries {
+		assert2(q.ID != "", "query ID must not be empty")
+		assert2(q.Query != nil, "query %v must not be nil", q.ID)
+		m.remove(q.ID)
+		m.queries[q.ID] = q.Query
+		terms, ok := extractTerms(q.Query)
+		if !ok {
+			m.anyQueries[q.ID] = true
+			continue
+		}
+		for _, term := range terms {
+			key := termKey{term.Field, string(term.Bytes)}
+			ids, ok := m.terms[key]
+			if !ok {
+				ids = make(map[string]bool)
+				m.terms[key] = ids
+			}
+			ids[q.ID] = true
+			m.queryTerms[q.ID] = append(m.queryTerms[q.ID], key)
+		}
+	}
+}
+
+/* Removes the queries with the given IDs, if registered. */
+func (m *Monitor) Delete(ids ...string) {
+	m.Lock()
+	defer m.Unlock()
+	for _, id := range ids {
+		m.remove(id)
+	}
+}
+
+func (m *Monitor) remove(id string) {
+	if _, ok := m.queries[id]; !ok {
+		return
+	}
+	delete(m.queries, id)
+	delete(m.anyQueries, id)
+	for _, key := range m.queryTerms[id] {
+		if ids := m.terms[key]; ids != nil {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(m.terms, key)
+			}
+		}
+	}
+	delete(m.queryTerms, id)
+}
+
+/* Returns the query registered with id, or nil. */
+func (m *Monitor) Query(id string) search.Query {
+	m.RLock()
+	defer m.RUnlock()
+	return m.queries[id]
+}
+
+/* Returns the number of registered queries. */
+func (m *Monitor) Size() int {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.queries)
+}
+
+/*
+Returns the registered queries matching doc, by decreasing score,
+then by ID.
+*/
+func (m *Monitor) Match(doc []IndexableField) ([]QueryMatch, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	mi, err := m.newMemoryIndex(doc)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]bool)
+	for _, field := range doc {
+		fields[field.Name()] = true
+	}
+	candidates, err := m.candidates(mi.CreateReader(), fields)
+	if err != nil {
+		return nil, err
+	}
+	searcher := mi.CreateSearcher()
+	var matches []QueryMatch
+	for id := range candidates {
+		topDocs, err := searcher.SearchTop(m.queries[id], 1)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("cannot run query %v: %v", id, err))
+		}
+		if topDocs.TotalHits > 0 {
+			matches = append(matches, QueryMatch{id, topDocs.ScoreDocs[0].Score})
+		}
+	}
+	sort.Sort(queryMatches(matches))
+	return matches, nil
+}
+
+func (m *Monitor) newMemoryIndex(doc []IndexableField) (*memory.MemoryIndex, error) {
+	mi := memory.NewMemoryIndex(false)
+	if m.similarity != nil {
+		mi.SetSimilarity(m.similarity)
+	}
+	for _, field := range doc {
+		if err := mi.AddIndexableField(field, m.analyzer); err != nil {
+			return nil, err
+		}
+	}
+	return mi, nil
+}
+
+/*
+Returns the IDs of the queries sharing a term with the document in
+reader, and of those run against any document.
+*/
+func (m *Monitor) candidates(reader index.AtomicReader, fields map[string]bool) (map[string]bool, error) {
+	ans := make(map[string]bool)
+	for id := range m.anyQueries {
+		ans[id] = true
+	}
+	for field := range fields {
+		terms := reader.Terms(field)
+		if terms == nil {
+			continue
+		}
+		it := terms.Iterator(nil)
+		for {
+			term, err := it.Next()
+			if err != nil {
+				return nil, err
+			}
+			if term == nil {
+				break
+			}
+			for id := range m.terms[termKey{field, string(term)}] {
+				ans[id] = true
+			}
+		}
+	}
+	return ans, nil
+}
+
+type termKey struct {
+	field, text string
+}
+
+type queryMatches []QueryMatch
+
+func (a queryMatches) Len() int      { return len(a) }
+func (a queryMatches) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a queryMatches) Less(i, j int) bool {
+	if a[i].Score != a[j].Score {
+		return a[i].Score > a[j].Score
+	}
+	return a[i].ID < a[j].ID
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
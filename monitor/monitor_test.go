@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	ac "github.com/jtejido/golucene/analysis/core"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func termQuery(field, text string) search.Query {
+	return search.NewTermQuery(index.NewTerm(field, text))
+}
+
+func TestExtractTerms(t *testing.T) {
+	phrase := search.NewPhraseQuery()
+	phrase.Add(index.NewTerm("f", "a"))
+	phrase.Add(index.NewTerm("f", "longest"))
+
+	conj := search.NewBooleanQuery()
+	conj.Add(phrase, search.MUST)
+	conj.Add(termQuery("f", "x"), search.SHOULD)
+
+	disj := search.NewBooleanQuery()
+	disj.Add(termQuery("f", "x"), search.SHOULD)
+	disj.Add(termQuery("g", "y"), search.SHOULD)
+	disj.Add(termQuery("f", "z"), search.MUST_NOT)
+
+	neg := search.NewBooleanQuery()
+	neg.Add(termQuery("f", "z"), search.MUST_NOT)
+
+	unknown := search.NewBooleanQuery()
+	unknown.Add(neg, search.MUST)
+	unknown.Add(termQuery("f", "x"), search.SHOULD)
+
+	for i, c := range []struct {
+		query    search.Query
+		ok       bool
+		expected string
+	}{
+		{termQuery("f", "x"), true, "f:x"},
+		{phrase, true, "f:longest"},
+		{conj, true, "f:longest"},
+		{disj, true, "f:x g:y"},
+		{neg, false, ""},
+		{unknown, false, ""},
+		{search.NewConstantScoreQuery(disj), true, "f:x g:y"},
+	} {
+		terms, ok := extractTerms(c.query)
+		if ok != c.ok {
+			t.Errorf("#%v: expected %v, but %v", i, c.ok, ok)
+			continue
+		}
+		var s string
+		for j, term := range terms {
+			if j > 0 {
+				s += " "
+			}
+			s += term.String()
+		}
+		if s != c.expected {
+			t.Errorf("#%v: expected terms [%v], but [%v]", i, c.expected, s)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	testindex.UseDefaultSimilarity()
+	m := NewMonitor(ac.NewWhitespaceAnalyzer())
+
+	phrase := search.NewPhraseQuery()
+	phrase.Add(index.NewTerm("body", "quick"))
+	phrase.Add(index.NewTerm("body", "fox"))
+	neg := search.NewBooleanQuery()
+	neg.Add(termQuery("body", "dog"), search.MUST_NOT)
+	m.Register(
+		MonitorQuery{"fox", termQuery("body", "fox")},
+		MonitorQuery{"phrase", phrase},
+		MonitorQuery{"cat", termQuery("body", "cat")},
+		MonitorQuery{"lang", termQuery("lang", "en")},
+		MonitorQuery{"neg", neg},
+	)
+	if n := m.Size(); n != 5 {
+		t.Fatalf("expected 5 queries, but %v", n)
+	}
+
+	d := docu.NewDocument()
+	d.Add(docu.NewTextFieldFromString("body", "the quick fox", docu.STORE_NO))
+	d.Add(docu.NewFieldFromString("lang", "en", docu.STRING_FIELD_TYPE_NOT_STORED))
+	d.Add(docu.NewStoredFieldFromString("cat", "cat"))
+
+	mi, err := m.newMemoryIndex(d.Fields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := m.candidates(mi.CreateReader(), map[string]bool{"body": true, "lang": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the phrase is indexed under "quick", the longest term
+	for _, id := range []string{"fox", "phrase", "lang", "neg"} {
+		if !candidates[id] {
+			t.Errorf("expected %v to be a candidate", id)
+		}
+	}
+	if candidates["cat"] {
+		t.Error("expected cat not to be a candidate")
+	}
+
+	matches, err := m.Match(d.Fields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool)
+	for i, match := range matches {
+		ids[match.ID] = true
+		if match.Score <= 0 {
+			t.Errorf("expected a positive score for %v", match.ID)
+		}
+		if i > 0 && matches[i-1].Score < match.Score {
+			t.Errorf("expected matches sorted by score, but %v", matches)
+		}
+	}
+	// a purely negative query matches nothing
+	if len(ids) != 3 || !ids["fox"] || !ids["phrase"] || !ids["lang"] {
+		t.Errorf("unexpected matches %v", matches)
+	}
+
+	m.Delete("fox", "lang")
+	m.Register(MonitorQuery{"phrase", termQuery("body", "cat")})
+	if matches, err = m.Match(d.Fields()); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no match, but %v", matches)
+	}
+	if n := m.Size(); n != 3 {
+		t.Errorf("expected 3 queries, but %v", n)
+	}
+	if len(m.terms) != 1 {
+		t.Errorf("expected the terms of removed queries to be dropped, but %v", m.terms)
+	}
+}
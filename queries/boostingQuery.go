@@ -0,0 +1,182 @@
+package queries
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// queries/BoostingQuery.java
+
+/*
+The BoostingQuery class can be used to effectively demote results
+that match a given query. Unlike the "NOT" clause, this still selects
+documents that contain undesirable terms, but reduces their overall
+score:
+
+	q := NewBoostingQuery(positiveQuery, negativeQuery, 0.01)
+
+In this scenario the positive query "apple" matches documents about
+both the fruit and the company, and the negative query
+"computer OR phone" is used to demote the documents about the
+company: their score is multiplied by the negative boost.
+
+Only documents matching the positive query are returned; the
+negative query has no influence on which documents match.
+*/
+type BoostingQuery struct {
+	*search.AbstractQuery
+	boost   float32      // the amount to boost by
+	match   search.Query // query to match
+	context search.Query // boost when matches too
+}
+
+func NewBoostingQuery(match, context search.Query, boost float32) *BoostingQuery {
+	ans := &BoostingQuery{boost: boost, match: match, context: context.Clone()}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+/* Returns the query whose matches are returned. */
+func (q *BoostingQuery) Match() search.Query { return q.match }
+
+/* Returns the query whose matches are demoted. */
+func (q *BoostingQuery) Context() search.Query { return q.context }
+
+/* Returns the factor applied to the score of demoted documents. */
+func (q *BoostingQuery) NegativeBoost() float32 { return q.boost }
+
+func (q *BoostingQuery) Rewrite(reader index.IndexReader) search.Query {
+	match, context := q.match.Rewrite(reader), q.context.Rewrite(reader)
+	if match == q.match && context == q.context {
+		return q
+	}
+	ans := &BoostingQuery{boost: q.boost, match: match, context: context}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *BoostingQuery) Clone() search.Query {
+	ans := NewBoostingQuery(q.match.Clone(), q.context, q.boost)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *BoostingQuery) CreateWeight(searcher *search.IndexSearcher) (search.Weight, error) {
+	return newBoostingWeight(q, searcher)
+}
+
+func (q *BoostingQuery) ToString(field string) string {
+	ans := fmt.Sprintf("%v/%v", q.match.ToString(field), q.context.ToString(field))
+	if q.Boost() != 1 {
+		ans = fmt.Sprintf("(%v)^%v", ans, q.Boost())
+	}
+	return ans
+}
+
+type boostingWeight struct {
+	*search.WeightImpl
+	owner         *BoostingQuery
+	matchWeight   search.Weight
+	contextWeight search.Weight
+}
+
+func newBoostingWeight(owner *BoostingQuery, searcher *search.IndexSearcher) (*boostingWeight, error) {
+	matchWeight, err := owner.match.CreateWeight(searcher)
+	if err != nil {
+		return nil, err
+	}
+	contextWeight, err := owner.context.CreateWeight(searcher)
+	if err != nil {
+		return nil, err
+	}
+	ans := &boostingWeight{
+		owner:         owner,
+		matchWeight:   matchWeight,
+		contextWeight: contextWeight,
+	}
+	ans.WeightImpl = search.NewWeightImpl(ans)
+	return ans, nil
+}
+
+func (w *boostingWeight) ValueForNormalization() float32 {
+	// the context query only demotes, so it does not take part in the
+	// normalization; it is still called in case of side effects
+	w.contextWeight.ValueForNormalization()
+	boost := w.owner.Boost()
+	return w.matchWeight.ValueForNormalization() * boost * boost
+}
+
+func (w *boostingWeight) Normalize(norm, topLevelBoost float32) {
+	topLevelBoost *= w.owner.Boost()
+	w.matchWeight.Normalize(norm, topLevelBoost)
+	w.contextWeight.Normalize(norm, topLevelBoost)
+}
+
+func (w *boostingWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *boostingWeight) Scorer(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (search.Scorer, error) {
+	matchScorer, err := w.matchWeight.Scorer(ctx, acceptDocs)
+	if err != nil || matchScorer == nil {
+		return nil, err
+	}
+	contextScorer, err := w.contextWeight.Scorer(ctx, acceptDocs)
+	if err != nil {
+		return nil, err
+	}
+	return &boostingScorer{w, matchScorer, contextScorer}, nil
+}
+
+func (w *boostingWeight) Explain(ctx *index.AtomicReaderContext, doc int) (search.Explanation, error) {
+	matchExpl, err := w.matchWeight.Explain(ctx, doc)
+	if err != nil || !matchExpl.IsMatch() {
+		return matchExpl, err
+	}
+	contextExpl, err := w.contextWeight.Explain(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	if !contextExpl.IsMatch() {
+		return matchExpl, nil
+	}
+	ans := search.NewComplexExplanation(true, matchExpl.Value()*w.owner.boost,
+		fmt.Sprintf("%v, demoted, product of:", w.owner))
+	ans.AddDetail(matchExpl)
+	ans.AddDetail(search.NewExplanation(w.owner.boost, "negative boost"))
+	return ans, nil
+}
+
+type boostingScorer struct {
+	weight        *boostingWeight
+	matchScorer   search.Scorer
+	contextScorer search.Scorer
+}
+
+func (s *boostingScorer) Weight() search.Weight           { return s.weight }
+func (s *boostingScorer) DocId() int                      { return s.matchScorer.DocId() }
+func (s *boostingScorer) NextDoc() (int, error)           { return s.matchScorer.NextDoc() }
+func (s *boostingScorer) Advance(target int) (int, error) { return s.matchScorer.Advance(target) }
+func (s *boostingScorer) Freq() (int, error)              { return s.matchScorer.Freq() }
+func (s *boostingScorer) Cost() int64                     { return s.matchScorer.Cost() }
+
+func (s *boostingScorer) Score() (float32, error) {
+	score, err := s.matchScorer.Score()
+	if err != nil || s.contextScorer == nil {
+		return score, err
+	}
+	doc := s.matchScorer.DocId()
+	contextDoc := s.contextScorer.DocId()
+	if contextDoc < doc {
+		if contextDoc, err = s.contextScorer.Advance(doc); err != nil {
+			return 0, err
+		}
+	}
+	if contextDoc == doc {
+		score *= s.weight.owner.boost
+	}
+	return score, nil
+}
@@ -0,0 +1,69 @@
+package queries
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+)
+
+// queries/CustomScoreProvider.java
+
+/*
+An instance of this type is created for each segment the
+CustomScoreQuery is executed on. It computes the custom score of a
+document from the score of the subquery and the values of the
+function queries.
+
+To customize, implement this interface and set a factory with
+CustomScoreQuery.SetCustomScoreProvider().
+*/
+type CustomScoreProvider interface {
+	// Computes a custom score for a match of the subquery.
+	CustomScore(doc int, subQueryScore float32, valSrcScores []float32) float32
+	// Explains the custom score. Whenever overriding CustomScore(),
+	// this method should also be overridden to provide the correct
+	// explanation for the part of the custom scoring.
+	CustomExplain(doc int, subQueryExpl search.Explanation, valSrcExpls []search.Explanation) search.Explanation
+}
+
+/*
+The default CustomScoreProvider, which multiplies the score of the
+subquery with the values of the function queries.
+*/
+type DefaultCustomScoreProvider struct {
+	context *index.AtomicReaderContext
+}
+
+func NewDefaultCustomScoreProvider(context *index.AtomicReaderContext) *DefaultCustomScoreProvider {
+	return &DefaultCustomScoreProvider{context}
+}
+
+func (p *DefaultCustomScoreProvider) CustomScore(doc int, subQueryScore float32, valSrcScores []float32) float32 {
+	score := subQueryScore
+	for _, valSrcScore := range valSrcScores {
+		score *= valSrcScore
+	}
+	return score
+}
+
+func (p *DefaultCustomScoreProvider) CustomExplain(doc int, subQueryExpl search.Explanation,
+	valSrcExpls []search.Explanation) search.Explanation {
+
+	if len(valSrcExpls) == 0 {
+		return subQueryExpl
+	}
+	valSrcScore := float32(1)
+	for _, valSrcExpl := range valSrcExpls {
+		valSrcScore *= valSrcExpl.Value()
+	}
+	ans := search.NewExplanation(valSrcScore*subQueryExpl.Value(), "custom score: product of:")
+	ans.AddDetail(subQueryExpl)
+	for _, valSrcExpl := range valSrcExpls {
+		ans.AddDetail(valSrcExpl)
+	}
+	return ans
+}
+
+func (p *DefaultCustomScoreProvider) String() string {
+	return fmt.Sprintf("DefaultCustomScoreProvider(%v)", p.context)
+}
@@ -0,0 +1,321 @@
+package queries
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/queries/function"
+)
+
+// queries/CustomScoreQuery.java
+
+/*
+Query that sets document score as a programmatic function of several
+(sub) scores:
+
+ 1. the score of its subQuery (any query)
+ 2. (optional) the score of its FunctionQuery's (or queries).
+
+Subclasses can modify the computation by setting a
+CustomScoreProvider factory with SetCustomScoreProvider().
+*/
+type CustomScoreQuery struct {
+	*search.AbstractQuery
+	subQuery       search.Query
+	scoringQueries []search.Query // never nil (empty array if there are no valSrcQueries).
+	strict         bool           // if true, valueSource part of query does not take part in weights normalization.
+	provider       func(*index.AtomicReaderContext) CustomScoreProvider
+}
+
+/*
+Create a CustomScoreQuery over input subQuery and a set of
+FunctionQuerys (possibly none). The score of a document is computed
+by the CustomScoreProvider, by default the product of the subQuery
+score and the function values.
+*/
+func NewCustomScoreQuery(subQuery search.Query, scoringQueries ...*function.FunctionQuery) *CustomScoreQuery {
+	ans := &CustomScoreQuery{subQuery: subQuery}
+	for _, q := range scoringQueries {
+		ans.scoringQueries = append(ans.scoringQueries, q)
+	}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+/*
+Sets the factory of the CustomScoreProvider used for each segment. If
+not set, DefaultCustomScoreProvider is used.
+*/
+func (q *CustomScoreQuery) SetCustomScoreProvider(provider func(*index.AtomicReaderContext) CustomScoreProvider) {
+	q.provider = provider
+}
+
+func (q *CustomScoreQuery) customScoreProvider(context *index.AtomicReaderContext) CustomScoreProvider {
+	if q.provider != nil {
+		return q.provider(context)
+	}
+	return NewDefaultCustomScoreProvider(context)
+}
+
+/*
+Checks if this is strict custom scoring. In strict custom scoring,
+the ValueSource part does not participate in weight normalization.
+This may be useful when one wants full control over how scores are
+modified, and does not care about normalizing by the ValueSource part.
+One particular case where this is useful if for testing this query.
+
+Note: only has effect when the ValueSource part is not nil.
+*/
+func (q *CustomScoreQuery) IsStrict() bool { return q.strict }
+
+/* Set the strict mode of this query. */
+func (q *CustomScoreQuery) SetStrict(strict bool) { q.strict = strict }
+
+/* The sub-query that CustomScoreQuery wraps, affecting both the score and which documents match. */
+func (q *CustomScoreQuery) SubQuery() search.Query { return q.subQuery }
+
+/* The scoring queries that only affect the score of CustomScoreQuery. */
+func (q *CustomScoreQuery) ScoringQueries() []search.Query { return q.scoringQueries }
+
+/* A short name of this query, used in ToString(). */
+func (q *CustomScoreQuery) Name() string { return "custom" }
+
+func (q *CustomScoreQuery) Rewrite(reader index.IndexReader) search.Query {
+	var clone *CustomScoreQuery
+
+	sq := q.subQuery.Rewrite(reader)
+	if sq != q.subQuery {
+		clone = q.Clone().(*CustomScoreQuery)
+		clone.subQuery = sq
+	}
+
+	for i, scoringQuery := range q.scoringQueries {
+		v := scoringQuery.Rewrite(reader)
+		if v != scoringQuery {
+			if clone == nil {
+				clone = q.Clone().(*CustomScoreQuery)
+			}
+			clone.scoringQueries[i] = v
+		}
+	}
+
+	if clone == nil {
+		return q
+	}
+	return clone
+}
+
+func (q *CustomScoreQuery) Clone() search.Query {
+	ans := &CustomScoreQuery{
+		subQuery:       q.subQuery.Clone(),
+		scoringQueries: make([]search.Query, len(q.scoringQueries)),
+		strict:         q.strict,
+		provider:       q.provider,
+	}
+	for i, scoringQuery := range q.scoringQueries {
+		ans.scoringQueries[i] = scoringQuery.Clone()
+	}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *CustomScoreQuery) ToString(field string) string {
+	var buf bytes.Buffer
+	buf.WriteString(q.Name())
+	buf.WriteRune('(')
+	buf.WriteString(q.subQuery.ToString(field))
+	for _, scoringQuery := range q.scoringQueries {
+		buf.WriteString(", ")
+		buf.WriteString(scoringQuery.ToString(field))
+	}
+	buf.WriteRune(')')
+	if q.strict {
+		buf.WriteString(" STRICT")
+	}
+	if boost := q.Boost(); boost != 1 {
+		fmt.Fprintf(&buf, "^%v", boost)
+	}
+	return buf.String()
+}
+
+func (q *CustomScoreQuery) CreateWeight(searcher *search.IndexSearcher) (search.Weight, error) {
+	return newCustomWeight(q, searcher)
+}
+
+type customWeight struct {
+	*search.WeightImpl
+	owner          *CustomScoreQuery
+	subQueryWeight search.Weight
+	valSrcWeights  []search.Weight
+	qStrict        bool
+	queryWeight    float32
+}
+
+func newCustomWeight(owner *CustomScoreQuery, searcher *search.IndexSearcher) (*customWeight, error) {
+	subQueryWeight, err := owner.subQuery.CreateWeight(searcher)
+	if err != nil {
+		return nil, err
+	}
+	ans := &customWeight{
+		owner:          owner,
+		subQueryWeight: subQueryWeight,
+		valSrcWeights:  make([]search.Weight, len(owner.scoringQueries)),
+		qStrict:        owner.strict,
+	}
+	for i, scoringQuery := range owner.scoringQueries {
+		if ans.valSrcWeights[i], err = scoringQuery.CreateWeight(searcher); err != nil {
+			return nil, err
+		}
+	}
+	ans.WeightImpl = search.NewWeightImpl(ans)
+	return ans, nil
+}
+
+func (w *customWeight) ValueForNormalization() float32 {
+	sum := w.subQueryWeight.ValueForNormalization()
+	for _, valSrcWeight := range w.valSrcWeights {
+		if w.qStrict {
+			valSrcWeight.ValueForNormalization() // do not include ValueSource part in the query normalization
+		} else {
+			sum += valSrcWeight.ValueForNormalization()
+		}
+	}
+	return sum
+}
+
+func (w *customWeight) Normalize(norm, topLevelBoost float32) {
+	// note we DONT incorporate our boost, nor pass down any topLevelBoost
+	// (e.g. from outer BQ), as there is no guarantee that the
+	// CustomScoreProvider's function obeys the distributive law... it
+	// might call sqrt() on the subQuery score or some other arbitrary
+	// function other than multiplication. so, instead boosts are
+	// applied directly in Score()
+	w.subQueryWeight.Normalize(norm, 1)
+	for _, valSrcWeight := range w.valSrcWeights {
+		if w.qStrict {
+			valSrcWeight.Normalize(1, 1) // do not normalize the ValueSource part
+		} else {
+			valSrcWeight.Normalize(norm, 1)
+		}
+	}
+	w.queryWeight = topLevelBoost * w.owner.Boost()
+}
+
+func (w *customWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *customWeight) Scorer(context *index.AtomicReaderContext, acceptDocs util.Bits) (search.Scorer, error) {
+	subQueryScorer, err := w.subQueryWeight.Scorer(context, acceptDocs)
+	if err != nil || subQueryScorer == nil {
+		return nil, err
+	}
+	valSrcScorers := make([]search.Scorer, len(w.valSrcWeights))
+	for i, valSrcWeight := range w.valSrcWeights {
+		if valSrcScorers[i], err = valSrcWeight.Scorer(context, acceptDocs); err != nil {
+			return nil, err
+		}
+	}
+	return newCustomScorer(w.owner.customScoreProvider(context), w, w.queryWeight,
+		subQueryScorer, valSrcScorers), nil
+}
+
+func (w *customWeight) Explain(context *index.AtomicReaderContext, doc int) (search.Explanation, error) {
+	subQueryExpl, err := w.subQueryWeight.Explain(context, doc)
+	if err != nil || !subQueryExpl.IsMatch() {
+		return subQueryExpl, err
+	}
+	// match
+	valSrcExpls := make([]search.Explanation, len(w.valSrcWeights))
+	for i, valSrcWeight := range w.valSrcWeights {
+		if valSrcExpls[i], err = valSrcWeight.Explain(context, doc); err != nil {
+			return nil, err
+		}
+	}
+	customExp := w.owner.customScoreProvider(context).CustomExplain(doc, subQueryExpl, valSrcExpls)
+	sc := w.queryWeight * customExp.Value()
+	ans := search.NewComplexExplanation(true, sc, fmt.Sprintf("%v, product of:", w.owner))
+	ans.AddDetail(customExp)
+	ans.AddDetail(search.NewExplanation(w.queryWeight, "queryWeight"))
+	return ans, nil
+}
+
+/*
+A scorer that applies a (callback) function on scores of the
+subQuery.
+*/
+type customScorer struct {
+	weight         *customWeight
+	qWeight        float32
+	subQueryScorer search.Scorer
+	valSrcScorers  []search.Scorer
+	provider       CustomScoreProvider
+	vScores        []float32 // reused in score() to avoid allocating this array for each doc
+}
+
+func newCustomScorer(provider CustomScoreProvider, w *customWeight, qWeight float32,
+	subQueryScorer search.Scorer, valSrcScorers []search.Scorer) *customScorer {
+
+	return &customScorer{
+		weight:         w,
+		qWeight:        qWeight,
+		subQueryScorer: subQueryScorer,
+		valSrcScorers:  valSrcScorers,
+		provider:       provider,
+		vScores:        make([]float32, len(valSrcScorers)),
+	}
+}
+
+func (s *customScorer) Weight() search.Weight { return s.weight }
+func (s *customScorer) DocId() int            { return s.subQueryScorer.DocId() }
+func (s *customScorer) Freq() (int, error)    { return s.subQueryScorer.Freq() }
+func (s *customScorer) Cost() int64           { return s.subQueryScorer.Cost() }
+
+func (s *customScorer) NextDoc() (int, error) {
+	doc, err := s.subQueryScorer.NextDoc()
+	if err != nil {
+		return doc, err
+	}
+	return doc, s.advanceValSrcScorers(doc)
+}
+
+func (s *customScorer) Advance(target int) (int, error) {
+	doc, err := s.subQueryScorer.Advance(target)
+	if err != nil {
+		return doc, err
+	}
+	return doc, s.advanceValSrcScorers(doc)
+}
+
+func (s *customScorer) advanceValSrcScorers(doc int) error {
+	for _, valSrcScorer := range s.valSrcScorers {
+		if valSrcScorer != nil && valSrcScorer.DocId() < doc {
+			if _, err := valSrcScorer.Advance(doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *customScorer) Score() (float32, error) {
+	doc := s.subQueryScorer.DocId()
+	for i, valSrcScorer := range s.valSrcScorers {
+		s.vScores[i] = 0
+		if valSrcScorer != nil && valSrcScorer.DocId() == doc {
+			score, err := valSrcScorer.Score()
+			if err != nil {
+				return 0, err
+			}
+			s.vScores[i] = score
+		}
+	}
+	subQueryScore, err := s.subQueryScorer.Score()
+	if err != nil {
+		return 0, err
+	}
+	return s.qWeight * s.provider.CustomScore(doc, subQueryScore, s.vScores), nil
+}
@@ -0,0 +1,115 @@
+package queries_test
+
+import (
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries"
+	"github.com/jtejido/golucene/queries/function"
+	vs "github.com/jtejido/golucene/queries/function/valuesource"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func newTestSearcher(t *testing.T, docs ...[2]string) *search.IndexSearcher {
+	var fields [][]model.IndexableField
+	for _, text := range docs {
+		d := docu.NewDocument()
+		d.Add(docu.NewTextFieldFromString("body", text[0], docu.STORE_NO))
+		d.Add(docu.NewFieldFromString("popularity", text[1], docu.STRING_FIELD_TYPE_STORED))
+		fields = append(fields, d.Fields())
+	}
+	return search.NewIndexSearcher(testindex.NewReader(t, fields...))
+}
+
+func assertOrder(t *testing.T, searcher *search.IndexSearcher, q search.Query, expected ...int) {
+	docs, err := searcher.SearchTop(q, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs.TotalHits != len(expected) {
+		t.Fatalf("%v: expected %v hits, but %v", q, len(expected), docs.TotalHits)
+	}
+	for i, doc := range expected {
+		if got := docs.ScoreDocs[i].Doc; got != doc {
+			t.Errorf("%v: expected doc %v at rank %v, but %v", q, doc, i, got)
+		}
+	}
+}
+
+func TestFunctionQueries(t *testing.T) {
+	searcher := newTestSearcher(t,
+		[2]string{"quick fox", "10"},
+		[2]string{"quick quick fox", "1"},
+		[2]string{"lazy dog", "100"},
+		[2]string{"quick brown fox jumps", "50"})
+
+	popularity := vs.NewStoredFieldSource("popularity")
+	quick := search.NewTermQuery(index.NewTerm("body", "quick"))
+
+	assertOrder(t, searcher, function.NewFunctionQuery(popularity), 2, 3, 0, 1)
+	assertOrder(t, searcher, function.NewFunctionQuery(vs.NewReciprocalFloatFunction(popularity, 1, 1, 1)), 1, 0, 3, 2)
+	assertOrder(t, searcher, quick, 1, 0, 3)
+	assertOrder(t, searcher, function.NewBoostedQuery(quick, popularity), 3, 0, 1)
+	assertOrder(t, searcher, function.NewBoostedQuery(quick, vs.NewConstValueSource(2)), 1, 0, 3)
+
+	csq := queries.NewCustomScoreQuery(quick, function.NewFunctionQuery(vs.NewLogFloatFunction(popularity)))
+	assertOrder(t, searcher, csq, 3, 0, 1)
+	csq.SetCustomScoreProvider(func(*index.AtomicReaderContext) queries.CustomScoreProvider {
+		return subQueryScoreProvider{}
+	})
+	assertOrder(t, searcher, csq, 1, 0, 3)
+
+	exp, err := searcher.Explain(function.NewBoostedQuery(quick, popularity), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exp.IsMatch() {
+		t.Errorf("expected a match, but %v", exp)
+	}
+}
+
+// ranks by the subquery score, ignoring the function values
+type subQueryScoreProvider struct{}
+
+func (p subQueryScoreProvider) CustomScore(doc int, subQueryScore float32, valSrcScores []float32) float32 {
+	return subQueryScore
+}
+
+func (p subQueryScoreProvider) CustomExplain(doc int, subQueryExpl search.Explanation, valSrcExpls []search.Explanation) search.Explanation {
+	return subQueryExpl
+}
+
+func TestFieldValueSources(t *testing.T) {
+	searcher := newTestSearcher(t,
+		[2]string{"quick fox", "10"},
+		[2]string{"quick quick fox", "none"},
+		[2]string{"lazy dog", "100"})
+	leaf := searcher.IndexReader().Leaves()[0]
+	context := function.NewContext(searcher)
+
+	popularity, err := vs.NewStoredFieldSource("popularity").Values(context, leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for doc, expected := range []float64{10, 0, 100} {
+		if v := popularity.DoubleVal(doc); v != expected {
+			t.Errorf("expected popularity %v of doc %v, but %v", expected, doc, v)
+		}
+		if exists := popularity.Exists(doc); exists != (doc != 1) {
+			t.Errorf("unexpected Exists() %v of doc %v", exists, doc)
+		}
+	}
+
+	// out of order access
+	freqs, err := vs.NewTermFreqValueSource("body", "quick").Values(context, leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range []int{2, 1, 0, 1} {
+		if v, expected := freqs.IntVal(doc), []int{1, 2, 0}[doc]; v != expected {
+			t.Errorf("expected termfreq %v of doc %v, but %v", expected, doc, v)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package function
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+)
+
+// queries/function/BoostedQuery.java
+
+/*
+Query that is boosted by a ValueSource: the score of each document
+matched by the wrapped query is multiplied by the value of the
+function for that document.
+*/
+type BoostedQuery struct {
+	*search.AbstractQuery
+	q        search.Query
+	boostVal ValueSource // optionally transform score
+}
+
+func NewBoostedQuery(subQuery search.Query, boostVal ValueSource) *BoostedQuery {
+	ans := &BoostedQuery{q: subQuery, boostVal: boostVal}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+func (q *BoostedQuery) Query() search.Query      { return q.q }
+func (q *BoostedQuery) ValueSource() ValueSource { return q.boostVal }
+
+func (q *BoostedQuery) Rewrite(reader index.IndexReader) search.Query {
+	newQ := q.q.Rewrite(reader)
+	if newQ == q.q {
+		return q
+	}
+	ans := NewBoostedQuery(newQ, q.boostVal)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *BoostedQuery) Clone() search.Query {
+	ans := NewBoostedQuery(q.q.Clone(), q.boostVal)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *BoostedQuery) CreateWeight(searcher *search.IndexSearcher) (search.Weight, error) {
+	return newBoostedWeight(q, searcher)
+}
+
+func (q *BoostedQuery) ToString(field string) string {
+	ans := fmt.Sprintf("boost(%v,%v)", q.q.ToString(field), q.boostVal.Description())
+	if boost := q.Boost(); boost != 1 {
+		ans = fmt.Sprintf("%v^%v", ans, boost)
+	}
+	return ans
+}
+
+type boostedWeight struct {
+	*search.WeightImpl
+	owner    *BoostedQuery
+	qWeight  search.Weight
+	fcontext Context
+}
+
+func newBoostedWeight(owner *BoostedQuery, searcher *search.IndexSearcher) (*boostedWeight, error) {
+	qWeight, err := owner.q.CreateWeight(searcher)
+	if err != nil {
+		return nil, err
+	}
+	ans := &boostedWeight{
+		owner:    owner,
+		qWeight:  qWeight,
+		fcontext: NewContext(searcher),
+	}
+	ans.WeightImpl = search.NewWeightImpl(ans)
+	if err = owner.boostVal.CreateWeight(ans.fcontext, searcher); err != nil {
+		return nil, err
+	}
+	return ans, nil
+}
+
+func (w *boostedWeight) ValueForNormalization() float32 {
+	sum := w.qWeight.ValueForNormalization()
+	boost := w.owner.Boost()
+	return sum * boost * boost
+}
+
+func (w *boostedWeight) Normalize(norm, topLevelBoost float32) {
+	w.qWeight.Normalize(norm, topLevelBoost*w.owner.Boost())
+}
+
+func (w *boostedWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *boostedWeight) Scorer(context *index.AtomicReaderContext, acceptDocs util.Bits) (search.Scorer, error) {
+	subQueryScorer, err := w.qWeight.Scorer(context, acceptDocs)
+	if err != nil || subQueryScorer == nil {
+		return nil, err
+	}
+	vals, err := w.owner.boostVal.Values(w.fcontext, context)
+	if err != nil {
+		return nil, err
+	}
+	return &boostedScorer{w, w.owner.Boost(), subQueryScorer, vals}, nil
+}
+
+func (w *boostedWeight) Explain(context *index.AtomicReaderContext, doc int) (search.Explanation, error) {
+	subQueryExpl, err := w.qWeight.Explain(context, doc)
+	if err != nil || !subQueryExpl.IsMatch() {
+		return subQueryExpl, err
+	}
+	vals, err := w.owner.boostVal.Values(w.fcontext, context)
+	if err != nil {
+		return nil, err
+	}
+	sc := subQueryExpl.Value() * vals.FloatVal(doc)
+	ans := search.NewComplexExplanation(true, sc, fmt.Sprintf("%v, product of:", w.owner))
+	ans.AddDetail(subQueryExpl)
+	ans.AddDetail(vals.Explain(doc))
+	return ans, nil
+}
+
+type boostedScorer struct {
+	weight  *boostedWeight
+	qWeight float32
+	scorer  search.Scorer
+	vals    FunctionValues
+}
+
+func (s *boostedScorer) Weight() search.Weight           { return s.weight }
+func (s *boostedScorer) DocId() int                      { return s.scorer.DocId() }
+func (s *boostedScorer) NextDoc() (int, error)           { return s.scorer.NextDoc() }
+func (s *boostedScorer) Advance(target int) (int, error) { return s.scorer.Advance(target) }
+func (s *boostedScorer) Freq() (int, error)              { return s.scorer.Freq() }
+func (s *boostedScorer) Cost() int64                     { return s.scorer.Cost() }
+
+func (s *boostedScorer) Score() (float32, error) {
+	score, err := s.scorer.Score()
+	if err != nil {
+		return 0, err
+	}
+	score *= s.qWeight * s.vals.FloatVal(s.scorer.DocId())
+	// Current Lucene priority queues can't handle NaN and -Infinity, so
+	// map to -Float.MAX_VALUE. This conditional handles both -infinity
+	// and NaN since comparisons with NaN are always false.
+	if score > -math.MaxFloat32 {
+		return score, nil
+	}
+	return -math.MaxFloat32, nil
+}
+
+func (s *boostedScorer) String() string {
+	return fmt.Sprintf("boosted(%v)", s.scorer)
+}
@@ -0,0 +1,157 @@
+package docvalues
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"strconv"
+)
+
+// queries/function/docvalues/FloatDocValues.java
+
+type FloatDocValuesSPI interface {
+	FloatVal(doc int) float32
+}
+
+/*
+Abstract FunctionValues implementation which supports retrieving
+float values. Implementations can control how the float values are
+loaded through FloatVal().
+*/
+type FloatDocValues struct {
+	spi FloatDocValuesSPI
+	vs  function.ValueSource
+}
+
+func NewFloatDocValues(vs function.ValueSource, spi FloatDocValuesSPI) *FloatDocValues {
+	return &FloatDocValues{spi, vs}
+}
+
+func (v *FloatDocValues) FloatVal(doc int) float32  { return v.spi.FloatVal(doc) }
+func (v *FloatDocValues) IntVal(doc int) int        { return int(v.spi.FloatVal(doc)) }
+func (v *FloatDocValues) LongVal(doc int) int64     { return int64(v.spi.FloatVal(doc)) }
+func (v *FloatDocValues) DoubleVal(doc int) float64 { return float64(v.spi.FloatVal(doc)) }
+func (v *FloatDocValues) Exists(doc int) bool       { return true }
+
+func (v *FloatDocValues) StrVal(doc int) string {
+	return strconv.FormatFloat(float64(v.spi.FloatVal(doc)), 'g', -1, 32)
+}
+
+func (v *FloatDocValues) ToString(doc int) string {
+	return fmt.Sprintf("%v=%v", v.vs.Description(), v.StrVal(doc))
+}
+
+func (v *FloatDocValues) Explain(doc int) search.Explanation {
+	return search.NewExplanation(v.spi.FloatVal(doc), v.ToString(doc))
+}
+
+// queries/function/docvalues/DoubleDocValues.java
+
+type DoubleDocValuesSPI interface {
+	DoubleVal(doc int) float64
+}
+
+/*
+Abstract FunctionValues implementation which supports retrieving
+double values. Implementations can control how the double values are
+loaded through DoubleVal().
+*/
+type DoubleDocValues struct {
+	spi DoubleDocValuesSPI
+	vs  function.ValueSource
+}
+
+func NewDoubleDocValues(vs function.ValueSource, spi DoubleDocValuesSPI) *DoubleDocValues {
+	return &DoubleDocValues{spi, vs}
+}
+
+func (v *DoubleDocValues) DoubleVal(doc int) float64 { return v.spi.DoubleVal(doc) }
+func (v *DoubleDocValues) FloatVal(doc int) float32  { return float32(v.spi.DoubleVal(doc)) }
+func (v *DoubleDocValues) IntVal(doc int) int        { return int(v.spi.DoubleVal(doc)) }
+func (v *DoubleDocValues) LongVal(doc int) int64     { return int64(v.spi.DoubleVal(doc)) }
+func (v *DoubleDocValues) Exists(doc int) bool       { return true }
+
+func (v *DoubleDocValues) StrVal(doc int) string {
+	return strconv.FormatFloat(v.spi.DoubleVal(doc), 'g', -1, 64)
+}
+
+func (v *DoubleDocValues) ToString(doc int) string {
+	return fmt.Sprintf("%v=%v", v.vs.Description(), v.StrVal(doc))
+}
+
+func (v *DoubleDocValues) Explain(doc int) search.Explanation {
+	return search.NewExplanation(v.FloatVal(doc), v.ToString(doc))
+}
+
+// queries/function/docvalues/IntDocValues.java
+
+type IntDocValuesSPI interface {
+	IntVal(doc int) int
+}
+
+/*
+Abstract FunctionValues implementation which supports retrieving int
+values. Implementations can control how the int values are loaded
+through IntVal().
+*/
+type IntDocValues struct {
+	spi IntDocValuesSPI
+	vs  function.ValueSource
+}
+
+func NewIntDocValues(vs function.ValueSource, spi IntDocValuesSPI) *IntDocValues {
+	return &IntDocValues{spi, vs}
+}
+
+func (v *IntDocValues) IntVal(doc int) int        { return v.spi.IntVal(doc) }
+func (v *IntDocValues) FloatVal(doc int) float32  { return float32(v.spi.IntVal(doc)) }
+func (v *IntDocValues) LongVal(doc int) int64     { return int64(v.spi.IntVal(doc)) }
+func (v *IntDocValues) DoubleVal(doc int) float64 { return float64(v.spi.IntVal(doc)) }
+func (v *IntDocValues) StrVal(doc int) string     { return strconv.Itoa(v.spi.IntVal(doc)) }
+func (v *IntDocValues) Exists(doc int) bool       { return true }
+
+func (v *IntDocValues) ToString(doc int) string {
+	return fmt.Sprintf("%v=%v", v.vs.Description(), v.StrVal(doc))
+}
+
+func (v *IntDocValues) Explain(doc int) search.Explanation {
+	return search.NewExplanation(v.FloatVal(doc), v.ToString(doc))
+}
+
+// queries/function/docvalues/LongDocValues.java
+
+type LongDocValuesSPI interface {
+	LongVal(doc int) int64
+}
+
+/*
+Abstract FunctionValues implementation which supports retrieving
+int64 values. Implementations can control how the values are loaded
+through LongVal().
+*/
+type LongDocValues struct {
+	spi LongDocValuesSPI
+	vs  function.ValueSource
+}
+
+func NewLongDocValues(vs function.ValueSource, spi LongDocValuesSPI) *LongDocValues {
+	return &LongDocValues{spi, vs}
+}
+
+func (v *LongDocValues) LongVal(doc int) int64     { return v.spi.LongVal(doc) }
+func (v *LongDocValues) FloatVal(doc int) float32  { return float32(v.spi.LongVal(doc)) }
+func (v *LongDocValues) IntVal(doc int) int        { return int(v.spi.LongVal(doc)) }
+func (v *LongDocValues) DoubleVal(doc int) float64 { return float64(v.spi.LongVal(doc)) }
+func (v *LongDocValues) Exists(doc int) bool       { return true }
+
+func (v *LongDocValues) StrVal(doc int) string {
+	return strconv.FormatInt(v.spi.LongVal(doc), 10)
+}
+
+func (v *LongDocValues) ToString(doc int) string {
+	return fmt.Sprintf("%v=%v", v.vs.Description(), v.StrVal(doc))
+}
+
+func (v *LongDocValues) Explain(doc int) search.Explanation {
+	return search.NewExplanation(v.FloatVal(doc), v.ToString(doc))
+}
@@ -0,0 +1,180 @@
+package function
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"math"
+)
+
+// queries/function/FunctionQuery.java
+
+/*
+Returns a score for each document based on a ValueSource, often some
+function of the value of a field.
+
+Note: This API is experimental and may change in non
+backward-compatible ways in the future.
+*/
+type FunctionQuery struct {
+	*search.AbstractQuery
+	fn ValueSource
+}
+
+func NewFunctionQuery(fn ValueSource) *FunctionQuery {
+	ans := &FunctionQuery{fn: fn}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+/* Returns the associated ValueSource. */
+func (q *FunctionQuery) ValueSource() ValueSource {
+	return q.fn
+}
+
+func (q *FunctionQuery) CreateWeight(searcher *search.IndexSearcher) (search.Weight, error) {
+	return newFunctionWeight(q, searcher)
+}
+
+func (q *FunctionQuery) Clone() search.Query {
+	ans := NewFunctionQuery(q.fn)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *FunctionQuery) ToString(field string) string {
+	qboost := q.Boost()
+	if qboost == 1 {
+		return q.fn.Description()
+	}
+	return fmt.Sprintf("(%v)^%v", q.fn.Description(), qboost)
+}
+
+type FunctionWeight struct {
+	*search.WeightImpl
+	owner       *FunctionQuery
+	searcher    *search.IndexSearcher
+	queryNorm   float32
+	queryWeight float32
+	context     Context
+}
+
+func newFunctionWeight(owner *FunctionQuery, searcher *search.IndexSearcher) (*FunctionWeight, error) {
+	ans := &FunctionWeight{
+		owner:    owner,
+		searcher: searcher,
+		context:  NewContext(searcher),
+	}
+	ans.WeightImpl = search.NewWeightImpl(ans)
+	if err := owner.fn.CreateWeight(ans.context, searcher); err != nil {
+		return nil, err
+	}
+	return ans, nil
+}
+
+func (w *FunctionWeight) ValueForNormalization() float32 {
+	w.queryWeight = w.owner.Boost()
+	return w.queryWeight * w.queryWeight
+}
+
+func (w *FunctionWeight) Normalize(norm, topLevelBoost float32) {
+	w.queryNorm = norm * topLevelBoost
+	w.queryWeight *= w.queryNorm
+}
+
+func (w *FunctionWeight) IsScoresDocsOutOfOrder() bool {
+	return false
+}
+
+func (w *FunctionWeight) Scorer(context *index.AtomicReaderContext, acceptDocs util.Bits) (search.Scorer, error) {
+	return newAllScorer(context, acceptDocs, w, w.queryWeight)
+}
+
+func (w *FunctionWeight) Explain(context *index.AtomicReaderContext, doc int) (search.Explanation, error) {
+	scorer, err := newAllScorer(context, context.Reader().(index.AtomicReader).LiveDocs(), w, w.queryWeight)
+	if err != nil {
+		return nil, err
+	}
+	return scorer.explain(doc), nil
+}
+
+/*
+Scores every document of the segment accepted by acceptDocs with the
+value of the function.
+*/
+type AllScorer struct {
+	weight     *FunctionWeight
+	maxDoc     int
+	qWeight    float32
+	doc        int
+	acceptDocs util.Bits
+	vals       FunctionValues
+}
+
+func newAllScorer(context *index.AtomicReaderContext, acceptDocs util.Bits, w *FunctionWeight, qWeight float32) (*AllScorer, error) {
+	vals, err := w.owner.fn.Values(w.context, context)
+	if err != nil {
+		return nil, err
+	}
+	return &AllScorer{
+		weight:     w,
+		maxDoc:     context.Reader().MaxDoc(),
+		qWeight:    qWeight,
+		doc:        -1,
+		acceptDocs: acceptDocs,
+		vals:       vals,
+	}, nil
+}
+
+func (s *AllScorer) Weight() search.Weight { return s.weight }
+func (s *AllScorer) DocId() int            { return s.doc }
+
+/*
+Instead of using a DocIdSetIterator, just iterate over all the
+documents, skipping the ones not accepted.
+*/
+func (s *AllScorer) NextDoc() (int, error) {
+	for {
+		s.doc++
+		if s.doc >= s.maxDoc {
+			s.doc = NO_MORE_DOCS
+			return s.doc, nil
+		}
+		if s.acceptDocs != nil && !s.acceptDocs.At(s.doc) {
+			continue
+		}
+		return s.doc, nil
+	}
+}
+
+func (s *AllScorer) Advance(target int) (int, error) {
+	// this will work even if target==NO_MORE_DOCS
+	s.doc = target - 1
+	return s.NextDoc()
+}
+
+func (s *AllScorer) Score() (float32, error) {
+	score := s.qWeight * s.vals.FloatVal(s.doc)
+	// Current Lucene priority queues can't handle NaN and -Infinity, so
+	// map to -Float.MAX_VALUE. This conditional handles both -infinity
+	// and NaN since comparisons with NaN are always false.
+	if score > -math.MaxFloat32 {
+		return score, nil
+	}
+	return -math.MaxFloat32, nil
+}
+
+func (s *AllScorer) Cost() int64        { return int64(s.maxDoc) }
+func (s *AllScorer) Freq() (int, error) { return 1, nil }
+
+func (s *AllScorer) explain(doc int) search.Explanation {
+	sc := s.qWeight * s.vals.FloatVal(doc)
+	ans := search.NewComplexExplanation(true, sc,
+		fmt.Sprintf("FunctionQuery(%v), product of:", s.weight.owner.fn.Description()))
+	ans.AddDetail(s.vals.Explain(doc))
+	ans.AddDetail(search.NewExplanation(s.weight.owner.Boost(), "boost"))
+	ans.AddDetail(search.NewExplanation(s.weight.queryNorm, "queryNorm"))
+	return ans
+}
@@ -0,0 +1,28 @@
+package function
+
+import (
+	"github.com/jtejido/golucene/core/search"
+)
+
+// queries/function/FunctionValues.java
+
+/*
+Represents field values as different types. Normally created via a
+ValueSource for a particular field and reader.
+
+Documents must be requested in increasing order within a segment;
+implementations may have to start over (at a cost) otherwise.
+*/
+type FunctionValues interface {
+	FloatVal(doc int) float32
+	IntVal(doc int) int
+	LongVal(doc int) int64
+	DoubleVal(doc int) float64
+	StrVal(doc int) string
+	// Returns true if there is a value for this document
+	Exists(doc int) bool
+	// Returns a string representation of the value of doc, prefixed
+	// by the description of the values.
+	ToString(doc int) string
+	Explain(doc int) search.Explanation
+}
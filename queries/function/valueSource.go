@@ -0,0 +1,44 @@
+package function
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+)
+
+// queries/function/ValueSource.java
+
+/*
+Instantiates FunctionValues for a particular reader.
+
+Often used when creating a FunctionQuery.
+*/
+type ValueSource interface {
+	// Gets the values for this reader and the context that was
+	// previously passed to CreateWeight()
+	Values(context Context, readerContext *index.AtomicReaderContext) (FunctionValues, error)
+	// description of field, used in Explain()
+	Description() string
+	// Implementations should propagate CreateWeight to sub-ValueSources
+	// which can optionally store weight info in the context. The
+	// context object will be passed to Values() where this info can be
+	// retrieved.
+	CreateWeight(context Context, searcher *search.IndexSearcher) error
+}
+
+/*
+Per-search state shared by the ValueSources of a query, keyed by
+arbitrary (comparable) values. The searcher is available under the
+"searcher" key.
+*/
+type Context map[interface{}]interface{}
+
+/* Returns a new non-threadsafe context map. */
+func NewContext(searcher *search.IndexSearcher) Context {
+	return Context{"searcher": searcher}
+}
+
+/* Returns the searcher stored in the context by NewContext(). */
+func (c Context) Searcher() *search.IndexSearcher {
+	searcher, _ := c["searcher"].(*search.IndexSearcher)
+	return searcher
+}
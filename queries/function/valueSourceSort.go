@@ -0,0 +1,136 @@
+package function
+
+import (
+	"container/heap"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"math"
+	"sort"
+)
+
+// queries/function/ValueSource.java (ValueSourceSortField, ValueSourceComparator)
+
+/*
+Collects the top hits of a search by the values of a ValueSource,
+rather than by score, like sorting on ValueSource.getSortField() does
+in Lucene. Hits are ordered by increasing value, or decreasing value
+if reverse is true; hits whose value doesn't exist come last, and
+ties are broken by increasing doc ID.
+*/
+type ValueSourceSortCollector struct {
+	source    ValueSource
+	context   Context
+	numHits   int
+	docBase   int
+	values    FunctionValues
+	err       error
+	hits      valueHitQueue
+	totalHits int
+}
+
+/*
+Creates a ValueSourceSortCollector keeping the numHits first hits by
+the values of source, for searches run with searcher.
+*/
+func NewValueSourceSortCollector(searcher *search.IndexSearcher, source ValueSource,
+	numHits int, reverse bool) (*ValueSourceSortCollector, error) {
+
+	context := NewContext(searcher)
+	if err := source.CreateWeight(context, searcher); err != nil {
+		return nil, err
+	}
+	return &ValueSourceSortCollector{
+		source:  source,
+		context: context,
+		numHits: numHits,
+		hits:    valueHitQueue{reverse: reverse},
+	}, nil
+}
+
+func (c *ValueSourceSortCollector) SetScorer(scorer search.Scorer) {}
+
+func (c *ValueSourceSortCollector) SetNextReader(ctx *index.AtomicReaderContext) {
+	c.docBase = ctx.DocBase
+	// Collect() reports the error
+	c.values, c.err = c.source.Values(c.context, ctx)
+}
+
+func (c *ValueSourceSortCollector) AcceptsDocsOutOfOrder() bool {
+	return true
+}
+
+func (c *ValueSourceSortCollector) Collect(doc int) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.totalHits++
+	hit := &valueHit{doc: c.docBase + doc, missing: !c.values.Exists(doc)}
+	if !hit.missing {
+		hit.value = c.values.DoubleVal(doc)
+	}
+	if c.numHits <= 0 {
+		return nil
+	}
+	if len(c.hits.hits) == c.numHits {
+		if !c.hits.before(hit, c.hits.hits[0]) {
+			// not competitive
+			return nil
+		}
+		heap.Pop(&c.hits)
+	}
+	heap.Push(&c.hits, hit)
+	return nil
+}
+
+/*
+Returns the collected hits, in sort order. The Score of each
+ScoreDoc holds its value, or NaN if it has none.
+*/
+func (c *ValueSourceSortCollector) TopDocs() search.TopDocs {
+	hits := make([]*valueHit, len(c.hits.hits))
+	copy(hits, c.hits.hits)
+	sort.Slice(hits, func(i, j int) bool { return c.hits.before(hits[i], hits[j]) })
+
+	scoreDocs := make([]*search.ScoreDoc, len(hits))
+	for i, hit := range hits {
+		score := float32(math.NaN())
+		if !hit.missing {
+			score = float32(hit.value)
+		}
+		scoreDocs[i] = search.NewScoreDoc(hit.doc, score)
+	}
+	return search.NewTopDocs(c.totalHits, scoreDocs, float32(math.NaN()))
+}
+
+type valueHit struct {
+	doc     int
+	value   float64
+	missing bool
+}
+
+/* A heap of the collected hits, the last one in sort order on top. */
+type valueHitQueue struct {
+	hits    []*valueHit
+	reverse bool
+}
+
+/* Returns true if a sorts before b. */
+func (q *valueHitQueue) before(a, b *valueHit) bool {
+	if a.missing != b.missing {
+		return b.missing
+	}
+	if !a.missing && a.value != b.value {
+		return (a.value < b.value) != q.reverse
+	}
+	return a.doc < b.doc
+}
+
+func (q *valueHitQueue) Len() int           { return len(q.hits) }
+func (q *valueHitQueue) Less(i, j int) bool { return q.before(q.hits[j], q.hits[i]) }
+func (q *valueHitQueue) Swap(i, j int)      { q.hits[i], q.hits[j] = q.hits[j], q.hits[i] }
+func (q *valueHitQueue) Push(x interface{}) { q.hits = append(q.hits, x.(*valueHit)) }
+func (q *valueHitQueue) Pop() interface{} {
+	ans := q.hits[len(q.hits)-1]
+	q.hits = q.hits[:len(q.hits)-1]
+	return ans
+}
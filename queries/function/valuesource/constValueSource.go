@@ -0,0 +1,40 @@
+package valuesource
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"github.com/jtejido/golucene/queries/function/docvalues"
+)
+
+// queries/function/valuesource/ConstValueSource.java
+
+/* ConstValueSource returns a constant for all documents. */
+type ConstValueSource struct {
+	constant float32
+}
+
+func NewConstValueSource(constant float32) *ConstValueSource {
+	return &ConstValueSource{constant}
+}
+
+func (vs *ConstValueSource) Description() string {
+	return fmt.Sprintf("const(%v)", vs.constant)
+}
+
+func (vs *ConstValueSource) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	return nil
+}
+
+func (vs *ConstValueSource) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	return docvalues.NewFloatDocValues(vs, vs), nil
+}
+
+func (vs *ConstValueSource) FloatVal(doc int) float32 {
+	return vs.constant
+}
+
+func (vs *ConstValueSource) String() string {
+	return vs.Description()
+}
@@ -0,0 +1,209 @@
+package valuesource
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/codec/spi"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"github.com/jtejido/golucene/queries/function/docvalues"
+	"strconv"
+	"sync"
+)
+
+/*
+StoredFieldSource obtains float values from the stored value of a
+field, e.g. a popularity or a timestamp added with the document.
+Numeric stored values are used as is, string values are parsed as
+floats. Documents without a (parsable) value get 0 and report false
+from Exists().
+
+Like the FieldCache of Lucene, the values of all the documents of a
+segment are loaded by the first Values() call on it, so that read
+errors are returned from there, and cached by segment core until the
+core is closed.
+
+NOTE: values can't be read from doc values yet: the default codec has
+no doc values format ported, and SegmentReader.NumericDocValues() is
+not implemented.
+*/
+type StoredFieldSource struct {
+	field string
+}
+
+func NewStoredFieldSource(field string) *StoredFieldSource {
+	return &StoredFieldSource{field}
+}
+
+func (vs *StoredFieldSource) Field() string { return vs.field }
+
+func (vs *StoredFieldSource) Description() string {
+	return fmt.Sprintf("float(%v)", vs.field)
+}
+
+func (vs *StoredFieldSource) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	return nil
+}
+
+func (vs *StoredFieldSource) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	values, err := fieldValuesCache.get(readerContext.Reader().(index.AtomicReader), vs.field)
+	if err != nil {
+		return nil, err
+	}
+	ans := &storedFieldValues{storedValues: values}
+	ans.DoubleDocValues = docvalues.NewDoubleDocValues(vs, ans)
+	return ans, nil
+}
+
+func (vs *StoredFieldSource) String() string {
+	return vs.Description()
+}
+
+type storedFieldValues struct {
+	*docvalues.DoubleDocValues
+	*storedValues
+}
+
+func (v *storedFieldValues) DoubleVal(doc int) float64 {
+	return v.values[doc]
+}
+
+func (v *storedFieldValues) Exists(doc int) bool {
+	return v.exists[doc]
+}
+
+/* The values of a stored field, by document of a segment. */
+type storedValues struct {
+	values []float64
+	exists []bool
+}
+
+func loadStoredValues(reader index.AtomicReader, field string) (*storedValues, error) {
+	ans := &storedValues{
+		values: make([]float64, reader.MaxDoc()),
+		exists: make([]bool, reader.MaxDoc()),
+	}
+	for doc := range ans.values {
+		visitor := &numericFieldVisitor{field: field}
+		if err := reader.VisitDocument(doc, visitor); err != nil {
+			return nil, err
+		}
+		ans.values[doc], ans.exists[doc] = visitor.value, visitor.found
+	}
+	return ans, nil
+}
+
+/*
+Caches the values of stored fields by segment core and field name.
+Entries are shared by reopened readers, and evicted once their core
+is closed.
+*/
+type storedValuesCache struct {
+	sync.Mutex
+	cache map[interface{}]map[string]*storedValues
+}
+
+var fieldValuesCache = &storedValuesCache{
+	cache: make(map[interface{}]map[string]*storedValues),
+}
+
+/* Returns the values of field in reader, loading them on first use. */
+func (c *storedValuesCache) get(reader index.AtomicReader, field string) (*storedValues, error) {
+	segment, ok := reader.(*index.SegmentReader)
+	if !ok {
+		// no core to key on, nor to be told of its close
+		return loadStoredValues(reader, field)
+	}
+	key := segment.CoreCacheKey()
+
+	c.Lock()
+	fields, listening := c.cache[key]
+	ans, ok := fields[field]
+	c.Unlock()
+	if ok {
+		return ans, nil
+	}
+	if !listening {
+		// Before publishing, so that the entry of a core closed meanwhile
+		// is evicted, and outside of the lock: registering waits on the
+		// core's listener routine, which takes the lock to evict.
+		segment.AddCoreClosedListener(c)
+	}
+	ans, err := loadStoredValues(reader, field)
+	if err != nil {
+		return nil, err
+	}
+	c.Lock()
+	defer c.Unlock()
+	if segment.RefCount() <= 0 {
+		// the core was closed, and its entries evicted, meanwhile
+		return ans, nil
+	}
+	if fields, listening = c.cache[key]; !listening {
+		fields = make(map[string]*storedValues)
+		c.cache[key] = fields
+	}
+	if cached, ok := fields[field]; ok {
+		// loaded concurrently
+		return cached, nil
+	}
+	fields[field] = ans
+	return ans, nil
+}
+
+/* Evicts the fields of a closed segment core. */
+func (c *storedValuesCache) OnClose(ownerCoreCacheKey interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.cache, ownerCoreCacheKey)
+}
+
+/* Collects the first numeric value of a single stored field. */
+type numericFieldVisitor struct {
+	field string
+	value float64
+	found bool
+}
+
+func (v *numericFieldVisitor) set(value float64) error {
+	v.value, v.found = value, true
+	return nil
+}
+
+func (v *numericFieldVisitor) BinaryField(fi *model.FieldInfo, value []byte) error {
+	return v.StringField(fi, string(value))
+}
+
+func (v *numericFieldVisitor) StringField(fi *model.FieldInfo, value string) error {
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return v.set(f)
+	}
+	return nil
+}
+
+func (v *numericFieldVisitor) IntField(fi *model.FieldInfo, value int) error {
+	return v.set(float64(value))
+}
+
+func (v *numericFieldVisitor) LongField(fi *model.FieldInfo, value int64) error {
+	return v.set(float64(value))
+}
+
+func (v *numericFieldVisitor) FloatField(fi *model.FieldInfo, value float32) error {
+	return v.set(float64(value))
+}
+
+func (v *numericFieldVisitor) DoubleField(fi *model.FieldInfo, value float64) error {
+	return v.set(value)
+}
+
+func (v *numericFieldVisitor) NeedsField(fi *model.FieldInfo) (spi.StoredFieldVisitorStatus, error) {
+	if v.found {
+		return spi.STORED_FIELD_VISITOR_STATUS_STOP, nil
+	}
+	if fi.Name == v.field {
+		return spi.STORED_FIELD_VISITOR_STATUS_YES, nil
+	}
+	return spi.STORED_FIELD_VISITOR_STATUS_NO, nil
+}
@@ -0,0 +1,65 @@
+package valuesource
+
+import (
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func TestStoredFieldSourceCache(t *testing.T) {
+	var docs [][]model.IndexableField
+	for _, popularity := range []string{"10", "none", "2.5"} {
+		d := docu.NewDocument()
+		d.Add(docu.NewFieldFromString("popularity", popularity, docu.STRING_FIELD_TYPE_STORED))
+		docs = append(docs, d.Fields())
+	}
+	reader := testindex.NewReader(t, docs...)
+	context := function.NewContext(search.NewIndexSearcher(reader))
+	source := NewStoredFieldSource("popularity")
+
+	leaves := reader.Leaves()
+	for _, ctx := range leaves {
+		values, err := source.Values(context, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := NewStoredFieldSource("popularity").Values(context, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values.(*storedFieldValues).storedValues != again.(*storedFieldValues).storedValues {
+			t.Error("expected the values to be loaded once per segment")
+		}
+	}
+	values, err := source.Values(context, leaves[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for doc, expected := range []float64{10, 0, 2.5} {
+		if v := values.DoubleVal(doc); v != expected {
+			t.Errorf("doc %v: expected %v, but %v", doc, expected, v)
+		}
+		if exists := values.Exists(doc); exists != (expected != 0) {
+			t.Errorf("doc %v: expected exists=%v, but %v", doc, expected != 0, exists)
+		}
+	}
+
+	keys := make([]interface{}, len(leaves))
+	for i, ctx := range leaves {
+		keys[i] = ctx.Reader().(*index.SegmentReader).CoreCacheKey()
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fieldValuesCache.Lock()
+	defer fieldValuesCache.Unlock()
+	for _, key := range keys {
+		if _, ok := fieldValuesCache.cache[key]; ok {
+			t.Error("expected the cache to be emptied when the reader closes")
+		}
+	}
+}
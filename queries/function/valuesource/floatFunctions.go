@@ -0,0 +1,477 @@
+package valuesource
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"github.com/jtejido/golucene/queries/function/docvalues"
+	"math"
+)
+
+// queries/function/valuesource/MultiFloatFunction.java
+
+type MultiFloatFunctionSPI interface {
+	Name() string
+	Func(doc int, values []function.FunctionValues) float32
+}
+
+/*
+Abstract ValueSource implementation which wraps multiple ValueSources
+and applies an extendible float function to their values.
+*/
+type MultiFloatFunction struct {
+	spi     MultiFloatFunctionSPI
+	sources []function.ValueSource
+}
+
+func NewMultiFloatFunction(spi MultiFloatFunctionSPI, sources []function.ValueSource) *MultiFloatFunction {
+	return &MultiFloatFunction{spi, sources}
+}
+
+func (f *MultiFloatFunction) Description() string {
+	var buf bytes.Buffer
+	buf.WriteString(f.spi.Name())
+	buf.WriteRune('(')
+	for i, source := range f.sources {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.WriteString(source.Description())
+	}
+	buf.WriteRune(')')
+	return buf.String()
+}
+
+func (f *MultiFloatFunction) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	for _, source := range f.sources {
+		if err := source.CreateWeight(context, searcher); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *MultiFloatFunction) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	values := make([]function.FunctionValues, len(f.sources))
+	for i, source := range f.sources {
+		var err error
+		if values[i], err = source.Values(context, readerContext); err != nil {
+			return nil, err
+		}
+	}
+	ans := &multiFloatValues{f: f, values: values}
+	ans.FloatDocValues = docvalues.NewFloatDocValues(f, ans)
+	return ans, nil
+}
+
+func (f *MultiFloatFunction) String() string {
+	return f.Description()
+}
+
+type multiFloatValues struct {
+	*docvalues.FloatDocValues
+	f      *MultiFloatFunction
+	values []function.FunctionValues
+}
+
+func (v *multiFloatValues) FloatVal(doc int) float32 {
+	return v.f.spi.Func(doc, v.values)
+}
+
+func (v *multiFloatValues) ToString(doc int) string {
+	var buf bytes.Buffer
+	buf.WriteString(v.f.spi.Name())
+	buf.WriteRune('(')
+	for i, values := range v.values {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.WriteString(values.ToString(doc))
+	}
+	buf.WriteRune(')')
+	return buf.String()
+}
+
+// queries/function/valuesource/SumFloatFunction.java
+
+/* SumFloatFunction returns the sum of its components. */
+type SumFloatFunction struct {
+	*MultiFloatFunction
+}
+
+func NewSumFloatFunction(sources ...function.ValueSource) *SumFloatFunction {
+	ans := new(SumFloatFunction)
+	ans.MultiFloatFunction = NewMultiFloatFunction(ans, sources)
+	return ans
+}
+
+func (f *SumFloatFunction) Name() string { return "sum" }
+
+func (f *SumFloatFunction) Func(doc int, values []function.FunctionValues) (sum float32) {
+	for _, v := range values {
+		sum += v.FloatVal(doc)
+	}
+	return
+}
+
+// queries/function/valuesource/ProductFloatFunction.java
+
+/* ProductFloatFunction returns the product of its components. */
+type ProductFloatFunction struct {
+	*MultiFloatFunction
+}
+
+func NewProductFloatFunction(sources ...function.ValueSource) *ProductFloatFunction {
+	ans := new(ProductFloatFunction)
+	ans.MultiFloatFunction = NewMultiFloatFunction(ans, sources)
+	return ans
+}
+
+func (f *ProductFloatFunction) Name() string { return "product" }
+
+func (f *ProductFloatFunction) Func(doc int, values []function.FunctionValues) float32 {
+	product := float32(1)
+	for _, v := range values {
+		product *= v.FloatVal(doc)
+	}
+	return product
+}
+
+// queries/function/valuesource/MaxFloatFunction.java
+
+/* MaxFloatFunction returns the max of its components. */
+type MaxFloatFunction struct {
+	*MultiFloatFunction
+}
+
+func NewMaxFloatFunction(sources ...function.ValueSource) *MaxFloatFunction {
+	ans := new(MaxFloatFunction)
+	ans.MultiFloatFunction = NewMultiFloatFunction(ans, sources)
+	return ans
+}
+
+func (f *MaxFloatFunction) Name() string { return "max" }
+
+func (f *MaxFloatFunction) Func(doc int, values []function.FunctionValues) float32 {
+	if len(values) == 0 {
+		return 0
+	}
+	max := float32(-math.MaxFloat32)
+	for _, v := range values {
+		if val := v.FloatVal(doc); val > max {
+			max = val
+		}
+	}
+	return max
+}
+
+// queries/function/valuesource/MinFloatFunction.java
+
+/* MinFloatFunction returns the min of its components. */
+type MinFloatFunction struct {
+	*MultiFloatFunction
+}
+
+func NewMinFloatFunction(sources ...function.ValueSource) *MinFloatFunction {
+	ans := new(MinFloatFunction)
+	ans.MultiFloatFunction = NewMultiFloatFunction(ans, sources)
+	return ans
+}
+
+func (f *MinFloatFunction) Name() string { return "min" }
+
+func (f *MinFloatFunction) Func(doc int, values []function.FunctionValues) float32 {
+	if len(values) == 0 {
+		return 0
+	}
+	min := float32(math.MaxFloat32)
+	for _, v := range values {
+		if val := v.FloatVal(doc); val < min {
+			min = val
+		}
+	}
+	return min
+}
+
+// queries/function/valuesource/DualFloatFunction.java
+
+type DualFloatFunctionSPI interface {
+	Name() string
+	Func(doc int, a, b function.FunctionValues) float32
+}
+
+/* Abstract ValueSource implementation which applies a function to two ValueSources. */
+type DualFloatFunction struct {
+	spi  DualFloatFunctionSPI
+	a, b function.ValueSource
+}
+
+func NewDualFloatFunction(spi DualFloatFunctionSPI, a, b function.ValueSource) *DualFloatFunction {
+	return &DualFloatFunction{spi, a, b}
+}
+
+func (f *DualFloatFunction) Description() string {
+	return fmt.Sprintf("%v(%v,%v)", f.spi.Name(), f.a.Description(), f.b.Description())
+}
+
+func (f *DualFloatFunction) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	if err := f.a.CreateWeight(context, searcher); err != nil {
+		return err
+	}
+	return f.b.CreateWeight(context, searcher)
+}
+
+func (f *DualFloatFunction) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	aVals, err := f.a.Values(context, readerContext)
+	if err != nil {
+		return nil, err
+	}
+	bVals, err := f.b.Values(context, readerContext)
+	if err != nil {
+		return nil, err
+	}
+	ans := &dualFloatValues{f: f, aVals: aVals, bVals: bVals}
+	ans.FloatDocValues = docvalues.NewFloatDocValues(f, ans)
+	return ans, nil
+}
+
+func (f *DualFloatFunction) String() string {
+	return f.Description()
+}
+
+type dualFloatValues struct {
+	*docvalues.FloatDocValues
+	f            *DualFloatFunction
+	aVals, bVals function.FunctionValues
+}
+
+func (v *dualFloatValues) FloatVal(doc int) float32 {
+	return v.f.spi.Func(doc, v.aVals, v.bVals)
+}
+
+func (v *dualFloatValues) ToString(doc int) string {
+	return fmt.Sprintf("%v(%v,%v)", v.f.spi.Name(), v.aVals.ToString(doc), v.bVals.ToString(doc))
+}
+
+// queries/function/valuesource/DivFloatFunction.java
+
+/* Function to divide "a" by "b". */
+type DivFloatFunction struct {
+	*DualFloatFunction
+}
+
+func NewDivFloatFunction(a, b function.ValueSource) *DivFloatFunction {
+	ans := new(DivFloatFunction)
+	ans.DualFloatFunction = NewDualFloatFunction(ans, a, b)
+	return ans
+}
+
+func (f *DivFloatFunction) Name() string { return "div" }
+
+func (f *DivFloatFunction) Func(doc int, a, b function.FunctionValues) float32 {
+	return a.FloatVal(doc) / b.FloatVal(doc)
+}
+
+// queries/function/valuesource/PowFloatFunction.java
+
+/* Function to raise the base "a" to the power "b". */
+type PowFloatFunction struct {
+	*DualFloatFunction
+}
+
+func NewPowFloatFunction(a, b function.ValueSource) *PowFloatFunction {
+	ans := new(PowFloatFunction)
+	ans.DualFloatFunction = NewDualFloatFunction(ans, a, b)
+	return ans
+}
+
+func (f *PowFloatFunction) Name() string { return "pow" }
+
+func (f *PowFloatFunction) Func(doc int, a, b function.FunctionValues) float32 {
+	return float32(math.Pow(float64(a.FloatVal(doc)), float64(b.FloatVal(doc))))
+}
+
+// queries/function/valuesource/SimpleFloatFunction.java
+
+type SimpleFloatFunctionSPI interface {
+	Name() string
+	Func(doc int, vals function.FunctionValues) float32
+}
+
+/* A simple float function with a single argument. */
+type SimpleFloatFunction struct {
+	spi    SimpleFloatFunctionSPI
+	source function.ValueSource
+}
+
+func NewSimpleFloatFunction(spi SimpleFloatFunctionSPI, source function.ValueSource) *SimpleFloatFunction {
+	return &SimpleFloatFunction{spi, source}
+}
+
+func (f *SimpleFloatFunction) Description() string {
+	return fmt.Sprintf("%v(%v)", f.spi.Name(), f.source.Description())
+}
+
+func (f *SimpleFloatFunction) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	return f.source.CreateWeight(context, searcher)
+}
+
+func (f *SimpleFloatFunction) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	vals, err := f.source.Values(context, readerContext)
+	if err != nil {
+		return nil, err
+	}
+	ans := &simpleFloatValues{f: f, vals: vals}
+	ans.FloatDocValues = docvalues.NewFloatDocValues(f, ans)
+	return ans, nil
+}
+
+func (f *SimpleFloatFunction) String() string {
+	return f.Description()
+}
+
+type simpleFloatValues struct {
+	*docvalues.FloatDocValues
+	f    *SimpleFloatFunction
+	vals function.FunctionValues
+}
+
+func (v *simpleFloatValues) FloatVal(doc int) float32 {
+	return v.f.spi.Func(doc, v.vals)
+}
+
+func (v *simpleFloatValues) ToString(doc int) string {
+	return fmt.Sprintf("%v(%v)", v.f.spi.Name(), v.vals.ToString(doc))
+}
+
+/* Function to take the base 10 log of its argument. */
+type LogFloatFunction struct {
+	*SimpleFloatFunction
+}
+
+func NewLogFloatFunction(source function.ValueSource) *LogFloatFunction {
+	ans := new(LogFloatFunction)
+	ans.SimpleFloatFunction = NewSimpleFloatFunction(ans, source)
+	return ans
+}
+
+func (f *LogFloatFunction) Name() string { return "log" }
+
+func (f *LogFloatFunction) Func(doc int, vals function.FunctionValues) float32 {
+	return float32(math.Log10(float64(vals.FloatVal(doc))))
+}
+
+// queries/function/valuesource/LinearFloatFunction.java
+
+/*
+LinearFloatFunction implements a linear function over another
+ValueSource.
+
+Normally used as an argument to a FunctionQuery.
+*/
+type LinearFloatFunction struct {
+	source           function.ValueSource
+	slope, intercept float32
+}
+
+func NewLinearFloatFunction(source function.ValueSource, slope, intercept float32) *LinearFloatFunction {
+	return &LinearFloatFunction{source, slope, intercept}
+}
+
+func (f *LinearFloatFunction) Description() string {
+	return fmt.Sprintf("%v*float(%v)+%v", f.slope, f.source.Description(), f.intercept)
+}
+
+func (f *LinearFloatFunction) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	return f.source.CreateWeight(context, searcher)
+}
+
+func (f *LinearFloatFunction) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	vals, err := f.source.Values(context, readerContext)
+	if err != nil {
+		return nil, err
+	}
+	ans := &linearFloatValues{f: f, vals: vals}
+	ans.FloatDocValues = docvalues.NewFloatDocValues(f, ans)
+	return ans, nil
+}
+
+func (f *LinearFloatFunction) String() string {
+	return f.Description()
+}
+
+type linearFloatValues struct {
+	*docvalues.FloatDocValues
+	f    *LinearFloatFunction
+	vals function.FunctionValues
+}
+
+func (v *linearFloatValues) FloatVal(doc int) float32 {
+	return v.vals.FloatVal(doc)*v.f.slope + v.f.intercept
+}
+
+func (v *linearFloatValues) ToString(doc int) string {
+	return fmt.Sprintf("%v*float(%v)+%v", v.f.slope, v.vals.ToString(doc), v.f.intercept)
+}
+
+// queries/function/valuesource/ReciprocalFloatFunction.java
+
+/*
+ReciprocalFloatFunction implements a reciprocal function
+f(x) = a/(mx+b), based on the float value of a field or function as
+exported by ValueSource.
+
+When a and b are equal, and x>=0, this function has a maximum value
+of 1 that drops as x increases. Increasing the value of a and b
+together results in a movement of the entire function to a flatter
+part of the curve.
+
+A common use is to boost recent documents, e.g. recip(age,1,1000,1000)
+where age is the age of the document.
+*/
+type ReciprocalFloatFunction struct {
+	source  function.ValueSource
+	m, a, b float32
+}
+
+func NewReciprocalFloatFunction(source function.ValueSource, m, a, b float32) *ReciprocalFloatFunction {
+	return &ReciprocalFloatFunction{source, m, a, b}
+}
+
+func (f *ReciprocalFloatFunction) Description() string {
+	return fmt.Sprintf("%v/(%v*float(%v)+%v)", f.a, f.m, f.source.Description(), f.b)
+}
+
+func (f *ReciprocalFloatFunction) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	return f.source.CreateWeight(context, searcher)
+}
+
+func (f *ReciprocalFloatFunction) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	vals, err := f.source.Values(context, readerContext)
+	if err != nil {
+		return nil, err
+	}
+	ans := &reciprocalFloatValues{f: f, vals: vals}
+	ans.FloatDocValues = docvalues.NewFloatDocValues(f, ans)
+	return ans, nil
+}
+
+func (f *ReciprocalFloatFunction) String() string {
+	return f.Description()
+}
+
+type reciprocalFloatValues struct {
+	*docvalues.FloatDocValues
+	f    *ReciprocalFloatFunction
+	vals function.FunctionValues
+}
+
+func (v *reciprocalFloatValues) FloatVal(doc int) float32 {
+	return v.f.a / (v.f.m*v.vals.FloatVal(doc) + v.f.b)
+}
+
+func (v *reciprocalFloatValues) ToString(doc int) string {
+	return fmt.Sprintf("%v/(%v*float(%v)+%v)", v.f.a, v.f.m, v.vals.ToString(doc), v.f.b)
+}
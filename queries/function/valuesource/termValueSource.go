@@ -0,0 +1,245 @@
+package valuesource
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/queries/function"
+	"github.com/jtejido/golucene/queries/function/docvalues"
+)
+
+/*
+The subset of TFIDFSimilarity needed by the norm and idf value
+sources, satisfied by DefaultSimilarity.
+*/
+type tfidfSimilarity interface {
+	Idf(docFreq, numDocs int64) float32
+	DecodeNormValue(norm int64) float32
+}
+
+func asTFIDF(searcher *search.IndexSearcher, name string) (tfidfSimilarity, error) {
+	if searcher == nil {
+		return nil, fmt.Errorf("%v requires the searcher in the context", name)
+	}
+	if sim, ok := searcher.Similarity().(tfidfSimilarity); ok {
+		return sim, nil
+	}
+	return nil, fmt.Errorf("%v requires a TFIDFSimilarity (such as DefaultSimilarity)", name)
+}
+
+// queries/function/valuesource/NormValueSource.java
+
+/*
+Function that returns the decoded norm, as computed by the
+TFIDFSimilarity of the searcher, for every document.
+
+Note that the configured Similarity for the field must be a
+TFIDFSimilarity.
+*/
+type NormValueSource struct {
+	field string
+}
+
+func NewNormValueSource(field string) *NormValueSource {
+	return &NormValueSource{field}
+}
+
+func (vs *NormValueSource) Name() string { return "norm" }
+
+func (vs *NormValueSource) Description() string {
+	return fmt.Sprintf("%v(%v)", vs.Name(), vs.field)
+}
+
+func (vs *NormValueSource) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	context["searcher"] = searcher
+	return nil
+}
+
+func (vs *NormValueSource) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	sim, err := asTFIDF(context.Searcher(), vs.Name())
+	if err != nil {
+		return nil, err
+	}
+	norms, err := readerContext.Reader().(index.AtomicReader).NormValues(vs.field)
+	if err != nil {
+		return nil, err
+	}
+	if norms == nil {
+		return NewConstValueSource(0).Values(context, readerContext)
+	}
+	return docvalues.NewFloatDocValues(vs, floatValuesFunc(func(doc int) float32 {
+		return sim.DecodeNormValue(norms(doc))
+	})), nil
+}
+
+func (vs *NormValueSource) String() string {
+	return vs.Description()
+}
+
+type floatValuesFunc func(doc int) float32
+
+func (f floatValuesFunc) FloatVal(doc int) float32 { return f(doc) }
+
+type intValuesFunc func(doc int) int
+
+func (f intValuesFunc) IntVal(doc int) int { return f(doc) }
+
+// queries/function/valuesource/TermFreqValueSource.java
+
+/*
+Function that returns the term frequency (the number of times the
+term occurs in the field) of a term for every document, or 0 if the
+document does not contain the term.
+*/
+type TermFreqValueSource struct {
+	field string
+	term  *index.Term
+}
+
+func NewTermFreqValueSource(field, value string) *TermFreqValueSource {
+	return &TermFreqValueSource{field, index.NewTerm(field, value)}
+}
+
+func (vs *TermFreqValueSource) Name() string { return "termfreq" }
+
+func (vs *TermFreqValueSource) Description() string {
+	return fmt.Sprintf("%v(%v,%v)", vs.Name(), vs.field, string(vs.term.Bytes))
+}
+
+func (vs *TermFreqValueSource) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	return nil
+}
+
+func (vs *TermFreqValueSource) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	freqs, err := vs.freqs(readerContext.Reader().(index.AtomicReader))
+	if err != nil {
+		return nil, err
+	}
+	return docvalues.NewIntDocValues(vs, intValuesFunc(func(doc int) int {
+		return freqs[doc]
+	})), nil
+}
+
+/*
+Reads the postings of the term in the segment up front, so that I/O
+errors are returned by Values() and documents can be asked in any
+order.
+*/
+func (vs *TermFreqValueSource) freqs(reader index.AtomicReader) (map[int]int, error) {
+	ans := make(map[int]int)
+	terms := reader.Terms(vs.field)
+	if terms == nil {
+		return ans, nil
+	}
+	te := terms.Iterator(nil)
+	ok, err := te.SeekExact(vs.term.Bytes)
+	if err != nil || !ok {
+		return ans, err
+	}
+	docs, err := te.Docs(reader.LiveDocs(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		doc, err := docs.NextDoc()
+		if err != nil {
+			return nil, err
+		}
+		if doc == NO_MORE_DOCS {
+			return ans, nil
+		}
+		if ans[doc], err = docs.Freq(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (vs *TermFreqValueSource) String() string {
+	return vs.Description()
+}
+
+// queries/function/valuesource/DocFreqValueSource.java
+
+/*
+DocFreqValueSource returns the number of documents containing the
+term, in the whole index.
+*/
+type DocFreqValueSource struct {
+	field string
+	term  *index.Term
+}
+
+func NewDocFreqValueSource(field, value string) *DocFreqValueSource {
+	return &DocFreqValueSource{field, index.NewTerm(field, value)}
+}
+
+func (vs *DocFreqValueSource) Name() string { return "docfreq" }
+
+func (vs *DocFreqValueSource) Description() string {
+	return fmt.Sprintf("%v(%v,%v)", vs.Name(), vs.field, string(vs.term.Bytes))
+}
+
+func (vs *DocFreqValueSource) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	context["searcher"] = searcher
+	return nil
+}
+
+func (vs *DocFreqValueSource) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	searcher := context.Searcher()
+	if searcher == nil {
+		return nil, errors.New("docfreq requires the searcher in the context")
+	}
+	docFreq, err := searcher.IndexReader().DocFreq(vs.term)
+	if err != nil {
+		return nil, err
+	}
+	return docvalues.NewIntDocValues(vs, intValuesFunc(func(doc int) int {
+		return docFreq
+	})), nil
+}
+
+func (vs *DocFreqValueSource) String() string {
+	return vs.Description()
+}
+
+// queries/function/valuesource/IDFValueSource.java
+
+/*
+Function that returns the inverse document frequency of the term, as
+computed by the TFIDFSimilarity of the searcher.
+*/
+type IDFValueSource struct {
+	*DocFreqValueSource
+}
+
+func NewIDFValueSource(field, value string) *IDFValueSource {
+	return &IDFValueSource{NewDocFreqValueSource(field, value)}
+}
+
+func (vs *IDFValueSource) Name() string { return "idf" }
+
+func (vs *IDFValueSource) Description() string {
+	return fmt.Sprintf("%v(%v,%v)", vs.Name(), vs.field, string(vs.term.Bytes))
+}
+
+func (vs *IDFValueSource) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	searcher := context.Searcher()
+	sim, err := asTFIDF(searcher, vs.Name())
+	if err != nil {
+		return nil, err
+	}
+	docFreq, err := searcher.IndexReader().DocFreq(vs.term)
+	if err != nil {
+		return nil, err
+	}
+	idf := sim.Idf(int64(docFreq), int64(searcher.IndexReader().MaxDoc()))
+	return docvalues.NewFloatDocValues(vs, floatValuesFunc(func(doc int) float32 {
+		return idf
+	})), nil
+}
+
+func (vs *IDFValueSource) String() string {
+	return vs.Description()
+}
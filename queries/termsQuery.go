@@ -0,0 +1,179 @@
+package queries
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+	"sort"
+)
+
+// queries/TermsFilter.java
+
+/*
+Constructs a filter for docs matching any of the terms added to this
+type. Unlike a RangeFilter this can be used for filtering on multiple
+terms that are not necessarily in a sequence. An example might be a
+collection of primary keys from a database query result or perhaps a
+choice of "category" labels picked by the end user. As a filter, this
+is much faster than the equivalent query (a BooleanQuery with many
+"should" TermQueries)
+
+The terms are sorted once, so that each segment is visited with a
+single TermsEnum per field, seeking forward through the terms
+dictionary.
+*/
+type TermsFilter struct {
+	terms []*index.Term // sorted by field, then bytes; no duplicates
+}
+
+func NewTermsFilter(terms ...*index.Term) *TermsFilter {
+	sorted := make([]*index.Term, len(terms))
+	copy(sorted, terms)
+	sort.Sort(termsByFieldAndBytes(sorted))
+	// remove duplicates
+	var uniq []*index.Term
+	for i, term := range sorted {
+		if i == 0 || term.Field != sorted[i-1].Field || !bytes.Equal(term.Bytes, sorted[i-1].Bytes) {
+			uniq = append(uniq, term)
+		}
+	}
+	return &TermsFilter{uniq}
+}
+
+/* Creates a filter on the given field for the given values. */
+func NewTermsFilterFromStrings(field string, values ...string) *TermsFilter {
+	terms := make([]*index.Term, len(values))
+	for i, value := range values {
+		terms[i] = index.NewTerm(field, value)
+	}
+	return NewTermsFilter(terms...)
+}
+
+func (f *TermsFilter) DocIdSet(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (search.DocIdSet, error) {
+	reader := ctx.Reader().(index.AtomicReader)
+	var result *util.FixedBitSet // lazy init if needed - no need to create a big bitset ahead of time
+	var termsEnum model.TermsEnum
+	var docs model.DocsEnum
+	var terms model.Terms
+	lastField := ""
+	for i, term := range f.terms {
+		if i == 0 || term.Field != lastField {
+			lastField = term.Field
+			if terms = reader.Terms(term.Field); terms == nil {
+				continue
+			}
+			termsEnum = terms.Iterator(termsEnum)
+		} else if terms == nil {
+			continue
+		}
+		ok, err := termsEnum.SeekExact(term.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if docs, err = termsEnum.DocsByFlags(acceptDocs, docs, model.DOCS_ENUM_FLAG_NONE); err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = util.NewFixedBitSetOf(reader.MaxDoc())
+		}
+		var doc int
+		for doc, err = docs.NextDoc(); err == nil && doc != NO_MORE_DOCS; doc, err = docs.NextDoc() {
+			result.Set(doc)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return search.NewDocIdBitSet(result), nil
+}
+
+func (f *TermsFilter) String() string {
+	var buf bytes.Buffer
+	for i, term := range f.terms {
+		if i > 0 {
+			buf.WriteRune(' ')
+		}
+		fmt.Fprintf(&buf, "%v:%v", term.Field, string(term.Bytes))
+	}
+	return buf.String()
+}
+
+type termsByFieldAndBytes []*index.Term
+
+func (s termsByFieldAndBytes) Len() int      { return len(s) }
+func (s termsByFieldAndBytes) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s termsByFieldAndBytes) Less(i, j int) bool {
+	if s[i].Field != s[j].Field {
+		return s[i].Field < s[j].Field
+	}
+	return bytes.Compare(s[i].Bytes, s[j].Bytes) < 0
+}
+
+// queries/TermsQuery.java
+
+/*
+Specialization for a disjunction over many terms that behaves like a
+ConstantScoreQuery over a BooleanQuery containing only
+BooleanClause.Occur.SHOULD clauses. Every matching document gets a
+score equal to the boost of the query, and there is no limit on the
+number of terms.
+*/
+type TermsQuery struct {
+	*search.AbstractQuery
+	filter *TermsFilter
+}
+
+func NewTermsQuery(terms ...*index.Term) *TermsQuery {
+	return newTermsQuery(NewTermsFilter(terms...))
+}
+
+/* Creates a query on the given field for the given values. */
+func NewTermsQueryFromStrings(field string, values ...string) *TermsQuery {
+	return newTermsQuery(NewTermsFilterFromStrings(field, values...))
+}
+
+func newTermsQuery(filter *TermsFilter) *TermsQuery {
+	ans := &TermsQuery{filter: filter}
+	ans.AbstractQuery = search.NewAbstractQuery(ans)
+	return ans
+}
+
+func (q *TermsQuery) Rewrite(reader index.IndexReader) search.Query {
+	ans := search.NewConstantScoreQueryWithFilter(q.filter)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *TermsQuery) Clone() search.Query {
+	ans := newTermsQuery(q.filter)
+	ans.SetBoost(q.Boost())
+	return ans
+}
+
+func (q *TermsQuery) ToString(field string) string {
+	var buf bytes.Buffer
+	for i, term := range q.filter.terms {
+		if i > 0 {
+			buf.WriteRune(' ')
+		}
+		if term.Field != field {
+			buf.WriteString(term.Field)
+			buf.WriteRune(':')
+		}
+		buf.Write(term.Bytes)
+	}
+	if q.Boost() != 1 {
+		return fmt.Sprintf("(%v)^%v", buf.String(), q.Boost())
+	}
+	return buf.String()
+}
@@ -0,0 +1,41 @@
+package queries_test
+
+import (
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries"
+	"testing"
+)
+
+func TestTermsAndBoostingQuery(t *testing.T) {
+	searcher := newTestSearcher(t,
+		[2]string{"apple fruit", "1"},
+		[2]string{"apple computer", "2"},
+		[2]string{"apple phone apple", "3"},
+		[2]string{"banana fruit", "4"})
+
+	tq := queries.NewTermsQueryFromStrings("popularity", "4", "2", "9", "2")
+	assertOrder(t, searcher, tq, 1, 3)
+	if s := tq.ToString("popularity"); s != "2 4 9" {
+		t.Errorf("expected sorted, unique terms, but %v", s)
+	}
+	exp, err := searcher.Explain(tq, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exp.IsMatch() {
+		t.Errorf("expected a match, but %v", exp)
+	}
+
+	apple := search.NewTermQuery(index.NewTerm("body", "apple"))
+	assertOrder(t, searcher, apple, 2, 0, 1)
+	negative := queries.NewTermsQuery(index.NewTerm("body", "computer"), index.NewTerm("body", "phone"))
+	bq := queries.NewBoostingQuery(apple, negative, 0.01)
+	assertOrder(t, searcher, bq, 0, 2, 1)
+	if exp, err = searcher.Explain(bq, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !exp.IsMatch() || exp.Value() >= 0.1 {
+		t.Errorf("expected a demoted match, but %v", exp)
+	}
+}
@@ -94,7 +94,20 @@ func (qp *QueryBuilder) createFieldQuery(analyzer analysis.Analyzer,
 				// no phrase query:
 
 				if positionCount == 1 {
-					panic("not implemented yet")
+					// simple case: only one position, with synonyms
+					q := qp.newBooleanQuery(true)
+					for i := 0; i < numTokens; i++ {
+						hasNext, err := buffer.IncrementToken()
+						if err != nil {
+							continue // safe to ignore error, because we know the number of tokens
+						}
+						assert(hasNext)
+						termAtt.FillBytesRef()
+
+						currentQuery := qp.newTermQuery(index.NewTermFromBytes(field, util.DeepCopyOf(bytes).ToBytes()))
+						q.Add(currentQuery, search.SHOULD)
+					}
+					return q
 				} else {
 					// multiple positions
 					q := qp.newBooleanQuery(false)
@@ -108,7 +121,13 @@ func (qp *QueryBuilder) createFieldQuery(analyzer analysis.Analyzer,
 						termAtt.FillBytesRef()
 
 						if posIncrAtt != nil && posIncrAtt.PositionIncrement() == 0 {
-							panic("not implemented yet")
+							bq, ok := currentQuery.(*search.BooleanQuery)
+							if !ok {
+								bq = qp.newBooleanQuery(true)
+								bq.Add(currentQuery, search.SHOULD)
+								currentQuery = bq
+							}
+							bq.Add(qp.newTermQuery(index.NewTermFromBytes(field, util.DeepCopyOf(bytes).ToBytes())), search.SHOULD)
 						} else {
 							if currentQuery != nil {
 								q.Add(currentQuery, operator)
@@ -120,12 +139,69 @@ func (qp *QueryBuilder) createFieldQuery(analyzer analysis.Analyzer,
 					return q
 				}
 			} else {
-				panic("not implemented yet")
+				// phrase query:
+				mpq := qp.newMultiPhraseQuery()
+				mpq.SetSlop(phraseSlop)
+				var multiTerms []*index.Term
+				position := int32(-1)
+				for i := 0; i < numTokens; i++ {
+					positionIncrement := 1
+					hasNext, err := buffer.IncrementToken()
+					if err != nil {
+						continue // safe to ignore error, because we know the number of tokens
+					}
+					assert(hasNext)
+					termAtt.FillBytesRef()
+					if posIncrAtt != nil {
+						positionIncrement = posIncrAtt.PositionIncrement()
+					}
+
+					if positionIncrement > 0 && len(multiTerms) > 0 {
+						if qp.enablePositionIncrements {
+							mpq.AddTermsWithPosition(multiTerms, position)
+						} else {
+							mpq.AddTerms(multiTerms...)
+						}
+						multiTerms = nil
+					}
+					position += int32(positionIncrement)
+					multiTerms = append(multiTerms, index.NewTermFromBytes(field, util.DeepCopyOf(bytes).ToBytes()))
+				}
+				if qp.enablePositionIncrements {
+					mpq.AddTermsWithPosition(multiTerms, position)
+				} else {
+					mpq.AddTerms(multiTerms...)
+				}
+				return mpq
 			}
 		} else {
-			panic("not implemented yet")
+			pq := qp.newPhraseQuery()
+			pq.SetSlop(phraseSlop)
+			position := int32(-1)
+
+			for i := 0; i < numTokens; i++ {
+				positionIncrement := 1
+
+				hasNext, err := buffer.IncrementToken()
+				if err != nil {
+					continue // safe to ignore error, because we know the number of tokens
+				}
+				assert(hasNext)
+				termAtt.FillBytesRef()
+				if posIncrAtt != nil {
+					positionIncrement = posIncrAtt.PositionIncrement()
+				}
+
+				term := index.NewTermFromBytes(field, util.DeepCopyOf(bytes).ToBytes())
+				if qp.enablePositionIncrements {
+					position += int32(positionIncrement)
+					pq.AddTermWithPosition(term, position)
+				} else {
+					pq.Add(term)
+				}
+			}
+			return pq
 		}
-		panic("should not be here")
 	}
 }
 
@@ -137,3 +213,11 @@ func (qp *QueryBuilder) newBooleanQuery(disableCoord bool) *search.BooleanQuery
 func (qp *QueryBuilder) newTermQuery(term *index.Term) search.Query {
 	return search.NewTermQuery(term)
 }
+
+func (qp *QueryBuilder) newPhraseQuery() *search.PhraseQuery {
+	return search.NewPhraseQuery()
+}
+
+func (qp *QueryBuilder) newMultiPhraseQuery() *search.MultiPhraseQuery {
+	return search.NewMultiPhraseQuery()
+}
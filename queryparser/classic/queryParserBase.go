@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jtejido/golucene/core/analysis"
+	"github.com/jtejido/golucene/core/index"
 	"github.com/jtejido/golucene/core/search"
 	"strconv"
 	"strings"
@@ -36,6 +37,8 @@ type QueryParserBase struct {
 	field      string
 	phraseSlop int
 
+	schema *index.Schema
+
 	autoGeneratePhraseQueries bool
 }
 
@@ -60,6 +63,16 @@ func (qp *QueryParserBase) Parse(query string) (res search.Query, err error) {
 	return qp.newBooleanQuery(false), nil
 }
 
+/*
+Sets the Schema of the index, e.g. as returned by index.ReadSchema().
+Queries on undeclared or not indexed fields are then rejected, the
+text of untokenized fields is not analyzed, and fields declaring an
+analyzer are analyzed with it instead of the parser's.
+*/
+func (qp *QueryParserBase) SetSchema(schema *index.Schema) {
+	qp.schema = schema
+}
+
 // L408
 func (qp *QueryParserBase) addClause(clauses []*search.BooleanClause,
 	conj, mods int, q search.Query) []*search.BooleanClause {
@@ -114,8 +127,24 @@ func (qp *QueryParserBase) addClause(clauses []*search.BooleanClause,
 }
 
 // L461
-func (qp *QueryParserBase) fieldQuery(field, queryText string, quoted bool) search.Query {
-	return qp.newFieldQuery(qp.analyzer, field, queryText, quoted)
+func (qp *QueryParserBase) fieldQuery(field, queryText string, quoted bool) (search.Query, error) {
+	analyzer := qp.analyzer
+	if qp.schema != nil {
+		f := qp.schema.Field(field)
+		if f == nil {
+			return nil, errors.New(fmt.Sprintf("field %v is not declared in the schema", field))
+		}
+		if !f.Type.Indexed() {
+			return nil, errors.New(fmt.Sprintf("field %v is not indexed", field))
+		}
+		if !f.Type.Tokenized() {
+			return search.NewTermQuery(index.NewTerm(field, queryText)), nil
+		}
+		if f.Analyzer != nil {
+			analyzer = f.Analyzer
+		}
+	}
+	return qp.newFieldQuery(analyzer, field, queryText, quoted), nil
 }
 
 func (qp *QueryParserBase) newFieldQuery(analyzer analysis.Analyzer,
@@ -131,17 +160,20 @@ func (qp *QueryParserBase) newFieldQuery(analyzer analysis.Analyzer,
 		quoted || qp.autoGeneratePhraseQueries, qp.phraseSlop)
 }
 
-func (qp *QueryParserBase) baseFieldQuery(field, queryText string, slop int) search.Query {
-	query := qp.fieldQuery(field, queryText, true)
+func (qp *QueryParserBase) baseFieldQuery(field, queryText string, slop int) (search.Query, error) {
+	query, err := qp.fieldQuery(field, queryText, true)
+	if err != nil {
+		return nil, err
+	}
 
-	// if (query instanceof PhraseQuery) {
-	//   ((PhraseQuery) query).setSlop(slop);
-	// }
-	// if (query instanceof MultiPhraseQuery) {
-	//   ((MultiPhraseQuery) query).setSlop(slop);
-	// }
+	switch q := query.(type) {
+	case *search.PhraseQuery:
+		q.SetSlop(slop)
+	case *search.MultiPhraseQuery:
+		q.SetSlop(slop)
+	}
 
-	return query
+	return query, nil
 }
 
 func (qp *QueryParserBase) rangeQuery(field, part1, part2 string, startInclusive, endInclusive bool) search.Query {
@@ -194,7 +226,7 @@ func (qp *QueryParserBase) handleBareTokenQuery(qField string,
 	} else if fuzzy {
 		panic("not implemented yet")
 	} else {
-		return qp.fieldQuery(qField, termImage, false), nil
+		return qp.fieldQuery(qField, termImage, false)
 	}
 }
 
@@ -210,10 +242,10 @@ func (qp *QueryParserBase) handleQuotedTerm(qfield string, term, fuzzySlop *Toke
 	}
 
 	var termImage string
-	if termImage, err = qp.discardEscapeChar(term.image[1:]); err != nil {
+	if termImage, err = qp.discardEscapeChar(term.image[1 : len(term.image)-1]); err != nil {
 		return nil, err
 	}
-	return qp.baseFieldQuery(qfield, termImage, s), nil
+	return qp.baseFieldQuery(qfield, termImage, s)
 }
 
 // L876
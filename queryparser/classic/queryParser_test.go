@@ -0,0 +1,133 @@
+package classic
+
+import (
+	std "github.com/jtejido/golucene/analysis/core"
+	. "github.com/jtejido/golucene/core/analysis"
+	. "github.com/jtejido/golucene/core/analysis/tokenattributes"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/core/util"
+	"io"
+	"testing"
+)
+
+// Emits a synonym at the same position right after each mapped term.
+type synonymFilter struct {
+	*TokenFilter
+	input      TokenStream
+	synonyms   map[string]string
+	termAtt    CharTermAttribute
+	posIncAtt  PositionIncrementAttribute
+	pending    string
+	savedState *util.AttributeState
+}
+
+func newSynonymFilter(in TokenStream, synonyms map[string]string) *synonymFilter {
+	ans := &synonymFilter{
+		TokenFilter: NewTokenFilter(in),
+		input:       in,
+		synonyms:    synonyms,
+	}
+	ans.termAtt = ans.Attributes().Add("CharTermAttribute").(CharTermAttribute)
+	ans.posIncAtt = ans.Attributes().Add("PositionIncrementAttribute").(PositionIncrementAttribute)
+	return ans
+}
+
+func (f *synonymFilter) IncrementToken() (bool, error) {
+	if f.savedState != nil {
+		f.Attributes().RestoreState(f.savedState)
+		f.savedState = nil
+		f.termAtt.CopyBuffer([]rune(f.pending))
+		f.posIncAtt.SetPositionIncrement(0)
+		return true, nil
+	}
+	ok, err := f.input.IncrementToken()
+	if err != nil || !ok {
+		return ok, err
+	}
+	term := string(f.termAtt.Buffer()[:f.termAtt.Length()])
+	if syn, found := f.synonyms[term]; found {
+		f.pending = syn
+		f.savedState = f.Attributes().CaptureState()
+	}
+	return true, nil
+}
+
+func (f *synonymFilter) Reset() error {
+	f.savedState = nil
+	return f.TokenFilter.Reset()
+}
+
+type synonymAnalyzer struct {
+	*AnalyzerImpl
+}
+
+func newSynonymAnalyzer() *synonymAnalyzer {
+	ans := new(synonymAnalyzer)
+	ans.AnalyzerImpl = NewAnalyzer()
+	ans.Spi = ans
+	return ans
+}
+
+func (a *synonymAnalyzer) CreateComponents(fieldName string, reader io.RuneReader) *TokenStreamComponents {
+	source := std.NewWhitespaceTokenizer(reader)
+	return NewTokenStreamComponents(source, newSynonymFilter(source,
+		map[string]string{"dog": "dogs", "quick": "fast"}))
+}
+
+func parse(t *testing.T, query string) search.Query {
+	q, err := NewQueryParser(util.VERSION_4_10, "f", newSynonymAnalyzer()).Parse(query)
+	if err != nil {
+		t.Fatalf("%v: %v", query, err)
+	}
+	return q
+}
+
+func TestParseSynonyms(t *testing.T) {
+	// single position: synonyms become SHOULD clauses of one query
+	q := parse(t, "dog")
+	bq, ok := q.(*search.BooleanQuery)
+	if !ok {
+		t.Fatalf("dog: expected *BooleanQuery, but %T", q)
+	}
+	if n := len(bq.Clauses()); n != 2 {
+		t.Errorf("dog: expected 2 clauses, but %v", n)
+	}
+	if s := q.ToString("f"); s != "dog dogs" {
+		t.Errorf("dog: expected 'dog dogs', but '%v'", s)
+	}
+
+	// several positions: synonyms nest under the clause of their position
+	q = parse(t, "quick brown dog")
+	if s := q.ToString("f"); s != "(quick fast) brown (dog dogs)" {
+		t.Errorf("expected '(quick fast) brown (dog dogs)', but '%v'", s)
+	}
+}
+
+func TestParsePhraseSlop(t *testing.T) {
+	q := parse(t, `"quick brown dog"~2`)
+	mpq, ok := q.(*search.MultiPhraseQuery)
+	if !ok {
+		t.Fatalf("expected *MultiPhraseQuery, but %T", q)
+	}
+	if mpq.Slop() != 2 {
+		t.Errorf("expected slop 2, but %v", mpq.Slop())
+	}
+	if n := len(mpq.TermArrays()); n != 3 {
+		t.Errorf("expected 3 positions, but %v", n)
+	}
+	if s := q.ToString("f"); s != `"(quick fast) brown (dog dogs)"~2` {
+		t.Errorf(`expected '"(quick fast) brown (dog dogs)"~2', but '%v'`, s)
+	}
+
+	q = parse(t, `"brown cat"~3`)
+	pq, ok := q.(*search.PhraseQuery)
+	if !ok {
+		t.Fatalf("expected *PhraseQuery, but %T", q)
+	}
+	if pq.Slop() != 3 {
+		t.Errorf("expected slop 3, but %v", pq.Slop())
+	}
+	if s := q.ToString("f"); s != `"brown cat"~3` {
+		t.Errorf(`expected '"brown cat"~3', but '%v'`, s)
+	}
+}
@@ -1051,10 +1051,10 @@ func (tm *TokenManager) jjAddStates(start, end int) {
 	for {
 		tm.jjstateSet[tm.jjnewStateCnt] = jjnextStates[start]
 		tm.jjnewStateCnt++
-		start++
-		if start >= end {
+		if start == end {
 			break
 		}
+		start++
 	}
 }
 
@@ -1068,13 +1068,13 @@ func (tm *TokenManager) jjCheckNAddTwoStates(state1, state2 int) {
 func (tm *TokenManager) jjCheckNAddStates(start, end int) {
 	assert(start < end)
 	assert(start >= 0)
-	assert(end <= len(jjnextStates))
+	assert(end < len(jjnextStates))
 	for {
 		tm.jjCheckNAdd(jjnextStates[start])
-		start++
-		if start >= end {
+		if start == end {
 			break
 		}
+		start++
 	}
 }
 
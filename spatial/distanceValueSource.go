@@ -0,0 +1,88 @@
+package spatial
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"github.com/jtejido/golucene/queries/function/docvalues"
+)
+
+// spatial/util/DistanceToShapeValueSource.java
+
+/*
+DistanceValueSource gives the distance in kilometers from a center to
+the nearest point stored in a LatLonField. Documents without a point
+get the farthest distance possible, half the earth's circumference,
+and report false from Exists().
+
+The points of a segment are loaded lazily from stored fields, and
+cached for the lifetime of the returned FunctionValues.
+*/
+type DistanceValueSource struct {
+	field  string
+	center Point
+}
+
+func (vs *DistanceValueSource) Center() Point {
+	return vs.center
+}
+
+func (vs *DistanceValueSource) Description() string {
+	return fmt.Sprintf("dist(%v,%v,%v)", vs.field, vs.center.Lat, vs.center.Lon)
+}
+
+func (vs *DistanceValueSource) CreateWeight(context function.Context, searcher *search.IndexSearcher) error {
+	return nil
+}
+
+func (vs *DistanceValueSource) Values(context function.Context, readerContext *index.AtomicReaderContext) (function.FunctionValues, error) {
+	ans := &distanceValues{
+		vs:        vs,
+		reader:    readerContext.Reader(),
+		distances: make(map[int]float64),
+	}
+	ans.DoubleDocValues = docvalues.NewDoubleDocValues(vs, ans)
+	return ans, nil
+}
+
+func (vs *DistanceValueSource) String() string {
+	return vs.Description()
+}
+
+type distanceValues struct {
+	*docvalues.DoubleDocValues
+	vs        *DistanceValueSource
+	reader    index.IndexReader
+	distances map[int]float64
+}
+
+/* Returns the distance to the nearest point of doc, or -1 if it has none. */
+func (v *distanceValues) load(doc int) float64 {
+	if dist, ok := v.distances[doc]; ok {
+		return dist
+	}
+	points, err := loadPoints(v.reader, v.vs.field, doc)
+	if err != nil {
+		panic(err)
+	}
+	dist := -1.0
+	for i, p := range points {
+		if d := Distance(v.vs.center, p); i == 0 || d < dist {
+			dist = d
+		}
+	}
+	v.distances[doc] = dist
+	return dist
+}
+
+func (v *distanceValues) DoubleVal(doc int) float64 {
+	if dist := v.load(doc); dist >= 0 {
+		return dist
+	}
+	return 180 * degreeToKm
+}
+
+func (v *distanceValues) Exists(doc int) bool {
+	return v.load(doc) >= 0
+}
@@ -0,0 +1,216 @@
+package spatial
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/analysis"
+	. "github.com/jtejido/golucene/core/analysis/tokenattributes"
+	"github.com/jtejido/golucene/core/codec/spi"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// spatial/prefix/RecursivePrefixTreeStrategy.java
+
+/*
+Default fraction of the diagonal of a query shape's bounding box used
+to pick the grid level at which the shape is approximated.
+*/
+const DEFAULT_DIST_ERR_PCT = 0.025
+
+/* Field type of the cell tokens: indexed as DOCS_ONLY, without norms. */
+var cellFieldType = func() *docu.FieldType {
+	ft := docu.NewFieldTypeFrom(docu.STRING_FIELD_TYPE_NOT_STORED)
+	ft.SetTokenized(true)
+	ft.Freeze()
+	return ft
+}()
+
+/*
+LatLonField indexes geographic points and searches them by shape.
+
+Each point is indexed as the tokens of the grid cells holding it, at
+all levels of a SpatialPrefixTree, and its coordinates are stored as
+"lat,lon" under the same field name. A document may hold several
+points.
+
+A shape query approximates the shape with grid cells, coarse inside
+the shape and finer along its edges. Documents in cells within the
+shape match right away, while the points of those in cells crossing
+its edges are checked exactly against the shape, so the precision of
+the tree and the detail of the approximation only affect speed.
+*/
+type LatLonField struct {
+	name       string
+	tree       SpatialPrefixTree
+	distErrPct float64
+}
+
+func NewLatLonField(name string, tree SpatialPrefixTree) *LatLonField {
+	assert2(name != "", "name cannot be empty")
+	assert2(tree != nil, "tree cannot be nil")
+	return &LatLonField{name, tree, DEFAULT_DIST_ERR_PCT}
+}
+
+func (f *LatLonField) Name() string {
+	return f.name
+}
+
+func (f *LatLonField) Tree() SpatialPrefixTree {
+	return f.tree
+}
+
+/*
+Sets the fraction, in [0, 0.5], of the diagonal of a query shape's
+bounding box below which its approximating cells aren't refined. 0
+refines cells up to the finest level of the tree.
+*/
+func (f *LatLonField) SetDistErrPct(distErrPct float64) {
+	assert2(distErrPct >= 0 && distErrPct <= 0.5, "distErrPct must be in [0, 0.5], got %v", distErrPct)
+	f.distErrPct = distErrPct
+}
+
+/* Returns the fields to add to a document to index p. */
+func (f *LatLonField) CreateIndexableFields(p Point) []model.IndexableField {
+	checkLat(p.Lat)
+	checkLon(p.Lon)
+	return []model.IndexableField{
+		docu.NewFieldFromTokenStream(f.name, newCellTokenStream(pointTokens(f.tree, p)), cellFieldType),
+		docu.NewStoredFieldFromString(f.name, formatPoint(p)),
+	}
+}
+
+/* Returns a query matching the documents with a point within shape. */
+func (f *LatLonField) NewShapeQuery(shape Shape) search.Query {
+	return search.NewConstantScoreQueryWithFilter(f.NewShapeFilter(shape))
+}
+
+/* Returns a filter accepting the documents with a point within shape. */
+func (f *LatLonField) NewShapeFilter(shape Shape) search.Filter {
+	return newShapeFilter(f.name, shape, f.tree, f.detailLevel(shape))
+}
+
+/* Matches the points within a rectangle, which may cross the dateline. */
+func (f *LatLonField) NewBoundingBoxQuery(minLat, maxLat, minLon, maxLon float64) search.Query {
+	return f.NewShapeQuery(NewRectangle(minLat, maxLat, minLon, maxLon))
+}
+
+/* Matches the points within radiusKm kilometers of center. */
+func (f *LatLonField) NewDistanceQuery(center Point, radiusKm float64) search.Query {
+	return f.NewShapeQuery(NewCircle(center, radiusKm))
+}
+
+/* Matches the points within the polygon of the given vertices. */
+func (f *LatLonField) NewPolygonQuery(vertices ...Point) search.Query {
+	return f.NewShapeQuery(NewPolygon(vertices...))
+}
+
+/*
+Returns a ValueSource giving the distance in kilometers from center to
+the nearest point of each document, e.g. to sort hits by distance, or
+to score them with a FunctionQuery.
+*/
+func (f *LatLonField) NewDistanceValueSource(center Point) function.ValueSource {
+	checkLat(center.Lat)
+	checkLon(center.Lon)
+	return &DistanceValueSource{f.name, center}
+}
+
+func (f *LatLonField) detailLevel(shape Shape) int {
+	bbox := shape.BoundingBox()
+	diagonal := math.Hypot(bbox.Width(), bbox.Height())
+	return levelForDistance(f.tree, diagonal*f.distErrPct)
+}
+
+func (f *LatLonField) String() string {
+	return fmt.Sprintf("LatLonField(%v,%v)", f.name, f.tree)
+}
+
+func formatPoint(p Point) string {
+	return strconv.FormatFloat(p.Lat, 'g', -1, 64) + "," + strconv.FormatFloat(p.Lon, 'g', -1, 64)
+}
+
+func parsePoint(s string) (p Point, ok bool) {
+	i := strings.IndexByte(s, ',')
+	if i < 0 {
+		return
+	}
+	var err1, err2 error
+	p.Lat, err1 = strconv.ParseFloat(s[:i], 64)
+	p.Lon, err2 = strconv.ParseFloat(s[i+1:], 64)
+	return p, err1 == nil && err2 == nil
+}
+
+/* Returns the points stored in the field of a document. */
+func loadPoints(reader index.IndexReader, field string, doc int) ([]Point, error) {
+	visitor := &pointsVisitor{field: field}
+	if err := reader.VisitDocument(doc, visitor); err != nil {
+		return nil, err
+	}
+	return visitor.points, nil
+}
+
+/* Collects the points stored in a single field. */
+type pointsVisitor struct {
+	field  string
+	points []Point
+}
+
+func (v *pointsVisitor) NeedsField(fi *model.FieldInfo) (spi.StoredFieldVisitorStatus, error) {
+	if fi.Name == v.field {
+		return spi.STORED_FIELD_VISITOR_STATUS_YES, nil
+	}
+	return spi.STORED_FIELD_VISITOR_STATUS_NO, nil
+}
+
+func (v *pointsVisitor) StringField(fi *model.FieldInfo, value string) error {
+	if p, ok := parsePoint(value); ok {
+		v.points = append(v.points, p)
+	}
+	return nil
+}
+
+func (v *pointsVisitor) BinaryField(fi *model.FieldInfo, value []byte) error {
+	return v.StringField(fi, string(value))
+}
+
+func (v *pointsVisitor) IntField(fi *model.FieldInfo, value int) error        { return nil }
+func (v *pointsVisitor) LongField(fi *model.FieldInfo, value int64) error     { return nil }
+func (v *pointsVisitor) FloatField(fi *model.FieldInfo, value float32) error  { return nil }
+func (v *pointsVisitor) DoubleField(fi *model.FieldInfo, value float64) error { return nil }
+
+// spatial/prefix/PrefixTreeStrategy.java (CellTokenStream)
+
+/* Emits the tokens of the cells holding a point. */
+type cellTokenStream struct {
+	*analysis.TokenStreamImpl
+	termAttribute CharTermAttribute
+	tokens        []string
+	next          int
+}
+
+func newCellTokenStream(tokens []string) *cellTokenStream {
+	ans := &cellTokenStream{TokenStreamImpl: analysis.NewTokenStream(), tokens: tokens}
+	ans.termAttribute = ans.Attributes().Add("CharTermAttribute").(CharTermAttribute)
+	return ans
+}
+
+func (ts *cellTokenStream) IncrementToken() (bool, error) {
+	if ts.next >= len(ts.tokens) {
+		return false, nil
+	}
+	ts.Attributes().Clear()
+	ts.termAttribute.AppendString(ts.tokens[ts.next])
+	ts.next++
+	return true, nil
+}
+
+func (ts *cellTokenStream) Reset() error {
+	ts.next = 0
+	return nil
+}
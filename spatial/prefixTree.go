@@ -0,0 +1,268 @@
+package spatial
+
+import (
+	"fmt"
+	"math"
+)
+
+// spatial/prefix/tree/SpatialPrefixTree.java
+
+/*
+A spatial prefix tree divides the world into a hierarchy of grid
+cells. Each cell is named by a token, which is prefixed by the token
+of its parent cell, so that the cells holding a point at all levels
+are the prefixes of the token of its finest cell.
+*/
+type SpatialPrefixTree interface {
+	// Number of levels below the world, the finest being MaxLevels().
+	MaxLevels() int
+	// Returns the token of the cell at the given level holding p.
+	PointToken(p Point, level int) string
+	// Returns the sub-cells of the cell named by token, or the cells
+	// of the first level if token is empty.
+	SubCells(token string) []Cell
+	// Returns the bounds of the cell named by token.
+	CellBounds(token string) Rectangle
+}
+
+/* A grid cell of a SpatialPrefixTree. */
+type Cell struct {
+	Token  string
+	Bounds Rectangle
+}
+
+func (c Cell) Level() int {
+	return len(c.Token)
+}
+
+func (c Cell) String() string {
+	return fmt.Sprintf("%v %v", c.Token, c.Bounds)
+}
+
+/*
+Returns the coarsest level of tree whose cells have their longest
+side no longer than dist degrees, or MaxLevels() if there's none.
+*/
+func levelForDistance(tree SpatialPrefixTree, dist float64) int {
+	token := ""
+	for level := 1; level <= tree.MaxLevels(); level++ {
+		cell := tree.SubCells(token)[0]
+		if math.Max(cell.Bounds.Width(), cell.Bounds.Height()) <= dist {
+			return level
+		}
+		token = cell.Token
+	}
+	return tree.MaxLevels()
+}
+
+/* All tokens of the cells holding p, from level 1 to MaxLevels(). */
+func pointTokens(tree SpatialPrefixTree, p Point) []string {
+	leaf := tree.PointToken(p, tree.MaxLevels())
+	ans := make([]string, len(leaf))
+	for i := range ans {
+		ans[i] = leaf[:i+1]
+	}
+	return ans
+}
+
+// spatial/prefix/tree/GeohashPrefixTree.java
+
+const GEOHASH_MAX_LEVELS = 24
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+var geohashDecodeMap = func() (ans [128]int) {
+	for i := range ans {
+		ans[i] = -1
+	}
+	for i, c := range geohashBase32 {
+		ans[c] = i
+	}
+	return
+}()
+
+/*
+A SpatialPrefixTree using geohashes: each level divides a cell into
+32 sub-cells, named by one more base-32 character.
+*/
+type GeohashPrefixTree struct {
+	maxLevels int
+}
+
+func NewGeohashPrefixTree(maxLevels int) *GeohashPrefixTree {
+	assert2(maxLevels > 0 && maxLevels <= GEOHASH_MAX_LEVELS,
+		"maxLevels must be in [1, %v], got %v", GEOHASH_MAX_LEVELS, maxLevels)
+	return &GeohashPrefixTree{maxLevels}
+}
+
+func (t *GeohashPrefixTree) MaxLevels() int {
+	return t.maxLevels
+}
+
+func (t *GeohashPrefixTree) PointToken(p Point, level int) string {
+	return EncodeGeohash(p, level)
+}
+
+func (t *GeohashPrefixTree) SubCells(token string) []Cell {
+	ans := make([]Cell, len(geohashBase32))
+	for i := range geohashBase32 {
+		sub := token + geohashBase32[i:i+1]
+		ans[i] = Cell{sub, t.CellBounds(sub)}
+	}
+	return ans
+}
+
+func (t *GeohashPrefixTree) CellBounds(token string) Rectangle {
+	return DecodeGeohashBounds(token)
+}
+
+func (t *GeohashPrefixTree) String() string {
+	return fmt.Sprintf("GeohashPrefixTree(maxLevels:%v)", t.maxLevels)
+}
+
+// spatial4j: io/GeohashUtils.java
+
+/* Encodes p as a geohash of the given number of characters. */
+func EncodeGeohash(p Point, precision int) string {
+	minLat, maxLat, minLon, maxLon := -90.0, 90.0, -180.0, 180.0
+	ans := make([]byte, precision)
+	even := true
+	for i := range ans {
+		ch := 0
+		for bit := 4; bit >= 0; bit-- {
+			if even {
+				mid := (minLon + maxLon) / 2
+				if p.Lon >= mid {
+					ch |= 1 << uint(bit)
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if p.Lat >= mid {
+					ch |= 1 << uint(bit)
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+		ans[i] = geohashBase32[ch]
+	}
+	return string(ans)
+}
+
+/* Returns the bounds of the cell named by geohash. */
+func DecodeGeohashBounds(geohash string) Rectangle {
+	ans := Rectangle{-90, 90, -180, 180}
+	even := true
+	for i := 0; i < len(geohash); i++ {
+		ch := -1
+		if c := geohash[i]; c < 128 {
+			ch = geohashDecodeMap[c]
+		}
+		assert2(ch >= 0, "invalid geohash %v", geohash)
+		for bit := 4; bit >= 0; bit-- {
+			set := ch&(1<<uint(bit)) != 0
+			if even {
+				mid := (ans.MinLon + ans.MaxLon) / 2
+				if set {
+					ans.MinLon = mid
+				} else {
+					ans.MaxLon = mid
+				}
+			} else {
+				mid := (ans.MinLat + ans.MaxLat) / 2
+				if set {
+					ans.MinLat = mid
+				} else {
+					ans.MaxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return ans
+}
+
+// spatial/prefix/tree/QuadPrefixTree.java
+
+const QUAD_MAX_LEVELS = 50
+
+/*
+A SpatialPrefixTree dividing each cell in 4 quadrants, named 'A' (north
+west), 'B' (north east), 'C' (south west) and 'D' (south east).
+*/
+type QuadPrefixTree struct {
+	maxLevels int
+}
+
+func NewQuadPrefixTree(maxLevels int) *QuadPrefixTree {
+	assert2(maxLevels > 0 && maxLevels <= QUAD_MAX_LEVELS,
+		"maxLevels must be in [1, %v], got %v", QUAD_MAX_LEVELS, maxLevels)
+	return &QuadPrefixTree{maxLevels}
+}
+
+func (t *QuadPrefixTree) MaxLevels() int {
+	return t.maxLevels
+}
+
+func (t *QuadPrefixTree) PointToken(p Point, level int) string {
+	bounds := Rectangle{-90, 90, -180, 180}
+	ans := make([]byte, level)
+	for i := range ans {
+		midLat := (bounds.MinLat + bounds.MaxLat) / 2
+		midLon := (bounds.MinLon + bounds.MaxLon) / 2
+		quad := byte('A')
+		if p.Lat < midLat {
+			quad += 2
+			bounds.MaxLat = midLat
+		} else {
+			bounds.MinLat = midLat
+		}
+		if p.Lon >= midLon {
+			quad++
+			bounds.MinLon = midLon
+		} else {
+			bounds.MaxLon = midLon
+		}
+		ans[i] = quad
+	}
+	return string(ans)
+}
+
+func (t *QuadPrefixTree) SubCells(token string) []Cell {
+	ans := make([]Cell, 4)
+	for i := range ans {
+		sub := token + string(rune('A'+i))
+		ans[i] = Cell{sub, t.CellBounds(sub)}
+	}
+	return ans
+}
+
+func (t *QuadPrefixTree) CellBounds(token string) Rectangle {
+	ans := Rectangle{-90, 90, -180, 180}
+	for i := 0; i < len(token); i++ {
+		quad := int(token[i]) - 'A'
+		assert2(quad >= 0 && quad < 4, "invalid quad token %v", token)
+		midLat := (ans.MinLat + ans.MaxLat) / 2
+		midLon := (ans.MinLon + ans.MaxLon) / 2
+		if quad&2 != 0 {
+			ans.MaxLat = midLat
+		} else {
+			ans.MinLat = midLat
+		}
+		if quad&1 != 0 {
+			ans.MinLon = midLon
+		} else {
+			ans.MaxLon = midLon
+		}
+	}
+	return ans
+}
+
+func (t *QuadPrefixTree) String() string {
+	return fmt.Sprintf("QuadPrefixTree(maxLevels:%v)", t.maxLevels)
+}
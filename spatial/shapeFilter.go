@@ -0,0 +1,129 @@
+package spatial
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	. "github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	. "github.com/jtejido/golucene/core/search/model"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// spatial/prefix/IntersectsPrefixTreeFilter.java
+
+/*
+Accepts the documents with a point within a shape. The shape is
+approximated once by grid cells: the documents of the cells within
+the shape are accepted, and those of the cells crossing its edges,
+down to the detail level, are checked against their stored points.
+*/
+type shapeFilter struct {
+	field        string
+	shape        Shape
+	within       []string // tokens of the cells within the shape
+	intersecting []string // tokens of the cells crossing the shape's edges
+}
+
+func newShapeFilter(field string, shape Shape, tree SpatialPrefixTree, detailLevel int) *shapeFilter {
+	ans := &shapeFilter{field: field, shape: shape}
+	ans.cover(tree, "", detailLevel)
+	return ans
+}
+
+func (f *shapeFilter) cover(tree SpatialPrefixTree, token string, detailLevel int) {
+	for _, cell := range tree.SubCells(token) {
+		switch f.shape.Relate(cell.Bounds) {
+		case WITHIN:
+			f.within = append(f.within, cell.Token)
+		case INTERSECTS:
+			if cell.Level() >= detailLevel {
+				f.intersecting = append(f.intersecting, cell.Token)
+			} else {
+				f.cover(tree, cell.Token, detailLevel)
+			}
+		}
+	}
+}
+
+func (f *shapeFilter) DocIdSet(ctx *index.AtomicReaderContext, acceptDocs util.Bits) (search.DocIdSet, error) {
+	reader := ctx.Reader().(index.AtomicReader)
+	fields := reader.Fields()
+	if fields == nil {
+		return nil, nil
+	}
+	terms := fields.Terms(f.field)
+	if terms == nil {
+		return nil, nil
+	}
+	termsEnum := terms.Iterator(nil)
+	matches := util.NewFixedBitSetOf(reader.MaxDoc())
+	candidates := util.NewFixedBitSetOf(reader.MaxDoc())
+	var docsEnum DocsEnum
+
+	visit := func(tokens []string, bits *util.FixedBitSet) error {
+		for _, token := range tokens {
+			ok, err := termsEnum.SeekExact([]byte(token))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if docsEnum, err = termsEnum.DocsByFlags(acceptDocs, docsEnum, DOCS_ENUM_FLAG_NONE); err != nil {
+				return err
+			}
+			for {
+				doc, err := docsEnum.NextDoc()
+				if err != nil {
+					return err
+				}
+				if doc == NO_MORE_DOCS {
+					break
+				}
+				if !matches.At(doc) {
+					bits.Set(doc)
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(f.within, matches); err != nil {
+		return nil, err
+	}
+	if err := visit(f.intersecting, candidates); err != nil {
+		return nil, err
+	}
+
+	// check the points of the candidates against the shape
+	for doc := nextSetBit(candidates, 0); doc >= 0; doc = nextSetBit(candidates, doc+1) {
+		if matches.At(doc) {
+			continue
+		}
+		points, err := loadPoints(reader, f.field, doc)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range points {
+			if f.shape.Contains(p) {
+				matches.Set(doc)
+				break
+			}
+		}
+	}
+
+	if matches.Cardinality() == 0 {
+		return nil, nil
+	}
+	return search.NewDocIdBitSet(matches), nil
+}
+
+func nextSetBit(bits *util.FixedBitSet, index int) int {
+	if index >= bits.Length() {
+		return -1
+	}
+	return bits.NextSetBit(index)
+}
+
+func (f *shapeFilter) String() string {
+	return fmt.Sprintf("ShapeFilter(%v:%v)", f.field, f.shape)
+}
@@ -0,0 +1,344 @@
+package spatial
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Mean radius of the earth, in kilometers.
+const EARTH_MEAN_RADIUS_KM = 6371.0087714
+
+/* A point on the earth, in degrees. */
+type Point struct {
+	Lat, Lon float64
+}
+
+func NewPoint(lat, lon float64) Point {
+	checkLat(lat)
+	checkLon(lon)
+	return Point{lat, lon}
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("Pt(lat=%v,lon=%v)", p.Lat, p.Lon)
+}
+
+func checkLat(lat float64) {
+	assert2(lat >= -90 && lat <= 90, "invalid latitude %v", lat)
+}
+
+func checkLon(lon float64) {
+	assert2(lon >= -180 && lon <= 180, "invalid longitude %v", lon)
+}
+
+/* Returns the great-circle distance between a and b, in kilometers. */
+func Distance(a, b Point) float64 {
+	return toDegrees(angularDistance(a, b)) * degreeToKm
+}
+
+var degreeToKm = EARTH_MEAN_RADIUS_KM * math.Pi / 180
+
+/* Haversine formula; returns the distance in radians. */
+func angularDistance(a, b Point) float64 {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	hLat := math.Sin((lat2 - lat1) / 2)
+	hLon := math.Sin(toRadians(b.Lon-a.Lon) / 2)
+	h := hLat*hLat + math.Cos(lat1)*math.Cos(lat2)*hLon*hLon
+	return 2 * math.Atan2(math.Sqrt(h), math.Sqrt(math.Max(0, 1-h)))
+}
+
+func toRadians(degrees float64) float64 { return degrees * math.Pi / 180 }
+func toDegrees(radians float64) float64 { return radians * 180 / math.Pi }
+
+/* How a grid cell relates to a Shape. */
+type Relation int
+
+const (
+	// The cell and the shape have no point in common.
+	DISJOINT = Relation(0)
+	// The cell and the shape may have points in common.
+	INTERSECTS = Relation(1)
+	// All points of the cell are within the shape.
+	WITHIN = Relation(2)
+)
+
+/* A shape to search points in. */
+type Shape interface {
+	// Returns a rectangle holding the whole shape.
+	BoundingBox() Rectangle
+	// Returns true if p is within the shape, boundary included.
+	Contains(p Point) bool
+	// Returns how the cell relates to the shape. DISJOINT and WITHIN
+	// must be exact, while INTERSECTS may be returned for a cell that
+	// is in fact disjoint.
+	Relate(cell Rectangle) Relation
+}
+
+// spatial4j: shape/Rectangle.java
+
+/*
+A rectangle of latitudes and longitudes. If MinLon > MaxLon, the
+rectangle crosses the dateline.
+*/
+type Rectangle struct {
+	MinLat, MaxLat, MinLon, MaxLon float64
+}
+
+func NewRectangle(minLat, maxLat, minLon, maxLon float64) Rectangle {
+	checkLat(minLat)
+	checkLat(maxLat)
+	checkLon(minLon)
+	checkLon(maxLon)
+	assert2(minLat <= maxLat, "minLat %v > maxLat %v", minLat, maxLat)
+	return Rectangle{minLat, maxLat, minLon, maxLon}
+}
+
+func (r Rectangle) CrossesDateline() bool {
+	return r.MinLon > r.MaxLon
+}
+
+/* Width in degrees of longitude. */
+func (r Rectangle) Width() float64 {
+	if r.CrossesDateline() {
+		return 360 - (r.MinLon - r.MaxLon)
+	}
+	return r.MaxLon - r.MinLon
+}
+
+/* Height in degrees of latitude. */
+func (r Rectangle) Height() float64 {
+	return r.MaxLat - r.MinLat
+}
+
+func (r Rectangle) Center() Point {
+	lon := r.MinLon + r.Width()/2
+	if lon > 180 {
+		lon -= 360
+	}
+	return Point{(r.MinLat + r.MaxLat) / 2, lon}
+}
+
+func (r Rectangle) BoundingBox() Rectangle {
+	return r
+}
+
+func (r Rectangle) Contains(p Point) bool {
+	return p.Lat >= r.MinLat && p.Lat <= r.MaxLat && r.containsLon(p.Lon)
+}
+
+func (r Rectangle) containsLon(lon float64) bool {
+	if r.CrossesDateline() {
+		return lon >= r.MinLon || lon <= r.MaxLon
+	}
+	return lon >= r.MinLon && lon <= r.MaxLon
+}
+
+/* cell must not cross the dateline. */
+func (r Rectangle) Relate(cell Rectangle) Relation {
+	if cell.MaxLat < r.MinLat || cell.MinLat > r.MaxLat {
+		return DISJOINT
+	}
+	var lonDisjoint, lonWithin bool
+	if r.CrossesDateline() {
+		lonDisjoint = cell.MaxLon < r.MinLon && cell.MinLon > r.MaxLon
+		lonWithin = cell.MinLon >= r.MinLon || cell.MaxLon <= r.MaxLon
+	} else {
+		lonDisjoint = cell.MaxLon < r.MinLon || cell.MinLon > r.MaxLon
+		lonWithin = cell.MinLon >= r.MinLon && cell.MaxLon <= r.MaxLon
+	}
+	switch {
+	case lonDisjoint:
+		return DISJOINT
+	case lonWithin && cell.MinLat >= r.MinLat && cell.MaxLat <= r.MaxLat:
+		return WITHIN
+	}
+	return INTERSECTS
+}
+
+func (r Rectangle) String() string {
+	return fmt.Sprintf("Rect(lat=%v TO %v,lon=%v TO %v)", r.MinLat, r.MaxLat, r.MinLon, r.MaxLon)
+}
+
+// spatial4j: shape/Circle.java
+
+/* The points within a distance of a center, on the sphere. */
+type Circle struct {
+	Center   Point
+	RadiusKm float64
+	bbox     Rectangle
+}
+
+func NewCircle(center Point, radiusKm float64) *Circle {
+	checkLat(center.Lat)
+	checkLon(center.Lon)
+	assert2(radiusKm >= 0, "invalid radius %v", radiusKm)
+	return &Circle{Center: center, RadiusKm: radiusKm, bbox: circleBoundingBox(center, radiusKm)}
+}
+
+func circleBoundingBox(center Point, radiusKm float64) Rectangle {
+	radius := radiusKm / degreeToKm
+	minLat, maxLat := center.Lat-radius, center.Lat+radius
+	if minLat <= -90 || maxLat >= 90 {
+		// a pole is within the circle
+		return Rectangle{math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180}
+	}
+	sinLon := math.Sin(toRadians(radius)) / math.Cos(toRadians(center.Lat))
+	if sinLon >= 1 {
+		return Rectangle{minLat, maxLat, -180, 180}
+	}
+	dLon := toDegrees(math.Asin(sinLon))
+	return Rectangle{minLat, maxLat, normalizeLon(center.Lon - dLon), normalizeLon(center.Lon + dLon)}
+}
+
+func normalizeLon(lon float64) float64 {
+	switch {
+	case lon < -180:
+		return lon + 360
+	case lon > 180:
+		return lon - 360
+	}
+	return lon
+}
+
+func (c *Circle) BoundingBox() Rectangle {
+	return c.bbox
+}
+
+func (c *Circle) Contains(p Point) bool {
+	return Distance(c.Center, p) <= c.RadiusKm
+}
+
+func (c *Circle) Relate(cell Rectangle) Relation {
+	if c.bbox.Relate(cell) == DISJOINT {
+		return DISJOINT
+	}
+	// With a radius under 90 degrees, and the cell away from the
+	// antipodal meridian, the farthest point of the cell from the
+	// center is one of its corners.
+	antipodal := normalizeLon(c.Center.Lon + 180)
+	if c.RadiusKm < 90*degreeToKm && (antipodal < cell.MinLon || antipodal > cell.MaxLon) &&
+		c.Contains(Point{cell.MinLat, cell.MinLon}) && c.Contains(Point{cell.MinLat, cell.MaxLon}) &&
+		c.Contains(Point{cell.MaxLat, cell.MinLon}) && c.Contains(Point{cell.MaxLat, cell.MaxLon}) {
+		return WITHIN
+	}
+	return INTERSECTS
+}
+
+func (c *Circle) String() string {
+	return fmt.Sprintf("Circle(%v, d=%vkm)", c.Center, c.RadiusKm)
+}
+
+// spatial4j: shape/jts/JtsGeometry.java
+
+/*
+A simple polygon, whose edges are straight lines in the lat/lon
+plane. It must not cross the dateline. The last vertex connects back
+to the first one.
+*/
+type Polygon struct {
+	Vertices []Point
+	bbox     Rectangle
+}
+
+func NewPolygon(vertices ...Point) *Polygon {
+	assert2(len(vertices) >= 3, "a polygon needs at least 3 vertices, got %v", len(vertices))
+	bbox := Rectangle{90, -90, 180, -180}
+	for _, v := range vertices {
+		checkLat(v.Lat)
+		checkLon(v.Lon)
+		bbox.MinLat, bbox.MaxLat = math.Min(bbox.MinLat, v.Lat), math.Max(bbox.MaxLat, v.Lat)
+		bbox.MinLon, bbox.MaxLon = math.Min(bbox.MinLon, v.Lon), math.Max(bbox.MaxLon, v.Lon)
+	}
+	return &Polygon{vertices, bbox}
+}
+
+func (p *Polygon) BoundingBox() Rectangle {
+	return p.bbox
+}
+
+/* Ray casting, with points on an edge counted as contained. */
+func (p *Polygon) Contains(pt Point) bool {
+	if !p.bbox.Contains(pt) {
+		return false
+	}
+	inside := false
+	for i, j := 0, len(p.Vertices)-1; i < len(p.Vertices); j, i = i, i+1 {
+		a, b := p.Vertices[i], p.Vertices[j]
+		if onSegment(pt, a, b) {
+			return true
+		}
+		if (a.Lat > pt.Lat) != (b.Lat > pt.Lat) &&
+			pt.Lon < (b.Lon-a.Lon)*(pt.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (p *Polygon) Relate(cell Rectangle) Relation {
+	if p.bbox.Relate(cell) == DISJOINT {
+		return DISJOINT
+	}
+	corners := []Point{
+		{cell.MinLat, cell.MinLon}, {cell.MinLat, cell.MaxLon},
+		{cell.MaxLat, cell.MaxLon}, {cell.MaxLat, cell.MinLon},
+	}
+	for i, j := 0, len(p.Vertices)-1; i < len(p.Vertices); j, i = i, i+1 {
+		for k := range corners {
+			if segmentsIntersect(p.Vertices[i], p.Vertices[j], corners[k], corners[(k+1)%4]) {
+				return INTERSECTS
+			}
+		}
+	}
+	// the boundaries don't touch: either one holds the other, or they
+	// are disjoint
+	if cell.Contains(p.Vertices[0]) {
+		return INTERSECTS
+	}
+	if p.Contains(cell.Center()) {
+		return WITHIN
+	}
+	return DISJOINT
+}
+
+func (p *Polygon) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("Polygon(")
+	for i, v := range p.Vertices {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%v %v", v.Lat, v.Lon)
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+/* Cross product of (b-a) and (c-a), in the lon/lat plane. */
+func orientation(a, b, c Point) float64 {
+	return (b.Lon-a.Lon)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lon-a.Lon)
+}
+
+func onSegment(p, a, b Point) bool {
+	return orientation(a, b, p) == 0 &&
+		p.Lon >= math.Min(a.Lon, b.Lon) && p.Lon <= math.Max(a.Lon, b.Lon) &&
+		p.Lat >= math.Min(a.Lat, b.Lat) && p.Lat <= math.Max(a.Lat, b.Lat)
+}
+
+/* Returns true if segments ab and cd have any point in common. */
+func segmentsIntersect(a, b, c, d Point) bool {
+	o1, o2 := orientation(a, b, c), orientation(a, b, d)
+	o3, o4 := orientation(c, d, a), orientation(c, d, b)
+	if ((o1 > 0 && o2 < 0) || (o1 < 0 && o2 > 0)) &&
+		((o3 > 0 && o4 < 0) || (o3 < 0 && o4 > 0)) {
+		return true
+	}
+	return onSegment(c, a, b) || onSegment(d, a, b) || onSegment(a, c, d) || onSegment(b, c, d)
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
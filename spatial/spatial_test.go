@@ -0,0 +1,165 @@
+package spatial
+
+import (
+	"fmt"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search"
+	"github.com/jtejido/golucene/queries/function"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func place(field *LatLonField, name string, points ...Point) []model.IndexableField {
+	d := docu.NewDocument()
+	d.Add(docu.NewFieldFromString("name", name, docu.STRING_FIELD_TYPE_STORED))
+	for _, p := range points {
+		for _, f := range field.CreateIndexableFields(p) {
+			d.Add(f)
+		}
+	}
+	return d.Fields()
+}
+
+/* Returns the sorted names of the hits of query. */
+func searchNames(t *testing.T, searcher *search.IndexSearcher, query search.Query) []string {
+	docs, err := searcher.SearchTop(query, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ans []string
+	for _, hit := range docs.ScoreDocs {
+		d, err := searcher.IndexReader().Document(hit.Doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ans = append(ans, d.Get("name"))
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+var cities = map[string]Point{
+	"paris":  {48.8566, 2.3522},
+	"london": {51.5074, -0.1278},
+	"berlin": {52.52, 13.405},
+	"nyc":    {40.7128, -74.006},
+	"tokyo":  {35.6762, 139.6503},
+	"suva":   {-18.1248, 178.4501},
+	"apia":   {-13.8507, -171.7514},
+}
+
+func TestLatLonField(t *testing.T) {
+	for _, tree := range []SpatialPrefixTree{NewGeohashPrefixTree(9), NewQuadPrefixTree(20)} {
+		field := NewLatLonField("location", tree)
+		var docs [][]model.IndexableField
+		for name, p := range cities {
+			docs = append(docs, place(field, name, p))
+		}
+		docs = append(docs, place(field, "both", cities["nyc"], cities["tokyo"]))
+		docs = append(docs, place(field, "nowhere"))
+		searcher := search.NewIndexSearcher(testindex.NewReader(t, docs...))
+
+		for _, test := range []struct {
+			query    search.Query
+			expected string
+		}{
+			{field.NewBoundingBoxQuery(45, 55, -5, 15), "[berlin london paris]"},
+			{field.NewBoundingBoxQuery(-20, -10, 170, -170), "[apia suva]"},
+			{field.NewBoundingBoxQuery(30, 45, -80, 150), "[both nyc tokyo]"},
+			{field.NewDistanceQuery(cities["paris"], 345), "[london paris]"},
+			{field.NewDistanceQuery(cities["paris"], 340), "[paris]"},
+			{field.NewDistanceQuery(cities["suva"], 1300), "[apia suva]"},
+			{field.NewPolygonQuery(Point{45, -5}, Point{55, -5}, Point{55, 15}), "[london paris]"},
+			{field.NewPolygonQuery(Point{0, 0}, Point{1, 0}, Point{1, 1}), "[]"},
+		} {
+			if names := fmt.Sprint(searchNames(t, searcher, test.query)); names != test.expected {
+				t.Errorf("%v: %v, expected %v, but %v", tree, test.query, test.expected, names)
+			}
+		}
+
+		// sort by distance from paris, a document counting its nearest
+		// point, and documents without points last
+		vs := field.NewDistanceValueSource(cities["paris"])
+		c, err := function.NewValueSourceSortCollector(searcher, vs, len(docs), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = searcher.SearchCollector(search.NewMatchAllDocsQuery(), nil, c); err != nil {
+			t.Fatal(err)
+		}
+		var sorted []string
+		distances := make(map[string]float32)
+		for _, hit := range c.TopDocs().ScoreDocs {
+			d, err := searcher.IndexReader().Document(hit.Doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sorted = append(sorted, d.Get("name"))
+			distances[d.Get("name")] = hit.Score
+		}
+		if len(sorted) != len(docs) || fmt.Sprint(sorted[:3]) != "[paris london berlin]" ||
+			sorted[len(sorted)-1] != "nowhere" || !math.IsNaN(float64(distances["nowhere"])) {
+			t.Errorf("unexpected distance order %v", sorted)
+		}
+		if distances["both"] != distances["nyc"] {
+			t.Errorf("expected both at the distance of nyc, but %v", distances)
+		}
+
+		// score by distance
+		hits, err := searcher.SearchTop(function.NewFunctionQuery(vs), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := Distance(cities["paris"], cities["suva"]); math.Abs(float64(hits.ScoreDocs[1].Score)-d) > 1 {
+			t.Errorf("expected suva at %v km, but %v", d, hits.ScoreDocs[1].Score)
+		}
+	}
+}
+
+/* Compares shape queries to a brute force check of random points. */
+func TestShapeQueriesRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	points := make([]Point, 400)
+	for i := range points {
+		points[i] = Point{rnd.Float64()*180 - 90, rnd.Float64()*360 - 180}
+	}
+	randomShape := func() Shape {
+		center := Point{rnd.Float64()*160 - 80, rnd.Float64()*360 - 180}
+		size := rnd.Float64() * 40
+		switch rnd.Intn(3) {
+		case 0:
+			return NewCircle(center, size*degreeToKm)
+		case 1:
+			return NewRectangle(math.Max(-90, center.Lat-size/2), math.Min(90, center.Lat+size/2),
+				normalizeLon(center.Lon-size), normalizeLon(center.Lon+size))
+		}
+		lon := math.Max(-170, math.Min(170, center.Lon))
+		return NewPolygon(Point{center.Lat - size/4, lon - size/4}, Point{center.Lat + size/4, lon},
+			Point{center.Lat - size/4, lon + size/4}, Point{center.Lat, lon})
+	}
+
+	for _, tree := range []SpatialPrefixTree{NewGeohashPrefixTree(6), NewQuadPrefixTree(12)} {
+		field := NewLatLonField("location", tree)
+		var docs [][]model.IndexableField
+		for i, p := range points {
+			docs = append(docs, place(field, fmt.Sprintf("%03d", i), p))
+		}
+		searcher := search.NewIndexSearcher(testindex.NewReader(t, docs...))
+		for i := 0; i < 30; i++ {
+			shape := randomShape()
+			var expected []string
+			for i, p := range points {
+				if shape.Contains(p) {
+					expected = append(expected, fmt.Sprintf("%03d", i))
+				}
+			}
+			if names := searchNames(t, searcher, field.NewShapeQuery(shape)); fmt.Sprint(names) != fmt.Sprint(expected) {
+				t.Errorf("%v: %v, expected %v, but %v", tree, shape, expected, names)
+			}
+		}
+	}
+}
@@ -0,0 +1,534 @@
+package analyzing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/jtejido/golucene/core/analysis"
+	ta "github.com/jtejido/golucene/core/analysis/tokenattributes"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/core/util/fst"
+	"github.com/jtejido/golucene/suggest"
+	"math"
+	"sort"
+)
+
+// search/suggest/analyzing/AnalyzingSuggester.java
+
+const (
+	// Include this flag in the options parameter to
+	// NewAnalyzingSuggesterWithOptions() to always return the exact
+	// match first, regardless of score. This has no performance impact
+	// but could result in low-quality suggestions.
+	EXACT_FIRST = 1
+
+	// Include this flag in the options parameter to
+	// NewAnalyzingSuggesterWithOptions() to preserve token separators
+	// when matching.
+	PRESERVE_SEP = 2
+)
+
+const (
+	// Represents the separation between tokens, if PRESERVE_SEP was
+	// specified
+	SEP_LABEL = 0x1F
+
+	// Marks the end of the analyzed input and start of dedup byte.
+	END_BYTE = 0x0
+
+	// Escapes the reserved bytes which may occur inside a token
+	ESCAPE_LABEL = 0x1E
+)
+
+var weightOutputs = fst.NewPairOutputs(fst.PositiveIntOutputsSingleton(), fst.ByteSequenceOutputsSingleton())
+
+/* Compares the weights of two FST outputs; lower costs are better. */
+func weightComparator(a, b interface{}) int {
+	w1, _ := weightOutputs.Split(a)
+	w2, _ := weightOutputs.Split(b)
+	v1 := fst.PositiveIntOutputsSingleton().Value(w1)
+	v2 := fst.PositiveIntOutputsSingleton().Value(w2)
+	if v1 < v2 {
+		return -1
+	} else if v1 > v2 {
+		return 1
+	}
+	return 0
+}
+
+/* Returns the cost of an entry; weights must be in [0, MaxInt32]. */
+func encodeWeight(value int64) (int64, error) {
+	if value < 0 || value > math.MaxInt32 {
+		return 0, fmt.Errorf("cannot handle weight %v", value)
+	}
+	return math.MaxInt32 - value, nil
+}
+
+func decodeWeight(encoded int64) int64 {
+	return math.MaxInt32 - encoded
+}
+
+/* A path from the root of the FST to a node where completion starts. */
+type prefixPath struct {
+	fstNode *fst.Arc
+	output  interface{}
+	input   *util.IntsRefBuilder
+}
+
+/* Lets FuzzySuggester change how prefix paths are found. */
+type analyzingSuggesterSPI interface {
+	prefixPaths(key []byte) ([]*prefixPath, error)
+}
+
+/*
+Suggester that first analyzes the surface form, adds the analyzed
+form to a weighted FST, and then does the same thing at lookup time.
+This means lookup is based on the analyzed form while suggestions are
+still the surface form(s).
+
+This can result in powerful suggester functionality. For example, if
+you use an analyzer removing stop words, then the partial text "ghost
+chr..." could see the suggestion "The Ghost of Christmas Past". Token
+normalization like stemmers, accent removal, etc., would allow
+suggestions to ignore such variations.
+
+There are some limitations:
+
+  - A lookup from a query like "net" in English won't be any
+    different than "net " (ie, user added a trailing space) because
+    analyzers don't reflect when they've seen a token separator and
+    when they haven't.
+  - If you're using StopFilter, and the user will type "fast apple",
+    but so far all they've typed is "fast a", again because the
+    analyzer doesn't convey whether it's seen a token separator after
+    the "a", StopFilter will remove that "a" causing far more matches
+    than you'd expect.
+  - Lookups with the empty string return no results instead of all
+    results.
+  - The analyzed form is a single path: tokens stacked on the same
+    position as a previous token (e.g. injected synonyms) are ignored.
+*/
+type AnalyzingSuggester struct {
+	spi analyzingSuggesterSPI
+
+	// FST: input is the analyzed form, with a null byte between terms
+	// weights are encoded as costs: (MaxInt32-weight), and surface form
+	// is the output.
+	fst *fst.FST
+
+	// Analyzer that will be used for analyzing suggestions at index time.
+	indexAnalyzer analysis.Analyzer
+
+	// Analyzer that will be used for analyzing suggestions at query time.
+	queryAnalyzer analysis.Analyzer
+
+	// True if exact match suggestions should always be returned first.
+	exactFirst bool
+
+	// True if separator between tokens should be preserved.
+	preserveSep bool
+
+	// Maximum number of dup surface forms (different surface forms for
+	// the same analyzed form).
+	maxSurfaceFormsPerAnalyzedForm int
+
+	// Number of entries the lookup was built with
+	count int64
+}
+
+/*
+Calls NewAnalyzingSuggesterWithOptions(analyzer, analyzer,
+EXACT_FIRST|PRESERVE_SEP, 256)
+*/
+func NewAnalyzingSuggester(analyzer analysis.Analyzer) *AnalyzingSuggester {
+	return NewAnalyzingSuggesterWithOptions(analyzer, analyzer, EXACT_FIRST|PRESERVE_SEP, 256)
+}
+
+/*
+Creates a new suggester.
+
+indexAnalyzer is used to analyze suggestions at build time, and
+queryAnalyzer at lookup time. options is a bitwise or of EXACT_FIRST
+and PRESERVE_SEP. maxSurfaceFormsPerAnalyzedForm is the maximum
+number of surface forms to keep for a single analyzed form; when
+there are too many surface forms we discard the lowest weighted ones.
+It can be at most 256.
+*/
+func NewAnalyzingSuggesterWithOptions(indexAnalyzer, queryAnalyzer analysis.Analyzer,
+	options, maxSurfaceFormsPerAnalyzedForm int) *AnalyzingSuggester {
+
+	assert2(options&^(EXACT_FIRST|PRESERVE_SEP) == 0,
+		"options should only contain EXACT_FIRST and PRESERVE_SEP; got %v", options)
+	assert2(maxSurfaceFormsPerAnalyzedForm > 0 && maxSurfaceFormsPerAnalyzedForm <= 256,
+		"maxSurfaceFormsPerAnalyzedForm must be > 0 and <= 256 (got: %v)", maxSurfaceFormsPerAnalyzedForm)
+	ans := &AnalyzingSuggester{
+		indexAnalyzer:                  indexAnalyzer,
+		queryAnalyzer:                  queryAnalyzer,
+		exactFirst:                     (options & EXACT_FIRST) != 0,
+		preserveSep:                    (options & PRESERVE_SEP) != 0,
+		maxSurfaceFormsPerAnalyzedForm: maxSurfaceFormsPerAnalyzedForm,
+	}
+	ans.spi = ans
+	return ans
+}
+
+/*
+Returns the analyzed form of text: the bytes of its tokens, escaped
+and joined by SEP_LABEL if PRESERVE_SEP is set. Returns nil if the
+text produced no token.
+*/
+func (s *AnalyzingSuggester) analyze(analyzer analysis.Analyzer, text string) (analyzed []byte, err error) {
+	ts, err := analyzer.TokenStreamForString("", text)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseWhileSuppressingError(ts)
+
+	termAtt, ok := ts.Attributes().Get("TermToBytesRefAttribute").(ta.TermToBytesRefAttribute)
+	if !ok {
+		return nil, errors.New("analyzer doesn't produce TermToBytesRefAttribute")
+	}
+	posIncAtt, _ := ts.Attributes().Get("PositionIncrementAttribute").(ta.PositionIncrementAttribute)
+	term := termAtt.BytesRef()
+
+	if err = ts.Reset(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	numTokens := 0
+	for {
+		ok, err := ts.IncrementToken()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if posIncAtt != nil && posIncAtt.PositionIncrement() == 0 && numTokens > 0 {
+			// only a single path is supported
+			continue
+		}
+		termAtt.FillBytesRef()
+		if numTokens > 0 && s.preserveSep {
+			buf.WriteByte(SEP_LABEL)
+		}
+		for _, b := range term.ToBytes() {
+			switch b {
+			case SEP_LABEL, END_BYTE, ESCAPE_LABEL:
+				buf.WriteByte(ESCAPE_LABEL)
+			}
+			buf.WriteByte(b)
+		}
+		numTokens++
+	}
+	if err = ts.End(); err != nil {
+		return nil, err
+	}
+	if numTokens == 0 {
+		return nil, nil
+	}
+	return buf.Bytes(), nil
+}
+
+type analyzedEntry struct {
+	analyzed []byte
+	cost     int64
+	surface  []byte
+}
+
+type analyzedEntries []*analyzedEntry
+
+func (a analyzedEntries) Len() int      { return len(a) }
+func (a analyzedEntries) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+// by analyzed form, then by cost, then by surface form
+func (a analyzedEntries) Less(i, j int) bool {
+	if cmp := bytes.Compare(a[i].analyzed, a[j].analyzed); cmp != 0 {
+		return cmp < 0
+	}
+	if a[i].cost != a[j].cost {
+		return a[i].cost < a[j].cost
+	}
+	return bytes.Compare(a[i].surface, a[j].surface) < 0
+}
+
+func (s *AnalyzingSuggester) Build(dict suggest.Dictionary) error {
+	iterator, err := dict.EntryIterator()
+	if err != nil {
+		return err
+	}
+
+	var entries []*analyzedEntry
+	for {
+		surface, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		if surface == nil {
+			break
+		}
+		cost, err := encodeWeight(iterator.Weight())
+		if err != nil {
+			return err
+		}
+		analyzed, err := s.analyze(s.indexAnalyzer, string(surface))
+		if err != nil {
+			return err
+		}
+		if analyzed == nil {
+			// nothing to match on
+			continue
+		}
+		entries = append(entries, &analyzedEntry{analyzed, cost, append([]byte(nil), surface...)})
+	}
+	sort.Sort(analyzedEntries(entries))
+
+	builder := fst.NewDefaultBuilder(fst.INPUT_TYPE_BYTE1, weightOutputs)
+	scratchInts := util.NewIntsRefBuilder()
+	var previousAnalyzed []byte
+	dedup := 0
+	count := int64(0)
+	for _, e := range entries {
+		if previousAnalyzed != nil && bytes.Equal(e.analyzed, previousAnalyzed) {
+			dedup++
+			if dedup >= s.maxSurfaceFormsPerAnalyzedForm {
+				// More than maxSurfaceFormsPerAnalyzedForm dups: skip the
+				// rest:
+				continue
+			}
+		} else {
+			dedup = 0
+			previousAnalyzed = e.analyzed
+		}
+
+		// NOTE: must be byte 0 so we sort before whatever the next
+		// byte is:
+		input := make([]byte, len(e.analyzed), len(e.analyzed)+2)
+		copy(input, e.analyzed)
+		input = append(input, END_BYTE, byte(dedup))
+
+		var surface interface{} = e.surface
+		if len(e.surface) == 0 {
+			surface = fst.ByteSequenceOutputsSingleton().NoOutput()
+		}
+		output := weightOutputs.NewPair(fst.PositiveIntOutputsSingleton().Get(e.cost), surface)
+		if err = builder.Add(fst.ToIntsRef(input, scratchInts), output); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 {
+		s.fst, s.count = nil, 0
+		return nil
+	}
+	if s.fst, err = builder.Finish(); err != nil {
+		return err
+	}
+	s.count = count
+	return nil
+}
+
+func (s *AnalyzingSuggester) Store(out util.DataOutput) (err error) {
+	if err = out.WriteVLong(s.count); err != nil {
+		return err
+	}
+	if s.fst == nil {
+		return out.WriteByte(0)
+	}
+	if err = out.WriteByte(1); err != nil {
+		return err
+	}
+	return s.fst.Save(out)
+}
+
+func (s *AnalyzingSuggester) Load(in util.DataInput) (err error) {
+	if s.count, err = in.ReadVLong(); err != nil {
+		return err
+	}
+	hasFST, err := in.ReadByte()
+	if err != nil {
+		return err
+	}
+	if hasFST == 0 {
+		s.fst = nil
+		return nil
+	}
+	s.fst, err = fst.LoadFST(in, weightOutputs)
+	return err
+}
+
+func (s *AnalyzingSuggester) Count() int64 {
+	return s.count
+}
+
+/*
+Returns the single path following the analyzed key from the root of
+the FST, or none if no entry starts with it.
+*/
+func (s *AnalyzingSuggester) prefixPaths(key []byte) ([]*prefixPath, error) {
+	fstReader := s.fst.BytesReader()
+	arc := s.fst.FirstArc(new(fst.Arc))
+	output := s.fst.Outputs.NoOutput()
+	for _, b := range key {
+		if next, err := s.fst.FindTargetArc(int(b), arc, arc, fstReader); err != nil || next == nil {
+			return nil, err
+		}
+		output = s.fst.Outputs.Add(output, arc.Output)
+	}
+	input := util.NewIntsRefBuilder()
+	fst.ToIntsRef(key, input)
+	return []*prefixPath{{arc, output, input}}, nil
+}
+
+func (s *AnalyzingSuggester) lookupResult(output interface{}) *suggest.LookupResult {
+	cost, surface := weightOutputs.Split(output)
+	key := ""
+	if surface != fst.ByteSequenceOutputsSingleton().NoOutput() {
+		key = string(surface.([]byte))
+	}
+	return &suggest.LookupResult{
+		Key:   key,
+		Value: decodeWeight(fst.PositiveIntOutputsSingleton().Value(cost)),
+	}
+}
+
+func sameSurfaceForm(key string, output interface{}) bool {
+	_, surface := weightOutputs.Split(output)
+	if surface == fst.ByteSequenceOutputsSingleton().NoOutput() {
+		return key == ""
+	}
+	return key == string(surface.([]byte))
+}
+
+func (s *AnalyzingSuggester) Lookup(key string, onlyMorePopular bool, num int) ([]*suggest.LookupResult, error) {
+	assert(num > 0)
+
+	if onlyMorePopular {
+		return nil, errors.New("this suggester only works with onlyMorePopular=false")
+	}
+	if s.fst == nil {
+		return nil, nil
+	}
+
+	analyzed, err := s.analyze(s.queryAnalyzer, key)
+	if err != nil || analyzed == nil {
+		return nil, err
+	}
+
+	paths, err := s.spi.prefixPaths(analyzed)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	fstReader := s.fst.BytesReader()
+	scratchArc := new(fst.Arc)
+
+	var results []*suggest.LookupResult
+
+	if s.exactFirst {
+		count := 0
+		for _, path := range paths {
+			// This node has END_BYTE arc leaving, meaning it's an
+			// "exact" match:
+			if next, err := s.fst.FindTargetArc(END_BYTE, path.fstNode, scratchArc, fstReader); err != nil {
+				return nil, err
+			} else if next != nil {
+				count++
+			}
+		}
+
+		if count > 0 {
+			// Searcher just to find the single exact only match, if
+			// present:
+			searcher := fst.NewTopNSearcher(s.fst, count*s.maxSurfaceFormsPerAnalyzedForm,
+				count*s.maxSurfaceFormsPerAnalyzedForm, weightComparator)
+
+			// NOTE: we could almost get away with only using the first
+			// start node. The only catch is if
+			// maxSurfaceFormsPerAnalyzedForm had kicked in and pruned
+			// our exact match from one of these nodes ...:
+			for _, path := range paths {
+				if next, err := s.fst.FindTargetArc(END_BYTE, path.fstNode, scratchArc, fstReader); err != nil {
+					return nil, err
+				} else if next != nil {
+					if err = searcher.AddStartPaths(scratchArc,
+						s.fst.Outputs.Add(path.output, scratchArc.Output), false, path.input); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			completions, err := searcher.Search()
+			if err != nil {
+				return nil, err
+			}
+
+			// NOTE: this is rather inefficient: we enumerate every
+			// matching "exactly the same analyzed form" path, and then
+			// do linear scan to see if one of these exactly matches the
+			// input.
+			for _, completion := range completions.TopN {
+				if sameSurfaceForm(key, completion.Output) {
+					results = append(results, s.lookupResult(completion.Output))
+					break
+				}
+			}
+
+			if len(results) == num {
+				// That was quick:
+				return results, nil
+			}
+		}
+	}
+
+	// With several start paths the same completion can be reached
+	// more than once, so leave room for the duplicates we reject
+	searcher := fst.NewTopNSearcher(s.fst, num-len(results), num*(len(paths)+1), weightComparator)
+	seen := make(map[string]bool)
+	searcher.AcceptResult = func(input *util.IntsRef, output interface{}) bool {
+		// Dedup: when several start paths overlap we can get
+		// duplicate surface forms:
+		surface := s.lookupResult(output).Key
+		if seen[surface] {
+			return false
+		}
+		seen[surface] = true
+
+		// In exactFirst mode, don't accept any paths matching the
+		// surface form since that will create duplicate results:
+		return !s.exactFirst || !sameSurfaceForm(key, output)
+	}
+
+	for _, path := range paths {
+		if err = searcher.AddStartPaths(path.fstNode, path.output, true, path.input); err != nil {
+			return nil, err
+		}
+	}
+
+	completions, err := searcher.Search()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, completion := range completions.TopN {
+		results = append(results, s.lookupResult(completion.Output))
+		if len(results) == num {
+			// In the exactFirst=true case the search may produce one
+			// extra path
+			break
+		}
+	}
+	return results, nil
+}
+
+func assert(ok bool) {
+	if !ok {
+		panic("assert fail")
+	}
+}
+
+func assert2(ok bool, msg string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(msg, args...))
+	}
+}
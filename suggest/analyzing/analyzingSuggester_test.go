@@ -0,0 +1,114 @@
+package analyzing
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/analysis/standard"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/suggest"
+	"testing"
+)
+
+func keys(results []*suggest.LookupResult) string {
+	return fmt.Sprintf("%v", results)
+}
+
+func newDictionary() *suggest.InMemoryDictionary {
+	return suggest.NewInMemoryDictionary().
+		Add("the ghost of christmas past", 50).
+		Add("barbeque", 8).
+		Add("barbara", 6).
+		Add("barbecue", 10).
+		Add("Barbecue", 4).
+		Add("bar", 2)
+}
+
+func TestAnalyzingSuggesterLookup(t *testing.T) {
+	s := NewAnalyzingSuggester(standard.NewStandardAnalyzer())
+	if err := s.Build(newDictionary()); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Count(); n != 6 {
+		t.Errorf("Expected 6 entries, but %v", n)
+	}
+
+	for _, c := range []struct {
+		key      string
+		num      int
+		expected string
+	}{
+		// stop words are removed, and the case is folded
+		{"ghost chr", 3, "[the ghost of christmas past/50]"},
+		{"bar", 3, "[bar/2 barbecue/10 barbeque/8]"},
+		{"barb", 4, "[barbecue/10 barbeque/8 barbara/6 Barbecue/4]"},
+		{"Barbecue", 2, "[Barbecue/4 barbecue/10]"},
+		{"barbq", 2, "[]"},
+	} {
+		results, err := s.Lookup(c.key, false, c.num)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keys(results); got != c.expected {
+			t.Errorf("Lookup(%v): expected %v, but %v", c.key, c.expected, got)
+		}
+	}
+}
+
+func TestFuzzySuggesterLookup(t *testing.T) {
+	s := NewFuzzySuggester(standard.NewStandardAnalyzer())
+	if err := s.Build(newDictionary()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		key      string
+		expected string
+	}{
+		// substitution
+		{"barbq", "[barbecue/10 barbeque/8]"},
+		// transposition
+		{"brab", "[barbecue/10 barbeque/8]"},
+		// the first byte has to match
+		{"arbe", "[]"},
+		{"ghots", "[the ghost of christmas past/50]"},
+	} {
+		results, err := s.Lookup(c.key, false, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keys(results); got != c.expected {
+			t.Errorf("Lookup(%v): expected %v, but %v", c.key, c.expected, got)
+		}
+	}
+}
+
+func TestAnalyzingSuggesterStoreAndLoad(t *testing.T) {
+	analyzer := standard.NewStandardAnalyzer()
+	s := NewAnalyzingSuggester(analyzer)
+	if err := s.Build(newDictionary()); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := store.NewRAMDirectory()
+	if err := suggest.StoreLookup(s, dir, "suggest.bin"); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewAnalyzingSuggester(analyzer)
+	if err := suggest.LoadLookup(loaded, dir, "suggest.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if n := loaded.Count(); n != s.Count() {
+		t.Errorf("Expected %v entries, but %v", s.Count(), n)
+	}
+
+	expected, err := s.Lookup("barb", false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := loaded.Lookup("barb", false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys(results) != keys(expected) {
+		t.Errorf("Expected %v, but %v", keys(expected), keys(results))
+	}
+}
@@ -0,0 +1,223 @@
+package analyzing
+
+import (
+	"github.com/jtejido/golucene/core/analysis"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/core/util/fst"
+)
+
+// search/suggest/analyzing/FuzzySuggester.java
+
+const (
+	// The default maximum number of edits for fuzzy suggestions.
+	DEFAULT_MAX_EDITS = 1
+	// The default transposition value passed to the constructor.
+	DEFAULT_TRANSPOSITIONS = true
+	// The default prefix length where edits are not allowed.
+	DEFAULT_NON_FUZZY_PREFIX = 1
+	// The default minimum length of the key passed to Lookup() before
+	// any edits are allowed.
+	DEFAULT_MIN_FUZZY_LENGTH = 3
+
+	// The maximum number of edits supported.
+	MAX_EDITS = 2
+)
+
+/*
+Implements a fuzzy AnalyzingSuggester. The similarity measurement is
+based on the Damerau-Levenshtein (optimal string alignment) algorithm,
+though you can explicitly choose classic Levenshtein by passing false
+for the transpositions parameter.
+
+At most, this query will match terms up to MAX_EDITS edits. Higher
+distances are not supported. Note that the fuzzy distance is measured
+in "byte space" on the bytes returned by the TokenStream's
+TermToBytesRefAttribute, usually UTF-8. Separators between tokens
+count as bytes as well.
+
+Instead of intersecting a Levenshtein automaton with the FST, the
+prefix paths are found by walking the FST while computing the edit
+distance of every visited prefix, pruning branches which can no
+longer match within the maximum number of edits.
+
+NOTE: This suggester does not boost suggestions that required no
+edits over suggestions that did require edits. This is a known
+limitation.
+*/
+type FuzzySuggester struct {
+	*AnalyzingSuggester
+
+	maxEdits       int
+	transpositions bool
+	nonFuzzyPrefix int
+	minFuzzyLength int
+}
+
+/* Creates a FuzzySuggester using the same analyzer at index and query time. */
+func NewFuzzySuggester(analyzer analysis.Analyzer) *FuzzySuggester {
+	return NewFuzzySuggesterWithOptions(analyzer, analyzer, EXACT_FIRST|PRESERVE_SEP, 256,
+		DEFAULT_MAX_EDITS, DEFAULT_TRANSPOSITIONS, DEFAULT_NON_FUZZY_PREFIX, DEFAULT_MIN_FUZZY_LENGTH)
+}
+
+/*
+Creates a FuzzySuggester instance.
+
+See NewAnalyzingSuggesterWithOptions() for the first four parameters.
+maxEdits must be >= 0 and <= MAX_EDITS. If transpositions is true, a
+transposition (swapping two adjacent bytes) counts as a single edit
+instead of two. nonFuzzyPrefix is the length of the common
+(non-fuzzy) prefix, and minFuzzyLength the minimum length of the
+analyzed lookup key before any edits are allowed.
+*/
+func NewFuzzySuggesterWithOptions(indexAnalyzer, queryAnalyzer analysis.Analyzer,
+	options, maxSurfaceFormsPerAnalyzedForm, maxEdits int, transpositions bool,
+	nonFuzzyPrefix, minFuzzyLength int) *FuzzySuggester {
+
+	assert2(maxEdits >= 0 && maxEdits <= MAX_EDITS,
+		"maxEdits must be between 0 and %v", MAX_EDITS)
+	assert2(nonFuzzyPrefix >= 0, "nonFuzzyPrefix must be >= 0 (got %v)", nonFuzzyPrefix)
+	assert2(minFuzzyLength >= 0, "minFuzzyLength must be >= 0 (got %v)", minFuzzyLength)
+
+	ans := &FuzzySuggester{
+		AnalyzingSuggester: NewAnalyzingSuggesterWithOptions(indexAnalyzer, queryAnalyzer,
+			options, maxSurfaceFormsPerAnalyzedForm),
+		maxEdits:       maxEdits,
+		transpositions: transpositions,
+		nonFuzzyPrefix: nonFuzzyPrefix,
+		minFuzzyLength: minFuzzyLength,
+	}
+	ans.spi = ans
+	return ans
+}
+
+func (s *FuzzySuggester) prefixPaths(key []byte) ([]*prefixPath, error) {
+	if len(key) < s.minFuzzyLength || len(key) <= s.nonFuzzyPrefix || s.maxEdits == 0 {
+		return s.AnalyzingSuggester.prefixPaths(key)
+	}
+
+	// the non-fuzzy prefix must match exactly
+	paths, err := s.AnalyzingSuggester.prefixPaths(key[:s.nonFuzzyPrefix])
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	w := &levenshteinWalker{
+		fst:            s.fst,
+		in:             s.fst.BytesReader(),
+		target:         key[s.nonFuzzyPrefix:],
+		maxEdits:       s.maxEdits,
+		transpositions: s.transpositions,
+	}
+	row := make([]int, len(w.target)+1)
+	for i := range row {
+		row[i] = i
+	}
+	escaped := s.nonFuzzyPrefix > 0 && isEscape(key[:s.nonFuzzyPrefix])
+	if err = w.walk(paths[0], row, nil, -1, escaped); err != nil {
+		return nil, err
+	}
+	return w.paths, nil
+}
+
+/* Returns true if the analyzed bytes end with an unescaped ESCAPE_LABEL. */
+func isEscape(analyzed []byte) bool {
+	n := 0
+	for i := len(analyzed) - 1; i >= 0 && analyzed[i] == ESCAPE_LABEL; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+/*
+Collects the nodes of the FST whose path from the root is within
+maxEdits of the target, computing the edit distance one row of the
+dynamic programming table per arc followed.
+*/
+type levenshteinWalker struct {
+	fst            *fst.FST
+	in             fst.BytesReader
+	target         []byte
+	maxEdits       int
+	transpositions bool
+
+	paths []*prefixPath
+}
+
+/*
+Visits the node reached by path; row holds the distances between the
+labels of path and every prefix of the target, prevRow the ones of the
+parent node, whose incoming label was prevLabel. escaped is true if
+the last label is an escape, in which case the next label is part of
+a token even if it is END_BYTE.
+*/
+func (w *levenshteinWalker) walk(path *prefixPath, row, prevRow []int, prevLabel int, escaped bool) error {
+	if row[len(row)-1] <= w.maxEdits {
+		// the whole target matched; every completion of this node is a
+		// candidate, so there is no need to go any deeper
+		w.paths = append(w.paths, path)
+		return nil
+	}
+	if minOf(row) > w.maxEdits {
+		return nil
+	}
+
+	arc := new(fst.Arc)
+	if _, err := w.fst.ReadFirstTargetArc(path.fstNode, arc, w.in); err != nil {
+		return err
+	}
+	for {
+		if arc.Label != fst.FST_END_LABEL && (escaped || arc.Label != END_BYTE) {
+			input := util.NewIntsRefBuilder()
+			input.CopyInts(path.input.Get())
+			input.Append(arc.Label)
+			child := &prefixPath{
+				fstNode: new(fst.Arc).CopyFrom(arc),
+				output:  w.fst.Outputs.Add(path.output, arc.Output),
+				input:   input,
+			}
+			if err := w.walk(child, w.step(row, prevRow, prevLabel, arc.Label), row,
+				arc.Label, !escaped && arc.Label == ESCAPE_LABEL); err != nil {
+				return err
+			}
+		}
+		if arc.IsLast() {
+			break
+		}
+		if _, err := w.fst.ReadNextArc(arc, w.in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* Computes the row of the child reached by label. */
+func (w *levenshteinWalker) step(row, prevRow []int, prevLabel, label int) []int {
+	next := make([]int, len(row))
+	next[0] = row[0] + 1
+	for j := 1; j < len(row); j++ {
+		cost := 1
+		if int(w.target[j-1]) == label {
+			cost = 0
+		}
+		next[j] = minOf([]int{
+			row[j] + 1,       // insertion
+			next[j-1] + 1,    // deletion
+			row[j-1] + cost}) // substitution
+		if w.transpositions && prevRow != nil && j > 1 &&
+			int(w.target[j-1]) == prevLabel && int(w.target[j-2]) == label &&
+			prevRow[j-2]+1 < next[j] {
+			next[j] = prevRow[j-2] + 1
+		}
+	}
+	return next
+}
+
+func minOf(values []int) int {
+	ans := values[0]
+	for _, v := range values[1:] {
+		if v < ans {
+			ans = v
+		}
+	}
+	return ans
+}
@@ -0,0 +1,177 @@
+package suggest
+
+import (
+	"bytes"
+	"github.com/jtejido/golucene/core/index"
+	"sort"
+)
+
+// search/suggest/InputIterator.java
+
+/* Interface for enumerating term,weight pairs for suggester consumption. */
+type InputIterator interface {
+	// Returns the next term, or nil when the iterator is exhausted.
+	// The returned []byte may be re-used across calls.
+	Next() ([]byte, error)
+	// A term's weight, higher numbers mean better suggestions.
+	Weight() int64
+}
+
+// search/spell/Dictionary.java
+
+/* A simple interface representing a Dictionary. */
+type Dictionary interface {
+	// Returns an iterator over all the entries.
+	EntryIterator() (InputIterator, error)
+}
+
+/* A single term,weight pair. */
+type Input struct {
+	Term   []byte
+	Weight int64
+}
+
+type inputArrayIterator struct {
+	entries []*Input
+	current *Input
+}
+
+func (it *inputArrayIterator) Next() ([]byte, error) {
+	if len(it.entries) == 0 {
+		it.current = nil
+		return nil, nil
+	}
+	it.current, it.entries = it.entries[0], it.entries[1:]
+	return it.current.Term, nil
+}
+
+func (it *inputArrayIterator) Weight() int64 {
+	return it.current.Weight
+}
+
+/* A Dictionary holding its entries in memory. */
+type InMemoryDictionary struct {
+	entries []*Input
+}
+
+func NewInMemoryDictionary() *InMemoryDictionary {
+	return new(InMemoryDictionary)
+}
+
+/* Adds an entry; the same term may be added more than once. */
+func (d *InMemoryDictionary) Add(term string, weight int64) *InMemoryDictionary {
+	d.entries = append(d.entries, &Input{[]byte(term), weight})
+	return d
+}
+
+func (d *InMemoryDictionary) EntryIterator() (InputIterator, error) {
+	return &inputArrayIterator{entries: d.entries}, nil
+}
+
+// search/spell/HighFrequencyDictionary.java
+
+/*
+HighFrequencyDictionary: terms taken from the given field of an
+IndexReader, which occur in a percentage of documents that is greater
+than or equal to the specified threshold. The weight of each term is
+its document frequency.
+
+Threshold is a value in [0..1] representing the minimum number of
+documents (of the total) where a term should appear.
+*/
+type HighFrequencyDictionary struct {
+	reader index.IndexReader
+	field  string
+	thresh float32
+}
+
+func NewHighFrequencyDictionary(reader index.IndexReader, field string, thresh float32) *HighFrequencyDictionary {
+	return &HighFrequencyDictionary{reader, field, thresh}
+}
+
+func (d *HighFrequencyDictionary) EntryIterator() (InputIterator, error) {
+	entries, err := readTerms(d.reader, d.field)
+	if err != nil {
+		return nil, err
+	}
+	minNumDocs := int64(d.thresh * float32(d.reader.NumDocs()))
+	var accepted []*Input
+	for _, e := range entries {
+		if e.Weight >= minNumDocs {
+			accepted = append(accepted, e)
+		}
+	}
+	return &inputArrayIterator{entries: accepted}, nil
+}
+
+// search/spell/LuceneDictionary.java
+
+/*
+Lucene Dictionary: terms taken from the given field of an
+IndexReader. Every term has a weight of 1.
+*/
+type LuceneDictionary struct {
+	reader index.IndexReader
+	field  string
+}
+
+func NewLuceneDictionary(reader index.IndexReader, field string) *LuceneDictionary {
+	return &LuceneDictionary{reader, field}
+}
+
+func (d *LuceneDictionary) EntryIterator() (InputIterator, error) {
+	entries, err := readTerms(d.reader, d.field)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		e.Weight = 1
+	}
+	return &inputArrayIterator{entries: entries}, nil
+}
+
+type inputsByTerm []*Input
+
+func (a inputsByTerm) Len() int           { return len(a) }
+func (a inputsByTerm) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a inputsByTerm) Less(i, j int) bool { return bytes.Compare(a[i].Term, a[j].Term) < 0 }
+
+/*
+Returns the terms of the field in sorted order, weighted by their
+document frequency. Segments are walked one by one and the frequencies
+of terms found in several segments are summed.
+*/
+func readTerms(reader index.IndexReader, field string) ([]*Input, error) {
+	byTerm := make(map[string]*Input)
+	for _, ctx := range reader.Leaves() {
+		terms := ctx.Reader().(index.AtomicReader).Terms(field)
+		if terms == nil {
+			continue
+		}
+		termsEnum := terms.Iterator(nil)
+		for {
+			term, err := termsEnum.Next()
+			if err != nil {
+				return nil, err
+			}
+			if term == nil {
+				break
+			}
+			df, err := termsEnum.DocFreq()
+			if err != nil {
+				return nil, err
+			}
+			if e, ok := byTerm[string(term)]; ok {
+				e.Weight += int64(df)
+			} else {
+				byTerm[string(term)] = &Input{append([]byte(nil), term...), int64(df)}
+			}
+		}
+	}
+	entries := make([]*Input, 0, len(byTerm))
+	for _, e := range byTerm {
+		entries = append(entries, e)
+	}
+	sort.Sort(inputsByTerm(entries))
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package suggest
+
+import (
+	"fmt"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/core/util"
+)
+
+// search/suggest/Lookup.java
+
+/* Result of a lookup. */
+type LookupResult struct {
+	// the key's text
+	Key string
+	// the key's weight
+	Value int64
+}
+
+func (r *LookupResult) String() string {
+	return fmt.Sprintf("%v/%v", r.Key, r.Value)
+}
+
+/* Simple Lookup interface for string suggestions. */
+type Lookup interface {
+	// Builds up a new internal Lookup representation based on the
+	// given dictionary.
+	Build(dict Dictionary) error
+	// Look up a key and return possible completion for this key.
+	// onlyMorePopular returns only more popular results; num is the
+	// maximum number of results to return.
+	Lookup(key string, onlyMorePopular bool, num int) ([]*LookupResult, error)
+	// Persist the constructed lookup data to a DataOutput.
+	Store(out util.DataOutput) error
+	// Discard current lookup data and load it from a previously
+	// saved copy.
+	Load(in util.DataInput) error
+	// Returns the number of entries the lookup was built with.
+	Count() int64
+}
+
+/* Persists the lookup into a new file of the directory. */
+func StoreLookup(lookup Lookup, dir store.Directory, name string) (err error) {
+	out, err := dir.CreateOutput(name, store.IO_CONTEXT_DEFAULT)
+	if err != nil {
+		return err
+	}
+	var success = false
+	defer func() {
+		if success {
+			err = util.Close(out)
+		} else {
+			util.CloseWhileSuppressingError(out)
+		}
+	}()
+
+	if err = lookup.Store(out); err != nil {
+		return err
+	}
+	success = true
+	return nil
+}
+
+/* Loads the lookup from a file of the directory written by StoreLookup. */
+func LoadLookup(lookup Lookup, dir store.Directory, name string) (err error) {
+	in, err := dir.OpenInput(name, store.IO_CONTEXT_READONCE)
+	if err != nil {
+		return err
+	}
+	defer util.CloseWhileSuppressingError(in)
+	return lookup.Load(in)
+}
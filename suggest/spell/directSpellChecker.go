@@ -0,0 +1,538 @@
+package spell
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/util/automaton"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// search/spell/DirectSpellChecker.java
+
+/*
+The default StringDistance, Damerau-Levenshtein distance implemented
+internally via the Levenshtein automata. Using this distance has no
+additional cost, the scores are computed while the terms dictionary is
+enumerated. If you use another StringDistance, it is computed for
+every candidate after the terms are matched.
+*/
+var INTERNAL_LEVENSHTEIN StringDistance = NewLuceneLevenshteinDistance()
+
+// The default minimum score for suggestions.
+const DEFAULT_ACCURACY = 0.5
+
+/*
+Simple automaton-based spellchecker.
+
+Candidates are presented directly from the term dictionary, based on
+Levenshtein distance. Unlike an index-based spellchecker, no
+auxiliary index is required: the candidates are the terms of the
+field itself.
+
+A practical benefit of this spellchecker is that it requires no
+additional datastructures (neither in RAM nor on disk) to do its
+work.
+
+NOTE: the terms dictionary of every segment is enumerated and every
+term is run through the Levenshtein automata, since TermsEnum cannot
+intersect automata yet; it is meant for fields with a moderate number
+of unique terms.
+*/
+type DirectSpellChecker struct {
+	// maximum edit distance for candidate terms
+	maxEdits int
+	// minimum prefix for candidate terms
+	minPrefix int
+	// maximum number of top-N inspections per suggestion
+	maxInspections int
+	// minimum accuracy for a term to match
+	accuracy float32
+	// value in [0..1] (or absolute number >= 1) representing the minimum
+	// number of documents (of the total) where a term should appear.
+	thresholdFrequency float32
+	// minimum length of a query word to return suggestions
+	minQueryLength int
+	// value in [0..1] (or absolute number >= 1) representing the maximum
+	// number of documents (of the total) a query term can appear in to
+	// be corrected.
+	maxQueryFrequency float32
+	// true if the spellchecker should lowercase terms
+	lowerCaseTerms bool
+	// the comparator to use
+	comparator SuggestWordComparator
+	// the string distance to use
+	distance StringDistance
+}
+
+/*
+Creates a DirectSpellChecker with default configuration values:
+
+  - maxEdits: 2
+  - minPrefix: 1
+  - maxInspections: 5
+  - accuracy: 0.5
+  - thresholdFrequency: 0
+  - minQueryLength: 4
+  - maxQueryFrequency: 0.01
+  - lowerCaseTerms: true
+  - comparator: SuggestWordScoreComparator
+  - distance: INTERNAL_LEVENSHTEIN
+*/
+func NewDirectSpellChecker() *DirectSpellChecker {
+	return &DirectSpellChecker{
+		maxEdits:          automaton.MAXIMUM_SUPPORTED_DISTANCE,
+		minPrefix:         1,
+		maxInspections:    5,
+		accuracy:          DEFAULT_ACCURACY,
+		minQueryLength:    4,
+		maxQueryFrequency: 0.01,
+		lowerCaseTerms:    true,
+		comparator:        SuggestWordScoreComparator,
+		distance:          INTERNAL_LEVENSHTEIN,
+	}
+}
+
+/* Get the maximum number of Levenshtein edit-distances to draw candidate terms from. */
+func (sc *DirectSpellChecker) MaxEdits() int {
+	return sc.maxEdits
+}
+
+/*
+Sets the maximum number of Levenshtein edit-distances to draw
+candidate terms from. This value can be 1 or 2. The default is 2.
+
+Note: a large number of spelling errors occur with an edit distance
+of 1, by setting this value to 1 you can increase both performance
+and precision at the cost of recall.
+*/
+func (sc *DirectSpellChecker) SetMaxEdits(maxEdits int) {
+	if maxEdits < 1 || maxEdits > automaton.MAXIMUM_SUPPORTED_DISTANCE {
+		panic(fmt.Sprintf("Invalid maxEdits: %v", maxEdits))
+	}
+	sc.maxEdits = maxEdits
+}
+
+/* Get the minimal number of characters that must match exactly. */
+func (sc *DirectSpellChecker) MinPrefix() int {
+	return sc.minPrefix
+}
+
+/*
+Sets the minimal number of initial characters (default: 1) that must
+match exactly.
+
+This can improve both performance and accuracy of results, as
+misspellings are commonly not the first character.
+*/
+func (sc *DirectSpellChecker) SetMinPrefix(minPrefix int) {
+	sc.minPrefix = minPrefix
+}
+
+/* Get the maximum number of top-N inspections per suggestion. */
+func (sc *DirectSpellChecker) MaxInspections() int {
+	return sc.maxInspections
+}
+
+/*
+Set the maximum number of top-N inspections (default: 5) per
+suggestion.
+
+Increasing this number can improve the accuracy of results, at the
+cost of performance.
+*/
+func (sc *DirectSpellChecker) SetMaxInspections(maxInspections int) {
+	sc.maxInspections = maxInspections
+}
+
+/* Get the minimal accuracy from the StringDistance for a match. */
+func (sc *DirectSpellChecker) Accuracy() float32 {
+	return sc.accuracy
+}
+
+/*
+Set the minimal accuracy required (default: 0.5f) from a
+StringDistance for a suggestion match.
+*/
+func (sc *DirectSpellChecker) SetAccuracy(accuracy float32) {
+	sc.accuracy = accuracy
+}
+
+/* Get the minimal threshold of documents a term must appear for a match. */
+func (sc *DirectSpellChecker) ThresholdFrequency() float32 {
+	return sc.thresholdFrequency
+}
+
+/*
+Set the minimal threshold of documents a term must appear for a
+match.
+
+This can improve quality by only suggesting high-frequency terms.
+Note that very high values might decrease performance slightly, by
+forcing the spellchecker to draw more candidates from the term
+dictionary, but a practical value such as 1 can be very useful
+towards improving quality.
+
+This can be specified as a relative percentage of documents such as
+0.5f, or it can be specified as an absolute whole document frequency,
+such as 4f. Absolute document frequencies may not be fractional.
+*/
+func (sc *DirectSpellChecker) SetThresholdFrequency(thresholdFrequency float32) {
+	if thresholdFrequency >= 1 && thresholdFrequency != float32(math.Floor(float64(thresholdFrequency))) {
+		panic("Fractional absolute document frequencies are not allowed")
+	}
+	sc.thresholdFrequency = thresholdFrequency
+}
+
+/* Get the minimum length of a query term needed to return suggestions. */
+func (sc *DirectSpellChecker) MinQueryLength() int {
+	return sc.minQueryLength
+}
+
+/*
+Set the minimum length of a query term (default: 4) needed to return
+suggestions.
+
+Very short query terms will often cause only bad suggestions with any
+distance metric.
+*/
+func (sc *DirectSpellChecker) SetMinQueryLength(minQueryLength int) {
+	sc.minQueryLength = minQueryLength
+}
+
+/*
+Get the maximum threshold of documents a query term can appear in
+order to provide suggestions.
+*/
+func (sc *DirectSpellChecker) MaxQueryFrequency() float32 {
+	return sc.maxQueryFrequency
+}
+
+/*
+Set the maximum threshold (default: 0.01f) of documents a query term
+can appear in order to provide suggestions.
+
+Very high-frequency terms are typically spelled correctly. Additionally,
+this can increase performance as it will do no work for the common
+case of correctly-spelled input terms.
+
+This can be specified as a relative percentage of documents such as
+0.5f, or it can be specified as an absolute whole document frequency,
+such as 4f. Absolute document frequencies may not be fractional.
+*/
+func (sc *DirectSpellChecker) SetMaxQueryFrequency(maxQueryFrequency float32) {
+	if maxQueryFrequency >= 1 && maxQueryFrequency != float32(math.Floor(float64(maxQueryFrequency))) {
+		panic("Fractional absolute document frequencies are not allowed")
+	}
+	sc.maxQueryFrequency = maxQueryFrequency
+}
+
+/* true if the spellchecker should lowercase terms */
+func (sc *DirectSpellChecker) LowerCaseTerms() bool {
+	return sc.lowerCaseTerms
+}
+
+/*
+True if the spellchecker should lowercase terms (default: true)
+
+This is a convenience method, if your index field has more complicated
+analysis (such as StandardTokenizer removing punctuation), it's
+probably better to turn this off, and instead run your query terms
+through your Analyzer first.
+
+If this option is not on, case differences count as an edit!
+*/
+func (sc *DirectSpellChecker) SetLowerCaseTerms(lowerCaseTerms bool) {
+	sc.lowerCaseTerms = lowerCaseTerms
+}
+
+/* Get the current comparator in use. */
+func (sc *DirectSpellChecker) Comparator() SuggestWordComparator {
+	return sc.comparator
+}
+
+/*
+Set the comparator for sorting suggestions. The default is
+SuggestWordScoreComparator.
+*/
+func (sc *DirectSpellChecker) SetComparator(comparator SuggestWordComparator) {
+	sc.comparator = comparator
+}
+
+/* Get the string distance metric in use. */
+func (sc *DirectSpellChecker) Distance() StringDistance {
+	return sc.distance
+}
+
+/*
+Set the string distance metric. The default is INTERNAL_LEVENSHTEIN.
+
+Note: because this spellchecker draws its candidates from the term
+dictionary using Damerau-Levenshtein, it works best with an
+edit-distance-like string metric. If you use a different metric than
+the default, you might want to consider increasing MaxInspections to
+draw more candidates for your metric to rank.
+*/
+func (sc *DirectSpellChecker) SetDistance(distance StringDistance) {
+	sc.distance = distance
+}
+
+/*
+Calls SuggestSimilarWithAccuracy(term, numSug, ir, mode, Accuracy()),
+suggesting only when the term is not in the index. This is the
+"did you mean" of a query which had no hits.
+*/
+func (sc *DirectSpellChecker) SuggestSimilar(term *index.Term, numSug int,
+	ir index.IndexReader) ([]*SuggestWord, error) {
+	return sc.SuggestSimilarWithAccuracy(term, numSug, ir, SUGGEST_WHEN_NOT_IN_INDEX, sc.accuracy)
+}
+
+/*
+Suggest similar words.
+
+The similarity used to fetch the most relevant terms is an edit
+distance, therefore typically a low value for numSug will work very
+well.
+*/
+func (sc *DirectSpellChecker) SuggestSimilarWithAccuracy(term *index.Term, numSug int,
+	ir index.IndexReader, suggestMode SuggestMode, accuracy float32) ([]*SuggestWord, error) {
+
+	text := string(term.Bytes)
+	if sc.minQueryLength > 0 && utf8.RuneCountInString(text) < sc.minQueryLength {
+		return nil, nil
+	}
+
+	if sc.lowerCaseTerms {
+		text = strings.ToLower(text)
+		term = index.NewTerm(term.Field, text)
+	}
+
+	docfreq, err := ir.DocFreq(term)
+	if err != nil {
+		return nil, err
+	}
+
+	if suggestMode == SUGGEST_WHEN_NOT_IN_INDEX && docfreq > 0 {
+		return nil, nil
+	}
+
+	maxDoc := ir.MaxDoc()
+
+	if sc.maxQueryFrequency >= 1 && float32(docfreq) > sc.maxQueryFrequency {
+		return nil, nil
+	} else if docfreq > int(math.Ceil(float64(sc.maxQueryFrequency*float32(maxDoc)))) {
+		return nil, nil
+	}
+
+	if suggestMode != SUGGEST_MORE_POPULAR {
+		docfreq = 0
+	}
+
+	if sc.thresholdFrequency >= 1 {
+		docfreq = maxInt(docfreq, int(sc.thresholdFrequency))
+	} else if sc.thresholdFrequency > 0 {
+		docfreq = maxInt(docfreq, int(sc.thresholdFrequency*float32(maxDoc))-1)
+	}
+
+	inspections := numSug * sc.maxInspections
+
+	// try ed=1 first, in case we get lucky
+	terms, err := sc.suggest(term, inspections, ir, docfreq, 1, accuracy)
+	if err != nil {
+		return nil, err
+	}
+	if sc.maxEdits > 1 && len(terms) < inspections {
+		moreTerms, err := sc.suggest(term, inspections, ir, docfreq, sc.maxEdits, accuracy)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, st := range terms {
+			seen[st.term] = true
+		}
+		for _, st := range moreTerms {
+			if !seen[st.term] {
+				terms = append(terms, st)
+			}
+		}
+	}
+
+	// create the suggestword response, sort it, and trim it to size.
+	suggestions := make([]*SuggestWord, len(terms))
+	for i, st := range terms {
+		suggestions[i] = &SuggestWord{
+			String: st.term,
+			Score:  st.score,
+			Freq:   st.docfreq,
+		}
+	}
+	sort.Stable(&suggestWordsByRank{suggestions, sc.comparator})
+	if numSug < len(suggestions) {
+		suggestions = suggestions[:numSug]
+	}
+	return suggestions, nil
+}
+
+/* Holds a spelling correction for internal usage inside DirectSpellChecker. */
+type scoreTerm struct {
+	// The actual spellcheck correction.
+	term string
+	// The boost representing the similarity from the automata.
+	boost float32
+	// The df of the spellcheck correction.
+	docfreq int
+	// The score for the correction.
+	score float32
+}
+
+/* Sorts by boost, best first, and then by the text of the term. */
+type scoreTermsByBoost []*scoreTerm
+
+func (a scoreTermsByBoost) Len() int      { return len(a) }
+func (a scoreTermsByBoost) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a scoreTermsByBoost) Less(i, j int) bool {
+	if a[i].boost != a[j].boost {
+		return a[i].boost > a[j].boost
+	}
+	return a[i].term < a[j].term
+}
+
+/*
+Provide spelling corrections based on several parameters: returns at
+most numSug candidates within editDistance of the term, appearing in
+more than docfreq documents.
+*/
+func (sc *DirectSpellChecker) suggest(term *index.Term, numSug int, ir index.IndexReader,
+	docfreq, editDistance int, accuracy float32) ([]*scoreTerm, error) {
+
+	matcher := newFuzzyMatcher(string(term.Bytes), editDistance,
+		maxInt(sc.minPrefix, editDistance-1))
+
+	candidates := make(map[string]*scoreTerm)
+	for _, ctx := range ir.Leaves() {
+		terms := ctx.Reader().(index.AtomicReader).Terms(term.Field)
+		if terms == nil {
+			continue
+		}
+		// candidates share the prefix: skip the terms before it
+		termsEnum := terms.Iterator(nil)
+		status, err := termsEnum.SeekCeil(matcher.prefix)
+		if err != nil {
+			return nil, err
+		}
+		if status == model.SEEK_STATUS_END {
+			continue
+		}
+		candidateTerm := termsEnum.Term()
+		for ; candidateTerm != nil && err == nil; candidateTerm, err = termsEnum.Next() {
+			if !bytes.HasPrefix(candidateTerm, matcher.prefix) {
+				break // terms are sorted, no more candidates
+			}
+			// ignore exact match of the same term
+			if bytes.Equal(term.Bytes, candidateTerm) {
+				continue
+			}
+			var df int
+			if df, err = termsEnum.DocFreq(); err != nil {
+				break
+			}
+			if st, ok := candidates[string(candidateTerm)]; ok {
+				st.docfreq += df
+				continue
+			}
+			text := string(candidateTerm)
+			boost, ok := matcher.boost(text)
+			if !ok {
+				continue
+			}
+			candidates[text] = &scoreTerm{term: text, boost: boost, docfreq: df}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ans []*scoreTerm
+	for _, st := range candidates {
+		// check docFreq if required
+		if st.docfreq <= docfreq {
+			continue
+		}
+		if sc.distance == INTERNAL_LEVENSHTEIN {
+			// the boost is the real scaled lev score
+			st.score = st.boost
+		} else {
+			st.score = sc.distance.Distance(string(term.Bytes), st.term)
+		}
+		if st.score < accuracy {
+			continue
+		}
+		ans = append(ans, st)
+	}
+	sort.Sort(scoreTermsByBoost(ans))
+	if len(ans) > numSug {
+		ans = ans[:numSug]
+	}
+	return ans, nil
+}
+
+// search/FuzzyTermsEnum.java
+
+/*
+Matches terms within maxEdits of the text, the first prefixLength
+code points excluded, and computes their similarity: one automaton is
+built per edit distance, so the exact distance of a candidate is the
+smallest one whose automaton accepts it.
+*/
+type fuzzyMatcher struct {
+	prefix     []byte
+	termLength int
+	matchers   []*automaton.CharacterRunAutomaton
+}
+
+func newFuzzyMatcher(text string, maxEdits, prefixLength int) *fuzzyMatcher {
+	codePoints := []rune(text)
+	realPrefixLength := minInt(prefixLength, len(codePoints))
+	prefix := string(codePoints[:realPrefixLength])
+	builder := automaton.NewLevenshteinAutomata(string(codePoints[realPrefixLength:]), true)
+	ans := &fuzzyMatcher{
+		prefix:     []byte(prefix),
+		termLength: len(codePoints),
+		matchers:   make([]*automaton.CharacterRunAutomaton, maxEdits+1),
+	}
+	for i := range ans.matchers {
+		ans.matchers[i] = automaton.NewCharacterRunAutomaton(builder.ToAutomatonWithPrefix(i, prefix))
+	}
+	return ans
+}
+
+/*
+Returns the similarity of the term, 1 - ed/min(len(term), len(text)),
+or false if the term is not within maxEdits of the text, or its
+similarity is not positive.
+*/
+func (m *fuzzyMatcher) boost(term string) (float32, bool) {
+	codePoints := []rune(term)
+	for ed, matcher := range m.matchers {
+		if !matcher.RunChars(codePoints) {
+			continue
+		}
+		if ed == 0 { // exact match
+			return 1, true
+		}
+		similarity := 1 - float32(ed)/float32(minInt(len(codePoints), m.termLength))
+		return similarity, similarity > 0
+	}
+	return 0, false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
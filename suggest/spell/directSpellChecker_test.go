@@ -0,0 +1,96 @@
+package spell
+
+import (
+	"fmt"
+	std "github.com/jtejido/golucene/analysis/standard"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/util"
+	"github.com/jtejido/golucene/test_framework/testindex"
+	"testing"
+)
+
+func words(suggestions []*SuggestWord) string {
+	var ans []string
+	for _, w := range suggestions {
+		ans = append(ans, fmt.Sprintf("%v/%v", w.String, w.Freq))
+	}
+	return fmt.Sprintf("%v", ans)
+}
+
+func newReader(t *testing.T, texts ...string) index.IndexReader {
+	directory := testindex.NewDirectory(t)
+	conf := index.NewIndexWriterConfig(util.VERSION_LATEST, std.NewStandardAnalyzer())
+	writer := testindex.NewWriter(t, directory, conf)
+	for _, doc := range testindex.TextDocs("body", texts...) {
+		if err := writer.AddDocument(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return testindex.OpenReader(t, directory)
+}
+
+func TestDirectSpellChecker(t *testing.T) {
+	reader := newReader(t,
+		"fvie five", "five fiver", "five", "fives", "fine", "fine dining",
+		"house", "houses", "mouse", "horse", "horses")
+
+	spellChecker := NewDirectSpellChecker()
+	spellChecker.SetMaxQueryFrequency(0.5)
+
+	for _, c := range []struct {
+		text     string
+		expected string
+	}{
+		// the term is in the index
+		{"fvie", "[]"},
+		// a transposition first, then the terms within 2 edits by docFreq
+		{"fiev", "[five/3 fine/2 fiver/1]"},
+		// case is folded
+		{"HOUZE", "[house/1 horse/1 houses/1]"},
+		// the first character must match
+		{"jouse", "[]"},
+		{"mousr", "[mouse/1]"},
+		// no term has the prefix, nor sorts after it
+		{"zouse", "[]"},
+		// too short
+		{"hse", "[]"},
+	} {
+		suggestions, err := spellChecker.SuggestSimilar(index.NewTerm("body", c.text), 3, reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := words(suggestions); got != c.expected {
+			t.Errorf("SuggestSimilar(%v): expected %v, but %v", c.text, c.expected, got)
+		}
+	}
+
+	// 'five' is in the index, but more popular terms can be suggested
+	suggestions, err := spellChecker.SuggestSimilarWithAccuracy(index.NewTerm("body", "fives"), 2,
+		reader, SUGGEST_MORE_POPULAR, DEFAULT_ACCURACY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := words(suggestions), "[five/3 fine/2]"; got != expected {
+		t.Errorf("Expected %v, but %v", expected, got)
+	}
+}
+
+func TestLuceneLevenshteinDistance(t *testing.T) {
+	distance := NewLuceneLevenshteinDistance()
+	for _, c := range []struct {
+		s1, s2   string
+		expected float32
+	}{
+		{"house", "house", 1},
+		{"house", "hose", 0.75},
+		{"house", "huose", 0.8},
+		{"abc", "xyz", 0},
+	} {
+		if d := distance.Distance(c.s1, c.s2); d != c.expected {
+			t.Errorf("Distance(%v, %v): expected %v, but %v", c.s1, c.s2, c.expected, d)
+		}
+	}
+}
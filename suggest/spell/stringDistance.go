@@ -0,0 +1,81 @@
+package spell
+
+// search/spell/StringDistance.java
+
+/* Interface for string distances. */
+type StringDistance interface {
+	// Returns a float between 0 and 1 based on how similar the
+	// specified strings are to one another. Returning a value of 1
+	// means the specified strings are identical and 0 means the string
+	// are maximally different.
+	Distance(s1, s2 string) float32
+}
+
+// search/spell/LuceneLevenshteinDistance.java
+
+/*
+Damerau-Levenshtein (optimal string alignment) implemented in a
+consistent way as Lucene's FuzzyTermsEnum with the transpositions
+option enabled.
+
+Notes:
+
+  - This metric treats full unicode codepoints as characters.
+  - This metric scales raw edit distances into a floating point score
+    based upon the shortest of the two terms.
+  - Transpositions of two adjacent codepoints are treated as primitive
+    edits.
+  - Edits are applied in parallel: for example, "ab" and "bca" have
+    distance 3.
+*/
+type LuceneLevenshteinDistance struct{}
+
+func NewLuceneLevenshteinDistance() *LuceneLevenshteinDistance {
+	return &LuceneLevenshteinDistance{}
+}
+
+func (ld *LuceneLevenshteinDistance) Distance(target, other string) float32 {
+	// NOTE: if we cared, we could 3*m space instead of m*n space,
+	// similar to what LevenshteinDistance does, except cycling thru a
+	// ring of three horizontal cost arrays... but this comparator is
+	// never actually used by DirectSpellChecker, it's only used for
+	// merging results from multiple shards in "distributed spellcheck",
+	// and it's inefficient in other ways too...
+	targetPoints, otherPoints := []rune(target), []rune(other)
+	n, m := len(targetPoints), len(otherPoints)
+
+	d := make([][]int, n+1)
+	for i := range d {
+		d[i] = make([]int, m+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		d[0][j] = j
+	}
+
+	for j := 1; j <= m; j++ {
+		t_j := otherPoints[j-1]
+		for i := 1; i <= n; i++ {
+			cost := 1
+			if targetPoints[i-1] == t_j {
+				cost = 0
+			}
+			// minimum of cell to the left+1, to the top+1, diagonally
+			// left and up +cost
+			d[i][j] = minInt(minInt(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			// transposition
+			if i > 1 && j > 1 && targetPoints[i-1] == otherPoints[j-2] &&
+				targetPoints[i-2] == otherPoints[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+cost)
+			}
+		}
+	}
+	return 1 - float32(d[n][m])/float32(minInt(m, n))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,104 @@
+package spell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// search/spell/SuggestMode.java
+
+/* Set of strategies for suggesting related terms. */
+type SuggestMode int
+
+const (
+	// Generate suggestions only for terms not in the index (default).
+	SUGGEST_WHEN_NOT_IN_INDEX = SuggestMode(iota)
+	// Return only suggested words that are as frequent or more frequent
+	// than the searched word.
+	SUGGEST_MORE_POPULAR
+	// Always attempt to offer suggestions (however, other parameters may
+	// limit suggestions. For example, see
+	// DirectSpellChecker.SetMaxQueryFrequency()).
+	SUGGEST_ALWAYS
+)
+
+func (m SuggestMode) String() string {
+	switch m {
+	case SUGGEST_WHEN_NOT_IN_INDEX:
+		return "SUGGEST_WHEN_NOT_IN_INDEX"
+	case SUGGEST_MORE_POPULAR:
+		return "SUGGEST_MORE_POPULAR"
+	case SUGGEST_ALWAYS:
+		return "SUGGEST_ALWAYS"
+	}
+	panic(fmt.Sprintf("unknown suggest mode: %v", int(m)))
+}
+
+// search/spell/SuggestWord.java
+
+/* A spelling correction found by the spell checker. */
+type SuggestWord struct {
+	// the suggested word
+	String string
+	// The freq of the word
+	Freq int
+	// the score of the word
+	Score float32
+}
+
+/*
+Compares two suggestions; returns a negative number, zero, or a
+positive number if a is worse than, as good as, or better than b.
+*/
+type SuggestWordComparator func(a, b *SuggestWord) int
+
+// search/spell/SuggestWordScoreComparator.java
+
+/*
+Sorts by score first, then by frequency and then by the (reversed)
+text of the word.
+*/
+func SuggestWordScoreComparator(first, second *SuggestWord) int {
+	// first criteria: the distance
+	if first.Score > second.Score {
+		return 1
+	} else if first.Score < second.Score {
+		return -1
+	}
+	// second criteria (if first criteria is equal): the popularity
+	if val := first.Freq - second.Freq; val != 0 {
+		return val
+	}
+	// third criteria: term text
+	return strings.Compare(second.String, first.String)
+}
+
+// search/spell/SuggestWordFrequencyComparator.java
+
+/* Sorts by frequency first, then by score and then by the (reversed) text. */
+func SuggestWordFrequencyComparator(first, second *SuggestWord) int {
+	// first criteria: the popularity
+	if val := first.Freq - second.Freq; val != 0 {
+		return val
+	}
+	// second criteria (if first criteria is equal): the distance
+	if first.Score > second.Score {
+		return 1
+	} else if first.Score < second.Score {
+		return -1
+	}
+	// third criteria: term text
+	return strings.Compare(second.String, first.String)
+}
+
+/* Sorts suggestions best first. */
+type suggestWordsByRank struct {
+	words      []*SuggestWord
+	comparator SuggestWordComparator
+}
+
+func (s *suggestWordsByRank) Len() int      { return len(s.words) }
+func (s *suggestWordsByRank) Swap(i, j int) { s.words[i], s.words[j] = s.words[j], s.words[i] }
+func (s *suggestWordsByRank) Less(i, j int) bool {
+	return s.comparator(s.words[i], s.words[j]) > 0
+}
@@ -0,0 +1,127 @@
+/*
+Package testindex builds the throwaway indexes tests search over: a
+temporary FSDirectory, a writer with the whitespace analyzer and the
+default codec, and a reader on the documents added. Unless a test
+chose another one, DefaultSimilarity is used for indexing and search.
+
+Everything opened here is closed, and the directory removed, once the
+test completes.
+*/
+package testindex
+
+import (
+	ac "github.com/jtejido/golucene/analysis/core"
+	_ "github.com/jtejido/golucene/core/codec/lucene410"
+	docu "github.com/jtejido/golucene/core/document"
+	"github.com/jtejido/golucene/core/index"
+	"github.com/jtejido/golucene/core/index/model"
+	"github.com/jtejido/golucene/core/search/similarities"
+	"github.com/jtejido/golucene/core/store"
+	"github.com/jtejido/golucene/core/util"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+/*
+Sets DefaultSimilarity as index.DefaultSimilarity, if none is set. The
+functions of this package call it; tests that search without them, e.g.
+over a MemoryIndex, call it first.
+*/
+func UseDefaultSimilarity() {
+	if index.DefaultSimilarity == nil {
+		index.DefaultSimilarity = func() index.Similarity {
+			return similarities.NewDefaultSimilarity()
+		}
+	}
+}
+
+/* Returns an FSDirectory in a new temporary path. */
+func NewDirectory(t testing.TB) store.Directory {
+	t.Helper()
+	UseDefaultSimilarity()
+	path, err := ioutil.TempDir("", "golucene")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+	dir, err := store.OpenFSDirectory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dir.Close() })
+	return dir
+}
+
+/* Returns the default config of the writers of tests. */
+func NewConfig() *index.IndexWriterConfig {
+	UseDefaultSimilarity()
+	return index.NewIndexWriterConfig(util.VERSION_LATEST, ac.NewWhitespaceAnalyzer())
+}
+
+/*
+Opens an IndexWriter on dir, with the given config, or NewConfig() if
+nil. The test is expected to close it.
+*/
+func NewWriter(t testing.TB, dir store.Directory, conf *index.IndexWriterConfig) *index.IndexWriter {
+	t.Helper()
+	if conf == nil {
+		conf = NewConfig()
+	}
+	w, err := index.NewIndexWriter(dir, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+/* Opens a reader on the last commit of dir. */
+func OpenReader(t testing.TB, dir store.Directory) index.IndexReader {
+	t.Helper()
+	r, err := index.OpenDirectoryReader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+/* Adds the documents, in order, to a new index, and returns a reader on it. */
+func NewReader(t testing.TB, docs ...[]model.IndexableField) index.IndexReader {
+	t.Helper()
+	blocks := make([][][]model.IndexableField, len(docs))
+	for i, doc := range docs {
+		blocks[i] = [][]model.IndexableField{doc}
+	}
+	return NewBlockReader(t, blocks...)
+}
+
+/*
+Adds each block of documents with AddDocuments, so that they are kept
+together, to a new index, and returns a reader on it.
+*/
+func NewBlockReader(t testing.TB, blocks ...[][]model.IndexableField) index.IndexReader {
+	t.Helper()
+	dir := NewDirectory(t)
+	w := NewWriter(t, dir, nil)
+	for _, block := range blocks {
+		if err := w.AddDocuments(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return OpenReader(t, dir)
+}
+
+/* Returns documents with the texts in a field, unstored. */
+func TextDocs(field string, texts ...string) [][]model.IndexableField {
+	ans := make([][]model.IndexableField, len(texts))
+	for i, text := range texts {
+		d := docu.NewDocument()
+		d.Add(docu.NewTextFieldFromString(field, text, docu.STORE_NO))
+		ans[i] = d.Fields()
+	}
+	return ans
+}